@@ -0,0 +1,40 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+)
+
+var compressorsJSON bool
+
+// CompressorsCmd prints the compression methods available in this build
+var CompressorsCmd = &cobra.Command{
+	Use:   "compressors",
+	Short: "Display the compression methods available for WALG_COMPRESSION_METHOD in this build",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		infos := compression.ListCompressors()
+		if compressorsJSON {
+			err := json.NewEncoder(os.Stdout).Encode(infos)
+			tracelog.ErrorLogger.FatalOnError(err)
+			return
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "name\textension\tlevels\tdecompressor\tcgo")
+		for _, info := range infos {
+			fmt.Fprintf(writer, "%s\t%s\t%t\t%t\t%t\n", info.Name, info.FileExtension,
+				info.SupportsLevels, info.HasDecompressor, info.RequiresCgo)
+		}
+		tracelog.ErrorLogger.FatalOnError(writer.Flush())
+	},
+}
+
+func init() {
+	CompressorsCmd.Flags().BoolVar(&compressorsJSON, "json", false, "Prints output in json format")
+}
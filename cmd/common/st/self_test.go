@@ -0,0 +1,39 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/storagetools"
+)
+
+const (
+	selfTestShortDescription = "Check the configured storage, compression and encryption by the round-trip of a test object"
+
+	payloadSizeFlag        = "size"
+	defaultSelfTestPayload = 16 << 20
+)
+
+// selfTestCmd represents the selfTest command
+var selfTestCmd = &cobra.Command{
+	Use:   "self-test",
+	Short: selfTestShortDescription,
+	Long: "Uploads the generated payload through the configured compression and encryption, downloads it back " +
+		"and checks it is not changed. The failed stage is reported, the passed test reports the throughput. " +
+		"The test object is deleted afterwards.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		uploader, err := internal.ConfigureUploader()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		storagetools.HandleSelfTest(uploader.UploadingFolder, uploader.Compressor, payloadSize)
+	},
+}
+
+var payloadSize int64
+
+func init() {
+	StorageToolsCmd.AddCommand(selfTestCmd)
+	selfTestCmd.Flags().Int64Var(&payloadSize, payloadSizeFlag, defaultSelfTestPayload,
+		"Size of the test payload in bytes")
+}
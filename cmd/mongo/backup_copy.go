@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+const backupCopyShortDescription = "Copies backup with its oplog archives from one storage to another"
+
+var (
+	backupCopyFromConfig string
+	backupCopyToConfig   string
+	backupCopyUntil      string
+	backupCopyOpts       = archive.BackupCopyOptions{}
+)
+
+// backupCopyCmd represents the backup-copy command
+var backupCopyCmd = &cobra.Command{
+	Use:   "backup-copy <backup-name>",
+	Short: backupCopyShortDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, err := internal.FolderFromConfig(backupCopyFromConfig)
+		tracelog.ErrorLogger.FatalOnError(err)
+		to, err := internal.FolderFromConfig(backupCopyToConfig)
+		tracelog.ErrorLogger.FatalOnError(err)
+		if backupCopyUntil != "" {
+			until, err := models.TimestampFromStr(backupCopyUntil)
+			tracelog.ErrorLogger.FatalOnError(err)
+			backupCopyOpts.OplogUntil = &until
+		}
+
+		err = mongo.HandleBackupCopy(from, to, args[0], backupCopyOpts, os.Stdout)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupCopyCmd)
+	backupCopyCmd.Flags().StringVar(&backupCopyFromConfig, "from", "", "Config of the storage the backup is copied from")
+	backupCopyCmd.Flags().StringVar(&backupCopyToConfig, "to", "", "Config of the storage the backup is copied to")
+	backupCopyCmd.Flags().StringVar(&backupCopyUntil, "until", "",
+		"Timestamp the copied oplog archives reach, only the archives required to restore the backup are copied by default")
+	backupCopyCmd.Flags().BoolVar(&backupCopyOpts.WithoutOplog, "without-oplog", false, "Copy only the backup and its sentinel")
+	backupCopyCmd.Flags().BoolVar(&backupCopyOpts.DryRun, "dry-run", false, "Report the objects to copy without copying them")
+	_ = backupCopyCmd.MarkFlagRequired("from")
+	_ = backupCopyCmd.MarkFlagRequired("to")
+}
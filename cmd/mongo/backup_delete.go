@@ -30,7 +30,7 @@ var backupDeleteCmd = &cobra.Command{
 		defer func() { _ = signalHandler.Close() }()
 
 		// set up storage downloader client
-		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+		downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
 		tracelog.ErrorLogger.FatalOnError(err)
 
 		// set up storage downloader client
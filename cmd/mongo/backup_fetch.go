@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -34,7 +35,7 @@ var backupFetchCmd = &cobra.Command{
 		backupSelector, err := internal.NewBackupNameSelector(args[0], true)
 		tracelog.ErrorLogger.FatalOnError(err)
 
-		internal.HandleBackupFetch(folder, backupSelector, internal.GetBackupToCommandFetcher(restoreCmd))
+		internal.HandleBackupFetch(folder, backupSelector, mongo.BackupDataFetcher(restoreCmd))
 	},
 }
 
@@ -11,7 +11,10 @@ import (
 
 const backupListShortDescription = "Prints available backups"
 
-var verbose bool
+var (
+	verbose bool
+	summary bool
+)
 
 // backupListCmd represents the backupList command
 var backupListCmd = &cobra.Command{
@@ -19,8 +22,13 @@ var backupListCmd = &cobra.Command{
 	Short: backupListShortDescription, // TODO : improve description
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+		downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
 		tracelog.ErrorLogger.FatalOnError(err)
+		if summary {
+			err = mongo.HandleBackupsSummary(downloader, os.Stdout)
+			tracelog.ErrorLogger.FatalOnError(err)
+			return
+		}
 		listing := archive.NewDefaultTabbedBackupListing()
 		err = mongo.HandleBackupsList(downloader, listing, os.Stdout, verbose)
 		tracelog.ErrorLogger.FatalOnError(err)
@@ -30,4 +38,6 @@ var backupListCmd = &cobra.Command{
 func init() {
 	cmd.AddCommand(backupListCmd)
 	backupListCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose mode")
+	backupListCmd.PersistentFlags().BoolVar(&summary, "summary", false,
+		"Print durations, sizes and compression ratios of the backups from the last finished one")
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 	"github.com/wal-g/wal-g/internal/databases/mongo/client"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -18,10 +19,14 @@ const (
 	backupPushShortDescription = "Pushes backup to storage"
 	PermanentFlag              = "permanent"
 	PermanentShorthand         = "p"
+	LabelFlag                  = "label"
+	VerifyUploadFlag           = "verify-upload"
 )
 
 var (
-	permanent = false
+	permanent    = false
+	labels       []string
+	verifyUpload = false
 )
 
 // backupPushCmd represents the backupPush command
@@ -43,13 +48,18 @@ var backupPushCmd = &cobra.Command{
 
 		uplProvider, err := internal.ConfigureSplitUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
-		uplProvider.ChangeDirectory(utility.BaseBackupPath)
+		keyTemplate, err := archive.ConfigureKeyTemplate()
+		tracelog.ErrorLogger.FatalOnError(err)
+		uploader := archive.NewBackupStorageUploader(uplProvider, keyTemplate)
+		uploader.VerifyUpload = verifyUpload
+		uploader.SetMetricsCollector(metrics.DefaultCollector())
 
 		backupCmd, err := internal.GetCommandSettingContext(ctx, internal.NameStreamCreateCmd)
 		tracelog.ErrorLogger.FatalOnError(err)
 		backupCmd.Stderr = os.Stderr
-		uploader := archive.NewStorageUploader(uplProvider)
-		metaConstructor := archive.NewBackupMongoMetaConstructor(ctx, mongoClient, uplProvider.Folder(), permanent)
+		backupLabels, err := internal.GetBackupLabels(labels)
+		tracelog.ErrorLogger.FatalOnError(err)
+		metaConstructor := archive.NewBackupMongoMetaConstructor(ctx, mongoClient, uplProvider.Folder(), permanent, backupLabels)
 
 		err = mongo.HandleBackupPush(uploader, metaConstructor, backupCmd)
 		tracelog.ErrorLogger.FatalfOnError("Backup creation failed: %v", err)
@@ -63,5 +73,8 @@ var backupPushCmd = &cobra.Command{
 
 func init() {
 	backupPushCmd.Flags().BoolVarP(&permanent, PermanentFlag, PermanentShorthand, false, "Pushes permanent backup")
+	backupPushCmd.Flags().StringArrayVar(&labels, LabelFlag, nil, "Attaches key=value label to the backup, may be repeated")
+	backupPushCmd.Flags().BoolVar(&verifyUpload, VerifyUploadFlag, false,
+		"Reads the uploaded backup back and checks its size and sha256 before uploading the sentinel")
 	cmd.AddCommand(backupPushCmd)
 }
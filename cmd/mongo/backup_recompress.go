@@ -0,0 +1,49 @@
+package mongo
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo"
+)
+
+const backupRecompressShortDescription = "Recompresses backup stream by the current compression method"
+
+var (
+	backupRecompressAll    bool
+	backupRecompressDryRun bool
+)
+
+// backupRecompressCmd represents the backup-recompress command
+var backupRecompressCmd = &cobra.Command{
+	Use:   "backup-recompress [backup-name]",
+	Short: backupRecompressShortDescription,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if backupRecompressAll {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		tracelog.ErrorLogger.FatalOnError(err)
+		compressor, err := internal.ConfigureCompressor()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		backupName := ""
+		if len(args) > 0 {
+			backupName = args[0]
+		}
+		err = mongo.HandleBackupRecompress(folder, backupName, compressor, backupRecompressDryRun, os.Stdout)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupRecompressCmd)
+	backupRecompressCmd.Flags().BoolVar(&backupRecompressAll, "all", false, "Recompress all the backups in the storage")
+	backupRecompressCmd.Flags().BoolVar(&backupRecompressDryRun, "dry-run", false,
+		"Report the objects to recompress without recompressing them")
+}
@@ -13,6 +13,7 @@ import (
 const (
 	retainAfterFlag  = "retain-after"
 	retainCountFlag  = "retain-count"
+	retainDaysFlag   = "retain-days"
 	purgeOplogFlag   = "purge-oplog"
 	purgeGarbageFlag = "purge-garbage"
 )
@@ -23,6 +24,7 @@ var (
 	purgeGarbage bool
 	retainAfter  string
 	retainCount  uint
+	retainDays   uint
 )
 
 // deleteCmd represents the delete command
@@ -37,12 +39,18 @@ func runPurge(cmd *cobra.Command, args []string) {
 		mongo.PurgeDryRun(!confirmed),
 		mongo.PurgeOplog(purgeOplog),
 		mongo.PurgeGarbage(purgeGarbage)}
+	if cmd.Flags().Changed(retainAfterFlag) && cmd.Flags().Changed(retainDaysFlag) {
+		tracelog.ErrorLogger.Fatalf("Flags %q and %q can not be used together\n", retainAfterFlag, retainDaysFlag)
+	}
 	if cmd.Flags().Changed(retainAfterFlag) {
 		retainAfterTime, err := time.Parse(time.RFC3339, retainAfter)
 		tracelog.ErrorLogger.FatalfOnError("Can not parse retain time: %v", err)
 		opts = append(opts, mongo.PurgeRetainAfter(retainAfterTime))
+	} else if cmd.Flags().Changed(retainDaysFlag) {
+		retainAfterTime := time.Now().AddDate(0, 0, -int(retainDays))
+		opts = append(opts, mongo.PurgeRetainAfter(retainAfterTime))
 	} else if cmd.Flags().Changed(purgeOplogFlag) {
-		tracelog.ErrorLogger.Fatalf("Flag %q requires %q to be passed\n", purgeOplogFlag, retainAfterFlag)
+		tracelog.ErrorLogger.Fatalf("Flag %q requires %q or %q to be passed\n", purgeOplogFlag, retainAfterFlag, retainDaysFlag)
 	}
 
 	if cmd.Flags().Changed(retainCountFlag) {
@@ -53,7 +61,7 @@ func runPurge(cmd *cobra.Command, args []string) {
 	}
 
 	// set up storage downloader client
-	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+	downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	// set up storage downloader client
@@ -62,6 +70,7 @@ func runPurge(cmd *cobra.Command, args []string) {
 
 	err = mongo.HandlePurge(downloader, purger, opts...)
 	tracelog.ErrorLogger.FatalOnError(err)
+	logListCacheHitRatio(downloader)
 }
 
 func init() {
@@ -71,4 +80,5 @@ func init() {
 	deleteCmd.Flags().BoolVar(&purgeGarbage, purgeGarbageFlag, false, "Purge garbage in backup folder")
 	deleteCmd.Flags().StringVar(&retainAfter, retainAfterFlag, "", "Keep backups newer")
 	deleteCmd.Flags().UintVar(&retainCount, retainCountFlag, 0, "Keep minimum count, except permanent backups")
+	deleteCmd.Flags().UintVar(&retainDays, retainDaysFlag, 0, "Keep backups finished during the last days")
 }
@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/mongo"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+)
+
+const integrityScanShortDescription = "Checks stored backups and oplog archives against their checksums"
+
+var integrityScanOpts = archive.IntegrityScanOptions{}
+
+// integrityScanCmd represents the integrity-scan command
+var integrityScanCmd = &cobra.Command{
+	Use:   "integrity-scan",
+	Short: integrityScanShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		err = mongo.HandleIntegrityScan(downloader, integrityScanOpts, os.Stdout)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	cmd.AddCommand(integrityScanCmd)
+	integrityScanCmd.Flags().Float64Var(&integrityScanOpts.SamplePercent, "sample", 100,
+		"Percent of the backups and oplog archives picked randomly to check")
+	integrityScanCmd.Flags().IntVar(&integrityScanOpts.Parallelism, "parallel", runtime.NumCPU(),
+		"Number of objects downloaded at once")
+}
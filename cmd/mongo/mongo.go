@@ -10,6 +10,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/metrics"
 )
 
 var dbShortDescription = "MongoDB backup tool"
@@ -40,6 +42,25 @@ func Execute() {
 	}
 }
 
+// newStorageDownloader builds the storage downloader counting its listing cache hits and misses
+// and its failovers to the secondary storages by the default metrics collector
+func newStorageDownloader(opts archive.StorageSettings) (*archive.StorageDownloader, error) {
+	downloader, err := archive.NewStorageDownloader(opts)
+	if err != nil {
+		return nil, err
+	}
+	downloader.SetListCacheCounter(metrics.DefaultCollector())
+	downloader.SetFailoverCounter(metrics.DefaultCollector())
+	return downloader, nil
+}
+
+// logListCacheHitRatio reports the share of the storage listings served from the cache, if they are cached
+func logListCacheHitRatio(downloader *archive.StorageDownloader) {
+	if ratio, ok := downloader.ListCacheHitRatio(); ok {
+		tracelog.InfoLogger.Printf("Storage listings served from the cache: %.0f%%", ratio*100)
+	}
+}
+
 func init() {
 	common.Init(cmd, internal.MONGO)
 
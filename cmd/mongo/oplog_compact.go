@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/internal/metrics"
+)
+
+const (
+	defaultOplogCompactTargetSize = 64 << 20
+	targetSizeFlag                = "target-size"
+)
+
+var (
+	confirmedOplogCompact bool
+	oplogCompactTarget    int64
+)
+
+// oplogCompactCmd represents the oplog archives compaction command
+var oplogCompactCmd = &cobra.Command{
+	Use:   "oplog-compact",
+	Short: "Merges small consecutive oplog archives",
+	Args:  cobra.NoArgs,
+	Run:   runOplogCompact,
+}
+
+func runOplogCompact(cmd *cobra.Command, args []string) {
+	// set up storage downloader client
+	downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	// set up storage uploader client
+	uplProvider, err := internal.ConfigureUploader()
+	tracelog.ErrorLogger.FatalOnError(err)
+	uplProvider.ChangeDirectory(models.OplogArchBasePath)
+	uploader := archive.NewStorageUploader(uplProvider)
+	uploader.SetMetricsCollector(metrics.DefaultCollector())
+	deduplication, err := internal.GetBoolSettingDefault(internal.OplogArchiveDeduplication, false)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if deduplication {
+		chunkStore := archive.NewStorageChunkStore(uplProvider.Folder().GetSubFolder(models.OplogChunksPath),
+			uplProvider.Compression(), internal.ConfigureCrypter())
+		uploader.EnableDeduplication(chunkStore)
+	}
+
+	// set up storage purger client
+	purger, err := archive.NewStoragePurger(archive.NewDefaultStorageSettings())
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = mongo.HandleOplogCompact(downloader, uploader, purger, oplogCompactTarget, !confirmedOplogCompact)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+func init() {
+	cmd.AddCommand(oplogCompactCmd)
+	oplogCompactCmd.Flags().BoolVar(&confirmedOplogCompact, internal.ConfirmFlag, false, "Confirms oplog archives compaction")
+	oplogCompactCmd.Flags().Int64Var(&oplogCompactTarget, targetSizeFlag, defaultOplogCompactTargetSize,
+		"Maximal stored size of the merged oplog archive in bytes, larger archives are not compacted")
+}
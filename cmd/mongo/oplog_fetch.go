@@ -17,6 +17,14 @@ import (
 
 var (
 	format string
+	// oplogStorageURL is the file:// URL of the local directory the oplog archives are read from, if set
+	oplogStorageURL string
+)
+
+const (
+	storageURLFlag        = "storage-url"
+	storageURLDescription = "file:// URL of the local directory with the storage layout to read the oplog archives from " +
+		"instead of the configured storage"
 )
 
 // oplogFetchCmd represents oplog replay procedure
@@ -40,7 +48,7 @@ var oplogFetchCmd = &cobra.Command{
 		oplogApplier := stages.NewGenericApplier(formatApplier)
 
 		// set up storage downloader client
-		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+		downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings().WithRootURL(oplogStorageURL))
 		tracelog.ErrorLogger.FatalOnError(err)
 
 		// discover archive sequence to replay
@@ -55,6 +63,7 @@ var oplogFetchCmd = &cobra.Command{
 		// run worker cycle
 		err = mongo.HandleOplogReplay(ctx, since, until, oplogFetcher, oplogApplier)
 		tracelog.ErrorLogger.FatalOnError(err)
+		logListCacheHitRatio(downloader)
 	},
 }
 
@@ -62,4 +71,5 @@ func init() {
 	cmd.AddCommand(oplogFetchCmd)
 	oplogFetchCmd.PersistentFlags().StringVarP(
 		&format, "format", "f", "json", "Valid values: json, bson, bson-raw")
+	oplogFetchCmd.Flags().StringVar(&oplogStorageURL, storageURLFlag, "", storageURLDescription)
 }
@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/mongo"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+)
+
+const oplogListShortDescription = "Prints oplog archives with their compression algorithms and sizes"
+
+var oplogListJSON bool
+
+// oplogListCmd represents the oplog-list command
+var oplogListCmd = &cobra.Command{
+	Use:   "oplog-list",
+	Short: oplogListShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
+		tracelog.ErrorLogger.FatalOnError(err)
+		err = mongo.HandleOplogList(downloader, os.Stdout, oplogListJSON)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	cmd.AddCommand(oplogListCmd)
+	oplogListCmd.Flags().BoolVar(&oplogListJSON, "json", false, "Print the archives as JSON")
+}
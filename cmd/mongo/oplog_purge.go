@@ -35,7 +35,7 @@ func pitrDiscoveryAfterTime() *time.Time {
 func runOplogPurge(cmd *cobra.Command, args []string) {
 	pitrAfterTime := pitrDiscoveryAfterTime()
 	// set up storage downloader client
-	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+	downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	// set up storage purger client
@@ -44,6 +44,7 @@ func runOplogPurge(cmd *cobra.Command, args []string) {
 
 	err = mongo.HandleOplogPurge(downloader, purger, pitrAfterTime, !confirmedOplogPurge)
 	tracelog.ErrorLogger.FatalOnError(err)
+	logListCacheHitRatio(downloader)
 }
 
 func init() {
@@ -16,6 +16,7 @@ import (
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
 	"github.com/wal-g/wal-g/internal/databases/mongo/stages"
 	"github.com/wal-g/wal-g/internal/databases/mongo/stats"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/internal/webserver"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -60,6 +61,12 @@ func runOplogPush(ctx context.Context, pushArgs oplogPushRunArgs, statsArgs oplo
 	}
 	uplProvider.ChangeDirectory(models.OplogArchBasePath)
 	uploader := archive.NewStorageUploader(uplProvider)
+	uploader.SetMetricsCollector(metrics.DefaultCollector())
+	if pushArgs.deduplication {
+		chunkStore := archive.NewStorageChunkStore(uplProvider.Folder().GetSubFolder(models.OplogChunksPath),
+			uplProvider.Compression(), internal.ConfigureCrypter())
+		uploader.EnableDeduplication(chunkStore)
+	}
 
 	// set up mongodb client and oplog fetcher
 	mongoClient, err := client.NewMongoClient(ctx, pushArgs.mongodbURL)
@@ -84,7 +91,7 @@ func runOplogPush(ctx context.Context, pushArgs oplogPushRunArgs, statsArgs oplo
 	}
 
 	// Lookup for last timestamp archived to storage (set up storage downloader client)
-	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+	downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings())
 	if err != nil {
 		return err
 	}
@@ -115,6 +122,7 @@ func runOplogPush(ctx context.Context, pushArgs oplogPushRunArgs, statsArgs oplo
 		pushArgs.archiveAfterSize,
 		pushArgs.archiveTimeout,
 		uploadStatsUpdater)
+	oplogApplier.SetUploadConcurrency(pushArgs.uploadConcurrency)
 	oplogFetcher := stages.NewCursorMajFetcher(mongoClient, oplogCursor, pushArgs.lwUpdate)
 
 	// run working cycle
@@ -128,6 +136,8 @@ type oplogPushRunArgs struct {
 	primaryWait        bool
 	primaryWaitTimeout time.Duration
 	lwUpdate           time.Duration
+	deduplication      bool
+	uploadConcurrency  int
 }
 
 func buildOplogPushRunArgs() (args oplogPushRunArgs, err error) {
@@ -159,6 +169,16 @@ func buildOplogPushRunArgs() (args oplogPushRunArgs, err error) {
 	}
 
 	args.lwUpdate, err = internal.GetDurationSetting(internal.MongoDBLastWriteUpdateInterval)
+	if err != nil {
+		return
+	}
+
+	args.deduplication, err = internal.GetBoolSettingDefault(internal.OplogArchiveDeduplication, false)
+	if err != nil {
+		return
+	}
+
+	args.uploadConcurrency, err = internal.GetMaxConcurrency(internal.OplogArchiveUploadConcurrency)
 	return
 }
 
@@ -49,6 +49,10 @@ type oplogReplayRunArgs struct {
 
 	oplogAlwaysUpsert    *bool
 	oplogApplicationMode *string
+
+	cacheSize int64
+	cacheDir  string
+	prefetch  int64
 }
 
 func buildOplogReplayRunArgs(cmdargs []string) (args oplogReplayRunArgs, err error) {
@@ -87,6 +91,14 @@ func buildOplogReplayRunArgs(cmdargs []string) (args oplogReplayRunArgs, err err
 		args.oplogApplicationMode = &oplogApplicationMode
 	}
 
+	if args.cacheSize, err = internal.GetInt64SettingDefault(internal.OplogReplayCacheSize, 0); err != nil {
+		return
+	}
+	if args.prefetch, err = internal.GetInt64SettingDefault(internal.OplogReplayPrefetch, 4); err != nil {
+		return
+	}
+	args.cacheDir, _ = internal.GetSetting(internal.OplogReplayCacheDir)
+
 	return args, nil
 }
 
@@ -117,10 +129,11 @@ func runOplogReplay(ctx context.Context, replayArgs oplogReplayRunArgs) error {
 	oplogApplier := stages.NewGenericApplier(dbApplier)
 
 	// set up storage downloader client
-	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
+	downloader, err := newStorageDownloader(archive.NewDefaultStorageSettings().WithRootURL(oplogStorageURL))
 	if err != nil {
 		return err
 	}
+	defer logListCacheHitRatio(downloader)
 	// discover archive sequence to replay
 	archives, err := downloader.ListOplogArchives()
 	if err != nil {
@@ -131,8 +144,29 @@ func runOplogReplay(ctx context.Context, replayArgs oplogReplayRunArgs) error {
 		return err
 	}
 
+	// prefetch the archives into the local cache, if enabled
+	var archiveDownloader archive.Downloader = downloader
+	if replayArgs.cacheSize > 0 {
+		cacheDir := replayArgs.cacheDir
+		if cacheDir == "" {
+			if cacheDir, err = os.MkdirTemp("", "walg-oplog-cache"); err != nil {
+				return err
+			}
+			defer func() { _ = os.RemoveAll(cacheDir) }()
+		}
+		tracelog.InfoLogger.Printf("Prefetching %d oplog archives ahead into '%s' (up to %d bytes)",
+			replayArgs.prefetch, cacheDir, replayArgs.cacheSize)
+		cachedDownloader, err := archive.NewCachedDownloader(downloader, path, cacheDir, replayArgs.cacheSize,
+			int(replayArgs.prefetch))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cachedDownloader.Close() }()
+		archiveDownloader = cachedDownloader
+	}
+
 	// setup storage fetcher
-	oplogFetcher := stages.NewStorageFetcher(downloader, path)
+	oplogFetcher := stages.NewStorageFetcher(archiveDownloader, path)
 
 	// run worker cycle
 	return mongo.HandleOplogReplay(ctx, replayArgs.since, replayArgs.until, oplogFetcher, oplogApplier)
@@ -140,4 +174,5 @@ func runOplogReplay(ctx context.Context, replayArgs oplogReplayRunArgs) error {
 
 func init() {
 	cmd.AddCommand(oplogReplayCmd)
+	oplogReplayCmd.Flags().StringVar(&oplogStorageURL, storageURLFlag, "", storageURLDescription)
 }
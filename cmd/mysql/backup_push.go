@@ -14,6 +14,7 @@ const (
 	permanentFlag              = "permanent"
 	permanentShorthand         = "p"
 	addUserDataFlag            = "add-user-data"
+	labelFlag                  = "label"
 )
 
 var (
@@ -39,11 +40,15 @@ var (
 				userData = viper.GetString(internal.SentinelUserDataSetting)
 			}
 
-			mysql.HandleBackupPush(folder, uploader, backupCmd, permanent, userData)
+			backupLabels, err := internal.GetBackupLabels(labels)
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			mysql.HandleBackupPush(folder, uploader, backupCmd, permanent, userData, backupLabels)
 		},
 	}
 	permanent = false
 	userData  = ""
+	labels    []string
 )
 
 func init() {
@@ -55,4 +60,5 @@ func init() {
 		false, "Pushes permanent backup")
 	backupPushCmd.Flags().StringVar(&userData, addUserDataFlag,
 		"", "Write the provided user data to the backup sentinel and metadata files.")
+	backupPushCmd.Flags().StringArrayVar(&labels, labelFlag, nil, "Attaches key=value label to the backup, may be repeated")
 }
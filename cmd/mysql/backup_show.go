@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+)
+
+const backupShowShortDescription = "Prints the details of the backup stored in its sentinel"
+
+var (
+	// backupShowCmd represents the backupShow command
+	backupShowCmd = &cobra.Command{
+		Use:   "backup-show backup_name",
+		Short: backupShowShortDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			err = mysql.HandleBackupShow(folder, args[0], os.Stdout, showJSON, showPretty)
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+	}
+	showJSON   = false
+	showPretty = false
+)
+
+func init() {
+	cmd.AddCommand(backupShowCmd)
+
+	backupShowCmd.Flags().BoolVar(&showJSON, JSONFlag, false, "Prints output in json format")
+	backupShowCmd.Flags().BoolVar(&showPretty, PrettyFlag, false, "Prints indented json if combined with --json")
+}
@@ -21,6 +21,11 @@ For information about pattern syntax view: https://golang.org/pkg/path/filepath/
 	reverseDeltaUnpackDescription = "Unpack delta backups in reverse order (beta feature)"
 	skipRedundantTarsDescription  = "Skip tars with no useful data (requires reverse delta unpack)"
 	targetUserDataDescription     = "Fetch storage backup which has the specified user data"
+	restoreOnlyDescription        = "Restore only the specified databases (names or OIDs) and the system databases"
+	excludeOptionalDescription    = "Skip the files not needed to bootstrap a standby (statistics, logs, replication slots), " +
+		"the patterns are overridden by WALG_RESTORE_EXCLUDE"
+	forceFetchDescription         = "Skip the check that the restored backup fits into the free disk space"
+	deterministicOrderDescription = "Extract the tar entries one by one in the sorted order, so the restores are reproducible"
 )
 
 var fileMask string
@@ -28,6 +33,10 @@ var restoreSpec string
 var reverseDeltaUnpack bool
 var skipRedundantTars bool
 var fetchTargetUserData string
+var restoreOnly []string
+var excludeOptional bool
+var forceFetch bool
+var deterministicOrder bool
 
 var backupFetchCmd = &cobra.Command{
 	Use:   "backup-fetch destination_directory [backup_name | --target-user-data <data>]",
@@ -43,13 +52,21 @@ var backupFetchCmd = &cobra.Command{
 		folder, err := internal.ConfigureFolder()
 		tracelog.ErrorLogger.FatalOnError(err)
 
+		excludePatterns, err := postgres.GetRestoreExcludePatterns(excludeOptional)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		if deterministicOrder {
+			viper.Set(internal.RestoreInOrderSetting, true)
+		}
+
 		var pgFetcher func(folder storage.Folder, backup internal.Backup)
 		reverseDeltaUnpack = reverseDeltaUnpack || viper.GetBool(internal.UseReverseUnpackSetting)
 		skipRedundantTars = skipRedundantTars || viper.GetBool(internal.SkipRedundantTarsSetting)
 		if reverseDeltaUnpack {
-			pgFetcher = postgres.GetPgFetcherNew(args[0], fileMask, restoreSpec, skipRedundantTars)
+			pgFetcher = postgres.GetPgFetcherNew(args[0], fileMask, restoreSpec, skipRedundantTars, restoreOnly, excludePatterns,
+				forceFetch)
 		} else {
-			pgFetcher = postgres.GetPgFetcherOld(args[0], fileMask, restoreSpec)
+			pgFetcher = postgres.GetPgFetcherOld(args[0], fileMask, restoreSpec, restoreOnly, excludePatterns, forceFetch)
 		}
 
 		internal.HandleBackupFetch(folder, targetBackupSelector, pgFetcher)
@@ -81,5 +98,11 @@ func init() {
 		false, skipRedundantTarsDescription)
 	backupFetchCmd.Flags().StringVar(&fetchTargetUserData, "target-user-data",
 		"", targetUserDataDescription)
+	backupFetchCmd.Flags().StringSliceVar(&restoreOnly, "restore-only",
+		nil, restoreOnlyDescription)
+	backupFetchCmd.Flags().BoolVar(&excludeOptional, "exclude-optional",
+		false, excludeOptionalDescription)
+	backupFetchCmd.Flags().BoolVar(&forceFetch, "force", false, forceFetchDescription)
+	backupFetchCmd.Flags().BoolVar(&deterministicOrder, "deterministic-order", false, deterministicOrderDescription)
 	Cmd.AddCommand(backupFetchCmd)
 }
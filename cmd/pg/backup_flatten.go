@@ -0,0 +1,39 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	BackupFlattenShortDescription = "Consolidates the incremental backup chain into the full backup"
+	BackupFlattenLongDescription  = `Restores the base backup of the chain ending with the given delta backup,
+	applies its increments in order and uploads the result as the full backup named without the delta suffix.
+	The chain itself is kept, so it can be removed by the delete command afterwards.`
+	WorkDirFlag        = "work-dir"
+	WorkDirDescription = "Directory to restore the backup chain to, the system temporary directory by default"
+)
+
+var (
+	// backupFlattenCmd represents the backupFlatten command
+	backupFlattenCmd = &cobra.Command{
+		Use:   "backup-flatten backup_name",
+		Short: BackupFlattenShortDescription,
+		Long:  BackupFlattenLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			uploader, err := postgres.ConfigureWalUploader()
+			tracelog.ErrorLogger.FatalOnError(err)
+			postgres.HandleBackupFlatten(uploader.Uploader, args[0], flattenWorkDir)
+		},
+	}
+	flattenWorkDir string
+)
+
+func init() {
+	backupFlattenCmd.Flags().StringVar(&flattenWorkDir, WorkDirFlag, os.TempDir(), WorkDirDescription)
+	Cmd.AddCommand(backupFlattenCmd)
+}
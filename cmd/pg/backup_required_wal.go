@@ -0,0 +1,37 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupRequiredWalShortDescription = "Reports the WAL segments required to restore the backup"
+	backupRequiredWalLongDescription  = `Lists the WAL segments from the backup start LSN to its finish LSN
+	and the ones of them missing in the WAL archive. Use LATEST for the latest backup.`
+)
+
+var (
+	// backupRequiredWalCmd represents the backup-required-wal command
+	backupRequiredWalCmd = &cobra.Command{
+		Use:   "backup-required-wal backup_name",
+		Short: backupRequiredWalShortDescription,
+		Long:  backupRequiredWalLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			postgres.HandleBackupRequiredWal(folder, args[0], os.Stdout, backupRequiredWalJSON)
+		},
+	}
+	backupRequiredWalJSON bool
+)
+
+func init() {
+	Cmd.AddCommand(backupRequiredWalCmd)
+	backupRequiredWalCmd.Flags().BoolVar(&backupRequiredWalJSON, JSONFlag, false, "Prints output in json format")
+}
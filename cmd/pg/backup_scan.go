@@ -0,0 +1,38 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	BackupScanShortDescription = "Verifies the backup files against their checksums without restoring them"
+	BackupScanLongDescription  = `Streams each file of the backup through the decompression to nowhere,
+	comparing it with the checksum stored in the files metadata, and reports the mismatched and missing files.
+	Nothing is written to disk. The increments of the delta backup are read but not verified.`
+	StopOnErrorFlag        = "stop-on-error"
+	StopOnErrorDescription = "Stop the scan at the first mismatched or missing file instead of collecting all of them"
+)
+
+var (
+	// backupScanCmd represents the backupScan command
+	backupScanCmd = &cobra.Command{
+		Use:   "backup-scan backup_name",
+		Short: BackupScanShortDescription,
+		Long:  BackupScanLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			postgres.HandleBackupScan(folder, args[0], scanStopOnError)
+		},
+	}
+	scanStopOnError bool
+)
+
+func init() {
+	backupScanCmd.Flags().BoolVar(&scanStopOnError, StopOnErrorFlag, false, StopOnErrorDescription)
+	Cmd.AddCommand(backupScanCmd)
+}
@@ -9,6 +9,8 @@ import (
 
 const UseSentinelTimeFlag = "use-sentinel-time"
 const UseSentinelTimeDescription = "Use backup creation time from sentinel for backups ordering."
+const ForceFlag = "force"
+const ForceDescription = "Delete backups even if some increments based on them are kept"
 const DeleteGarbageExamples = `  garbage           Deletes outdated WAL archives and leftover backups files from storage
   garbage ARCHIVES  Deletes only outdated WAL archives from storage
   garbage BACKUPS   Deletes only leftover backups files from storage`
@@ -17,6 +19,7 @@ const DeleteGarbageUse = "garbage [ARCHIVES|BACKUPS]"
 var confirmed = false
 var useSentinelTime = false
 var deleteTargetUserData = ""
+var forceOrphans = false
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -66,7 +69,8 @@ func runDeleteBefore(cmd *cobra.Command, args []string) {
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
-	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
+	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime,
+		internal.AllowOrphanedIncrements(forceOrphans))
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
@@ -78,7 +82,8 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
-	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
+	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime,
+		internal.AllowOrphanedIncrements(forceOrphans))
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
@@ -90,7 +95,8 @@ func runDeleteEverything(cmd *cobra.Command, args []string) {
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
-	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
+	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime,
+		internal.AllowOrphanedIncrements(forceOrphans))
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	deleteHandler.HandleDeleteEverything(args, permanentBackups, confirmed)
@@ -110,7 +116,8 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 		args = args[1:]
 	}
 
-	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
+	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime,
+		internal.AllowOrphanedIncrements(forceOrphans))
 	tracelog.ErrorLogger.FatalOnError(err)
 	targetBackupSelector, err := internal.CreateTargetDeleteBackupSelector(cmd, args, deleteTargetUserData, postgres.NewGenericMetaFetcher())
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -144,4 +151,5 @@ func init() {
 	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd, deleteGarbageCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
 	deleteCmd.PersistentFlags().BoolVar(&useSentinelTime, UseSentinelTimeFlag, false, UseSentinelTimeDescription)
+	deleteCmd.PersistentFlags().BoolVar(&forceOrphans, ForceFlag, false, ForceDescription)
 }
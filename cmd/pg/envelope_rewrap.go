@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const (
+	EnvelopeRewrapShortDescription = "Rewraps the detached envelope data keys with the current master key"
+	EnvelopeRewrapLongDescription  = `Rewraps the data keys stored apart from the encrypted backups and WAL files
+	with the master key set by WALG_ENVELOPE_CURRENT_KEY_ID, the encrypted objects are not re-uploaded.
+	The keys already wrapped by the current master key are skipped.`
+)
+
+// envelopeRewrapCmd represents the envelopeRewrap command
+var envelopeRewrapCmd = &cobra.Command{
+	Use:   "envelope-rewrap",
+	Short: EnvelopeRewrapShortDescription,
+	Long:  EnvelopeRewrapLongDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := internal.HandleEnvelopeRewrap(internal.ConfigureCrypter())
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	Cmd.AddCommand(envelopeRewrapCmd)
+}
@@ -0,0 +1,37 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const (
+	TarFsyncModeMigrateShortDescription = "Prints the WALG_TAR_FSYNC_MODE value replacing the deprecated fsync settings"
+	TarFsyncModeMigrateLongDescription  = `Prints the WALG_TAR_FSYNC_MODE value keeping the current restore behavior
+	configured by the deprecated WALG_TAR_DISABLE_FSYNC. With --write-config the value is saved
+	to the JSON config file and the deprecated setting is removed from it.`
+	writeConfigFlag        = "write-config"
+	writeConfigDescription = "JSON config file to save the fsync mode to"
+)
+
+var tarFsyncModeMigrateConfigFile string
+
+// tarFsyncModeMigrateCmd represents the tarFsyncModeMigrate command
+var tarFsyncModeMigrateCmd = &cobra.Command{
+	Use:   "tar-fsync-mode-migrate",
+	Short: TarFsyncModeMigrateShortDescription,
+	Long:  TarFsyncModeMigrateLongDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := internal.HandleTarFsyncModeMigrate(os.Stdout, tarFsyncModeMigrateConfigFile)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	tarFsyncModeMigrateCmd.Flags().StringVar(&tarFsyncModeMigrateConfigFile, writeConfigFlag, "", writeConfigDescription)
+	Cmd.AddCommand(tarFsyncModeMigrateCmd)
+}
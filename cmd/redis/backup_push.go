@@ -16,12 +16,14 @@ import (
 
 var (
 	permanent = false
+	labels    []string
 )
 
 const (
 	backupPushShortDescription = "Makes backup and uploads it to storage"
 	PermanentFlag              = "permanent"
 	PermanentShorthand         = "p"
+	LabelFlag                  = "label"
 )
 
 // backupPushCmd represents the backupPush command
@@ -48,7 +50,9 @@ var backupPushCmd = &cobra.Command{
 			backupCmd.Env = append(backupCmd.Env, fmt.Sprintf("REDISCLI_AUTH=%s", redisPassword))
 		}
 		backupCmd.Stderr = os.Stderr
-		metaConstructor := archive.NewBackupRedisMetaConstructor(ctx, uploader.UploadingFolder, permanent)
+		backupLabels, err := internal.GetBackupLabels(labels)
+		tracelog.ErrorLogger.FatalOnError(err)
+		metaConstructor := archive.NewBackupRedisMetaConstructor(ctx, uploader.UploadingFolder, permanent, backupLabels)
 
 		err = redis.HandleBackupPush(uploader, backupCmd, metaConstructor)
 		tracelog.ErrorLogger.FatalfOnError("Redis backup creation failed: %v", err)
@@ -62,5 +66,6 @@ var backupPushCmd = &cobra.Command{
 
 func init() {
 	backupPushCmd.Flags().BoolVarP(&permanent, PermanentFlag, PermanentShorthand, false, "Pushes backup with 'permanent' flag")
+	backupPushCmd.Flags().StringArrayVar(&labels, LabelFlag, nil, "Attaches key=value label to the backup, may be repeated")
 	cmd.AddCommand(backupPushCmd)
 }
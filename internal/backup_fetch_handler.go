@@ -29,6 +29,12 @@ func (err BackupNonExistenceError) Error() string {
 
 // GetBackupToCommandFetcher returns function that copies all bytes from backup to cmd's stdin
 func GetBackupToCommandFetcher(cmd *exec.Cmd) func(folder storage.Folder, backup Backup) {
+	return GetBackupToCommandFetcherBy(cmd, GetBackupStreamFetcher)
+}
+
+// GetBackupToCommandFetcherBy is GetBackupToCommandFetcher with the backup stream format detected by getStreamFetcher
+func GetBackupToCommandFetcherBy(cmd *exec.Cmd,
+	getStreamFetcher func(backup Backup) (StreamFetcher, error)) func(folder storage.Folder, backup Backup) {
 	return func(folder storage.Folder, backup Backup) {
 		stdin, err := cmd.StdinPipe()
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
@@ -37,7 +43,7 @@ func GetBackupToCommandFetcher(cmd *exec.Cmd) func(folder storage.Folder, backup
 		err = cmd.Start()
 		tracelog.ErrorLogger.FatalfOnError("Failed to start restore command: %v\n", err)
 
-		fetcher, err := GetBackupStreamFetcher(backup)
+		fetcher, err := getStreamFetcher(backup)
 		tracelog.ErrorLogger.FatalfOnError("Failed to detect backup format: %v\n", err)
 
 		err = fetcher(backup, stdin)
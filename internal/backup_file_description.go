@@ -13,10 +13,12 @@ type BackupFileDescription struct {
 	MTime         time.Time
 	CorruptBlocks *CorruptBlocksInfo `json:",omitempty"`
 	UpdatesCount  uint64
+	Checksum      *FileChecksum `json:",omitempty"`
+	FileMode      int64         `json:",omitempty"`
 }
 
 func NewBackupFileDescription(isIncremented, isSkipped bool, modTime time.Time) *BackupFileDescription {
-	return &BackupFileDescription{isIncremented, isSkipped, modTime, nil, 0}
+	return &BackupFileDescription{isIncremented, isSkipped, modTime, nil, 0, nil, 0}
 }
 
 type CorruptBlocksInfo struct {
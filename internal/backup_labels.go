@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+type InvalidBackupLabelError struct {
+	error
+}
+
+func newInvalidBackupLabelError(label string, reason string) InvalidBackupLabelError {
+	return InvalidBackupLabelError{errors.Errorf("invalid backup label '%s': %s", label, reason)}
+}
+
+func (err InvalidBackupLabelError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetBackupLabels collects the backup labels from the WALG_BACKUP_LABELS setting
+// (comma-separated key=value pairs) and the given key=value pairs, the latter take precedence
+func GetBackupLabels(labelPairs []string) (map[string]string, error) {
+	var pairs []string
+	if labelsStr, ok := GetSetting(BackupLabelsSetting); ok && labelsStr != "" {
+		pairs = append(pairs, strings.Split(labelsStr, ",")...)
+	}
+	return ParseBackupLabels(append(pairs, labelPairs...))
+}
+
+// ParseBackupLabels parses the key=value pairs, returns nil if there are no labels
+func ParseBackupLabels(labelPairs []string) (map[string]string, error) {
+	if len(labelPairs) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(labelPairs))
+	for _, pair := range labelPairs {
+		keyValue := strings.SplitN(pair, "=", 2)
+		if len(keyValue) != 2 {
+			return nil, newInvalidBackupLabelError(pair, "expected key=value")
+		}
+		key, value := strings.TrimSpace(keyValue[0]), keyValue[1]
+		if key == "" {
+			return nil, newInvalidBackupLabelError(pair, "key is empty")
+		}
+		if err := validateBackupLabelText(pair, key); err != nil {
+			return nil, err
+		}
+		if err := validateBackupLabelText(pair, value); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// FormatBackupLabels prints the labels as comma-separated key=value pairs sorted by the keys
+func FormatBackupLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// validateBackupLabelText rejects the text which cannot be stored in the sentinel as is
+func validateBackupLabelText(label, text string) error {
+	if !utf8.ValidString(text) {
+		return newInvalidBackupLabelError(label, "not a valid UTF-8 string")
+	}
+	if strings.IndexFunc(text, unicode.IsControl) != -1 {
+		return newInvalidBackupLabelError(label, "contains control characters")
+	}
+	return nil
+}
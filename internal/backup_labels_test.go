@@ -0,0 +1,43 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestParseBackupLabels(t *testing.T) {
+	labels, err := internal.ParseBackupLabels([]string{"region=eu-west", "app_version=1.2=rc", "empty="})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu-west", "app_version": "1.2=rc", "empty": ""}, labels)
+}
+
+func TestParseBackupLabels_NoLabels(t *testing.T) {
+	labels, err := internal.ParseBackupLabels(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, labels)
+}
+
+func TestParseBackupLabels_Invalid(t *testing.T) {
+	for _, pair := range []string{"region", "=eu-west", "region=eu\nwest", "reg\x00ion=eu", "region=\xff"} {
+		_, err := internal.ParseBackupLabels([]string{pair})
+		assert.IsType(t, internal.InvalidBackupLabelError{}, err, pair)
+	}
+}
+
+func TestGetBackupLabels_FlagsOverrideSetting(t *testing.T) {
+	viper.Set(internal.BackupLabelsSetting, "region=eu-west,cluster=main")
+	defer viper.Set(internal.BackupLabelsSetting, nil)
+
+	labels, err := internal.GetBackupLabels([]string{"cluster=reserve"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu-west", "cluster": "reserve"}, labels)
+}
+
+func TestFormatBackupLabels(t *testing.T) {
+	assert.Equal(t, "cluster=main,region=eu-west",
+		internal.FormatBackupLabels(map[string]string{"region": "eu-west", "cluster": "main"}))
+	assert.Equal(t, "", internal.FormatBackupLabels(nil))
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// CorruptSentinelError is returned when the sentinel of the backup exists but can not be parsed
+type CorruptSentinelError struct {
+	error
+}
+
+func newCorruptSentinelError(backupName string, err error) CorruptSentinelError {
+	return CorruptSentinelError{errors.Wrapf(err, "sentinel of backup '%s' is corrupt", backupName)}
+}
+
+func (err CorruptSentinelError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// FetchExistingSentinel fetches the sentinel of the backup into sentinelDto like FetchSentinel, but tells the failures
+// apart: the BackupNonExistenceError is returned if the backup has no sentinel, the CorruptSentinelError
+// if the sentinel is read but can not be parsed
+func (backup *Backup) FetchExistingSentinel(sentinelDto interface{}) error {
+	if err := backup.AssureExists(); err != nil {
+		return err
+	}
+	reader, err := backup.Folder.ReadObject(backup.getStopSentinelPath())
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	sentinel, err := io.ReadAll(reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the sentinel of backup '%s'", backup.Name)
+	}
+	unmarshaller, err := NewDtoSerializer()
+	if err != nil {
+		return err
+	}
+	if err = unmarshaller.Unmarshal(bytes.NewReader(sentinel), sentinelDto); err != nil {
+		return newCorruptSentinelError(backup.Name, err)
+	}
+	return nil
+}
+
+// BackupDetailsField is the named value of the backup details printed by backup-show
+type BackupDetailsField struct {
+	Name  string
+	Value string
+}
+
+// WriteBackupDetails prints the details as JSON if asJSON is set, the fields with the non-empty values
+// as the table otherwise
+func WriteBackupDetails(details interface{}, fields []BackupDetailsField, output io.Writer, asJSON, pretty bool) error {
+	if asJSON {
+		return WriteAsJSON(details, output, pretty)
+	}
+	writer := table.NewWriter()
+	writer.SetOutputMirror(output)
+	writer.AppendHeader(table.Row{"Field", "Value"})
+	for _, field := range fields {
+		if field.Value != "" {
+			writer.AppendRow(table.Row{field.Name, field.Value})
+		}
+	}
+	writer.Render()
+	return nil
+}
+
+// FormatDetailsTime formats the timestamp for WriteBackupDetails, the zero one is empty
+func FormatDetailsTime(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return ""
+	}
+	return timestamp.Format(time.RFC3339)
+}
+
+// FormatDetailsSize formats the size for WriteBackupDetails, the zero one is empty,
+// since the sentinels of the older backups lack the sizes
+func FormatDetailsSize(size int64) string {
+	if size == 0 {
+		return ""
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// FormatDetailsJSON formats the value, e.g. the user data, as JSON for WriteBackupDetails, the nil one is empty
+func FormatDetailsJSON(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
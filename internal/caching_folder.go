@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// ListCache is implemented by the folders caching their listings
+type ListCache interface {
+	// SetCacheCounter registers counter to receive the listing cache hits and misses
+	SetCacheCounter(counter metrics.CacheCounter)
+	// HitRatio returns the share of the listings served from the cache, 0 if nothing was listed
+	HitRatio() float64
+	// Invalidate drops the cached listings of the folder and its subfolders
+	Invalidate()
+}
+
+// CachingFolder memoizes the ListFolder results of the wrapped folder and its subfolders for the TTL.
+// The writes through the wrapper drop the cached listings of the folders they change, while the writes
+// bypassing it become visible once the TTL expires or the listing is dropped by Invalidate.
+// It is safe for concurrent use.
+type CachingFolder struct {
+	storage.Folder
+	cache *folderListCache
+}
+
+// cachingRangeFolder is the CachingFolder of the folder able to read the object ranges
+type cachingRangeFolder struct {
+	*CachingFolder
+	rangeReader storage.RangeReader
+}
+
+var _ ListCache = &CachingFolder{}
+var _ storage.RangeReader = cachingRangeFolder{}
+
+type folderListCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	counter metrics.CacheCounter
+
+	mutex   sync.Mutex
+	entries map[string]folderListEntry
+	// generation is increased by every invalidation, so the listing started before it is not cached
+	generation uint64
+	hits       uint64
+	misses     uint64
+}
+
+type folderListEntry struct {
+	objects    []storage.Object
+	subFolders []storage.Folder
+	expiresAt  time.Time
+}
+
+// NewCachingFolder wraps the folder to cache its listings for the ttl, the returned folder implements the ListCache.
+// The range reads are passed through if the folder supports them.
+func NewCachingFolder(folder storage.Folder, ttl time.Duration) storage.Folder {
+	return newCachingFolder(folder, &folderListCache{ttl: ttl, now: time.Now, entries: make(map[string]folderListEntry)})
+}
+
+func newCachingFolder(folder storage.Folder, cache *folderListCache) storage.Folder {
+	cachingFolder := &CachingFolder{Folder: folder, cache: cache}
+	if rangeReader, ok := folder.(storage.RangeReader); ok {
+		return cachingRangeFolder{CachingFolder: cachingFolder, rangeReader: rangeReader}
+	}
+	return cachingFolder
+}
+
+func (folder cachingRangeFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	return folder.rangeReader.ReadObjectVersion(objectRelativePath)
+}
+
+func (folder cachingRangeFolder) ReadObjectRange(objectRelativePath string, offset int64,
+	version string) (io.ReadCloser, error) {
+	return folder.rangeReader.ReadObjectRange(objectRelativePath, offset, version)
+}
+
+// SetCacheCounter registers counter to receive the listing cache hits and misses, nothing is counted if it is not set.
+// The counter is shared by the subfolders.
+func (folder *CachingFolder) SetCacheCounter(counter metrics.CacheCounter) {
+	folder.cache.mutex.Lock()
+	defer folder.cache.mutex.Unlock()
+	folder.cache.counter = counter
+}
+
+// HitRatio returns the share of the listings served from the cache, 0 if nothing was listed
+func (folder *CachingFolder) HitRatio() float64 {
+	folder.cache.mutex.Lock()
+	defer folder.cache.mutex.Unlock()
+	if folder.cache.hits+folder.cache.misses == 0 {
+		return 0
+	}
+	return float64(folder.cache.hits) / float64(folder.cache.hits+folder.cache.misses)
+}
+
+// Invalidate drops the cached listings of the folder and its subfolders,
+// so the next listing reflects the objects uploaded bypassing the wrapper
+func (folder *CachingFolder) Invalidate() {
+	folder.cache.invalidate(folder.GetPath())
+}
+
+func (folder *CachingFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	path := folder.GetPath()
+	if entry, ok := folder.cache.load(path); ok {
+		return entry.objects, entry.subFolders, nil
+	}
+
+	generation := folder.cache.currentGeneration()
+	objects, subFolders, err := folder.Folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, subFolder := range subFolders {
+		subFolders[i] = newCachingFolder(subFolder, folder.cache)
+	}
+	entry := folder.cache.store(path, generation, objects, subFolders)
+	return entry.objects, entry.subFolders, nil
+}
+
+func (folder *CachingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return newCachingFolder(folder.Folder.GetSubFolder(subFolderRelativePath), folder.cache)
+}
+
+func (folder *CachingFolder) PutObject(name string, content io.Reader) error {
+	defer folder.cache.invalidateParents(folder.objectPath(name))
+	return folder.Folder.PutObject(name, content)
+}
+
+func (folder *CachingFolder) DeleteObjects(objectRelativePaths []string) error {
+	defer func() {
+		for _, objectRelativePath := range objectRelativePaths {
+			folder.cache.invalidateParents(folder.objectPath(objectRelativePath))
+		}
+	}()
+	return folder.Folder.DeleteObjects(objectRelativePaths)
+}
+
+func (folder *CachingFolder) CopyObject(srcPath string, dstPath string) error {
+	defer folder.cache.invalidateParents(folder.objectPath(dstPath))
+	return folder.Folder.CopyObject(srcPath, dstPath)
+}
+
+// objectPath prefixes the relative path by the folder path the same way the listings are keyed
+func (folder *CachingFolder) objectPath(objectRelativePath string) string {
+	return folder.GetPath() + strings.TrimPrefix(objectRelativePath, "/")
+}
+
+// load returns the copy of the unexpired listing, counting the hit or the miss
+func (cache *folderListCache) load(path string) (folderListEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	entry, ok := cache.entries[path]
+	if !ok || !cache.now().Before(entry.expiresAt) {
+		cache.misses++
+		if cache.counter != nil {
+			cache.counter.IncCacheMisses(metrics.FolderListOperation)
+		}
+		return folderListEntry{}, false
+	}
+	cache.hits++
+	if cache.counter != nil {
+		cache.counter.IncCacheHits(metrics.FolderListOperation)
+	}
+	return entry.copy(), true
+}
+
+func (cache *folderListCache) currentGeneration() uint64 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.generation
+}
+
+// store caches the listing unless the cache was invalidated since the listing started,
+// the copy of the listing is returned, so the callers can not change the cached one
+func (cache *folderListCache) store(path string, generation uint64,
+	objects []storage.Object, subFolders []storage.Folder) folderListEntry {
+	entry := folderListEntry{objects: objects, subFolders: subFolders, expiresAt: cache.now().Add(cache.ttl)}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if generation == cache.generation {
+		cache.entries[path] = entry
+	}
+	return entry.copy()
+}
+
+// invalidate drops the listings of the folder at the path and its subfolders
+func (cache *folderListCache) invalidate(path string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.generation++
+	for entryPath := range cache.entries {
+		if strings.HasPrefix(entryPath, path) {
+			delete(cache.entries, entryPath)
+		}
+	}
+}
+
+// invalidateParents drops the listings of all the folders containing the object at the path,
+// since the object or its subfolder appears in each of them
+func (cache *folderListCache) invalidateParents(objectPath string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.generation++
+	for entryPath := range cache.entries {
+		if strings.HasPrefix(objectPath, entryPath) {
+			delete(cache.entries, entryPath)
+		}
+	}
+}
+
+func (entry folderListEntry) copy() folderListEntry {
+	return folderListEntry{objects: append([]storage.Object(nil), entry.objects...),
+		subFolders: append([]storage.Folder(nil), entry.subFolders...), expiresAt: entry.expiresAt}
+}
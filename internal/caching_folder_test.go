@@ -0,0 +1,128 @@
+package internal_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// listCountingFolder counts the listings reaching the storage
+type listCountingFolder struct {
+	storage.Folder
+	mutex    *sync.Mutex
+	listings *int
+}
+
+func (folder listCountingFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	folder.mutex.Lock()
+	*folder.listings++
+	folder.mutex.Unlock()
+	return folder.Folder.ListFolder()
+}
+
+func (folder listCountingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return listCountingFolder{folder.Folder.GetSubFolder(subFolderRelativePath), folder.mutex, folder.listings}
+}
+
+type testCacheCounter struct {
+	hits, misses int
+}
+
+func (counter *testCacheCounter) IncCacheHits(operation metrics.Operation) {
+	counter.hits++
+}
+
+func (counter *testCacheCounter) IncCacheMisses(operation metrics.Operation) {
+	counter.misses++
+}
+
+func listedNames(t *testing.T, folder storage.Folder) []string {
+	objects, _, err := folder.ListFolder()
+	assert.NoError(t, err)
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	return names
+}
+
+func TestCachingFolder_InvalidatesOnWrites(t *testing.T) {
+	listings := 0
+	baseFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := internal.NewCachingFolder(listCountingFolder{baseFolder, &sync.Mutex{}, &listings}, time.Hour)
+	counter := &testCacheCounter{}
+	folder.(internal.ListCache).SetCacheCounter(counter)
+	oplogFolder := folder.GetSubFolder("oplog")
+
+	assert.NoError(t, oplogFolder.PutObject("first", bytes.NewBufferString("first")))
+	assert.Equal(t, []string{"first"}, listedNames(t, oplogFolder))
+	assert.Equal(t, []string{"first"}, listedNames(t, oplogFolder))
+	assert.Equal(t, 1, listings)
+
+	// the write through the wrapper is listed at once
+	assert.NoError(t, oplogFolder.PutObject("second", bytes.NewBufferString("second")))
+	assert.ElementsMatch(t, []string{"first", "second"}, listedNames(t, oplogFolder))
+	assert.Equal(t, 2, listings)
+	assert.NoError(t, oplogFolder.DeleteObjects([]string{"first"}))
+	assert.Equal(t, []string{"second"}, listedNames(t, oplogFolder))
+	assert.Equal(t, 3, listings)
+
+	// the write bypassing the wrapper is listed once the cache is invalidated
+	assert.NoError(t, baseFolder.GetSubFolder("oplog").PutObject("third", bytes.NewBufferString("third")))
+	assert.Equal(t, []string{"second"}, listedNames(t, oplogFolder))
+	folder.(internal.ListCache).Invalidate()
+	assert.ElementsMatch(t, []string{"second", "third"}, listedNames(t, oplogFolder))
+	assert.Equal(t, 4, listings)
+
+	assert.Equal(t, 2, counter.hits)
+	assert.Equal(t, 4, counter.misses)
+	assert.InDelta(t, 2.0/6, folder.(internal.ListCache).HitRatio(), 1e-9)
+}
+
+func TestCachingFolder_PassesRangeReads(t *testing.T) {
+	baseFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	_, ok := internal.NewCachingFolder(baseFolder, time.Hour).GetSubFolder("oplog").(storage.RangeReader)
+	assert.True(t, ok)
+
+	listings := 0
+	_, ok = internal.NewCachingFolder(listCountingFolder{baseFolder, &sync.Mutex{}, &listings}, time.Hour).(storage.RangeReader)
+	assert.False(t, ok)
+}
+
+func TestCachingFolder_ExpiresByTTL(t *testing.T) {
+	listings := 0
+	baseFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := internal.NewCachingFolder(listCountingFolder{baseFolder, &sync.Mutex{}, &listings}, time.Nanosecond)
+
+	assert.NoError(t, baseFolder.PutObject("object", bytes.NewBufferString("object")))
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond)
+		assert.Equal(t, []string{"object"}, listedNames(t, folder))
+	}
+	assert.Equal(t, 3, listings)
+	assert.Zero(t, folder.(internal.ListCache).HitRatio())
+}
+
+func TestCachingFolder_ConcurrentListings(t *testing.T) {
+	baseFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	folder := internal.NewCachingFolder(baseFolder, time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, folder.PutObject(string(rune('a'+i)), bytes.NewBufferString("object")))
+			_, _, err := folder.ListFolder()
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, listedNames(t, folder), 8)
+}
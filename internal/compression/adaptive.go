@@ -0,0 +1,208 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+)
+
+const (
+	AdaptiveAlgorithmName = "adaptive"
+	AdaptiveFileExtension = "adz"
+
+	DefaultAdaptiveSampleSize = 64 << 10
+
+	// the samples with the higher entropy in bits per byte are considered already compressed
+	incompressibleEntropy = 7.5
+	// the stream is stored if the best candidate does not shrink the sample at least by this fraction
+	minAdaptiveSaving = 0.05
+	// a later candidate is chosen only if it saves at least this fraction of the sample more than the earlier one
+	minAdaptiveCandidateGain = 0.1
+)
+
+// DefaultAdaptiveCandidates are ordered from the fastest algorithm to the slowest one
+var DefaultAdaptiveCandidates = []string{lz4.AlgorithmName, lzma.AlgorithmName}
+
+// adaptiveMagic starts the adaptive stream, it is followed by the length and the extension of the chosen algorithm,
+// the empty extension means the stream is stored uncompressed. Then the stream of the chosen algorithm goes.
+var adaptiveMagic = []byte{'W', 'G', 'A', 'D', 1}
+
+func init() {
+	Compressors[AdaptiveAlgorithmName] = NewAdaptiveCompressor(DefaultAdaptiveCandidates)
+	CompressingAlgorithms = append(CompressingAlgorithms, AdaptiveAlgorithmName)
+	Decompressors = append(Decompressors, AdaptiveDecompressor{})
+}
+
+// AdaptiveCompressor picks the algorithm for each stream by its first SampleSize bytes:
+// the high entropy samples are stored as is, otherwise the sample is trial-compressed by the Candidates.
+// The Candidates are expected to be ordered from the fastest to the slowest one,
+// a slower candidate is chosen only if it compresses the sample noticeably better.
+type AdaptiveCompressor struct {
+	Candidates []Compressor
+	SampleSize int
+}
+
+// NewAdaptiveCompressor uses the candidates present in Compressors, the missing ones are skipped
+func NewAdaptiveCompressor(candidates []string) AdaptiveCompressor {
+	compressors := make([]Compressor, 0, len(candidates))
+	for _, candidate := range candidates {
+		if compressor, ok := Compressors[candidate]; ok {
+			compressors = append(compressors, compressor)
+		}
+	}
+	return AdaptiveCompressor{Candidates: compressors, SampleSize: DefaultAdaptiveSampleSize}
+}
+
+func (compressor AdaptiveCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	sampleSize := compressor.SampleSize
+	if sampleSize < 1 {
+		sampleSize = DefaultAdaptiveSampleSize
+	}
+	return &adaptiveWriter{candidates: compressor.Candidates, output: writer, sample: make([]byte, 0, sampleSize)}
+}
+
+func (compressor AdaptiveCompressor) FileExtension() string {
+	return AdaptiveFileExtension
+}
+
+type adaptiveWriter struct {
+	candidates []Compressor
+	output     io.Writer
+	sample     []byte
+	// writer is the chosen algorithm writer, it is set once the sample is collected
+	writer io.WriteCloser
+}
+
+func (writer *adaptiveWriter) Write(p []byte) (int, error) {
+	if writer.writer != nil {
+		return writer.writer.Write(p)
+	}
+	n := copy(writer.sample[len(writer.sample):cap(writer.sample)], p)
+	writer.sample = writer.sample[:len(writer.sample)+n]
+	if len(writer.sample) < cap(writer.sample) {
+		return n, nil
+	}
+	if err := writer.startStream(); err != nil {
+		return n, err
+	}
+	written, err := writer.writer.Write(p[n:])
+	return n + written, err
+}
+
+// startStream chooses the algorithm by the sample, writes the header and the sample to the chosen algorithm writer
+func (writer *adaptiveWriter) startStream() error {
+	chosen := chooseAdaptiveCompressor(writer.candidates, writer.sample)
+	extension := ""
+	if chosen != nil {
+		extension = chosen.FileExtension()
+	}
+	header := append(append(append([]byte{}, adaptiveMagic...), byte(len(extension))), extension...)
+	if _, err := writer.output.Write(header); err != nil {
+		return err
+	}
+	if chosen != nil {
+		writer.writer = chosen.NewWriter(writer.output)
+	} else {
+		writer.writer = nopWriteCloser{writer.output}
+	}
+	_, err := writer.writer.Write(writer.sample)
+	writer.sample = nil
+	return err
+}
+
+func (writer *adaptiveWriter) Close() error {
+	if writer.writer == nil {
+		if err := writer.startStream(); err != nil {
+			return err
+		}
+	}
+	return writer.writer.Close()
+}
+
+// chooseAdaptiveCompressor returns nil if the sample should be stored uncompressed
+func chooseAdaptiveCompressor(candidates []Compressor, sample []byte) Compressor {
+	if len(sample) == 0 || len(candidates) == 0 {
+		return nil
+	}
+	if entropy := byteEntropy(sample); entropy >= incompressibleEntropy {
+		tracelog.DebugLogger.Printf("Adaptive compression: sample entropy is %.2f bits per byte, storing as is", entropy)
+		return nil
+	}
+
+	var chosen Compressor
+	chosenSize := float64(len(sample)) * (1 - minAdaptiveSaving)
+	for _, candidate := range candidates {
+		var compressed bytes.Buffer
+		candidateWriter := candidate.NewWriter(&compressed)
+		// writes to the buffer do not fail, so do the writes of the compressors to it
+		_, _ = candidateWriter.Write(sample)
+		_ = candidateWriter.Close()
+		size := float64(compressed.Len())
+		if chosen != nil && chosenSize-size < float64(len(sample))*minAdaptiveCandidateGain {
+			continue
+		}
+		if size < chosenSize {
+			chosen, chosenSize = candidate, size
+		}
+	}
+	if chosen != nil {
+		tracelog.DebugLogger.Printf("Adaptive compression: using '%s' compression method", chosen.FileExtension())
+	}
+	return chosen
+}
+
+// byteEntropy is the Shannon entropy of the data in bits per byte
+func byteEntropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// AdaptiveDecompressor decompresses the streams of AdaptiveCompressor by the algorithm stored in the header
+type AdaptiveDecompressor struct{}
+
+func (decompressor AdaptiveDecompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	header := make([]byte, len(adaptiveMagic)+1)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("failed to read the adaptive compression header: %w", err)
+	}
+	if !bytes.Equal(header[:len(adaptiveMagic)], adaptiveMagic) {
+		return nil, fmt.Errorf("unexpected adaptive compression header %x", header[:len(adaptiveMagic)])
+	}
+	extension := make([]byte, header[len(adaptiveMagic)])
+	if _, err := io.ReadFull(src, extension); err != nil {
+		return nil, fmt.Errorf("failed to read the adaptive compression header: %w", err)
+	}
+	if len(extension) == 0 {
+		return io.NopCloser(src), nil
+	}
+	chosen := FindDecompressor(string(extension))
+	if chosen == nil || chosen.FileExtension() == AdaptiveFileExtension {
+		return nil, fmt.Errorf("unsupported compression method of the adaptive stream: '%s'", extension)
+	}
+	return chosen.Decompress(src)
+}
+
+func (decompressor AdaptiveDecompressor) FileExtension() string {
+	return AdaptiveFileExtension
+}
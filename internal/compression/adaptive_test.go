@@ -0,0 +1,80 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+)
+
+func adaptiveRoundTrip(t *testing.T, compressor AdaptiveCompressor, data []byte) []byte {
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	// uneven writes cross the sample boundary
+	for offset := 0; offset < len(data); offset += 777 {
+		end := offset + 777
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := writer.Write(data[offset:end])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	reader, err := DecompressDetected(bytes.NewReader(compressed.Bytes()))
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+	return compressed.Bytes()
+}
+
+func adaptiveChosenExtension(compressed []byte) string {
+	length := int(compressed[len(adaptiveMagic)])
+	return string(compressed[len(adaptiveMagic)+1 : len(adaptiveMagic)+1+length])
+}
+
+func TestAdaptiveCompressor_RoundTrip(t *testing.T) {
+	compressor := AdaptiveCompressor{Candidates: []Compressor{lz4.Compressor{}, lzma.Compressor{}}, SampleSize: 4096}
+	random := make([]byte, 10*1000+123)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 300)
+
+	compressed := adaptiveRoundTrip(t, compressor, random)
+	assert.Equal(t, "", adaptiveChosenExtension(compressed))
+
+	compressed = adaptiveRoundTrip(t, compressor, text)
+	assert.Less(t, len(compressed), len(text)/2)
+	assert.NotEqual(t, "", adaptiveChosenExtension(compressed))
+
+	// the stream shorter than the sample is chosen on close
+	compressed = adaptiveRoundTrip(t, compressor, text[:100])
+	assert.NotEqual(t, "", adaptiveChosenExtension(compressed))
+	adaptiveRoundTrip(t, compressor, []byte{})
+}
+
+func TestAdaptiveCompressor_PrefersFasterCandidate(t *testing.T) {
+	text := bytes.Repeat([]byte("0123456789"), 1000)
+	compressed := adaptiveRoundTrip(t, AdaptiveCompressor{Candidates: []Compressor{lz4.Compressor{}, lzma.Compressor{}}}, text)
+	assert.Equal(t, lz4.FileExtension, adaptiveChosenExtension(compressed))
+
+	compressed = adaptiveRoundTrip(t, AdaptiveCompressor{Candidates: []Compressor{lzma.Compressor{}}}, text)
+	assert.Equal(t, lzma.FileExtension, adaptiveChosenExtension(compressed))
+}
+
+func TestAdaptiveCompressor_Registered(t *testing.T) {
+	compressor, ok := Compressors[AdaptiveAlgorithmName]
+	assert.True(t, ok)
+	assert.Equal(t, AdaptiveFileExtension, compressor.FileExtension())
+	assert.IsType(t, AdaptiveDecompressor{}, GetDecompressorByCompressor(compressor))
+}
+
+func TestAdaptiveDecompressor_UnknownMethod(t *testing.T) {
+	stream := append(append(append([]byte{}, adaptiveMagic...), 3), "foo"...)
+	_, err := AdaptiveDecompressor{}.Decompress(bytes.NewReader(stream))
+	assert.Error(t, err)
+}
@@ -7,17 +7,30 @@ import (
 	"io"
 
 	"github.com/google/brotli/go/cbrotli"
+	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
 const (
 	AlgorithmName = "brotli"
 	FileExtension = "br"
+
+	DefaultQuality = 3
+	MinQuality     = 0
+	MaxQuality     = 11
 )
 
 type Compressor struct{}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: 3})
+	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: DefaultQuality})
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: level})
+}
+
+func (compressor Compressor) ValidateLevel(level int) error {
+	return computils.ValidateCompressionLevel(AlgorithmName, level, MinQuality, MaxQuality)
 }
 
 func (compressor Compressor) FileExtension() string {
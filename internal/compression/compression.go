@@ -1,7 +1,10 @@
 package compression
 
 import (
+	"fmt"
 	"io"
+	"reflect"
+	"sort"
 )
 
 type Compressor interface {
@@ -9,11 +12,74 @@ type Compressor interface {
 	FileExtension() string
 }
 
+// LeveledCompressor is the Compressor which allows to trade CPU for the compression ratio
+type LeveledCompressor interface {
+	Compressor
+	NewWriterLevel(writer io.Writer, level int) io.WriteCloser
+	// ValidateLevel fails if the level is outside of the range allowed by the algorithm
+	ValidateLevel(level int) error
+}
+
 type Decompressor interface {
 	Decompress(src io.Reader) (io.ReadCloser, error)
 	FileExtension() string
 }
 
+// WithLevel returns the Compressor which always uses the provided compression level
+func WithLevel(compressor Compressor, level int) (Compressor, error) {
+	leveledCompressor, ok := compressor.(LeveledCompressor)
+	if !ok {
+		return nil, fmt.Errorf("compression method with '%s' extension does not support compression levels",
+			compressor.FileExtension())
+	}
+	if err := leveledCompressor.ValidateLevel(level); err != nil {
+		return nil, err
+	}
+	return fixedLevelCompressor{leveledCompressor, level}, nil
+}
+
+type fixedLevelCompressor struct {
+	LeveledCompressor
+	level int
+}
+
+func (compressor fixedLevelCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	return compressor.NewWriterLevel(writer, compressor.level)
+}
+
+// Describe returns the name the compressor is registered by in Compressors and the level it compresses with,
+// the level is nil if the default one is used. The FallbackCompressor, the fixed level and the base
+// of the ParallelCompressor are looked through. The algorithm is empty if the compressor is not registered.
+func Describe(compressor Compressor) (algorithm string, level *int) {
+	switch typed := compressor.(type) {
+	case FallbackCompressor:
+		_, level = Describe(typed.Compressor)
+		return typed.Algorithm, level
+	case fixedLevelCompressor:
+		algorithm, _ = Describe(typed.LeveledCompressor)
+		fixedLevel := typed.level
+		return algorithm, &fixedLevel
+	case ParallelCompressor:
+		algorithm, level = Describe(typed.Base)
+		if algorithm == "" {
+			return "", level
+		}
+		return ParallelAlgorithmPrefix + algorithm, level
+	}
+	// the compressors are compared by type, since some of them are not comparable
+	names := make([]string, 0, len(Compressors))
+	for name := range Compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if reflect.TypeOf(Compressors[name]) == reflect.TypeOf(compressor) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
 func GetDecompressorByCompressor(compressor Compressor) Decompressor {
 	return FindDecompressor(compressor.FileExtension())
 }
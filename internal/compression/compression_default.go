@@ -7,19 +7,23 @@ import (
 	"github.com/wal-g/wal-g/internal/compression/gzip"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
 	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/compression/none"
 	"github.com/wal-g/wal-g/internal/compression/zstd"
 )
 
-var CompressingAlgorithms = []string{lz4.AlgorithmName, lzma.AlgorithmName}
+var CompressingAlgorithms = []string{lz4.AlgorithmName, lzma.AlgorithmName, none.AlgorithmName, gzip.AlgorithmName}
 
 var Compressors = map[string]Compressor{
 	lz4.AlgorithmName:  lz4.Compressor{},
 	lzma.AlgorithmName: lzma.Compressor{},
+	none.AlgorithmName: none.Compressor{},
+	gzip.AlgorithmName: gzip.Compressor{},
 }
 
 var Decompressors = []Decompressor{
 	lz4.Decompressor{},
 	lzma.Decompressor{},
+	none.Decompressor{},
 	zstd.Decompressor{},
 	gzip.Decompressor{},
 }
@@ -7,6 +7,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/compression/none"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -62,3 +67,127 @@ func TestBigDataCompression(t *testing.T) {
 		testCompressor(compressor, testData, t)
 	}
 }
+
+func TestLeveledCompression(t *testing.T) {
+	const DataSize = 1 << 20
+	randomReader := io.LimitReader(NewBiasedRandomReader(), DataSize)
+	var testData bytes.Buffer
+	io.Copy(&testData, randomReader)
+	levels := map[Compressor][]int{
+		lz4.Compressor{}:  {lz4.MinLevel, 5, lz4.MaxLevel},
+		zstd.Compressor{}: {zstd.MinLevel, 10, zstd.MaxLevel},
+		gzip.Compressor{}: {gzip.MinLevel, 6, gzip.MaxLevel},
+	}
+	for compressor, compressorLevels := range levels {
+		for _, level := range compressorLevels {
+			leveledCompressor, err := WithLevel(compressor, level)
+			assert.NoError(t, err)
+			testCompressor(leveledCompressor, testData, t)
+		}
+		_, err := WithLevel(compressor, -1)
+		assert.Error(t, err)
+	}
+}
+
+func TestLeveledCompression_NotSupported(t *testing.T) {
+	_, err := WithLevel(lzma.Compressor{}, 1)
+	assert.Error(t, err)
+}
+
+func TestDecompressDetected(t *testing.T) {
+	for _, compressingAlgorithm := range CompressingAlgorithms {
+		if compressingAlgorithm == none.AlgorithmName {
+			// the stored data has no signature
+			continue
+		}
+		var compressed bytes.Buffer
+		compressingWriter := Compressors[compressingAlgorithm].NewWriter(&compressed)
+		_, err := io.WriteString(compressingWriter, "detected by the leading bytes")
+		assert.NoError(t, err)
+		assert.NoError(t, compressingWriter.Close())
+
+		decompressedReader, err := DecompressDetected(&compressed)
+		assert.NoError(t, err)
+		decompressed, err := io.ReadAll(decompressedReader)
+		assert.NoError(t, err)
+		assert.Equal(t, "detected by the leading bytes", string(decompressed))
+	}
+}
+
+func TestDetectDecompressor_Lz4(t *testing.T) {
+	var compressed bytes.Buffer
+	compressingWriter := lz4.Compressor{}.NewWriter(&compressed)
+	_, err := io.WriteString(compressingWriter, "lz4 frame")
+	assert.NoError(t, err)
+	assert.NoError(t, compressingWriter.Close())
+
+	decompressor, reader, err := DetectDecompressor(&compressed)
+	assert.NoError(t, err)
+	assert.IsType(t, lz4.Decompressor{}, decompressor)
+	decompressedReader, err := decompressor.Decompress(reader)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(decompressedReader)
+	assert.NoError(t, err)
+	assert.Equal(t, "lz4 frame", string(decompressed))
+}
+
+func TestDetectDecompressor_Gzip(t *testing.T) {
+	var compressed bytes.Buffer
+	compressingWriter := Compressors[gzip.AlgorithmName].NewWriter(&compressed)
+	_, err := io.WriteString(compressingWriter, "gzip member")
+	assert.NoError(t, err)
+	assert.NoError(t, compressingWriter.Close())
+
+	decompressor, reader, err := DetectDecompressor(&compressed)
+	assert.NoError(t, err)
+	assert.IsType(t, gzip.Decompressor{}, decompressor)
+	decompressedReader, err := decompressor.Decompress(reader)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(decompressedReader)
+	assert.NoError(t, err)
+	assert.Equal(t, "gzip member", string(decompressed))
+}
+
+func TestDetectDecompressor_UnknownFormat(t *testing.T) {
+	_, reader, err := DetectDecompressor(bytes.NewBufferString("plain text"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "lz4")
+
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain text", string(content))
+}
+
+func TestNoneCompressor_StoresAsIs(t *testing.T) {
+	var stored bytes.Buffer
+	writer := Compressors[none.AlgorithmName].NewWriter(&stored)
+	_, err := io.WriteString(writer, "stored as is")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, "stored as is", stored.String())
+
+	assert.Equal(t, none.Decompressor{}, FindDecompressor("."+none.FileExtension))
+	assert.NotContains(t, Compressors, ParallelAlgorithmPrefix+none.AlgorithmName)
+}
+
+func TestDescribe(t *testing.T) {
+	algorithm, level := Describe(Compressors[lzma.AlgorithmName])
+	assert.Equal(t, lzma.AlgorithmName, algorithm)
+	assert.Nil(t, level)
+
+	leveled, err := WithLevel(Compressors[gzip.AlgorithmName], 3)
+	assert.NoError(t, err)
+	algorithm, level = Describe(leveled)
+	assert.Equal(t, gzip.AlgorithmName, algorithm)
+	assert.Equal(t, 3, *level)
+
+	algorithm, level = Describe(ParallelCompressor{Base: leveled})
+	assert.Equal(t, ParallelAlgorithmPrefix+gzip.AlgorithmName, algorithm)
+	assert.Equal(t, 3, *level)
+
+	algorithm, _ = Describe(FallbackCompressor{Compressor: Compressors[lz4.AlgorithmName], Algorithm: lz4.AlgorithmName})
+	assert.Equal(t, lz4.AlgorithmName, algorithm)
+
+	algorithm, _ = Describe(Compressors[AdaptiveAlgorithmName])
+	assert.Equal(t, AdaptiveAlgorithmName, algorithm)
+}
@@ -4,18 +4,34 @@
 package compression
 
 import (
+	"errors"
+
+	"github.com/wal-g/wal-g/internal/compression/gzip"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
 	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/compression/none"
 )
 
-var CompressingAlgorithms = []string{lz4.AlgorithmName, lzma.AlgorithmName}
+var CompressingAlgorithms = []string{lz4.AlgorithmName, lzma.AlgorithmName, none.AlgorithmName, gzip.AlgorithmName}
 
 var Compressors = map[string]Compressor{
 	lz4.AlgorithmName:  lz4.Compressor{},
 	lzma.AlgorithmName: lzma.Compressor{},
+	none.AlgorithmName: none.Compressor{},
+	gzip.AlgorithmName: gzip.Compressor{},
 }
 
 var Decompressors = []Decompressor{
 	lz4.Decompressor{},
 	lzma.Decompressor{},
+	none.Decompressor{},
+	gzip.Decompressor{},
+}
+
+func RegisterZstdDictionary(path string) error {
+	return errors.New("zstd dictionaries are not supported on windows")
+}
+
+func RegisterZstdLong(windowLog int) error {
+	return errors.New("zstd long-distance matching is not supported on windows")
 }
@@ -0,0 +1,12 @@
+package computils
+
+import "fmt"
+
+// ValidateCompressionLevel checks that the level is within the range allowed by the algorithm
+func ValidateCompressionLevel(algorithmName string, level, minLevel, maxLevel int) error {
+	if level < minLevel || level > maxLevel {
+		return fmt.Errorf("%s compression level must be in range [%d, %d], got %d",
+			algorithmName, minLevel, maxLevel, level)
+	}
+	return nil
+}
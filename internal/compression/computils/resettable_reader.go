@@ -0,0 +1,10 @@
+package computils
+
+import "io"
+
+// ResettableReader is the decompressing reader which can be reused to decode the other stream
+type ResettableReader interface {
+	io.Reader
+	// Reset discards the state of the previous stream and starts decoding the src
+	Reset(src io.Reader) error
+}
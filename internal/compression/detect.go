@@ -0,0 +1,69 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the longest signature is the lzma header: properties, dictionary size and unknown uncompressed size
+const maxMagicBytesSize = 13
+
+// magicMatchers recognize the formats by the leading bytes, keyed by the decompressor file extension.
+// The formats without a reliable signature (e.g. brotli) can not be detected.
+var magicMatchers = map[string]func(header []byte) bool{
+	"lz4":  hasMagicPrefix(0x04, 0x22, 0x4D, 0x18),
+	"zst":  hasMagicPrefix(0x28, 0xB5, 0x2F, 0xFD),
+	"gz":   hasMagicPrefix(0x1F, 0x8B),
+	"lzo":  hasMagicPrefix(0x89, 'L', 'Z', 'O', 0x00, 0x0D, 0x0A, 0x1A, 0x0A),
+	"lzma": isLzmaHeader,
+	"pz":   hasMagicPrefix(parallelMagic...),
+	"adz":  hasMagicPrefix(adaptiveMagic...),
+}
+
+func hasMagicPrefix(magic ...byte) func(header []byte) bool {
+	return func(header []byte) bool {
+		return bytes.HasPrefix(header, magic)
+	}
+}
+
+// isLzmaHeader matches the headers written by the lzma compressor: default properties and unknown size
+func isLzmaHeader(header []byte) bool {
+	return len(header) >= maxMagicBytesSize && header[0] == 0x5D &&
+		bytes.Equal(header[5:maxMagicBytesSize], bytes.Repeat([]byte{0xFF}, 8))
+}
+
+// DetectDecompressor peeks the leading bytes of the src and picks the registered Decompressor by them.
+// The returned reader must be used instead of the src even if the detection fails, since it holds the peeked bytes.
+func DetectDecompressor(src io.Reader) (Decompressor, io.Reader, error) {
+	bufferedSrc := bufio.NewReader(src)
+	header, err := bufferedSrc.Peek(maxMagicBytesSize)
+	if err != nil && err != io.EOF {
+		return nil, bufferedSrc, err
+	}
+
+	triedAlgorithms := make([]string, 0, len(Decompressors))
+	for _, decompressor := range Decompressors {
+		matches, ok := magicMatchers[decompressor.FileExtension()]
+		if !ok {
+			continue
+		}
+		if matches(header) {
+			return decompressor, bufferedSrc, nil
+		}
+		triedAlgorithms = append(triedAlgorithms, decompressor.FileExtension())
+	}
+	return nil, bufferedSrc, fmt.Errorf("failed to detect the compression format by the leading bytes, tried: %s",
+		strings.Join(triedAlgorithms, ", "))
+}
+
+// DecompressDetected decompresses the src using the Decompressor detected by DetectDecompressor
+func DecompressDetected(src io.Reader) (io.ReadCloser, error) {
+	decompressor, bufferedSrc, err := DetectDecompressor(src)
+	if err != nil {
+		return nil, err
+	}
+	return decompressor.Decompress(bufferedSrc)
+}
@@ -0,0 +1,85 @@
+package compression
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/compression/none"
+)
+
+// DefaultEstimateSampleSize caps the sample read by EstimateRatios to keep the estimation fast
+const DefaultEstimateSampleSize = 16 << 20
+
+// RatioEstimate is the result of compressing the sample with one algorithm
+type RatioEstimate struct {
+	// Ratio is the sample size divided by the compressed size
+	Ratio float64
+	// ThroughputMBps is the number of the sample megabytes (1 << 20 bytes) compressed per second
+	ThroughputMBps float64
+}
+
+// EstimateRatios compresses at most maxSampleBytes of the sample with each of the algorithms registered in Compressors,
+// DefaultEstimateSampleSize bytes are read if maxSampleBytes is not positive. The "none" baseline is always estimated.
+// It helps to pick the compression method for the data without compressing all of it.
+func EstimateRatios(sample io.Reader, algorithms []string, maxSampleBytes int64) (map[string]RatioEstimate, error) {
+	if maxSampleBytes <= 0 {
+		maxSampleBytes = DefaultEstimateSampleSize
+	}
+	data, err := io.ReadAll(io.LimitReader(sample, maxSampleBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the compression sample: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("compression sample is empty")
+	}
+
+	estimates := make(map[string]RatioEstimate, len(algorithms)+1)
+	for _, algorithm := range append([]string{none.AlgorithmName}, algorithms...) {
+		if _, ok := estimates[algorithm]; ok {
+			continue
+		}
+		compressor, ok := Compressors[algorithm]
+		if !ok {
+			return nil, fmt.Errorf("unknown compression method '%s', supported are: %v", algorithm, CompressingAlgorithms)
+		}
+		estimate, err := estimateRatio(compressor, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress the sample with '%s': %w", algorithm, err)
+		}
+		estimates[algorithm] = estimate
+	}
+	return estimates, nil
+}
+
+func estimateRatio(compressor Compressor, data []byte) (RatioEstimate, error) {
+	output := &countingWriter{}
+	start := time.Now()
+	writer := compressor.NewWriter(output)
+	if _, err := writer.Write(data); err != nil {
+		return RatioEstimate{}, err
+	}
+	if err := writer.Close(); err != nil {
+		return RatioEstimate{}, err
+	}
+	elapsed := time.Since(start)
+	if elapsed <= 0 {
+		elapsed = time.Nanosecond
+	}
+
+	estimate := RatioEstimate{ThroughputMBps: float64(len(data)) / (1 << 20) / elapsed.Seconds()}
+	if output.written > 0 {
+		estimate.Ratio = float64(len(data)) / float64(output.written)
+	}
+	return estimate, nil
+}
+
+// countingWriter discards the compressed output, only its size matters
+type countingWriter struct {
+	written int64
+}
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	writer.written += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,55 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/none"
+)
+
+func TestEstimateRatios(t *testing.T) {
+	sample := bytes.Repeat([]byte("compressible sample "), 10000)
+
+	estimates, err := EstimateRatios(bytes.NewReader(sample), []string{lz4.AlgorithmName}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, estimates, 2)
+	assert.Equal(t, 1.0, estimates[none.AlgorithmName].Ratio)
+	assert.Greater(t, estimates[lz4.AlgorithmName].Ratio, 10.0)
+	for _, estimate := range estimates {
+		assert.Positive(t, estimate.ThroughputMBps)
+	}
+}
+
+func TestEstimateRatios_CapsSample(t *testing.T) {
+	sample := &countingReader{reader: rand.New(rand.NewSource(1))}
+
+	estimates, err := EstimateRatios(sample, []string{lz4.AlgorithmName, none.AlgorithmName}, 1<<16)
+	assert.NoError(t, err)
+	assert.Len(t, estimates, 2)
+	assert.Equal(t, int64(1<<16), sample.read)
+	// the random data does not shrink
+	assert.Less(t, estimates[lz4.AlgorithmName].Ratio, 1.01)
+}
+
+func TestEstimateRatios_Errors(t *testing.T) {
+	_, err := EstimateRatios(bytes.NewReader([]byte("sample")), []string{"unknown"}, 0)
+	assert.Error(t, err)
+
+	_, err = EstimateRatios(bytes.NewReader(nil), []string{lz4.AlgorithmName}, 0)
+	assert.Error(t, err)
+}
+
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.read += int64(n)
+	return n, err
+}
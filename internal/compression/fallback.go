@@ -0,0 +1,42 @@
+package compression
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+)
+
+// FallbackCompressor is the first available Compressor from the prioritized list of algorithms.
+// It allows the same configuration for the binaries built with the different sets of algorithms (e.g. without brotli).
+type FallbackCompressor struct {
+	Compressor
+	Algorithm string
+}
+
+// NewFallbackCompressor picks the first of algorithms present in Compressors,
+// the chosen compressor reports its own FileExtension, so the archives are decompressed with the right algorithm
+func NewFallbackCompressor(algorithms []string) (FallbackCompressor, error) {
+	for _, algorithm := range algorithms {
+		compressor, ok := Compressors[algorithm]
+		if !ok {
+			tracelog.WarningLogger.Printf("Compression method '%s' is not available in this build, trying the next one", algorithm)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Using '%s' compression method", algorithm)
+		return FallbackCompressor{compressor, algorithm}, nil
+	}
+	return FallbackCompressor{}, fmt.Errorf("none of compression methods %v is available, supported methods are: %v",
+		algorithms, CompressingAlgorithms)
+}
+
+// ParseAlgorithmList splits comma separated list of compression methods
+func ParseAlgorithmList(value string) []string {
+	algorithms := make([]string, 0)
+	for _, algorithm := range strings.Split(value, ",") {
+		if algorithm = strings.TrimSpace(algorithm); algorithm != "" {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
@@ -0,0 +1,28 @@
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+)
+
+func TestNewFallbackCompressor_PicksFirstAvailable(t *testing.T) {
+	compressor, err := NewFallbackCompressor([]string{"missing", lzma.AlgorithmName, lz4.AlgorithmName})
+	assert.NoError(t, err)
+	assert.Equal(t, lzma.AlgorithmName, compressor.Algorithm)
+	assert.Equal(t, lzma.FileExtension, compressor.FileExtension())
+	assert.Equal(t, lzma.Decompressor{}, GetDecompressorByCompressor(compressor))
+}
+
+func TestNewFallbackCompressor_NoneAvailable(t *testing.T) {
+	_, err := NewFallbackCompressor([]string{"missing", "absent"})
+	assert.Error(t, err)
+	_, err = NewFallbackCompressor(nil)
+	assert.Error(t, err)
+}
+
+func TestParseAlgorithmList(t *testing.T) {
+	assert.Equal(t, []string{"brotli", "lz4"}, ParseAlgorithmList(" brotli, lz4,,"))
+}
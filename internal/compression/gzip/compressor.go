@@ -3,14 +3,39 @@ package gzip
 import (
 	"compress/gzip"
 	"io"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression/computils"
+)
+
+const (
+	AlgorithmName = "gzip"
+
+	// MinLevel is the fastest compression, MaxLevel is the best compression ratio
+	MinLevel = gzip.BestSpeed
+	MaxLevel = gzip.BestCompression
 )
 
+// Compressor writes the standard gzip streams readable by the external tools such as gunzip
 type Compressor struct{}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
 	return gzip.NewWriter(writer)
 }
 
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	gzipWriter, err := gzip.NewWriterLevel(writer, level)
+	if err != nil {
+		tracelog.WarningLogger.Printf("failed to set gzip compression level %d, using the default one: %v", level, err)
+		return gzip.NewWriter(writer)
+	}
+	return gzipWriter
+}
+
+func (compressor Compressor) ValidateLevel(level int) error {
+	return computils.ValidateCompressionLevel(AlgorithmName, level, MinLevel, MaxLevel)
+}
+
 func (compressor Compressor) FileExtension() string {
 	return FileExtension
 }
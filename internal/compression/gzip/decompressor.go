@@ -3,6 +3,8 @@ package gzip
 import (
 	"compress/gzip"
 	"io"
+
+	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
 type Decompressor struct{}
@@ -16,3 +18,8 @@ func (decompressor Decompressor) Decompress(src io.Reader) (io.ReadCloser, error
 func (decompressor Decompressor) FileExtension() string {
 	return FileExtension
 }
+
+// NewResettableReader creates the reader which can be reset to decode the other stream
+func (decompressor Decompressor) NewResettableReader(src io.Reader) (computils.ResettableReader, error) {
+	return gzip.NewReader(src)
+}
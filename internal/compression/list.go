@@ -0,0 +1,94 @@
+package compression
+
+import (
+	"sort"
+	"strings"
+)
+
+// cgoFileExtensions are the extensions of the algorithms implemented by the C libraries,
+// such algorithms are missing in the cgo-free builds. The packages of brotli and zstd are not imported here,
+// since they are excluded from some builds.
+var cgoFileExtensions = map[string]bool{
+	"br":  true,
+	"zst": true,
+}
+
+// CompressorInfo describes the compression method available in this build
+type CompressorInfo struct {
+	Name          string `json:"name"`
+	FileExtension string `json:"file_extension"`
+	// SupportsLevels is true if WALG_COMPRESSION_LEVEL is applied to the method
+	SupportsLevels bool `json:"supports_levels"`
+	// HasDecompressor is true if the archives written by the method can be read by this build
+	HasDecompressor bool `json:"has_decompressor"`
+	RequiresCgo     bool `json:"requires_cgo"`
+}
+
+// ListCompressors describes the registered Compressors sorted by name
+func ListCompressors() []CompressorInfo {
+	names := make([]string, 0, len(Compressors))
+	for name := range Compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]CompressorInfo, 0, len(names))
+	for _, name := range names {
+		compressor := Compressors[name]
+		infos = append(infos, CompressorInfo{
+			Name:            name,
+			FileExtension:   compressor.FileExtension(),
+			SupportsLevels:  supportsLevels(compressor),
+			HasDecompressor: GetDecompressorByCompressor(compressor) != nil,
+			RequiresCgo:     requiresCgo(compressor),
+		})
+	}
+	return infos
+}
+
+// supportsLevels checks if the compression level is applied to the compressor, as ConfigureCompressor does it
+func supportsLevels(compressor Compressor) bool {
+	switch typedCompressor := compressor.(type) {
+	case ParallelCompressor:
+		return supportsLevels(typedCompressor.Base)
+	case AdaptiveCompressor:
+		return false
+	}
+	_, ok := compressor.(LeveledCompressor)
+	return ok
+}
+
+// requiresCgo checks if the compressor or any of the compressors it is built upon is implemented by the C library
+func requiresCgo(compressor Compressor) bool {
+	switch typedCompressor := compressor.(type) {
+	case ParallelCompressor:
+		return requiresCgo(typedCompressor.Base)
+	case AdaptiveCompressor:
+		for _, candidate := range typedCompressor.Candidates {
+			if requiresCgo(candidate) {
+				return true
+			}
+		}
+		return false
+	}
+	return cgoFileExtensions[compressor.FileExtension()]
+}
+
+// AlgorithmByExtension returns the name of the registered compressor writing the files with the extension,
+// so the algorithm of the stored file is known without reading it. The extension of the parallel compressors
+// does not tell their base algorithm, so "parallel" is returned for it. If several compressors write
+// the extension, e.g. the zstd with and without the dictionary, the first one by name is returned.
+// The name is empty if no compressor of this build writes the extension.
+func AlgorithmByExtension(extension string) string {
+	extension = strings.TrimPrefix(extension, ".")
+	if extension == ParallelFileExtension {
+		return strings.TrimSuffix(ParallelAlgorithmPrefix, "-")
+	}
+	algorithm := ""
+	for name, compressor := range Compressors {
+		if compressor.FileExtension() == extension && (algorithm == "" || name < algorithm) {
+			algorithm = name
+		}
+	}
+	return algorithm
+}
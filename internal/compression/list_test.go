@@ -0,0 +1,55 @@
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/compression/none"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+func TestListCompressors(t *testing.T) {
+	infos := ListCompressors()
+	assert.Len(t, infos, len(Compressors))
+	byName := make(map[string]CompressorInfo, len(infos))
+	for i, info := range infos {
+		if i > 0 {
+			assert.Less(t, infos[i-1].Name, info.Name)
+		}
+		byName[info.Name] = info
+	}
+
+	assert.Equal(t, CompressorInfo{Name: lz4.AlgorithmName, FileExtension: lz4.FileExtension,
+		SupportsLevels: true, HasDecompressor: true}, byName[lz4.AlgorithmName])
+	assert.Equal(t, CompressorInfo{Name: lzma.AlgorithmName, FileExtension: lzma.FileExtension,
+		HasDecompressor: true}, byName[lzma.AlgorithmName])
+	assert.Equal(t, CompressorInfo{Name: gzip.AlgorithmName, FileExtension: gzip.FileExtension,
+		SupportsLevels: true, HasDecompressor: true}, byName[gzip.AlgorithmName])
+	assert.Equal(t, CompressorInfo{Name: none.AlgorithmName, FileExtension: none.FileExtension,
+		HasDecompressor: true}, byName[none.AlgorithmName])
+	assert.Equal(t, CompressorInfo{Name: ParallelAlgorithmPrefix + lz4.AlgorithmName,
+		FileExtension: ParallelFileExtension, SupportsLevels: true, HasDecompressor: true},
+		byName[ParallelAlgorithmPrefix+lz4.AlgorithmName])
+	assert.False(t, byName[AdaptiveAlgorithmName].SupportsLevels)
+}
+
+func TestAlgorithmByExtension(t *testing.T) {
+	assert.Equal(t, lz4.AlgorithmName, AlgorithmByExtension(lz4.FileExtension))
+	assert.Equal(t, lzma.AlgorithmName, AlgorithmByExtension("."+lzma.FileExtension))
+	assert.Equal(t, none.AlgorithmName, AlgorithmByExtension(none.FileExtension))
+	assert.Equal(t, AdaptiveAlgorithmName, AlgorithmByExtension(AdaptiveFileExtension))
+	assert.Equal(t, "parallel", AlgorithmByExtension(ParallelFileExtension))
+	assert.Equal(t, "", AlgorithmByExtension("lzo"))
+	assert.Equal(t, "", AlgorithmByExtension(""))
+}
+
+func TestRequiresCgo(t *testing.T) {
+	assert.True(t, requiresCgo(zstd.Compressor{}))
+	assert.True(t, requiresCgo(NewParallelCompressor(zstd.Compressor{})))
+	assert.True(t, requiresCgo(AdaptiveCompressor{Candidates: []Compressor{lz4.Compressor{}, zstd.Compressor{}}}))
+	assert.False(t, requiresCgo(AdaptiveCompressor{Candidates: []Compressor{lz4.Compressor{}}}))
+	assert.False(t, requiresCgo(lz4.Compressor{}))
+}
@@ -4,17 +4,57 @@ import (
 	"io"
 
 	"github.com/pierrec/lz4/v4"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
 const (
 	AlgorithmName = "lz4"
 	FileExtension = "lz4"
+
+	// MinLevel is the fast compression used by default
+	MinLevel = 0
+	MaxLevel = 9
 )
 
-type Compressor struct{}
+// Compressor writes the frames with the per-block and the content checksums,
+// so the corruption is detected on decompression independently of the storage
+type Compressor struct {
+	// DisableBlockChecksum leaves only the content checksum of the frame, as the archives written before have
+	DisableBlockChecksum bool
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return lz4.NewWriter(writer)
+	return compressor.newWriter(writer)
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	lz4Writer := compressor.newWriter(writer)
+	if err := lz4Writer.Apply(lz4.CompressionLevelOption(toCompressionLevel(level))); err != nil {
+		tracelog.WarningLogger.Printf("failed to set lz4 compression level %d, using the default one: %v", level, err)
+	}
+	return lz4Writer
+}
+
+func (compressor Compressor) newWriter(writer io.Writer) *lz4.Writer {
+	lz4Writer := lz4.NewWriter(writer)
+	err := lz4Writer.Apply(lz4.ChecksumOption(true), lz4.BlockChecksumOption(!compressor.DisableBlockChecksum))
+	if err != nil {
+		tracelog.WarningLogger.Printf("failed to set lz4 checksums: %v", err)
+	}
+	return lz4Writer
+}
+
+func (compressor Compressor) ValidateLevel(level int) error {
+	return computils.ValidateCompressionLevel(AlgorithmName, level, MinLevel, MaxLevel)
+}
+
+// toCompressionLevel converts the level from the [MinLevel, MaxLevel] range to the lz4 one
+func toCompressionLevel(level int) lz4.CompressionLevel {
+	if level <= MinLevel {
+		return lz4.Fast
+	}
+	return lz4.Level1 << (level - 1)
 }
 
 func (compressor Compressor) FileExtension() string {
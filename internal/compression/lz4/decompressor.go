@@ -1,17 +1,63 @@
 package lz4
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/pierrec/lz4/v4"
+	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
+// Decompressor is backed by the pure Go lz4 implementation, so it is available in the cgo-free builds too.
+// The checksums present in the frame are verified, the frames without them are read as well.
 type Decompressor struct{}
 
 func (decompressor Decompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
-	return io.NopCloser(lz4.NewReader(src)), nil
+	return io.NopCloser(&checkedReader{lz4.NewReader(src)}), nil
 }
 
 func (decompressor Decompressor) FileExtension() string {
 	return FileExtension
 }
+
+// NewResettableReader creates the reader which can be reset to decode the other stream
+func (decompressor Decompressor) NewResettableReader(src io.Reader) (computils.ResettableReader, error) {
+	return &resettableReader{checkedReader{lz4.NewReader(src)}}, nil
+}
+
+type resettableReader struct {
+	checkedReader
+}
+
+func (reader *resettableReader) Reset(src io.Reader) error {
+	reader.Reader.Reset(src)
+	return nil
+}
+
+// CorruptionError is returned once the decompressed data does not match the checksum stored in the frame
+type CorruptionError struct {
+	error
+}
+
+func (err CorruptionError) Error() string {
+	return fmt.Sprintf("lz4 archive is corrupted: %v", err.error)
+}
+
+func (err CorruptionError) Unwrap() error {
+	return err.error
+}
+
+// checkedReader reports the checksum mismatches as the CorruptionError
+type checkedReader struct {
+	*lz4.Reader
+}
+
+func (reader *checkedReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	if errors.Is(err, lz4.ErrInvalidBlockChecksum) || errors.Is(err, lz4.ErrInvalidFrameChecksum) ||
+		errors.Is(err, lz4.ErrInvalidHeaderChecksum) {
+		err = CorruptionError{err}
+	}
+	return n, err
+}
@@ -0,0 +1,25 @@
+package compression
+
+import "github.com/wal-g/wal-g/internal/compression/lz4"
+
+// RegisterLz4BlockChecksum switches the per-block checksums of the lz4 frames, the content checksum is always written.
+// The compressors built upon lz4 are updated to use the setting as well, their other settings are kept.
+func RegisterLz4BlockChecksum(enabled bool) {
+	compressor := lz4.Compressor{DisableBlockChecksum: !enabled}
+	Compressors[lz4.AlgorithmName] = compressor
+	if parallel, ok := Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName].(ParallelCompressor); ok {
+		parallel.Base = compressor
+		Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName] = parallel
+	}
+	if adaptive, ok := Compressors[AdaptiveAlgorithmName].(AdaptiveCompressor); ok {
+		candidates := make([]Compressor, 0, len(adaptive.Candidates))
+		for _, candidate := range adaptive.Candidates {
+			if _, ok := candidate.(lz4.Compressor); ok {
+				candidate = compressor
+			}
+			candidates = append(candidates, candidate)
+		}
+		adaptive.Candidates = candidates
+		Compressors[AdaptiveAlgorithmName] = adaptive
+	}
+}
@@ -0,0 +1,90 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+)
+
+func lz4Compress(t *testing.T, data []byte) []byte {
+	var compressed bytes.Buffer
+	writer := Compressors[lz4.AlgorithmName].NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return compressed.Bytes()
+}
+
+func lz4Decompress(compressed []byte) ([]byte, error) {
+	reader, err := lz4.Decompressor{}.Decompress(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// lz4HasBlockChecksum checks the block checksum flag of the frame descriptor
+func lz4HasBlockChecksum(compressed []byte) bool {
+	return compressed[4]&(1<<4) != 0
+}
+
+func TestLz4BlockChecksum_DetectsCorruption(t *testing.T) {
+	data := make([]byte, 1<<16)
+	rand.New(rand.NewSource(1)).Read(data)
+	compressed := lz4Compress(t, data)
+	assert.True(t, lz4HasBlockChecksum(compressed))
+
+	decompressed, err := lz4Decompress(compressed)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	// the random data is stored in the uncompressed blocks, so the flipped byte is only caught by the checksum
+	compressed[len(compressed)/2] ^= 0xff
+	_, err = lz4Decompress(compressed)
+	var corruptionError lz4.CorruptionError
+	assert.True(t, errors.As(err, &corruptionError), "unexpected error: %v", err)
+	assert.Contains(t, err.Error(), "lz4 archive is corrupted")
+}
+
+func TestLz4BlockChecksum_Toggle(t *testing.T) {
+	defer RegisterLz4BlockChecksum(true)
+	data := bytes.Repeat([]byte("checksummed lz4 data "), 1000)
+	withChecksums := lz4Compress(t, data)
+
+	RegisterLz4BlockChecksum(false)
+	withoutChecksums := lz4Compress(t, data)
+	assert.False(t, lz4HasBlockChecksum(withoutChecksums))
+	assert.Equal(t, lz4.Compressor{DisableBlockChecksum: true},
+		Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName].(ParallelCompressor).Base)
+
+	// the archives are readable regardless of the setting
+	for _, compressed := range [][]byte{withChecksums, withoutChecksums} {
+		decompressed, err := lz4Decompress(compressed)
+		assert.NoError(t, err)
+		assert.Equal(t, data, decompressed)
+	}
+}
+
+func TestLz4BlockChecksum_KeepsCompressorSettings(t *testing.T) {
+	parallel, adaptive := Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName], Compressors[AdaptiveAlgorithmName]
+	defer func() {
+		Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName], Compressors[AdaptiveAlgorithmName] = parallel, adaptive
+		RegisterLz4BlockChecksum(true)
+	}()
+	Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName] = ParallelCompressor{Base: lz4.Compressor{}, BlockSize: 4096,
+		BlocksInFlight: 2}
+	Compressors[AdaptiveAlgorithmName] = AdaptiveCompressor{Candidates: []Compressor{lz4.Compressor{}, lzma.Compressor{}},
+		SampleSize: 4096}
+
+	RegisterLz4BlockChecksum(false)
+	assert.Equal(t, ParallelCompressor{Base: lz4.Compressor{DisableBlockChecksum: true}, BlockSize: 4096, BlocksInFlight: 2},
+		Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName])
+	assert.Equal(t, AdaptiveCompressor{Candidates: []Compressor{lz4.Compressor{DisableBlockChecksum: true}, lzma.Compressor{}},
+		SampleSize: 4096}, Compressors[AdaptiveAlgorithmName])
+}
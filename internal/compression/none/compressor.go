@@ -0,0 +1,27 @@
+package none
+
+import (
+	"io"
+)
+
+const (
+	AlgorithmName = "none"
+	FileExtension = "raw"
+)
+
+// Compressor stores the data as is, e.g. the already compressed files which do not shrink anymore
+type Compressor struct{}
+
+func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
+	return nopWriteCloser{writer}
+}
+
+func (compressor Compressor) FileExtension() string {
+	return FileExtension
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,16 @@
+package none
+
+import (
+	"io"
+)
+
+// Decompressor passes the stored data through unchanged
+type Decompressor struct{}
+
+func (decompressor Decompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(src), nil
+}
+
+func (decompressor Decompressor) FileExtension() string {
+	return FileExtension
+}
@@ -0,0 +1,250 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/wal-g/wal-g/internal/compression/none"
+)
+
+const (
+	ParallelAlgorithmPrefix = "parallel-"
+	ParallelFileExtension   = "pz"
+
+	DefaultParallelBlockSize = 1 << 20
+	// MaxParallelBlockSize bounds the memory allocated for a block read from the untrusted stream
+	MaxParallelBlockSize = 1 << 30
+)
+
+// parallelMagic starts the parallel stream, it is followed by the length and the extension of the base algorithm.
+// Then the blocks go: the big endian uint32 length of the compressed block and the block itself,
+// which is the complete stream of the base algorithm. The zero length terminates the stream.
+var parallelMagic = []byte{'W', 'G', 'P', 'Z', 1}
+
+func init() {
+	for _, algorithm := range CompressingAlgorithms {
+		if algorithm == none.AlgorithmName {
+			// there is nothing to parallelize
+			continue
+		}
+		if algorithm == AdaptiveAlgorithmName {
+			// the adaptive method chooses the algorithm per stream, it is not made for the blocks
+			continue
+		}
+		parallelAlgorithm := ParallelAlgorithmPrefix + algorithm
+		Compressors[parallelAlgorithm] = NewParallelCompressor(Compressors[algorithm])
+		CompressingAlgorithms = append(CompressingAlgorithms, parallelAlgorithm)
+	}
+	Decompressors = append(Decompressors, ParallelDecompressor{})
+}
+
+// ParallelCompressor splits the stream into blocks and compresses them by the Base compressor concurrently.
+// At most BlocksInFlight blocks are kept in memory, the compressed blocks are written in the input order.
+type ParallelCompressor struct {
+	Base           Compressor
+	BlockSize      int
+	BlocksInFlight int
+}
+
+func NewParallelCompressor(base Compressor) ParallelCompressor {
+	return ParallelCompressor{Base: base, BlockSize: DefaultParallelBlockSize, BlocksInFlight: runtime.NumCPU()}
+}
+
+func (compressor ParallelCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	blocksInFlight := compressor.BlocksInFlight
+	if blocksInFlight < 1 {
+		blocksInFlight = 1
+	}
+	blockSize := compressor.BlockSize
+	if blockSize < 1 || blockSize > MaxParallelBlockSize {
+		blockSize = DefaultParallelBlockSize
+	}
+	return &parallelWriter{
+		base:        compressor.Base,
+		output:      writer,
+		block:       make([]byte, 0, blockSize),
+		maxInFlight: blocksInFlight,
+	}
+}
+
+func (compressor ParallelCompressor) FileExtension() string {
+	return ParallelFileExtension
+}
+
+type parallelWriter struct {
+	base          Compressor
+	output        io.Writer
+	block         []byte
+	inFlight      []chan *bytes.Buffer
+	maxInFlight   int
+	headerWritten bool
+	err           error
+}
+
+func (writer *parallelWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if writer.err != nil {
+			return written, writer.err
+		}
+		n := copy(writer.block[len(writer.block):cap(writer.block)], p)
+		writer.block = writer.block[:len(writer.block)+n]
+		written += n
+		p = p[n:]
+		if len(writer.block) == cap(writer.block) {
+			writer.dispatchBlock()
+		}
+	}
+	return written, writer.err
+}
+
+// dispatchBlock starts the compression of the current block, the oldest block is written first if there is no free slot
+func (writer *parallelWriter) dispatchBlock() {
+	if len(writer.inFlight) == writer.maxInFlight {
+		writer.writeOldestBlock()
+	}
+	block := writer.block
+	compressed := make(chan *bytes.Buffer, 1)
+	go func() {
+		var buf bytes.Buffer
+		blockWriter := writer.base.NewWriter(&buf)
+		// writes to the buffer do not fail, so do the writes of the compressors to it
+		_, _ = blockWriter.Write(block)
+		_ = blockWriter.Close()
+		compressed <- &buf
+	}()
+	writer.inFlight = append(writer.inFlight, compressed)
+	writer.block = make([]byte, 0, cap(block))
+}
+
+func (writer *parallelWriter) writeOldestBlock() {
+	buf := <-writer.inFlight[0]
+	writer.inFlight = writer.inFlight[1:]
+	if writer.err != nil {
+		return
+	}
+	if writer.err = writer.writeHeader(); writer.err != nil {
+		return
+	}
+	writer.err = writeParallelBlock(writer.output, buf.Bytes())
+}
+
+func (writer *parallelWriter) writeHeader() error {
+	if writer.headerWritten {
+		return nil
+	}
+	writer.headerWritten = true
+	extension := writer.base.FileExtension()
+	header := append(append(append([]byte{}, parallelMagic...), byte(len(extension))), extension...)
+	_, err := writer.output.Write(header)
+	return err
+}
+
+func writeParallelBlock(output io.Writer, block []byte) error {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(block)))
+	if _, err := output.Write(length); err != nil {
+		return err
+	}
+	_, err := output.Write(block)
+	return err
+}
+
+// Close compresses the rest of the data, waits for all the blocks to be written and terminates the stream
+func (writer *parallelWriter) Close() error {
+	if len(writer.block) > 0 {
+		writer.dispatchBlock()
+	}
+	for len(writer.inFlight) > 0 {
+		writer.writeOldestBlock()
+	}
+	if writer.err != nil {
+		return writer.err
+	}
+	if writer.err = writer.writeHeader(); writer.err != nil {
+		return writer.err
+	}
+	writer.err = writeParallelBlock(writer.output, nil)
+	return writer.err
+}
+
+// ParallelDecompressor decompresses the streams of ParallelCompressor by the base algorithm stored in the header
+type ParallelDecompressor struct{}
+
+func (decompressor ParallelDecompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	header := make([]byte, len(parallelMagic)+1)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("failed to read the parallel compression header: %w", err)
+	}
+	if !bytes.Equal(header[:len(parallelMagic)], parallelMagic) {
+		return nil, fmt.Errorf("unexpected parallel compression header %x", header[:len(parallelMagic)])
+	}
+	extension := make([]byte, header[len(parallelMagic)])
+	if _, err := io.ReadFull(src, extension); err != nil {
+		return nil, fmt.Errorf("failed to read the parallel compression header: %w", err)
+	}
+	base := FindDecompressor(string(extension))
+	if base == nil || base.FileExtension() == ParallelFileExtension {
+		return nil, fmt.Errorf("unsupported base compression method of the parallel stream: '%s'", extension)
+	}
+	return io.NopCloser(&parallelReader{base: Pooled(base), src: src}), nil
+}
+
+func (decompressor ParallelDecompressor) FileExtension() string {
+	return ParallelFileExtension
+}
+
+type parallelReader struct {
+	base  Decompressor
+	src   io.Reader
+	block io.ReadCloser
+	done  bool
+}
+
+func (reader *parallelReader) Read(p []byte) (int, error) {
+	for !reader.done {
+		if reader.block != nil {
+			n, err := reader.block.Read(p)
+			if err == io.EOF {
+				_ = reader.block.Close()
+				reader.block, err = nil, nil
+			}
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		if err := reader.nextBlock(); err != nil {
+			return 0, err
+		}
+	}
+	return 0, io.EOF
+}
+
+func (reader *parallelReader) nextBlock() error {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(reader.src, length); err != nil {
+		return fmt.Errorf("failed to read the parallel compression block length: %w", err)
+	}
+	blockSize := binary.BigEndian.Uint32(length)
+	if blockSize == 0 {
+		reader.done = true
+		return nil
+	}
+	if blockSize > MaxParallelBlockSize {
+		return fmt.Errorf("parallel compression block of %d bytes exceeds the limit of %d bytes", blockSize, MaxParallelBlockSize)
+	}
+	block := make([]byte, blockSize)
+	if _, err := io.ReadFull(reader.src, block); err != nil {
+		return fmt.Errorf("failed to read the parallel compression block: %w", err)
+	}
+	decompressed, err := reader.base.Decompress(bytes.NewReader(block))
+	if err != nil {
+		return err
+	}
+	reader.block = decompressed
+	return nil
+}
@@ -0,0 +1,75 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+)
+
+func TestParallelCompressor_RoundTrip(t *testing.T) {
+	data := make([]byte, 10*1000+123)
+	rand.New(rand.NewSource(1)).Read(data[:len(data)/2])
+
+	for _, blocksInFlight := range []int{1, 3} {
+		compressor := ParallelCompressor{Base: lz4.Compressor{}, BlockSize: 1000, BlocksInFlight: blocksInFlight}
+		var compressed bytes.Buffer
+		writer := compressor.NewWriter(&compressed)
+		// uneven writes cross the block boundaries
+		for offset := 0; offset < len(data); offset += 777 {
+			end := offset + 777
+			if end > len(data) {
+				end = len(data)
+			}
+			_, err := writer.Write(data[offset:end])
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, writer.Close())
+
+		decompressor := GetDecompressorByCompressor(compressor)
+		assert.IsType(t, ParallelDecompressor{}, decompressor)
+		reader, err := decompressor.Decompress(&compressed)
+		assert.NoError(t, err)
+		decompressed, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, data, decompressed)
+	}
+}
+
+func TestParallelCompressor_EmptyStream(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := ParallelCompressor{Base: lzma.Compressor{}, BlockSize: 16, BlocksInFlight: 2}.NewWriter(&compressed)
+	assert.NoError(t, writer.Close())
+
+	reader, err := DecompressDetected(&compressed)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Empty(t, decompressed)
+}
+
+func TestParallelCompressor_Registered(t *testing.T) {
+	compressor, ok := Compressors[ParallelAlgorithmPrefix+lz4.AlgorithmName]
+	assert.True(t, ok)
+	assert.Equal(t, ParallelFileExtension, compressor.FileExtension())
+	assert.Contains(t, CompressingAlgorithms, ParallelAlgorithmPrefix+lzma.AlgorithmName)
+	assert.NotContains(t, Compressors, ParallelAlgorithmPrefix+AdaptiveAlgorithmName)
+	assert.NotContains(t, CompressingAlgorithms, ParallelAlgorithmPrefix+AdaptiveAlgorithmName)
+}
+
+func TestParallelDecompressor_TruncatedStream(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := ParallelCompressor{Base: lz4.Compressor{}, BlockSize: 8, BlocksInFlight: 2}.NewWriter(&compressed)
+	_, err := writer.Write([]byte("truncated parallel stream"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	reader, err := ParallelDecompressor{}.Decompress(bytes.NewReader(compressed.Bytes()[:compressed.Len()-2]))
+	assert.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package compression
+
+import (
+	"io"
+	"sync"
+
+	"github.com/wal-g/wal-g/internal/compression/computils"
+)
+
+// ResettableDecompressor creates the readers which can be reset to decode the other stream,
+// so they are reused by the DecompressorPool instead of being allocated per object
+type ResettableDecompressor interface {
+	Decompressor
+	NewResettableReader(src io.Reader) (computils.ResettableReader, error)
+}
+
+// DecompressorPool is the Decompressor reusing the readers of the ResettableDecompressor.
+// The reader is returned to the pool once it is closed, the reader which failed is dropped
+// since its state may be broken.
+type DecompressorPool struct {
+	decompressor ResettableDecompressor
+	pool         sync.Pool
+}
+
+var decompressorPools sync.Map
+
+// Pooled returns the DecompressorPool shared by the callers of the same decompressor,
+// the decompressors which can not reset their readers (e.g. brotli) are returned as is
+func Pooled(decompressor Decompressor) Decompressor {
+	resettable, ok := decompressor.(ResettableDecompressor)
+	if !ok {
+		return decompressor
+	}
+	if pool, ok := decompressorPools.Load(resettable); ok {
+		return pool.(*DecompressorPool)
+	}
+	pool, _ := decompressorPools.LoadOrStore(resettable, NewDecompressorPool(resettable))
+	return pool.(*DecompressorPool)
+}
+
+func NewDecompressorPool(decompressor ResettableDecompressor) *DecompressorPool {
+	return &DecompressorPool{decompressor: decompressor}
+}
+
+// Decompress takes the reader from the pool and resets it to the src, the new reader is created if the pool is empty
+func (pool *DecompressorPool) Decompress(src io.Reader) (io.ReadCloser, error) {
+	if reader, ok := pool.pool.Get().(computils.ResettableReader); ok {
+		if err := reader.Reset(src); err != nil {
+			return nil, err
+		}
+		return &pooledReader{reader: reader, pool: pool}, nil
+	}
+	reader, err := pool.decompressor.NewResettableReader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledReader{reader: reader, pool: pool}, nil
+}
+
+func (pool *DecompressorPool) FileExtension() string {
+	return pool.decompressor.FileExtension()
+}
+
+// put releases the source of the previous stream before the reader is pooled
+func (pool *DecompressorPool) put(reader computils.ResettableReader) {
+	_ = reader.Reset(eofReader{})
+	pool.pool.Put(reader)
+}
+
+type eofReader struct{}
+
+func (eofReader) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+type pooledReader struct {
+	reader computils.ResettableReader
+	pool   *DecompressorPool
+	err    error
+}
+
+func (reader *pooledReader) Read(p []byte) (int, error) {
+	if reader.reader == nil {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := reader.reader.Read(p)
+	if err != nil && err != io.EOF {
+		reader.err = err
+	}
+	return n, err
+}
+
+// Close puts the reader back to the pool, its further reads fail
+func (reader *pooledReader) Close() error {
+	if reader.reader == nil {
+		return nil
+	}
+	if reader.err == nil {
+		reader.pool.put(reader.reader)
+	} else if closer, ok := reader.reader.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	reader.reader = nil
+	return nil
+}
@@ -0,0 +1,113 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+var pooledAlgorithms = []struct {
+	compressor   Compressor
+	decompressor ResettableDecompressor
+}{
+	{lz4.Compressor{}, lz4.Decompressor{}},
+	{gzip.Compressor{}, gzip.Decompressor{}},
+	{zstd.Compressor{}, zstd.Decompressor{}},
+}
+
+func compressTestData(t testing.TB, compressor Compressor, data []byte) []byte {
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return compressed.Bytes()
+}
+
+func decompressTestData(t testing.TB, decompressor Decompressor, compressed []byte) []byte {
+	reader, err := decompressor.Decompress(bytes.NewReader(compressed))
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	return data
+}
+
+func TestDecompressorPool_ResetsReusedReaders(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, algorithm := range pooledAlgorithms {
+		pool := NewDecompressorPool(algorithm.decompressor)
+		payloads := make([][]byte, 3)
+		for i := range payloads {
+			payloads[i] = make([]byte, 1<<16+i*1000)
+			random.Read(payloads[i][:len(payloads[i])/2])
+		}
+
+		for _, payload := range payloads {
+			compressed := compressTestData(t, algorithm.compressor, payload)
+			assert.Equal(t, payload, decompressTestData(t, pool, compressed), algorithm.decompressor.FileExtension())
+		}
+
+		// the partially read stream does not leak into the next one
+		reader, err := pool.Decompress(bytes.NewReader(compressTestData(t, algorithm.compressor, payloads[0])))
+		assert.NoError(t, err)
+		_, err = io.ReadFull(reader, make([]byte, 100))
+		assert.NoError(t, err)
+		assert.NoError(t, reader.Close())
+		_, err = reader.Read(make([]byte, 1))
+		assert.Error(t, err)
+		compressed := compressTestData(t, algorithm.compressor, payloads[1])
+		assert.Equal(t, payloads[1], decompressTestData(t, pool, compressed), algorithm.decompressor.FileExtension())
+
+		// the reader failed on the truncated stream is dropped
+		reader, err = pool.Decompress(bytes.NewReader(compressed[:len(compressed)/2]))
+		if err == nil {
+			_, err = io.ReadAll(reader)
+			assert.Error(t, err, algorithm.decompressor.FileExtension())
+			assert.NoError(t, reader.Close())
+		}
+		assert.Equal(t, payloads[2], decompressTestData(t, pool, compressTestData(t, algorithm.compressor, payloads[2])))
+	}
+}
+
+func TestPooled(t *testing.T) {
+	assert.Same(t, Pooled(lz4.Decompressor{}), Pooled(lz4.Decompressor{}))
+	assert.NotSame(t, Pooled(zstd.Decompressor{}), Pooled(zstd.Decompressor{WindowLogMax: 30}))
+	assert.Equal(t, ParallelDecompressor{}, Pooled(ParallelDecompressor{}))
+	assert.Nil(t, Pooled(nil))
+}
+
+func BenchmarkDecompressorPool(b *testing.B) {
+	data := make([]byte, 1<<16)
+	rand.New(rand.NewSource(1)).Read(data[:len(data)/2])
+	for _, algorithm := range pooledAlgorithms {
+		compressed := compressTestData(b, algorithm.compressor, data)
+		for _, decompressor := range []Decompressor{algorithm.decompressor, NewDecompressorPool(algorithm.decompressor)} {
+			name := algorithm.decompressor.FileExtension()
+			if _, ok := decompressor.(*DecompressorPool); ok {
+				name += "/pooled"
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					reader, err := decompressor.Decompress(bytes.NewReader(compressed))
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err = io.Copy(io.Discard, reader); err != nil {
+						b.Fatal(err)
+					}
+					_ = reader.Close()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package compression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegisterCompressor adds the compression method implemented outside of WAL-G, e.g. by the in-house codec
+// linked into the custom build, the plugins call it through pkg/compression. The compressor is selected by the name in WALG_COMPRESSION_METHOD,
+// the decompressor is found by the ext of the stored files. Either of c and d may be nil:
+// the method without the decompressor can not be restored by this build, the decompressor alone only reads.
+//
+// The registries are not guarded by a mutex, so RegisterCompressor must be called only during the program
+// initialization, i.e. from the init() of the plugin package, before any compression method is looked up.
+// The registered method does not get the parallel variant, since the variants are made before the plugin init runs.
+func RegisterCompressor(name, ext string, c Compressor, d Decompressor) error {
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" {
+		return fmt.Errorf("compression method '%s' must have a file extension", name)
+	}
+	if c == nil && d == nil {
+		return fmt.Errorf("compression method '%s' has neither a compressor nor a decompressor", name)
+	}
+	if c != nil {
+		if name == "" {
+			return fmt.Errorf("compressor with '%s' extension must have a name", ext)
+		}
+		if _, ok := Compressors[name]; ok {
+			return fmt.Errorf("compression method '%s' is already registered", name)
+		}
+		if c.FileExtension() != ext {
+			return fmt.Errorf("compressor '%s' writes '%s' files rather than '%s'", name, c.FileExtension(), ext)
+		}
+	}
+	if d != nil {
+		if FindDecompressor(ext) != nil {
+			return fmt.Errorf("decompressor of '%s' files is already registered", ext)
+		}
+		if d.FileExtension() != ext {
+			return fmt.Errorf("decompressor '%s' reads '%s' files rather than '%s'", name, d.FileExtension(), ext)
+		}
+	}
+
+	if c != nil {
+		Compressors[name] = c
+		CompressingAlgorithms = append(CompressingAlgorithms, name)
+	}
+	if d != nil {
+		Decompressors = append(Decompressors, d)
+	}
+	return nil
+}
+
+// MustRegisterCompressor is RegisterCompressor panicking on the error, it is convenient in init()
+func MustRegisterCompressor(name, ext string, c Compressor, d Decompressor) {
+	if err := RegisterCompressor(name, ext, c, d); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,98 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+const xorKey = 0x5A
+
+// xorCodec is the trivial codec standing for the plugin one
+type xorCodec struct{}
+
+func (xorCodec) FileExtension() string {
+	return "xor"
+}
+
+func (xorCodec) NewWriter(writer io.Writer) io.WriteCloser {
+	return &xorWriter{writer: writer}
+}
+
+func (xorCodec) Decompress(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(&xorReader{reader: src}), nil
+}
+
+type xorWriter struct {
+	writer io.Writer
+}
+
+func (w *xorWriter) Write(p []byte) (int, error) {
+	encoded := make([]byte, len(p))
+	for i, b := range p {
+		encoded[i] = b ^ xorKey
+	}
+	return w.writer.Write(encoded)
+}
+
+func (w *xorWriter) Close() error {
+	return nil
+}
+
+type xorReader struct {
+	reader io.Reader
+}
+
+func (r *xorReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	for i := 0; i < n; i++ {
+		p[i] ^= xorKey
+	}
+	return n, err
+}
+
+func registerXorCodec(t *testing.T) {
+	algorithms, decompressors := CompressingAlgorithms, Decompressors
+	t.Cleanup(func() {
+		delete(Compressors, "xor")
+		CompressingAlgorithms, Decompressors = algorithms, decompressors
+	})
+	assert.NoError(t, RegisterCompressor("xor", ".xor", xorCodec{}, xorCodec{}))
+}
+
+func TestRegisterCompressor_RoundTrip(t *testing.T) {
+	registerXorCodec(t)
+	assert.Contains(t, CompressingAlgorithms, "xor")
+
+	compressor, ok := Compressors["xor"]
+	assert.True(t, ok)
+	data := bytes.Repeat([]byte("plugin codec "), 100)
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NotEqual(t, data, compressed.Bytes())
+
+	decompressor := FindDecompressor("." + compressor.FileExtension())
+	assert.NotNil(t, decompressor)
+	reader, err := decompressor.Decompress(&compressed)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestRegisterCompressor_RejectsDuplicates(t *testing.T) {
+	registerXorCodec(t)
+	assert.Error(t, RegisterCompressor("xor", "xor2", nil, nil))
+	assert.Error(t, RegisterCompressor("xor", "xor", xorCodec{}, nil))
+	assert.Error(t, RegisterCompressor("xor2", "xor", nil, xorCodec{}))
+	assert.Error(t, RegisterCompressor(lz4.AlgorithmName, lz4.FileExtension, lz4.Compressor{}, nil))
+	// the extension must match the one of the codec
+	assert.Error(t, RegisterCompressor("xor2", "xor2", xorCodec{}, nil))
+	assert.NotContains(t, Compressors, "xor2")
+}
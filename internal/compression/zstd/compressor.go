@@ -4,17 +4,37 @@ import (
 	"io"
 
 	"github.com/DataDog/zstd"
+	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
 const (
 	AlgorithmName = "zstd"
 	FileExtension = "zst"
+
+	DefaultLevel = 3
+	MinLevel     = 1
+	MaxLevel     = 22
 )
 
-type Compressor struct{}
+// Compressor enables the long-distance matching with the window of 2^WindowLog bytes
+// if WindowLog is set
+type Compressor struct {
+	WindowLog int
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return zstd.NewWriterLevel(writer, 3)
+	return compressor.NewWriterLevel(writer, DefaultLevel)
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	if compressor.WindowLog != 0 {
+		return newLongWriter(writer, level, compressor.WindowLog)
+	}
+	return zstd.NewWriterLevel(writer, level)
+}
+
+func (compressor Compressor) ValidateLevel(level int) error {
+	return computils.ValidateCompressionLevel(AlgorithmName, level, MinLevel, MaxLevel)
 }
 
 func (compressor Compressor) FileExtension() string {
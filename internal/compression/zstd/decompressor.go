@@ -2,17 +2,33 @@ package zstd
 
 import (
 	"io"
+	"runtime"
 
-	"github.com/DataDog/zstd"
 	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
-type Decompressor struct{}
+// Decompressor accepts the frames with the window up to 2^WindowLogMax bytes
+// if WindowLogMax is set, otherwise up to 2^DefaultWindowLogMax
+type Decompressor struct {
+	WindowLogMax int
+}
 
 func (decompressor Decompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
-	return zstd.NewReader(computils.NewUntilEOFReader(src)), nil
+	return newReader(src, decompressor.WindowLogMax), nil
 }
 
 func (decompressor Decompressor) FileExtension() string {
 	return FileExtension
 }
+
+// NewResettableReader creates the reader which can be reset to decode the other stream,
+// the decoder is freed once the reader is closed or garbage collected
+func (decompressor Decompressor) NewResettableReader(src io.Reader) (computils.ResettableReader, error) {
+	windowLogMax := decompressor.WindowLogMax
+	if windowLogMax == 0 {
+		windowLogMax = DefaultWindowLogMax
+	}
+	reader := newLongReader(computils.NewUntilEOFReader(src), windowLogMax).(*longReader)
+	runtime.SetFinalizer(reader, func(reader *longReader) { _ = reader.Close() })
+	return reader, nil
+}
@@ -0,0 +1,147 @@
+package zstd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DataDog/zstd"
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/compression/computils"
+)
+
+const (
+	DictionaryAlgorithmName = "zstd_dict"
+
+	frameMagicNumber      = 0xFD2FB528
+	dictionaryMagicNumber = 0xEC30A437
+	// magic number, frame header descriptor, window descriptor and the longest dictionary id
+	maxFrameHeaderPrefixSize = 4 + 1 + 1 + 4
+)
+
+// Dictionaries maps the dictionary id to the dictionary content
+type Dictionaries map[uint32][]byte
+
+// LoadDictionaries reads the dictionary from the file
+// or all the dictionaries from the directory at the path
+func LoadDictionaries(path string) (Dictionaries, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load zstd dictionaries from '%s'", path)
+	}
+	paths := []string{path}
+	if fileInfo.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list zstd dictionaries in '%s'", path)
+		}
+		paths = paths[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				paths = append(paths, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	dictionaries := make(Dictionaries, len(paths))
+	for _, dictionaryPath := range paths {
+		dictionary, err := os.ReadFile(dictionaryPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read zstd dictionary '%s'", dictionaryPath)
+		}
+		id, err := DictionaryID(dictionary)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid zstd dictionary '%s'", dictionaryPath)
+		}
+		dictionaries[id] = dictionary
+	}
+	return dictionaries, nil
+}
+
+// DictionaryID returns the id stored in the zstd dictionary header
+func DictionaryID(dictionary []byte) (uint32, error) {
+	if len(dictionary) < 8 || binary.LittleEndian.Uint32(dictionary) != dictionaryMagicNumber {
+		return 0, errors.New("zstd dictionary magic number not found")
+	}
+	return binary.LittleEndian.Uint32(dictionary[4:]), nil
+}
+
+// FrameDictionaryID returns the dictionary id from the zstd frame header,
+// 0 means the frame does not require a dictionary
+func FrameDictionaryID(frameHeader []byte) uint32 {
+	if len(frameHeader) < 5 || binary.LittleEndian.Uint32(frameHeader) != frameMagicNumber {
+		return 0
+	}
+	descriptor := frameHeader[4]
+	position := 5
+	if descriptor&(1<<5) == 0 {
+		// the window descriptor is present if the frame is not single segment
+		position++
+	}
+	idSize := [4]int{0, 1, 2, 4}[descriptor&3]
+	if len(frameHeader) < position+idSize {
+		return 0
+	}
+	idBytes := frameHeader[position : position+idSize]
+	switch idSize {
+	case 1:
+		return uint32(idBytes[0])
+	case 2:
+		return uint32(binary.LittleEndian.Uint16(idBytes))
+	case 4:
+		return binary.LittleEndian.Uint32(idBytes)
+	}
+	return 0
+}
+
+// DictCompressor compresses the data using the preset dictionary
+type DictCompressor struct {
+	Dictionary []byte
+}
+
+func (compressor *DictCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	return zstd.NewWriterLevelDict(writer, DefaultLevel, compressor.Dictionary)
+}
+
+func (compressor *DictCompressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	return zstd.NewWriterLevelDict(writer, level, compressor.Dictionary)
+}
+
+func (compressor *DictCompressor) ValidateLevel(level int) error {
+	return computils.ValidateCompressionLevel(DictionaryAlgorithmName, level, MinLevel, MaxLevel)
+}
+
+func (compressor *DictCompressor) FileExtension() string {
+	return FileExtension
+}
+
+// DictDecompressor picks the dictionary by the id embedded in the frame header,
+// frames compressed without a dictionary are decompressed as usual
+type DictDecompressor struct {
+	Dictionaries Dictionaries
+	WindowLogMax int
+}
+
+func (decompressor *DictDecompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	bufferedSrc := bufio.NewReader(src)
+	frameHeader, err := bufferedSrc.Peek(maxFrameHeaderPrefixSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	id := FrameDictionaryID(frameHeader)
+	if id == 0 {
+		return newReader(bufferedSrc, decompressor.WindowLogMax), nil
+	}
+	dictionary, ok := decompressor.Dictionaries[id]
+	if !ok {
+		return nil, fmt.Errorf("zstd frame requires the dictionary %d which is not loaded", id)
+	}
+	return zstd.NewReaderDict(computils.NewUntilEOFReader(bufferedSrc), dictionary), nil
+}
+
+func (decompressor *DictDecompressor) FileExtension() string {
+	return FileExtension
+}
@@ -0,0 +1,42 @@
+package zstd
+
+/*
+#include <stddef.h>
+
+// implemented by the zstd sources bundled with github.com/DataDog/zstd
+size_t ZDICT_trainFromBuffer(void* dictBuffer, size_t dictBufferCapacity,
+	const void* samplesBuffer, const size_t* samplesSizes, unsigned nbSamples);
+unsigned ZDICT_isError(size_t errorCode);
+const char* ZDICT_getErrorName(size_t errorCode);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// TrainDictionary builds the zstd dictionary of at most maxSize bytes from the samples
+func TrainDictionary(samples [][]byte, maxSize int) ([]byte, error) {
+	var samplesBuffer []byte
+	samplesSizes := make([]C.size_t, 0, len(samples))
+	for _, sample := range samples {
+		if len(sample) == 0 {
+			continue
+		}
+		samplesBuffer = append(samplesBuffer, sample...)
+		samplesSizes = append(samplesSizes, C.size_t(len(sample)))
+	}
+	if len(samplesSizes) == 0 || maxSize <= 0 {
+		return nil, errors.New("failed to train zstd dictionary: no samples provided")
+	}
+
+	dictionary := make([]byte, maxSize)
+	size := C.ZDICT_trainFromBuffer(unsafe.Pointer(&dictionary[0]), C.size_t(maxSize),
+		unsafe.Pointer(&samplesBuffer[0]), &samplesSizes[0], C.unsigned(len(samplesSizes)))
+	if C.ZDICT_isError(size) != 0 {
+		return nil, errors.Errorf("failed to train zstd dictionary: %s", C.GoString(C.ZDICT_getErrorName(size)))
+	}
+	return dictionary[:size], nil
+}
@@ -0,0 +1,264 @@
+package zstd
+
+/*
+#include <stddef.h>
+
+// The prototypes of the stable zstd API compiled into github.com/DataDog/zstd,
+// its wrappers do not expose the advanced compression parameters
+
+typedef struct ZSTD_CCtx_s ZSTD_CCtx;
+typedef struct ZSTD_DCtx_s ZSTD_DCtx;
+typedef struct { const void* src; size_t size; size_t pos; } ZSTD_inBuffer;
+typedef struct { void* dst; size_t size; size_t pos; } ZSTD_outBuffer;
+
+ZSTD_CCtx* ZSTD_createCCtx(void);
+size_t ZSTD_freeCCtx(ZSTD_CCtx* cctx);
+size_t ZSTD_CCtx_setParameter(ZSTD_CCtx* cctx, int param, int value);
+size_t ZSTD_compressStream2(ZSTD_CCtx* cctx, ZSTD_outBuffer* output, ZSTD_inBuffer* input, int endOp);
+ZSTD_DCtx* ZSTD_createDCtx(void);
+size_t ZSTD_freeDCtx(ZSTD_DCtx* dctx);
+size_t ZSTD_DCtx_setParameter(ZSTD_DCtx* dctx, int param, int value);
+size_t ZSTD_DCtx_reset(ZSTD_DCtx* dctx, int reset);
+size_t ZSTD_decompressStream(ZSTD_DCtx* dctx, ZSTD_outBuffer* output, ZSTD_inBuffer* input);
+unsigned ZSTD_isError(size_t code);
+const char* ZSTD_getErrorName(size_t code);
+
+// the buffers are passed by the plain pointers, cgo does not allow passing the structs holding Go pointers
+
+static size_t walg_compressStream(ZSTD_CCtx* cctx, void* dst, size_t dstSize, size_t* dstPos,
+	const void* src, size_t srcSize, size_t* srcPos, int endOp) {
+	ZSTD_outBuffer output = {dst, dstSize, *dstPos};
+	ZSTD_inBuffer input = {src, srcSize, *srcPos};
+	size_t result = ZSTD_compressStream2(cctx, &output, &input, endOp);
+	*dstPos = output.pos;
+	*srcPos = input.pos;
+	return result;
+}
+
+static size_t walg_decompressStream(ZSTD_DCtx* dctx, void* dst, size_t dstSize, size_t* dstPos,
+	const void* src, size_t srcSize, size_t* srcPos) {
+	ZSTD_outBuffer output = {dst, dstSize, *dstPos};
+	ZSTD_inBuffer input = {src, srcSize, *srcPos};
+	size_t result = ZSTD_decompressStream(dctx, &output, &input);
+	*dstPos = output.pos;
+	*srcPos = input.pos;
+	return result;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"unsafe"
+
+	"github.com/DataDog/zstd"
+	"github.com/wal-g/wal-g/internal/compression/computils"
+)
+
+const (
+	MinWindowLog = 10
+	// DefaultWindowLogMax is the largest window the decoder accepts unless the limit is raised
+	DefaultWindowLogMax = 27
+
+	cCompressionLevel            = 100
+	cWindowLog                   = 101
+	cEnableLongDistanceMatching  = 160
+	dWindowLogMax                = 100
+	resetSessionOnly             = 1
+	endOpContinue                = 0
+	endOpEnd                     = 2
+	longStreamBufferSize         = 128 << 10
+	maxWindowLog32, maxWindowLog = 30, 31
+)
+
+// MaxWindowLog returns the largest window log zstd allows on this platform
+func MaxWindowLog() int {
+	if bits.UintSize == 32 {
+		return maxWindowLog32
+	}
+	return maxWindowLog
+}
+
+// ValidateWindowLog checks the long-distance matching window log is in the range allowed by zstd
+func ValidateWindowLog(windowLog int) error {
+	if windowLog < MinWindowLog || windowLog > MaxWindowLog() {
+		return fmt.Errorf("zstd window log %d is out of the allowed range %d-%d",
+			windowLog, MinWindowLog, MaxWindowLog())
+	}
+	return nil
+}
+
+func zstdError(code C.size_t) error {
+	if C.ZSTD_isError(code) == 0 {
+		return nil
+	}
+	return errors.New(C.GoString(C.ZSTD_getErrorName(code)))
+}
+
+func bufferPointer(buffer []byte) unsafe.Pointer {
+	if len(buffer) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&buffer[0])
+}
+
+// longWriter compresses the stream with the long-distance matching enabled
+type longWriter struct {
+	writer io.Writer
+	ctx    *C.ZSTD_CCtx
+	dst    []byte
+	err    error
+}
+
+func newLongWriter(writer io.Writer, level, windowLog int) io.WriteCloser {
+	ctx := C.ZSTD_createCCtx()
+	longWriter := &longWriter{writer: writer, ctx: ctx, dst: make([]byte, longStreamBufferSize)}
+	for _, parameter := range [][2]int{{cCompressionLevel, level}, {cWindowLog, windowLog}, {cEnableLongDistanceMatching, 1}} {
+		if err := zstdError(C.ZSTD_CCtx_setParameter(ctx, C.int(parameter[0]), C.int(parameter[1]))); err != nil {
+			longWriter.err = fmt.Errorf("failed to set zstd parameter %d to %d: %w", parameter[0], parameter[1], err)
+			break
+		}
+	}
+	return longWriter
+}
+
+func (writer *longWriter) Write(p []byte) (int, error) {
+	var srcPos C.size_t
+	for writer.err == nil && int(srcPos) < len(p) {
+		_, writer.err = writer.compress(p, &srcPos, endOpContinue)
+	}
+	return int(srcPos), writer.err
+}
+
+func (writer *longWriter) Close() error {
+	if writer.ctx == nil {
+		return writer.err
+	}
+	defer func() {
+		C.ZSTD_freeCCtx(writer.ctx)
+		writer.ctx = nil
+	}()
+	var srcPos C.size_t
+	for remaining := C.size_t(1); writer.err == nil && remaining != 0; {
+		remaining, writer.err = writer.compress(nil, &srcPos, endOpEnd)
+	}
+	return writer.err
+}
+
+// compress passes the src to zstd and writes the compressed output, the number of bytes
+// left to flush is returned
+func (writer *longWriter) compress(src []byte, srcPos *C.size_t, endOp int) (C.size_t, error) {
+	var dstPos C.size_t
+	remaining := C.walg_compressStream(writer.ctx, bufferPointer(writer.dst), C.size_t(len(writer.dst)), &dstPos,
+		bufferPointer(src), C.size_t(len(src)), srcPos, C.int(endOp))
+	if err := zstdError(remaining); err != nil {
+		return 0, fmt.Errorf("zstd compression failed: %w", err)
+	}
+	if dstPos > 0 {
+		if _, err := writer.writer.Write(writer.dst[:dstPos]); err != nil {
+			return 0, err
+		}
+	}
+	return remaining, nil
+}
+
+// longReader decompresses the stream allowing the window up to the configured limit
+type longReader struct {
+	reader         io.Reader
+	ctx            *C.ZSTD_DCtx
+	src            []byte
+	srcPos, srcEnd int
+	srcEOF         bool
+	// frameComplete is false while the decoder waits for the rest of the frame
+	frameComplete bool
+	err           error
+	// parameterErr makes the decoder unusable even after the reset
+	parameterErr error
+	// positions are the output and input positions passed to zstd, they are kept here not to be allocated per read
+	positions [2]C.size_t
+}
+
+func newLongReader(reader io.Reader, windowLogMax int) io.ReadCloser {
+	ctx := C.ZSTD_createDCtx()
+	longReader := &longReader{reader: reader, ctx: ctx, src: make([]byte, longStreamBufferSize), frameComplete: true}
+	if err := zstdError(C.ZSTD_DCtx_setParameter(ctx, dWindowLogMax, C.int(windowLogMax))); err != nil {
+		longReader.parameterErr = fmt.Errorf("failed to set zstd window log limit to %d: %w", windowLogMax, err)
+		longReader.err = longReader.parameterErr
+	}
+	return longReader
+}
+
+func (reader *longReader) Read(p []byte) (int, error) {
+	if reader.err != nil || len(p) == 0 {
+		return 0, reader.err
+	}
+	for {
+		if reader.srcPos == reader.srcEnd && !reader.srcEOF {
+			n, err := reader.reader.Read(reader.src)
+			reader.srcPos, reader.srcEnd = 0, n
+			if err == io.EOF {
+				reader.srcEOF = true
+			} else if err != nil {
+				reader.err = err
+				return 0, err
+			}
+		}
+		if reader.srcPos == reader.srcEnd && reader.srcEOF {
+			reader.err = io.EOF
+			if !reader.frameComplete {
+				reader.err = io.ErrUnexpectedEOF
+			}
+			return 0, reader.err
+		}
+
+		dstPos, srcPos := &reader.positions[0], &reader.positions[1]
+		*dstPos, *srcPos = 0, C.size_t(reader.srcPos)
+		src := reader.src[:reader.srcEnd]
+		result := C.walg_decompressStream(reader.ctx, bufferPointer(p), C.size_t(len(p)), dstPos,
+			bufferPointer(src), C.size_t(len(src)), srcPos)
+		if err := zstdError(result); err != nil {
+			reader.err = fmt.Errorf("zstd decompression failed: %w", err)
+			return 0, reader.err
+		}
+		reader.srcPos = int(*srcPos)
+		reader.frameComplete = result == 0
+		if *dstPos > 0 {
+			return int(*dstPos), nil
+		}
+	}
+}
+
+func (reader *longReader) Close() error {
+	if reader.ctx != nil {
+		C.ZSTD_freeDCtx(reader.ctx)
+		reader.ctx = nil
+	}
+	return nil
+}
+
+// Reset starts decoding the new stream keeping the decoder parameters and buffers
+func (reader *longReader) Reset(src io.Reader) error {
+	if reader.ctx == nil {
+		return errors.New("zstd reader is closed")
+	}
+	if err := zstdError(C.ZSTD_DCtx_reset(reader.ctx, resetSessionOnly)); err != nil {
+		return fmt.Errorf("failed to reset zstd decoder: %w", err)
+	}
+	reader.reader = computils.NewUntilEOFReader(src)
+	reader.srcPos, reader.srcEnd, reader.srcEOF = 0, 0, false
+	reader.frameComplete = true
+	reader.err = reader.parameterErr
+	return reader.err
+}
+
+// newReader uses the decoder with the raised window limit only if it is set
+func newReader(src io.Reader, windowLogMax int) io.ReadCloser {
+	src = computils.NewUntilEOFReader(src)
+	if windowLogMax == 0 {
+		return zstd.NewReader(src)
+	}
+	return newLongReader(src, windowLogMax)
+}
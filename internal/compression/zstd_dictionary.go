@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package compression
+
+import "github.com/wal-g/wal-g/internal/compression/zstd"
+
+// RegisterZstdDictionary loads the zstd dictionaries from the path and registers
+// the compressor using the dictionary, the zstd decompressor is replaced to pick
+// the dictionary by the id stored in the frame
+func RegisterZstdDictionary(path string) error {
+	dictionaries, err := zstd.LoadDictionaries(path)
+	if err != nil {
+		return err
+	}
+	decompressor := &zstd.DictDecompressor{Dictionaries: dictionaries}
+	for i := range Decompressors {
+		if Decompressors[i].FileExtension() == zstd.FileExtension {
+			Decompressors[i] = decompressor
+		}
+	}
+
+	// the compressor is registered only if the path points to a single dictionary
+	if len(dictionaries) != 1 {
+		return nil
+	}
+	for _, dictionary := range dictionaries {
+		if _, ok := Compressors[zstd.DictionaryAlgorithmName]; !ok {
+			CompressingAlgorithms = append(CompressingAlgorithms, zstd.DictionaryAlgorithmName)
+		}
+		Compressors[zstd.DictionaryAlgorithmName] = &zstd.DictCompressor{Dictionary: dictionary}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+//go:build !windows
+// +build !windows
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+func trainTestDictionary(t *testing.T) []byte {
+	samples := make([][]byte, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, []byte(fmt.Sprintf("record %d of the similar WAL segment payload %d", i, i*7)))
+	}
+	dictionary, err := zstd.TrainDictionary(samples, 4096)
+	assert.NoError(t, err)
+	return dictionary
+}
+
+func TestZstdDictionaryCompression(t *testing.T) {
+	decompressors := append([]Decompressor{}, Decompressors...)
+	algorithms := append([]string{}, CompressingAlgorithms...)
+	defer func() {
+		Decompressors, CompressingAlgorithms = decompressors, algorithms
+		delete(Compressors, zstd.DictionaryAlgorithmName)
+	}()
+
+	dictionary := trainTestDictionary(t)
+	dictionaryPath := filepath.Join(t.TempDir(), "wal.dict")
+	assert.NoError(t, os.WriteFile(dictionaryPath, dictionary, 0600))
+	assert.NoError(t, RegisterZstdDictionary(dictionaryPath))
+	assert.Contains(t, CompressingAlgorithms, zstd.DictionaryAlgorithmName)
+
+	var testData bytes.Buffer
+	testData.WriteString("record 42 of the similar WAL segment payload 294")
+	testCompressor(Compressors[zstd.DictionaryAlgorithmName], testData, t)
+
+	// frames without the dictionary are still readable
+	var plainData bytes.Buffer
+	plainData.WriteString("plain zstd frame")
+	testCompressor(zstd.Compressor{}, plainData, t)
+}
+
+func TestZstdDictionaryDecompression_MissingDictionary(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := (&zstd.DictCompressor{Dictionary: trainTestDictionary(t)}).NewWriter(&compressed)
+	_, err := io.WriteString(writer, "record 1 of the similar WAL segment payload 7")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	_, err = (&zstd.DictDecompressor{}).Decompress(&compressed)
+	assert.Error(t, err)
+}
+
+func TestFrameDictionaryID(t *testing.T) {
+	dictionary := trainTestDictionary(t)
+	id, err := zstd.DictionaryID(dictionary)
+	assert.NoError(t, err)
+
+	var compressed bytes.Buffer
+	writer := (&zstd.DictCompressor{Dictionary: dictionary}).NewWriter(&compressed)
+	_, err = io.WriteString(writer, "payload")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, id, zstd.FrameDictionaryID(compressed.Bytes()))
+
+	compressed.Reset()
+	writer = zstd.Compressor{}.NewWriter(&compressed)
+	_, err = io.WriteString(writer, "payload")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, uint32(0), zstd.FrameDictionaryID(compressed.Bytes()))
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package compression
+
+import "github.com/wal-g/wal-g/internal/compression/zstd"
+
+// RegisterZstdLong enables the zstd long-distance matching with the window of 2^windowLog bytes,
+// the zstd decompressors are set to accept such windows
+func RegisterZstdLong(windowLog int) error {
+	if err := zstd.ValidateWindowLog(windowLog); err != nil {
+		return err
+	}
+	Compressors[zstd.AlgorithmName] = zstd.Compressor{WindowLog: windowLog}
+
+	// the frames compressed before with the default window must stay readable
+	windowLogMax := windowLog
+	if windowLogMax < zstd.DefaultWindowLogMax {
+		windowLogMax = zstd.DefaultWindowLogMax
+	}
+	for i := range Decompressors {
+		switch decompressor := Decompressors[i].(type) {
+		case zstd.Decompressor:
+			Decompressors[i] = zstd.Decompressor{WindowLogMax: windowLogMax}
+		case *zstd.DictDecompressor:
+			decompressor.WindowLogMax = windowLogMax
+		}
+	}
+	return nil
+}
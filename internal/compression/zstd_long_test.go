@@ -0,0 +1,78 @@
+//go:build !windows
+// +build !windows
+
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+func compressZstd(t *testing.T, compressor Compressor, data []byte) []byte {
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return compressed.Bytes()
+}
+
+func TestZstdLongCompression(t *testing.T) {
+	decompressors := append([]Decompressor{}, Decompressors...)
+	defer func() {
+		Decompressors = decompressors
+		Compressors[zstd.AlgorithmName] = zstd.Compressor{}
+	}()
+
+	assert.NoError(t, RegisterZstdLong(28))
+	assert.Equal(t, zstd.Compressor{WindowLog: 28}, Compressors[zstd.AlgorithmName])
+	assert.Equal(t, zstd.Decompressor{WindowLogMax: 28}, GetDecompressorByCompressor(Compressors[zstd.AlgorithmName]))
+
+	var testData bytes.Buffer
+	_, err := io.Copy(&testData, io.LimitReader(NewBiasedRandomReader(), 1<<20))
+	assert.NoError(t, err)
+	testCompressor(Compressors[zstd.AlgorithmName], testData, t)
+
+	// the frame window exceeds the default decoder limit
+	compressed := compressZstd(t, Compressors[zstd.AlgorithmName], testData.Bytes())
+	reader, err := zstd.Decompressor{}.Decompress(bytes.NewReader(compressed))
+	assert.NoError(t, err)
+	_, err = io.Copy(io.Discard, reader)
+	assert.Error(t, err)
+
+	// the frames with the default window are still readable
+	testCompressor(zstd.Compressor{}, testData, t)
+}
+
+func TestZstdLongDecompression_KeepsDefaultWindowLimit(t *testing.T) {
+	decompressors := append([]Decompressor{}, Decompressors...)
+	defer func() {
+		Decompressors = decompressors
+		Compressors[zstd.AlgorithmName] = zstd.Compressor{}
+	}()
+
+	assert.NoError(t, RegisterZstdLong(zstd.MinWindowLog))
+	assert.Equal(t, zstd.Decompressor{WindowLogMax: zstd.DefaultWindowLogMax},
+		GetDecompressorByCompressor(Compressors[zstd.AlgorithmName]))
+}
+
+func TestZstdLongDecompression_Truncated(t *testing.T) {
+	data := bytes.Repeat([]byte("repetitive backup stream "), 1<<12)
+	compressed := compressZstd(t, zstd.Compressor{WindowLog: 20}, data)
+
+	reader, err := zstd.Decompressor{WindowLogMax: 20}.Decompress(bytes.NewReader(compressed[:len(compressed)-4]))
+	assert.NoError(t, err)
+	_, err = io.Copy(io.Discard, reader)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestRegisterZstdLong_InvalidWindowLog(t *testing.T) {
+	for _, windowLog := range []int{0, zstd.MinWindowLog - 1, zstd.MaxWindowLog() + 1} {
+		assert.Error(t, RegisterZstdLong(windowLog))
+	}
+	assert.Equal(t, zstd.Compressor{}, Compressors[zstd.AlgorithmName])
+}
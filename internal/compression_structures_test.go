@@ -11,6 +11,7 @@ import (
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/none"
 	"github.com/wal-g/wal-g/testtools"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -151,7 +152,11 @@ func testCompressAndEncryptErrorPropagation(compressor compression.Compressor, t
 }
 
 func TestCompressAndEncryptErrorPropagation(t *testing.T) {
-	for _, compressor := range compression.Compressors {
+	for algorithm, compressor := range compression.Compressors {
+		if algorithm == none.AlgorithmName {
+			// the stored stream is not longer than the input, so the delayed error is never reached
+			continue
+		}
 		go testCompressAndEncryptErrorPropagation(compressor, t)
 	}
 }
@@ -31,14 +31,26 @@ const (
 	UploadDiskConcurrencySetting = "WALG_UPLOAD_DISK_CONCURRENCY"
 	UploadQueueSetting           = "WALG_UPLOAD_QUEUE"
 	SentinelUserDataSetting      = "WALG_SENTINEL_USER_DATA"
+	BackupLabelsSetting          = "WALG_BACKUP_LABELS"
 	PreventWalOverwriteSetting   = "WALG_PREVENT_WAL_OVERWRITE"
 	UploadWalMetadata            = "WALG_UPLOAD_WAL_METADATA"
 	DeltaMaxStepsSetting         = "WALG_DELTA_MAX_STEPS"
 	DeltaOriginSetting           = "WALG_DELTA_ORIGIN"
 	CompressionMethodSetting     = "WALG_COMPRESSION_METHOD"
+	CompressionBlockSizeSetting  = "WALG_COMPRESSION_BLOCK_SIZE"
+	CompressionBlocksSetting     = "WALG_COMPRESSION_BLOCKS_IN_FLIGHT"
+	CompressionLevelSetting      = "WALG_COMPRESSION_LEVEL"
+	CompressionCandidatesSetting = "WALG_COMPRESSION_ADAPTIVE_CANDIDATES"
+	CompressionSampleSizeSetting = "WALG_COMPRESSION_ADAPTIVE_SAMPLE_SIZE"
+	ZstdDictPathSetting          = "WALG_ZSTD_DICT_PATH"
+	ZstdLongSetting              = "WALG_ZSTD_LONG"
+	Lz4BlockChecksumSetting      = "WALG_LZ4_BLOCK_CHECKSUM"
+	DownloadRangeResumesSetting  = "WALG_DOWNLOAD_RANGE_RESUMES"
+	StorageOpTimeoutSetting      = "WALG_STORAGE_OP_TIMEOUT"
 	StoragePrefixSetting         = "WALG_STORAGE_PREFIX"
 	DiskRateLimitSetting         = "WALG_DISK_RATE_LIMIT"
 	NetworkRateLimitSetting      = "WALG_NETWORK_RATE_LIMIT"
+	NetworkRateLimitBurstSetting = "WALG_NETWORK_RATE_LIMIT_BURST"
 	UseWalDeltaSetting           = "WALG_USE_WAL_DELTA"
 	UseReverseUnpackSetting      = "WALG_USE_REVERSE_UNPACK"
 	SkipRedundantTarsSetting     = "WALG_SKIP_REDUNDANT_TARS"
@@ -53,15 +65,45 @@ const (
 	LogLevelSetting              = "WALG_LOG_LEVEL"
 	TarSizeThresholdSetting      = "WALG_TAR_SIZE_THRESHOLD"
 	TarDisableFsyncSetting       = "WALG_TAR_DISABLE_FSYNC"
+	TarFsyncModeSetting          = "WALG_TAR_FSYNC_MODE"
+	TarFsyncModeOverridesSetting = "WALG_TAR_FSYNC_MODE_OVERRIDES"
+	TarFsyncConcurrencySetting   = "WALG_TAR_FSYNC_CONCURRENCY"
+	TarExtractConcurrencySetting = "WALG_TAR_EXTRACT_CONCURRENCY"
+	VerifyFileChecksumsSetting   = "WALG_VERIFY_EXTRACTED_CHECKSUMS"
+	RestoreXattrsSetting         = "WALG_RESTORE_XATTRS"
+	RestoreXattrsStrictSetting   = "WALG_RESTORE_XATTRS_STRICT"
+	RestoreChownSetting          = "WALG_RESTORE_CHOWN"
+	RestoreChownStrictSetting    = "WALG_RESTORE_CHOWN_STRICT"
+	RestoreChownUIDMapSetting    = "WALG_RESTORE_CHOWN_UID_MAP"
+	RestoreChownGIDMapSetting    = "WALG_RESTORE_CHOWN_GID_MAP"
+	RestoreSeedDirSetting        = "WALG_RESTORE_SEED_DIRECTORY"
+	RestoreSharedBaseSetting     = "WALG_RESTORE_SHARED_BASE"
+	RestoreSharedFilesSetting    = "WALG_RESTORE_SHARED_FILES"
+	RestoreCopyBufferSetting     = "WALG_RESTORE_COPY_BUFFER_BYTES"
+	RestoreForceRewriteSetting   = "WALG_RESTORE_FORCE_REWRITE"
+	RestoreExcludeSetting        = "WALG_RESTORE_EXCLUDE"
+	RestoreFileRetriesSetting    = "WALG_RESTORE_FILE_RETRIES"
+	RestoreRangeThresholdSetting = "WALG_RESTORE_RANGE_THRESHOLD_BYTES"
+	RestoreRangeStreamsSetting   = "WALG_RESTORE_RANGE_STREAMS"
+	RestoreAtomicWritesSetting   = "WALG_RESTORE_ATOMIC_WRITES"
+	RestoreJournalSetting        = "WALG_RESTORE_JOURNAL"
+	RestoreDiskHeadroomSetting   = "WALG_RESTORE_DISK_HEADROOM_BYTES"
+	RestoreInOrderSetting        = "WALG_RESTORE_DETERMINISTIC_ORDER"
+	RestoreContinueOnErrSetting  = "WALG_RESTORE_CONTINUE_ON_ERROR"
 	CseKmsIDSetting              = "WALG_CSE_KMS_ID"
 	CseKmsRegionSetting          = "WALG_CSE_KMS_REGION"
 	LibsodiumKeySetting          = "WALG_LIBSODIUM_KEY"
 	LibsodiumKeyPathSetting      = "WALG_LIBSODIUM_KEY_PATH"
 	LibsodiumKeyTransform        = "WALG_LIBSODIUM_KEY_TRANSFORM"
+	EnvelopeMasterKeysSetting    = "WALG_ENVELOPE_MASTER_KEYS"
+	EnvelopeKmsKeyIDsSetting     = "WALG_ENVELOPE_KMS_KEY_IDS"
+	EnvelopeCurrentKeyIDSetting  = "WALG_ENVELOPE_CURRENT_KEY_ID"
+	EnvelopeDetachedKeysSetting  = "WALG_ENVELOPE_DETACHED_KEYS"
 	GpgKeyIDSetting              = "GPG_KEY_ID"
 	PgpKeySetting                = "WALG_PGP_KEY"
 	PgpKeyPathSetting            = "WALG_PGP_KEY_PATH"
 	PgpKeyPassphraseSetting      = "WALG_PGP_KEY_PASSPHRASE"
+	PassphraseCommandSetting     = "WALG_PASSPHRASE_COMMAND"
 	PgDataSetting                = "PGDATA"
 	UserSetting                  = "USER" // TODO : do something with it
 	PgPortSetting                = "PGPORT"
@@ -77,15 +119,23 @@ const (
 	NameStreamRestoreCmd         = "WALG_STREAM_RESTORE_COMMAND"
 	MaxDelayedSegmentsCount      = "WALG_INTEGRITY_MAX_DELAYED_WALS"
 	PrefetchDir                  = "WALG_PREFETCH_DIR"
+	PrefetchDepth                = "WALG_PREFETCH_DEPTH"
+	PrefetchCacheSize            = "WALG_PREFETCH_CACHE_SIZE"
 	PgReadyRename                = "PG_READY_RENAME"
 	SerializerTypeSetting        = "WALG_SERIALIZER_TYPE"
 	StreamSplitterPartitions     = "WALG_STREAM_SPLITTER_PARTITIONS"
 	StreamSplitterBlockSize      = "WALG_STREAM_SPLITTER_BLOCK_SIZE"
+	StreamPartSizeSetting        = "WALG_STREAM_PART_SIZE"
+	StreamResumeTokenSetting     = "WALG_STREAM_RESUME_TOKEN"
 
 	MongoDBUriSetting               = "MONGODB_URI"
 	MongoDBLastWriteUpdateInterval  = "MONGODB_LAST_WRITE_UPDATE_INTERVAL"
+	MongoDBClusterName              = "MONGODB_CLUSTER_NAME"
+	MongoDBBackupKeyTemplate        = "MONGODB_BACKUP_KEY_TEMPLATE"
 	OplogArchiveAfterSize           = "OPLOG_ARCHIVE_AFTER_SIZE"
 	OplogArchiveTimeoutInterval     = "OPLOG_ARCHIVE_TIMEOUT_INTERVAL"
+	OplogArchiveDeduplication       = "OPLOG_ARCHIVE_DEDUPLICATION"
+	OplogArchiveUploadConcurrency   = "OPLOG_ARCHIVE_UPLOAD_CONCURRENCY"
 	OplogPITRDiscoveryInterval      = "OPLOG_PITR_DISCOVERY_INTERVAL"
 	OplogPushStatsEnabled           = "OPLOG_PUSH_STATS_ENABLED"
 	OplogPushStatsLoggingInterval   = "OPLOG_PUSH_STATS_LOGGING_INTERVAL"
@@ -96,6 +146,15 @@ const (
 	OplogReplayOplogAlwaysUpsert    = "OPLOG_REPLAY_OPLOG_ALWAYS_UPSERT"
 	OplogReplayOplogApplicationMode = "OPLOG_REPLAY_OPLOG_APPLICATION_MODE"
 	OplogReplayIgnoreErrorCodes     = "OPLOG_REPLAY_IGNORE_ERROR_CODES"
+	OplogReplayCacheSize            = "OPLOG_REPLAY_CACHE_SIZE"
+	OplogReplayCacheDir             = "OPLOG_REPLAY_CACHE_DIR"
+	OplogReplayPrefetch             = "OPLOG_REPLAY_PREFETCH"
+	DownloadMaxRetriesSetting       = "WALG_DOWNLOAD_MAX_RETRIES"
+	DownloadRetryBaseDelay          = "WALG_DOWNLOAD_RETRY_BASE_DELAY"
+	DownloadRetryMultiplier         = "WALG_DOWNLOAD_RETRY_MULTIPLIER"
+	DownloadRetryJitter             = "WALG_DOWNLOAD_RETRY_JITTER"
+	FailoverStoragesSetting         = "WALG_FAILOVER_STORAGES"
+	StorageListCacheTTL             = "WALG_STORAGE_LIST_CACHE_TTL"
 
 	MysqlDatasourceNameSetting = "WALG_MYSQL_DATASOURCE_NAME"
 	MysqlSslCaSetting          = "WALG_MYSQL_SSL_CA"
@@ -153,9 +212,17 @@ var (
 		UploadWalMetadata:            "NOMETADATA",
 		DeltaMaxStepsSetting:         "0",
 		CompressionMethodSetting:     "lz4",
+		Lz4BlockChecksumSetting:      "true",
 		UseWalDeltaSetting:           "false",
 		TarSizeThresholdSetting:      "1073741823", // (1 << 30) - 1
 		TarDisableFsyncSetting:       "false",
+		TarFsyncConcurrencySetting:   "4",
+		RestoreRangeStreamsSetting:   "4",
+		VerifyFileChecksumsSetting:   "false",
+		RestoreXattrsSetting:         "false",
+		RestoreXattrsStrictSetting:   "false",
+		RestoreChownSetting:          "false",
+		RestoreChownStrictSetting:    "false",
 		TotalBgUploadedLimit:         "32",
 		UseReverseUnpackSetting:      "false",
 		SkipRedundantTarsSetting:     "false",
@@ -173,10 +240,14 @@ var (
 		OplogPushStatsLoggingInterval:  "30s",
 		OplogPushStatsUpdateInterval:   "30s",
 		OplogPushWaitForBecomePrimary:  "false",
+		OplogArchiveDeduplication:      "false",
+		OplogArchiveUploadConcurrency:  "1",
 		OplogPushPrimaryCheckInterval:  "30s",
 		OplogArchiveTimeoutInterval:    "60s",
 		OplogArchiveAfterSize:          "16777216", // 32 << (10 * 2)
 		MongoDBLastWriteUpdateInterval: "3s",
+		OplogReplayCacheSize:           "0",
+		OplogReplayPrefetch:            "4",
 		StreamSplitterBlockSize:        "1048576",
 	}
 
@@ -211,26 +282,68 @@ var (
 		UploadDiskConcurrencySetting: true,
 		UploadQueueSetting:           true,
 		SentinelUserDataSetting:      true,
+		BackupLabelsSetting:          true,
 		PreventWalOverwriteSetting:   true,
 		UploadWalMetadata:            true,
 		DeltaMaxStepsSetting:         true,
 		DeltaOriginSetting:           true,
 		CompressionMethodSetting:     true,
+		CompressionBlockSizeSetting:  true,
+		CompressionBlocksSetting:     true,
+		CompressionLevelSetting:      true,
+		CompressionCandidatesSetting: true,
+		CompressionSampleSizeSetting: true,
+		ZstdDictPathSetting:          true,
+		ZstdLongSetting:              true,
+		Lz4BlockChecksumSetting:      true,
+		DownloadRangeResumesSetting:  true,
+		StorageOpTimeoutSetting:      true,
 		StoragePrefixSetting:         true,
 		DiskRateLimitSetting:         true,
 		NetworkRateLimitSetting:      true,
+		NetworkRateLimitBurstSetting: true,
 		UseWalDeltaSetting:           true,
 		LogLevelSetting:              true,
 		TarSizeThresholdSetting:      true,
 		TarDisableFsyncSetting:       true,
+		TarFsyncModeSetting:          true,
+		TarFsyncModeOverridesSetting: true,
+		TarFsyncConcurrencySetting:   true,
+		TarExtractConcurrencySetting: true,
+		VerifyFileChecksumsSetting:   true,
+		RestoreXattrsSetting:         true,
+		RestoreXattrsStrictSetting:   true,
+		RestoreChownSetting:          true,
+		RestoreChownStrictSetting:    true,
+		RestoreChownUIDMapSetting:    true,
+		RestoreChownGIDMapSetting:    true,
+		RestoreSeedDirSetting:        true,
+		RestoreSharedBaseSetting:     true,
+		RestoreSharedFilesSetting:    true,
+		RestoreCopyBufferSetting:     true,
+		RestoreForceRewriteSetting:   true,
+		RestoreExcludeSetting:        true,
+		RestoreFileRetriesSetting:    true,
+		RestoreRangeThresholdSetting: true,
+		RestoreRangeStreamsSetting:   true,
+		RestoreAtomicWritesSetting:   true,
+		RestoreJournalSetting:        true,
+		RestoreDiskHeadroomSetting:   true,
+		RestoreInOrderSetting:        true,
+		RestoreContinueOnErrSetting:  true,
 		"WALG_" + GpgKeyIDSetting:    true,
 		"WALE_" + GpgKeyIDSetting:    true,
 		PgpKeySetting:                true,
 		PgpKeyPathSetting:            true,
 		PgpKeyPassphraseSetting:      true,
+		PassphraseCommandSetting:     true,
 		LibsodiumKeySetting:          true,
 		LibsodiumKeyPathSetting:      true,
 		LibsodiumKeyTransform:        true,
+		EnvelopeMasterKeysSetting:    true,
+		EnvelopeKmsKeyIDsSetting:     true,
+		EnvelopeCurrentKeyIDSetting:  true,
+		EnvelopeDetachedKeysSetting:  true,
 		TotalBgUploadedLimit:         true,
 		NameStreamCreateCmd:          true,
 		NameStreamRestoreCmd:         true,
@@ -276,6 +389,7 @@ var (
 		"WALG_S3_SSE":                 true,
 		"WALG_S3_SSE_C":               true,
 		"WALG_S3_SSE_KMS_ID":          true,
+		"WALG_S3_SSE_KMS_KEY_ID":      true,
 		"WALG_CSE_KMS_ID":             true,
 		"WALG_CSE_KMS_REGION":         true,
 		"WALG_S3_MAX_PART_SIZE":       true,
@@ -295,6 +409,7 @@ var (
 		"AZURE_ENVIRONMENT_NAME":   true,
 		"WALG_AZURE_BUFFER_SIZE":   true,
 		"WALG_AZURE_MAX_BUFFERS":   true,
+		"WALG_AZURE_ACCESS_TIER":   true,
 
 		// GS
 		"WALG_GS_PREFIX":                 true,
@@ -336,6 +451,8 @@ var (
 		PgWalSize:         true,
 		"PGPASSFILE":      true,
 		PrefetchDir:       true,
+		PrefetchDepth:     true,
+		PrefetchCacheSize: true,
 		PgReadyRename:     true,
 		PgBackRestStanza:  true,
 	}
@@ -344,6 +461,8 @@ var (
 		// MongoDB
 		MongoDBUriSetting:              true,
 		MongoDBLastWriteUpdateInterval: true,
+		MongoDBClusterName:             true,
+		MongoDBBackupKeyTemplate:       true,
 		OplogArchiveTimeoutInterval:    true,
 		OplogArchiveAfterSize:          true,
 		OplogPushStatsEnabled:          true,
@@ -353,8 +472,21 @@ var (
 		OplogPushWaitForBecomePrimary:  true,
 		OplogPushPrimaryCheckInterval:  true,
 		OplogPITRDiscoveryInterval:     true,
+		OplogArchiveDeduplication:      true,
+		OplogArchiveUploadConcurrency:  true,
+		OplogReplayCacheSize:           true,
+		OplogReplayCacheDir:            true,
+		OplogReplayPrefetch:            true,
+		DownloadMaxRetriesSetting:      true,
+		DownloadRetryBaseDelay:         true,
+		DownloadRetryMultiplier:        true,
+		DownloadRetryJitter:            true,
+		FailoverStoragesSetting:        true,
+		StorageListCacheTTL:            true,
 		StreamSplitterBlockSize:        true,
 		StreamSplitterPartitions:       true,
+		StreamPartSizeSetting:          true,
+		StreamResumeTokenSetting:       true,
 	}
 
 	SQLServerAllowedSettings = map[string]bool{
@@ -379,6 +511,8 @@ var (
 		MysqlCheckGTIDs:            true,
 		StreamSplitterPartitions:   true,
 		StreamSplitterBlockSize:    true,
+		StreamPartSizeSetting:      true,
+		StreamResumeTokenSetting:   true,
 	}
 
 	RedisAllowedSettings = map[string]bool{
@@ -514,6 +648,9 @@ func Configure() {
 	}
 
 	configureLimiters()
+	configureZstdDictionary()
+	configureZstdLong()
+	configureLz4BlockChecksum()
 }
 
 // ConfigureAndRunDefaultWebServer configures and runs web server
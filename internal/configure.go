@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/wal-g/wal-g/internal/crypto/yckms"
@@ -18,6 +19,7 @@ import (
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/crypto/awskms"
+	"github.com/wal-g/wal-g/internal/crypto/envelope"
 	"github.com/wal-g/wal-g/internal/crypto/openpgp"
 	"github.com/wal-g/wal-g/internal/fsutil"
 	"github.com/wal-g/wal-g/internal/limiters"
@@ -68,6 +70,18 @@ func (err UnknownCompressionMethodError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type InvalidCompressionLevelError struct {
+	error
+}
+
+func newInvalidCompressionLevelError(err error) InvalidCompressionLevelError {
+	return InvalidCompressionLevelError{errors.Wrapf(err, "Invalid %s", CompressionLevelSetting)}
+}
+
+func (err InvalidCompressionLevelError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type UnsetRequiredSettingError struct {
 	error
 }
@@ -119,8 +133,12 @@ func configureLimiters() {
 
 	if viper.IsSet(NetworkRateLimitSetting) {
 		netLimit := viper.GetInt64(NetworkRateLimitSetting)
-		limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(netLimit),
-			int(netLimit+DefaultDataBurstRateLimit)) // Add 8 pages to possible bursts
+		netBurst := netLimit + DefaultDataBurstRateLimit // Add 8 pages to possible bursts
+		if burst := viper.GetInt64(NetworkRateLimitBurstSetting); burst > 0 {
+			netBurst = burst
+		}
+		// the limiter is shared by all the uploads and downloads, so it limits their aggregate rate
+		limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(netLimit), int(netBurst))
 	}
 }
 
@@ -130,6 +148,10 @@ func ConfigureFolder() (storage.Folder, error) {
 	if err != nil {
 		return nil, err
 	}
+	folder, err = ConfigureStorageOpTimeout(folder)
+	if err != nil {
+		return nil, err
+	}
 
 	return ConfigureStoragePrefix(folder), nil
 }
@@ -194,13 +216,132 @@ func GetPgSlotName() (pgSlotName string) {
 	return
 }
 
-// TODO : unit tests
+func configureZstdDictionary() {
+	dictionaryPath, ok := GetSetting(ZstdDictPathSetting)
+	if !ok {
+		return
+	}
+	err := compression.RegisterZstdDictionary(dictionaryPath)
+	tracelog.ErrorLogger.FatalfOnError("Failed to load zstd dictionary: %v", err)
+}
+
+func configureZstdLong() {
+	windowLog, ok := GetSetting(ZstdLongSetting)
+	if !ok {
+		return
+	}
+	err := configureZstdLongWindow(windowLog)
+	tracelog.ErrorLogger.FatalfOnError("Invalid "+ZstdLongSetting+" setting: %v", err)
+}
+
+func configureZstdLongWindow(windowLog string) error {
+	value, err := strconv.Atoi(windowLog)
+	if err != nil {
+		return errors.Errorf("zstd window log must be an integer, got '%s'", windowLog)
+	}
+	return compression.RegisterZstdLong(value)
+}
+
+func configureLz4BlockChecksum() {
+	compression.RegisterLz4BlockChecksum(viper.GetBool(Lz4BlockChecksumSetting))
+}
+
+// ConfigureCompressor uses the compression method set, the comma separated list of methods
+// is the fallback chain: the first method available in this build is used
 func ConfigureCompressor() (compression.Compressor, error) {
-	compressionMethod := viper.GetString(CompressionMethodSetting)
-	if _, ok := compression.Compressors[compressionMethod]; !ok {
-		return nil, newUnknownCompressionMethodError()
+	compressor, err := configureCompressionMethod(viper.GetString(CompressionMethodSetting))
+	if err != nil {
+		return nil, err
+	}
+	if parallelCompressor, ok := compressor.(compression.ParallelCompressor); ok {
+		return configureParallelCompressor(parallelCompressor)
+	}
+	if adaptiveCompressor, ok := compressor.(compression.AdaptiveCompressor); ok {
+		return configureAdaptiveCompressor(adaptiveCompressor)
+	}
+	if !viper.IsSet(CompressionLevelSetting) {
+		return compressor, nil
+	}
+	level, err := strconv.Atoi(viper.GetString(CompressionLevelSetting))
+	if err != nil {
+		return nil, newInvalidCompressionLevelError(err)
+	}
+	compressor, err = compression.WithLevel(compressor, level)
+	if err != nil {
+		return nil, newInvalidCompressionLevelError(err)
+	}
+	return compressor, nil
+}
+
+func configureParallelCompressor(compressor compression.ParallelCompressor) (compression.Compressor, error) {
+	if viper.IsSet(CompressionLevelSetting) {
+		level, err := strconv.Atoi(viper.GetString(CompressionLevelSetting))
+		if err != nil {
+			return nil, newInvalidCompressionLevelError(err)
+		}
+		base, err := compression.WithLevel(compressor.Base, level)
+		if err != nil {
+			return nil, newInvalidCompressionLevelError(err)
+		}
+		compressor.Base = base
+	}
+	if viper.IsSet(CompressionBlockSizeSetting) {
+		blockSize := viper.GetInt(CompressionBlockSizeSetting)
+		if blockSize < 1 || blockSize > compression.MaxParallelBlockSize {
+			return nil, errors.Errorf("%s must be in range [1, %d], but %d is given",
+				CompressionBlockSizeSetting, compression.MaxParallelBlockSize, blockSize)
+		}
+		compressor.BlockSize = blockSize
+	}
+	if viper.IsSet(CompressionBlocksSetting) {
+		blocksInFlight, err := GetMaxConcurrency(CompressionBlocksSetting)
+		if err != nil {
+			return nil, err
+		}
+		compressor.BlocksInFlight = blocksInFlight
+	}
+	return compressor, nil
+}
+
+// configureAdaptiveCompressor sets the candidates and the sample size, the compression level is not applied
+// since the candidates have the different level ranges
+func configureAdaptiveCompressor(compressor compression.AdaptiveCompressor) (compression.Compressor, error) {
+	if viper.IsSet(CompressionCandidatesSetting) {
+		candidates := compression.ParseAlgorithmList(viper.GetString(CompressionCandidatesSetting))
+		compressor.Candidates = make([]compression.Compressor, 0, len(candidates))
+		for _, candidate := range candidates {
+			candidateCompressor, ok := compression.Compressors[candidate]
+			if !ok || candidate == compression.AdaptiveAlgorithmName {
+				return nil, errors.Errorf("%s: compression method '%s' is not available, supported methods are: %v",
+					CompressionCandidatesSetting, candidate, compression.CompressingAlgorithms)
+			}
+			compressor.Candidates = append(compressor.Candidates, candidateCompressor)
+		}
 	}
-	return compression.Compressors[compressionMethod], nil
+	if viper.IsSet(CompressionSampleSizeSetting) {
+		sampleSize := viper.GetInt(CompressionSampleSizeSetting)
+		if sampleSize < 1 {
+			return nil, errors.Errorf("%s must be positive, but %d is given", CompressionSampleSizeSetting, sampleSize)
+		}
+		compressor.SampleSize = sampleSize
+	}
+	return compressor, nil
+}
+
+func configureCompressionMethod(compressionMethod string) (compression.Compressor, error) {
+	if !strings.Contains(compressionMethod, ",") {
+		compressor, ok := compression.Compressors[compressionMethod]
+		if !ok {
+			return nil, newUnknownCompressionMethodError()
+		}
+		return compressor, nil
+	}
+	fallback, err := compression.NewFallbackCompressor(compression.ParseAlgorithmList(compressionMethod))
+	if err != nil {
+		return nil, UnknownCompressionMethodError{err}
+	}
+	// the chosen compressor itself is returned, so it keeps the optional interfaces like LeveledCompressor
+	return fallback.Compressor, nil
 }
 
 func ConfigureLogging() error {
@@ -272,6 +413,16 @@ func ConfigureSplitUploader() (uploader UploaderProvider, err error) {
 		return nil, errors.Wrap(err, "failed to configure compression")
 	}
 
+	// resumable upload is enabled by the part size
+	if viper.IsSet(StreamPartSizeSetting) {
+		var partSize = viper.GetSizeInBytes(StreamPartSizeSetting)
+		if partSize == 0 {
+			return nil, errors.Errorf("%s should be greater than zero", StreamPartSizeSetting)
+		}
+		var resumeToken = viper.GetString(StreamResumeTokenSetting)
+		return NewResumableStreamUploader(compressor, folder, int(partSize), resumeToken), nil
+	}
+
 	var partitions = viper.GetInt(StreamSplitterPartitions)
 	var blockSize = viper.GetSizeInBytes(StreamSplitterBlockSize)
 
@@ -282,23 +433,31 @@ func ConfigureSplitUploader() (uploader UploaderProvider, err error) {
 // ConfigureCrypter uses environment variables to create and configure a crypter.
 // In case no configuration in environment variables found, return `<nil>` value.
 func ConfigureCrypter() crypto.Crypter {
-	loadPassphrase := func() (string, bool) {
-		return GetSetting(PgpKeyPassphraseSetting)
-	}
-
-	// key can be either private (for download) or public (for upload)
+	// key can be either private (for download) or public (for upload),
+	// the passphrase is loaded for the private key only
 	if viper.IsSet(PgpKeySetting) {
-		return openpgp.CrypterFromKey(viper.GetString(PgpKeySetting), loadPassphrase)
+		armoredKey := viper.GetString(PgpKeySetting)
+		return configurePgpCrypter(PgpKeySetting+"="+armoredKey,
+			func(loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
+				return openpgp.CrypterFromKey(armoredKey, loadPassphrase)
+			})
 	}
 
 	// key can be either private (for download) or public (for upload)
 	if viper.IsSet(PgpKeyPathSetting) {
-		return openpgp.CrypterFromKeyPath(viper.GetString(PgpKeyPathSetting), loadPassphrase)
+		armoredKeyPath := viper.GetString(PgpKeyPathSetting)
+		return configurePgpCrypter(PgpKeyPathSetting+"="+armoredKeyPath,
+			func(loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
+				return openpgp.CrypterFromKeyPath(armoredKeyPath, loadPassphrase)
+			})
 	}
 
 	if keyRingID, ok := getWaleCompatibleSetting(GpgKeyIDSetting); ok {
 		tracelog.WarningLogger.Printf(DeprecatedExternalGpgMessage)
-		return openpgp.CrypterFromKeyRingID(keyRingID, loadPassphrase)
+		return configurePgpCrypter(GpgKeyIDSetting+"="+keyRingID,
+			func(loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
+				return openpgp.CrypterFromKeyRingID(keyRingID, loadPassphrase)
+			})
 	}
 
 	if viper.IsSet(CseKmsIDSetting) {
@@ -309,6 +468,10 @@ func ConfigureCrypter() crypto.Crypter {
 		return yckms.YcCrypterFromKeyIDAndCredential(viper.GetString(YcKmsKeyIDSetting), viper.GetString(YcSaKeyFileSetting))
 	}
 
+	if crypter := configureEnvelopeCrypter(); crypter != nil {
+		return crypter
+	}
+
 	if crypter := configureLibsodiumCrypter(); crypter != nil {
 		return crypter
 	}
@@ -316,10 +479,71 @@ func ConfigureCrypter() crypto.Crypter {
 	return nil
 }
 
+// configureEnvelopeCrypter builds envelope crypter from local and AWS KMS master keys,
+// returns nil if no master keys are configured
+func configureEnvelopeCrypter() crypto.Crypter {
+	if !viper.IsSet(EnvelopeMasterKeysSetting) && !viper.IsSet(EnvelopeKmsKeyIDsSetting) {
+		return nil
+	}
+
+	masterKeys, err := envelope.ParseLocalMasterKeys(viper.GetString(EnvelopeMasterKeysSetting))
+	tracelog.ErrorLogger.FatalfOnError("Can't parse envelope master keys: %v", err)
+	for _, keyID := range strings.Split(viper.GetString(EnvelopeKmsKeyIDsSetting), ",") {
+		if keyID = strings.TrimSpace(keyID); keyID != "" {
+			kmsMasterKey, err := envelope.NewAwsKmsMasterKey(keyID, viper.GetString(CseKmsRegionSetting))
+			tracelog.ErrorLogger.FatalfOnError("Can't configure envelope KMS master key: %v", err)
+			masterKeys = append(masterKeys, kmsMasterKey)
+		}
+	}
+
+	crypter, err := envelope.NewCrypter(viper.GetString(EnvelopeCurrentKeyIDSetting), masterKeys)
+	tracelog.ErrorLogger.FatalfOnError("Can't configure envelope crypter: %v", err)
+
+	// the key store is attached even if the keys are not detached, so the objects encrypted with detached keys
+	// remain readable after the setting is turned off
+	detachKeys := viper.GetBool(EnvelopeDetachedKeysSetting)
+	folder, err := ConfigureFolder()
+	if err != nil {
+		if detachKeys {
+			tracelog.ErrorLogger.FatalfOnError("Can't configure envelope key store: %v", err)
+		}
+		tracelog.DebugLogger.Printf("Envelope key store is not configured: %v", err)
+		return crypter
+	}
+	crypter.SetKeyStore(envelope.NewKeyStore(folder.GetSubFolder(envelope.KeyStorePath)), detachKeys)
+	return crypter
+}
+
 func GetMaxDownloadConcurrency() (int, error) {
 	return GetMaxConcurrency(DownloadConcurrencySetting)
 }
 
+// GetPrefetchDepth returns the number of WAL segments prefetched ahead of the fetched one,
+// the download concurrency is used if it is not set
+func GetPrefetchDepth() (int, error) {
+	if !viper.IsSet(PrefetchDepth) {
+		return GetMaxDownloadConcurrency()
+	}
+	return getNonNegativeIntSetting(PrefetchDepth)
+}
+
+// GetPrefetchCacheSize returns the maximal number of WAL segments kept in the prefetch directory, 0 means unlimited
+func GetPrefetchCacheSize() (int, error) {
+	return getNonNegativeIntSetting(PrefetchCacheSize)
+}
+
+func getNonNegativeIntSetting(setting string) (int, error) {
+	valueStr := viper.GetString(setting)
+	if valueStr == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("non-negative integer expected for %s setting but given '%s'", setting, valueStr)
+	}
+	return value, nil
+}
+
 func GetMaxUploadConcurrency() (int, error) {
 	return GetMaxConcurrency(UploadConcurrencySetting)
 }
@@ -442,6 +666,18 @@ func GetBoolSettingDefault(setting string, def bool) (bool, error) {
 	return strconv.ParseBool(val)
 }
 
+func GetInt64SettingDefault(setting string, def int64) (int64, error) {
+	val, ok := GetSetting(setting)
+	if !ok {
+		return def, nil
+	}
+	intVal, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("integer expected for %s setting but given '%s': %w", setting, val, err)
+	}
+	return intVal, nil
+}
+
 func GetBoolSetting(setting string) (val bool, ok bool, err error) {
 	valstr, ok := GetSetting(setting)
 	if !ok {
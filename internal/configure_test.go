@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
 )
 
 func TestGetMaxConcurrency_InvalidKey(t *testing.T) {
@@ -170,6 +171,98 @@ func prepareDataFolder(t *testing.T, name string) string {
 	return dir
 }
 
+func TestConfigureCompressor_Level(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "lz4")
+	viper.Set(internal.CompressionLevelSetting, "9")
+	compressor, err := internal.ConfigureCompressor()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "lz4", compressor.FileExtension())
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_LevelOutOfRange(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "lz4")
+	viper.Set(internal.CompressionLevelSetting, "19")
+	_, err := internal.ConfigureCompressor()
+
+	assert.IsType(t, internal.InvalidCompressionLevelError{}, err)
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_LevelNotSupported(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "lzma")
+	viper.Set(internal.CompressionLevelSetting, "5")
+	_, err := internal.ConfigureCompressor()
+
+	assert.IsType(t, internal.InvalidCompressionLevelError{}, err)
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_FallbackChain(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "missing, lzma, lz4")
+	compressor, err := internal.ConfigureCompressor()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "lzma", compressor.FileExtension())
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_FallbackChainWithLevel(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "missing,lz4")
+	viper.Set(internal.CompressionLevelSetting, "5")
+	compressor, err := internal.ConfigureCompressor()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "lz4", compressor.FileExtension())
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_SingleMissingMethod(t *testing.T) {
+	for _, method := range []string{"missing", "missing,absent"} {
+		viper.Set(internal.CompressionMethodSetting, method)
+		_, err := internal.ConfigureCompressor()
+
+		assert.IsType(t, internal.UnknownCompressionMethodError{}, err)
+	}
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_Parallel(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "parallel-lz4")
+	viper.Set(internal.CompressionLevelSetting, "3")
+	viper.Set(internal.CompressionBlockSizeSetting, "4096")
+	viper.Set(internal.CompressionBlocksSetting, "2")
+	compressor, err := internal.ConfigureCompressor()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pz", compressor.FileExtension())
+	parallelCompressor := compressor.(compression.ParallelCompressor)
+	assert.Equal(t, 4096, parallelCompressor.BlockSize)
+	assert.Equal(t, 2, parallelCompressor.BlocksInFlight)
+	assert.Equal(t, "lz4", parallelCompressor.Base.FileExtension())
+	resetToDefaults()
+}
+
+func TestConfigureCompressor_Adaptive(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "adaptive")
+	viper.Set(internal.CompressionCandidatesSetting, "lzma")
+	viper.Set(internal.CompressionSampleSizeSetting, "4096")
+	compressor, err := internal.ConfigureCompressor()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "adz", compressor.FileExtension())
+	adaptiveCompressor := compressor.(compression.AdaptiveCompressor)
+	assert.Equal(t, 4096, adaptiveCompressor.SampleSize)
+	assert.Len(t, adaptiveCompressor.Candidates, 1)
+	assert.Equal(t, "lzma", adaptiveCompressor.Candidates[0].FileExtension())
+
+	viper.Set(internal.CompressionCandidatesSetting, "lz4,adaptive")
+	_, err = internal.ConfigureCompressor()
+	assert.Error(t, err)
+	resetToDefaults()
+}
+
 func resetToDefaults() {
 	viper.Reset()
 	internal.ConfigureSettings(internal.PG)
@@ -0,0 +1,270 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/minio/sio"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+)
+
+const (
+	// DataKeyLen is the length of the per-object data keys and of the local master keys
+	DataKeyLen  = 32
+	maxFieldLen = math.MaxUint16
+)
+
+// headerMagic starts each encrypted object, it is followed by the master key id and the wrapped data key,
+// both prefixed with big-endian uint16 length
+var headerMagic = []byte("WALGENV1")
+
+// detachedHeaderMagic starts the objects encrypted with the detached data key,
+// it is followed by the id of the data key in the key store prefixed with big-endian uint16 length
+var detachedHeaderMagic = []byte("WALGENV2")
+
+const dataKeyIDLen = 16
+
+type UnknownMasterKeyError struct {
+	error
+}
+
+func NewUnknownMasterKeyError(id string) UnknownMasterKeyError {
+	return UnknownMasterKeyError{errors.Errorf("object is encrypted with unknown envelope master key '%s'", id)}
+}
+
+func (err UnknownMasterKeyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// Crypter encrypts each object with the new random data key wrapped by the current master key.
+// The other master keys are used only for decryption, so the master key can be rotated
+// without re-encrypting the objects.
+// If the data keys are detached, the wrapped data key is stored in the key store once per crypter
+// and the objects reference it by id, so the keys can be rewrapped with the new master key in place.
+type Crypter struct {
+	currentKey MasterKey
+	masterKeys map[string]MasterKey
+
+	keyStore   *KeyStore
+	detachKeys bool
+
+	mutex sync.Mutex
+	// sessionKeyID identifies the detached data key encrypting the objects of this crypter
+	sessionKeyID string
+	// dataKeys caches the unwrapped detached data keys by id
+	dataKeys map[string][]byte
+}
+
+var _ crypto.Crypter = &Crypter{}
+
+// NewCrypter builds crypter with the given master keys, the key with currentKeyID wraps the new data keys,
+// the first key is used if currentKeyID is empty
+func NewCrypter(currentKeyID string, masterKeys []MasterKey) (*Crypter, error) {
+	if len(masterKeys) == 0 {
+		return nil, NewInvalidMasterKeyError("no master keys configured")
+	}
+	crypter := &Crypter{masterKeys: make(map[string]MasterKey, len(masterKeys)), dataKeys: make(map[string][]byte)}
+	for _, masterKey := range masterKeys {
+		if _, ok := crypter.masterKeys[masterKey.ID()]; ok {
+			return nil, NewInvalidMasterKeyError(fmt.Sprintf("duplicate key id '%s'", masterKey.ID()))
+		}
+		crypter.masterKeys[masterKey.ID()] = masterKey
+	}
+
+	if currentKeyID == "" {
+		currentKeyID = masterKeys[0].ID()
+	}
+	currentKey, ok := crypter.masterKeys[currentKeyID]
+	if !ok {
+		return nil, NewInvalidMasterKeyError(fmt.Sprintf("current key '%s' is not configured", currentKeyID))
+	}
+	crypter.currentKey = currentKey
+	return crypter, nil
+}
+
+// SetKeyStore sets the store of the detached data keys, the new objects are encrypted with detached keys if detachKeys
+// is set. The objects encrypted with detached keys can not be decrypted without the key store.
+func (crypter *Crypter) SetKeyStore(keyStore *KeyStore, detachKeys bool) {
+	crypter.keyStore = keyStore
+	crypter.detachKeys = detachKeys
+}
+
+func (crypter *Crypter) Name() string {
+	return "Envelope/Crypter"
+}
+
+// Encrypt writes the object header and creates encryption writer with the new data key
+func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	if crypter.keyStore != nil && crypter.detachKeys {
+		return crypter.encryptWithDetachedKey(writer)
+	}
+
+	dataKey := make([]byte, DataKeyLen)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, errors.Wrap(err, "can not generate data key")
+	}
+	wrappedKey, err := crypter.currentKey.Wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) > maxFieldLen {
+		return nil, errors.Errorf("wrapped data key is too long: %d bytes", len(wrappedKey))
+	}
+
+	var header bytes.Buffer
+	header.Write(headerMagic)
+	writeField(&header, []byte(crypter.currentKey.ID()))
+	writeField(&header, wrappedKey)
+	if _, err := writer.Write(header.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "can not write envelope header")
+	}
+
+	// the underlying writer is closed by the caller
+	return sio.EncryptWriter(struct{ io.Writer }{writer}, sio.Config{Key: dataKey})
+}
+
+// Decrypt reads the object header, unwraps the data key with the master key it was wrapped with
+// and creates decrypted reader
+func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	magic := make([]byte, len(headerMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, errors.Wrap(err, "can not read envelope header")
+	}
+	if bytes.Equal(magic, detachedHeaderMagic) {
+		return crypter.decryptWithDetachedKey(reader)
+	}
+	if !bytes.Equal(magic, headerMagic) {
+		return nil, errors.New("object is not encrypted with envelope crypter")
+	}
+	keyID, err := readField(reader)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := readField(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, ok := crypter.masterKeys[string(keyID)]
+	if !ok {
+		return nil, NewUnknownMasterKeyError(string(keyID))
+	}
+	dataKey, err := masterKey.Unwrap(wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return sio.DecryptReader(reader, sio.Config{Key: dataKey})
+}
+
+// encryptWithDetachedKey writes the header referencing the session data key, the key is created on the first use
+func (crypter *Crypter) encryptWithDetachedKey(writer io.Writer) (io.WriteCloser, error) {
+	keyID, dataKey, err := crypter.sessionDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.Write(detachedHeaderMagic)
+	writeField(&header, []byte(keyID))
+	if _, err := writer.Write(header.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "can not write envelope header")
+	}
+
+	// each stream is encrypted with the random nonce, so the data key is safely shared between the objects
+	return sio.EncryptWriter(struct{ io.Writer }{writer}, sio.Config{Key: dataKey})
+}
+
+func (crypter *Crypter) sessionDataKey() (string, []byte, error) {
+	crypter.mutex.Lock()
+	defer crypter.mutex.Unlock()
+	if crypter.sessionKeyID != "" {
+		return crypter.sessionKeyID, crypter.dataKeys[crypter.sessionKeyID], nil
+	}
+
+	rawID := make([]byte, dataKeyIDLen)
+	dataKey := make([]byte, DataKeyLen)
+	if _, err := rand.Read(rawID); err != nil {
+		return "", nil, errors.Wrap(err, "can not generate data key id")
+	}
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", nil, errors.Wrap(err, "can not generate data key")
+	}
+	wrappedKey, err := crypter.currentKey.Wrap(dataKey)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(wrappedKey) > maxFieldLen {
+		return "", nil, errors.Errorf("wrapped data key is too long: %d bytes", len(wrappedKey))
+	}
+	keyID := hex.EncodeToString(rawID)
+	err = crypter.keyStore.put(keyID, wrappedDataKey{masterKeyID: crypter.currentKey.ID(), wrappedKey: wrappedKey})
+	if err != nil {
+		return "", nil, err
+	}
+	crypter.sessionKeyID = keyID
+	crypter.dataKeys[keyID] = dataKey
+	return keyID, dataKey, nil
+}
+
+func (crypter *Crypter) decryptWithDetachedKey(reader io.Reader) (io.Reader, error) {
+	keyID, err := readField(reader)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := crypter.detachedDataKey(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	return sio.DecryptReader(reader, sio.Config{Key: dataKey})
+}
+
+func (crypter *Crypter) detachedDataKey(keyID string) ([]byte, error) {
+	crypter.mutex.Lock()
+	defer crypter.mutex.Unlock()
+	if dataKey, ok := crypter.dataKeys[keyID]; ok {
+		return dataKey, nil
+	}
+	if crypter.keyStore == nil {
+		return nil, errors.Errorf("object is encrypted with detached data key '%s', but key store is not configured", keyID)
+	}
+
+	key, err := crypter.keyStore.get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	masterKey, ok := crypter.masterKeys[key.masterKeyID]
+	if !ok {
+		return nil, NewUnknownMasterKeyError(key.masterKeyID)
+	}
+	dataKey, err := masterKey.Unwrap(key.wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	crypter.dataKeys[keyID] = dataKey
+	return dataKey, nil
+}
+
+func writeField(buffer *bytes.Buffer, field []byte) {
+	_ = binary.Write(buffer, binary.BigEndian, uint16(len(field)))
+	buffer.Write(field)
+}
+
+func readField(reader io.Reader) ([]byte, error) {
+	var fieldLen uint16
+	if err := binary.Read(reader, binary.BigEndian, &fieldLen); err != nil {
+		return nil, errors.Wrap(err, "can not read envelope header")
+	}
+	field := make([]byte, fieldLen)
+	if _, err := io.ReadFull(reader, field); err != nil {
+		return nil, errors.Wrap(err, "can not read envelope header")
+	}
+	return field, nil
+}
@@ -0,0 +1,113 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const someSecret = "so very secret thingy"
+
+func newTestMasterKey(t *testing.T, id string, fill byte) MasterKey {
+	masterKey, err := NewLocalMasterKey(id, bytes.Repeat([]byte{fill}, DataKeyLen))
+	assert.NoError(t, err)
+	return masterKey
+}
+
+func encrypt(t *testing.T, crypter *Crypter, data string) []byte {
+	var buffer bytes.Buffer
+	writer, err := crypter.Encrypt(&buffer)
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func decrypt(crypter *Crypter, data []byte) (string, error) {
+	reader, err := crypter.Decrypt(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := ioutil.ReadAll(reader)
+	return string(decrypted), err
+}
+
+func TestEncryptionCycle(t *testing.T) {
+	crypter, err := NewCrypter("", []MasterKey{newTestMasterKey(t, "first", 1)})
+	assert.NoError(t, err)
+
+	first, second := encrypt(t, crypter, someSecret), encrypt(t, crypter, someSecret)
+	assert.NotEqual(t, first, second, "each object must be encrypted with its own data key")
+
+	decrypted, err := decrypt(crypter, first)
+	assert.NoError(t, err)
+	assert.Equal(t, someSecret, decrypted)
+}
+
+func TestMasterKeyRotation(t *testing.T) {
+	oldKey, newKey := newTestMasterKey(t, "old", 1), newTestMasterKey(t, "new", 2)
+	oldCrypter, err := NewCrypter("", []MasterKey{oldKey})
+	assert.NoError(t, err)
+	encryptedWithOld := encrypt(t, oldCrypter, someSecret)
+
+	rotatedCrypter, err := NewCrypter("new", []MasterKey{oldKey, newKey})
+	assert.NoError(t, err)
+	encryptedWithNew := encrypt(t, rotatedCrypter, someSecret)
+
+	for _, encrypted := range [][]byte{encryptedWithOld, encryptedWithNew} {
+		decrypted, err := decrypt(rotatedCrypter, encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, someSecret, decrypted)
+	}
+
+	_, err = decrypt(oldCrypter, encryptedWithNew)
+	assert.IsType(t, UnknownMasterKeyError{}, err)
+}
+
+func TestDecrypt_WrongMasterKey(t *testing.T) {
+	crypter, err := NewCrypter("", []MasterKey{newTestMasterKey(t, "key", 1)})
+	assert.NoError(t, err)
+	encrypted := encrypt(t, crypter, someSecret)
+
+	otherCrypter, err := NewCrypter("", []MasterKey{newTestMasterKey(t, "key", 2)})
+	assert.NoError(t, err)
+	_, err = decrypt(otherCrypter, encrypted)
+	assert.Error(t, err)
+
+	_, err = otherCrypter.Decrypt(io.LimitReader(bytes.NewReader(encrypted), 4))
+	assert.Error(t, err)
+}
+
+func TestNewCrypter_InvalidKeys(t *testing.T) {
+	key := newTestMasterKey(t, "key", 1)
+	_, err := NewCrypter("", nil)
+	assert.Error(t, err)
+	_, err = NewCrypter("missing", []MasterKey{key})
+	assert.Error(t, err)
+	_, err = NewCrypter("", []MasterKey{key, key})
+	assert.Error(t, err)
+}
+
+func TestParseLocalMasterKeys(t *testing.T) {
+	encodedKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, DataKeyLen))
+	masterKeys, err := ParseLocalMasterKeys("2021:" + encodedKey + ", 2022:" + encodedKey)
+	assert.NoError(t, err)
+	assert.Len(t, masterKeys, 2)
+	assert.Equal(t, "2022", masterKeys[1].ID())
+
+	for _, value := range []string{"2021", "2021:not base64", "2021:" + base64.StdEncoding.EncodeToString([]byte("short"))} {
+		_, err = ParseLocalMasterKeys(value)
+		assert.IsType(t, InvalidMasterKeyError{}, err)
+	}
+
+	// the entry without the id may be the bare key, which must not leak to the logs
+	_, err = ParseLocalMasterKeys("2021:" + encodedKey + "," + encodedKey)
+	assert.IsType(t, InvalidMasterKeyError{}, err)
+	assert.Contains(t, err.Error(), "entry #2")
+	assert.NotContains(t, err.Error(), encodedKey)
+}
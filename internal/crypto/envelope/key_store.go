@@ -0,0 +1,77 @@
+package envelope
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// KeyStorePath is the storage folder of the detached data keys
+const KeyStorePath = "envelope_keys/"
+
+// keyObjectMagic starts each key object, it is followed by the master key id and the wrapped data key
+// in the same format as in the object header
+var keyObjectMagic = []byte("WALGKEY1")
+
+// KeyStore keeps the wrapped data keys apart from the encrypted objects, which reference them by id.
+// So the master key is replaced by rewriting the small key objects only.
+type KeyStore struct {
+	folder storage.Folder
+}
+
+func NewKeyStore(folder storage.Folder) *KeyStore {
+	return &KeyStore{folder: folder}
+}
+
+type wrappedDataKey struct {
+	masterKeyID string
+	wrappedKey  []byte
+}
+
+func (keyStore *KeyStore) put(id string, key wrappedDataKey) error {
+	var object bytes.Buffer
+	object.Write(keyObjectMagic)
+	writeField(&object, []byte(key.masterKeyID))
+	writeField(&object, key.wrappedKey)
+	return errors.Wrapf(keyStore.folder.PutObject(id, &object), "can not upload data key '%s'", id)
+}
+
+func (keyStore *KeyStore) get(id string) (wrappedDataKey, error) {
+	reader, err := keyStore.folder.ReadObject(id)
+	if err != nil {
+		return wrappedDataKey{}, errors.Wrapf(err, "can not read data key '%s'", id)
+	}
+	defer utility.LoggedClose(reader, "")
+
+	magic := make([]byte, len(keyObjectMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return wrappedDataKey{}, errors.Wrapf(err, "can not read data key '%s'", id)
+	}
+	if !bytes.Equal(magic, keyObjectMagic) {
+		return wrappedDataKey{}, errors.Errorf("object '%s' is not an envelope data key", id)
+	}
+	masterKeyID, err := readField(reader)
+	if err != nil {
+		return wrappedDataKey{}, err
+	}
+	wrappedKey, err := readField(reader)
+	if err != nil {
+		return wrappedDataKey{}, err
+	}
+	return wrappedDataKey{masterKeyID: string(masterKeyID), wrappedKey: wrappedKey}, nil
+}
+
+func (keyStore *KeyStore) list() ([]string, error) {
+	objects, _, err := keyStore.folder.ListFolder()
+	if err != nil {
+		return nil, errors.Wrap(err, "can not list data keys")
+	}
+	ids := make([]string, 0, len(objects))
+	for _, object := range objects {
+		ids = append(ids, object.GetName())
+	}
+	return ids, nil
+}
@@ -0,0 +1,150 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// MasterKey wraps and unwraps the per-object data keys
+type MasterKey interface {
+	// ID identifies the master key in the object header, so it is looked up on decryption
+	ID() string
+	Wrap(dataKey []byte) ([]byte, error)
+	Unwrap(wrappedKey []byte) ([]byte, error)
+}
+
+type InvalidMasterKeyError struct {
+	error
+}
+
+func NewInvalidMasterKeyError(description string) InvalidMasterKeyError {
+	return InvalidMasterKeyError{errors.Errorf("invalid envelope master key: %s", description)}
+}
+
+func (err InvalidMasterKeyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// LocalMasterKey wraps the data keys with AES-256-GCM using the locally configured key
+type LocalMasterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+func NewLocalMasterKey(id string, key []byte) (*LocalMasterKey, error) {
+	if id == "" {
+		return nil, NewInvalidMasterKeyError("empty key id")
+	}
+	if len(id) > maxFieldLen {
+		return nil, NewInvalidMasterKeyError(fmt.Sprintf("key id '%s' is too long", id))
+	}
+	if len(key) != DataKeyLen {
+		return nil, NewInvalidMasterKeyError(fmt.Sprintf("key '%s' must be %d bytes long, got %d", id, DataKeyLen, len(key)))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalMasterKey{id: id, aead: aead}, nil
+}
+
+func (masterKey *LocalMasterKey) ID() string {
+	return masterKey.id
+}
+
+// Wrap encrypts the data key, the random nonce is prepended to the result
+func (masterKey *LocalMasterKey) Wrap(dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, masterKey.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return masterKey.aead.Seal(nonce, nonce, dataKey, []byte(masterKey.id)), nil
+}
+
+func (masterKey *LocalMasterKey) Unwrap(wrappedKey []byte) ([]byte, error) {
+	nonceSize := masterKey.aead.NonceSize()
+	if len(wrappedKey) < nonceSize {
+		return nil, errors.Errorf("wrapped data key is too short for master key '%s'", masterKey.id)
+	}
+	dataKey, err := masterKey.aead.Open(nil, wrappedKey[:nonceSize], wrappedKey[nonceSize:], []byte(masterKey.id))
+	return dataKey, errors.Wrapf(err, "can not unwrap data key with master key '%s'", masterKey.id)
+}
+
+// ParseLocalMasterKeys parses comma-separated id:base64key pairs.
+// The malformed entry is reported by its position only, since it may be the bare key.
+func ParseLocalMasterKeys(value string) ([]MasterKey, error) {
+	var masterKeys []MasterKey
+	for i, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		idAndKey := strings.SplitN(pair, ":", 2)
+		if len(idAndKey) != 2 {
+			return nil, NewInvalidMasterKeyError(fmt.Sprintf("entry #%d is not in id:base64key format", i+1))
+		}
+		key, err := base64.StdEncoding.DecodeString(idAndKey[1])
+		if err != nil {
+			return nil, NewInvalidMasterKeyError(fmt.Sprintf("key '%s' is not base64 encoded", idAndKey[0]))
+		}
+		masterKey, err := NewLocalMasterKey(idAndKey[0], key)
+		if err != nil {
+			return nil, err
+		}
+		masterKeys = append(masterKeys, masterKey)
+	}
+	return masterKeys, nil
+}
+
+// AwsKmsMasterKey wraps the data keys with AWS KMS key
+type AwsKmsMasterKey struct {
+	keyID string
+	svc   *kms.KMS
+}
+
+// NewAwsKmsMasterKey creates the KMS client once, it is shared by all the wraps and unwraps
+func NewAwsKmsMasterKey(keyID string, region string) (*AwsKmsMasterKey, error) {
+	kmsConfig := aws.NewConfig()
+	if region != "" {
+		kmsConfig = kmsConfig.WithRegion(region)
+	}
+	kmsSession, err := session.NewSession()
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not create AWS session for KMS key '%s'", keyID)
+	}
+	return &AwsKmsMasterKey{keyID: keyID, svc: kms.New(kmsSession, kmsConfig)}, nil
+}
+
+func (masterKey *AwsKmsMasterKey) ID() string {
+	return masterKey.keyID
+}
+
+func (masterKey *AwsKmsMasterKey) Wrap(dataKey []byte) ([]byte, error) {
+	output, err := masterKey.svc.Encrypt(&kms.EncryptInput{KeyId: aws.String(masterKey.keyID), Plaintext: dataKey})
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not wrap data key with KMS key '%s'", masterKey.keyID)
+	}
+	return output.CiphertextBlob, nil
+}
+
+func (masterKey *AwsKmsMasterKey) Unwrap(wrappedKey []byte) ([]byte, error) {
+	output, err := masterKey.svc.Decrypt(&kms.DecryptInput{KeyId: aws.String(masterKey.keyID), CiphertextBlob: wrappedKey})
+	if err != nil {
+		return nil, errors.Wrapf(err, "can not unwrap data key with KMS key '%s'", masterKey.keyID)
+	}
+	return output.Plaintext, nil
+}
@@ -0,0 +1,59 @@
+package envelope
+
+import (
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// RewrapResult counts the detached data keys processed by RewrapDataKeys
+type RewrapResult struct {
+	Rewrapped int
+	Skipped   int
+}
+
+// RewrapDataKeys wraps the detached data keys with the current master key, the keys are unwrapped
+// by the master keys they were wrapped with. Only the small key objects are rewritten, the objects
+// encrypted with the data keys are not touched. The keys already wrapped by the current master key are skipped,
+// so the rewrap can be safely rerun after a failure.
+func (crypter *Crypter) RewrapDataKeys() (RewrapResult, error) {
+	var result RewrapResult
+	if crypter.keyStore == nil {
+		return result, errors.New("key store of the detached data keys is not configured")
+	}
+	keyIDs, err := crypter.keyStore.list()
+	if err != nil {
+		return result, err
+	}
+
+	currentKeyID := crypter.currentKey.ID()
+	for _, keyID := range keyIDs {
+		key, err := crypter.keyStore.get(keyID)
+		if err != nil {
+			return result, err
+		}
+		if key.masterKeyID == currentKeyID {
+			result.Skipped++
+			continue
+		}
+
+		masterKey, ok := crypter.masterKeys[key.masterKeyID]
+		if !ok {
+			return result, NewUnknownMasterKeyError(key.masterKeyID)
+		}
+		dataKey, err := masterKey.Unwrap(key.wrappedKey)
+		if err != nil {
+			return result, err
+		}
+		wrappedKey, err := crypter.currentKey.Wrap(dataKey)
+		if err != nil {
+			return result, err
+		}
+		if err := crypter.keyStore.put(keyID, wrappedDataKey{masterKeyID: currentKeyID, wrappedKey: wrappedKey}); err != nil {
+			return result, err
+		}
+		tracelog.DebugLogger.Printf("Data key '%s' is rewrapped from master key '%s' to '%s'",
+			keyID, key.masterKeyID, currentKeyID)
+		result.Rewrapped++
+	}
+	return result, nil
+}
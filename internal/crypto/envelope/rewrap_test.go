@@ -0,0 +1,79 @@
+package envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func newDetachedCrypter(t *testing.T, keyStore *KeyStore, currentKeyID string, masterKeys ...MasterKey) *Crypter {
+	crypter, err := NewCrypter(currentKeyID, masterKeys)
+	assert.NoError(t, err)
+	crypter.SetKeyStore(keyStore, true)
+	return crypter
+}
+
+func TestDetachedKeysEncryptionCycle(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	crypter := newDetachedCrypter(t, NewKeyStore(folder), "", newTestMasterKey(t, "first", 1))
+
+	first, second := encrypt(t, crypter, someSecret), encrypt(t, crypter, someSecret)
+	assert.NotEqual(t, first, second)
+	assert.True(t, bytes.HasPrefix(first, detachedHeaderMagic))
+	keys, _, err := folder.ListFolder()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1, "data key is shared by the objects of the crypter")
+
+	otherCrypter := newDetachedCrypter(t, NewKeyStore(folder), "", newTestMasterKey(t, "first", 1))
+	for _, encrypted := range [][]byte{first, second} {
+		decrypted, err := decrypt(otherCrypter, encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, someSecret, decrypted)
+	}
+
+	withoutKeyStore, err := NewCrypter("", []MasterKey{newTestMasterKey(t, "first", 1)})
+	assert.NoError(t, err)
+	_, err = decrypt(withoutKeyStore, first)
+	assert.Error(t, err)
+}
+
+func TestRewrapDataKeys(t *testing.T) {
+	keyStore := NewKeyStore(memory.NewFolder("", memory.NewStorage()))
+	oldKey, newKey := newTestMasterKey(t, "old", 1), newTestMasterKey(t, "new", 2)
+	encrypted := [][]byte{
+		encrypt(t, newDetachedCrypter(t, keyStore, "", oldKey), someSecret),
+		encrypt(t, newDetachedCrypter(t, keyStore, "", oldKey), someSecret),
+	}
+
+	rotatedCrypter := newDetachedCrypter(t, keyStore, "new", oldKey, newKey)
+	encrypted = append(encrypted, encrypt(t, rotatedCrypter, someSecret))
+	result, err := rotatedCrypter.RewrapDataKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, RewrapResult{Rewrapped: 2, Skipped: 1}, result)
+
+	result, err = rotatedCrypter.RewrapDataKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, RewrapResult{Rewrapped: 0, Skipped: 3}, result)
+
+	newOnlyCrypter := newDetachedCrypter(t, keyStore, "", newKey)
+	for _, object := range encrypted {
+		decrypted, err := decrypt(newOnlyCrypter, object)
+		assert.NoError(t, err)
+		assert.Equal(t, someSecret, decrypted)
+	}
+}
+
+func TestRewrapDataKeys_UnknownMasterKey(t *testing.T) {
+	keyStore := NewKeyStore(memory.NewFolder("", memory.NewStorage()))
+	encrypt(t, newDetachedCrypter(t, keyStore, "", newTestMasterKey(t, "old", 1)), someSecret)
+
+	_, err := newDetachedCrypter(t, keyStore, "", newTestMasterKey(t, "new", 2)).RewrapDataKeys()
+	assert.IsType(t, UnknownMasterKeyError{}, err)
+
+	withoutKeyStore, err := NewCrypter("", []MasterKey{newTestMasterKey(t, "new", 2)})
+	assert.NoError(t, err)
+	_, err = withoutKeyStore.RewrapDataKeys()
+	assert.Error(t, err)
+}
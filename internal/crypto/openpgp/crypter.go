@@ -30,7 +30,7 @@ type Crypter struct {
 	PubKey    openpgp.EntityList
 	SecretKey openpgp.EntityList
 
-	loadPassphrase func() (string, bool)
+	loadPassphrase func() ([]byte, bool, error)
 
 	mutex sync.RWMutex
 }
@@ -40,17 +40,17 @@ func (crypter *Crypter) Name() string {
 }
 
 // CrypterFromKey creates Crypter from armored key.
-func CrypterFromKey(armoredKey string, loadPassphrase func() (string, bool)) crypto.Crypter {
+func CrypterFromKey(armoredKey string, loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
 	return &Crypter{ArmoredKey: armoredKey, IsUseArmoredKey: true, loadPassphrase: loadPassphrase}
 }
 
 // CrypterFromKeyPath creates Crypter from armored key path.
-func CrypterFromKeyPath(armoredKeyPath string, loadPassphrase func() (string, bool)) crypto.Crypter {
+func CrypterFromKeyPath(armoredKeyPath string, loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
 	return &Crypter{ArmoredKeyPath: armoredKeyPath, IsUseArmoredKeyPath: true, loadPassphrase: loadPassphrase}
 }
 
 // CrypterFromKeyRingID create Crypter from key ring ID.
-func CrypterFromKeyRingID(keyRingID string, loadPassphrase func() (string, bool)) crypto.Crypter {
+func CrypterFromKeyRingID(keyRingID string, loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
 	return &Crypter{KeyRingID: keyRingID, IsUseKeyRingID: true, loadPassphrase: loadPassphrase}
 }
 
@@ -145,6 +145,36 @@ func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
 	return md.UnverifiedBody, nil
 }
 
+// HasPrivateKey checks the armored key or the key file holds the private key,
+// false is returned for the key ring ID since the legacy gpg keyring is exported on demand
+func (crypter *Crypter) HasPrivateKey() (bool, error) {
+	var entityList openpgp.EntityList
+	var err error
+	switch {
+	case crypter.IsUseArmoredKey:
+		evaluatedKey := strings.Replace(crypter.ArmoredKey, `\n`, "\n", -1)
+		entityList, err = openpgp.ReadArmoredKeyRing(strings.NewReader(evaluatedKey))
+	case crypter.IsUseArmoredKeyPath:
+		entityList, err = readPGPKey(crypter.ArmoredKeyPath)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	for _, entity := range entityList {
+		if entity.PrivateKey != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LoadSecretKey loads the secret key and decrypts it with the passphrase ahead of the first decryption
+func (crypter *Crypter) LoadSecretKey() error {
+	return crypter.loadSecret()
+}
+
 // load the secret key based on the settings
 func (crypter *Crypter) loadSecret() error {
 	// check if we actually need to load it
@@ -199,10 +229,17 @@ func (crypter *Crypter) loadSecret() error {
 		crypter.SecretKey = entityList
 	}
 
-	if passphrase, ok := crypter.loadPassphrase(); ok {
+	// the key is loaded again by the next call unless it is decrypted
+	passphrase, ok, err := crypter.loadPassphrase()
+	if err != nil {
+		crypter.SecretKey = nil
+		return errors.Wrap(err, "can't load the PGP key passphrase")
+	}
+	if ok {
 		err := decryptSecretKey(crypter.SecretKey, passphrase)
 
 		if err != nil {
+			crypter.SecretKey = nil
 			return errors.WithStack(err)
 		}
 	}
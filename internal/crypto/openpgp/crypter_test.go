@@ -17,8 +17,8 @@ const (
 	PrivateKeyEnvFilePath = "./testdata/pgpTestPrivateKeyEnv"
 )
 
-func noPassphrase() (string, bool) {
-	return "", false
+func noPassphrase() ([]byte, bool, error) {
+	return nil, false, nil
 }
 
 func MockArmedCrypterFromEnv() crypto.Crypter {
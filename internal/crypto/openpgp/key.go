@@ -34,8 +34,13 @@ func readPGPKey(path string) (openpgp.EntityList, error) {
 	return entityList, nil
 }
 
-func decryptSecretKey(entityList openpgp.EntityList, passphrase string) error {
-	passphraseBytes := []byte(passphrase)
+// decryptSecretKey decrypts the keyring with the passphrase, the passphrase is zeroed once it is done
+func decryptSecretKey(entityList openpgp.EntityList, passphraseBytes []byte) error {
+	defer func() {
+		for i := range passphraseBytes {
+			passphraseBytes[i] = 0
+		}
+	}()
 
 	for _, entity := range entityList {
 		err := entity.PrivateKey.Decrypt(passphraseBytes)
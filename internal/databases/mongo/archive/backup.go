@@ -44,7 +44,7 @@ func NewTabbedBackupListing(minwidth, tabwidth, padding int, padchar byte, flags
 func (bl *TabbedBackupListing) Backups(backups []models.Backup, output io.Writer) error {
 	writer := tabwriter.NewWriter(output, bl.minwidth, bl.tabwidth, bl.padding, bl.padchar, bl.flags)
 
-	_, err := fmt.Fprintln(writer, "name\tfinish_local_time\tts_before\tts_after\tdata_size\tpermanent\tuser_data")
+	_, err := fmt.Fprintln(writer, "name\tfinish_local_time\tts_before\tts_after\tdata_size\tpermanent\tuser_data\tlabels")
 	if err != nil {
 		return err
 	}
@@ -57,7 +57,7 @@ func (bl *TabbedBackupListing) Backups(backups []models.Backup, output io.Writer
 		}
 
 		_, err := fmt.Fprintf(writer,
-			"%v\t%v\t%v\t%v\t%d\t%v\t%s\n",
+			"%v\t%v\t%v\t%v\t%d\t%v\t%s\t%s\n",
 			b.BackupName,
 			b.FinishLocalTime.Format(time.RFC3339),
 			b.MongoMeta.Before.LastMajTS,
@@ -65,6 +65,7 @@ func (bl *TabbedBackupListing) Backups(backups []models.Backup, output io.Writer
 			b.DataSize,
 			b.Permanent,
 			rawUserData,
+			internal.FormatBackupLabels(b.Labels),
 		)
 		if err != nil {
 			return err
@@ -98,6 +99,7 @@ type MongoMetaConstructor struct {
 	folder    storage.Folder
 	meta      models.BackupMeta
 	permanent bool
+	labels    map[string]string
 }
 
 func (m *MongoMetaConstructor) MetaInfo() interface{} {
@@ -106,6 +108,7 @@ func (m *MongoMetaConstructor) MetaInfo() interface{} {
 		StartLocalTime:  meta.StartTime,
 		FinishLocalTime: meta.FinishTime,
 		UserData:        meta.User,
+		Labels:          meta.Labels,
 		MongoMeta:       meta.Mongo,
 		DataSize:        meta.DataSize,
 		Permanent:       meta.Permanent,
@@ -116,8 +119,9 @@ func (m *MongoMetaConstructor) MetaInfo() interface{} {
 func NewBackupMongoMetaConstructor(ctx context.Context,
 	mc client.MongoDriver,
 	folder storage.Folder,
-	permanent bool) internal.MetaConstructor {
-	return &MongoMetaConstructor{ctx: ctx, client: mc, folder: folder, permanent: permanent}
+	permanent bool,
+	labels map[string]string) internal.MetaConstructor {
+	return &MongoMetaConstructor{ctx: ctx, client: mc, folder: folder, permanent: permanent, labels: labels}
 }
 
 func (m *MongoMetaConstructor) Init() error {
@@ -135,6 +139,7 @@ func (m *MongoMetaConstructor) Init() error {
 		StartTime: utility.TimeNowCrossPlatformLocal(),
 		Permanent: m.permanent,
 		User:      userData,
+		Labels:    m.labels,
 		Mongo: models.MongoMeta{
 			Before: models.NodeMeta{
 				LastTS:    lastTS,
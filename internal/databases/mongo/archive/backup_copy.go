@@ -0,0 +1,261 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupCopyOptions configures the copy of the backup between the storages.
+type BackupCopyOptions struct {
+	// OplogUntil extends the copied oplog archives up to the timestamp,
+	// only the archives required to restore the backup itself are copied if it is not set.
+	OplogUntil *models.Timestamp
+	// WithoutOplog copies only the backup stream and the sentinel.
+	WithoutOplog bool
+	// DryRun reports the objects to be copied without copying them.
+	DryRun bool
+}
+
+// BackupCopyReport lists the storage objects of the copied backup, the paths are relative to the storage root.
+type BackupCopyReport struct {
+	Copied      []string `json:"Copied"`
+	Skipped     []string `json:"Skipped"`
+	CopiedBytes int64    `json:"CopiedBytes"`
+	DryRun      bool     `json:"DryRun"`
+}
+
+// CopyBackup copies the backup from the source storage root to the destination one preserving the object names:
+// the backup stream, the oplog archives (along with their checksums and the chunks of the deduplicated ones)
+// and the sentinel.
+// The sentinel is copied last, so the backup is not listed at the destination until all its objects are copied.
+// The objects present at the destination with the same size are skipped,
+// each copied object is read back from the destination and compared with the source.
+func CopyBackup(from, to storage.Folder, backupName string, opts BackupCopyOptions) (BackupCopyReport, error) {
+	report := BackupCopyReport{Copied: []string{}, Skipped: []string{}, DryRun: opts.DryRun}
+	backup := internal.NewBackup(from.GetSubFolder(utility.BaseBackupPath), backupName)
+	var sentinel models.Backup
+	if err := backup.FetchSentinel(&sentinel); err != nil {
+		return report, fmt.Errorf("can not fetch backup '%s' sentinel: %w", backupName, err)
+	}
+	sentinel.BackupName = backupName
+
+	// the stream of the backup uploaded with the key template is stored under the key prefix, see BackupDataFolder
+	backupDir := path.Join(sentinel.KeyPrefix, utility.BaseBackupPath, backupName)
+	objects, err := listObjectSizes(from, backupDir, true)
+	if err != nil {
+		return report, err
+	}
+	if len(objects) == 0 {
+		return report, fmt.Errorf("backup '%s' has no stream objects in '%s'", backupName, backupDir)
+	}
+	paths := sortedPaths(objects)
+	if !opts.WithoutOplog {
+		oplogObjects, oplogPaths, err := backupOplogObjects(from, sentinel, opts.OplogUntil)
+		if err != nil {
+			return report, err
+		}
+		for objectPath, size := range oplogObjects {
+			objects[objectPath] = size
+		}
+		paths = append(paths, oplogPaths...)
+	}
+	sentinelPath := path.Join(utility.BaseBackupPath, internal.SentinelNameFromBackup(backupName))
+	sentinelObjects, err := listObjectSizes(from, utility.BaseBackupPath, false)
+	if err != nil {
+		return report, err
+	}
+	objects[sentinelPath] = sentinelObjects[sentinelPath]
+	paths = append(paths, sentinelPath)
+
+	existing, err := listDestinationSizes(to, backupDir, opts.WithoutOplog)
+	if err != nil {
+		return report, err
+	}
+	for _, objectPath := range paths {
+		if size, ok := existing[objectPath]; ok && size == objects[objectPath] {
+			tracelog.DebugLogger.Printf("Object '%s' is already present at the destination, skipping", objectPath)
+			report.Skipped = append(report.Skipped, objectPath)
+			continue
+		}
+		if !opts.DryRun {
+			if err := copyObject(from, to, objectPath, objects[objectPath]); err != nil {
+				return report, err
+			}
+			tracelog.InfoLogger.Printf("Copied '%s' (%d bytes)", objectPath, objects[objectPath])
+		}
+		report.Copied = append(report.Copied, objectPath)
+		report.CopiedBytes += objects[objectPath]
+	}
+	return report, nil
+}
+
+// backupOplogObjects selects the oplog archives required to replay the oplog since the backup start
+// up to the until ts (the backup finish if it is not set), the chunks of the deduplicated archives precede them
+// and the checksums follow them.
+func backupOplogObjects(from storage.Folder, sentinel models.Backup,
+	until *models.Timestamp) (map[string]int64, []string, error) {
+	oplogsFolder := from.GetSubFolder(models.OplogArchBasePath)
+	archiveObjects, _, err := oplogsFolder.ListFolder()
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not list oplog archives folder: %w", err)
+	}
+	archives, err := archivesFromObjects(archiveObjects)
+	if err != nil {
+		return nil, nil, err
+	}
+	untilTS := sentinel.MongoMeta.After.LastMajTS
+	if until != nil {
+		untilTS = *until
+	}
+	sinceTS := sentinel.MongoMeta.Before.LastMajTS
+	selected, err := ArchivesBetweenTS(archives, sinceTS, untilTS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not select oplog archives of backup '%s': %w", sentinel.BackupName, err)
+	}
+	if len(selected) == 0 || !models.LessTS(selected[0].Start, sinceTS) {
+		// the replay starts from the archive with the since ts record, it ends right at the window start
+		for _, arch := range archives {
+			if arch.Type == models.ArchiveTypeOplog && arch.End == sinceTS {
+				selected = append([]models.Archive{arch}, selected...)
+				break
+			}
+		}
+	}
+
+	archiveSizes := make(map[string]int64, len(archiveObjects))
+	for _, object := range archiveObjects {
+		archiveSizes[object.GetName()] = object.GetSize()
+	}
+	checksumSizes, err := listObjectSizes(from, path.Join(models.OplogArchBasePath, models.OplogChecksumsPath), false)
+	if err != nil {
+		return nil, nil, err
+	}
+	var chunkSizes map[string]int64
+	objects := make(map[string]int64)
+	paths := make([]string, 0, len(selected))
+	for _, arch := range selected {
+		if arch.IsManifest() {
+			if chunkSizes == nil {
+				if chunkSizes, err = listObjectSizes(from, path.Join(models.OplogArchBasePath, models.OplogChunksPath), false); err != nil {
+					return nil, nil, err
+				}
+			}
+			manifest, err := readArchiveManifest(oplogsFolder, arch)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, chunk := range manifest.Chunks {
+				chunkPath := path.Join(models.OplogArchBasePath, models.OplogChunksPath, chunkFilename(chunk, manifest.Compression))
+				if _, ok := objects[chunkPath]; !ok {
+					objects[chunkPath] = chunkSizes[chunkPath]
+					paths = append(paths, chunkPath)
+				}
+			}
+		}
+		archivePath := path.Join(models.OplogArchBasePath, arch.Filename())
+		objects[archivePath] = archiveSizes[arch.Filename()]
+		paths = append(paths, archivePath)
+		// the checksums are not uploaded by the older versions
+		checksumPath := path.Join(models.OplogArchBasePath, models.OplogChecksumsPath, arch.ChecksumFilename())
+		if size, ok := checksumSizes[checksumPath]; ok {
+			objects[checksumPath] = size
+			paths = append(paths, checksumPath)
+		}
+	}
+	return objects, paths, nil
+}
+
+// listDestinationSizes lists the objects of the copied backup already present at the destination
+func listDestinationSizes(to storage.Folder, backupDir string, withoutOplog bool) (map[string]int64, error) {
+	sizes, err := listObjectSizes(to, backupDir, true)
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{utility.BaseBackupPath}
+	if !withoutOplog {
+		dirs = append(dirs, models.OplogArchBasePath, path.Join(models.OplogArchBasePath, models.OplogChecksumsPath),
+			path.Join(models.OplogArchBasePath, models.OplogChunksPath))
+	}
+	for _, dir := range dirs {
+		dirSizes, err := listObjectSizes(to, dir, false)
+		if err != nil {
+			return nil, err
+		}
+		for objectPath, size := range dirSizes {
+			sizes[objectPath] = size
+		}
+	}
+	return sizes, nil
+}
+
+// listObjectSizes lists the objects in the dir of the root folder, the paths are relative to the root
+func listObjectSizes(root storage.Folder, dir string, recursive bool) (map[string]int64, error) {
+	folder := root.GetSubFolder(dir)
+	var objects []storage.Object
+	var err error
+	if recursive {
+		objects, err = storage.ListFolderRecursively(folder)
+	} else {
+		objects, _, err = folder.ListFolder()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not list folder '%s': %w", dir, err)
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, object := range objects {
+		sizes[path.Join(dir, object.GetName())] = object.GetSize()
+	}
+	return sizes, nil
+}
+
+func sortedPaths(objects map[string]int64) []string {
+	paths := make([]string, 0, len(objects))
+	for objectPath := range objects {
+		paths = append(paths, objectPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// copyObject streams the object from the source to the destination,
+// then reads it back from the destination and compares the size and the SHA-256 digest with the source
+func copyObject(from, to storage.Folder, objectPath string, size int64) error {
+	reader, err := from.ReadObject(objectPath)
+	if err != nil {
+		return fmt.Errorf("can not read object '%s': %w", objectPath, err)
+	}
+	defer utility.LoggedClose(reader, "")
+	sourceDigest := sha256.New()
+	var sourceSize int64
+	if err := to.PutObject(objectPath, internal.NewWithSizeReader(io.TeeReader(reader, sourceDigest), &sourceSize)); err != nil {
+		return fmt.Errorf("can not upload object '%s': %w", objectPath, err)
+	}
+	if sourceSize != size {
+		return fmt.Errorf("object '%s' of %d bytes is listed with %d bytes", objectPath, sourceSize, size)
+	}
+
+	copied, err := to.ReadObject(objectPath)
+	if err != nil {
+		return fmt.Errorf("can not read back copied object '%s': %w", objectPath, err)
+	}
+	defer utility.LoggedClose(copied, "")
+	copiedDigest := sha256.New()
+	copiedSize, err := io.Copy(copiedDigest, copied)
+	if err != nil {
+		return fmt.Errorf("can not read back copied object '%s': %w", objectPath, err)
+	}
+	if copiedSize != sourceSize || !bytes.Equal(copiedDigest.Sum(nil), sourceDigest.Sum(nil)) {
+		return fmt.Errorf("copied object '%s' differs from the source: %d bytes copied of %d", objectPath, copiedSize, sourceSize)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package archive
+
+import (
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func prepareBackupCopySource(t *testing.T) (storage.Folder, string, []models.Archive) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	t.Cleanup(func() { viper.Set(internal.SerializerTypeSetting, nil) })
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	constructor := &testMetaConstructor{backup: models.Backup{MongoMeta: models.MongoMeta{
+		Before: models.NodeMeta{LastMajTS: models.Timestamp{TS: 3}},
+		After:  models.NodeMeta{LastMajTS: models.Timestamp{TS: 4}},
+	}}}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder.GetSubFolder(utility.BaseBackupPath))).
+		UploadBackup(strings.NewReader(strings.Repeat("backup data ", 1000)), testErrWaiter{}, constructor))
+
+	oplogUploader := NewStorageUploader(internal.NewUploader(compressor, folder.GetSubFolder(models.OplogArchBasePath)))
+	archives := make([]models.Archive, 0, 6)
+	for i := uint32(1); i <= 6; i++ {
+		arch, err := models.NewArchive(models.Timestamp{TS: i}, models.Timestamp{TS: i + 1}, compressor.FileExtension(),
+			models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		assert.NoError(t, oplogUploader.UploadOplogArchive(strings.NewReader(strings.Repeat("oplog ", int(i)*100)),
+			arch.Start, arch.End))
+		archives = append(archives, arch)
+	}
+	return folder, constructor.backup.BackupName, archives
+}
+
+func readTestObject(t *testing.T, folder storage.Folder, objectPath string) []byte {
+	reader, err := folder.ReadObject(objectPath)
+	assert.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return content
+}
+
+func TestCopyBackup(t *testing.T) {
+	from, backupName, archives := prepareBackupCopySource(t)
+	to := memory.NewFolder("", memory.NewStorage())
+
+	report, err := CopyBackup(from, to, backupName, BackupCopyOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, report.Skipped)
+	assert.Equal(t, path.Join(utility.BaseBackupPath, internal.SentinelNameFromBackup(backupName)),
+		report.Copied[len(report.Copied)-1])
+	var copiedBytes int64
+	for _, objectPath := range report.Copied {
+		content := readTestObject(t, to, objectPath)
+		assert.Equal(t, readTestObject(t, from, objectPath), content)
+		copiedBytes += int64(len(content))
+	}
+	assert.Equal(t, copiedBytes, report.CopiedBytes)
+	// the replay starts from the archive ending at the backup start
+	assert.Contains(t, report.Copied, path.Join(models.OplogArchBasePath, archives[1].Filename()))
+	assert.Contains(t, report.Copied, path.Join(models.OplogArchBasePath, archives[2].Filename()))
+	assert.Contains(t, report.Copied,
+		path.Join(models.OplogArchBasePath, models.OplogChecksumsPath, archives[2].ChecksumFilename()))
+	assert.NotContains(t, report.Copied, path.Join(models.OplogArchBasePath, archives[5].Filename()))
+
+	repeated, err := CopyBackup(from, to, backupName, BackupCopyOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, repeated.Copied)
+	assert.Equal(t, report.Copied, repeated.Skipped)
+}
+
+func TestCopyBackup_KeyPrefix(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	from := memory.NewFolder("", memory.NewStorage())
+	keyTemplate, err := NewKeyTemplate("{cluster}/{yyyy}", "rs01")
+	assert.NoError(t, err)
+	constructor := &testMetaConstructor{}
+	assert.NoError(t, NewBackupStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], from), keyTemplate).
+		UploadBackup(strings.NewReader(strings.Repeat("backup data ", 1000)), testErrWaiter{}, constructor))
+	backupName := constructor.backup.BackupName
+	to := memory.NewFolder("", memory.NewStorage())
+
+	report, err := CopyBackup(from, to, backupName, BackupCopyOptions{WithoutOplog: true})
+	assert.NoError(t, err)
+	backupDir := path.Join(constructor.backup.KeyPrefix, utility.BaseBackupPath, backupName)
+	streamObjects, err := storage.ListFolderRecursively(from.GetSubFolder(backupDir))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, streamObjects)
+	for _, object := range streamObjects {
+		objectPath := path.Join(backupDir, object.GetName())
+		assert.Contains(t, report.Copied, objectPath)
+		assert.Equal(t, readTestObject(t, from, objectPath), readTestObject(t, to, objectPath))
+	}
+
+	repeated, err := CopyBackup(from, to, backupName, BackupCopyOptions{WithoutOplog: true})
+	assert.NoError(t, err)
+	assert.Empty(t, repeated.Copied)
+}
+
+func TestCopyBackup_OplogUntil(t *testing.T) {
+	from, backupName, archives := prepareBackupCopySource(t)
+	to := memory.NewFolder("", memory.NewStorage())
+
+	report, err := CopyBackup(from, to, backupName, BackupCopyOptions{OplogUntil: &models.Timestamp{TS: 7}})
+	assert.NoError(t, err)
+	assert.Contains(t, report.Copied, path.Join(models.OplogArchBasePath, archives[5].Filename()))
+
+	_, err = CopyBackup(from, to, backupName, BackupCopyOptions{OplogUntil: &models.Timestamp{TS: 100}})
+	assert.Error(t, err)
+}
+
+func TestCopyBackup_DryRun(t *testing.T) {
+	from, backupName, _ := prepareBackupCopySource(t)
+	to := memory.NewFolder("", memory.NewStorage())
+
+	report, err := CopyBackup(from, to, backupName, BackupCopyOptions{DryRun: true, WithoutOplog: true})
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.NotEmpty(t, report.Copied)
+	assert.Positive(t, report.CopiedBytes)
+	for _, objectPath := range report.Copied {
+		assert.False(t, strings.HasPrefix(objectPath, models.OplogArchBasePath))
+		exists, err := to.Exists(objectPath)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	}
+}
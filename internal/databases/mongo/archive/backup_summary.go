@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+const unknownSummaryValue = "unknown"
+
+// BackupSummary is the row of the backups summary, the zero sizes mean they are not recorded in the backup sentinel
+type BackupSummary struct {
+	BackupName       string
+	StartTime        time.Time
+	FinishTime       time.Time
+	Duration         time.Duration
+	CompressedSize   int64
+	UncompressedSize int64
+}
+
+func NewBackupSummary(backup *models.Backup) BackupSummary {
+	return BackupSummary{
+		BackupName:       backup.BackupName,
+		StartTime:        backup.StartLocalTime,
+		FinishTime:       backup.FinishLocalTime,
+		Duration:         backup.FinishLocalTime.Sub(backup.StartLocalTime),
+		CompressedSize:   backup.CompressedSize,
+		UncompressedSize: backup.UncompressedSize,
+	}
+}
+
+// CompressionRatio is the uncompressed size divided by the compressed one, false is returned if they are unknown
+func (summary BackupSummary) CompressionRatio() (float64, bool) {
+	if summary.CompressedSize == 0 || summary.UncompressedSize == 0 {
+		return 0, false
+	}
+	return float64(summary.UncompressedSize) / float64(summary.CompressedSize), true
+}
+
+// ListBackupSummaries loads the sentinels of all the backups and summarizes them from the last finished one
+func ListBackupSummaries(downloader Downloader) ([]BackupSummary, error) {
+	backupTimes, _, err := downloader.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	backups, err := downloader.LoadBackups(BackupNamesFromBackupTimes(backupTimes))
+	if err != nil {
+		return nil, err
+	}
+	return NewBackupSummaries(backups), nil
+}
+
+// NewBackupSummaries summarizes the backups sorted by the finish time descending
+func NewBackupSummaries(backups []models.Backup) []BackupSummary {
+	summaries := make([]BackupSummary, 0, len(backups))
+	for i := range backups {
+		summaries = append(summaries, NewBackupSummary(&backups[i]))
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].FinishTime.After(summaries[j].FinishTime)
+	})
+	return summaries
+}
+
+// WriteBackupSummaries prints the summaries as a table, the sizes missing in the sentinels are printed as unknown
+func WriteBackupSummaries(summaries []BackupSummary, output io.Writer) error {
+	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	_, err := fmt.Fprintln(writer, "name\tstart_local_time\tfinish_local_time\tduration\tcompressed_size\tuncompressed_size\tratio")
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		ratio := unknownSummaryValue
+		if value, ok := summary.CompressionRatio(); ok {
+			ratio = fmt.Sprintf("%.2f", value)
+		}
+		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			summary.BackupName,
+			summary.StartTime.Format(time.RFC3339),
+			summary.FinishTime.Format(time.RFC3339),
+			summary.Duration.Round(time.Second),
+			formatSummarySize(summary.CompressedSize),
+			formatSummarySize(summary.UncompressedSize),
+			ratio,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func formatSummarySize(size int64) string {
+	if size == 0 {
+		return unknownSummaryValue
+	}
+	return fmt.Sprintf("%d", size)
+}
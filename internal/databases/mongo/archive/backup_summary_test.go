@@ -0,0 +1,106 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type testMetaConstructor struct {
+	backup models.Backup
+}
+
+func (constructor *testMetaConstructor) Init() error { return nil }
+
+func (constructor *testMetaConstructor) Finalize(backupName string) error {
+	constructor.backup.BackupName = backupName
+	return nil
+}
+
+func (constructor *testMetaConstructor) MetaInfo() interface{} { return &constructor.backup }
+
+type testErrWaiter struct{}
+
+func (testErrWaiter) Wait() error { return nil }
+
+func TestStorageUploader_UploadBackup_RecordsSizes(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	su := NewBackupStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder), nil)
+	backupsFolder := folder.GetSubFolder(utility.BaseBackupPath)
+
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	content := strings.Repeat("backup data ", 1000)
+	constructor := &testMetaConstructor{}
+	err := su.UploadBackup(strings.NewReader(content), testErrWaiter{}, constructor)
+	assert.NoError(t, err)
+
+	reader, err := backupsFolder.ReadObject(internal.SentinelNameFromBackup(constructor.backup.BackupName))
+	assert.NoError(t, err)
+	var sentinel models.Backup
+	assert.NoError(t, json.NewDecoder(reader).Decode(&sentinel))
+	assert.Equal(t, int64(len(content)), sentinel.UncompressedSize)
+	// the stream metadata is not a part of the compressed backup data
+	objects, _, err := backupsFolder.GetSubFolder(constructor.backup.BackupName).ListFolder()
+	assert.NoError(t, err)
+	var streamSize int64
+	for _, object := range objects {
+		if strings.HasSuffix(object.GetName(), "."+lz4.FileExtension) {
+			streamSize += object.GetSize()
+		}
+	}
+	assert.Equal(t, streamSize, sentinel.CompressedSize)
+	assert.Less(t, sentinel.CompressedSize, sentinel.UncompressedSize)
+}
+
+func TestNewBackupSummaries_SortsByFinishTimeDescending(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	backups := []models.Backup{
+		{BackupName: "first", StartLocalTime: start, FinishLocalTime: start.Add(time.Minute)},
+		{BackupName: "third", StartLocalTime: start, FinishLocalTime: start.Add(3 * time.Minute)},
+		{BackupName: "second", StartLocalTime: start, FinishLocalTime: start.Add(2 * time.Minute)},
+	}
+
+	summaries := NewBackupSummaries(backups)
+	names := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		names = append(names, summary.BackupName)
+	}
+	assert.Equal(t, []string{"third", "second", "first"}, names)
+	assert.Equal(t, 3*time.Minute, summaries[0].Duration)
+}
+
+func TestWriteBackupSummaries_UnknownSizes(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	summaries := NewBackupSummaries([]models.Backup{
+		{BackupName: "new", StartLocalTime: start, FinishLocalTime: start.Add(2 * time.Hour),
+			CompressedSize: 100, UncompressedSize: 250},
+		{BackupName: "old", StartLocalTime: start, FinishLocalTime: start.Add(time.Hour)},
+	})
+	ratio, ok := summaries[0].CompressionRatio()
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, ratio)
+	_, ok = summaries[1].CompressionRatio()
+	assert.False(t, ok)
+
+	var output bytes.Buffer
+	assert.NoError(t, WriteBackupSummaries(summaries, &output))
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []string{"new", "2021-01-01T00:00:00Z", "2021-01-01T02:00:00Z", "2h0m0s", "100", "250", "2.50"},
+		strings.Fields(lines[1]))
+	assert.Equal(t, []string{"old", "2021-01-01T00:00:00Z", "2021-01-01T01:00:00Z", "1h0m0s", "unknown", "unknown", "unknown"},
+		strings.Fields(lines[2]))
+}
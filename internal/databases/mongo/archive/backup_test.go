@@ -0,0 +1,25 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+func TestTabbedBackupListing_Backups_Labels(t *testing.T) {
+	backups := []models.Backup{
+		{BackupName: "stream_20220301T100000Z", FinishLocalTime: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+			Labels: map[string]string{"region": "eu-west", "cluster": "main"}},
+		{BackupName: "stream_20220302T100000Z", FinishLocalTime: time.Date(2022, 3, 2, 10, 0, 0, 0, time.UTC)},
+	}
+
+	output := &bytes.Buffer{}
+	assert.NoError(t, NewDefaultTabbedBackupListing().Backups(backups, output))
+	lines := bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 3)
+	assert.True(t, bytes.HasSuffix(lines[0], []byte("labels")))
+	assert.True(t, bytes.HasSuffix(lines[2], []byte("cluster=main,region=eu-west")))
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ChunkStore keeps the chunks of deduplicated oplog archives addressed by their content hash.
+type ChunkStore interface {
+	// PutChunk uploads the chunk unless the same content is already stored, returns the number of uploaded bytes.
+	PutChunk(data []byte) (chunk models.ArchiveChunk, uploadedBytes int, err error)
+	// GetChunk writes the content of the chunk stored with the given compression.
+	GetChunk(chunk models.ArchiveChunk, ext string, writer io.Writer) error
+	// Compression returns file extension of the chunks being put.
+	Compression() string
+}
+
+// StorageChunkStore stores compressed and encrypted chunks in storage folder.
+type StorageChunkStore struct {
+	folder     storage.Folder
+	compressor compression.Compressor
+	crypter    crypto.Crypter
+}
+
+// NewStorageChunkStore builds chunk store, compressor may be nil if the store is used only for downloading.
+func NewStorageChunkStore(folder storage.Folder, compressor compression.Compressor, crypter crypto.Crypter) *StorageChunkStore {
+	return &StorageChunkStore{folder: folder, compressor: compressor, crypter: crypter}
+}
+
+// Compression returns file extension of the chunks being put.
+func (cs *StorageChunkStore) Compression() string {
+	return cs.compressor.FileExtension()
+}
+
+// PutChunk uploads the chunk unless the same content is already stored, returns the number of uploaded bytes.
+func (cs *StorageChunkStore) PutChunk(data []byte) (models.ArchiveChunk, int, error) {
+	hash := sha256.Sum256(data)
+	chunk := models.ArchiveChunk{Hash: hex.EncodeToString(hash[:]), Size: len(data)}
+	chunkName := chunkFilename(chunk, cs.Compression())
+
+	exists, err := cs.folder.Exists(chunkName)
+	if err != nil {
+		return models.ArchiveChunk{}, 0, fmt.Errorf("can not check chunk '%s' existence: %w", chunkName, err)
+	}
+	if exists {
+		return chunk, 0, nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(internal.CompressAndEncrypt(bytes.NewReader(data), cs.compressor, cs.crypter)); err != nil {
+		return models.ArchiveChunk{}, 0, fmt.Errorf("can not compress chunk '%s': %w", chunkName, err)
+	}
+	if err := cs.folder.PutObject(chunkName, bytes.NewReader(buf.Bytes())); err != nil {
+		return models.ArchiveChunk{}, 0, fmt.Errorf("can not upload chunk '%s': %w", chunkName, err)
+	}
+	return chunk, buf.Len(), nil
+}
+
+// GetChunk writes the content of the chunk stored with the given compression, the content is verified by its hash.
+func (cs *StorageChunkStore) GetChunk(chunk models.ArchiveChunk, ext string, writer io.Writer) error {
+	chunkName := chunkFilename(chunk, ext)
+	var buf bytes.Buffer
+	if err := internal.DownloadFile(cs.folder, chunkName, ext, bufferWriteCloser{&buf}); err != nil {
+		return fmt.Errorf("can not download chunk '%s': %w", chunkName, err)
+	}
+
+	hash := sha256.Sum256(buf.Bytes())
+	if buf.Len() != chunk.Size || hex.EncodeToString(hash[:]) != chunk.Hash {
+		return fmt.Errorf("chunk '%s' content does not match its hash", chunkName)
+	}
+	_, err := utility.FastCopy(writer, &buf)
+	return err
+}
+
+func chunkFilename(chunk models.ArchiveChunk, ext string) string {
+	return chunk.Hash + "." + ext
+}
@@ -0,0 +1,55 @@
+package archive
+
+// Content-defined chunking parameters, the boundaries depend only on the content,
+// so the shared parts of consecutive archives produce the same chunks.
+const (
+	minChunkSize = 256 << 10
+	maxChunkSize = 4 << 20
+	// boundary is found when the top bits of the hash are zero, that gives about minChunkSize + 1MB on average
+	chunkBoundaryShift = 64 - 20
+)
+
+var gearTable = buildGearTable()
+
+// buildGearTable fills the rolling hash table with the fixed pseudo-random values (splitmix64),
+// the table must never change, otherwise already stored chunks will not be reused
+func buildGearTable() (table [256]uint64) {
+	state := uint64(0x9E3779B97F4A7C15)
+	for i := range table {
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}
+
+// splitChunks cuts data into chunks at the positions chosen by the gear rolling hash
+func splitChunks(data []byte) [][]byte {
+	var chunks [][]byte
+	for len(data) > 0 {
+		size := nextChunkSize(data)
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return chunks
+}
+
+func nextChunkSize(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+	limit := len(data)
+	if limit > maxChunkSize {
+		limit = maxChunkSize
+	}
+	var hash uint64
+	for i := minChunkSize; i < limit; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash>>chunkBoundaryShift == 0 {
+			return i + 1
+		}
+	}
+	return limit
+}
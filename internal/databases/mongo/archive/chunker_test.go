@@ -0,0 +1,29 @@
+package archive
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitChunks(t *testing.T) {
+	data := make([]byte, 5*maxChunkSize)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	chunks := splitChunks(data)
+	assert.Equal(t, data, bytes.Join(chunks, nil))
+	for _, chunk := range chunks[:len(chunks)-1] {
+		assert.GreaterOrEqual(t, len(chunk), minChunkSize)
+		assert.LessOrEqual(t, len(chunk), maxChunkSize)
+	}
+
+	// boundaries depend on the content only, so the shifted data has the same chunks after the first boundary
+	shifted := splitChunks(append([]byte("inserted"), data...))
+	assert.Equal(t, chunks[len(chunks)-2:], shifted[len(shifted)-2:])
+}
+
+func TestSplitChunks_Empty(t *testing.T) {
+	assert.Empty(t, splitChunks(nil))
+}
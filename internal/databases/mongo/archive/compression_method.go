@@ -0,0 +1,23 @@
+package archive
+
+import (
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+// NewCompressionMethod describes the compressor to be recorded along with the stream it compresses.
+func NewCompressionMethod(compressor compression.Compressor) *models.CompressionMethod {
+	algorithm, level := compression.Describe(compressor)
+	return &models.CompressionMethod{Algorithm: algorithm, Level: level, Extension: compressor.FileExtension()}
+}
+
+// BackupStreamFetcher returns the fetcher decompressing the backup stream by the method recorded in the sentinel,
+// so the WALG_COMPRESSION_METHOD changed since the upload does not matter. The stream of the backup
+// uploaded before the method was recorded is found by its extension.
+func BackupStreamFetcher(backup internal.Backup, sentinel *models.Backup) (internal.StreamFetcher, error) {
+	if sentinel.Compression == nil {
+		return internal.GetBackupStreamFetcher(backup)
+	}
+	return internal.GetBackupStreamFetcherByExtension(backup, sentinel.Compression.Extension)
+}
@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func putCompressedStream(t *testing.T, folder storage.Folder, backupName string, compressor compression.Compressor,
+	content string) {
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := io.WriteString(writer, content)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, folder.PutObject(internal.GetStreamName(backupName, compressor.FileExtension()), &compressed))
+}
+
+func fetchBackupStream(t *testing.T, folder storage.Folder, sentinel *models.Backup) string {
+	backup := internal.NewBackup(folder, sentinel.BackupName)
+	fetcher, err := BackupStreamFetcher(backup, sentinel)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, fetcher(backup, bufferWriteCloser{&buf}))
+	return buf.String()
+}
+
+func TestStorageUploader_UploadBackup_RecordsCompression(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor, err := compression.WithLevel(compression.Compressors[gzip.AlgorithmName], 9)
+	assert.NoError(t, err)
+	constructor := &testMetaConstructor{}
+	content := strings.Repeat("backup data ", 1000)
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadBackup(strings.NewReader(content), testErrWaiter{}, constructor))
+
+	level := 9
+	assert.Equal(t, &models.CompressionMethod{Algorithm: gzip.AlgorithmName, Level: &level, Extension: gzip.FileExtension},
+		constructor.backup.Compression)
+	assert.Equal(t, content, fetchBackupStream(t, folder, &constructor.backup))
+}
+
+func TestBackupStreamFetcher_MixedCompressionMethods(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	algorithms := []string{lz4.AlgorithmName, lzma.AlgorithmName, gzip.AlgorithmName}
+	sentinels := make([]models.Backup, 0, len(algorithms))
+	for i, algorithm := range algorithms {
+		compressor := compression.Compressors[algorithm]
+		backupName := internal.StreamPrefix + strings.Repeat(string(rune('0'+i)), 16)
+		putCompressedStream(t, folder, backupName, compressor, "backup of "+algorithm)
+		sentinels = append(sentinels, models.Backup{BackupName: backupName, Compression: NewCompressionMethod(compressor)})
+	}
+
+	for i := range sentinels {
+		assert.Equal(t, "backup of "+algorithms[i], fetchBackupStream(t, folder, &sentinels[i]))
+	}
+}
+
+func TestBackupStreamFetcher_PrefersRecordedCompression(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	backupName := internal.StreamPrefix + "20221010T101010Z"
+	// the stream left by the earlier upload with the other compression method is found first by the extension
+	putCompressedStream(t, folder, backupName, compression.Compressors[lz4.AlgorithmName], "stale stream")
+	gzipCompressor := compression.Compressors[gzip.AlgorithmName]
+	putCompressedStream(t, folder, backupName, gzipCompressor, "actual stream")
+
+	recorded := models.Backup{BackupName: backupName, Compression: NewCompressionMethod(gzipCompressor)}
+	assert.Equal(t, "actual stream", fetchBackupStream(t, folder, &recorded))
+
+	notRecorded := models.Backup{BackupName: backupName}
+	assert.Equal(t, "stale stream", fetchBackupStream(t, folder, &notRecorded))
+}
+
+func TestBackupStreamFetcher_FallsBackToExtension(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	backupName := internal.StreamPrefix + "20221010T101010Z"
+	putCompressedStream(t, folder, backupName, compression.Compressors[lzma.AlgorithmName], "backup data")
+
+	mismatched := models.Backup{BackupName: backupName,
+		Compression: NewCompressionMethod(compression.Compressors[lz4.AlgorithmName])}
+	assert.Equal(t, "backup data", fetchBackupStream(t, folder, &mismatched))
+
+	unknown := models.Backup{BackupName: backupName, Compression: &models.CompressionMethod{Extension: "unknown"}}
+	backup := internal.NewBackup(folder, backupName)
+	fetcher, err := BackupStreamFetcher(backup, &unknown)
+	assert.NoError(t, err)
+	assert.Error(t, fetcher(backup, bufferWriteCloser{&bytes.Buffer{}}))
+}
+
+func TestStorageUploader_UploadOplogArchive_RecordsCompression(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lzma.AlgorithmName]
+	su := NewStorageUploader(internal.NewUploader(compressor, folder))
+	firstTS, lastTS := models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}
+	assert.NoError(t, su.UploadOplogArchive(strings.NewReader("oplog"), firstTS, lastTS))
+
+	arch, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	reader, err := folder.GetSubFolder(models.OplogChecksumsPath).ReadObject(arch.ChecksumFilename())
+	assert.NoError(t, err)
+	defer reader.Close()
+	var checksum models.ArchiveChecksum
+	assert.NoError(t, json.NewDecoder(reader).Decode(&checksum))
+	assert.Equal(t, &models.CompressionMethod{Algorithm: lzma.AlgorithmName, Extension: compressor.FileExtension()},
+		checksum.Compression)
+	assert.Equal(t, int64(len("oplog")), checksum.Size)
+}
+
+func TestStorageDownloader_DownloadOplogArchive_UsesRecordedCompression(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lzma.AlgorithmName]
+	firstTS, lastTS := models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadOplogArchive(strings.NewReader("oplog"), firstTS, lastTS))
+	uploaded, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+
+	// the extension of the archive names the other compression than the recorded one
+	renamed, err := models.NewArchive(firstTS, lastTS, lz4.FileExtension, models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	assert.NoError(t, folder.CopyObject(uploaded.Filename(), renamed.Filename()))
+	assert.NoError(t, folder.CopyObject(models.OplogChecksumsPath+uploaded.ChecksumFilename(),
+		models.OplogChecksumsPath+renamed.ChecksumFilename()))
+
+	downloader := &StorageDownloader{oplogsFolder: folder}
+	var buf bytes.Buffer
+	assert.NoError(t, downloader.DownloadOplogArchive(renamed, nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, "oplog", buf.String())
+
+	// without the checksum the archive is decompressed by its extension
+	assert.NoError(t, folder.DeleteObjects([]string{models.OplogChecksumsPath + renamed.ChecksumFilename()}))
+	assert.Error(t, downloader.DownloadOplogArchive(renamed, nil, bufferWriteCloser{&bytes.Buffer{}}))
+}
@@ -0,0 +1,129 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// ArchiveNotFoundError is returned when the oplog archive is missing in the primary and all the failover storages
+type ArchiveNotFoundError struct {
+	Name string
+}
+
+func (err ArchiveNotFoundError) Error() string {
+	return fmt.Sprintf("oplog archive '%s' does not exist in any storage", err.Name)
+}
+
+// ConfigureFailoverFolders builds the folders of the failover storages in the order they are listed in the setting,
+// each storage is configured by its own config file.
+func ConfigureFailoverFolders() ([]storage.Folder, error) {
+	configFiles, ok := internal.GetSetting(internal.FailoverStoragesSetting)
+	if !ok {
+		return nil, nil
+	}
+	folders := make([]storage.Folder, 0)
+	for _, configFile := range strings.Split(configFiles, ",") {
+		configFile = strings.TrimSpace(configFile)
+		if configFile == "" {
+			continue
+		}
+		folder, err := internal.FolderFromConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// SetFailoverCounter registers counter to receive failover events, nothing is counted if it is not set.
+func (sd *StorageDownloader) SetFailoverCounter(counter metrics.FailoverCounter) {
+	sd.failoverCounter = counter
+}
+
+// withFailover fetches the archive by fn from the primary storage and then from the failover ones until it succeeds.
+// ArchiveNotFoundError is returned if the archive is missing everywhere,
+// otherwise the error of the primary storage is returned.
+func (sd *StorageDownloader) withFailover(operation metrics.Operation, arch models.Archive,
+	fn func(folder storage.Folder) error) error {
+	primaryErr := fn(sd.oplogsFolder)
+	if primaryErr == nil || len(sd.failoverOplogsFolders) == 0 {
+		return primaryErr
+	}
+	tracelog.WarningLogger.Printf("Failed to fetch oplog archive '%s' from primary storage, trying failover storages: %v",
+		arch.Filename(), primaryErr)
+
+	missingEverywhere := isArchiveMissing(sd.oplogsFolder, arch)
+	for i, folder := range sd.failoverOplogsFolders {
+		err := fn(folder)
+		if err == nil {
+			tracelog.InfoLogger.Printf("Oplog archive '%s' is fetched from failover storage #%d", arch.Filename(), i+1)
+			sd.incFailovers(operation)
+			return nil
+		}
+		tracelog.WarningLogger.Printf("Failed to fetch oplog archive '%s' from failover storage #%d: %v",
+			arch.Filename(), i+1, err)
+		missingEverywhere = missingEverywhere && isArchiveMissing(folder, arch)
+	}
+	if missingEverywhere {
+		return ArchiveNotFoundError{Name: arch.Filename()}
+	}
+	return fmt.Errorf("can not fetch oplog archive '%s' from any storage: %w", arch.Filename(), primaryErr)
+}
+
+// isArchiveMissing distinguishes the archive missing in the storage from the unreachable storage
+func isArchiveMissing(folder storage.Folder, arch models.Archive) bool {
+	exists, err := folder.Exists(arch.Filename())
+	return err == nil && !exists
+}
+
+// listFailoverOplogArchives merges the archives listed in the primary storage with the listings of the failover ones.
+// The failed failover listings are skipped, primaryErr is returned if none of the storages could be listed.
+func (sd *StorageDownloader) listFailoverOplogArchives(archives []models.Archive, primaryErr error) ([]models.Archive, error) {
+	seen := make(map[string]bool, len(archives))
+	for _, arch := range archives {
+		seen[arch.Filename()] = true
+	}
+	listed := 0
+	for i, folder := range sd.failoverOplogsFolders {
+		var objects []storage.Object
+		err := sd.retryPolicy.Do(func() (err error) {
+			objects, _, err = folder.ListFolder()
+			return err
+		})
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to list oplog archives in failover storage #%d: %v", i+1, err)
+			continue
+		}
+		failoverArchives, err := archivesFromObjects(objects)
+		if err != nil {
+			return nil, err
+		}
+		for _, arch := range failoverArchives {
+			if !seen[arch.Filename()] {
+				seen[arch.Filename()] = true
+				archives = append(archives, arch)
+			}
+		}
+		listed++
+	}
+	if primaryErr != nil {
+		if listed == 0 {
+			return nil, fmt.Errorf("can not list oplog archives folder in any storage: %w", primaryErr)
+		}
+		sd.incFailovers(metrics.OplogListOperation)
+	}
+	return archives, nil
+}
+
+func (sd *StorageDownloader) incFailovers(operation metrics.Operation) {
+	if sd.failoverCounter != nil {
+		sd.failoverCounter.IncFailovers(operation)
+	}
+}
@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// unreachableFolder fails all the reads and listings
+type unreachableFolder struct {
+	storage.Folder
+}
+
+func (f unreachableFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("storage is unreachable")
+}
+
+func (f unreachableFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	return nil, nil, fmt.Errorf("storage is unreachable")
+}
+
+func (f unreachableFolder) Exists(objectRelativePath string) (bool, error) {
+	return false, fmt.Errorf("storage is unreachable")
+}
+
+type testFailoverCounter struct {
+	mu        sync.Mutex
+	failovers map[metrics.Operation]int
+}
+
+func (c *testFailoverCounter) IncFailovers(operation metrics.Operation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failovers[operation]++
+}
+
+func uploadTestOplogArchive(t *testing.T, folder storage.Folder, start uint32, content string) models.Archive {
+	firstTS, lastTS := models.Timestamp{TS: start, Inc: 1}, models.Timestamp{TS: start + 1, Inc: 1}
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadOplogArchive(bytes.NewReader([]byte(content)), firstTS, lastTS))
+	arch, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	return arch
+}
+
+func newFailoverDownloader(primary storage.Folder, failovers ...storage.Folder) (*StorageDownloader, *testFailoverCounter) {
+	downloader := &StorageDownloader{oplogsFolder: primary, failoverOplogsFolders: failovers}
+	counter := &testFailoverCounter{failovers: map[metrics.Operation]int{}}
+	downloader.SetFailoverCounter(counter)
+	return downloader, counter
+}
+
+func TestStorageDownloader_DownloadOplogArchive_FailsOver(t *testing.T) {
+	secondary := memory.NewFolder("", memory.NewStorage())
+	arch := uploadTestOplogArchive(t, secondary, 1, "oplog")
+
+	for _, primary := range []storage.Folder{memory.NewFolder("", memory.NewStorage()),
+		unreachableFolder{memory.NewFolder("", memory.NewStorage())}} {
+		downloader, counter := newFailoverDownloader(primary, secondary)
+		var buf bytes.Buffer
+		assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+		assert.Equal(t, "oplog", buf.String())
+
+		reader, err := downloader.OplogArchiveReader(arch)
+		assert.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "oplog", string(data))
+		assert.NoError(t, reader.Close())
+
+		assert.Equal(t, 2, counter.failovers[metrics.OplogArchiveOperation])
+	}
+}
+
+func TestStorageDownloader_DownloadOplogArchive_PrefersPrimary(t *testing.T) {
+	primary, secondary := memory.NewFolder("", memory.NewStorage()), memory.NewFolder("", memory.NewStorage())
+	arch := uploadTestOplogArchive(t, primary, 1, "primary")
+	uploadTestOplogArchive(t, secondary, 1, "secondary")
+
+	downloader, counter := newFailoverDownloader(primary, secondary)
+	var buf bytes.Buffer
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, "primary", buf.String())
+	assert.Empty(t, counter.failovers)
+}
+
+func TestStorageDownloader_DownloadOplogArchive_MissingEverywhere(t *testing.T) {
+	arch := uploadTestOplogArchive(t, memory.NewFolder("", memory.NewStorage()), 1, "oplog")
+
+	downloader, counter := newFailoverDownloader(memory.NewFolder("", memory.NewStorage()),
+		memory.NewFolder("", memory.NewStorage()))
+	var buf bytes.Buffer
+	err := downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf})
+	assert.Equal(t, ArchiveNotFoundError{Name: arch.Filename()}, err)
+	assert.Empty(t, counter.failovers)
+
+	downloader, _ = newFailoverDownloader(unreachableFolder{memory.NewFolder("", memory.NewStorage())},
+		memory.NewFolder("", memory.NewStorage()))
+	err = downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf})
+	assert.Error(t, err)
+	var notFoundErr ArchiveNotFoundError
+	assert.False(t, errors.As(err, &notFoundErr))
+}
+
+func TestStorageDownloader_ListOplogArchives_MergesFailoverListings(t *testing.T) {
+	primary, secondary := memory.NewFolder("", memory.NewStorage()), memory.NewFolder("", memory.NewStorage())
+	shared := uploadTestOplogArchive(t, primary, 1, "oplog")
+	uploadTestOplogArchive(t, secondary, 1, "oplog")
+	secondaryOnly := uploadTestOplogArchive(t, secondary, 2, "oplog")
+
+	downloader, counter := newFailoverDownloader(primary, unreachableFolder{secondary}, secondary)
+	archives, err := downloader.ListOplogArchives()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Archive{shared, secondaryOnly}, archives)
+	assert.Empty(t, counter.failovers)
+
+	downloader, counter = newFailoverDownloader(unreachableFolder{primary}, secondary)
+	archives, err = downloader.ListOplogArchives()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []models.Archive{shared, secondaryOnly}, archives)
+	assert.Equal(t, 1, counter.failovers[metrics.OplogListOperation])
+
+	downloader, _ = newFailoverDownloader(unreachableFolder{primary}, unreachableFolder{secondary})
+	_, err = downloader.ListOplogArchives()
+	assert.Error(t, err)
+}
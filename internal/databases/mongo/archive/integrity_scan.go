@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// IntegrityStatus is the result of the stored object check
+type IntegrityStatus string
+
+const (
+	IntegrityOK IntegrityStatus = "ok"
+	// IntegrityMismatch means the object is readable but its digest or size differs from the stored checksum
+	IntegrityMismatch IntegrityStatus = "mismatch"
+	// IntegrityUnreadable means the object can not be downloaded, decrypted or decompressed
+	IntegrityUnreadable IntegrityStatus = "unreadable"
+	// IntegrityNoChecksum means the object is readable but the checksum was not stored on upload
+	IntegrityNoChecksum IntegrityStatus = "no_checksum"
+
+	IntegrityBackupObject = "backup"
+	IntegrityOplogObject  = "oplog"
+)
+
+// IntegrityCheck is the result of the backup or oplog archive check
+type IntegrityCheck struct {
+	ObjectName     string
+	Type           string
+	ExpectedSHA256 string `json:",omitempty"`
+	ActualSHA256   string `json:",omitempty"`
+	ExpectedSize   int64  `json:",omitempty"`
+	ActualSize     int64  `json:",omitempty"`
+	Status         IntegrityStatus
+	Error          string `json:",omitempty"`
+}
+
+// IsCorrupted is true if the object is not readable or does not match the stored checksum
+func (check IntegrityCheck) IsCorrupted() bool {
+	return check.Status == IntegrityMismatch || check.Status == IntegrityUnreadable
+}
+
+// IntegrityScanOptions bounds the cost of the scan
+type IntegrityScanOptions struct {
+	// SamplePercent of the objects picked randomly are checked, all the objects are checked if it is 100
+	SamplePercent float64
+	// Parallelism is the number of objects downloaded at once
+	Parallelism int
+	Random      *rand.Rand
+}
+
+// Validate checks the sample percent and the parallelism are in the allowed range
+func (opts IntegrityScanOptions) Validate() error {
+	if opts.SamplePercent <= 0 || opts.SamplePercent > 100 {
+		return fmt.Errorf("sample percent must be in (0, 100] range, got %v", opts.SamplePercent)
+	}
+	if opts.Parallelism < 1 {
+		return fmt.Errorf("parallelism must be positive, got %d", opts.Parallelism)
+	}
+	return nil
+}
+
+// integrityTask checks one object by downloading it to the digest, the stored checksum is read lazily
+// to skip it for the objects left out of the sample
+type integrityTask struct {
+	check IntegrityCheck
+	// open reads the stored checksum and returns the function downloading the object
+	open func() (*models.StreamChecksum, func(digest *streamDigest) error, error)
+}
+
+// ScanIntegrity downloads and decompresses the sampled backups and oplog archives and compares their digests
+// with the checksums stored on upload. The checks are returned in the order of the backups and then the archives.
+func (sd *StorageDownloader) ScanIntegrity(opts IntegrityScanOptions) ([]IntegrityCheck, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	tasks, err := sd.integrityTasks()
+	if err != nil {
+		return nil, err
+	}
+	if opts.SamplePercent < 100 {
+		tasks = sampleIntegrityTasks(tasks, opts.SamplePercent, opts.Random)
+	}
+	return runIntegrityTasks(tasks, opts.Parallelism), nil
+}
+
+func (sd *StorageDownloader) integrityTasks() ([]integrityTask, error) {
+	backupTimes, _, err := sd.ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("can not list backups: %w", err)
+	}
+	archives, err := sd.ListOplogArchives()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]integrityTask, 0, len(backupTimes)+len(archives))
+	for _, backupName := range BackupNamesFromBackupTimes(backupTimes) {
+		tasks = append(tasks, sd.backupIntegrityTask(backupName))
+	}
+	for _, arch := range archives {
+		if arch.Type == models.ArchiveTypeOplog {
+			tasks = append(tasks, sd.oplogIntegrityTask(arch))
+		}
+	}
+	return tasks, nil
+}
+
+func (sd *StorageDownloader) backupIntegrityTask(backupName string) integrityTask {
+	return integrityTask{
+		check: IntegrityCheck{ObjectName: backupName, Type: IntegrityBackupObject},
+		open: func() (*models.StreamChecksum, func(digest *streamDigest) error, error) {
+			sentinel, err := sd.BackupMeta(backupName)
+			if err != nil {
+				return nil, nil, err
+			}
+			return sentinel.Checksum, func(digest *streamDigest) error {
+				backup := internal.NewBackup(BackupDataFolder(sd.rootFolder, &sentinel), backupName)
+				fetcher, err := BackupStreamFetcher(backup, &sentinel)
+				if err != nil {
+					return fmt.Errorf("can not fetch stream metadata: %w", err)
+				}
+				return fetcher(backup, digest)
+			}, nil
+		},
+	}
+}
+
+func (sd *StorageDownloader) oplogIntegrityTask(arch models.Archive) integrityTask {
+	return integrityTask{
+		check: IntegrityCheck{ObjectName: arch.Filename(), Type: IntegrityOplogObject},
+		open: func() (*models.StreamChecksum, func(digest *streamDigest) error, error) {
+			download := func(digest *streamDigest) error {
+				return sd.DownloadOplogArchive(arch, nil, digest)
+			}
+			checksum, err := readArchiveChecksum(sd.oplogsFolder, arch)
+			if err != nil || checksum == nil {
+				return nil, download, err
+			}
+			return &checksum.StreamChecksum, download, nil
+		},
+	}
+}
+
+// readArchiveChecksum returns nil checksum if it was not uploaded along with the archive
+func readArchiveChecksum(folder storage.Folder, arch models.Archive) (*models.ArchiveChecksum, error) {
+	reader, err := folder.GetSubFolder(models.OplogChecksumsPath).ReadObject(arch.ChecksumFilename())
+	var notFoundErr storage.ObjectNotFoundError
+	if errors.As(err, &notFoundErr) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not read archive checksum: %w", err)
+	}
+	defer utility.LoggedClose(reader, "")
+
+	var checksum models.ArchiveChecksum
+	if err := json.NewDecoder(reader).Decode(&checksum); err != nil {
+		return nil, fmt.Errorf("can not unmarshal archive checksum: %w", err)
+	}
+	return &checksum, nil
+}
+
+func (task integrityTask) run() IntegrityCheck {
+	check := task.check
+	expected, download, err := task.open()
+	if err != nil {
+		check.Status, check.Error = IntegrityUnreadable, err.Error()
+		return check
+	}
+	if expected != nil {
+		check.ExpectedSHA256, check.ExpectedSize = expected.SHA256, expected.Size
+	}
+
+	digest := newStreamDigest()
+	if err := download(digest); err != nil {
+		check.Status, check.Error = IntegrityUnreadable, err.Error()
+		return check
+	}
+
+	actual := digest.checksum()
+	check.ActualSHA256, check.ActualSize = actual.SHA256, actual.Size
+	switch {
+	case check.ExpectedSHA256 == "":
+		check.Status = IntegrityNoChecksum
+	case check.ExpectedSHA256 != check.ActualSHA256 || check.ExpectedSize != check.ActualSize:
+		check.Status = IntegrityMismatch
+	default:
+		check.Status = IntegrityOK
+	}
+	return check
+}
+
+// sampleIntegrityTasks picks each task with the samplePercent probability keeping the order
+func sampleIntegrityTasks(tasks []integrityTask, samplePercent float64, random *rand.Rand) []integrityTask {
+	if random == nil {
+		random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	sampled := tasks[:0]
+	for _, task := range tasks {
+		if random.Float64()*100 < samplePercent {
+			sampled = append(sampled, task)
+		}
+	}
+	return sampled
+}
+
+func runIntegrityTasks(tasks []integrityTask, parallelism int) []IntegrityCheck {
+	checks := make([]IntegrityCheck, len(tasks))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range tasks {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			checks[i] = tasks[i].run()
+		}(i)
+	}
+	wg.Wait()
+	return checks
+}
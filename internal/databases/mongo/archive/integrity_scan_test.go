@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestStorageDownloader_ScanIntegrity(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	backupsFolder, oplogsFolder := folder.GetSubFolder(utility.BaseBackupPath), folder.GetSubFolder(models.OplogArchBasePath)
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	constructor := &testMetaConstructor{}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, backupsFolder)).
+		UploadBackup(strings.NewReader(strings.Repeat("backup data ", 1000)), testErrWaiter{}, constructor))
+
+	oplogUploader := NewStorageUploader(internal.NewUploader(compressor, oplogsFolder))
+	archives := make([]models.Archive, 0, 4)
+	for i := uint32(1); i <= 4; i++ {
+		arch, err := models.NewArchive(models.Timestamp{TS: i}, models.Timestamp{TS: i + 1}, compressor.FileExtension(),
+			models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		assert.NoError(t, oplogUploader.UploadOplogArchive(strings.NewReader(strings.Repeat("oplog ", int(i)*100)), arch.Start, arch.End))
+		archives = append(archives, arch)
+	}
+	checksumsFolder := oplogsFolder.GetSubFolder(models.OplogChecksumsPath)
+	assert.NoError(t, checksumsFolder.DeleteObjects([]string{archives[1].ChecksumFilename()}))
+	assert.NoError(t, checksumsFolder.PutObject(archives[2].ChecksumFilename(),
+		strings.NewReader(`{"Size": 300, "SHA256": "0000"}`)))
+	assert.NoError(t, oplogsFolder.PutObject(archives[3].Filename(), strings.NewReader("not lz4")))
+
+	downloader := &StorageDownloader{rootFolder: folder, backupsFolder: backupsFolder, oplogsFolder: oplogsFolder}
+	checks, err := downloader.ScanIntegrity(IntegrityScanOptions{SamplePercent: 100, Parallelism: 2})
+	assert.NoError(t, err)
+	statuses := make(map[string]IntegrityStatus, len(checks))
+	for _, check := range checks {
+		statuses[check.ObjectName] = check.Status
+	}
+	assert.Equal(t, map[string]IntegrityStatus{
+		constructor.backup.BackupName: IntegrityOK,
+		archives[0].Filename():        IntegrityOK,
+		archives[1].Filename():        IntegrityNoChecksum,
+		archives[2].Filename():        IntegrityMismatch,
+		archives[3].Filename():        IntegrityUnreadable,
+	}, statuses)
+	assert.Equal(t, IntegrityBackupObject, checks[0].Type)
+	assert.Equal(t, checks[0].ExpectedSHA256, checks[0].ActualSHA256)
+	assert.Equal(t, int64(12000), checks[0].ActualSize)
+
+	sampled, err := downloader.ScanIntegrity(IntegrityScanOptions{SamplePercent: 50, Parallelism: 1,
+		Random: rand.New(rand.NewSource(1))})
+	assert.NoError(t, err)
+	assert.Less(t, len(sampled), len(checks))
+}
+
+func TestStoragePurger_DeleteOplogArchives_DeletesChecksums(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	firstTS, lastTS := models.Timestamp{TS: 1}, models.Timestamp{TS: 2}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadOplogArchive(strings.NewReader("oplog"), firstTS, lastTS))
+	arch, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	checksum, err := readArchiveChecksum(folder, arch)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), checksum.Size)
+
+	assert.NoError(t, (&StoragePurger{oplogsFolder: folder}).DeleteOplogArchives([]models.Archive{arch}))
+	checksum, err = readArchiveChecksum(folder, arch)
+	assert.NoError(t, err)
+	assert.Nil(t, checksum)
+}
+
+func TestIntegrityScanOptions_Validate(t *testing.T) {
+	assert.NoError(t, IntegrityScanOptions{SamplePercent: 100, Parallelism: 1}.Validate())
+	assert.Error(t, IntegrityScanOptions{SamplePercent: 0, Parallelism: 1}.Validate())
+	assert.Error(t, IntegrityScanOptions{SamplePercent: 101, Parallelism: 1}.Validate())
+	assert.Error(t, IntegrityScanOptions{SamplePercent: 10, Parallelism: 0}.Validate())
+}
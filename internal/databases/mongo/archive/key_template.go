@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// Key template variables.
+const (
+	ClusterKeyVariable = "cluster"
+	YearKeyVariable    = "yyyy"
+	MonthKeyVariable   = "mm"
+	DayKeyVariable     = "dd"
+)
+
+var keyTemplateVariableRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// KeyTemplate builds the storage key prefix of the backup data, e.g. {cluster}/{yyyy}/{mm},
+// the dates are expanded from the backup time in UTC.
+type KeyTemplate struct {
+	template string
+	cluster  string
+}
+
+// NewKeyTemplate validates the template, the unknown variables are rejected.
+func NewKeyTemplate(template, cluster string) (*KeyTemplate, error) {
+	template = strings.Trim(template, "/")
+	if template == "" {
+		return nil, fmt.Errorf("key template must not be empty")
+	}
+	literal := keyTemplateVariableRegexp.ReplaceAllString(template, "")
+	if strings.ContainsAny(literal, "{}") {
+		return nil, fmt.Errorf("key template '%s' has unbalanced braces", template)
+	}
+	for _, match := range keyTemplateVariableRegexp.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case YearKeyVariable, MonthKeyVariable, DayKeyVariable:
+		case ClusterKeyVariable:
+			if cluster == "" {
+				return nil, fmt.Errorf("key template '%s' requires %s to be set", template, internal.MongoDBClusterName)
+			}
+			if strings.Contains(cluster, "/") {
+				return nil, fmt.Errorf("cluster name '%s' must not contain '/'", cluster)
+			}
+		default:
+			return nil, fmt.Errorf("unknown variable '%s' in key template '%s'", match[0], template)
+		}
+	}
+	for _, segment := range strings.Split(template, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("key template '%s' has invalid path segment '%s'", template, segment)
+		}
+	}
+	return &KeyTemplate{template: template, cluster: cluster}, nil
+}
+
+// ConfigureKeyTemplate reads the backup key template from the settings, nil is returned if it is not set.
+func ConfigureKeyTemplate() (*KeyTemplate, error) {
+	template, ok := internal.GetSetting(internal.MongoDBBackupKeyTemplate)
+	if !ok {
+		return nil, nil
+	}
+	keyTemplate, err := NewKeyTemplate(template, viper.GetString(internal.MongoDBClusterName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", internal.MongoDBBackupKeyTemplate, err)
+	}
+	return keyTemplate, nil
+}
+
+// String returns the template as it is configured.
+func (kt *KeyTemplate) String() string {
+	return kt.template
+}
+
+// Expand substitutes the variables, the dates are taken from ts in UTC.
+func (kt *KeyTemplate) Expand(ts time.Time) string {
+	ts = ts.UTC()
+	values := map[string]string{
+		ClusterKeyVariable: kt.cluster,
+		YearKeyVariable:    ts.Format("2006"),
+		MonthKeyVariable:   ts.Format("01"),
+		DayKeyVariable:     ts.Format("02"),
+	}
+	return keyTemplateVariableRegexp.ReplaceAllStringFunc(kt.template, func(variable string) string {
+		return values[strings.Trim(variable, "{}")]
+	})
+}
+
+// keyTemplateTime is the time the backup is named by: the resumed upload keeps the prefix of the backup being resumed.
+func keyTemplateTime() time.Time {
+	resumeToken := viper.GetString(internal.StreamResumeTokenSetting)
+	if ts, err := time.Parse(utility.BackupTimeFormat, strings.TrimPrefix(resumeToken, internal.StreamPrefix)); err == nil {
+		return ts
+	}
+	return utility.TimeNowCrossPlatformUTC()
+}
+
+// BackupDataFolder returns the folder with the backup stream, it is under the key prefix recorded in the sentinel
+// if the backup was uploaded with the key template.
+func BackupDataFolder(rootFolder storage.Folder, backup *models.Backup) storage.Folder {
+	return rootFolder.GetSubFolder(path.Join(backup.KeyPrefix, utility.BaseBackupPath))
+}
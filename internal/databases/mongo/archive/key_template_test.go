@@ -0,0 +1,88 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestNewKeyTemplate_Expand(t *testing.T) {
+	keyTemplate, err := NewKeyTemplate("/backups/{cluster}/{yyyy}/{mm}/{dd}/", "rs01")
+	assert.NoError(t, err)
+	assert.Equal(t, "backups/{cluster}/{yyyy}/{mm}/{dd}", keyTemplate.String())
+	ts := time.Date(2021, 3, 31, 23, 30, 0, 0, time.FixedZone("", -3*60*60))
+	assert.Equal(t, "backups/rs01/2021/04/01", keyTemplate.Expand(ts))
+}
+
+func TestNewKeyTemplate_Invalid(t *testing.T) {
+	for template, cluster := range map[string]string{
+		"":                     "rs01",
+		"{cluster}/{yy}":       "rs01",
+		"{cluster}/{yyyy":      "rs01",
+		"{cluster}}/{yyyy}":    "rs01",
+		"{cluster}/{mm}":       "",
+		"{cluster}/{mm}/":      "rs/01",
+		"{yyyy}//{mm}":         "",
+		"{yyyy}/../{mm}":       "",
+		"{yyyy}/{mm}/{bucket}": "rs01",
+	} {
+		_, err := NewKeyTemplate(template, cluster)
+		assert.Error(t, err, template)
+	}
+}
+
+func TestBackupStorageUploader_UploadsUnderKeyPrefix(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	keyTemplate, err := NewKeyTemplate("{cluster}/{yyyy}", "rs01")
+	assert.NoError(t, err)
+	su := NewBackupStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder), keyTemplate)
+	constructor := &testMetaConstructor{}
+	assert.NoError(t, su.UploadBackup(strings.NewReader(strings.Repeat("backup data ", 1000)), testErrWaiter{}, constructor))
+	backupName := constructor.backup.BackupName
+	keyPrefix := "rs01/" + utility.TimeNowCrossPlatformUTC().Format("2006")
+
+	downloader := &StorageDownloader{rootFolder: folder, backupsFolder: folder.GetSubFolder(utility.BaseBackupPath),
+		oplogsFolder: folder.GetSubFolder(models.OplogArchBasePath)}
+	backupTimes, garbage, err := downloader.ListBackups()
+	assert.NoError(t, err)
+	assert.Len(t, backupTimes, 1)
+	assert.Empty(t, garbage)
+	sentinel, err := downloader.BackupMeta(backupName)
+	assert.NoError(t, err)
+	assert.Equal(t, "{cluster}/{yyyy}", sentinel.KeyTemplate)
+	assert.Equal(t, keyPrefix, sentinel.KeyPrefix)
+
+	dataObjects, _, err := BackupDataFolder(folder, &sentinel).GetSubFolder(backupName).ListFolder()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, dataObjects)
+	checks, err := downloader.ScanIntegrity(IntegrityScanOptions{SamplePercent: 100, Parallelism: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, IntegrityOK, checks[0].Status)
+
+	purger := &StoragePurger{rootFolder: folder, backupsFolder: downloader.backupsFolder}
+	assert.NoError(t, purger.DeleteBackups([]models.Backup{sentinel}))
+	dataObjects, _, err = BackupDataFolder(folder, &sentinel).GetSubFolder(backupName).ListFolder()
+	assert.NoError(t, err)
+	assert.Empty(t, dataObjects)
+	backupTimes, _, err = downloader.ListBackups()
+	assert.NoError(t, err)
+	assert.Empty(t, backupTimes)
+}
+
+func TestKeyTemplateTime_ResumedUpload(t *testing.T) {
+	viper.Set(internal.StreamResumeTokenSetting, internal.StreamPrefix+"20201231T235959Z")
+	defer viper.Set(internal.StreamResumeTokenSetting, nil)
+	assert.Equal(t, time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC), keyTemplateTime())
+}
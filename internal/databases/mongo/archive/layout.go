@@ -2,6 +2,8 @@ package archive
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/wal-g/tracelog"
@@ -64,6 +66,101 @@ func SequenceBetweenTS(archives []models.Archive, since, until models.Timestamp)
 	return nil, fmt.Errorf("cycles in archive sequence detected")
 }
 
+// ArchivesGapError is returned when the oplog archives do not cover the requested window
+type ArchivesGapError struct {
+	After  models.Timestamp
+	Before models.Timestamp
+}
+
+func (err ArchivesGapError) Error() string {
+	return fmt.Sprintf("oplog archives gap detected: oplog after '%s' and before '%s' is missing", err.After, err.Before)
+}
+
+// ArchivesBetweenTS selects the oplog archives required to replay oplog after the since ts up to the until ts,
+// the window is (since, until] as the archive is (Start, End]. Nothing is required if since equals until.
+// Result is sorted by the start ts, overlapping archives are kept as is.
+// ArchivesGapError is returned if selected archives do not cover the whole window.
+func ArchivesBetweenTS(archives []models.Archive, since, until models.Timestamp) ([]models.Archive, error) {
+	if models.LessTS(until, since) {
+		return nil, fmt.Errorf("until ts must be greater or equal to since ts")
+	}
+
+	selected := selectArchivesBetweenTS(archives, since, until)
+	if since == until {
+		return selected, nil
+	}
+	if len(selected) == 0 {
+		return nil, ArchivesGapError{After: since, Before: until}
+	}
+	if models.LessTS(since, selected[0].Start) {
+		return nil, ArchivesGapError{After: since, Before: selected[0].Start}
+	}
+	coveredTS := selected[0].End
+	for _, arch := range selected[1:] {
+		if models.LessTS(coveredTS, arch.Start) {
+			return nil, ArchivesGapError{After: coveredTS, Before: arch.Start}
+		}
+		coveredTS = models.MaxTS(coveredTS, arch.End)
+	}
+	if models.LessTS(coveredTS, until) {
+		return nil, ArchivesGapError{After: coveredTS, Before: until}
+	}
+	return selected, nil
+}
+
+// OplogChainGaps verifies that the oplog archives required to replay oplog after the since ts up to the until ts
+// form the chain: each archive starts exactly at the end of the previous one. Holes and overlaps are returned as gaps.
+func OplogChainGaps(archives []models.Archive, since, until models.Timestamp) ([]models.Gap, error) {
+	if models.LessTS(until, since) {
+		return nil, fmt.Errorf("until ts must be greater or equal to since ts")
+	}
+
+	selected := selectArchivesBetweenTS(archives, since, until)
+	if since == until {
+		return []models.Gap{}, nil
+	}
+	if len(selected) == 0 {
+		return []models.Gap{{Start: since, End: until}}, nil
+	}
+
+	gaps := make([]models.Gap, 0)
+	if models.LessTS(since, selected[0].Start) {
+		gaps = append(gaps, models.Gap{Start: since, End: selected[0].Start})
+	}
+	for i := 1; i < len(selected); i++ {
+		prevEnd, start := selected[i-1].End, selected[i].Start
+		if models.LessTS(prevEnd, start) {
+			gaps = append(gaps, models.Gap{Start: prevEnd, End: start})
+		} else if models.LessTS(start, prevEnd) {
+			gaps = append(gaps, models.Gap{Start: start, End: prevEnd, Overlap: true})
+		}
+	}
+	if lastEnd := selected[len(selected)-1].End; models.LessTS(lastEnd, until) {
+		gaps = append(gaps, models.Gap{Start: lastEnd, End: until})
+	}
+	return gaps, nil
+}
+
+// selectArchivesBetweenTS filters oplog archives containing any oplog in the (since, until] window,
+// result is sorted by the start ts
+func selectArchivesBetweenTS(archives []models.Archive, since, until models.Timestamp) []models.Archive {
+	selected := make([]models.Archive, 0)
+	for _, arch := range archives {
+		// archive contains oplog with ts in (Start, End] interval, both bounds of the window are compared the same way
+		if arch.Type != models.ArchiveTypeOplog || !models.LessTS(since, arch.End) || !models.LessTS(arch.Start, until) {
+			continue
+		}
+		selected = append(selected, arch)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].Start == selected[j].Start {
+			return models.LessTS(selected[i].End, selected[j].End)
+		}
+		return models.LessTS(selected[i].Start, selected[j].Start)
+	})
+	return selected
+}
+
 // BackupNamesFromBackupTimes forms list of backup names from BackupTime
 func BackupNamesFromBackupTimes(backups []internal.BackupTime) []string {
 	names := make([]string, 0, len(backups))
@@ -111,6 +208,78 @@ func SplitMongoBackups(backups []models.Backup, purgeBackups, retainBackups map[
 	return purge, retain
 }
 
+// SplitPurgingBackupsByFinishTime selects backups to be deleted and retained by their finish time.
+// Permanent backups, the newest retainCount backups and backups finished after retainAfter are retained.
+// The newest backup finished before retainAfter is retained as well if oplog archives make it a base
+// of the restore points after retainAfter, it is returned as pitrBase.
+func SplitPurgingBackupsByFinishTime(backups []models.Backup,
+	archives []models.Archive,
+	retainCount *int,
+	retainAfter *time.Time) (purge, retain []models.Backup, pitrBase *models.Backup) {
+	sorted := make([]models.Backup, len(backups))
+	copy(sorted, backups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].FinishLocalTime.After(sorted[j].FinishLocalTime)
+	})
+
+	if retainAfter != nil {
+		pitrBase = selectPITRBase(sorted, archives, *retainAfter)
+	}
+
+	retainedCount := 0
+	for i := range sorted {
+		backup := sorted[i]
+		switch {
+		case backup.Permanent:
+			tracelog.DebugLogger.Printf("Preserving backup due to keep permanent policy: %s", backup.Name())
+		case retainCount != nil && retainedCount < *retainCount:
+			retainedCount++
+			tracelog.DebugLogger.Printf("Preserving backup due to retain count policy [%d/%d]: %s",
+				retainedCount, *retainCount, backup.Name())
+		case retainAfter != nil && backup.FinishLocalTime.After(*retainAfter):
+			tracelog.DebugLogger.Printf("Preserving backup due to retain time policy: %s", backup.Name())
+		case pitrBase != nil && backup.BackupName == pitrBase.BackupName:
+			tracelog.DebugLogger.Printf("Preserving backup due to oplog coverage of restore points after %v: %s",
+				*retainAfter, backup.Name())
+		default:
+			purge = append(purge, backup)
+			continue
+		}
+		retain = append(retain, backup)
+	}
+	return purge, retain, pitrBase
+}
+
+// selectPITRBase returns the newest backup finished before retainAfter if it is the base for point-in-time recovery,
+// backups must be sorted by the finish time from the newest to the oldest
+func selectPITRBase(backups []models.Backup, archives []models.Archive, retainAfter time.Time) *models.Backup {
+	for i := range backups {
+		if backups[i].FinishLocalTime.After(retainAfter) {
+			continue
+		}
+		if IsPITRBase(backups[i], archives) {
+			return &backups[i]
+		}
+		return nil
+	}
+	return nil
+}
+
+// IsPITRBase checks that oplog archives continuously cover the backup and go beyond it,
+// so the backup is the base for point-in-time recovery to the newer timestamps.
+func IsPITRBase(backup models.Backup, archives []models.Archive) bool {
+	before, after := backup.MongoMeta.Before.LastMajTS, backup.MongoMeta.After.LastMajTS
+	if _, err := ArchivesBetweenTS(archives, before, after); err != nil {
+		return false
+	}
+	for _, arch := range archives {
+		if arch.Type == models.ArchiveTypeOplog && models.LessTS(after, arch.End) {
+			return true
+		}
+	}
+	return false
+}
+
 func MongoModelToTimedBackup(backups []models.Backup) []internal.TimedBackup {
 	if backups == nil {
 		return nil
@@ -185,7 +354,7 @@ func SelectPurgingOplogArchives(archives []models.Archive,
 		}
 
 		// retain if arch is part of backup
-		if backup := models.FirstOverlappingBackupForArch(arch, backups); backup != emptyBackup {
+		if backup := models.FirstOverlappingBackupForArch(arch, backups); !reflect.DeepEqual(backup, emptyBackup) {
 			tracelog.DebugLogger.Printf(
 				"Keeping oplog archive due to overlapping with backup (%+v): %s", backup, arch.Filename())
 			continue
@@ -194,3 +363,60 @@ func SelectPurgingOplogArchives(archives []models.Archive,
 	}
 	return purgeArchives
 }
+
+// SelectCompactingOplogArchives splits the oplog archives smaller than targetSize into the runs to be merged.
+// Each run consists of at least two archives forming the chain: every archive starts exactly at the end
+// of the previous one. The total size of the run does not exceed targetSize.
+// Runs never span gaps and overlaps, archives sharing the start ts with another one are not compacted.
+func SelectCompactingOplogArchives(sizes map[models.Archive]int64, targetSize int64) [][]models.Archive {
+	archives := make([]models.Archive, 0, len(sizes))
+	startCount := make(map[models.Timestamp]int, len(sizes))
+	for arch := range sizes {
+		if arch.Type != models.ArchiveTypeOplog {
+			continue
+		}
+		archives = append(archives, arch)
+		startCount[arch.Start]++
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		if archives[i].Start == archives[j].Start {
+			return models.LessTS(archives[i].End, archives[j].End)
+		}
+		return models.LessTS(archives[i].Start, archives[j].Start)
+	})
+
+	var runs [][]models.Archive
+	var run []models.Archive
+	var runSize int64
+	finishRun := func() {
+		if len(run) > 1 {
+			runs = append(runs, run)
+		}
+		run, runSize = nil, 0
+	}
+	for _, arch := range archives {
+		size := sizes[arch]
+		if size >= targetSize || startCount[arch.Start] > 1 {
+			finishRun()
+			continue
+		}
+		if len(run) > 0 && (run[len(run)-1].End != arch.Start || runSize+size > targetSize) {
+			finishRun()
+		}
+		run = append(run, arch)
+		runSize += size
+	}
+	finishRun()
+	return runs
+}
+
+// VerifyOplogChain checks that each archive starts exactly at the end of the previous one,
+// ArchivesGapError is returned otherwise.
+func VerifyOplogChain(archives []models.Archive) error {
+	for i := 1; i < len(archives); i++ {
+		if archives[i-1].End != archives[i].Start {
+			return ArchivesGapError{After: archives[i-1].End, Before: archives[i].Start}
+		}
+	}
+	return nil
+}
@@ -292,6 +292,264 @@ func TestSequenceBetweenTSMarkedGaps(t *testing.T) {
 	}
 }
 
+func TestArchivesBetweenTS(t *testing.T) {
+	type args struct {
+		since models.Timestamp
+		until models.Timestamp
+	}
+
+	tests := []struct {
+		name     string
+		archives []models.Archive
+		args     args
+		expected []models.Archive
+		err      error
+	}{
+		{
+			name:     "whole range",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579000001, Inc: 2},
+				until: models.Timestamp{TS: 1579004001, Inc: 2},
+			},
+			expected: continuousArchives,
+		},
+		{
+			name:     "archives straddle the boundaries",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579001500, Inc: 1},
+				until: models.Timestamp{TS: 1579002500, Inc: 1},
+			},
+			expected: continuousArchives[1:4],
+		},
+		{
+			name:     "since equals until",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579002001, Inc: 1},
+				until: models.Timestamp{TS: 1579002001, Inc: 1},
+			},
+			expected: []models.Archive{},
+		},
+		{
+			name:     "archive ending at since is not required",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579002001, Inc: 1},
+				until: models.Timestamp{TS: 1579002001, Inc: 50},
+			},
+			expected: continuousArchives[2:3],
+		},
+		{
+			name:     "since is the start of the first archive",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579000001, Inc: 1},
+				until: models.Timestamp{TS: 1579001001, Inc: 1},
+			},
+			expected: continuousArchives[:1],
+		},
+		{
+			name:     "overlapping archives are kept",
+			archives: shuffledArchives(continuousArchivesOverlappedFirst),
+			args: args{
+				since: models.Timestamp{TS: 1579000001, Inc: 2},
+				until: models.Timestamp{TS: 1579001001, Inc: 2},
+			},
+			expected: continuousArchivesOverlappedFirst[:2],
+		},
+		{
+			name:     "error: gap inside the range",
+			archives: shuffledArchives(gapArchivesWithMarks),
+			args: args{
+				since: models.Timestamp{TS: 1579000001, Inc: 2},
+				until: models.Timestamp{TS: 1579004001, Inc: 2},
+			},
+			err: ArchivesGapError{After: models.Timestamp{TS: 1579002001, Inc: 1}, Before: models.Timestamp{TS: 1579002001, Inc: 99}},
+		},
+		{
+			name:     "error: since is before the first archive",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579000000, Inc: 1},
+				until: models.Timestamp{TS: 1579001001, Inc: 1},
+			},
+			err: ArchivesGapError{After: models.Timestamp{TS: 1579000000, Inc: 1}, Before: models.Timestamp{TS: 1579000001, Inc: 1}},
+		},
+		{
+			name:     "error: until is after the last archive",
+			archives: shuffledArchives(continuousArchives),
+			args: args{
+				since: models.Timestamp{TS: 1579003500, Inc: 1},
+				until: models.Timestamp{TS: 1579005000, Inc: 1},
+			},
+			err: ArchivesGapError{After: models.Timestamp{TS: 1579004001, Inc: 2}, Before: models.Timestamp{TS: 1579005000, Inc: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ArchivesBetweenTS(tt.archives, tt.args.since, tt.args.until)
+			if tt.err != nil {
+				assert.Equal(t, tt.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestOplogChainGaps(t *testing.T) {
+	since := models.Timestamp{TS: 1579000001, Inc: 2}
+	until := models.Timestamp{TS: 1579004001, Inc: 2}
+
+	tests := []struct {
+		name     string
+		archives []models.Archive
+		since    models.Timestamp
+		until    models.Timestamp
+		expected []models.Gap
+	}{
+		{
+			name:     "continuous chain",
+			archives: shuffledArchives(continuousArchives),
+			since:    since,
+			until:    until,
+			expected: []models.Gap{},
+		},
+		{
+			name:     "hole in the chain",
+			archives: shuffledArchives(gapArchivesWithMarks),
+			since:    since,
+			until:    until,
+			expected: []models.Gap{
+				{Start: models.Timestamp{TS: 1579002001, Inc: 1}, End: models.Timestamp{TS: 1579002001, Inc: 99}},
+			},
+		},
+		{
+			name:     "overlap in the chain",
+			archives: shuffledArchives(continuousArchivesOverlappedMiddle),
+			since:    since,
+			until:    until,
+			expected: []models.Gap{
+				{Start: models.Timestamp{TS: 1579002001, Inc: 1}, End: models.Timestamp{TS: 1579002001, Inc: 99}, Overlap: true},
+				{Start: models.Timestamp{TS: 1579002001, Inc: 99}, End: models.Timestamp{TS: 1579002010, Inc: 1}, Overlap: true},
+			},
+		},
+		{
+			name:     "window is not covered at the borders",
+			archives: shuffledArchives(continuousArchives[1:4]),
+			since:    since,
+			until:    until,
+			expected: []models.Gap{
+				{Start: since, End: models.Timestamp{TS: 1579001001, Inc: 2}},
+				{Start: models.Timestamp{TS: 1579003001, Inc: 3}, End: until},
+			},
+		},
+		{
+			name:     "no archives",
+			archives: []models.Archive{},
+			since:    since,
+			until:    until,
+			expected: []models.Gap{{Start: since, End: until}},
+		},
+		{
+			name:     "empty window",
+			archives: []models.Archive{},
+			since:    since,
+			until:    since,
+			expected: []models.Gap{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gaps, err := OplogChainGaps(tt.archives, tt.since, tt.until)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, gaps)
+		})
+	}
+
+	_, err := OplogChainGaps(continuousArchives, until, since)
+	assert.Error(t, err)
+}
+
+func archiveSizes(archives []models.Archive, size int64) map[models.Archive]int64 {
+	sizes := make(map[models.Archive]int64, len(archives))
+	for _, arch := range archives {
+		sizes[arch] = size
+	}
+	return sizes
+}
+
+func TestSelectCompactingOplogArchives(t *testing.T) {
+	withLarge := archiveSizes(continuousArchives, 10)
+	withLarge[continuousArchives[2]] = 100
+
+	tests := []struct {
+		name       string
+		sizes      map[models.Archive]int64
+		targetSize int64
+		expected   [][]models.Archive
+	}{
+		{
+			name:       "continuous chain",
+			sizes:      archiveSizes(continuousArchives, 10),
+			targetSize: 100,
+			expected:   [][]models.Archive{continuousArchives},
+		},
+		{
+			name:       "chain is split by target size",
+			sizes:      archiveSizes(continuousArchives, 10),
+			targetSize: 20,
+			expected:   [][]models.Archive{continuousArchives[0:2], continuousArchives[2:4]},
+		},
+		{
+			name:       "large archive is kept",
+			sizes:      withLarge,
+			targetSize: 100,
+			expected:   [][]models.Archive{continuousArchives[0:2], continuousArchives[3:5]},
+		},
+		{
+			name:       "hole in the chain",
+			sizes:      archiveSizes(gapArchives, 10),
+			targetSize: 100,
+			expected:   [][]models.Archive{gapArchives[0:2], gapArchives[2:4]},
+		},
+		{
+			name:       "gap archive in the chain",
+			sizes:      archiveSizes(gapArchivesWithMarks, 10),
+			targetSize: 100,
+			expected:   [][]models.Archive{gapArchivesWithMarks[0:2], gapArchivesWithMarks[3:5]},
+		},
+		{
+			name:       "overlapping archives are kept",
+			sizes:      archiveSizes(continuousArchivesOverlappedFirst, 10),
+			targetSize: 100,
+			expected:   [][]models.Archive{continuousArchivesOverlappedFirst[2:6]},
+		},
+		{
+			name:       "nothing to compact",
+			sizes:      archiveSizes(continuousArchives[:1], 10),
+			targetSize: 100,
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SelectCompactingOplogArchives(tt.sizes, tt.targetSize))
+		})
+	}
+}
+
+func TestVerifyOplogChain(t *testing.T) {
+	assert.NoError(t, VerifyOplogChain(continuousArchives))
+	assert.Equal(t, ArchivesGapError{After: gapArchives[1].End, Before: gapArchives[2].Start}, VerifyOplogChain(gapArchives))
+}
+
 var (
 	arch1 = models.Archive{Start: models.Timestamp{TS: 1579881975, Inc: 1}, End: models.Timestamp{TS: 1579881985, Inc: 2}, Ext: "br", Type: "oplog"}
 	arch2 = models.Archive{Start: models.Timestamp{TS: 1579881985, Inc: 2}, End: models.Timestamp{TS: 1579882985, Inc: 1}, Ext: "br", Type: "oplog"}
@@ -871,3 +1129,81 @@ func TestSelectPurgingOplogArchives(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitPurgingBackupsByFinishTime(t *testing.T) {
+	newBackup := func(name string, before, after uint32, finish time.Time, permanent bool) models.Backup {
+		return models.Backup{
+			BackupName:      name,
+			FinishLocalTime: finish,
+			Permanent:       permanent,
+			MongoMeta: models.MongoMeta{
+				Before: models.NodeMeta{LastMajTS: models.Timestamp{TS: before, Inc: 1}},
+				After:  models.NodeMeta{LastMajTS: models.Timestamp{TS: after, Inc: 1}},
+			},
+		}
+	}
+	now := time.Unix(1579004001, 0)
+	oldest := newBackup("oldest", 1579000101, 1579000201, now.Add(-72*time.Hour), false)
+	permanent := newBackup("permanent", 1579000501, 1579000601, now.Add(-60*time.Hour), true)
+	base := newBackup("base", 1579001101, 1579001201, now.Add(-48*time.Hour), false)
+	overGap := newBackup("over_gap", 1579001501, 1579002501, now.Add(-48*time.Hour), false)
+	newest := newBackup("newest", 1579003101, 1579003201, now.Add(-1*time.Hour), false)
+	retainAfter := now.Add(-24 * time.Hour)
+	retainCount := 1
+
+	tests := []struct {
+		name        string
+		backups     []models.Backup
+		archives    []models.Archive
+		retainCount *int
+		retainAfter *time.Time
+		purge       []string
+		retain      []string
+		pitrBase    string
+	}{
+		{
+			name:        "base of restore points after retain time is kept",
+			backups:     []models.Backup{oldest, newest, base, permanent},
+			archives:    continuousArchives,
+			retainAfter: &retainAfter,
+			purge:       []string{"oldest"},
+			retain:      []string{"newest", "base", "permanent"},
+			pitrBase:    "base",
+		},
+		{
+			name:        "backup is not kept without oplog",
+			backups:     []models.Backup{oldest, newest, base},
+			retainAfter: &retainAfter,
+			purge:       []string{"base", "oldest"},
+			retain:      []string{"newest"},
+		},
+		{
+			name:        "backup is not kept if oplog has a gap",
+			backups:     []models.Backup{oldest, newest, overGap},
+			archives:    gapArchives,
+			retainAfter: &retainAfter,
+			purge:       []string{"over_gap", "oldest"},
+			retain:      []string{"newest"},
+		},
+		{
+			name:        "count policy",
+			backups:     []models.Backup{oldest, base, newest, permanent},
+			archives:    continuousArchives,
+			retainCount: &retainCount,
+			purge:       []string{"base", "oldest"},
+			retain:      []string{"newest", "permanent"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purge, retain, pitrBase := SplitPurgingBackupsByFinishTime(tt.backups, tt.archives, tt.retainCount, tt.retainAfter)
+			assert.Equal(t, tt.purge, BackupNamesFromBackups(purge))
+			assert.Equal(t, tt.retain, BackupNamesFromBackups(retain))
+			if tt.pitrBase == "" {
+				assert.Nil(t, pitrBase)
+			} else if assert.NotNil(t, pitrBase) {
+				assert.Equal(t, tt.pitrBase, pitrBase.BackupName)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package archive
+
+import (
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// ConfigureListCache wraps the folder to cache its listings for WALG_STORAGE_LIST_CACHE_TTL,
+// the folder is returned as is if the setting is not set or zero.
+func ConfigureListCache(folder storage.Folder) (storage.Folder, error) {
+	if _, ok := internal.GetSetting(internal.StorageListCacheTTL); !ok {
+		return folder, nil
+	}
+	ttl, err := internal.GetDurationSetting(internal.StorageListCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		return folder, nil
+	}
+	return internal.NewCachingFolder(folder, ttl), nil
+}
+
+// SetListCacheCounter registers counter to receive the listing cache hits and misses,
+// nothing is counted if it is not set or the listings are not cached.
+func (sd *StorageDownloader) SetListCacheCounter(counter metrics.CacheCounter) {
+	if listCache, ok := sd.rootFolder.(internal.ListCache); ok {
+		listCache.SetCacheCounter(counter)
+	}
+}
+
+// InvalidateListCache drops the cached listings, so the archives uploaded since they were cached are listed,
+// nothing is done if the listings are not cached.
+func (sd *StorageDownloader) InvalidateListCache() {
+	if listCache, ok := sd.rootFolder.(internal.ListCache); ok {
+		listCache.Invalidate()
+	}
+}
+
+// ListCacheHitRatio returns the share of the listings served from the cache,
+// false is returned if the listings are not cached.
+func (sd *StorageDownloader) ListCacheHitRatio() (float64, bool) {
+	if listCache, ok := sd.rootFolder.(internal.ListCache); ok {
+		return listCache.HitRatio(), true
+	}
+	return 0, false
+}
@@ -2,18 +2,24 @@ package archive
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -25,6 +31,7 @@ var (
 // Uploader defines interface to store mongodb backups and oplog archives
 type Uploader interface {
 	UploadOplogArchive(stream io.Reader, firstTS, lastTS models.Timestamp) error // TODO: rename firstTS
+	UploadOplogArchives(archives []OplogArchiveUpload, concurrency int) ([]error, error)
 	UploadGapArchive(err error, firstTS, lastTS models.Timestamp) error
 	UploadBackup(stream io.Reader, cmd internal.ErrWaiter, metaConstructor internal.MetaConstructor) error
 }
@@ -32,7 +39,8 @@ type Uploader interface {
 // Downloader defines interface to fetch mongodb oplog archives
 type Downloader interface {
 	BackupMeta(name string) (models.Backup, error)
-	DownloadOplogArchive(arch models.Archive, writeCloser io.WriteCloser) error
+	DownloadOplogArchive(arch models.Archive, from *models.Timestamp, writeCloser io.WriteCloser) error
+	OplogArchiveReader(arch models.Archive) (io.ReadCloser, error)
 	ListOplogArchives() ([]models.Archive, error)
 	LoadBackups(names []string) ([]models.Backup, error)
 	ListBackups() ([]internal.BackupTime, []string, error)
@@ -49,6 +57,8 @@ type Purger interface {
 type StorageSettings struct {
 	oplogsPath  string
 	backupsPath string
+	// rootURL is the file:// URL of the local directory used instead of the configured storage, if set
+	rootURL string
 }
 
 // NewDefaultStorageSettings builds default storage settings struct
@@ -59,25 +69,62 @@ func NewDefaultStorageSettings() StorageSettings {
 	}
 }
 
+// WithRootURL makes the downloader read the local directory with the storage layout instead of the configured storage,
+// e.g. the archives copied to the disk of the air-gapped host. Only file:// URLs are supported.
+func (opts StorageSettings) WithRootURL(rootURL string) StorageSettings {
+	opts.rootURL = rootURL
+	return opts
+}
+
 // StorageDownloader extends base folder with mongodb specific.
 type StorageDownloader struct {
 	rootFolder    storage.Folder
 	oplogsFolder  storage.Folder
 	backupsFolder storage.Folder
+	retryPolicy   RetryPolicy
+	// oplog archives are fetched from these folders in order if the primary storage fails
+	failoverOplogsFolders []storage.Folder
+	failoverCounter       metrics.FailoverCounter // failovers are counted if set
 }
 
 // NewStorageDownloader builds mongodb downloader.
 func NewStorageDownloader(opts StorageSettings) (*StorageDownloader, error) {
+	if opts.rootURL != "" {
+		return newLocalStorageDownloader(opts)
+	}
 	folder, err := internal.ConfigureFolder()
 	if err != nil {
 		return nil, err
 	}
+	folder, err = ConfigureListCache(folder)
+	if err != nil {
+		return nil, err
+	}
+	retryPolicy, err := ConfigureRetryPolicy()
+	if err != nil {
+		return nil, err
+	}
+	failoverFolders, err := ConfigureFailoverFolders()
+	if err != nil {
+		return nil, err
+	}
+	failoverOplogsFolders := make([]storage.Folder, 0, len(failoverFolders))
+	for _, failoverFolder := range failoverFolders {
+		failoverOplogsFolders = append(failoverOplogsFolders, failoverFolder.GetSubFolder(opts.oplogsPath))
+	}
 	return &StorageDownloader{rootFolder: folder,
-			oplogsFolder:  folder.GetSubFolder(opts.oplogsPath),
-			backupsFolder: folder.GetSubFolder(opts.backupsPath)},
+			oplogsFolder:          folder.GetSubFolder(opts.oplogsPath),
+			backupsFolder:         folder.GetSubFolder(opts.backupsPath),
+			retryPolicy:           retryPolicy,
+			failoverOplogsFolders: failoverOplogsFolders},
 		nil
 }
 
+// SetRetryPolicy replaces the policy used to retry downloads and listings on transient storage errors.
+func (sd *StorageDownloader) SetRetryPolicy(policy RetryPolicy) {
+	sd.retryPolicy = policy
+}
+
 // BackupMeta downloads sentinel contents.
 func (sd *StorageDownloader) BackupMeta(name string) (models.Backup, error) {
 	backup := internal.NewBackup(sd.backupsFolder, name)
@@ -109,22 +156,271 @@ func (sd *StorageDownloader) LoadBackups(names []string) ([]models.Backup, error
 }
 
 //ListBackups lists backups in folder
-func (sd *StorageDownloader) ListBackups() ([]internal.BackupTime, []string, error) {
-	return internal.GetBackupsAndGarbage(sd.backupsFolder)
+func (sd *StorageDownloader) ListBackups() (backups []internal.BackupTime, garbage []string, err error) {
+	err = sd.retryPolicy.Do(func() error {
+		backups, garbage, err = internal.GetBackupsAndGarbage(sd.backupsFolder)
+		return err
+	})
+	return backups, garbage, err
 }
 
 // DownloadOplogArchive downloads, decompresses and decrypts (if needed) oplog archive.
-func (sd *StorageDownloader) DownloadOplogArchive(arch models.Archive, writeCloser io.WriteCloser) error {
-	return internal.DownloadFile(sd.oplogsFolder, arch.Filename(), arch.Extension(), writeCloser)
+// The writeCloser may be any io.WriteCloser, e.g. ioextensions.MultiWriteCloser fanning out the oplog
+// to the disk and the validating sinks. The download is aborted by the first failed write. The writeCloser
+// is closed once the archive is written or the download fails, the Close error is returned.
+// The archive is streamed: only opening of the archive is retried and failed over.
+// If from is set, the oplog records are parsed as they are downloaded and only the ones since from are written,
+// the records are checked to be ordered by the timestamp.
+func (sd *StorageDownloader) DownloadOplogArchive(arch models.Archive, from *models.Timestamp, writeCloser io.WriteCloser) error {
+	return downloadOplogArchiveSince(sd.OplogArchiveReader, arch, from, writeCloser)
+}
+
+// downloadOplogArchiveSince streams the archive opened by openReader to the writeCloser,
+// only the records since from are written if it is set
+func downloadOplogArchiveSince(openReader func(models.Archive) (io.ReadCloser, error), arch models.Archive,
+	from *models.Timestamp, writeCloser io.WriteCloser) error {
+	if from == nil {
+		return downloadOplogArchive(openReader, arch, writeCloser)
+	}
+	tailWriter := newOplogTailWriter(writeCloser, *from)
+	if err := downloadOplogArchive(openReader, arch, tailWriter); err != nil {
+		return fmt.Errorf("can not download oplog archive '%s' since '%s': %w", arch.Filename(), *from, err)
+	}
+	return tailWriter.finish()
+}
+
+// downloadOplogArchive streams the archive to the writeCloser, it is never buffered as a whole.
+// Only opening of the archive is retried and failed over by the StorageDownloader, so no partial data is written
+// by the failed attempts. The failed reads in the middle of the stream are resumed since the offset read
+// if WALG_DOWNLOAD_RANGE_RESUMES is set, the chunks of the deduplicated archive are retried one by one.
+func downloadOplogArchive(openReader func(models.Archive) (io.ReadCloser, error), arch models.Archive,
+	writeCloser io.WriteCloser) error {
+	reader, err := openReader(arch)
+	if err != nil {
+		utility.LoggedClose(writeCloser, "")
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	if _, err = utility.FastCopy(&utility.EmptyWriteIgnorer{Writer: writeCloser}, reader); err != nil {
+		utility.LoggedClose(writeCloser, "")
+		return err
+	}
+	return writeCloser.Close()
+}
+
+func readArchiveManifest(folder storage.Folder, arch models.Archive) (models.ArchiveManifest, error) {
+	var manifest models.ArchiveManifest
+	reader, err := internal.DownloadFileReader(folder, arch.Filename(), arch.ManifestCompression())
+	if err != nil {
+		return manifest, fmt.Errorf("can not read archive manifest '%s': %w", arch.Filename(), err)
+	}
+	defer utility.LoggedClose(reader, "")
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("can not unmarshal archive manifest '%s': %w", arch.Filename(), err)
+	}
+	return manifest, nil
+}
+
+// OplogArchiveReader opens oplog archive and returns the reader streaming the decompressed oplog,
+// the archive is downloaded, decrypted and decompressed lazily as the reader is consumed.
+// Only opening of the archive is retried and failed over, the errors in the middle of the stream are returned by the reader.
+// Closing the reader releases the storage connection.
+func (sd *StorageDownloader) OplogArchiveReader(arch models.Archive) (reader io.ReadCloser, err error) {
+	err = sd.withFailover(metrics.OplogArchiveOperation, arch, func(folder storage.Folder) error {
+		return sd.retryPolicy.Do(func() error {
+			reader, err = openOplogArchive(folder, arch, sd.retryPolicy)
+			return err
+		})
+	})
+	return reader, err
+}
+
+// openOplogArchive opens the archive to be read at the rate of the network limiter,
+// the stored bytes are limited as they are received, i.e. before the decompression
+func openOplogArchive(folder storage.Folder, arch models.Archive, retryPolicy RetryPolicy) (io.ReadCloser, error) {
+	folder = newNetworkLimitFolder(folder)
+	if arch.IsManifest() {
+		manifest, err := readArchiveManifest(folder, arch)
+		if err != nil {
+			return nil, err
+		}
+		chunkStore := NewStorageChunkStore(folder.GetSubFolder(models.OplogChunksPath), nil, nil)
+		return &manifestReader{chunkStore: chunkStore, manifest: manifest, retryPolicy: retryPolicy}, nil
+	}
+	return internal.DownloadFileReader(folder, arch.Filename(), oplogArchiveExtension(folder, arch))
+}
+
+// oplogArchiveExtension returns the extension of the compression recorded in the archive checksum,
+// the archive uploaded before the compression was recorded is decompressed by its own extension.
+// The checksum is optional, so the archive is decompressed by its extension if the checksum can not be read.
+func oplogArchiveExtension(folder storage.Folder, arch models.Archive) string {
+	checksum, err := readArchiveChecksum(folder, arch)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Can not read the compression of oplog archive '%s', "+
+			"decompressing it by the extension: %v", arch.Filename(), err)
+		return arch.Extension()
+	}
+	if checksum == nil || checksum.Compression == nil || checksum.Compression.Extension == "" {
+		return arch.Extension()
+	}
+	return checksum.Compression.Extension
+}
+
+// manifestReader reads the chunks of deduplicated oplog archive one by one as the data is consumed,
+// the failed chunk download is retried by the retryPolicy
+type manifestReader struct {
+	chunkStore  *StorageChunkStore
+	manifest    models.ArchiveManifest
+	retryPolicy RetryPolicy
+	next        int
+	buf         bytes.Buffer
+}
+
+func (mr *manifestReader) Read(p []byte) (int, error) {
+	for mr.buf.Len() == 0 {
+		if mr.next == len(mr.manifest.Chunks) {
+			return 0, io.EOF
+		}
+		err := mr.retryPolicy.Do(func() error {
+			mr.buf.Reset()
+			return mr.chunkStore.GetChunk(mr.manifest.Chunks[mr.next], mr.manifest.Compression, &mr.buf)
+		})
+		if err != nil {
+			return 0, err
+		}
+		mr.next++
+	}
+	return mr.buf.Read(p)
+}
+
+func (mr *manifestReader) Close() error {
+	mr.next = len(mr.manifest.Chunks)
+	mr.buf.Reset()
+	return nil
+}
+
+// BatchDownloadOplogArchives opens oplog archives concurrently and streams them to the writers built by out
+// in the order of given archives. At most concurrency archives are opened at once, none is buffered as a whole:
+// the archives opened ahead wait for the earlier ones to be consumed. The storage reads are aborted once ctx
+// is cancelled, the first error cancels the rest downloads and is returned.
+func (sd *StorageDownloader) BatchDownloadOplogArchives(ctx context.Context, archives []models.Archive,
+	out func(models.Archive) io.WriteCloser, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	errgrp, downloadCtx := errgroup.WithContext(ctx)
+	opened := make([]chan io.ReadCloser, len(archives))
+	for i := range opened {
+		opened[i] = make(chan io.ReadCloser, 1)
+	}
+	// slot is released when the archive is consumed, so it bounds the opened archives
+	slots := make(chan struct{}, concurrency)
+
+	errgrp.Go(func() error {
+		for i := range archives {
+			select {
+			case slots <- struct{}{}:
+			case <-downloadCtx.Done():
+				return nil
+			}
+			i := i
+			errgrp.Go(func() error {
+				reader, err := sd.OplogArchiveReader(archives[i])
+				if err != nil {
+					return fmt.Errorf("can not download oplog archive '%s': %w", archives[i].Filename(), err)
+				}
+				opened[i] <- reader
+				return nil
+			})
+		}
+		return nil
+	})
+
+	errgrp.Go(func() error {
+		for i, arch := range archives {
+			var reader io.ReadCloser
+			select {
+			case reader = <-opened[i]:
+			case <-downloadCtx.Done():
+				return nil
+			}
+			err := writeOplogArchive(out(arch), &contextReader{ctx: downloadCtx, reader: reader})
+			utility.LoggedClose(reader, "")
+			<-slots
+			if err != nil {
+				return fmt.Errorf("can not write oplog archive '%s': %w", arch.Filename(), err)
+			}
+		}
+		return nil
+	})
+
+	err := errgrp.Wait()
+	// the archives opened ahead of the failure are not consumed
+	for _, reader := range opened {
+		select {
+		case openedReader := <-reader:
+			utility.LoggedClose(openedReader, "")
+		default:
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func writeOplogArchive(writeCloser io.WriteCloser, reader io.Reader) error {
+	if _, err := utility.FastCopy(&utility.EmptyWriteIgnorer{Writer: writeCloser}, reader); err != nil {
+		_ = writeCloser.Close()
+		return err
+	}
+	return writeCloser.Close()
+}
+
+// contextReader fails the reads once the context is cancelled, so the storage read in flight is aborted promptly
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (reader *contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.Read(p)
+}
+
+type bufferWriteCloser struct {
+	*bytes.Buffer
+}
+
+func (bufferWriteCloser) Close() error {
+	return nil
 }
 
 // ListOplogArchives fetches all oplog archives existed in storage.
+// The listings of the failover storages are merged with the primary one, the archives are deduplicated by name.
 func (sd *StorageDownloader) ListOplogArchives() ([]models.Archive, error) {
-	objects, _, err := sd.oplogsFolder.ListFolder()
-	if err != nil {
+	objects, err := sd.listOplogsFolder()
+	if err != nil && len(sd.failoverOplogsFolders) == 0 {
 		return nil, fmt.Errorf("can not list oplog archives folder: %w", err)
 	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to list oplog archives in primary storage, using failover storages: %v", err)
+		return sd.listFailoverOplogArchives(nil, err)
+	}
 
+	archives, err := archivesFromObjects(objects)
+	if err != nil {
+		return nil, err
+	}
+	if len(sd.failoverOplogsFolders) == 0 {
+		return archives, nil
+	}
+	return sd.listFailoverOplogArchives(archives, nil)
+}
+
+func archivesFromObjects(objects []storage.Object) ([]models.Archive, error) {
 	archives := make([]models.Archive, 0, len(objects))
 	for _, key := range objects {
 		archName := key.GetName()
@@ -137,10 +433,81 @@ func (sd *StorageDownloader) ListOplogArchives() ([]models.Archive, error) {
 	return archives, nil
 }
 
+// ListOplogArchiveSizes fetches all oplog archives in storage along with their stored sizes.
+func (sd *StorageDownloader) ListOplogArchiveSizes() (map[models.Archive]int64, error) {
+	objects, err := sd.listOplogsFolder()
+	if err != nil {
+		return nil, fmt.Errorf("can not list oplog archives folder: %w", err)
+	}
+
+	sizes := make(map[models.Archive]int64, len(objects))
+	for _, key := range objects {
+		archName := key.GetName()
+		arch, err := models.ArchFromFilename(archName)
+		if err != nil {
+			return nil, fmt.Errorf("can not convert retrieve timestamps since oplog archive Ext '%s': %w", archName, err)
+		}
+		sizes[arch] = key.GetSize()
+	}
+	return sizes, nil
+}
+
+// OplogArchiveMeta describes the stored oplog archive without downloading it.
+type OplogArchiveMeta struct {
+	Archive   models.Archive `json:"Archive"`
+	Algorithm string         `json:"Algorithm"`
+	Size      int64          `json:"Size"`
+}
+
+// ListOplogArchivesWithMeta fetches all oplog archives in storage along with their compression algorithms
+// named by the extensions and their stored sizes, sorted by the start ts. The archives with the legacy extensions
+// get the unknown algorithm, the objects not named as the archives are skipped rather than fail the listing.
+func (sd *StorageDownloader) ListOplogArchivesWithMeta() ([]OplogArchiveMeta, error) {
+	objects, err := sd.listOplogsFolder()
+	if err != nil {
+		return nil, fmt.Errorf("can not list oplog archives folder: %w", err)
+	}
+
+	metas := make([]OplogArchiveMeta, 0, len(objects))
+	for _, key := range objects {
+		archName := key.GetName()
+		arch, err := models.ArchFromFilename(archName)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Skipping the object '%s' in the oplog archives folder: %v", archName, err)
+			continue
+		}
+		metas = append(metas, OplogArchiveMeta{Archive: arch, Algorithm: arch.Algorithm(), Size: key.GetSize()})
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return models.LessTS(metas[i].Archive.Start, metas[j].Archive.Start)
+	})
+	return metas, nil
+}
+
+// ListOplogArchivesBetween fetches oplog archives required to replay oplog after the from ts up to the until ts.
+// Archives are sorted by the start ts, ArchivesGapError is returned if they do not cover the whole window.
+func (sd *StorageDownloader) ListOplogArchivesBetween(from, until models.Timestamp) ([]models.Archive, error) {
+	archives, err := sd.ListOplogArchives()
+	if err != nil {
+		return nil, err
+	}
+	return ArchivesBetweenTS(archives, from, until)
+}
+
+// ValidateOplogChain checks that oplog archives since the from ts up to the until ts form the continuous chain
+// without holes and overlaps, the found problems are returned as gaps.
+func (sd *StorageDownloader) ValidateOplogChain(from, until models.Timestamp) ([]models.Gap, error) {
+	archives, err := sd.ListOplogArchives()
+	if err != nil {
+		return nil, err
+	}
+	return OplogChainGaps(archives, from, until)
+}
+
 // LastKnownArchiveTS returns the most recent existed timestamp in storage folder.
 func (sd *StorageDownloader) LastKnownArchiveTS() (models.Timestamp, error) {
 	maxTS := models.Timestamp{}
-	keys, _, err := sd.oplogsFolder.ListFolder()
+	keys, err := sd.listOplogsFolder()
 	if err != nil {
 		return models.Timestamp{}, fmt.Errorf("can not fetch keys since storage folder: %w ", err)
 	}
@@ -155,6 +522,14 @@ func (sd *StorageDownloader) LastKnownArchiveTS() (models.Timestamp, error) {
 	return maxTS, nil
 }
 
+func (sd *StorageDownloader) listOplogsFolder() (objects []storage.Object, err error) {
+	err = sd.retryPolicy.Do(func() error {
+		objects, _, err = sd.oplogsFolder.ListFolder()
+		return err
+	})
+	return objects, err
+}
+
 // DiscardUploader reads provided data and returns success
 type DiscardUploader struct {
 	compressor compression.Compressor
@@ -180,6 +555,19 @@ func (d *DiscardUploader) UploadOplogArchive(archReader io.Reader, firstTS, last
 	return nil
 }
 
+// UploadOplogArchives reads the archives one by one
+func (d *DiscardUploader) UploadOplogArchives(archives []OplogArchiveUpload, concurrency int) ([]error, error) {
+	errs := make([]error, len(archives))
+	var firstErr error
+	for i, arch := range archives {
+		errs[i] = d.UploadOplogArchive(arch.Stream, arch.FirstTS, arch.LastTS)
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+	return errs, firstErr
+}
+
 // UploadGapArchive returns nil error
 func (d *DiscardUploader) UploadGapArchive(err error, firstTS, lastTS models.Timestamp) error {
 	return nil
@@ -194,32 +582,164 @@ func (d *DiscardUploader) UploadBackup(stream io.Reader, cmd internal.ErrWaiter,
 // is NOT thread-safe
 type StorageUploader struct {
 	internal.UploaderProvider
-	crypter crypto.Crypter // usages only in UploadOplogArchive
-	buf     *bytes.Buffer
+	crypter    crypto.Crypter // usages only in UploadOplogArchive
+	buf        *bytes.Buffer
+	chunkStore ChunkStore        // oplog archives are deduplicated if set
+	collector  metrics.Collector // upload metrics are measured if set
+	// retryPolicy retries the uploads of the buffered oplog archives, the backup stream is not retried
+	retryPolicy RetryPolicy
+	// VerifyUpload makes backup stream to be read back and compared with the uploaded one before sentinel upload
+	VerifyUpload bool
+	// the backup stream is uploaded under the prefix expanded from keyTemplate, the sentinel is uploaded
+	// to sentinelFolder if it is set
+	keyTemplate    *KeyTemplate
+	keyPrefix      string
+	sentinelFolder storage.Folder
 }
 
 // NewStorageUploader builds mongodb uploader.
 func NewStorageUploader(upl internal.UploaderProvider) *StorageUploader {
 	upl.DisableSizeTracking() // providing io.ReaderAt+io.ReadSeeker to s3 upload enables buffer pool usage
-	return &StorageUploader{upl, internal.ConfigureCrypter(), &bytes.Buffer{}}
+	return &StorageUploader{UploaderProvider: upl, crypter: internal.ConfigureCrypter(), buf: &bytes.Buffer{},
+		retryPolicy: DefaultRetryPolicy()}
+}
+
+// NewBackupStorageUploader builds mongodb backup uploader from the uploader provider at the storage root.
+// If keyTemplate is set, the backup stream is uploaded to the backups folder under the expanded prefix,
+// while the sentinel stays in the root backups folder to keep the backups listing, the prefix is recorded in the sentinel.
+func NewBackupStorageUploader(upl internal.UploaderProvider, keyTemplate *KeyTemplate) *StorageUploader {
+	sentinelFolder := upl.Folder().GetSubFolder(utility.BaseBackupPath)
+	keyPrefix := ""
+	if keyTemplate != nil {
+		keyPrefix = keyTemplate.Expand(keyTemplateTime())
+	}
+	upl.ChangeDirectory(path.Join(keyPrefix, utility.BaseBackupPath))
+	// size tracking is kept to record the compressed backup size, the backup stream is compressed
+	// through a pipe, so the buffer pool is not used for it anyway
+	return &StorageUploader{
+		UploaderProvider: upl,
+		crypter:          internal.ConfigureCrypter(),
+		buf:              &bytes.Buffer{},
+		retryPolicy:      DefaultRetryPolicy(),
+		keyTemplate:      keyTemplate,
+		keyPrefix:        keyPrefix,
+		sentinelFolder:   sentinelFolder,
+	}
+}
+
+// EnableDeduplication makes oplog archives to be uploaded as manifests referencing the chunks in the chunk store.
+// Changes storage layout: archives uploaded with deduplication are not readable by the older versions.
+func (su *StorageUploader) EnableDeduplication(chunkStore ChunkStore) {
+	su.chunkStore = chunkStore
 }
 
 // UploadOplogArchive compresses a stream and uploads it with given archive name.
 func (su *StorageUploader) UploadOplogArchive(stream io.Reader, firstTS, lastTS models.Timestamp) error {
+	_, err := su.uploadObservedOplogArchive(stream, firstTS, lastTS, su.buf)
+	return err
+}
+
+// uploadObservedOplogArchive uploads oplog archive using buf, the upload metrics are measured if the collector is set.
+func (su *StorageUploader) uploadObservedOplogArchive(stream io.Reader, firstTS, lastTS models.Timestamp,
+	buf *bytes.Buffer) (models.Archive, error) {
+	if su.collector == nil {
+		arch, _, err := su.uploadOplogArchive(stream, firstTS, lastTS, buf)
+		return arch, err
+	}
+	var rawBytes int64
+	startTime := time.Now()
+	arch, uploadedBytes, err := su.uploadOplogArchive(internal.NewWithSizeReader(stream, &rawBytes), firstTS, lastTS, buf)
+	su.observeUpload(metrics.OplogArchiveOperation, rawBytes, uploadedBytes, startTime, err)
+	return arch, err
+}
+
+// uploadOplogArchive uploads oplog archive and its checksum, the number of the archive data bytes put to storage
+// is returned. The archive contents are kept in buf until uploaded.
+func (su *StorageUploader) uploadOplogArchive(stream io.Reader, firstTS, lastTS models.Timestamp,
+	buf *bytes.Buffer) (models.Archive, int64, error) {
+	digest := newStreamDigest()
+	stream = io.TeeReader(stream, digest)
+	var arch models.Archive
+	var uploadedBytes int64
+	var err error
+	if su.chunkStore != nil {
+		arch, uploadedBytes, err = su.uploadDeduplicatedOplogArchive(stream, firstTS, lastTS, buf)
+	} else {
+		arch, uploadedBytes, err = su.uploadCompressedOplogArchive(stream, firstTS, lastTS, buf)
+	}
+	if err != nil {
+		return arch, uploadedBytes, err
+	}
+
+	checksum := models.ArchiveChecksum{StreamChecksum: *digest.checksum()}
+	if su.chunkStore == nil {
+		checksum.Compression = NewCompressionMethod(su.Compression())
+	}
+	checksumBytes, err := json.Marshal(checksum)
+	if err != nil {
+		return arch, uploadedBytes, fmt.Errorf("can not marshal archive checksum: %w", err)
+	}
+	err = su.uploadWithRetries(metrics.OplogArchiveOperation, models.OplogChecksumsPath+arch.ChecksumFilename(),
+		checksumBytes)
+	return arch, uploadedBytes, err
+}
+
+func (su *StorageUploader) uploadCompressedOplogArchive(stream io.Reader,
+	firstTS, lastTS models.Timestamp, buf *bytes.Buffer) (models.Archive, int64, error) {
 	arch, err := models.NewArchive(firstTS, lastTS, su.Compression().FileExtension(), models.ArchiveTypeOplog)
 	if err != nil {
-		return fmt.Errorf("can not build archive: %w", err)
+		return arch, 0, fmt.Errorf("can not build archive: %w", err)
 	}
 
-	_, err = su.buf.ReadFrom(internal.CompressAndEncrypt(stream, su.UploaderProvider.Compression(), su.crypter))
+	_, err = buf.ReadFrom(internal.CompressAndEncrypt(stream, su.UploaderProvider.Compression(), su.crypter))
 	// TODO: warn if read > 2 * models.MaxDocumentSize and shrink buf capacity if it's too high
-	defer su.buf.Reset()
+	defer buf.Reset()
 	if err != nil {
-		return err
+		return arch, 0, err
 	}
 
 	// providing io.ReaderAt+io.ReadSeeker to s3 upload enables buffer pool usage
-	return su.Upload(arch.Filename(), bytes.NewReader(su.buf.Bytes()))
+	return arch, int64(buf.Len()), su.uploadWithRetries(metrics.OplogArchiveOperation, arch.Filename(), buf.Bytes())
+}
+
+// uploadDeduplicatedOplogArchive splits a stream into chunks, uploads the new ones and then the manifest referencing them.
+// The manifest is compressed and encrypted as the regular archives, the chunks are purged with the last referencing manifest.
+func (su *StorageUploader) uploadDeduplicatedOplogArchive(stream io.Reader,
+	firstTS, lastTS models.Timestamp, buf *bytes.Buffer) (models.Archive, int64, error) {
+	arch, err := models.NewArchive(firstTS, lastTS, models.ManifestExt(su.Compression().FileExtension()),
+		models.ArchiveTypeOplog)
+	if err != nil {
+		return arch, 0, fmt.Errorf("can not build archive: %w", err)
+	}
+
+	_, err = buf.ReadFrom(stream)
+	defer buf.Reset()
+	if err != nil {
+		return arch, 0, err
+	}
+
+	var uploadedBytes int64
+	manifest := models.ArchiveManifest{Compression: su.chunkStore.Compression()}
+	for _, data := range splitChunks(buf.Bytes()) {
+		chunk, chunkBytes, err := su.chunkStore.PutChunk(data)
+		if err != nil {
+			return arch, uploadedBytes, err
+		}
+		uploadedBytes += int64(chunkBytes)
+		manifest.Chunks = append(manifest.Chunks, chunk)
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return arch, uploadedBytes, fmt.Errorf("can not marshal archive manifest: %w", err)
+	}
+	buf.Reset()
+	_, err = buf.ReadFrom(internal.CompressAndEncrypt(bytes.NewReader(manifestBytes), su.Compression(), su.crypter))
+	if err != nil {
+		return arch, uploadedBytes, fmt.Errorf("can not compress archive manifest: %w", err)
+	}
+	return arch, uploadedBytes + int64(buf.Len()),
+		su.uploadWithRetries(metrics.OplogArchiveOperation, arch.Filename(), buf.Bytes())
 }
 
 // UploadGap uploads mark indicating archiving gap.
@@ -234,6 +754,9 @@ func (su *StorageUploader) UploadGapArchive(archErr error, firstTS, lastTS model
 	}
 
 	if err := su.PushStreamToDestination(strings.NewReader(archErr.Error()), arch.Filename()); err != nil {
+		if su.collector != nil {
+			su.collector.IncFailures(metrics.OplogArchiveOperation)
+		}
 		return fmt.Errorf("error while uploading stream: %w", err)
 	}
 	return nil
@@ -245,8 +768,15 @@ func (su *StorageUploader) UploadBackup(stream io.Reader, cmd internal.ErrWaiter
 	if err != nil {
 		return fmt.Errorf("can not init meta provider: %+v", err)
 	}
-	backupName, err := su.PushStream(stream)
+	digest := newStreamDigest()
+	stream = io.TeeReader(stream, digest)
+	var uncompressedSize int64
+	backupName, compressedSize, err := su.pushBackupStream(internal.NewWithSizeReader(stream, &uncompressedSize))
 	if err != nil {
+		if _, ok := su.UploaderProvider.(*internal.ResumableStreamUploader); ok {
+			return fmt.Errorf("can not push stream, set %s=%s to resume the upload: %+v",
+				internal.StreamResumeTokenSetting, backupName, err)
+		}
 		return fmt.Errorf("can not push stream: %+v", err)
 	}
 
@@ -259,7 +789,29 @@ func (su *StorageUploader) UploadBackup(stream io.Reader, cmd internal.ErrWaiter
 	}
 
 	backupSentinel := metaConstructor.MetaInfo()
-	if err := internal.UploadSentinel(su.UploaderProvider, backupSentinel, backupName); err != nil {
+	if sentinel, ok := backupSentinel.(*models.Backup); ok {
+		sentinel.UncompressedSize, sentinel.CompressedSize = uncompressedSize, compressedSize
+		sentinel.Checksum = digest.checksum()
+		sentinel.CompressedSHA256 = su.CompressedStreamDigests()
+		sentinel.Compression = NewCompressionMethod(su.Compression())
+		if su.keyTemplate != nil {
+			sentinel.KeyTemplate, sentinel.KeyPrefix = su.keyTemplate.String(), su.keyPrefix
+		}
+	}
+	if su.VerifyUpload {
+		verification, err := su.verifyBackupStream(backupName, digest)
+		if err != nil {
+			return fmt.Errorf("backup verification failed: %+v", err)
+		}
+		if sentinel, ok := backupSentinel.(*models.Backup); ok {
+			sentinel.Verification = verification
+		}
+	}
+	sentinelFolder := su.sentinelFolder
+	if sentinelFolder == nil {
+		sentinelFolder = su.Folder()
+	}
+	if err := internal.UploadDto(sentinelFolder, backupSentinel, internal.SentinelNameFromBackup(backupName)); err != nil {
 		return fmt.Errorf("can not upload sentinel: %+v", err)
 	}
 	return nil
@@ -267,6 +819,7 @@ func (su *StorageUploader) UploadBackup(stream io.Reader, cmd internal.ErrWaiter
 
 // StoragePurger deletes files in storage.
 type StoragePurger struct {
+	rootFolder    storage.Folder
 	oplogsFolder  storage.Folder
 	backupsFolder storage.Folder
 }
@@ -278,13 +831,24 @@ func NewStoragePurger(opts StorageSettings) (*StoragePurger, error) {
 		return nil, err
 	}
 
-	return &StoragePurger{oplogsFolder: folder.GetSubFolder(opts.oplogsPath),
+	return &StoragePurger{rootFolder: folder,
+		oplogsFolder:  folder.GetSubFolder(opts.oplogsPath),
 		backupsFolder: folder.GetSubFolder(opts.backupsPath)}, nil
 }
 
 // DeleteBackups purges given backups files
 // TODO: extract BackupLayout abstraction and provide DataPath(), SentinelPath(), Exists() methods
 func (sp *StoragePurger) DeleteBackups(backups []models.Backup) error {
+	// the streams uploaded under the key prefix are deleted before the sentinels
+	for i := range backups {
+		if backups[i].KeyPrefix == "" {
+			continue
+		}
+		dataFolder := BackupDataFolder(sp.rootFolder, &backups[i])
+		if err := internal.DeleteBackups(dataFolder, []string{backups[i].BackupName}); err != nil {
+			return err
+		}
+	}
 	backupNames := BackupNamesFromBackups(backups)
 	return internal.DeleteBackups(sp.backupsFolder, backupNames)
 }
@@ -294,12 +858,68 @@ func (sp *StoragePurger) DeleteGarbage(garbage []string) error {
 	return internal.DeleteGarbage(sp.backupsFolder, garbage)
 }
 
-// DeleteOplogArchives purges given oplogs files
+// DeleteOplogArchives purges given oplogs files along with their checksums,
+// the chunks of the purged deduplicated archives are purged unless the remaining manifests reference them
 func (sp *StoragePurger) DeleteOplogArchives(archives []models.Archive) error {
-	oplogKeys := make([]string, 0, len(archives))
+	purgedChunks, err := manifestChunkKeys(sp.oplogsFolder, archives)
+	if err != nil {
+		return err
+	}
+	oplogKeys := make([]string, 0, 2*len(archives))
 	for _, arch := range archives {
-		oplogKeys = append(oplogKeys, arch.Filename())
+		oplogKeys = append(oplogKeys, arch.Filename(), models.OplogChecksumsPath+arch.ChecksumFilename())
 	}
 	tracelog.DebugLogger.Printf("Oplog keys will be deleted: %+v\n", oplogKeys)
-	return sp.oplogsFolder.DeleteObjects(oplogKeys)
+	if err := sp.oplogsFolder.DeleteObjects(oplogKeys); err != nil {
+		return err
+	}
+	if len(purgedChunks) == 0 {
+		return nil
+	}
+	return sp.deleteUnreferencedChunks(purgedChunks)
+}
+
+// deleteUnreferencedChunks purges the chunks which are not referenced by the remaining manifests.
+// The manifests are listed after the purged ones are deleted, but the chunk reused by oplog-push
+// may still be purged before its manifest is uploaded, so the purge should not overlap the deduplicated uploads.
+func (sp *StoragePurger) deleteUnreferencedChunks(chunkKeys map[string]bool) error {
+	objects, _, err := sp.oplogsFolder.ListFolder()
+	if err != nil {
+		return fmt.Errorf("can not list oplog archives folder: %w", err)
+	}
+	archives, err := archivesFromObjects(objects)
+	if err != nil {
+		return err
+	}
+	referencedChunks, err := manifestChunkKeys(sp.oplogsFolder, archives)
+	if err != nil {
+		return err
+	}
+	unreferenced := make([]string, 0, len(chunkKeys))
+	for chunkKey := range chunkKeys {
+		if !referencedChunks[chunkKey] {
+			unreferenced = append(unreferenced, chunkKey)
+		}
+	}
+	sort.Strings(unreferenced)
+	tracelog.DebugLogger.Printf("Oplog chunks will be deleted: %+v\n", unreferenced)
+	return sp.oplogsFolder.GetSubFolder(models.OplogChunksPath).DeleteObjects(unreferenced)
+}
+
+// manifestChunkKeys collects the keys of the chunks referenced by the manifests among the archives
+func manifestChunkKeys(folder storage.Folder, archives []models.Archive) (map[string]bool, error) {
+	chunkKeys := make(map[string]bool)
+	for _, arch := range archives {
+		if !arch.IsManifest() {
+			continue
+		}
+		manifest, err := readArchiveManifest(folder, arch)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range manifest.Chunks {
+			chunkKeys[chunkFilename(chunk, manifest.Compression)] = true
+		}
+	}
+	return chunkKeys, nil
 }
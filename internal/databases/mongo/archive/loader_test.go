@@ -1,16 +1,28 @@
 package archive
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/ioextensions"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/test/mocks"
 )
 
@@ -21,7 +33,7 @@ func TestStorageUploader_UploadOplogArchive_ProperInterfaces(t *testing.T) {
 	defer mockCtl.Finish()
 
 	storageProv := mocks.NewMockFolder(mockCtl)
-	storageProv.EXPECT().PutObject(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(_ string, content io.Reader) error {
+	storageProv.EXPECT().PutObject(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(func(_ string, content io.Reader) error {
 		if _, ok := content.(io.ReaderAt); !ok {
 			t.Errorf("can not cast PutObject content to io.ReaderAt")
 		}
@@ -45,3 +57,345 @@ func TestStorageUploader_UploadOplogArchive_ProperInterfaces(t *testing.T) {
 		t.Errorf("UploadOplogArchive() error = %v", err)
 	}
 }
+
+// latencyFolder delays reads, the earlier archives are the slowest ones
+type latencyFolder struct {
+	storage.Folder
+	latency     map[string]time.Duration
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (f *latencyFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	inFlight := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		maxInFlight := atomic.LoadInt32(&f.maxInFlight)
+		if inFlight <= maxInFlight || atomic.CompareAndSwapInt32(&f.maxInFlight, maxInFlight, inFlight) {
+			break
+		}
+	}
+	time.Sleep(f.latency[objectRelativePath])
+	return f.Folder.ReadObject(objectRelativePath)
+}
+
+type recordingWriteCloser struct {
+	bytes.Buffer
+	name     string
+	mu       *sync.Mutex
+	consumed *[]string
+}
+
+func (w *recordingWriteCloser) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	*w.consumed = append(*w.consumed, w.name+":"+w.String())
+	return nil
+}
+
+func newBatchDownloadFixture(t *testing.T, count int) (*StorageDownloader, *latencyFolder, []models.Archive) {
+	folder := &latencyFolder{Folder: memory.NewFolder("", memory.NewStorage()), latency: map[string]time.Duration{}}
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	archives := make([]models.Archive, 0, count)
+	for i := 0; i < count; i++ {
+		arch, err := models.NewArchive(models.Timestamp{TS: uint32(i), Inc: 1}, models.Timestamp{TS: uint32(i + 1), Inc: 1},
+			compressor.FileExtension(), models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		var compressed bytes.Buffer
+		writer := compressor.NewWriter(&compressed)
+		_, err = writer.Write([]byte(fmt.Sprintf("oplog_%d", i)))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+		assert.NoError(t, folder.PutObject(arch.Filename(), &compressed))
+		folder.latency[arch.Filename()] = time.Duration(count-i) * 5 * time.Millisecond
+		archives = append(archives, arch)
+	}
+	return &StorageDownloader{oplogsFolder: folder}, folder, archives
+}
+
+func TestStorageDownloader_BatchDownloadOplogArchives_KeepsOrder(t *testing.T) {
+	downloader, folder, archives := newBatchDownloadFixture(t, 8)
+
+	var mu sync.Mutex
+	consumed := make([]string, 0, len(archives))
+	err := downloader.BatchDownloadOplogArchives(context.Background(), archives, func(arch models.Archive) io.WriteCloser {
+		return &recordingWriteCloser{name: arch.Filename(), mu: &mu, consumed: &consumed}
+	}, 3)
+	assert.NoError(t, err)
+
+	expected := make([]string, 0, len(archives))
+	for i, arch := range archives {
+		expected = append(expected, fmt.Sprintf("%s:oplog_%d", arch.Filename(), i))
+	}
+	assert.Equal(t, expected, consumed)
+	assert.LessOrEqual(t, atomic.LoadInt32(&folder.maxInFlight), int32(3))
+}
+
+func TestStorageDownloader_BatchDownloadOplogArchives_PropagatesError(t *testing.T) {
+	downloader, _, archives := newBatchDownloadFixture(t, 4)
+	missing, err := models.NewArchive(models.Timestamp{TS: 100, Inc: 1}, models.Timestamp{TS: 101, Inc: 1},
+		lz4.FileExtension, models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	archives = append([]models.Archive{archives[0], missing}, archives[1:]...)
+
+	var mu sync.Mutex
+	consumed := make([]string, 0, len(archives))
+	err = downloader.BatchDownloadOplogArchives(context.Background(), archives, func(arch models.Archive) io.WriteCloser {
+		return &recordingWriteCloser{name: arch.Filename(), mu: &mu, consumed: &consumed}
+	}, 2)
+	assert.Error(t, err)
+	assert.LessOrEqual(t, len(consumed), 1)
+}
+
+func TestStorageDownloader_BatchDownloadOplogArchives_StopsOnCancel(t *testing.T) {
+	downloader, _, archives := newBatchDownloadFixture(t, 6)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	consumed := make([]string, 0, len(archives))
+	err := downloader.BatchDownloadOplogArchives(ctx, archives, func(arch models.Archive) io.WriteCloser {
+		// the restore is cancelled while the first archive is consumed
+		cancel()
+		return &recordingWriteCloser{name: arch.Filename(), mu: &mu, consumed: &consumed}
+	}, 2)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.LessOrEqual(t, len(consumed), 1)
+}
+
+func TestStorageUploader_UploadOplogArchive_Deduplication(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	chunksFolder := folder.GetSubFolder(models.OplogChunksPath)
+	su := NewStorageUploader(internal.NewUploader(compressor, folder))
+	su.EnableDeduplication(NewStorageChunkStore(chunksFolder, compressor, nil))
+
+	prefix := make([]byte, 3*maxChunkSize)
+	rand.New(rand.NewSource(1)).Read(prefix)
+	contents := [][]byte{prefix, append(append([]byte{}, prefix...), []byte("next oplog batch")...)}
+	archives := make([]models.Archive, 0, len(contents))
+	for i, content := range contents {
+		firstTS, lastTS := models.Timestamp{TS: uint32(i), Inc: 1}, models.Timestamp{TS: uint32(i + 1), Inc: 1}
+		assert.NoError(t, su.UploadOplogArchive(bytes.NewReader(content), firstTS, lastTS))
+		arch, err := models.NewArchive(firstTS, lastTS, models.ManifestExt(lz4.FileExtension), models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		archives = append(archives, arch)
+	}
+
+	chunks, _, err := chunksFolder.ListFolder()
+	assert.NoError(t, err)
+	assert.Less(t, len(chunks), 2*len(splitChunks(prefix)))
+
+	downloader := &StorageDownloader{oplogsFolder: folder}
+	for i, arch := range archives {
+		var buf bytes.Buffer
+		assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+		assert.Equal(t, contents[i], buf.Bytes())
+	}
+}
+
+func TestStoragePurger_DeleteOplogArchives_PurgesUnreferencedChunks(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	chunksFolder := folder.GetSubFolder(models.OplogChunksPath)
+	su := NewStorageUploader(internal.NewUploader(compressor, folder))
+	su.EnableDeduplication(NewStorageChunkStore(chunksFolder, compressor, nil))
+
+	prefix := make([]byte, 3*maxChunkSize)
+	rand.New(rand.NewSource(1)).Read(prefix)
+	suffix := make([]byte, 2*maxChunkSize)
+	rand.New(rand.NewSource(2)).Read(suffix)
+	contents := [][]byte{prefix, append(append([]byte{}, prefix...), suffix...)}
+	archives := make([]models.Archive, 0, len(contents))
+	for i, content := range contents {
+		firstTS, lastTS := models.Timestamp{TS: uint32(i), Inc: 1}, models.Timestamp{TS: uint32(i + 1), Inc: 1}
+		assert.NoError(t, su.UploadOplogArchive(bytes.NewReader(content), firstTS, lastTS))
+		arch, err := models.NewArchive(firstTS, lastTS, models.ManifestExt(lz4.FileExtension), models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		archives = append(archives, arch)
+	}
+	// the manifest is compressed as the regular archives
+	reader, err := folder.ReadObject(archives[0].Filename())
+	assert.NoError(t, err)
+	stored, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.False(t, json.Valid(stored))
+
+	// only the chunks of the second archive are kept
+	referencedChunks, err := manifestChunkKeys(folder, archives[1:])
+	assert.NoError(t, err)
+	purger := &StoragePurger{oplogsFolder: folder}
+	assert.NoError(t, purger.DeleteOplogArchives(archives[:1]))
+	remainingChunks, _, err := chunksFolder.ListFolder()
+	assert.NoError(t, err)
+	remainingKeys := make(map[string]bool, len(remainingChunks))
+	for _, chunk := range remainingChunks {
+		remainingKeys[chunk.GetName()] = true
+	}
+	assert.Equal(t, referencedChunks, remainingKeys)
+
+	var buf bytes.Buffer
+	downloader := &StorageDownloader{oplogsFolder: folder}
+	assert.NoError(t, downloader.DownloadOplogArchive(archives[1], nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, contents[1], buf.Bytes())
+
+	assert.NoError(t, purger.DeleteOplogArchives(archives[1:]))
+	remainingChunks, _, err = chunksFolder.ListFolder()
+	assert.NoError(t, err)
+	assert.Empty(t, remainingChunks)
+}
+
+type closeTrackingFolder struct {
+	storage.Folder
+	opened int32
+}
+
+type closeTrackingReader struct {
+	io.ReadCloser
+	opened *int32
+}
+
+func (r closeTrackingReader) Close() error {
+	atomic.AddInt32(r.opened, -1)
+	return r.ReadCloser.Close()
+}
+
+func (f *closeTrackingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := f.Folder.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&f.opened, 1)
+	return closeTrackingReader{reader, &f.opened}, nil
+}
+
+func TestStorageDownloader_OplogArchiveReader(t *testing.T) {
+	folder := &closeTrackingFolder{Folder: memory.NewFolder("", memory.NewStorage())}
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	content := make([]byte, 3*maxChunkSize)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	plainTS := []models.Timestamp{{TS: 1, Inc: 1}, {TS: 2, Inc: 1}}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadOplogArchive(bytes.NewReader(content), plainTS[0], plainTS[1]))
+	plainArch, err := models.NewArchive(plainTS[0], plainTS[1], compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+
+	dedupTS := []models.Timestamp{{TS: 2, Inc: 1}, {TS: 3, Inc: 1}}
+	su := NewStorageUploader(internal.NewUploader(compressor, folder))
+	su.EnableDeduplication(NewStorageChunkStore(folder.GetSubFolder(models.OplogChunksPath), compressor, nil))
+	assert.NoError(t, su.UploadOplogArchive(bytes.NewReader(content), dedupTS[0], dedupTS[1]))
+	dedupArch, err := models.NewArchive(dedupTS[0], dedupTS[1], models.ManifestExt(lz4.FileExtension), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+
+	downloader := &StorageDownloader{oplogsFolder: folder}
+	for _, arch := range []models.Archive{plainArch, dedupArch} {
+		reader, err := downloader.OplogArchiveReader(arch)
+		assert.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, content, data)
+		assert.NoError(t, reader.Close())
+	}
+
+	reader, err := downloader.OplogArchiveReader(plainArch)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&folder.opened))
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&folder.opened))
+
+	missing, err := models.NewArchive(models.Timestamp{TS: 5, Inc: 1}, models.Timestamp{TS: 6, Inc: 1},
+		compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	_, err = downloader.OplogArchiveReader(missing)
+	assert.Error(t, err)
+}
+
+func TestStorageDownloader_DownloadOplogArchive_PlainWithDeduplicated(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	firstTS, lastTS := models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadOplogArchive(bytes.NewReader([]byte("plain")), firstTS, lastTS))
+
+	arch, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, (&StorageDownloader{oplogsFolder: folder}).DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, "plain", buf.String())
+}
+
+func TestStorageDownloader_ListOplogArchivesWithMeta(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	uploader := NewStorageUploader(internal.NewUploader(compressor, folder))
+	assert.NoError(t, uploader.UploadOplogArchive(bytes.NewReader([]byte("second")),
+		models.Timestamp{TS: 3, Inc: 1}, models.Timestamp{TS: 4, Inc: 1}))
+	uploader.EnableDeduplication(NewStorageChunkStore(folder.GetSubFolder(models.OplogChunksPath), compressor, nil))
+	assert.NoError(t, uploader.UploadOplogArchive(bytes.NewReader([]byte("third")),
+		models.Timestamp{TS: 4, Inc: 1}, models.Timestamp{TS: 5, Inc: 1}))
+	assert.NoError(t, folder.PutObject("oplog_1.1_3.1.lzo", bytes.NewReader([]byte("legacy"))))
+	assert.NoError(t, folder.PutObject("unrelated", bytes.NewReader([]byte("unrelated"))))
+
+	metas, err := (&StorageDownloader{oplogsFolder: folder}).ListOplogArchivesWithMeta()
+	assert.NoError(t, err)
+	assert.Len(t, metas, 3)
+	algorithms := make([]string, 0, len(metas))
+	for _, meta := range metas {
+		algorithms = append(algorithms, meta.Algorithm)
+		assert.Positive(t, meta.Size)
+	}
+	assert.Equal(t, []string{models.ArchiveAlgorithmUnknown, lz4.AlgorithmName, models.ArchiveAlgorithmDeduplicated},
+		algorithms)
+	assert.Equal(t, int64(len("legacy")), metas[0].Size)
+}
+
+// trackingSink records the written bytes and whether it was closed, failing the writes or the close if set
+type trackingSink struct {
+	bytes.Buffer
+	writeErr error
+	closeErr error
+	closed   bool
+}
+
+func (sink *trackingSink) Write(p []byte) (int, error) {
+	if sink.writeErr != nil {
+		return 0, sink.writeErr
+	}
+	return sink.Buffer.Write(p)
+}
+
+func (sink *trackingSink) Close() error {
+	sink.closed = true
+	return sink.closeErr
+}
+
+func TestStorageDownloader_DownloadOplogArchive_MultipleSinks(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	arch := uploadTestOplogArchive(t, folder, 1, "oplog")
+	downloader := &StorageDownloader{oplogsFolder: folder}
+
+	disk, hasher := &trackingSink{}, &trackingSink{}
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, ioextensions.NewMultiWriteCloser(disk, hasher)))
+	assert.Equal(t, "oplog", disk.String())
+	assert.Equal(t, "oplog", hasher.String())
+	assert.True(t, disk.closed)
+	assert.True(t, hasher.closed)
+
+	// the failed sink aborts the download, all the sinks are closed and their close errors are aggregated
+	writeErr, closeErr := errors.New("validation failed"), errors.New("counter close failed")
+	disk, failing, counter := &trackingSink{}, &trackingSink{writeErr: writeErr}, &trackingSink{closeErr: closeErr}
+	err := downloader.DownloadOplogArchive(arch, nil, ioextensions.NewMultiWriteCloser(disk, failing, counter))
+	assert.ErrorIs(t, err, writeErr)
+	assert.Equal(t, "oplog", disk.String())
+	assert.Zero(t, counter.Len())
+	assert.True(t, disk.closed)
+	assert.True(t, failing.closed)
+	assert.True(t, counter.closed)
+
+	disk, counter = &trackingSink{closeErr: errors.New("disk close failed")}, &trackingSink{closeErr: closeErr}
+	err = downloader.DownloadOplogArchive(arch, nil, ioextensions.NewMultiWriteCloser(disk, counter))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "disk close failed")
+		assert.Contains(t, err.Error(), "counter close failed")
+	}
+}
@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/wal-g/wal-g/pkg/storages/fs"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const localStorageScheme = "file"
+
+// newLocalStorageDownloader builds the downloader reading the local directory set by the rootURL,
+// the local reads are neither retried nor failed over.
+func newLocalStorageDownloader(opts StorageSettings) (*StorageDownloader, error) {
+	folder, err := localFolderFromURL(opts.rootURL)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageDownloader{rootFolder: folder,
+			oplogsFolder:  folder.GetSubFolder(opts.oplogsPath),
+			backupsFolder: folder.GetSubFolder(opts.backupsPath),
+			retryPolicy:   RetryPolicy{MaxAttempts: 1}},
+		nil
+}
+
+// localFolderFromURL opens the local directory given by the file:// URL, e.g. file:///mnt/archives
+func localFolderFromURL(rootURL string) (storage.Folder, error) {
+	parsed, err := url.Parse(rootURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse storage URL '%s': %w", rootURL, err)
+	}
+	if parsed.Scheme != localStorageScheme {
+		return nil, fmt.Errorf("unsupported storage URL '%s': only %s:// URLs are supported", rootURL, localStorageScheme)
+	}
+	if parsed.Host != "" && parsed.Host != "localhost" {
+		return nil, fmt.Errorf("unsupported storage URL '%s': only local paths are supported", rootURL)
+	}
+	if !filepath.IsAbs(parsed.Path) {
+		return nil, fmt.Errorf("storage URL '%s' must have the absolute path", rootURL)
+	}
+	return fs.ConfigureFolder(filepath.Clean(parsed.Path), nil)
+}
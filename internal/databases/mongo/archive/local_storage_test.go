@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/fs"
+)
+
+func TestNewStorageDownloader_ReadsLocalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	uploader := NewStorageUploader(internal.NewUploader(compressor, fs.NewFolder(dir, models.OplogArchBasePath)))
+	contents := make(map[models.Archive]string)
+	for i := uint32(1); i <= 3; i++ {
+		arch, err := models.NewArchive(models.Timestamp{TS: i}, models.Timestamp{TS: i + 1}, compressor.FileExtension(),
+			models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		contents[arch] = strings.Repeat("oplog ", int(i)*100)
+		assert.NoError(t, uploader.UploadOplogArchive(strings.NewReader(contents[arch]), arch.Start, arch.End))
+	}
+
+	downloader, err := NewStorageDownloader(NewDefaultStorageSettings().WithRootURL("file://" + dir))
+	assert.NoError(t, err)
+	archives, err := downloader.ListOplogArchives()
+	assert.NoError(t, err)
+	assert.Len(t, archives, len(contents))
+	for _, arch := range archives {
+		var buf bytes.Buffer
+		assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+		assert.Equal(t, contents[arch], buf.String())
+	}
+}
+
+func TestNewStorageDownloader_RejectsUnsupportedURL(t *testing.T) {
+	for _, rootURL := range []string{"s3://bucket/path", "file://host/path", "file:relative/path", "file:///not/existing"} {
+		_, err := NewStorageDownloader(NewDefaultStorageSettings().WithRootURL(rootURL))
+		assert.Error(t, err, rootURL)
+	}
+}
@@ -45,13 +45,13 @@ func (_m *Downloader) BackupMeta(name string) (models.Backup, error) {
 	return r0, r1
 }
 
-// DownloadOplogArchive provides a mock function with given fields: arch, writeCloser
-func (_m *Downloader) DownloadOplogArchive(arch models.Archive, writeCloser io.WriteCloser) error {
-	ret := _m.Called(arch, writeCloser)
+// DownloadOplogArchive provides a mock function with given fields: arch, from, writeCloser
+func (_m *Downloader) DownloadOplogArchive(arch models.Archive, from *models.Timestamp, writeCloser io.WriteCloser) error {
+	ret := _m.Called(arch, from, writeCloser)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(models.Archive, io.WriteCloser) error); ok {
-		r0 = rf(arch, writeCloser)
+	if rf, ok := ret.Get(0).(func(models.Archive, *models.Timestamp, io.WriteCloser) error); ok {
+		r0 = rf(arch, from, writeCloser)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -185,3 +185,33 @@ func (_m *Downloader) LoadBackups(names []string) ([]models.Backup, error) {
 
 	return r0, r1
 }
+
+// OplogArchiveReader provides a mock function with given fields: arch
+func (_m *Downloader) OplogArchiveReader(arch models.Archive) (io.ReadCloser, error) {
+	ret := _m.Called(arch)
+
+	if len(ret) == 1 {
+		rf, ok := ret.Get(0).(func(models.Archive) (io.ReadCloser, error))
+		if ok {
+			return rf(arch)
+		}
+	}
+
+	var r0 io.ReadCloser
+	if rf, ok := ret.Get(0).(func(models.Archive) io.ReadCloser); ok {
+		r0 = rf(arch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(models.Archive) error); ok {
+		r1 = rf(arch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
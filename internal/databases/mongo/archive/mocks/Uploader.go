@@ -5,6 +5,8 @@ package archivemocks
 import (
 	io "io"
 
+	archive "github.com/wal-g/wal-g/internal/databases/mongo/archive"
+
 	internal "github.com/wal-g/wal-g/internal"
 
 	mock "github.com/stretchr/testify/mock"
@@ -58,3 +60,26 @@ func (_m *Uploader) UploadOplogArchive(stream io.Reader, firstTS models.Timestam
 
 	return r0
 }
+
+// UploadOplogArchives provides a mock function with given fields: archives, concurrency
+func (_m *Uploader) UploadOplogArchives(archives []archive.OplogArchiveUpload, concurrency int) ([]error, error) {
+	ret := _m.Called(archives, concurrency)
+
+	var r0 []error
+	if rf, ok := ret.Get(0).(func([]archive.OplogArchiveUpload, int) []error); ok {
+		r0 = rf(archives, concurrency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]archive.OplogArchiveUpload, int) error); ok {
+		r1 = rf(archives, concurrency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
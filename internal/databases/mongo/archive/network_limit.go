@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"io"
+
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// networkLimitFolder reads the objects at the rate of the network limiter shared by all the transfers,
+// so the limit is applied to the bytes received from the storage
+type networkLimitFolder struct {
+	storage.Folder
+}
+
+// networkLimitRangeFolder is the networkLimitFolder of the folder able to read the object ranges
+type networkLimitRangeFolder struct {
+	networkLimitFolder
+	rangeReader storage.RangeReader
+}
+
+var _ storage.RangeReader = networkLimitRangeFolder{}
+
+// newNetworkLimitFolder returns the folder as is if the network limiter is not set,
+// the range reads are passed through if the folder supports them
+func newNetworkLimitFolder(folder storage.Folder) storage.Folder {
+	if limiters.NetworkLimiter == nil {
+		return folder
+	}
+	limitFolder := networkLimitFolder{Folder: folder}
+	if rangeReader, ok := folder.(storage.RangeReader); ok {
+		return networkLimitRangeFolder{networkLimitFolder: limitFolder, rangeReader: rangeReader}
+	}
+	return limitFolder
+}
+
+func (folder networkLimitFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return newNetworkLimitFolder(folder.Folder.GetSubFolder(subFolderRelativePath))
+}
+
+func (folder networkLimitFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.Folder.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	return newNetworkLimitReadCloser(reader), nil
+}
+
+func (folder networkLimitRangeFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	reader, version, err := folder.rangeReader.ReadObjectVersion(objectRelativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return newNetworkLimitReadCloser(reader), version, nil
+}
+
+func (folder networkLimitRangeFolder) ReadObjectRange(objectRelativePath string, offset int64,
+	version string) (io.ReadCloser, error) {
+	reader, err := folder.rangeReader.ReadObjectRange(objectRelativePath, offset, version)
+	if err != nil {
+		return nil, err
+	}
+	return newNetworkLimitReadCloser(reader), nil
+}
+
+func newNetworkLimitReadCloser(reader io.ReadCloser) io.ReadCloser {
+	return ioextensions.ReadCascadeCloser{Reader: limiters.NewNetworkLimitReader(reader), Closer: reader}
+}
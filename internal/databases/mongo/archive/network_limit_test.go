@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"golang.org/x/time/rate"
+)
+
+func TestNetworkLimit_AppliedToStoredBytes(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	uploader := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+	compressible := make([]byte, 1<<20)
+	incompressible := make([]byte, 8<<10)
+	rand.New(rand.NewSource(1)).Read(incompressible)
+	archives := make([]models.Archive, 0, 2)
+	for i, content := range [][]byte{compressible, incompressible} {
+		firstTS, lastTS := models.Timestamp{TS: uint32(i + 1), Inc: 1}, models.Timestamp{TS: uint32(i + 2), Inc: 1}
+		assert.NoError(t, uploader.UploadOplogArchive(bytes.NewReader(content), firstTS, lastTS))
+		arch, err := models.NewArchive(firstTS, lastTS, lz4.FileExtension, models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		archives = append(archives, arch)
+	}
+
+	// 1MB would take 16s at the limit, its compressed stream fits the burst
+	limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(64<<10), 64<<10)
+	defer func() { limiters.NetworkLimiter = nil }()
+	start := time.Now()
+	_, err := uploader.PushStream(bytes.NewReader(compressible))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, (&StorageDownloader{oplogsFolder: folder}).DownloadOplogArchive(archives[0], nil,
+		bufferWriteCloser{&buf}))
+	assert.Equal(t, compressible, buf.Bytes())
+	assert.Less(t, time.Since(start), 4*time.Second)
+
+	limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(16<<10), 1<<10)
+	start = time.Now()
+	buf.Reset()
+	assert.NoError(t, (&StorageDownloader{oplogsFolder: folder}).DownloadOplogArchive(archives[1], nil,
+		bufferWriteCloser{&buf}))
+	assert.Equal(t, incompressible, buf.Bytes())
+	assert.Greater(t, time.Since(start), 300*time.Millisecond)
+}
@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+// OplogArchiveUpload is the oplog archive stream uploaded by UploadOplogArchives
+type OplogArchiveUpload struct {
+	Stream  io.Reader
+	FirstTS models.Timestamp
+	LastTS  models.Timestamp
+}
+
+// UploadOplogArchives uploads the archives, at most concurrency of them at once. Every archive is attempted:
+// the error of each archive is returned at its position along with the first error in the archives order.
+// The archives following the failed one are deleted once uploaded, so the archived oplog has no gap
+// and the next oplog push resumes since the failed archive.
+func (su *StorageUploader) UploadOplogArchives(archives []OplogArchiveUpload, concurrency int) ([]error, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	uploaded := make([]models.Archive, len(archives))
+	errs := make([]error, len(archives))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(archives); i++ {
+		wg.Add(1)
+		// each worker keeps its own buffer, the uploader one is not shared
+		go func(buf *bytes.Buffer) {
+			defer wg.Done()
+			for index := range indexes {
+				arch := archives[index]
+				uploaded[index], errs[index] = su.uploadObservedOplogArchive(arch.Stream, arch.FirstTS, arch.LastTS, buf)
+			}
+		}(&bytes.Buffer{})
+	}
+	for index := range archives {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	for index, err := range errs {
+		if err != nil {
+			su.deleteOplogArchives(uploaded[index:])
+			return errs, fmt.Errorf("can not upload oplog archive %d of %d: %w", index+1, len(archives), err)
+		}
+	}
+	return errs, nil
+}
+
+// deleteOplogArchives deletes the archives with their checksums, the ones not built are skipped
+func (su *StorageUploader) deleteOplogArchives(archives []models.Archive) {
+	var keys []string
+	for _, arch := range archives {
+		if arch.Ext == "" {
+			continue
+		}
+		keys = append(keys, arch.Filename(), models.OplogChecksumsPath+arch.ChecksumFilename())
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := su.Folder().DeleteObjects(keys); err != nil {
+		tracelog.WarningLogger.Printf("Can not delete oplog archives uploaded after the failed one: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// concurrencyTrackingFolder records the maximum number of objects being put at once
+type concurrencyTrackingFolder struct {
+	storage.Folder
+	mu       sync.Mutex
+	inFlight int
+	maximum  int
+}
+
+func (f *concurrencyTrackingFolder) PutObject(name string, content io.Reader) error {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maximum {
+		f.maximum = f.inFlight
+	}
+	f.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	err := f.Folder.PutObject(name, content)
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+	return err
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("failed to read")
+}
+
+func buildOplogArchiveUploads(count int) []OplogArchiveUpload {
+	archives := make([]OplogArchiveUpload, count)
+	for i := range archives {
+		archives[i] = OplogArchiveUpload{
+			Stream:  strings.NewReader(strings.Repeat("oplog document ", 100*(i+1))),
+			FirstTS: models.Timestamp{TS: uint32(i + 1), Inc: 1},
+			LastTS:  models.Timestamp{TS: uint32(i + 2), Inc: 1},
+		}
+	}
+	return archives
+}
+
+func TestStorageUploader_UploadOplogArchives(t *testing.T) {
+	folder := &concurrencyTrackingFolder{Folder: memory.NewFolder("", memory.NewStorage())}
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+
+	archives := buildOplogArchiveUploads(6)
+	errs, err := su.UploadOplogArchives(archives, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]error, len(archives)), errs)
+	assert.Equal(t, 3, folder.maximum)
+
+	for _, upload := range archives {
+		arch, err := models.NewArchive(upload.FirstTS, upload.LastTS, lz4.FileExtension, models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		for _, name := range []string{arch.Filename(), models.OplogChecksumsPath + arch.ChecksumFilename()} {
+			exists, err := folder.Exists(name)
+			assert.NoError(t, err)
+			assert.True(t, exists, name)
+		}
+	}
+}
+
+func TestStorageUploader_UploadOplogArchives_DeletesArchivesAfterFailed(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+
+	archives := buildOplogArchiveUploads(4)
+	archives[1].Stream = failingReader{}
+	errs, err := su.UploadOplogArchives(archives, 2)
+	assert.Error(t, err)
+	assert.Len(t, errs, len(archives))
+	assert.True(t, errors.Is(err, errs[1]))
+	for _, i := range []int{0, 2, 3} {
+		assert.NoError(t, errs[i])
+	}
+
+	for i, upload := range archives {
+		arch, err := models.NewArchive(upload.FirstTS, upload.LastTS, lz4.FileExtension, models.ArchiveTypeOplog)
+		assert.NoError(t, err)
+		exists, err := folder.Exists(arch.Filename())
+		assert.NoError(t, err)
+		checksumExists, err := folder.Exists(models.OplogChecksumsPath + arch.ChecksumFilename())
+		assert.NoError(t, err)
+		// only the archive preceding the failed one is kept
+		assert.Equal(t, i == 0, exists, arch.Filename())
+		assert.Equal(t, i == 0, checksumExists, arch.ChecksumFilename())
+	}
+}
@@ -0,0 +1,363 @@
+package archive
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type cacheEntryState int
+
+const (
+	cacheEntryDownloading cacheEntryState = iota
+	cacheEntryReady
+	cacheEntryFailed
+	// cacheEntryStreamed is the archive requested before its prefetch started, it is read from the storage
+	cacheEntryStreamed
+)
+
+// oplogCacheEntry is the decompressed oplog archive stored in the cache directory
+type oplogCacheEntry struct {
+	arch     models.Archive
+	index    int
+	path     string
+	size     int64
+	checksum []byte
+	state    cacheEntryState
+	err      error
+	consumed bool
+	readers  int
+	element  *list.Element
+}
+
+// CachedDownloader is the Downloader prefetching the oplog archives of the replayed sequence:
+// while the current archive is applied, the next lookahead archives are downloaded and decompressed
+// into the cache directory limited by maxSize bytes: the prefetch is paused once the limit is reached,
+// so the cache exceeds it by one archive at most. The consumed archives are evicted, the least recently used first.
+// The archive requested before its prefetch started is streamed from the storage rather than waited for.
+// Each cached archive is stored with its size and SHA-256 and is verified before it is read:
+// the corrupted cached file is removed and the archive is fetched from the storage again.
+type CachedDownloader struct {
+	Downloader
+	dir       string
+	maxSize   int64
+	lookahead int
+	sequence  Sequence
+	positions map[string]int
+
+	mutex   sync.Mutex
+	changed *sync.Cond
+	entries map[int]*oplogCacheEntry
+	lru     *list.List
+	size    int64
+	current int
+	closed  bool
+	done    chan struct{}
+}
+
+// NewCachedDownloader starts prefetching the archives of the sequence by the downloader into the dir,
+// Close stops it and removes the cached archives
+func NewCachedDownloader(downloader Downloader, sequence Sequence, dir string,
+	maxSize int64, lookahead int) (*CachedDownloader, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("can not create oplog cache directory '%s': %w", dir, err)
+	}
+	cd := &CachedDownloader{
+		Downloader: downloader,
+		dir:        dir,
+		maxSize:    maxSize,
+		lookahead:  lookahead,
+		sequence:   sequence,
+		positions:  make(map[string]int, len(sequence)),
+		entries:    make(map[int]*oplogCacheEntry),
+		lru:        list.New(),
+		done:       make(chan struct{}),
+	}
+	cd.changed = sync.NewCond(&cd.mutex)
+	for i, arch := range sequence {
+		cd.positions[arch.Filename()] = i
+	}
+	go cd.prefetch()
+	return cd, nil
+}
+
+// OplogArchiveReader returns the reader of the cached archive, the archives out of the sequence
+// and the ones which can not be cached are streamed from the storage
+func (cd *CachedDownloader) OplogArchiveReader(arch models.Archive) (io.ReadCloser, error) {
+	index, ok := cd.positions[arch.Filename()]
+	if !ok {
+		return cd.Downloader.OplogArchiveReader(arch)
+	}
+
+	cd.mutex.Lock()
+	for i, entry := range cd.entries {
+		if i < index {
+			entry.consumed = true
+			if entry.state == cacheEntryStreamed {
+				// the streamed archive has no cached file to evict
+				delete(cd.entries, i)
+			}
+		}
+	}
+	cd.current = index
+	entry := cd.entries[index]
+	if entry == nil {
+		// the prefetch is behind the replay, so the archive is not downloaded to the cache first
+		cd.entries[index] = &oplogCacheEntry{arch: arch, index: index, state: cacheEntryStreamed, consumed: true}
+		cd.changed.Broadcast()
+		cd.mutex.Unlock()
+		return cd.Downloader.OplogArchiveReader(arch)
+	}
+	cd.changed.Broadcast()
+	for entry.state == cacheEntryDownloading {
+		if cd.closed {
+			cd.mutex.Unlock()
+			return nil, fmt.Errorf("oplog cache is closed")
+		}
+		cd.changed.Wait()
+		entry = cd.entries[index]
+	}
+	if entry.state != cacheEntryReady {
+		// the failed and the streamed entries are kept, so the archive is not prefetched again
+		cd.mutex.Unlock()
+		if entry.state == cacheEntryFailed {
+			tracelog.WarningLogger.Printf("Oplog archive '%s' is not cached: %v", arch.Filename(), entry.err)
+		}
+		return cd.Downloader.OplogArchiveReader(arch)
+	}
+	entry.readers++
+	cd.lru.MoveToFront(entry.element)
+	cd.mutex.Unlock()
+
+	reader, err := cd.openEntry(entry)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Cached oplog archive '%s' is not used: %v", arch.Filename(), err)
+		cd.releaseEntry(entry, true)
+		return cd.Downloader.OplogArchiveReader(arch)
+	}
+	return reader, nil
+}
+
+// DownloadOplogArchive writes the archive to the writeCloser as the Downloader does,
+// the cached archive is read from the cache
+func (cd *CachedDownloader) DownloadOplogArchive(arch models.Archive, from *models.Timestamp,
+	writeCloser io.WriteCloser) error {
+	if _, ok := cd.positions[arch.Filename()]; !ok {
+		return cd.Downloader.DownloadOplogArchive(arch, from, writeCloser)
+	}
+	return downloadOplogArchiveSince(cd.OplogArchiveReader, arch, from, writeCloser)
+}
+
+// Close stops the prefetching and removes the cached archives
+func (cd *CachedDownloader) Close() error {
+	cd.mutex.Lock()
+	cd.closed = true
+	cd.changed.Broadcast()
+	cd.mutex.Unlock()
+	<-cd.done
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	for _, entry := range cd.entries {
+		cd.removeEntry(entry)
+	}
+	return nil
+}
+
+// prefetch downloads the archives since the current one up to the lookahead while the cache has room
+func (cd *CachedDownloader) prefetch() {
+	defer close(cd.done)
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	for {
+		index := cd.nextToPrefetch()
+		for index < 0 && !cd.closed {
+			cd.changed.Wait()
+			index = cd.nextToPrefetch()
+		}
+		if cd.closed {
+			return
+		}
+
+		entry := &oplogCacheEntry{
+			arch:  cd.sequence[index],
+			index: index,
+			path:  filepath.Join(cd.dir, cd.sequence[index].Filename()),
+			state: cacheEntryDownloading,
+		}
+		cd.entries[index] = entry
+		cd.mutex.Unlock()
+		size, checksum, err := cd.download(entry)
+		cd.mutex.Lock()
+
+		entry.size, entry.checksum, entry.err = size, checksum, err
+		if err != nil {
+			entry.state = cacheEntryFailed
+		} else {
+			entry.state = cacheEntryReady
+			entry.element = cd.lru.PushFront(entry)
+			cd.size += size
+			cd.evict(cd.maxSize)
+		}
+		cd.changed.Broadcast()
+	}
+}
+
+// nextToPrefetch returns the index of the archive to download next, -1 if there is none
+func (cd *CachedDownloader) nextToPrefetch() int {
+	for i := cd.current; i < len(cd.sequence) && i <= cd.current+cd.lookahead; i++ {
+		if _, ok := cd.entries[i]; ok {
+			continue
+		}
+		// the consumed archives give room to the prefetched ones
+		cd.evict(cd.maxSize - 1)
+		if cd.size < cd.maxSize {
+			return i
+		}
+		return -1
+	}
+	return -1
+}
+
+// evict removes the least recently used consumed archives until the cache size does not exceed the limit,
+// the archives being read are kept. The prefetched archives are not evicted, since they are to be read soon:
+// the prefetch is paused instead while the cache is full.
+func (cd *CachedDownloader) evict(limit int64) {
+	for element := cd.lru.Back(); element != nil && cd.size > limit; {
+		entry := element.Value.(*oplogCacheEntry)
+		element = element.Prev()
+		if entry.consumed && entry.readers == 0 && entry.index != cd.current {
+			tracelog.DebugLogger.Printf("Evicting oplog archive '%s' from cache", entry.arch.Filename())
+			cd.removeEntry(entry)
+		}
+	}
+}
+
+// removeEntry deletes the cached file and forgets the entry, the entry should not be read
+func (cd *CachedDownloader) removeEntry(entry *oplogCacheEntry) {
+	cd.removeEntryFile(entry)
+	delete(cd.entries, entry.index)
+}
+
+func (cd *CachedDownloader) removeEntryFile(entry *oplogCacheEntry) {
+	if entry.element != nil {
+		cd.lru.Remove(entry.element)
+		cd.size -= entry.size
+		entry.element = nil
+	}
+	if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+		tracelog.WarningLogger.Printf("Can not remove cached oplog archive '%s': %v", entry.path, err)
+	}
+}
+
+// download writes the decompressed archive to the cache file, returns its size and SHA-256
+func (cd *CachedDownloader) download(entry *oplogCacheEntry) (int64, []byte, error) {
+	file, err := os.OpenFile(entry.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, nil, fmt.Errorf("can not create cached oplog archive '%s': %w", entry.path, err)
+	}
+	checksumHash := sha256.New()
+	writer := &checksumWriteCloser{writer: io.MultiWriter(file, checksumHash)}
+	err = cd.Downloader.DownloadOplogArchive(entry.arch, nil, writer)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(entry.path)
+		return 0, nil, fmt.Errorf("can not download oplog archive '%s': %w", entry.arch.Filename(), err)
+	}
+	return writer.size, checksumHash.Sum(nil), nil
+}
+
+// openEntry opens the cached file and verifies it against the size and the SHA-256 calculated on download
+// before any byte of it is returned, so the corrupted archive is not applied partially
+func (cd *CachedDownloader) openEntry(entry *oplogCacheEntry) (io.ReadCloser, error) {
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyCachedFile(file, entry.size, entry.checksum); err != nil {
+		utility.LoggedClose(file, "")
+		return nil, err
+	}
+	return &cachedArchiveReader{
+		file:    file,
+		release: func() { cd.releaseEntry(entry, false) },
+	}, nil
+}
+
+// verifyCachedFile compares the file with the size and the checksum, the file is rewound to its start
+func verifyCachedFile(file *os.File, size int64, checksum []byte) error {
+	checksumHash := sha256.New()
+	read, err := io.Copy(checksumHash, file)
+	if err != nil {
+		return fmt.Errorf("can not read '%s': %w", file.Name(), err)
+	}
+	if read != size {
+		return fmt.Errorf("size %d of '%s' does not match the downloaded %d", read, file.Name(), size)
+	}
+	if !bytes.Equal(checksumHash.Sum(nil), checksum) {
+		return fmt.Errorf("checksum mismatch of cached oplog archive '%s'", file.Name())
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return err
+}
+
+// releaseEntry marks the entry read, the file of the corrupted one is removed
+// and the entry is marked failed, so the archive is streamed from the storage when it is read again
+func (cd *CachedDownloader) releaseEntry(entry *oplogCacheEntry, corrupted bool) {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	entry.readers--
+	entry.consumed = true
+	if corrupted && entry.element != nil {
+		cd.removeEntryFile(entry)
+		entry.state = cacheEntryFailed
+		entry.err = fmt.Errorf("cached file '%s' is corrupted", entry.path)
+	}
+	cd.evict(cd.maxSize)
+	cd.changed.Broadcast()
+}
+
+// checksumWriteCloser counts the archive bytes written to the cache file and the hash,
+// the file is closed by the cache once the download is finished rather than by the downloader
+type checksumWriteCloser struct {
+	writer io.Writer
+	size   int64
+}
+
+func (w *checksumWriteCloser) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *checksumWriteCloser) Close() error {
+	return nil
+}
+
+// cachedArchiveReader reads the verified cached file, the entry is released once the reader is closed
+type cachedArchiveReader struct {
+	file    *os.File
+	release func()
+	once    sync.Once
+}
+
+func (r *cachedArchiveReader) Read(p []byte) (int, error) {
+	return r.file.Read(p)
+}
+
+func (r *cachedArchiveReader) Close() error {
+	err := r.file.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitCachedArchive waits for the archive at the index to be prefetched
+func waitCachedArchive(t *testing.T, cd *CachedDownloader, index int) *oplogCacheEntry {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		cd.mutex.Lock()
+		entry, ok := cd.entries[index]
+		ready := ok && entry.state == cacheEntryReady
+		cd.mutex.Unlock()
+		if ready {
+			return entry
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("archive %d is not prefetched", index)
+	return nil
+}
+
+func readCachedArchive(t *testing.T, cd *CachedDownloader, index int) string {
+	reader, err := cd.OplogArchiveReader(cd.sequence[index])
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	return string(data)
+}
+
+func TestCachedDownloader_PrefetchesArchives(t *testing.T) {
+	storageDownloader, folder, archives := newBatchDownloadFixture(t, 6)
+	folder.latency = nil
+	dir := t.TempDir()
+	cd, err := NewCachedDownloader(storageDownloader, archives, dir, 1024, 2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "oplog_0", readCachedArchive(t, cd, 0))
+	waitCachedArchive(t, cd, 1)
+	waitCachedArchive(t, cd, 2)
+	cd.mutex.Lock()
+	_, prefetchedBeyondLookahead := cd.entries[3]
+	cd.mutex.Unlock()
+	assert.False(t, prefetchedBeyondLookahead)
+
+	for i := 1; i < len(archives); i++ {
+		assert.Equal(t, fmt.Sprintf("oplog_%d", i), readCachedArchive(t, cd, i))
+	}
+	assert.NoError(t, cd.Close())
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCachedDownloader_EvictsConsumedArchivesFirst(t *testing.T) {
+	storageDownloader, folder, archives := newBatchDownloadFixture(t, 4)
+	folder.latency = nil
+	// each archive is 7 bytes, so two of them fit in the cache
+	cd, err := NewCachedDownloader(storageDownloader, archives, t.TempDir(), 14, 1)
+	assert.NoError(t, err)
+	defer func() { _ = cd.Close() }()
+
+	assert.Equal(t, "oplog_0", readCachedArchive(t, cd, 0))
+	waitCachedArchive(t, cd, 1)
+	assert.Equal(t, "oplog_1", readCachedArchive(t, cd, 1))
+	waitCachedArchive(t, cd, 2)
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	_, hasConsumed := cd.entries[0]
+	assert.False(t, hasConsumed)
+	assert.Contains(t, cd.entries, 1)
+	assert.Contains(t, cd.entries, 2)
+	assert.LessOrEqual(t, cd.size, int64(14))
+}
+
+func TestCachedDownloader_RefetchesTruncatedArchive(t *testing.T) {
+	storageDownloader, folder, archives := newBatchDownloadFixture(t, 3)
+	folder.latency = nil
+	cd, err := NewCachedDownloader(storageDownloader, archives, t.TempDir(), 1024, 2)
+	assert.NoError(t, err)
+	defer func() { _ = cd.Close() }()
+
+	assert.Equal(t, "oplog_0", readCachedArchive(t, cd, 0))
+	entry := waitCachedArchive(t, cd, 1)
+	assert.NoError(t, os.WriteFile(entry.path, []byte("oplog"), 0600))
+
+	assert.Equal(t, "oplog_1", readCachedArchive(t, cd, 1))
+	_, err = os.Stat(entry.path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "oplog_2", readCachedArchive(t, cd, 2))
+}
+
+func TestCachedDownloader_RefetchesCorruptedArchive(t *testing.T) {
+	storageDownloader, folder, archives := newBatchDownloadFixture(t, 3)
+	folder.latency = nil
+	cd, err := NewCachedDownloader(storageDownloader, archives, t.TempDir(), 1024, 2)
+	assert.NoError(t, err)
+	defer func() { _ = cd.Close() }()
+
+	assert.Equal(t, "oplog_0", readCachedArchive(t, cd, 0))
+	entry := waitCachedArchive(t, cd, 1)
+	assert.NoError(t, os.WriteFile(entry.path, []byte("oplog_X"), 0600))
+
+	// the corrupted cached file of the same size is not streamed, the archive is read from the storage
+	assert.Equal(t, "oplog_1", readCachedArchive(t, cd, 1))
+	_, err = os.Stat(entry.path)
+	assert.True(t, os.IsNotExist(err))
+	cd.mutex.Lock()
+	assert.Equal(t, cacheEntryFailed, cd.entries[1].state)
+	cd.mutex.Unlock()
+	assert.Equal(t, "oplog_2", readCachedArchive(t, cd, 2))
+}
+
+func TestCachedDownloader_StreamsArchiveNotPrefetched(t *testing.T) {
+	storageDownloader, folder, archives := newBatchDownloadFixture(t, 4)
+	folder.latency = nil
+	cd, err := NewCachedDownloader(storageDownloader, archives, t.TempDir(), 1024, 0)
+	assert.NoError(t, err)
+	defer func() { _ = cd.Close() }()
+
+	assert.Equal(t, "oplog_3", readCachedArchive(t, cd, 3))
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	if assert.Contains(t, cd.entries, 3) {
+		assert.Equal(t, cacheEntryStreamed, cd.entries[3].state)
+	}
+}
+
+func TestCachedDownloader_DownloadOplogArchive_ReadsCache(t *testing.T) {
+	storageDownloader, folder, archives := newBatchDownloadFixture(t, 2)
+	folder.latency = nil
+	cd, err := NewCachedDownloader(storageDownloader, archives, t.TempDir(), 1024, 1)
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, cd.Close()) }()
+
+	waitCachedArchive(t, cd, 0)
+	assert.NoError(t, folder.DeleteObjects([]string{archives[0].Filename()}))
+	var buf bytes.Buffer
+	assert.NoError(t, cd.DownloadOplogArchive(archives[0], nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, "oplog_0", buf.String())
+}
@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// oplogTailWriter parses the oplog records written to it and passes to the writeCloser only the ones
+// since the from timestamp. The records are expected to be ordered by the timestamp.
+type oplogTailWriter struct {
+	writeCloser io.WriteCloser
+	from        models.Timestamp
+	// pending is the beginning of the record which is not written completely yet
+	pending []byte
+	lastTS  *models.Timestamp
+}
+
+func newOplogTailWriter(writeCloser io.WriteCloser, from models.Timestamp) *oplogTailWriter {
+	return &oplogTailWriter{writeCloser: writeCloser, from: from}
+}
+
+func (writer *oplogTailWriter) Write(p []byte) (int, error) {
+	writer.pending = append(writer.pending, p...)
+	for len(writer.pending) >= 4 {
+		size := int(binary.LittleEndian.Uint32(writer.pending[:4]))
+		if size < 5 {
+			return 0, fmt.Errorf("invalid oplog record length %d", size)
+		}
+		if len(writer.pending) < size {
+			break
+		}
+		if err := writer.writeRecord(writer.pending[:size]); err != nil {
+			return 0, err
+		}
+		writer.pending = writer.pending[size:]
+	}
+	return len(p), nil
+}
+
+func (writer *oplogTailWriter) writeRecord(record []byte) error {
+	op, err := models.OplogFromRaw(bson.Raw(record))
+	if err != nil {
+		return fmt.Errorf("oplog record decoding failed: %w", err)
+	}
+	ts := op.TS
+	models.PutOplogEntry(op)
+
+	if writer.lastTS != nil && !models.LessTS(*writer.lastTS, ts) {
+		return fmt.Errorf("oplog record '%s' follows the record '%s' in the archive", ts, *writer.lastTS)
+	}
+	writer.lastTS = &ts
+	if models.LessTS(ts, writer.from) {
+		return nil
+	}
+	_, err = writer.writeCloser.Write(record)
+	return err
+}
+
+func (writer *oplogTailWriter) Close() error {
+	return writer.writeCloser.Close()
+}
+
+// finish checks the archive is not truncated in the middle of the record once it is written completely
+func (writer *oplogTailWriter) finish() error {
+	if len(writer.pending) > 0 {
+		return fmt.Errorf("oplog archive ends with the incomplete record of %d bytes", len(writer.pending))
+	}
+	return nil
+}
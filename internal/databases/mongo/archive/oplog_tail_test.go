@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func marshalTestOplog(t *testing.T, timestamps ...models.Timestamp) [][]byte {
+	records := make([][]byte, 0, len(timestamps))
+	for _, ts := range timestamps {
+		record, err := bson.Marshal(bson.D{{Key: "ts", Value: models.BsonTimestampFromOplogTS(ts)}, {Key: "op", Value: "i"}})
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+	return records
+}
+
+func uploadTestOplogRecords(t *testing.T, records [][]byte) (*StorageDownloader, models.Archive) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	firstTS, lastTS := models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 9, Inc: 1}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder)).
+		UploadOplogArchive(bytes.NewReader(bytes.Join(records, nil)), firstTS, lastTS))
+	arch, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+	return &StorageDownloader{oplogsFolder: folder}, arch
+}
+
+func TestStorageDownloader_DownloadOplogArchive_SinceTimestamp(t *testing.T) {
+	records := marshalTestOplog(t, models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1},
+		models.Timestamp{TS: 2, Inc: 2}, models.Timestamp{TS: 3, Inc: 1})
+	downloader, arch := uploadTestOplogRecords(t, records)
+
+	var buf bytes.Buffer
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, &models.Timestamp{TS: 2, Inc: 2}, bufferWriteCloser{&buf}))
+	assert.Equal(t, bytes.Join(records[2:], nil), buf.Bytes())
+
+	buf.Reset()
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, bytes.Join(records, nil), buf.Bytes())
+
+	buf.Reset()
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, &models.Timestamp{TS: 5, Inc: 1}, bufferWriteCloser{&buf}))
+	assert.Empty(t, buf.Bytes())
+}
+
+func TestStorageDownloader_DownloadOplogArchive_SinceTimestampWithRetries(t *testing.T) {
+	records := marshalTestOplog(t, models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1})
+	downloader, arch := uploadTestOplogRecords(t, records)
+	downloader.retryPolicy = RetryPolicy{MaxAttempts: 2}
+
+	var buf bytes.Buffer
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, &models.Timestamp{TS: 2, Inc: 1}, bufferWriteCloser{&buf}))
+	assert.Equal(t, records[1], buf.Bytes())
+}
+
+func TestStorageDownloader_DownloadOplogArchive_SinceTimestampRejectsUnordered(t *testing.T) {
+	records := marshalTestOplog(t, models.Timestamp{TS: 2, Inc: 1}, models.Timestamp{TS: 1, Inc: 1})
+	downloader, arch := uploadTestOplogRecords(t, records)
+
+	var buf bytes.Buffer
+	assert.Error(t, downloader.DownloadOplogArchive(arch, &models.Timestamp{TS: 1, Inc: 1}, bufferWriteCloser{&buf}))
+}
+
+func TestStorageDownloader_DownloadOplogArchive_SinceTimestampRejectsTruncated(t *testing.T) {
+	records := marshalTestOplog(t, models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1})
+	records[1] = records[1][:len(records[1])-3]
+	downloader, arch := uploadTestOplogRecords(t, records)
+
+	var buf bytes.Buffer
+	assert.Error(t, downloader.DownloadOplogArchive(arch, &models.Timestamp{TS: 1, Inc: 1}, bufferWriteCloser{&buf}))
+	assert.Equal(t, records[0], buf.Bytes())
+}
@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupRecompressReport lists the objects of the backup stream recompressed by the target method,
+// the paths are relative to the backup stream folder.
+type BackupRecompressReport struct {
+	BackupName string                        `json:"BackupName"`
+	Objects    []internal.RecompressedObject `json:"Objects"`
+	DryRun     bool                          `json:"DryRun"`
+}
+
+// RecompressBackup recompresses the backup stream by the compressor in place, e.g. to migrate the backups made
+// with the other WALG_COMPRESSION_METHOD without the restore. The new stream is uploaded next to the old one
+// and verified against it and the checksum recorded in the sentinel, then the sentinel records the new compression
+// method and the old stream is deleted. The backup uploaded by parts is not supported,
+// since the compression is recorded in its stream metadata.
+func RecompressBackup(rootFolder storage.Folder, backupName string, compressor compression.Compressor,
+	dryRun bool) (BackupRecompressReport, error) {
+	report := BackupRecompressReport{BackupName: backupName, Objects: []internal.RecompressedObject{}, DryRun: dryRun}
+	sentinelFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	backup := internal.NewBackup(sentinelFolder, backupName)
+	var sentinel models.Backup
+	if err := backup.FetchSentinel(&sentinel); err != nil {
+		return report, fmt.Errorf("can not fetch backup '%s' sentinel: %w", backupName, err)
+	}
+	dataFolder := BackupDataFolder(rootFolder, &sentinel)
+	uploadedByParts, err := dataFolder.Exists(internal.StreamMetadataNameFromBackup(backupName))
+	if err != nil {
+		return report, fmt.Errorf("can not check backup '%s' stream metadata: %w", backupName, err)
+	}
+	if uploadedByParts {
+		return report, fmt.Errorf("backup '%s' is uploaded by parts, its recompression is not supported", backupName)
+	}
+
+	objects, err := internal.RecompressFolder(dataFolder.GetSubFolder(backupName), compressor, dryRun,
+		func(objects []internal.RecompressedObject) error {
+			if len(objects) != 1 {
+				return fmt.Errorf("backup '%s' has %d streams, expected one", backupName, len(objects))
+			}
+			stream := objects[0]
+			if sentinel.Checksum != nil && (sentinel.Checksum.Size != stream.Size || sentinel.Checksum.SHA256 != stream.SHA256) {
+				return fmt.Errorf("backup '%s' stream does not match the sentinel checksum: %d bytes with sha256 %s, "+
+					"recompressed %d bytes with sha256 %s", backupName, sentinel.Checksum.Size, sentinel.Checksum.SHA256,
+					stream.Size, stream.SHA256)
+			}
+			sentinel.Compression = NewCompressionMethod(compressor)
+			sentinel.CompressedSize = stream.CompressedSize
+			return internal.UploadDto(sentinelFolder, &sentinel, internal.SentinelNameFromBackup(backupName))
+		})
+	if err != nil {
+		return report, err
+	}
+	report.Objects = objects
+	return report, nil
+}
@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestRecompressBackup(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	rootFolder := memory.NewFolder("", memory.NewStorage())
+	backupsFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	constructor := &testMetaConstructor{}
+	content := strings.Repeat("backup data ", 1000)
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], backupsFolder)).
+		UploadBackup(strings.NewReader(content), testErrWaiter{}, constructor))
+	backupName := constructor.backup.BackupName
+	gzipCompressor := compression.Compressors[gzip.AlgorithmName]
+
+	report, err := RecompressBackup(rootFolder, backupName, gzipCompressor, true)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Objects, 1)
+	exists, err := backupsFolder.Exists(internal.GetStreamName(backupName, lz4.FileExtension))
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	report, err = RecompressBackup(rootFolder, backupName, gzipCompressor, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "stream."+lz4.FileExtension, report.Objects[0].Path)
+	assert.Equal(t, "stream."+gzip.FileExtension, report.Objects[0].NewPath)
+	exists, err = backupsFolder.Exists(internal.GetStreamName(backupName, lz4.FileExtension))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	var sentinel models.Backup
+	backup := internal.NewBackup(backupsFolder, backupName)
+	assert.NoError(t, backup.FetchSentinel(&sentinel))
+	assert.Equal(t, NewCompressionMethod(gzipCompressor), sentinel.Compression)
+	assert.Equal(t, report.Objects[0].CompressedSize, sentinel.CompressedSize)
+	assert.Equal(t, content, fetchBackupStream(t, backupsFolder, &sentinel))
+
+	// the backup compressed by the target method already is left as is
+	report, err = RecompressBackup(rootFolder, backupName, gzipCompressor, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Objects)
+}
+
+func TestRecompressBackup_ChecksumMismatch(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	rootFolder := memory.NewFolder("", memory.NewStorage())
+	backupsFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	backupName := internal.StreamPrefix + "20221010T101010Z"
+	putCompressedStream(t, backupsFolder, backupName, compression.Compressors[lz4.AlgorithmName], "backup data")
+	sentinel := models.Backup{BackupName: backupName, Checksum: &models.StreamChecksum{Size: 11, SHA256: "0000"}}
+	assert.NoError(t, internal.UploadDto(backupsFolder, &sentinel, internal.SentinelNameFromBackup(backupName)))
+
+	_, err := RecompressBackup(rootFolder, backupName, compression.Compressors[gzip.AlgorithmName], false)
+	assert.Error(t, err)
+	exists, err := backupsFolder.Exists(internal.GetStreamName(backupName, gzip.FileExtension))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = backupsFolder.Exists(internal.GetStreamName(backupName, lz4.FileExtension))
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// RetryPolicy defines how storage operations are retried on transient errors.
+// Delay before the n-th retry is BaseDelay * Multiplier^(n-1), randomly deviated by Jitter fraction of it.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	Multiplier  float64
+	Jitter      float64
+
+	sleep func(time.Duration)
+}
+
+// DefaultRetryPolicy builds policy used when nothing is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, Multiplier: 2, Jitter: 0.2}
+}
+
+// ConfigureRetryPolicy builds retry policy from WALG_DOWNLOAD_* settings
+func ConfigureRetryPolicy() (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+	if retriesStr, ok := internal.GetSetting(internal.DownloadMaxRetriesSetting); ok {
+		retries, err := strconv.Atoi(retriesStr)
+		if err != nil || retries < 0 {
+			return RetryPolicy{}, fmt.Errorf("non-negative integer expected for %s setting but given '%s'",
+				internal.DownloadMaxRetriesSetting, retriesStr)
+		}
+		policy.MaxAttempts = retries + 1
+	}
+	if _, ok := internal.GetSetting(internal.DownloadRetryBaseDelay); ok {
+		delay, err := internal.GetDurationSetting(internal.DownloadRetryBaseDelay)
+		if err != nil {
+			return RetryPolicy{}, err
+		}
+		policy.BaseDelay = delay
+	}
+	multiplier, err := getFloatSetting(internal.DownloadRetryMultiplier, policy.Multiplier)
+	if err != nil {
+		return RetryPolicy{}, err
+	}
+	policy.Multiplier = multiplier
+	jitter, err := getFloatSetting(internal.DownloadRetryJitter, policy.Jitter)
+	if err != nil {
+		return RetryPolicy{}, err
+	}
+	policy.Jitter = jitter
+	return policy, nil
+}
+
+func getFloatSetting(setting string, def float64) (float64, error) {
+	valueStr, ok := internal.GetSetting(setting)
+	if !ok {
+		return def, nil
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("non-negative number expected for %s setting but given '%s'", setting, valueStr)
+	}
+	return value, nil
+}
+
+// Do calls fn until it succeeds, returns non-retryable error or attempts are exhausted.
+// Zero policy makes the single attempt.
+func (p RetryPolicy) Do(fn func() error) error {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !IsRetryableError(err) {
+			return err
+		}
+		tracelog.WarningLogger.Printf("Storage operation failed (attempt %d of %d), retrying: %v", attempt, p.MaxAttempts, err)
+		p.wait(delay)
+		delay = time.Duration(float64(delay) * p.Multiplier)
+	}
+}
+
+func (p RetryPolicy) wait(delay time.Duration) {
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	if p.sleep != nil {
+		p.sleep(delay)
+		return
+	}
+	time.Sleep(delay)
+}
+
+// IsRetryableError checks if the storage error is transient: timeouts, dropped connections and server side failures.
+// Missing objects and the unknown errors are never retried.
+func IsRetryableError(err error) bool {
+	var notFoundErr storage.ObjectNotFoundError
+	if errors.As(err, &notFoundErr) {
+		return false
+	}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode() >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,186 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type statusCodeError struct {
+	code int
+}
+
+func (err statusCodeError) Error() string {
+	return fmt.Sprintf("status code %d", err.code)
+}
+
+func (err statusCodeError) StatusCode() int {
+	return err.code
+}
+
+// flakyFolder fails the first failures reads and listings with err, then delegates to the wrapped folder
+type flakyFolder struct {
+	storage.Folder
+	failures int
+	err      error
+	reads    int
+	listings int
+}
+
+func (f *flakyFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	f.reads++
+	if f.reads <= f.failures {
+		return nil, f.err
+	}
+	return f.Folder.ReadObject(objectRelativePath)
+}
+
+func (f *flakyFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	f.listings++
+	if f.listings <= f.failures {
+		return nil, nil, f.err
+	}
+	return f.Folder.ListFolder()
+}
+
+func newRetryFixture(t *testing.T, failures int, err error) (*StorageDownloader, *flakyFolder, models.Archive) {
+	folder := &flakyFolder{Folder: memory.NewFolder("", memory.NewStorage()), failures: failures, err: err}
+	firstTS, lastTS := models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder.Folder)).
+		UploadOplogArchive(bytes.NewReader([]byte("oplog")), firstTS, lastTS))
+	arch, archErr := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, archErr)
+
+	downloader := &StorageDownloader{oplogsFolder: folder}
+	downloader.SetRetryPolicy(RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, Multiplier: 2,
+		sleep: func(time.Duration) {}})
+	return downloader, folder, arch
+}
+
+func TestStorageDownloader_DownloadOplogArchive_RetriesTransientErrors(t *testing.T) {
+	downloader, folder, arch := newRetryFixture(t, 3, statusCodeError{503})
+
+	var buf bytes.Buffer
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, "oplog", buf.String())
+	assert.Equal(t, 4, folder.reads)
+}
+
+func TestStorageDownloader_DownloadOplogArchive_GivesUpAfterMaxAttempts(t *testing.T) {
+	downloader, folder, arch := newRetryFixture(t, 10, io.ErrUnexpectedEOF)
+
+	var buf bytes.Buffer
+	assert.Error(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+	assert.Empty(t, buf.String())
+	assert.Equal(t, 4, folder.reads)
+}
+
+func TestStorageDownloader_DownloadOplogArchive_NonRetryableErrors(t *testing.T) {
+	for _, err := range []error{statusCodeError{404}, storage.NewObjectNotFoundError("oplog"), fmt.Errorf("unknown")} {
+		downloader, folder, arch := newRetryFixture(t, 1, err)
+
+		var buf bytes.Buffer
+		assert.Error(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+		assert.Equal(t, 1, folder.reads, err.Error())
+	}
+}
+
+// droppingFolder drops each read of the object after dropAfter bytes as the dropped connection does
+type droppingFolder struct {
+	*memory.Folder
+	dropAfter    int64
+	rangeOffsets []int64
+}
+
+func (f *droppingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return f.ReadObjectRange(objectRelativePath, 0, "")
+}
+
+func (f *droppingFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	f.rangeOffsets = append(f.rangeOffsets, 0)
+	reader, version, err := f.Folder.ReadObjectVersion(objectRelativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return f.drop(reader), version, nil
+}
+
+func (f *droppingFolder) ReadObjectRange(objectRelativePath string, offset int64, version string) (io.ReadCloser, error) {
+	f.rangeOffsets = append(f.rangeOffsets, offset)
+	reader, err := f.Folder.ReadObjectRange(objectRelativePath, offset, version)
+	if err != nil {
+		return nil, err
+	}
+	return f.drop(reader), nil
+}
+
+func (f *droppingFolder) drop(reader io.Reader) io.ReadCloser {
+	return io.NopCloser(io.MultiReader(io.LimitReader(reader, f.dropAfter), iotest.ErrReader(io.ErrUnexpectedEOF)))
+}
+
+func TestStorageDownloader_DownloadOplogArchive_ResumesDroppedStream(t *testing.T) {
+	folder := &droppingFolder{Folder: memory.NewFolder("", memory.NewStorage()), dropAfter: 4096}
+	firstTS, lastTS := models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	oplog := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(oplog)
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compressor, folder.Folder)).
+		UploadOplogArchive(bytes.NewReader(oplog), firstTS, lastTS))
+	arch, err := models.NewArchive(firstTS, lastTS, compressor.FileExtension(), models.ArchiveTypeOplog)
+	assert.NoError(t, err)
+
+	viper.Set(internal.DownloadRangeResumesSetting, 100)
+	defer viper.Set(internal.DownloadRangeResumesSetting, nil)
+	downloader := &StorageDownloader{oplogsFolder: folder, retryPolicy: DefaultRetryPolicy()}
+	var buf bytes.Buffer
+	assert.NoError(t, downloader.DownloadOplogArchive(arch, nil, bufferWriteCloser{&buf}))
+	assert.Equal(t, oplog, buf.Bytes())
+	// the stream is resumed since the bytes already read instead of being downloaded again
+	for i, offset := range folder.rangeOffsets {
+		assert.Equal(t, int64(i)*folder.dropAfter, offset)
+	}
+	assert.Greater(t, len(folder.rangeOffsets), 1)
+}
+
+func TestStorageDownloader_ListOplogArchives_RetriesTransientErrors(t *testing.T) {
+	downloader, folder, arch := newRetryFixture(t, 2, statusCodeError{500})
+
+	archives, err := downloader.ListOplogArchives()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Archive{arch}, archives)
+	assert.Equal(t, 3, folder.listings)
+}
+
+func TestRetryPolicy_Do_Backoff(t *testing.T) {
+	var delays []time.Duration
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, Multiplier: 2,
+		sleep: func(delay time.Duration) { delays = append(delays, delay) }}
+
+	err := policy.Do(func() error { return statusCodeError{502} })
+	assert.Equal(t, statusCodeError{502}, err)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, delays)
+}
+
+func TestRetryPolicy_Do_ZeroPolicyMakesSingleAttempt(t *testing.T) {
+	attempts := 0
+	err := RetryPolicy{}.Do(func() error {
+		attempts++
+		return statusCodeError{500}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/metrics"
+)
+
+// SetMetricsCollector registers collector to receive upload metrics, nothing is measured if it is not set.
+func (su *StorageUploader) SetMetricsCollector(collector metrics.Collector) {
+	su.collector = collector
+}
+
+// SetRetryPolicy replaces the policy used to retry the uploads of the buffered oplog archives on transient storage errors.
+func (su *StorageUploader) SetRetryPolicy(policy RetryPolicy) {
+	su.retryPolicy = policy
+}
+
+// pushBackupStream pushes backup stream reporting its metrics to the collector, the returned compressed size
+// is counted by the uploader provider during the upload and is 0 if it can not be measured.
+// The stream is not buffered, so its upload is not retried.
+func (su *StorageUploader) pushBackupStream(stream io.Reader) (string, int64, error) {
+	var rawBytes int64
+	startTime := time.Now()
+	startSize, sizeErr := su.UploadedDataSize()
+	backupName, err := su.PushStream(internal.NewWithSizeReader(stream, &rawBytes))
+	var uploadedBytes int64
+	if err == nil && sizeErr == nil {
+		var endSize int64
+		endSize, sizeErr = su.UploadedDataSize()
+		uploadedBytes = endSize - startSize
+	}
+	if err == nil && sizeErr != nil {
+		tracelog.WarningLogger.Printf("Can not measure compressed size of backup '%s': %v", backupName, sizeErr)
+	}
+	if su.collector != nil {
+		su.observeUpload(metrics.BackupOperation, rawBytes, uploadedBytes, startTime, err)
+	}
+	return backupName, uploadedBytes, err
+}
+
+// uploadWithRetries puts the buffered object to storage retrying the transient errors, each retry is counted by the collector.
+func (su *StorageUploader) uploadWithRetries(operation metrics.Operation, path string, content []byte) error {
+	attempt := 0
+	return su.retryPolicy.Do(func() error {
+		if attempt > 0 && su.collector != nil {
+			su.collector.IncRetries(operation)
+		}
+		attempt++
+		return su.Upload(path, bytes.NewReader(content))
+	})
+}
+
+func (su *StorageUploader) observeUpload(operation metrics.Operation, rawBytes, uploadedBytes int64,
+	startTime time.Time, err error) {
+	if err != nil {
+		su.collector.IncFailures(operation)
+		return
+	}
+	su.collector.ObserveUpload(metrics.Upload{
+		Operation:     operation,
+		RawBytes:      rawBytes,
+		UploadedBytes: uploadedBytes,
+		Duration:      time.Since(startTime),
+	})
+}
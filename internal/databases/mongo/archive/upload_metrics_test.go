@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type recordingCollector struct {
+	mu       sync.Mutex
+	uploads  []metrics.Upload
+	retries  map[metrics.Operation]int
+	failures map[metrics.Operation]int
+}
+
+func newRecordingCollector() *recordingCollector {
+	return &recordingCollector{retries: map[metrics.Operation]int{}, failures: map[metrics.Operation]int{}}
+}
+
+func (c *recordingCollector) ObserveUpload(upload metrics.Upload) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uploads = append(c.uploads, upload)
+}
+
+func (c *recordingCollector) IncRetries(operation metrics.Operation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retries[operation]++
+}
+
+func (c *recordingCollector) IncFailures(operation metrics.Operation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures[operation]++
+}
+
+type failingPutFolder struct {
+	storage.Folder
+}
+
+func (failingPutFolder) PutObject(string, io.Reader) error {
+	return errors.New("failed to upload")
+}
+
+func TestStorageUploader_UploadOplogArchive_Metrics(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+	collector := newRecordingCollector()
+	su.SetMetricsCollector(collector)
+
+	content := strings.Repeat("oplog document ", 1000)
+	assert.NoError(t, su.UploadOplogArchive(strings.NewReader(content),
+		models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}))
+
+	assert.Len(t, collector.uploads, 1)
+	upload := collector.uploads[0]
+	assert.Equal(t, metrics.OplogArchiveOperation, upload.Operation)
+	assert.Equal(t, int64(len(content)), upload.RawBytes)
+	assert.Greater(t, upload.UploadedBytes, int64(0))
+	assert.Greater(t, upload.CompressionRatio(), 1.0)
+}
+
+// flakyPutFolder fails the first failures uploads with the transient error
+type flakyPutFolder struct {
+	storage.Folder
+	failures int
+	puts     int
+}
+
+func (f *flakyPutFolder) PutObject(name string, content io.Reader) error {
+	f.puts++
+	if f.puts <= f.failures {
+		return statusCodeError{503}
+	}
+	return f.Folder.PutObject(name, content)
+}
+
+func TestStorageUploader_UploadOplogArchive_CountsRetries(t *testing.T) {
+	folder := &flakyPutFolder{Folder: memory.NewFolder("", memory.NewStorage()), failures: 2}
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+	su.SetRetryPolicy(RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, sleep: func(time.Duration) {}})
+	collector := newRecordingCollector()
+	su.SetMetricsCollector(collector)
+
+	content := strings.Repeat("oplog document ", 1000)
+	assert.NoError(t, su.UploadOplogArchive(strings.NewReader(content),
+		models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}))
+
+	assert.Equal(t, 2, collector.retries[metrics.OplogArchiveOperation])
+	assert.Empty(t, collector.failures)
+	assert.Len(t, collector.uploads, 1)
+	// the uploaded bytes are the archive only, the checksum is not counted
+	archives, _, err := folder.ListFolder()
+	assert.NoError(t, err)
+	assert.Len(t, archives, 1)
+	assert.Equal(t, archives[0].GetSize(), collector.uploads[0].UploadedBytes)
+}
+
+func TestStorageUploader_UploadOplogArchive_MetricsFailure(t *testing.T) {
+	folder := failingPutFolder{memory.NewFolder("", memory.NewStorage())}
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+	collector := newRecordingCollector()
+	su.SetMetricsCollector(collector)
+
+	assert.Error(t, su.UploadOplogArchive(strings.NewReader("oplog"),
+		models.Timestamp{TS: 1, Inc: 1}, models.Timestamp{TS: 2, Inc: 1}))
+	assert.Empty(t, collector.uploads)
+	assert.Equal(t, 1, collector.failures[metrics.OplogArchiveOperation])
+}
+
+func TestStorageUploader_PushBackupStream_Metrics(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	su := NewBackupStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder), nil)
+	collector := newRecordingCollector()
+	su.SetMetricsCollector(collector)
+
+	content := strings.Repeat("backup data ", 1000)
+	_, compressedSize, err := su.pushBackupStream(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Greater(t, compressedSize, int64(0))
+
+	assert.Len(t, collector.uploads, 1)
+	upload := collector.uploads[0]
+	assert.Equal(t, metrics.BackupOperation, upload.Operation)
+	assert.Equal(t, int64(len(content)), upload.RawBytes)
+	assert.Greater(t, upload.UploadedBytes, int64(0))
+	assert.Less(t, upload.UploadedBytes, upload.RawBytes)
+	assert.Equal(t, compressedSize, upload.UploadedBytes)
+}
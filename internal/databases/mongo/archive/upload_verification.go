@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+// streamDigest counts and hashes the bytes written to it.
+type streamDigest struct {
+	hash hash.Hash
+	size int64
+}
+
+func newStreamDigest() *streamDigest {
+	return &streamDigest{hash: sha256.New()}
+}
+
+func (d *streamDigest) Write(p []byte) (int, error) {
+	d.size += int64(len(p))
+	return d.hash.Write(p)
+}
+
+// Close is needed to pass digest as the stream fetcher destination.
+func (d *streamDigest) Close() error {
+	return nil
+}
+
+func (d *streamDigest) verification() *models.UploadVerification {
+	return &models.UploadVerification{Size: d.size, SHA256: hex.EncodeToString(d.hash.Sum(nil))}
+}
+
+func (d *streamDigest) checksum() *models.StreamChecksum {
+	return &models.StreamChecksum{Size: d.size, SHA256: hex.EncodeToString(d.hash.Sum(nil))}
+}
+
+// verifyBackupStream downloads and decompresses the uploaded backup stream without buffering it,
+// and checks that its size and hash match the stream being uploaded.
+func (su *StorageUploader) verifyBackupStream(backupName string, uploaded *streamDigest) (*models.UploadVerification, error) {
+	tracelog.InfoLogger.Printf("Verifying uploaded backup '%s'", backupName)
+	backup := internal.NewBackup(su.Folder(), backupName)
+	fetcher, err := internal.GetBackupStreamFetcherByExtension(backup, su.Compression().FileExtension())
+	if err != nil {
+		return nil, fmt.Errorf("can not fetch stream metadata of '%s': %w", backupName, err)
+	}
+
+	downloaded := newStreamDigest()
+	if err := fetcher(backup, downloaded); err != nil {
+		return nil, fmt.Errorf("can not read back backup '%s': %w", backupName, err)
+	}
+
+	expected, actual := uploaded.verification(), downloaded.verification()
+	if expected.Size != actual.Size {
+		return nil, fmt.Errorf("backup '%s' size mismatch: uploaded %d bytes, read back %d bytes",
+			backupName, expected.Size, actual.Size)
+	}
+	if expected.SHA256 != actual.SHA256 {
+		return nil, fmt.Errorf("backup '%s' sha256 mismatch: uploaded %s, read back %s",
+			backupName, expected.SHA256, actual.SHA256)
+	}
+	tracelog.InfoLogger.Printf("Backup '%s' is verified: %d bytes, sha256 %s", backupName, actual.Size, actual.SHA256)
+	return actual, nil
+}
@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func pushDigestedBackupStream(t *testing.T, su *StorageUploader, content string) (string, *streamDigest) {
+	digest := newStreamDigest()
+	backupName, err := su.PushStream(io.TeeReader(strings.NewReader(content), digest))
+	assert.NoError(t, err)
+	return backupName, digest
+}
+
+func TestStorageUploader_VerifyBackupStream(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+
+	content := strings.Repeat("backup data ", 1000)
+	backupName, digest := pushDigestedBackupStream(t, su, content)
+
+	verification, err := su.verifyBackupStream(backupName, digest)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), verification.Size)
+	assert.Equal(t, digest.verification().SHA256, verification.SHA256)
+}
+
+func TestStorageUploader_VerifyBackupStream_Mismatch(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	su := NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+
+	backupName, _ := pushDigestedBackupStream(t, su, "backup data")
+	sameSizeDigest, otherSizeDigest := newStreamDigest(), newStreamDigest()
+	_, _ = sameSizeDigest.Write([]byte("backup DATA"))
+	_, _ = otherSizeDigest.Write([]byte("backup"))
+
+	_, err := su.verifyBackupStream(backupName, sameSizeDigest)
+	assert.Error(t, err)
+	_, err = su.verifyBackupStream(backupName, otherSizeDigest)
+	assert.Error(t, err)
+}
+
+func TestStorageUploader_UploadBackup_RecordsCompressedStreamDigest(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	constructor := &testMetaConstructor{}
+	assert.NoError(t, NewStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)).
+		UploadBackup(strings.NewReader(strings.Repeat("backup data ", 1000)), testErrWaiter{}, constructor))
+
+	streamPath := internal.GetStreamName(constructor.backup.BackupName, lz4.FileExtension)
+	reader, err := folder.ReadObject(streamPath)
+	assert.NoError(t, err)
+	stored, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	storedDigest := sha256.Sum256(stored)
+	assert.Equal(t, map[string]string{streamPath: hex.EncodeToString(storedDigest[:])},
+		constructor.backup.CompressedSHA256)
+}
@@ -0,0 +1,30 @@
+package mongo
+
+import (
+	"io"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// HandleBackupCopy copies the backup with its oplog archives from one storage to another
+// and prints the report of the copied objects as JSON.
+func HandleBackupCopy(from, to storage.Folder, backupName string, opts archive.BackupCopyOptions, output io.Writer) error {
+	report, err := archive.CopyBackup(from, to, backupName, opts)
+	if err != nil {
+		return err
+	}
+	if err := internal.WriteAsJSON(report, output, true); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		tracelog.InfoLogger.Printf("Dry run: %d objects (%d bytes) of backup '%s' would be copied, %d are already present",
+			len(report.Copied), report.CopiedBytes, backupName, len(report.Skipped))
+		return nil
+	}
+	tracelog.InfoLogger.Printf("Copied %d objects (%d bytes) of backup '%s', %d were already present",
+		len(report.Copied), report.CopiedBytes, backupName, len(report.Skipped))
+	return nil
+}
@@ -1 +1,26 @@
 package mongo
+
+import (
+	"os/exec"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// BackupDataFetcher copies the backup stream to the restore command stdin, the stream is located
+// under the key prefix recorded in the sentinel if the backup was uploaded with the key template,
+// and decompressed by the compression method recorded in the sentinel if any.
+func BackupDataFetcher(restoreCmd *exec.Cmd) func(folder storage.Folder, backup internal.Backup) {
+	return func(folder storage.Folder, backup internal.Backup) {
+		var sentinel models.Backup
+		err := backup.FetchSentinel(&sentinel)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup sentinel: %v\n", err)
+		fetcher := internal.GetBackupToCommandFetcherBy(restoreCmd, func(backup internal.Backup) (internal.StreamFetcher, error) {
+			return archive.BackupStreamFetcher(backup, &sentinel)
+		})
+		fetcher(folder, internal.NewBackup(archive.BackupDataFolder(folder, &sentinel), backup.Name))
+	}
+}
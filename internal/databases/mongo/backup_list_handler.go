@@ -33,3 +33,18 @@ func HandleBackupsList(downloader archive.Downloader,
 
 	return listing.Backups(backups, output)
 }
+
+// HandleBackupsSummary prints the durations and the sizes of the backups from the last finished one.
+func HandleBackupsSummary(downloader archive.Downloader, output io.Writer) error {
+	summaries, err := archive.ListBackupSummaries(downloader)
+	if err != nil {
+		return err
+	}
+
+	if len(summaries) == 0 {
+		tracelog.InfoLogger.Println("No backups found")
+		return nil
+	}
+
+	return archive.WriteBackupSummaries(summaries, output)
+}
@@ -0,0 +1,49 @@
+package mongo
+
+import (
+	"io"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// HandleBackupRecompress recompresses the stream of the backup, or of all the backups if the name is empty,
+// by the compressor and prints the reports of the recompressed objects as JSON.
+// The backups are recompressed one by one, the first failure stops the batch: the reports of the backups
+// recompressed before it are printed and the failure is returned.
+func HandleBackupRecompress(rootFolder storage.Folder, backupName string, compressor compression.Compressor,
+	dryRun bool, output io.Writer) error {
+	backupNames := []string{backupName}
+	if backupName == "" {
+		backups, err := internal.GetBackups(rootFolder.GetSubFolder(utility.BaseBackupPath))
+		if err != nil {
+			return err
+		}
+		backupNames = make([]string, 0, len(backups))
+		for _, backup := range backups {
+			backupNames = append(backupNames, backup.BackupName)
+		}
+	}
+
+	reports := make([]archive.BackupRecompressReport, 0, len(backupNames))
+	for _, name := range backupNames {
+		report, err := archive.RecompressBackup(rootFolder, name, compressor, dryRun)
+		if err != nil {
+			if writeErr := internal.WriteAsJSON(reports, output, true); writeErr != nil {
+				tracelog.ErrorLogger.Printf("Failed to print the recompression reports: %v", writeErr)
+			}
+			return err
+		}
+		reports = append(reports, report)
+		if dryRun {
+			tracelog.InfoLogger.Printf("Dry run: %d objects of backup '%s' would be recompressed", len(report.Objects), name)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Recompressed %d objects of backup '%s'", len(report.Objects), name)
+	}
+	return internal.WriteAsJSON(reports, output, true)
+}
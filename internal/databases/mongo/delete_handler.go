@@ -19,6 +19,11 @@ type PurgeSettings struct {
 
 type PurgeOption func(*PurgeSettings)
 
+// listCacheInvalidator is implemented by the downloaders caching the storage listings
+type listCacheInvalidator interface {
+	InvalidateListCache()
+}
+
 // PurgeRetainAfter ...
 func PurgeRetainAfter(retainAfter time.Time) PurgeOption {
 	return func(args *PurgeSettings) {
@@ -66,14 +71,23 @@ func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters .
 		return err
 	}
 
-	_, _, err = HandleBackupsPurge(backupTimes, downloader, purger, opts)
+	_, _, pitrBase, err := HandleBackupsPurge(backupTimes, downloader, purger, opts)
 	if err != nil {
 		return err
 	}
+	if invalidator, ok := downloader.(listCacheInvalidator); ok && !opts.dryRun {
+		// the backups are deleted by the purger bypassing the cached listings of the downloader
+		invalidator.InvalidateListCache()
+	}
 
 	if opts.purgeOplog {
+		oplogRetainAfter := opts.retainAfter
+		if pitrBase != nil {
+			// oplog archives are required to restore from the base backup
+			oplogRetainAfter = &pitrBase.FinishLocalTime
+		}
 		// TODO: fix error if retainBackups is empty
-		if err := HandleOplogPurge(downloader, purger, opts.retainAfter, opts.dryRun); err != nil {
+		if err := HandleOplogPurge(downloader, purger, oplogRetainAfter, opts.dryRun); err != nil {
 			return err
 		}
 	}
@@ -90,39 +104,42 @@ func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters .
 	return nil
 }
 
-// HandleBackupsPurge delete backups according to settings
+// HandleBackupsPurge delete backups according to settings, backups are ordered by the finish time.
+// The newest backup finished before the retain time is kept and returned as pitrBase
+// if oplog archives allow to restore from it to the points after the retain time.
 func HandleBackupsPurge(backupTimes []internal.BackupTime,
 	downloader archive.Downloader,
 	purger archive.Purger,
-	opts PurgeSettings) (purge, retain []models.Backup, err error) {
+	opts PurgeSettings) (purge, retain []models.Backup, pitrBase *models.Backup, err error) {
 	if len(backupTimes) == 0 { // TODO: refactor && support oplog purge even if backups do not exist
 		tracelog.InfoLogger.Println("No backups found")
-		return []models.Backup{}, []models.Backup{}, nil
+		return []models.Backup{}, []models.Backup{}, nil, nil
 	}
 
 	backups, err := downloader.LoadBackups(archive.BackupNamesFromBackupTimes(backupTimes))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	timedBackups := archive.MongoModelToTimedBackup(backups)
-
-	internal.SortTimedBackup(timedBackups)
-	purgeBackups, retainBackups, err := internal.SplitPurgingBackups(timedBackups, opts.retainCount, opts.retainAfter)
-
-	if err != nil {
-		return nil, nil, err
+	var archives []models.Archive
+	if opts.retainAfter != nil {
+		if archives, err = downloader.ListOplogArchives(); err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
-	purge, retain = archive.SplitMongoBackups(backups, purgeBackups, retainBackups)
+	purge, retain, pitrBase = archive.SplitPurgingBackupsByFinishTime(backups, archives, opts.retainCount, opts.retainAfter)
 	tracelog.InfoLogger.Printf("Backups selected to be deleted: %v", archive.BackupNamesFromBackups(purge))
 	tracelog.InfoLogger.Printf("Backups selected to be retained: %v", archive.BackupNamesFromBackups(retain))
+	if pitrBase != nil {
+		tracelog.InfoLogger.Printf("Backup %s is retained as a base for point-in-time recovery", pitrBase.BackupName)
+	}
 
 	if !opts.dryRun {
 		if err := purger.DeleteBackups(purge); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		tracelog.InfoLogger.Printf("Backups were purged: deleted: %d, retained: %v", len(purge), len(retain))
 	}
-	return purge, retain, nil
+	return purge, retain, pitrBase, nil
 }
@@ -0,0 +1,40 @@
+package mongo
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+)
+
+// IntegrityScanner checks the stored objects against their checksums
+type IntegrityScanner interface {
+	ScanIntegrity(opts archive.IntegrityScanOptions) ([]archive.IntegrityCheck, error)
+}
+
+// HandleIntegrityScan prints the checks of the sampled backups and oplog archives as JSON,
+// the error is returned if any of the checked objects is corrupted.
+func HandleIntegrityScan(scanner IntegrityScanner, opts archive.IntegrityScanOptions, output io.Writer) error {
+	checks, err := scanner.ScanIntegrity(opts)
+	if err != nil {
+		return err
+	}
+	if err := internal.WriteAsJSON(checks, output, true); err != nil {
+		return err
+	}
+
+	corrupted := 0
+	for _, check := range checks {
+		if check.IsCorrupted() {
+			tracelog.ErrorLogger.Printf("%s '%s' is corrupted: %s %s", check.Type, check.ObjectName, check.Status, check.Error)
+			corrupted++
+		}
+	}
+	if corrupted > 0 {
+		return fmt.Errorf("%d of %d checked objects are corrupted", corrupted, len(checks))
+	}
+	tracelog.InfoLogger.Printf("%d checked objects are not corrupted", len(checks))
+	return nil
+}
@@ -3,7 +3,9 @@ package models
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
+	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -55,6 +57,41 @@ func (a Archive) Extension() string {
 	return a.Ext
 }
 
+// Archive compression algorithms which are not the names of the compressors.
+const (
+	// ArchiveAlgorithmDeduplicated is the archive stored as the manifest of the chunks, which records their compression
+	ArchiveAlgorithmDeduplicated = "deduplicated"
+	// ArchiveAlgorithmUnknown is the archive extension naming no compressor of this build, e.g. the legacy one
+	ArchiveAlgorithmUnknown = "unknown"
+)
+
+// Algorithm returns the compression algorithm named by the archive extension, the archive is not read.
+func (a Archive) Algorithm() string {
+	if a.IsManifest() {
+		return ArchiveAlgorithmDeduplicated
+	}
+	if algorithm := compression.AlgorithmByExtension(a.Ext); algorithm != "" {
+		return algorithm
+	}
+	return ArchiveAlgorithmUnknown
+}
+
+// Gap describes the break in the oplog archives chain
+type Gap struct {
+	Start Timestamp
+	End   Timestamp
+	// Overlap is true if the interval is covered by several archives, otherwise oplog in the interval is missing
+	Overlap bool
+}
+
+// String returns text representation of Gap
+func (g Gap) String() string {
+	if g.Overlap {
+		return fmt.Sprintf("overlap between %s and %s", g.Start, g.End)
+	}
+	return fmt.Sprintf("missing oplog between %s and %s", g.Start, g.End)
+}
+
 // ArchFromFilename builds Arch from given path.
 // TODO: support empty extension
 func ArchFromFilename(path string) (Archive, error) {
@@ -73,3 +110,58 @@ func ArchFromFilename(path string) (Archive, error) {
 	}
 	return NewArchive(startTS, endTS, ext, archiveType)
 }
+
+// Deduplicated oplog archive constants.
+const (
+	ArchiveManifestExt = "manifest"
+	OplogChunksPath    = "chunks/"
+)
+
+// ManifestExt returns the extension of the manifest compressed and encrypted as the chunks,
+// e.g. manifest.lz4 for the lz4 chunks
+func ManifestExt(compressionExt string) string {
+	return ArchiveManifestExt + "." + compressionExt
+}
+
+// IsManifest returns if the archive is stored as the manifest of the chunks
+func (a Archive) IsManifest() bool {
+	return strings.HasPrefix(a.Ext, ArchiveManifestExt+".")
+}
+
+// ManifestCompression returns the extension of the manifest compression, e.g. lz4 for manifest.lz4
+func (a Archive) ManifestCompression() string {
+	return strings.TrimPrefix(a.Ext, ArchiveManifestExt+".")
+}
+
+// OplogChecksumsPath is the folder of the oplog archive checksums uploaded along with the archives.
+const OplogChecksumsPath = "checksums/"
+
+// StreamChecksum describes the decompressed stream stored in the backup or oplog archive.
+type StreamChecksum struct {
+	Size   int64  `json:"Size"`
+	SHA256 string `json:"SHA256"`
+}
+
+// ArchiveChecksum is the checksum uploaded along with the oplog archive
+type ArchiveChecksum struct {
+	StreamChecksum
+	// Compression is not recorded for the older archives and the deduplicated ones, which keep it in the manifest
+	Compression *CompressionMethod `json:"Compression,omitempty"`
+}
+
+// ChecksumFilename builds the name of the archive checksum object in the checksums folder.
+func (a Archive) ChecksumFilename() string {
+	return a.Filename() + ".json"
+}
+
+// ArchiveChunk references the content-addressed chunk of deduplicated oplog archive.
+type ArchiveChunk struct {
+	Hash string `json:"hash"`
+	Size int    `json:"size"`
+}
+
+// ArchiveManifest lists the chunks deduplicated oplog archive is reassembled from.
+type ArchiveManifest struct {
+	Compression string         `json:"compression"`
+	Chunks      []ArchiveChunk `json:"chunks"`
+}
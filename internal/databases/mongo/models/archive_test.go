@@ -291,3 +291,28 @@ func TestArchFromFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestArchive_Algorithm(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want string
+	}{
+		{ext: "lz4", want: "lz4"},
+		{ext: "lzma", want: "lzma"},
+		{ext: ManifestExt("lz4"), want: ArchiveAlgorithmDeduplicated},
+		{ext: "lzo", want: ArchiveAlgorithmUnknown},
+		// the manifests are always compressed, so the bare extension names no manifest
+		{ext: ArchiveManifestExt, want: ArchiveAlgorithmUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			arch, err := ArchFromFilename("oplog_1579541143.39_1579541443.33." + tt.ext)
+			if err != nil {
+				t.Fatalf("ArchFromFilename() error = %v", err)
+			}
+			if got := arch.Algorithm(); got != tt.want {
+				t.Errorf("Algorithm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
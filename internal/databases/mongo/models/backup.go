@@ -4,13 +4,42 @@ import "time"
 
 // Backup represents backup sentinel data
 type Backup struct {
-	BackupName      string      `json:"BackupName,omitempty"`
-	StartLocalTime  time.Time   `json:"StartLocalTime,omitempty"`
-	FinishLocalTime time.Time   `json:"FinishLocalTime,omitempty"`
-	UserData        interface{} `json:"UserData,omitempty"`
-	MongoMeta       MongoMeta   `json:"MongoMeta,omitempty"`
-	Permanent       bool        `json:"Permanent"`
-	DataSize        int64       `json:"DataSize,omitempty"`
+	BackupName      string              `json:"BackupName,omitempty"`
+	StartLocalTime  time.Time           `json:"StartLocalTime,omitempty"`
+	FinishLocalTime time.Time           `json:"FinishLocalTime,omitempty"`
+	UserData        interface{}         `json:"UserData,omitempty"`
+	Labels          map[string]string   `json:"Labels,omitempty"`
+	MongoMeta       MongoMeta           `json:"MongoMeta,omitempty"`
+	Permanent       bool                `json:"Permanent"`
+	DataSize        int64               `json:"DataSize,omitempty"`
+	Verification    *UploadVerification `json:"Verification,omitempty"`
+	// the sizes of the backup stream are not recorded in the sentinels of the older backups
+	UncompressedSize int64 `json:"UncompressedSize,omitempty"`
+	CompressedSize   int64 `json:"CompressedSize,omitempty"`
+	// Checksum is not recorded in the sentinels of the older backups
+	Checksum *StreamChecksum `json:"Checksum,omitempty"`
+	// CompressedSHA256 are the hex-encoded digests of the stored (compressed) stream objects by their paths
+	CompressedSHA256 map[string]string `json:"CompressedSHA256,omitempty"`
+	// KeyPrefix is expanded from KeyTemplate on upload, the backup stream is stored under it if set
+	KeyTemplate string `json:"KeyTemplate,omitempty"`
+	KeyPrefix   string `json:"KeyPrefix,omitempty"`
+	// Compression is not recorded in the sentinels of the older backups, their stream is found by the extension
+	Compression *CompressionMethod `json:"Compression,omitempty"`
+}
+
+// CompressionMethod is the compression the stream is uploaded with
+type CompressionMethod struct {
+	Algorithm string `json:"Algorithm,omitempty"`
+	// Level is not recorded if the default level of the algorithm is used
+	Level *int `json:"Level,omitempty"`
+	// Extension selects the decompressor of the stream on download
+	Extension string `json:"Extension"`
+}
+
+// UploadVerification represents the result of backup stream read-back after upload
+type UploadVerification struct {
+	Size   int64  `json:"Size"`
+	SHA256 string `json:"SHA256"`
 }
 
 func (b Backup) Name() string {
@@ -43,6 +72,7 @@ type BackupMeta struct {
 	DataSize   int64
 	Permanent  bool
 	User       interface{}
+	Labels     map[string]string
 	StartTime  time.Time
 	FinishTime time.Time
 }
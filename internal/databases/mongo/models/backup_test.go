@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/magiconair/properties/assert"
@@ -316,3 +317,17 @@ func TestFirstOverlappingBackupForArch(t *testing.T) {
 		})
 	}
 }
+
+func TestBackupLabelsBackwardCompatibility(t *testing.T) {
+	var backup Backup
+	err := json.Unmarshal([]byte(`{"BackupName":"stream_20201027T224823Z","Permanent":false}`), &backup)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(backup.Labels), 0)
+
+	backup.Labels = map[string]string{"region": "eu-west"}
+	raw, err := json.Marshal(backup)
+	assert.Equal(t, err, nil)
+	var restored Backup
+	assert.Equal(t, json.Unmarshal(raw, &restored), nil)
+	assert.Equal(t, restored.Labels, backup.Labels)
+}
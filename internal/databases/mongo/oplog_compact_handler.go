@@ -0,0 +1,82 @@
+package mongo
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+// OplogCompactDownloader fetches oplog archives to be compacted
+type OplogCompactDownloader interface {
+	DownloadOplogArchive(arch models.Archive, from *models.Timestamp, writeCloser io.WriteCloser) error
+	ListOplogArchiveSizes() (map[models.Archive]int64, error)
+}
+
+// HandleOplogCompact merges the runs of consecutive oplog archives smaller than targetSize into the single archives.
+// The merged archive is uploaded before the original ones are deleted, so the oplog remains available on failures.
+func HandleOplogCompact(downloader OplogCompactDownloader, uploader archive.Uploader, purger archive.Purger,
+	targetSize int64, dryRun bool) error {
+	sizes, err := downloader.ListOplogArchiveSizes()
+	if err != nil {
+		return fmt.Errorf("can not load oplog archives: %+v", err)
+	}
+
+	runs := archive.SelectCompactingOplogArchives(sizes, targetSize)
+	compacted := 0
+	for _, run := range runs {
+		tracelog.DebugLogger.Printf("Oplog archives selected to be merged into [%s, %s]: %v",
+			run[0].Start, run[len(run)-1].End, run)
+		if dryRun {
+			continue
+		}
+		if err := compactOplogArchives(downloader, uploader, purger, run); err != nil {
+			return err
+		}
+		compacted += len(run)
+	}
+	if !dryRun {
+		tracelog.InfoLogger.Printf("Oplog archives were compacted: %d into %d", compacted, len(runs))
+	}
+	return nil
+}
+
+// compactOplogArchives uploads the concatenated oplog of the archives chain and deletes the original archives
+func compactOplogArchives(downloader OplogCompactDownloader, uploader archive.Uploader, purger archive.Purger,
+	archives []models.Archive) error {
+	if err := archive.VerifyOplogChain(archives); err != nil {
+		return fmt.Errorf("can not compact oplog archives: %w", err)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		for _, arch := range archives {
+			if err := downloader.DownloadOplogArchive(arch, nil, nopWriteCloser{writer}); err != nil {
+				_ = writer.CloseWithError(fmt.Errorf("can not download oplog archive '%s': %w", arch.Filename(), err))
+				return
+			}
+		}
+		_ = writer.Close()
+	}()
+
+	firstTS, lastTS := archives[0].Start, archives[len(archives)-1].End
+	if err := uploader.UploadOplogArchive(reader, firstTS, lastTS); err != nil {
+		_ = reader.CloseWithError(err)
+		return fmt.Errorf("can not upload merged oplog archive [%s, %s]: %w", firstTS, lastTS, err)
+	}
+	if err := purger.DeleteOplogArchives(archives); err != nil {
+		return fmt.Errorf("can not delete compacted oplog archives: %w", err)
+	}
+	return nil
+}
+
+// nopWriteCloser keeps the pipe open when the downloader closes the writer after each archive
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,112 @@
+package mongo
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	mocks "github.com/wal-g/wal-g/internal/databases/mongo/archive/mocks"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+type compactTestDownloader struct {
+	contents map[models.Archive]string
+	failed   map[models.Archive]bool
+}
+
+func (d compactTestDownloader) DownloadOplogArchive(arch models.Archive, from *models.Timestamp,
+	writeCloser io.WriteCloser) error {
+	defer func() { _ = writeCloser.Close() }()
+	if d.failed[arch] {
+		return fmt.Errorf("download failed")
+	}
+	_, err := io.WriteString(writeCloser, d.contents[arch])
+	return err
+}
+
+func (d compactTestDownloader) ListOplogArchiveSizes() (map[models.Archive]int64, error) {
+	sizes := make(map[models.Archive]int64, len(d.contents))
+	for arch, content := range d.contents {
+		sizes[arch] = int64(len(content))
+	}
+	return sizes, nil
+}
+
+var compactArchives = []models.Archive{
+	{Start: models.Timestamp{TS: 100}, End: models.Timestamp{TS: 200}, Ext: "br", Type: models.ArchiveTypeOplog},
+	{Start: models.Timestamp{TS: 200}, End: models.Timestamp{TS: 300}, Ext: "br", Type: models.ArchiveTypeOplog},
+	{Start: models.Timestamp{TS: 300}, End: models.Timestamp{TS: 400}, Ext: "br", Type: models.ArchiveTypeOplog},
+	{Start: models.Timestamp{TS: 500}, End: models.Timestamp{TS: 600}, Ext: "br", Type: models.ArchiveTypeOplog},
+	{Start: models.Timestamp{TS: 600}, End: models.Timestamp{TS: 700}, Ext: "br", Type: models.ArchiveTypeOplog},
+}
+
+func newCompactTestDownloader() compactTestDownloader {
+	contents := make(map[models.Archive]string, len(compactArchives))
+	for i, arch := range compactArchives {
+		contents[arch] = fmt.Sprintf("oplog%d;", i)
+	}
+	return compactTestDownloader{contents: contents, failed: map[models.Archive]bool{}}
+}
+
+func expectMergedUpload(uploader *mocks.Uploader, firstTS, lastTS models.Timestamp, expected string) {
+	uploader.On("UploadOplogArchive", mock.Anything, firstTS, lastTS).
+		Run(func(args mock.Arguments) {
+			data, err := io.ReadAll(args.Get(0).(io.Reader))
+			if err != nil || string(data) != expected {
+				panic(fmt.Sprintf("unexpected merged archive content %q: %v", data, err))
+			}
+		}).Return(nil).Once()
+}
+
+func TestHandleOplogCompact(t *testing.T) {
+	downloader := newCompactTestDownloader()
+	uploader := &mocks.Uploader{}
+	expectMergedUpload(uploader, models.Timestamp{TS: 100}, models.Timestamp{TS: 400}, "oplog0;oplog1;oplog2;")
+	expectMergedUpload(uploader, models.Timestamp{TS: 500}, models.Timestamp{TS: 700}, "oplog3;oplog4;")
+	purger := &mocks.Purger{}
+	purger.On("DeleteOplogArchives", compactArchives[0:3]).Return(nil).Once().
+		On("DeleteOplogArchives", compactArchives[3:5]).Return(nil).Once()
+
+	err := HandleOplogCompact(downloader, uploader, purger, 100, false)
+	assert.NoError(t, err)
+	uploader.AssertExpectations(t)
+	purger.AssertExpectations(t)
+}
+
+func TestHandleOplogCompact_DryRun(t *testing.T) {
+	uploader := &mocks.Uploader{}
+	purger := &mocks.Purger{}
+
+	err := HandleOplogCompact(newCompactTestDownloader(), uploader, purger, 100, true)
+	assert.NoError(t, err)
+	uploader.AssertNotCalled(t, "UploadOplogArchive", mock.Anything, mock.Anything, mock.Anything)
+	purger.AssertNotCalled(t, "DeleteOplogArchives", mock.Anything)
+}
+
+func TestHandleOplogCompact_KeepsOriginalsOnFailure(t *testing.T) {
+	downloader := newCompactTestDownloader()
+	downloader.failed[compactArchives[1]] = true
+	uploader := &mocks.Uploader{}
+	uploader.On("UploadOplogArchive", mock.Anything, models.Timestamp{TS: 100}, models.Timestamp{TS: 400}).
+		Return(func(stream io.Reader, firstTS, lastTS models.Timestamp) error {
+			_, err := io.ReadAll(stream)
+			return err
+		}).Once()
+	purger := &mocks.Purger{}
+
+	err := HandleOplogCompact(downloader, uploader, purger, 100, false)
+	assert.Error(t, err)
+	purger.AssertNotCalled(t, "DeleteOplogArchives", mock.Anything)
+}
+
+func TestCompactOplogArchives_RefusesGap(t *testing.T) {
+	uploader := &mocks.Uploader{}
+	purger := &mocks.Purger{}
+
+	err := compactOplogArchives(newCompactTestDownloader(), uploader, purger, compactArchives[2:4])
+	assert.Error(t, err)
+	uploader.AssertNotCalled(t, "UploadOplogArchive", mock.Anything, mock.Anything, mock.Anything)
+	purger.AssertNotCalled(t, "DeleteOplogArchives", mock.Anything)
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+)
+
+// OplogListDownloader lists the oplog archives along with their compression algorithms and sizes
+type OplogListDownloader interface {
+	ListOplogArchivesWithMeta() ([]archive.OplogArchiveMeta, error)
+}
+
+// HandleOplogList prints the oplog archives with their compression algorithms and stored sizes
+// as JSON or as the table, the archives are not downloaded.
+func HandleOplogList(downloader OplogListDownloader, output io.Writer, asJSON bool) error {
+	metas, err := downloader.ListOplogArchivesWithMeta()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return internal.WriteAsJSON(metas, output, true)
+	}
+
+	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	if _, err = fmt.Fprintln(writer, "name\talgorithm\tsize"); err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		if _, err = fmt.Fprintf(writer, "%s\t%s\t%d\n", meta.Archive.Filename(), meta.Algorithm, meta.Size); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
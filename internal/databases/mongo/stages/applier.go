@@ -1,8 +1,10 @@
 package stages
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/wal-g/tracelog"
@@ -58,6 +60,8 @@ type StorageApplier struct {
 	size         int
 	timeout      time.Duration
 	statsUpdater stats.OplogUploadStatsUpdater
+	// up to concurrency archives cut by size are uploaded at once
+	concurrency int
 }
 
 // NewStorageApplier builds StorageApplier.
@@ -67,7 +71,20 @@ func NewStorageApplier(uploader archive.Uploader,
 	archiveAfterSize int,
 	archiveTimeout time.Duration,
 	statsUpdater stats.OplogUploadStatsUpdater) *StorageApplier {
-	return &StorageApplier{uploader, buf, archiveAfterSize, archiveTimeout, statsUpdater}
+	return &StorageApplier{uploader, buf, archiveAfterSize, archiveTimeout, statsUpdater, 1}
+}
+
+// SetUploadConcurrency makes archives cut by size to be held until concurrency of them are collected
+// and uploaded at once, the timeout and the end of oplog upload the collected archives as well.
+func (sa *StorageApplier) SetUploadConcurrency(concurrency int) {
+	sa.concurrency = concurrency
+}
+
+// pendingOplogArchive is the archive cut from the buffer and waiting for upload
+type pendingOplogArchive struct {
+	archive.OplogArchiveUpload
+	docs int
+	size int
 }
 
 // Apply runs working cycle that sends oplog records to storage.
@@ -77,11 +94,13 @@ func (sa *StorageApplier) Apply(ctx context.Context, oplogc chan *models.Oplog)
 	restartBatch := true
 	batchDocs := 0
 	batchSize := 0
+	var pending []pendingOplogArchive
 	errc := make(chan error)
 	go func() {
 		defer close(errc)
 		defer archiveTimer.Stop()
 		for oplogc != nil {
+			sizeExceeded := false
 			select {
 			case op, ok := <-oplogc:
 				if !ok {
@@ -102,6 +121,7 @@ func (sa *StorageApplier) Apply(ctx context.Context, oplogc chan *models.Oplog)
 				if sa.buf.Len() < sa.size {
 					continue
 				}
+				sizeExceeded = true
 				tracelog.DebugLogger.Println("Initializing archive upload due to archive size")
 
 			case <-archiveTimer.C:
@@ -110,35 +130,73 @@ func (sa *StorageApplier) Apply(ctx context.Context, oplogc chan *models.Oplog)
 
 			utility.ResetTimer(archiveTimer, sa.timeout)
 			batchSize = sa.buf.Len()
-			if batchSize == 0 {
+			if batchSize == 0 && len(pending) == 0 {
 				continue
 			}
 
-			bufReader, err := sa.buf.Reader()
-			if err != nil {
-				errc <- fmt.Errorf("can not get reader from buffer: %w", err)
-				return
+			if batchSize > 0 {
+				bufReader, err := sa.buf.Reader()
+				if err != nil {
+					errc <- fmt.Errorf("can not get reader from buffer: %w", err)
+					return
+				}
+				arch := pendingOplogArchive{archive.OplogArchiveUpload{Stream: bufReader, FirstTS: batchStartTS,
+					LastTS: lastKnownTS}, batchDocs, batchSize}
+				if sa.concurrency > 1 {
+					// the buffer is reused by the next archive while this one waits for upload
+					data, err := io.ReadAll(bufReader)
+					if err != nil {
+						errc <- fmt.Errorf("can not read archive from buffer: %w", err)
+						return
+					}
+					arch.Stream = bytes.NewReader(data)
+				}
+				pending = append(pending, arch)
+				batchDocs = 0
+				batchStartTS = lastKnownTS
 			}
 
-			// TODO: move upload to the next stage, batch accumulation should not be blocked by upload
-			// or switch to PushStreamToDestination (async api):
-			// we don't know archive name beforehand, so upload stream and rename key (it leads to failures and require gc)
-			// but consumes less memory
-			if err := sa.uploader.UploadOplogArchive(bufReader, batchStartTS, lastKnownTS); err != nil {
-				errc <- fmt.Errorf("can not upload oplog archive: %w", err)
-				return
-			}
-			if sa.statsUpdater != nil {
-				sa.statsUpdater.Update(batchDocs, batchSize, lastKnownTS)
+			if !sizeExceeded || len(pending) >= sa.concurrency {
+				// TODO: move upload to the next stage, batch accumulation should not be blocked by upload
+				// or switch to PushStreamToDestination (async api):
+				// we don't know archive name beforehand, so upload stream and rename key (it leads to failures and require gc)
+				// but consumes less memory
+				if err := sa.uploadArchives(pending); err != nil {
+					errc <- err
+					return
+				}
+				pending = nil
 			}
-			batchDocs = 0
 			if err := sa.buf.Reset(); err != nil {
 				errc <- fmt.Errorf("can not reset buffer for reuse: %w", err)
 				return
 			}
-			batchStartTS = lastKnownTS
 		}
 	}()
 
 	return errc, nil
 }
+
+// uploadArchives uploads the archives, the stats are updated once all of them are uploaded
+func (sa *StorageApplier) uploadArchives(archives []pendingOplogArchive) error {
+	if len(archives) == 1 {
+		arch := archives[0]
+		if err := sa.uploader.UploadOplogArchive(arch.Stream, arch.FirstTS, arch.LastTS); err != nil {
+			return fmt.Errorf("can not upload oplog archive: %w", err)
+		}
+	} else {
+		uploads := make([]archive.OplogArchiveUpload, len(archives))
+		for i, arch := range archives {
+			uploads[i] = arch.OplogArchiveUpload
+		}
+		if _, err := sa.uploader.UploadOplogArchives(uploads, sa.concurrency); err != nil {
+			return fmt.Errorf("can not upload oplog archives: %w", err)
+		}
+	}
+	if sa.statsUpdater != nil {
+		for _, arch := range archives {
+			sa.statsUpdater.Update(arch.docs, arch.size, arch.LastTS)
+		}
+	}
+	return nil
+}
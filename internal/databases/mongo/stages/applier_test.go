@@ -1,12 +1,14 @@
 package stages
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 	archiveMocks "github.com/wal-g/wal-g/internal/databases/mongo/archive/mocks"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
 )
@@ -185,3 +187,30 @@ func TestStorageApplier_Apply(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageApplier_Apply_UploadConcurrency(t *testing.T) {
+	upl := archiveMocks.Uploader{}
+	ts1 := models.Timestamp{TS: 1579002001, Inc: 1}
+	ts2 := models.Timestamp{TS: 1579002002, Inc: 1}
+	ts3 := models.Timestamp{TS: 1579002003, Inc: 1}
+	upl.On("UploadOplogArchives", []archive.OplogArchiveUpload{
+		{Stream: bytes.NewReader(make([]byte, 17)), FirstTS: ts1, LastTS: ts1},
+		{Stream: bytes.NewReader(make([]byte, 17)), FirstTS: ts1, LastTS: ts2},
+	}, 2).Return([]error{nil, nil}, nil).Once()
+	upl.On("UploadOplogArchive", bytes.NewReader(make([]byte, 17)), ts2, ts3).Return(nil).Once()
+
+	sa := NewStorageApplier(&upl, NewMemoryBuffer(), 16, time.Hour, nil)
+	sa.SetUploadConcurrency(2)
+	oplogc := make(chan *models.Oplog)
+	errc, err := sa.Apply(context.TODO(), oplogc)
+	assert.Nil(t, err)
+
+	// each document exceeds the archive size, so the archives are cut by size and uploaded in pairs
+	for _, ts := range []models.Timestamp{ts1, ts2, ts3} {
+		oplogc <- &models.Oplog{TS: ts, Data: make([]byte, 17)}
+	}
+	close(oplogc)
+
+	assert.Nil(t, <-errc)
+	upl.AssertExpectations(t)
+}
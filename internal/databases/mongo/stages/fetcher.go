@@ -11,6 +11,7 @@ import (
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 	"github.com/wal-g/wal-g/internal/databases/mongo/client"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/utility"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -163,57 +164,27 @@ func (sf *StorageFetcher) FetchBetween(ctx context.Context,
 		defer close(errc)
 		defer close(data)
 
-		buf := NewCloserBuffer() // TODO: switch to streaming interface
 		path := sf.path
 		firstFound := false
 
 		for _, arch := range path {
 			tracelog.DebugLogger.Printf("Fetching archive %s", arch.Filename())
 
-			err := sf.downloader.DownloadOplogArchive(arch, buf)
+			reader, err := sf.openArchive(arch, from, firstFound)
 			if err != nil {
 				errc <- fmt.Errorf("failed to download archive %s: %w", arch.Filename(), err)
 				return
 			}
-
-			for {
-				// TODO: benchmark & compare with bson_stream
-				raw, err := bson.NewFromIOReader(buf)
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					errc <- fmt.Errorf("error during read bson: %w", err)
-				}
-
-				op, err := models.OplogFromRaw(raw)
-				if err != nil {
-					errc <- fmt.Errorf("oplog record decoding failed: %w", err)
-					return
-				}
-
-				if !firstFound {
-					if op.TS != from { // from ts is not reached, continue
-						continue
-					}
-					firstFound = true
-				}
-
-				// TODO: do we need also check every op "op.TS > from"
-				if models.LessTS(until, op.TS) || op.TS == until {
-					tracelog.InfoLogger.Println("Oplog archives fetching is completed")
-					return
-				}
-
-				// tracelog.DebugLogger.Printf("Fetcher receieved op %s (%s on %s)", op.TS, op.OP, op.NS)
-				select {
-				case data <- op:
-				case <-ctx.Done():
-					tracelog.InfoLogger.Println("Oplog archives fetching is canceled")
-					return
-				}
+			var done bool
+			firstFound, done, err = fetchArchiveOplog(ctx, reader, data, from, until, firstFound)
+			utility.LoggedClose(reader, "")
+			if err != nil {
+				errc <- fmt.Errorf("failed to fetch archive %s: %w", arch.Filename(), err)
+				return
+			}
+			if done {
+				return
 			}
-			buf.Reset()
 			if !firstFound { // TODO: do we need this check, add skip flag
 				errc <- fmt.Errorf("'from' timestamp '%s' was not found in first archive: %s", from, arch.Filename())
 				return
@@ -225,3 +196,71 @@ func (sf *StorageFetcher) FetchBetween(ctx context.Context,
 
 	return data, errc, nil
 }
+
+// openArchive opens the archive to be fetched. Until the from ts is found, the records before it are skipped
+// while the archive is downloaded, so they are not decoded by the fetcher.
+func (sf *StorageFetcher) openArchive(arch models.Archive, from models.Timestamp, firstFound bool) (io.ReadCloser, error) {
+	if firstFound {
+		return sf.downloader.OplogArchiveReader(arch)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(sf.downloader.DownloadOplogArchive(arch, &from, pipeWriteCloser{writer}))
+	}()
+	return reader, nil
+}
+
+// pipeWriteCloser keeps the pipe open when the downloader closes the writer, so the pipe is closed
+// with the download error
+type pipeWriteCloser struct {
+	*io.PipeWriter
+}
+
+func (pipeWriteCloser) Close() error {
+	return nil
+}
+
+// fetchArchiveOplog decodes oplog records as the archive is streamed and sends the ones since from ts to data channel.
+// Returns if the from ts is found and if fetching is done: the until ts is reached or fetching is canceled.
+func fetchArchiveOplog(ctx context.Context,
+	reader io.Reader,
+	data chan *models.Oplog,
+	from, until models.Timestamp,
+	firstFound bool) (bool, bool, error) {
+	for {
+		// TODO: benchmark & compare with bson_stream
+		raw, err := bson.NewFromIOReader(reader)
+		if err != nil {
+			if err == io.EOF {
+				return firstFound, false, nil
+			}
+			return firstFound, false, fmt.Errorf("error during read bson: %w", err)
+		}
+
+		op, err := models.OplogFromRaw(raw)
+		if err != nil {
+			return firstFound, false, fmt.Errorf("oplog record decoding failed: %w", err)
+		}
+
+		if !firstFound {
+			if op.TS != from { // from ts is not reached, continue
+				continue
+			}
+			firstFound = true
+		}
+
+		// TODO: do we need also check every op "op.TS > from"
+		if models.LessTS(until, op.TS) || op.TS == until {
+			tracelog.InfoLogger.Println("Oplog archives fetching is completed")
+			return firstFound, true, nil
+		}
+
+		// tracelog.DebugLogger.Printf("Fetcher receieved op %s (%s on %s)", op.TS, op.OP, op.NS)
+		select {
+		case data <- op:
+		case <-ctx.Done():
+			tracelog.InfoLogger.Println("Oplog archives fetching is canceled")
+			return firstFound, true, nil
+		}
+	}
+}
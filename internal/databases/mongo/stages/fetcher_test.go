@@ -86,21 +86,31 @@ type DownloaderFields struct {
 func SetupDownloaderMocks(ops ...[]*models.Oplog) DownloaderFields {
 	dl := archiveMocks.Downloader{}
 	archives, raws := ArchRawMocks(ops...)
-	dl.On("DownloadOplogArchive", mock.Anything, mock.Anything).
-		Return(nil).
-		Run(func(args mock.Arguments) {
-			writer := args.Get(1).(io.WriteCloser)
-			arch := args.Get(0).(models.Archive)
-			for i, a := range archives {
-				if a == arch {
-					if _, err := writer.Write(raws[i]); err != nil {
-						panic(err)
-					}
-					return
+	// the first archive is downloaded since the from ts, the rest ones are read as is
+	dl.On("DownloadOplogArchive", archives[0], mock.Anything, mock.Anything).
+		Return(func(arch models.Archive, from *models.Timestamp, writeCloser io.WriteCloser) error {
+			defer writeCloser.Close()
+			for _, op := range ops[0] {
+				if models.LessTS(op.TS, *from) {
+					continue
+				}
+				if _, err := writeCloser.Write(op.Data); err != nil {
+					return err
 				}
 			}
-			panic("bad mock data")
-		}).Times(len(archives))
+			return nil
+		}).Once()
+	if len(archives) > 1 {
+		dl.On("OplogArchiveReader", mock.Anything).
+			Return(func(arch models.Archive) (io.ReadCloser, error) {
+				for i, a := range archives {
+					if a == arch {
+						return io.NopCloser(bytes.NewReader(raws[i])), nil
+					}
+				}
+				panic("bad mock data")
+			}).Times(len(archives) - 1)
+	}
 
 	return DownloaderFields{downloader: &dl, path: archives}
 }
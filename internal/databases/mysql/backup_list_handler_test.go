@@ -0,0 +1,23 @@
+package mysql
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestNewBackupDetail_Labels(t *testing.T) {
+	startTime := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	detail := NewBackupDetail(internal.BackupTime{BackupName: "stream_20220301T100000Z", Time: startTime},
+		StreamSentinelDto{StartLocalTime: startTime, StopLocalTime: startTime,
+			Labels: map[string]string{"region": "eu-west", "cluster": "main"}})
+	assert.Equal(t, map[string]string{"region": "eu-west", "cluster": "main"}, detail.Labels)
+
+	output := &bytes.Buffer{}
+	assert.NoError(t, writeBackupListDetails([]BackupDetail{detail}, output))
+	assert.Contains(t, output.String(), "labels")
+	assert.Contains(t, output.String(), "cluster=main,region=eu-west")
+}
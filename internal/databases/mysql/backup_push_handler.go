@@ -13,7 +13,7 @@ import (
 )
 
 func HandleBackupPush(folder storage.Folder, uploader internal.UploaderProvider,
-	backupCmd *exec.Cmd, isPermanent bool, userDataRaw string) {
+	backupCmd *exec.Cmd, isPermanent bool, userDataRaw string, labels map[string]string) {
 	db, err := getMySQLConnection()
 	tracelog.ErrorLogger.FatalOnError(err)
 	defer utility.LoggedClose(db, "")
@@ -31,7 +31,8 @@ func HandleBackupPush(folder storage.Folder, uploader internal.UploaderProvider,
 	stdout, stderr, err := utility.StartCommandWithStdoutStderr(backupCmd)
 	tracelog.ErrorLogger.FatalfOnError("failed to start backup create command: %v", err)
 
-	fileName, err := uploader.PushStream(limiters.NewDiskLimitReader(stdout))
+	digestReader := internal.NewStreamDigestReader(limiters.NewDiskLimitReader(stdout))
+	fileName, err := uploader.PushStream(digestReader)
 	tracelog.ErrorLogger.FatalfOnError("failed to push backup: %v", err)
 
 	err = backupCmd.Wait()
@@ -63,6 +64,7 @@ func HandleBackupPush(folder storage.Folder, uploader internal.UploaderProvider,
 	tracelog.ErrorLogger.FatalfOnError("Failed to unmarshal the provided UserData: %s", err)
 
 	sentinel := StreamSentinelDto{
+		SchemaVersion:    StreamSentinelSchemaVersion,
 		BinLogStart:      binlogStart,
 		BinLogEnd:        binlogEnd,
 		StartLocalTime:   timeStart,
@@ -70,8 +72,11 @@ func HandleBackupPush(folder storage.Folder, uploader internal.UploaderProvider,
 		Hostname:         hostname,
 		CompressedSize:   uploadedSize,
 		UncompressedSize: rawSize,
+		SHA256:           digestReader.SHA256(),
+		CompressedSHA256: uploader.CompressedStreamDigests(),
 		IsPermanent:      isPermanent,
 		UserData:         userData,
+		Labels:           labels,
 	}
 	tracelog.InfoLogger.Printf("Backup sentinel: %s", sentinel.String())
 
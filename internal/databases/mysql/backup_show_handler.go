@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupShowDetails is the sentinel of the backup printed by backup-show
+type BackupShowDetails struct {
+	BackupName string `json:"BackupName"`
+	// Duration is empty if the sentinel lacks the backup timestamps
+	Duration string            `json:"Duration,omitempty"`
+	Sentinel StreamSentinelDto `json:"Sentinel"`
+}
+
+// HandleBackupShow prints the sentinel of the backup by its name or LATEST as JSON or as the table of its fields
+func HandleBackupShow(folder storage.Folder, backupName string, output io.Writer, asJSON, pretty bool) error {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	if err != nil {
+		return err
+	}
+	var sentinel StreamSentinelDto
+	if err = backup.FetchExistingSentinel(&sentinel); err != nil {
+		return err
+	}
+
+	details := BackupShowDetails{BackupName: backup.Name, Sentinel: sentinel}
+	if !sentinel.StartLocalTime.IsZero() && !sentinel.StopLocalTime.IsZero() {
+		details.Duration = sentinel.StopLocalTime.Sub(sentinel.StartLocalTime).String()
+	}
+	return internal.WriteBackupDetails(details, details.fields(), output, asJSON, pretty)
+}
+
+//nolint:gocritic,hugeParam
+func (details BackupShowDetails) fields() []internal.BackupDetailsField {
+	sentinel := details.Sentinel
+	fields := []internal.BackupDetailsField{
+		{Name: "Name", Value: details.BackupName},
+		{Name: "Schema version", Value: strconv.Itoa(sentinel.SchemaVersion)},
+		{Name: "Start time", Value: internal.FormatDetailsTime(sentinel.StartLocalTime)},
+		{Name: "Finish time", Value: internal.FormatDetailsTime(sentinel.StopLocalTime)},
+		{Name: "Duration", Value: details.Duration},
+		{Name: "Hostname", Value: sentinel.Hostname},
+		{Name: "Binlog start", Value: sentinel.BinLogStart},
+		{Name: "Binlog end", Value: sentinel.BinLogEnd},
+		{Name: "Uncompressed size", Value: internal.FormatDetailsSize(sentinel.UncompressedSize)},
+		{Name: "Compressed size", Value: internal.FormatDetailsSize(sentinel.CompressedSize)},
+		{Name: "SHA256", Value: sentinel.SHA256},
+	}
+	paths := make([]string, 0, len(sentinel.CompressedSHA256))
+	for path := range sentinel.CompressedSHA256 {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fields = append(fields, internal.BackupDetailsField{Name: "Compressed SHA256 of " + path,
+			Value: sentinel.CompressedSHA256[path]})
+	}
+	return append(fields,
+		internal.BackupDetailsField{Name: "Permanent", Value: strconv.FormatBool(sentinel.IsPermanent)},
+		internal.BackupDetailsField{Name: "User data", Value: internal.FormatDetailsJSON(sentinel.UserData)},
+		internal.BackupDetailsField{Name: "Labels", Value: internal.FormatBackupLabels(sentinel.Labels)})
+}
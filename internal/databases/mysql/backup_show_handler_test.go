@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestHandleBackupShow(t *testing.T) {
+	internal.ConfigureSettings(internal.MYSQL)
+	internal.InitConfig()
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	startTime := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	backup := internal.NewBackup(folder.GetSubFolder(utility.BaseBackupPath), "stream_20220301T100000Z")
+	assert.NoError(t, backup.UploadSentinel(StreamSentinelDto{
+		SchemaVersion: StreamSentinelSchemaVersion, BinLogStart: "mysql-bin.000001",
+		StartLocalTime: startTime, StopLocalTime: startTime.Add(time.Hour), SHA256: "abc",
+		CompressedSHA256: map[string]string{"stream_20220301T100000Z/stream.br": "def"},
+		Labels:           map[string]string{"region": "eu-west", "cluster": "main"},
+	}))
+
+	output := &bytes.Buffer{}
+	assert.NoError(t, HandleBackupShow(folder, "stream_20220301T100000Z", output, false, false))
+	for _, expected := range []string{"mysql-bin.000001", "1h0m0s", "Compressed SHA256 of stream_20220301T100000Z/stream.br",
+		"cluster=main,region=eu-west"} {
+		assert.Contains(t, output.String(), expected)
+	}
+	// the labels are printed in their own row of the table
+	assert.Regexp(t, `Labels\s*\|?\s*cluster=main,region=eu-west`, output.String())
+	// the empty fields are omitted from the table
+	assert.NotContains(t, output.String(), "Hostname")
+
+	output.Reset()
+	assert.NoError(t, HandleBackupShow(folder, "stream_20220301T100000Z", output, true, true))
+	var details BackupShowDetails
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &details))
+	assert.Equal(t, "abc", details.Sentinel.SHA256)
+	assert.Equal(t, "1h0m0s", details.Duration)
+
+	err := HandleBackupShow(folder, "stream_20220302T100000Z", output, false, false)
+	assert.IsType(t, internal.BackupNonExistenceError{}, err)
+}
+
+func TestHandleBackupShow_V0Sentinel(t *testing.T) {
+	internal.ConfigureSettings(internal.MYSQL)
+	internal.InitConfig()
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	backupsFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	assert.NoError(t, backupsFolder.PutObject(internal.SentinelNameFromBackup("stream_20210601T100000Z"),
+		bytes.NewBufferString(`{"BinLogStart":"mysql-bin.000001","StartLocalTime":"2021-06-01T10:00:00Z"}`)))
+
+	output := &bytes.Buffer{}
+	assert.NoError(t, HandleBackupShow(folder, "stream_20210601T100000Z", output, true, false))
+	var details BackupShowDetails
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &details))
+	// the stored version is reported, the unknown finish time and duration are not invented
+	assert.Equal(t, 0, details.Sentinel.SchemaVersion)
+	assert.True(t, details.Sentinel.StopLocalTime.IsZero())
+	assert.Empty(t, details.Duration)
+}
@@ -187,7 +187,23 @@ func replaceHostInDatasourceName(datasourceName string, newHost string) string {
 	return userData + "@" + newHost + "/" + dbNameAndParams
 }
 
+// StreamSentinelSchemaVersion is the version of the StreamSentinelDto format written by this build
+const StreamSentinelSchemaVersion = 1
+
+// StreamSentinelDto describes the backup stream.
+//
+// The SchemaVersion is set at write time. Adding an optional field which older builds may safely ignore
+// does not change the version. The version is bumped once the older builds would misread the sentinel:
+// a field changes its meaning or format, or a field becomes required. Each bump adds the migration
+// converting the sentinel of the previous version to streamSentinelMigrations, so the sentinels of all the known
+// versions are read as the current one. The fields the older version lacks are left zero, since they are unknown.
+// The version the sentinel was written with is kept. The sentinels of the versions newer
+// than StreamSentinelSchemaVersion are refused instead of being misread.
+//
+// Version 0 (no SchemaVersion field) sentinels have the same fields, they may lack the StopLocalTime.
 type StreamSentinelDto struct {
+	SchemaVersion int `json:"SchemaVersion,omitempty"`
+
 	BinLogStart string `json:"BinLogStart,omitempty"`
 	// BinLogEnd field is for debug purpose only.
 	// As we can not guarantee that transactions in BinLogEnd file happened before or after backup
@@ -195,12 +211,17 @@ type StreamSentinelDto struct {
 	StartLocalTime time.Time `json:"StartLocalTime,omitempty"`
 	StopLocalTime  time.Time `json:"StopLocalTime,omitempty"`
 
-	UncompressedSize int64  `json:"UncompressedSize,omitempty"`
-	CompressedSize   int64  `json:"CompressedSize,omitempty"`
-	Hostname         string `json:"Hostname,omitempty"`
+	UncompressedSize int64 `json:"UncompressedSize,omitempty"`
+	CompressedSize   int64 `json:"CompressedSize,omitempty"`
+	// SHA256 is the hex-encoded digest of the uncompressed backup stream
+	SHA256 string `json:"SHA256,omitempty"`
+	// CompressedSHA256 are the hex-encoded digests of the stored (compressed) stream objects by their paths
+	CompressedSHA256 map[string]string `json:"CompressedSHA256,omitempty"`
+	Hostname         string            `json:"Hostname,omitempty"`
 
-	IsPermanent bool        `json:"IsPermanent,omitempty"`
-	UserData    interface{} `json:"UserData,omitempty"`
+	IsPermanent bool              `json:"IsPermanent,omitempty"`
+	UserData    interface{}       `json:"UserData,omitempty"`
+	Labels      map[string]string `json:"Labels,omitempty"`
 
 	//todo: add other fields from internal.GenericMetadata
 }
@@ -213,6 +234,32 @@ func (s *StreamSentinelDto) String() string {
 	return string(b)
 }
 
+// streamSentinelMigrations upgrade the sentinel of the version given by the index to the next version
+var streamSentinelMigrations = []func(sentinel *StreamSentinelDto){
+	// version 1 only started recording the SchemaVersion
+	0: func(sentinel *StreamSentinelDto) {},
+}
+
+// UnmarshalJSON reads the sentinel of any known version as the current one, the SchemaVersion keeps the read version
+func (s *StreamSentinelDto) UnmarshalJSON(data []byte) error {
+	// the alias has no UnmarshalJSON, so the fields are decoded as usual
+	type streamSentinelFields StreamSentinelDto
+	var fields streamSentinelFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	sentinel := StreamSentinelDto(fields)
+	if sentinel.SchemaVersion < 0 || sentinel.SchemaVersion > StreamSentinelSchemaVersion {
+		return fmt.Errorf("unsupported backup sentinel schema version %d, this build reads versions up to %d",
+			sentinel.SchemaVersion, StreamSentinelSchemaVersion)
+	}
+	for version := sentinel.SchemaVersion; version < StreamSentinelSchemaVersion; version++ {
+		streamSentinelMigrations[version](&sentinel)
+	}
+	*s = sentinel
+	return nil
+}
+
 type binlogHandler interface {
 	handleBinlog(binlogPath string) error
 }
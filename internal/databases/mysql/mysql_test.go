@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamSentinelDto_UnmarshalV0(t *testing.T) {
+	var sentinel StreamSentinelDto
+	err := json.Unmarshal([]byte(`{"BinLogStart":"mysql-bin.000001","StartLocalTime":"2021-06-01T10:00:00Z",`+
+		`"UncompressedSize":100,"IsPermanent":true}`), &sentinel)
+	assert.NoError(t, err)
+
+	startTime := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, 0, sentinel.SchemaVersion)
+	assert.Equal(t, "mysql-bin.000001", sentinel.BinLogStart)
+	assert.True(t, sentinel.StartLocalTime.Equal(startTime))
+	// the missing stop time is unknown rather than invented
+	assert.True(t, sentinel.StopLocalTime.IsZero())
+	assert.Equal(t, int64(100), sentinel.UncompressedSize)
+	assert.True(t, sentinel.IsPermanent)
+}
+
+func TestStreamSentinelDto_UnmarshalV1(t *testing.T) {
+	written := StreamSentinelDto{
+		SchemaVersion:  1,
+		BinLogStart:    "mysql-bin.000001",
+		StartLocalTime: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		StopLocalTime:  time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC),
+		SHA256:         "digest",
+		Hostname:       "host",
+	}
+	data, err := json.Marshal(written)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"SchemaVersion":1`)
+
+	var sentinel StreamSentinelDto
+	assert.NoError(t, json.Unmarshal(data, &sentinel))
+	assert.Equal(t, written, sentinel)
+}
+
+func TestStreamSentinelDto_RefusesNewerVersion(t *testing.T) {
+	var sentinel StreamSentinelDto
+	data := fmt.Sprintf(`{"SchemaVersion":%d,"BinLogStart":"mysql-bin.000001"}`, StreamSentinelSchemaVersion+1)
+	err := json.Unmarshal([]byte(data), &sentinel)
+	assert.Error(t, err)
+	assert.Empty(t, sentinel.BinLogStart)
+}
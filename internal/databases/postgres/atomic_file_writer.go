@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const atomicWriteTempSuffix = ".walg-tmp"
+
+// writesAtomically checks if the regular files are written to the temporary files and renamed over the targets,
+// by default only the new unwrap implementation does it
+func (tarInterpreter *FileTarInterpreter) writesAtomically() bool {
+	if tarInterpreter.atomicWrites != nil {
+		return *tarInterpreter.atomicWrites
+	}
+	return useNewUnwrapImplementation
+}
+
+// atomicWriteTempPath returns the path of the temporary file the targetPath is written to before the rename,
+// it is in the same directory, so the rename does not cross the file systems. The name is fixed,
+// so the file left by the interrupted restore is overwritten by the next one.
+func atomicWriteTempPath(targetPath string) string {
+	return filepath.Join(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+atomicWriteTempSuffix)
+}
+
+// commitAtomicWrite flushes the temporary file, renames it over the targetPath and flushes the directory,
+// so after a crash the targetPath is either its previous or the new complete version
+func commitAtomicWrite(tempFile *os.File, targetPath string) error {
+	if err := tempFile.Sync(); err != nil {
+		return errors.Wrapf(err, "Interpret: fsync of '%s' failed", tempFile.Name())
+	}
+	if err := os.Rename(tempFile.Name(), targetPath); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to rename '%s' to '%s'", tempFile.Name(), targetPath)
+	}
+	return syncDirectory(filepath.Dir(targetPath))
+}
+
+// syncDirectory flushes the directory entries, making the renames in it durable
+func syncDirectory(dirPath string) error {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return errors.Wrapf(err, "Interpret: failed to open directory '%s'", dirPath)
+	}
+	defer utility.LoggedClose(dir, "")
+	return errors.Wrapf(dir.Sync(), "Interpret: fsync of directory '%s' failed", dirPath)
+}
+
+// removeAtomicWriteTemp removes the temporary file of the failed write, the targetPath is left intact
+func removeAtomicWriteTemp(tempPath string) {
+	if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
+		tracelog.WarningLogger.Printf("Interpret: failed to remove temporary file '%s': %v", tempPath, err)
+	}
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestInterpret_AtomicWriteKeepsPreviousFileOnFailure(t *testing.T) {
+	viper.Set(internal.RestoreAtomicWritesSetting, true)
+	defer viper.Set(internal.RestoreAtomicWritesSetting, nil)
+
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "file")
+	assert.NoError(t, os.WriteFile(targetPath, []byte("previous"), 0600))
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+
+	reader := io.MultiReader(strings.NewReader("new con"), iotest.ErrReader(errors.New("connection reset")))
+	err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 11})
+	assert.Error(t, err)
+	contents, err := os.ReadFile(targetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous", string(contents))
+	_, err = os.Stat(atomicWriteTempPath(targetPath))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpret_AtomicWriteReplacesFile(t *testing.T) {
+	viper.Set(internal.RestoreAtomicWritesSetting, true)
+	defer viper.Set(internal.RestoreAtomicWritesSetting, nil)
+
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "file")
+	assert.NoError(t, os.WriteFile(targetPath, []byte("previous"), 0600))
+	// the leftover of the interrupted restore is overwritten
+	assert.NoError(t, os.WriteFile(atomicWriteTempPath(targetPath), []byte("leftover of the crash"), 0600))
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+
+	err := tarInterpreter.Interpret(strings.NewReader("new contents"),
+		&tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0640, Size: 12})
+	assert.NoError(t, err)
+	contents, err := os.ReadFile(targetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "new contents", string(contents))
+	info, err := os.Stat(targetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	_, err = os.Stat(atomicWriteTempPath(targetPath))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpret_NewUnwrapWritesNewFilesAtomically(t *testing.T) {
+	useNewUnwrapImplementation = true
+	defer func() { useNewUnwrapImplementation = false }()
+
+	dir := t.TempDir()
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	assert.True(t, tarInterpreter.writesAtomically())
+
+	reader := io.MultiReader(strings.NewReader("new con"), iotest.ErrReader(errors.New("connection reset")))
+	err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 11})
+	assert.Error(t, err)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	err = tarInterpreter.Interpret(strings.NewReader("new contents"),
+		&tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 12})
+	assert.NoError(t, err)
+	contents, err := os.ReadFile(filepath.Join(dir, "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new contents", string(contents))
+}
+
+func TestWritesAtomically_Setting(t *testing.T) {
+	useNewUnwrapImplementation = true
+	defer func() { useNewUnwrapImplementation = false }()
+	viper.Set(internal.RestoreAtomicWritesSetting, false)
+	defer viper.Set(internal.RestoreAtomicWritesSetting, nil)
+
+	tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	assert.False(t, tarInterpreter.writesAtomically())
+}
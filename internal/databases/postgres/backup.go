@@ -68,6 +68,11 @@ func (backup *Backup) GetTarNames() ([]string, error) {
 }
 
 func (backup *Backup) GetSentinel() (BackupSentinelDto, error) {
+	return backup.getSentinel(backup.FetchSentinel)
+}
+
+// getSentinel fetches the sentinel by fetchSentinel unless it is already fetched
+func (backup *Backup) getSentinel(fetchSentinel func(sentinelDto interface{}) error) (BackupSentinelDto, error) {
 	if backup.SentinelDto != nil {
 		return *backup.SentinelDto, nil
 	}
@@ -79,7 +84,7 @@ func (backup *Backup) GetSentinel() (BackupSentinelDto, error) {
 		DeprecatedSentinelFields
 	}{}
 
-	err := backup.FetchSentinel(&s)
+	err := fetchSentinel(&s)
 	if err != nil {
 		return BackupSentinelDto{}, err
 	}
@@ -241,26 +246,41 @@ func setTablespacePaths(spec TablespaceSpec) error {
 	return nil
 }
 
-// check that directory is empty before unwrap
+// check that directory is empty before unwrap, the directory of the resumed restore is not
 func (backup *Backup) unwrapToEmptyDirectory(
 	dbDataDirectory string, sentinelDto BackupSentinelDto,
-	filesMeta FilesMetadataDto, filesToUnwrap map[string]bool, createIncrementalFiles bool,
+	filesMeta FilesMetadataDto, filesToUnwrap map[string]bool, createIncrementalFiles bool, journal *RestoreJournal,
+	incrementedLater map[string]bool,
 ) error {
-	err := checkDBDirectoryForUnwrap(dbDataDirectory, sentinelDto, filesMeta)
-	if err != nil {
-		return err
+	if journal == nil || !journal.Resumed() {
+		err := checkDBDirectoryForUnwrap(dbDataDirectory, sentinelDto, filesMeta)
+		if err != nil {
+			return err
+		}
+	} else if sentinelDto.TablespaceSpec != nil && !sentinelDto.TablespaceSpec.empty() {
+		if err := setTablespacePaths(*sentinelDto.TablespaceSpec); err != nil {
+			return err
+		}
 	}
 
-	return backup.unwrapOld(dbDataDirectory, sentinelDto, filesMeta, filesToUnwrap, createIncrementalFiles)
+	return backup.unwrapOld(dbDataDirectory, sentinelDto, filesMeta, filesToUnwrap, createIncrementalFiles, journal,
+		incrementedLater)
 }
 
 // TODO : unit tests
-// Do the job of unpacking Backup object
+// Do the job of unpacking Backup object, the files completed according to the journal are skipped if it is set.
+// The incrementedLater files are patched by the later backups of the chain, so they are never shared.
 func (backup *Backup) unwrapOld(
 	dbDataDirectory string, sentinelDto BackupSentinelDto,
-	filesMeta FilesMetadataDto, filesToUnwrap map[string]bool, createIncrementalFiles bool,
+	filesMeta FilesMetadataDto, filesToUnwrap map[string]bool, createIncrementalFiles bool, journal *RestoreJournal,
+	incrementedLater map[string]bool,
 ) error {
+	if journal != nil && filesToUnwrap != nil {
+		filesToUnwrap = journal.pendingFiles(backup.Name, filesToUnwrap)
+	}
 	tarInterpreter := NewFileTarInterpreter(dbDataDirectory, sentinelDto, filesMeta, filesToUnwrap, createIncrementalFiles)
+	tarInterpreter.journal, tarInterpreter.journalBackupName = journal, backup.Name
+	tarInterpreter.incrementedLater = incrementedLater
 	tarsToExtract, pgControlKey, err := backup.getTarsToExtract(filesMeta, filesToUnwrap, false)
 	if err != nil {
 		return err
@@ -273,7 +293,7 @@ func (backup *Backup) unwrapOld(
 		return newPgControlNotFoundError()
 	}
 
-	err = internal.ExtractAll(tarInterpreter, tarsToExtract)
+	err = extractTars(tarInterpreter, tarsToExtract)
 	if err != nil {
 		return err
 	}
@@ -286,6 +306,10 @@ func (backup *Backup) unwrapOld(
 		}
 	}
 
+	if err = tarInterpreter.OnInterpretFinish(); err != nil {
+		return errors.Wrap(err, "failed to finish the backup extraction")
+	}
+
 	tracelog.InfoLogger.Print("\nBackup extraction complete.\n")
 	return nil
 }
@@ -366,6 +390,31 @@ func (backup *Backup) GetFilesToUnwrap(fileMask string) (map[string]bool, error)
 	return utility.SelectMatchingFiles(fileMask, filesToUnwrap)
 }
 
+// GetDatabaseFilesToUnwrap restricts the files to unwrap to the ones needed to restore only the given databases,
+// filesToUnwrap is returned as is if no databases are given
+func (backup *Backup) GetDatabaseFilesToUnwrap(filesToUnwrap map[string]bool, databases []string) (map[string]bool, error) {
+	if len(databases) == 0 {
+		return filesToUnwrap, nil
+	}
+	sentinel, filesMeta, err := backup.GetSentinelAndFilesMetadata()
+	if err != nil {
+		return nil, err
+	}
+	databaseFiles, err := GetDatabaseFilesToUnwrap(sentinel, filesMeta, databases)
+	if err != nil {
+		return nil, err
+	}
+	if filesToUnwrap == nil {
+		return databaseFiles, nil
+	}
+	for file := range databaseFiles {
+		if !filesToUnwrap[file] {
+			delete(databaseFiles, file)
+		}
+	}
+	return databaseFiles, nil
+}
+
 func shouldUnwrapTar(tarName string, filesMeta FilesMetadataDto, filesToUnwrap map[string]bool) bool {
 	// in case of base backup created with WALG_WITHOUT_FILES_METADATA
 	if len(filesMeta.TarFileSets) == 0 {
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestBackupPush_StoresFileChecksums(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	dataDirectory := t.TempDir()
+	writeFlattenTestFile(t, dataDirectory, "global/pg_control", []byte("pg_control"), time.Now())
+	writeFlattenTestFile(t, dataDirectory, "base/1/16384", []byte("original relation contents"), time.Now())
+
+	startLSN := uint64(1)
+	files := pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenBaseBackupName,
+		BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &startLSN}, nil, nil)
+	if assert.NotNil(t, files["/base/1/16384"].Checksum) {
+		assert.Equal(t, *sha256FileChecksum(t, "original relation contents"), *files["/base/1/16384"].Checksum)
+	}
+
+	viper.Set(internal.VerifyFileChecksumsSetting, true)
+	defer viper.Set(internal.VerifyFileChecksumsSetting, false)
+	// the failed tar partitions are retried until the concurrency drops to one
+	viper.Set(internal.DownloadConcurrencySetting, 1)
+	defer viper.Set(internal.DownloadConcurrencySetting, nil)
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	backup := NewBackup(baseBackupFolder, flattenBaseBackupName)
+	assert.Equal(t, []byte("original relation contents"),
+		readFlattenTestFile(t, restoreFlattenTestBackup(t, rootFolder, backup), "base/1/16384"))
+
+	corruptBackupTarPartitions(t, baseBackupFolder, "original relation contents", "damaged! relation contents")
+	filesToUnwrap, err := backup.GetFilesToUnwrap("")
+	assert.NoError(t, err)
+	restoreDirectory := t.TempDir()
+	err = deltaFetchRecursionOld(backup, rootFolder, restoreDirectory, nil, filesToUnwrap, nil)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(restoreDirectory, "base/1/16384"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func sha256FileChecksum(t *testing.T, content string) *internal.FileChecksum {
+	checksumHash, err := internal.NewChecksumHash(internal.SHA256ChecksumAlgorithm)
+	assert.NoError(t, err)
+	checksumHash.Write([]byte(content))
+	checksum := internal.NewFileChecksum(internal.SHA256ChecksumAlgorithm, checksumHash)
+	return &checksum
+}
+
+// corruptBackupTarPartitions replaces the contents in the uploaded tar partitions keeping them valid lz4 streams,
+// so only the file checksum may tell the corruption
+func corruptBackupTarPartitions(t *testing.T, folder storage.Folder, original, damaged string) {
+	objects, err := storage.ListFolderRecursively(folder)
+	assert.NoError(t, err)
+	lz4Compressor := compression.Compressors[lz4.AlgorithmName]
+	corrupted := false
+	for _, object := range objects {
+		if !strings.HasSuffix(object.GetName(), ".tar."+lz4Compressor.FileExtension()) {
+			continue
+		}
+		reader, err := folder.ReadObject(object.GetName())
+		assert.NoError(t, err)
+		decompressed, err := compression.GetDecompressorByCompressor(lz4Compressor).Decompress(reader)
+		assert.NoError(t, err)
+		tarContents, err := io.ReadAll(decompressed)
+		assert.NoError(t, err)
+		utility.LoggedClose(reader, "")
+		if !bytes.Contains(tarContents, []byte(original)) {
+			continue
+		}
+
+		var compressed bytes.Buffer
+		writer := lz4Compressor.NewWriter(&compressed)
+		_, err = writer.Write(bytes.ReplaceAll(tarContents, []byte(original), []byte(damaged)))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+		assert.NoError(t, folder.PutObject(object.GetName(), &compressed))
+		corrupted = true
+	}
+	assert.True(t, corrupted)
+}
@@ -63,7 +63,15 @@ func chooseTablespaceSpecification(sentinelDtoSpec, spec *TablespaceSpec) *Table
 // TODO : unit tests
 // deltaFetchRecursion function composes Backup object and recursively searches for necessary base backup
 func deltaFetchRecursionOld(backup Backup, folder storage.Folder, dbDataDirectory string,
-	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool) error {
+	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool, journal *RestoreJournal) error {
+	return deltaFetchChainOld(backup, folder, dbDataDirectory, tablespaceSpec, filesToUnwrap, journal, nil)
+}
+
+// deltaFetchChainOld restores the base backups of the chain first, incrementedLater are the files
+// the increments of the later backups of the chain are applied to
+func deltaFetchChainOld(backup Backup, folder storage.Folder, dbDataDirectory string,
+	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool, journal *RestoreJournal,
+	incrementedLater map[string]bool) error {
 	sentinelDto, filesMetaDto, err := backup.GetSentinelAndFilesMetadata()
 	if err != nil {
 		return err
@@ -78,7 +86,8 @@ func deltaFetchRecursionOld(backup Backup, folder storage.Folder, dbDataDirector
 			return err
 		}
 		incrementFrom := NewBackup(folder.GetSubFolder(utility.BaseBackupPath), *sentinelDto.IncrementFrom)
-		err = deltaFetchRecursionOld(incrementFrom, folder, dbDataDirectory, tablespaceSpec, baseFilesToUnwrap)
+		err = deltaFetchChainOld(incrementFrom, folder, dbDataDirectory, tablespaceSpec, baseFilesToUnwrap, journal,
+			getIncrementedFiles(filesMetaDto.Files, incrementedLater))
 		if err != nil {
 			return err
 		}
@@ -86,14 +95,34 @@ func deltaFetchRecursionOld(backup Backup, folder storage.Folder, dbDataDirector
 			*(sentinelDto.IncrementFrom), *(sentinelDto.IncrementFromLSN), *(sentinelDto.BackupStartLSN))
 	}
 
-	return backup.unwrapToEmptyDirectory(dbDataDirectory, sentinelDto, filesMetaDto, filesToUnwrap, false)
+	return backup.unwrapToEmptyDirectory(dbDataDirectory, sentinelDto, filesMetaDto, filesToUnwrap, false, journal,
+		incrementedLater)
 }
 
-func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string) func(rootFolder storage.Folder, backup internal.Backup) {
+// getIncrementedFiles adds the files incremented by the backup to the incrementedLater ones
+func getIncrementedFiles(backupFileStates internal.BackupFileList, incrementedLater map[string]bool) map[string]bool {
+	incrementedFiles := make(map[string]bool, len(incrementedLater))
+	for file := range incrementedLater {
+		incrementedFiles[file] = true
+	}
+	for file, fileDescription := range backupFileStates {
+		if fileDescription.IsIncremented {
+			incrementedFiles[file] = true
+		}
+	}
+	return incrementedFiles
+}
+
+func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string,
+	restoreOnly, excludePatterns []string, skipDiskSpaceCheck bool) func(rootFolder storage.Folder, backup internal.Backup) {
 	return func(rootFolder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
 		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(fileMask)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		filesToUnwrap, err = pgBackup.GetDatabaseFilesToUnwrap(filesToUnwrap, restoreOnly)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		filesToUnwrap, err = pgBackup.GetExcludedFilesToUnwrap(filesToUnwrap, excludePatterns)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		var spec *TablespaceSpec
 		if restoreSpecPath != "" {
@@ -102,8 +131,20 @@ func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string) func(roo
 			errMessege := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
 			tracelog.ErrorLogger.FatalfOnError(errMessege, err)
 		}
-		err = deltaFetchRecursionOld(pgBackup, rootFolder, utility.ResolveSymlink(dbDataDirectory), spec, filesToUnwrap)
+		journal, err := openConfiguredRestoreJournal(pgBackup.Name)
+		tracelog.ErrorLogger.FatalfOnError("Failed to open the restore journal: %v\n", err)
+		// the resumed restore has a part of the files on disk already
+		if !skipDiskSpaceCheck && (journal == nil || !journal.Resumed()) {
+			err = checkRestoreDiskSpace(pgBackup, dbDataDirectory)
+			tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		}
+		err = deltaFetchRecursionOld(pgBackup, rootFolder, utility.ResolveSymlink(dbDataDirectory), spec, filesToUnwrap,
+			journal)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		if journal != nil {
+			err = journal.Remove()
+			tracelog.ErrorLogger.FatalfOnError("Failed to remove the restore journal: %v\n", err)
+		}
 	}
 }
 
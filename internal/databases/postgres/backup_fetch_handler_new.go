@@ -3,6 +3,7 @@ package postgres
 import (
 	"fmt"
 
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
@@ -10,11 +11,16 @@ import (
 )
 
 func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedundantTars bool,
+	restoreOnly, excludePatterns []string, skipDiskSpaceCheck bool,
 ) func(folder storage.Folder, backup internal.Backup) {
 	return func(folder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
 		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(fileMask)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		filesToUnwrap, err = pgBackup.GetDatabaseFilesToUnwrap(filesToUnwrap, restoreOnly)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		filesToUnwrap, err = pgBackup.GetExcludedFilesToUnwrap(filesToUnwrap, excludePatterns)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		var spec *TablespaceSpec
 		if restoreSpecPath != "" {
@@ -24,6 +30,10 @@ func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedu
 			tracelog.ErrorLogger.FatalfOnError(errMessage, err)
 		}
 
+		if viper.GetString(internal.RestoreJournalSetting) != "" {
+			tracelog.WarningLogger.Printf("%s is not supported by the reverse delta unwrap, ignoring it",
+				internal.RestoreJournalSetting)
+		}
 		// directory must be empty before starting a deltaFetch
 		isEmpty, err := isDirectoryEmpty(dbDataDirectory)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
@@ -32,6 +42,10 @@ func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedu
 			tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n",
 				NewNonEmptyDBDataDirectoryError(dbDataDirectory))
 		}
+		if !skipDiskSpaceCheck {
+			err = checkRestoreDiskSpace(pgBackup, dbDataDirectory)
+			tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		}
 		config := NewFetchConfig(pgBackup.Name,
 			utility.ResolveSymlink(dbDataDirectory), folder, spec, filesToUnwrap, skipRedundantTars)
 		err = deltaFetchRecursionNew(config)
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// HandleBackupFlatten consolidates the incremental backup chain ending with the backup
+// into the full backup uploaded next to the chain, the chain itself is kept.
+// The chain is restored into the temporary directory created in the workDir.
+func HandleBackupFlatten(uploader *internal.Uploader, backupName string, workDir string) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, uploader.UploadingFolder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to find the backup: %v", err)
+
+	flattenedName, err := FlattenBackup(uploader.UploadingFolder, uploader.Compressor, internal.ConfigureCrypter(),
+		ToPgBackup(backup), workDir)
+	tracelog.ErrorLogger.FatalfOnError("Failed to flatten the backup: %v", err)
+	tracelog.InfoLogger.Printf("Wrote flattened backup with name %s", flattenedName)
+}
+
+// FlattenBackup applies the increments of the chain ending with the backup in order on top of its base backup
+// and uploads the result as the full backup with the fresh sentinel. Returns the name of the flattened backup:
+// the backup name without the delta suffix.
+func FlattenBackup(rootFolder storage.Folder, compressor compression.Compressor, crypter crypto.Crypter,
+	backup Backup, workDir string) (string, error) {
+	sentinelDto, _, err := backup.GetSentinelAndFilesMetadata()
+	if err != nil {
+		return "", err
+	}
+	if !sentinelDto.IsIncremental() {
+		return "", errors.Errorf("backup '%s' is not incremental", backup.Name)
+	}
+	if sentinelDto.TablespaceSpec != nil && !sentinelDto.TablespaceSpec.empty() {
+		return "", errors.Errorf("backup '%s' has tablespaces, flattening of such backups is not supported", backup.Name)
+	}
+	meta, err := backup.FetchMeta()
+	if err != nil {
+		return "", err
+	}
+
+	flattenedName := flattenedBackupName(backup.Name)
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	flattened := internal.NewBackup(baseBackupFolder, flattenedName)
+	exists, err := flattened.CheckExistence()
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errors.Errorf("backup '%s' already exists", flattenedName)
+	}
+
+	dataDirectory, err := os.MkdirTemp(workDir, "walg_flatten_")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the directory to restore the chain")
+	}
+	defer func() {
+		if err := os.RemoveAll(dataDirectory); err != nil {
+			tracelog.WarningLogger.Printf("Failed to remove '%s': %v", dataDirectory, err)
+		}
+	}()
+
+	tracelog.InfoLogger.Printf("Restoring the backup chain of %s into %s", backup.Name, dataDirectory)
+	filesToUnwrap, err := backup.GetFilesToUnwrap("")
+	if err != nil {
+		return "", err
+	}
+	err = deltaFetchRecursionOld(backup, rootFolder, dataDirectory, nil, filesToUnwrap, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to restore the backup chain")
+	}
+
+	uploader := internal.NewUploader(compressor, baseBackupFolder)
+	tracelog.InfoLogger.Printf("Uploading the flattened backup %s", flattenedName)
+	bundle, tarFileSets, err := uploadDataDirectory(uploader, crypter, dataDirectory, flattenedName,
+		nil, nil, sentinelDto.FilesMetadataDisabled)
+	if err != nil {
+		return "", err
+	}
+
+	sentinelDto.IncrementFrom = nil
+	sentinelDto.IncrementFromLSN = nil
+	sentinelDto.IncrementFullName = nil
+	sentinelDto.IncrementCount = nil
+	sentinelDto.UncompressedSize = atomic.LoadInt64(bundle.TarBallQueue.AllTarballsSize)
+	sentinelDto.CompressedSize, err = uploader.UploadedDataSize()
+	if err != nil {
+		return "", err
+	}
+	meta.UncompressedSize = sentinelDto.UncompressedSize
+	meta.CompressedSize = sentinelDto.CompressedSize
+	meta.IsPermanent = false
+
+	if !sentinelDto.FilesMetadataDisabled {
+		var filesMetaDto FilesMetadataDto
+		filesMetaDto.setFiles(bundle.GetFiles())
+		filesMetaDto.TarFileSets = tarFileSets.Get()
+		if err = uploadBackupDto(uploader, getFilesMetadataPath(flattenedName), filesMetaDto); err != nil {
+			return "", errors.Wrap(err, "failed to upload the files metadata")
+		}
+	}
+	if err = uploadBackupDto(uploader, storage.JoinPath(flattenedName, utility.MetadataFileName), meta); err != nil {
+		return "", errors.Wrap(err, "failed to upload the metadata")
+	}
+	err = internal.UploadSentinel(uploader, NewBackupSentinelDtoV2(sentinelDto, meta), flattenedName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to upload the sentinel")
+	}
+	return flattenedName, nil
+}
+
+// uploadDataDirectory packs the files of the data directory into the tarballs of the backup,
+// the files are compared with the incrementFromFiles when the incrementFromLsn is set.
+func uploadDataDirectory(uploader *internal.Uploader, crypter crypto.Crypter, dataDirectory, backupName string,
+	incrementFromLsn *uint64, incrementFromFiles internal.BackupFileList,
+	withoutFilesMetadata bool) (*Bundle, TarFileSets, error) {
+	bundle := NewBundle(dataDirectory, crypter, incrementFromLsn, incrementFromFiles, false,
+		viper.GetInt64(internal.TarSizeThresholdSetting))
+	err := bundle.StartQueue(internal.NewStorageTarBallMaker(backupName, uploader))
+	if err != nil {
+		return nil, nil, err
+	}
+	composerMaker, err := NewTarBallComposerMaker(RegularComposer, nil, uploader.UploadingFolder, backupName,
+		NewTarBallFilePackerOptions(false, false), withoutFilesMetadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = bundle.SetupComposer(composerMaker); err != nil {
+		return nil, nil, err
+	}
+	if err = filepath.Walk(dataDirectory, bundle.HandleWalkedFSObject); err != nil {
+		return nil, nil, err
+	}
+	tarFileSets, err := bundle.PackTarballs()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = bundle.FinishQueue(); err != nil {
+		return nil, nil, err
+	}
+	if bundle.Sentinel == nil {
+		return nil, nil, errors.Errorf("%s is not found in '%s'", PgControl, dataDirectory)
+	}
+	if err = bundle.UploadPgControl(uploader.Compressor.FileExtension()); err != nil {
+		return nil, nil, err
+	}
+	uploader.Finish()
+	if uploader.Failed.Load().(bool) {
+		return nil, nil, errors.Errorf("uploading failed during '%s' backup", backupName)
+	}
+	return bundle, tarFileSets, nil
+}
+
+func uploadBackupDto(uploader *internal.Uploader, path string, dto interface{}) error {
+	dtoBody, err := json.Marshal(dto)
+	if err != nil {
+		return internal.NewSentinelMarshallingError(path, err)
+	}
+	return uploader.Upload(path, bytes.NewReader(dtoBody))
+}
+
+// flattenedBackupName strips the delta suffix: base_X_D_Y becomes base_X
+func flattenedBackupName(backupName string) string {
+	if index := strings.Index(backupName, "_D_"); index > 0 {
+		return backupName[:index]
+	}
+	return backupName + "_flattened"
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	flattenBaseBackupName  = "base_000000010000000000000001"
+	flattenDeltaBackupName = "base_000000010000000000000003_D_000000010000000000000001"
+	flattenIncrementLSN    = uint64(0xc6bd4600 * 2)
+	flattenPagedFile       = "base/1/16384"
+)
+
+func TestFlattenBackup_RestoresIdenticallyToChain(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	dataDirectory := t.TempDir()
+	baseTime := time.Now().Add(-time.Hour)
+	writeFlattenTestFile(t, dataDirectory, "global/pg_control", []byte("pg_control"), baseTime)
+	writeFlattenTestFile(t, dataDirectory, "PG_VERSION", []byte("14\n"), baseTime)
+	pagedFile, err := os.ReadFile("../../../test/testdata/base_paged_file.bin")
+	assert.NoError(t, err)
+	writeFlattenTestFile(t, dataDirectory, flattenPagedFile, pagedFile, baseTime)
+
+	baseStartLSN := uint64(1)
+	baseFiles := pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenBaseBackupName,
+		BackupSentinelDto{BackupStartLSN: &baseStartLSN, BackupFinishLSN: &baseStartLSN}, nil, nil)
+
+	// the page of the paged file updated since the base backup is the only one in the increment
+	pageLSN := flattenIncrementLSN + 1
+	binary.LittleEndian.PutUint32(pagedFile[2*DatabasePageSize:], uint32(pageLSN>>32))
+	binary.LittleEndian.PutUint32(pagedFile[2*DatabasePageSize+4:], uint32(pageLSN))
+	copy(pagedFile[2*DatabasePageSize+4096:], "updated page")
+	writeFlattenTestFile(t, dataDirectory, flattenPagedFile, pagedFile, time.Now())
+	writeFlattenTestFile(t, dataDirectory, "PG_VERSION", []byte("15\n"), time.Now())
+	writeFlattenTestFile(t, dataDirectory, "base/1/16385", []byte("new file"), time.Now())
+
+	incrementFromLSN := flattenIncrementLSN
+	deltaStartLSN := flattenIncrementLSN + 1
+	baseName, count := flattenBaseBackupName, 1
+	deltaFiles := pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenDeltaBackupName,
+		BackupSentinelDto{BackupStartLSN: &deltaStartLSN, BackupFinishLSN: &deltaStartLSN,
+			IncrementFromLSN: &incrementFromLSN, IncrementFrom: &baseName, IncrementFullName: &baseName,
+			IncrementCount: &count}, &incrementFromLSN, baseFiles)
+	assert.True(t, deltaFiles["/"+flattenPagedFile].IsIncremented)
+
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	flattenedName, err := FlattenBackup(rootFolder, compression.Compressors[lz4.AlgorithmName], nil,
+		NewBackup(baseBackupFolder, flattenDeltaBackupName), t.TempDir())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "base_000000010000000000000003", flattenedName)
+
+	flattened := NewBackup(baseBackupFolder, flattenedName)
+	sentinelDto, filesMetaDto, err := flattened.GetSentinelAndFilesMetadata()
+	assert.NoError(t, err)
+	assert.False(t, sentinelDto.IsIncremental())
+	assert.Equal(t, deltaStartLSN, *sentinelDto.BackupStartLSN)
+	assert.False(t, filesMetaDto.Files["/"+flattenPagedFile].IsIncremented)
+
+	chainDirectory := restoreFlattenTestBackup(t, rootFolder, NewBackup(baseBackupFolder, flattenDeltaBackupName))
+	flattenedDirectory := restoreFlattenTestBackup(t, rootFolder, flattened)
+	for _, name := range []string{"global/pg_control", "PG_VERSION", flattenPagedFile, "base/1/16385"} {
+		expected, err := os.ReadFile(filepath.Join(dataDirectory, name))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, readFlattenTestFile(t, chainDirectory, name), name)
+		assert.Equal(t, expected, readFlattenTestFile(t, flattenedDirectory, name), name)
+	}
+
+	_, err = FlattenBackup(rootFolder, compression.Compressors[lz4.AlgorithmName], nil, flattened, t.TempDir())
+	assert.Error(t, err)
+}
+
+func pushFlattenTestBackup(t *testing.T, rootFolder storage.Folder, dataDirectory, backupName string,
+	sentinelDto BackupSentinelDto, incrementFromLsn *uint64, incrementFromFiles internal.BackupFileList) internal.BackupFileList {
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName],
+		rootFolder.GetSubFolder(utility.BaseBackupPath))
+	bundle, tarFileSets, err := uploadDataDirectory(uploader, nil, dataDirectory, backupName,
+		incrementFromLsn, incrementFromFiles, false)
+	assert.NoError(t, err)
+
+	var filesMetaDto FilesMetadataDto
+	filesMetaDto.setFiles(bundle.GetFiles())
+	filesMetaDto.TarFileSets = tarFileSets.Get()
+	assert.NoError(t, uploadBackupDto(uploader, getFilesMetadataPath(backupName), filesMetaDto))
+	meta := ExtendedMetadataDto{StartTime: time.Now(), FinishTime: time.Now()}
+	assert.NoError(t, uploadBackupDto(uploader, storage.JoinPath(backupName, utility.MetadataFileName), meta))
+	assert.NoError(t, internal.UploadSentinel(uploader, NewBackupSentinelDtoV2(sentinelDto, meta), backupName))
+	return filesMetaDto.Files
+}
+
+func restoreFlattenTestBackup(t *testing.T, rootFolder storage.Folder, backup Backup) string {
+	directory := t.TempDir()
+	filesToUnwrap, err := backup.GetFilesToUnwrap("")
+	assert.NoError(t, err)
+	assert.NoError(t, deltaFetchRecursionOld(backup, rootFolder, directory, nil, filesToUnwrap, nil))
+	return directory
+}
+
+// writeFlattenTestFile sets the modification time, which tells the changed files to the incremental backup
+func writeFlattenTestFile(t *testing.T, directory, name string, contents []byte, modTime time.Time) {
+	path := filepath.Join(directory, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, os.WriteFile(path, contents, 0600))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func readFlattenTestFile(t *testing.T, directory, name string) []byte {
+	contents, err := os.ReadFile(filepath.Join(directory, name))
+	assert.NoError(t, err)
+	return contents
+}
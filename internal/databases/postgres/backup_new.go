@@ -28,12 +28,39 @@ type UnwrapResult struct {
 	// store count of written increment blocks
 	writtenIncrementFiles      map[string]int64
 	writtenIncrementFilesMutex sync.Mutex
+	// for the dry-run mode store the action
+	// which would have been performed for each path
+	plannedActions      map[string]PlannedAction
+	plannedActionsMutex sync.Mutex
+	// time spent on each unwrapped file
+	fileTimings      []FileUnwrapTiming
+	fileTimingsMutex sync.Mutex
+	// files cloned from the seed directory and extracted from the backup
+	// if the seed directory is set
+	reflinkedFiles      []string
+	writtenFiles        []string
+	reflinkedFilesMutex sync.Mutex
+	// files already on disk with the contents matching the backup checksums
+	unchangedFiles      []string
+	unchangedFilesMutex sync.Mutex
+	// files symlinked to the shared base directory
+	sharedFiles      []string
+	sharedFilesMutex sync.Mutex
+	// files which were not restored in the continue on error mode
+	failedFiles      []FailedFile
+	failedFilesMutex sync.Mutex
 }
 
 func newUnwrapResult() *UnwrapResult {
 	return &UnwrapResult{make([]string, 0), sync.Mutex{},
 		make(map[string]int64), sync.Mutex{},
-		make(map[string]int64), sync.Mutex{}}
+		make(map[string]int64), sync.Mutex{},
+		make(map[string]PlannedAction), sync.Mutex{},
+		make([]FileUnwrapTiming, 0), sync.Mutex{},
+		make([]string, 0), make([]string, 0), sync.Mutex{},
+		make([]string, 0), sync.Mutex{},
+		make([]string, 0), sync.Mutex{},
+		make([]FailedFile, 0), sync.Mutex{}}
 }
 
 func checkDBDirectoryForUnwrapNew(dbDataDirectory string, sentinelDto BackupSentinelDto, filesMetaDto FilesMetadataDto) error {
@@ -89,7 +116,7 @@ func (backup *Backup) unwrapNew(
 		return nil, newPgControlNotFoundError()
 	}
 
-	err = internal.ExtractAll(tarInterpreter, tarsToExtract)
+	err = extractTars(tarInterpreter, tarsToExtract)
 	if _, ok := err.(internal.NoFilesToExtractError); ok {
 		// in case of no tars to extract, just ignore this backup and proceed to the next
 		tracelog.InfoLogger.Println("Skipping backup: no useful files found.")
@@ -107,6 +134,11 @@ func (backup *Backup) unwrapNew(
 		}
 	}
 
+	if err = tarInterpreter.OnInterpretFinish(); err != nil {
+		return nil, errors.Wrap(err, "failed to finish the backup extraction")
+	}
+
 	tracelog.InfoLogger.Print("\nBackup extraction complete.\n")
+	logSlowestFiles(tarInterpreter.UnwrapResult)
 	return tarInterpreter.UnwrapResult, nil
 }
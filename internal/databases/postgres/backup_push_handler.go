@@ -92,6 +92,7 @@ type BackupPgInfo struct {
 	pgVersion        int
 	pgDataDirectory  string
 	systemIdentifier *uint64
+	databases        map[string]uint32
 }
 
 // BackupHandler is the main struct which is handling the backup process
@@ -489,6 +490,17 @@ func getPgServerInfo() (pgInfo BackupPgInfo, err error) {
 	pgInfo.systemIdentifier = queryRunner.SystemIdentifier
 	tracelog.DebugLogger.Printf("Postgres SystemIdentifier: %d", queryRunner.Version)
 
+	// the list of databases is needed only for the partial restore, so the backup is not failed without it
+	databaseInfos, err := queryRunner.getDatabaseInfos()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get the list of databases: %v", err)
+	} else {
+		pgInfo.databases = make(map[string]uint32, len(databaseInfos))
+		for _, databaseInfo := range databaseInfos {
+			pgInfo.databases[databaseInfo.name] = uint32(databaseInfo.oid)
+		}
+	}
+
 	err = tmpConn.Close()
 	if err != nil {
 		return pgInfo, err
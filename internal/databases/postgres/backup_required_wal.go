@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupRequiredWal lists the WAL segments written during the backup, they are required to restore it
+// to the consistent state and should be retained in the WAL archive
+type BackupRequiredWal struct {
+	BackupName string   `json:"backup_name"`
+	Timeline   uint32   `json:"timeline"`
+	StartLSN   string   `json:"start_lsn"`
+	FinishLSN  string   `json:"finish_lsn"`
+	Segments   []string `json:"segments"`
+	// MissingSegments are the required segments absent in the WAL archive, it is filled by CheckArchive
+	MissingSegments []string `json:"missing_segments"`
+}
+
+// NewBackupRequiredWal computes the segments from the one containing the start LSN of the backup
+// to the one containing its finish LSN. The timeline is taken from the backup name, since the sentinel lacks it.
+func NewBackupRequiredWal(backupName string, sentinel BackupSentinelDto) (BackupRequiredWal, error) {
+	if sentinel.BackupStartLSN == nil || sentinel.BackupFinishLSN == nil {
+		return BackupRequiredWal{}, errors.Errorf("backup '%s' sentinel has no start or finish LSN", backupName)
+	}
+	startLSN, finishLSN := *sentinel.BackupStartLSN, *sentinel.BackupFinishLSN
+	if finishLSN < startLSN {
+		return BackupRequiredWal{}, errors.Errorf("backup '%s' finish LSN %s precedes its start LSN %s",
+			backupName, pglogrepl.LSN(finishLSN), pglogrepl.LSN(startLSN))
+	}
+	if !strings.HasPrefix(backupName, utility.BackupNamePrefix) ||
+		len(backupName) < len(utility.BackupNamePrefix)+8 {
+		return BackupRequiredWal{}, newIncorrectBackupNameError(backupName)
+	}
+	timeline, err := ParseTimelineFromBackupName(backupName)
+	if err != nil {
+		return BackupRequiredWal{}, errors.Wrapf(err, "failed to parse the timeline of backup '%s'", backupName)
+	}
+
+	firstSegmentNo, lastSegmentNo := newWalSegmentNo(startLSN), newWalSegmentNo(finishLSN)
+	// the finish LSN at the segment boundary points past the last record, as the stop backup LSN does
+	if finishLSN > startLSN && finishLSN%WalSegmentSize == 0 {
+		lastSegmentNo = lastSegmentNo.previous()
+	}
+	segments := make([]string, 0, lastSegmentNo-firstSegmentNo+1)
+	for segmentNo := firstSegmentNo; segmentNo <= lastSegmentNo; segmentNo = segmentNo.next() {
+		segments = append(segments, segmentNo.getFilename(timeline))
+	}
+	return BackupRequiredWal{
+		BackupName:      backupName,
+		Timeline:        timeline,
+		StartLSN:        pglogrepl.LSN(startLSN).String(),
+		FinishLSN:       pglogrepl.LSN(finishLSN).String(),
+		Segments:        segments,
+		MissingSegments: []string{},
+	}, nil
+}
+
+// CheckArchive fills the MissingSegments with the required segments absent in the walFolder
+func (requiredWal *BackupRequiredWal) CheckArchive(walFolder storage.Folder) error {
+	filenames, err := getFolderFilenames(walFolder)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the WAL folder")
+	}
+	archivedSegments := getSegmentsFromFiles(filenames)
+	requiredWal.MissingSegments = []string{}
+	for _, segment := range requiredWal.Segments {
+		description, err := NewWalSegmentDescription(segment)
+		if err != nil {
+			return err
+		}
+		if !archivedSegments[description] {
+			requiredWal.MissingSegments = append(requiredWal.MissingSegments, segment)
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as JSON
+func (requiredWal *BackupRequiredWal) WriteJSON(output io.Writer) error {
+	return json.NewEncoder(output).Encode(requiredWal)
+}
+
+// WritePlainText writes the human-readable report
+func (requiredWal *BackupRequiredWal) WritePlainText(output io.Writer) error {
+	var report strings.Builder
+	report.WriteString(fmt.Sprintf("Backup: %s\n", requiredWal.BackupName))
+	report.WriteString(fmt.Sprintf("Timeline: %d\n", requiredWal.Timeline))
+	report.WriteString(fmt.Sprintf("Start LSN: %s\n", requiredWal.StartLSN))
+	report.WriteString(fmt.Sprintf("Finish LSN: %s\n", requiredWal.FinishLSN))
+	report.WriteString(fmt.Sprintf("Required segments (%d): %s - %s\n", len(requiredWal.Segments),
+		requiredWal.Segments[0], requiredWal.Segments[len(requiredWal.Segments)-1]))
+	if len(requiredWal.MissingSegments) == 0 {
+		report.WriteString("Missing segments: none\n")
+	} else {
+		report.WriteString(fmt.Sprintf("Missing segments (%d):\n", len(requiredWal.MissingSegments)))
+		for _, segment := range requiredWal.MissingSegments {
+			report.WriteString(segment + "\n")
+		}
+	}
+	_, err := io.WriteString(output, report.String())
+	return err
+}
+
+// HandleBackupRequiredWal reports the WAL segments required by the backup and the ones missing in the archive
+func HandleBackupRequiredWal(rootFolder storage.Folder, backupName string, output io.Writer, useJSON bool) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, rootFolder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to get the backup: %v", err)
+	pgBackup := ToPgBackup(backup)
+	sentinel, err := pgBackup.GetSentinel()
+	tracelog.ErrorLogger.FatalfOnError("Failed to read the backup sentinel: %v", err)
+
+	requiredWal, err := NewBackupRequiredWal(pgBackup.Name, sentinel)
+	tracelog.ErrorLogger.FatalOnError(err)
+	err = requiredWal.CheckArchive(rootFolder.GetSubFolder(utility.WalPath))
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	if useJSON {
+		err = requiredWal.WriteJSON(output)
+	} else {
+		err = requiredWal.WritePlainText(output)
+	}
+	tracelog.ErrorLogger.FatalOnError(err)
+}
@@ -0,0 +1,70 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func newRequiredWalSentinel(startLSN, finishLSN uint64) postgres.BackupSentinelDto {
+	return postgres.BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &finishLSN}
+}
+
+func TestNewBackupRequiredWal(t *testing.T) {
+	segmentSize := postgres.WalSegmentSize
+	requiredWal, err := postgres.NewBackupRequiredWal("base_000000020000000000000003",
+		newRequiredWalSentinel(3*segmentSize+40, 5*segmentSize+100))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), requiredWal.Timeline)
+	assert.Equal(t, []string{
+		"000000020000000000000003",
+		"000000020000000000000004",
+		"000000020000000000000005",
+	}, requiredWal.Segments)
+
+	// the finish LSN at the segment boundary does not require the next segment
+	requiredWal, err = postgres.NewBackupRequiredWal("base_000000010000000000000003",
+		newRequiredWalSentinel(3*segmentSize+40, 4*segmentSize))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"000000010000000000000003"}, requiredWal.Segments)
+}
+
+func TestNewBackupRequiredWal_Errors(t *testing.T) {
+	_, err := postgres.NewBackupRequiredWal("base_000000010000000000000003", postgres.BackupSentinelDto{})
+	assert.Error(t, err)
+
+	_, err = postgres.NewBackupRequiredWal("base_000000010000000000000003", newRequiredWalSentinel(100, 50))
+	assert.Error(t, err)
+
+	_, err = postgres.NewBackupRequiredWal("backup", newRequiredWalSentinel(50, 100))
+	assert.Error(t, err)
+}
+
+func TestBackupRequiredWal_CheckArchive(t *testing.T) {
+	walFolder := memory.NewFolder("wal_005/", memory.NewStorage())
+	for _, name := range []string{"000000010000000000000003.lz4", "000000010000000000000005.lz4"} {
+		assert.NoError(t, walFolder.PutObject(name, &bytes.Buffer{}))
+	}
+	segmentSize := postgres.WalSegmentSize
+	requiredWal, err := postgres.NewBackupRequiredWal("base_000000010000000000000003",
+		newRequiredWalSentinel(3*segmentSize, 5*segmentSize+1))
+	assert.NoError(t, err)
+
+	assert.NoError(t, requiredWal.CheckArchive(walFolder))
+	assert.Equal(t, []string{"000000010000000000000004"}, requiredWal.MissingSegments)
+
+	var plainText bytes.Buffer
+	assert.NoError(t, requiredWal.WritePlainText(&plainText))
+	assert.Contains(t, plainText.String(), "Start LSN: 0/3000000")
+	assert.Contains(t, plainText.String(), "Missing segments (1):\n000000010000000000000004\n")
+
+	var jsonOutput bytes.Buffer
+	assert.NoError(t, requiredWal.WriteJSON(&jsonOutput))
+	var decoded postgres.BackupRequiredWal
+	assert.NoError(t, json.Unmarshal(jsonOutput.Bytes(), &decoded))
+	assert.Equal(t, requiredWal, decoded)
+}
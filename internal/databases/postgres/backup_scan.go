@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"archive/tar"
+	"context"
+	"hash"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupScanReport lists the files of the backup failed the scan
+type BackupScanReport struct {
+	// ScannedFiles is the number of the tar entries read
+	ScannedFiles int
+	// MismatchedFiles are the files not matching the checksums stored in the FilesMetadata
+	MismatchedFiles []ChecksumMismatchError
+	// MissingFiles are the files listed in the FilesMetadata but absent from the tars
+	MissingFiles []string
+	// FailedTars maps the tars which could not be read to the end to the read errors
+	FailedTars map[string]error
+
+	mutex        sync.Mutex
+	scannedFiles map[string]bool
+}
+
+func newBackupScanReport() *BackupScanReport {
+	return &BackupScanReport{FailedTars: make(map[string]error), scannedFiles: make(map[string]bool)}
+}
+
+// Failed is true if the scan found any mismatched or missing files or unreadable tars
+func (report *BackupScanReport) Failed() bool {
+	return len(report.MismatchedFiles) > 0 || len(report.MissingFiles) > 0 || len(report.FailedTars) > 0
+}
+
+func (report *BackupScanReport) addScannedFile(fileName string) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	report.ScannedFiles++
+	report.scannedFiles[fileName] = true
+}
+
+func (report *BackupScanReport) addMismatchedFile(err ChecksumMismatchError) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	report.MismatchedFiles = append(report.MismatchedFiles, err)
+}
+
+// HandleBackupScan streams the tars of the backup through the decompression without writing to disk
+// and verifies the files against the FilesMetadata, the failed scan is fatal
+func HandleBackupScan(folder storage.Folder, backupName string, stopOnError bool) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to find the backup: %v", err)
+
+	report, err := ScanBackup(ToPgBackup(backup), stopOnError)
+	tracelog.ErrorLogger.FatalfOnError("Backup scan failed: %v", err)
+	for _, mismatch := range report.MismatchedFiles {
+		tracelog.WarningLogger.Printf("Checksum mismatch: %s", mismatch.FileName)
+	}
+	for _, fileName := range report.MissingFiles {
+		tracelog.WarningLogger.Printf("Missing file: %s", fileName)
+	}
+	for tarName, tarErr := range report.FailedTars {
+		tracelog.WarningLogger.Printf("Unreadable tar %s: %v", tarName, tarErr)
+	}
+	if report.Failed() {
+		tracelog.ErrorLogger.Fatalf("Backup %s scan found %d mismatched and %d missing files, %d unreadable tars",
+			backup.Name, len(report.MismatchedFiles), len(report.MissingFiles), len(report.FailedTars))
+	}
+	tracelog.InfoLogger.Printf("Backup %s scan found no problems in %d files", backup.Name, report.ScannedFiles)
+}
+
+// ScanBackup reads each tar member of the backup through the decryption and decompression,
+// comparing the regular files with the checksums stored in the FilesMetadata, no output files are opened.
+// The increments of the delta backup are read but not verified, since the checksums describe the whole files.
+// Once stopOnError is set, the first mismatched or missing file or unreadable tar is returned as the error,
+// otherwise all of them are collected in the report.
+func ScanBackup(backup Backup, stopOnError bool) (*BackupScanReport, error) {
+	sentinelDto, filesMetaDto, err := backup.GetSentinelAndFilesMetadata()
+	if err != nil {
+		return nil, err
+	}
+	tarsToExtract, pgControlKey, err := backup.getTarsToExtract(filesMetaDto, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if pgControlKey != "" {
+		tarsToExtract = append(tarsToExtract,
+			internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey))
+	}
+
+	tarInterpreter, err := newFileTarInterpreter("", sentinelDto, filesMetaDto, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	report := newBackupScanReport()
+	tarInterpreter.ScanReport, tarInterpreter.stopScanOnError = report, stopOnError
+
+	for _, tarToExtract := range tarsToExtract {
+		tracelog.DebugLogger.Printf("Scanning %s", tarToExtract.Path())
+		err = internal.ExtractAllWithContext(context.Background(), tarInterpreter,
+			[]internal.ReaderMaker{tarToExtract}, 1)
+		if err == nil {
+			continue
+		}
+		if stopOnError {
+			return report, err
+		}
+		report.FailedTars[tarToExtract.Path()] = err
+	}
+
+	if len(filesMetaDto.Files) == 0 {
+		tracelog.WarningLogger.Printf("Backup %s has no files metadata, the missing files are not detected", backup.Name)
+		return report, nil
+	}
+	for fileName, description := range filesMetaDto.Files {
+		if !description.IsSkipped && !report.scannedFiles[fileName] {
+			report.MissingFiles = append(report.MissingFiles, fileName)
+		}
+	}
+	sort.Strings(report.MissingFiles)
+	if stopOnError && len(report.MissingFiles) > 0 {
+		return report, errors.Errorf("file '%s' of the backup is missing from its tars", report.MissingFiles[0])
+	}
+	return report, nil
+}
+
+// scanEntry reads the tar entry without writing to disk, the regular file is verified against its checksum
+func (tarInterpreter *FileTarInterpreter) scanEntry(fileReader io.Reader, fileInfo *tar.Header) error {
+	if fileInfo.Typeflag == tar.TypeReg || fileInfo.Typeflag == tar.TypeRegA {
+		if err := tarInterpreter.scanRegularFile(fileReader, fileInfo); err != nil {
+			return err
+		}
+	}
+	tarInterpreter.ScanReport.addScannedFile(fileInfo.Name)
+	return nil
+}
+
+func (tarInterpreter *FileTarInterpreter) scanRegularFile(fileReader io.Reader, fileInfo *tar.Header) error {
+	var expectedChecksum *internal.FileChecksum
+	fileDescription, ok := tarInterpreter.FilesMetadata.Files[fileInfo.Name]
+	if ok && !(tarInterpreter.Sentinel.IsIncremental() && fileDescription.IsIncremented) {
+		expectedChecksum = fileDescription.Checksum
+	}
+
+	writer := io.Discard
+	var checksumHash hash.Hash
+	if expectedChecksum != nil {
+		var err error
+		checksumHash, err = internal.NewChecksumHash(expectedChecksum.Algorithm)
+		if err != nil {
+			return errors.Wrapf(err, "Interpret: failed to verify checksum of '%s'", fileInfo.Name)
+		}
+		writer = checksumHash
+	}
+	copyBuffer := tarInterpreter.getCopyBuffer(fileInfo.Size)
+	defer tarInterpreter.putCopyBuffer(copyBuffer)
+	if _, err := io.CopyBuffer(writer, fileReader, copyBuffer); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to read '%s'", fileInfo.Name)
+	}
+
+	if checksumHash == nil {
+		return nil
+	}
+	actualChecksum := internal.NewFileChecksum(expectedChecksum.Algorithm, checksumHash)
+	if actualChecksum.Value == expectedChecksum.Value {
+		return nil
+	}
+	mismatchErr := newChecksumMismatchError(fileInfo.Name, *expectedChecksum, actualChecksum.Value)
+	if tarInterpreter.stopScanOnError {
+		return mismatchErr
+	}
+	tarInterpreter.ScanReport.addMismatchedFile(mismatchErr)
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestScanBackup(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	dataDirectory := t.TempDir()
+	baseTime := time.Now().Add(-time.Hour)
+	writeFlattenTestFile(t, dataDirectory, "global/pg_control", []byte("pg_control"), baseTime)
+	writeFlattenTestFile(t, dataDirectory, "PG_VERSION", []byte("14\n"), baseTime)
+	writeFlattenTestFile(t, dataDirectory, "base/1/16384", []byte("relation"), baseTime)
+	startLSN := uint64(1)
+	files := pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenBaseBackupName,
+		BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &startLSN}, nil, nil)
+
+	backup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), flattenBaseBackupName)
+	report, err := ScanBackup(backup, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, report.Failed())
+	assert.GreaterOrEqual(t, report.ScannedFiles, len(files))
+
+	// the files metadata records the checksums, one of them is wrong, and the file absent from the tars
+	contentsHash := sha256.Sum256([]byte("14\n"))
+	checksum := &internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm,
+		Value: hex.EncodeToString(contentsHash[:])}
+	for _, fileName := range []string{"/PG_VERSION", "/base/1/16384"} {
+		description := files[fileName]
+		description.Checksum = checksum
+		files[fileName] = description
+	}
+	files["/base/1/16385"] = *internal.NewBackupFileDescription(false, false, baseTime)
+	var filesMetaDto FilesMetadataDto
+	filesMetaDto.Files = files
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName],
+		rootFolder.GetSubFolder(utility.BaseBackupPath))
+	assert.NoError(t, uploadBackupDto(uploader, getFilesMetadataPath(flattenBaseBackupName), filesMetaDto))
+
+	report, err = ScanBackup(backup, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, report.Failed())
+	if assert.Len(t, report.MismatchedFiles, 1) {
+		assert.Equal(t, "/base/1/16384", report.MismatchedFiles[0].FileName)
+	}
+	assert.Equal(t, []string{"/base/1/16385"}, report.MissingFiles)
+	assert.Empty(t, report.FailedTars)
+
+	_, err = ScanBackup(backup, true)
+	var mismatchErr ChecksumMismatchError
+	assert.True(t, errors.As(err, &mismatchErr))
+}
@@ -32,6 +32,9 @@ type BackupSentinelDto struct {
 	UserData interface{} `json:"UserData,omitempty"`
 
 	FilesMetadataDisabled bool `json:"FilesMetadataDisabled,omitempty"`
+
+	// Databases maps the names of the backed up databases to their OIDs
+	Databases map[string]uint32 `json:"Databases,omitempty"`
 }
 
 func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSentinelDto {
@@ -57,6 +60,7 @@ func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSent
 	sentinel.UncompressedSize = bh.curBackupInfo.uncompressedSize
 	sentinel.CompressedSize = bh.curBackupInfo.compressedSize
 	sentinel.FilesMetadataDisabled = bh.arguments.withoutFilesMetadata
+	sentinel.Databases = bh.pgInfo.databases
 	return sentinel
 }
 
@@ -121,6 +125,8 @@ func (dto *BackupSentinelDto) IsIncremental() (isIncremental bool) {
 type FilesMetadataDto struct {
 	Files       internal.BackupFileList `json:"Files,omitempty"`
 	TarFileSets map[string][]string     `json:"TarFileSets,omitempty"`
+	// ImageLayout places the regular files into the preallocated image restored by the WriterAtTarInterpreter
+	ImageLayout map[string]ImageExtent `json:"ImageLayout,omitempty"`
 }
 
 func NewFilesMetadataDto(files internal.BackupFileList, tarFileSets TarFileSets) FilesMetadataDto {
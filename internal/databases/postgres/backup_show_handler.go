@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupShowDetails is the sentinel and the metadata of the backup printed by backup-show
+type BackupShowDetails struct {
+	BackupName    string `json:"BackupName"`
+	IsIncremental bool   `json:"IsIncremental"`
+	// Duration is empty if the backup has no metadata
+	Duration string            `json:"Duration,omitempty"`
+	Sentinel BackupSentinelDto `json:"Sentinel"`
+	// Metadata is nil if the metadata of the backup can not be read, e.g. it is uploaded by the older WAL-G
+	Metadata *ExtendedMetadataDto `json:"Metadata,omitempty"`
+}
+
+// HandleBackupShow prints the sentinel and the metadata of the backup by its name or LATEST
+// as JSON or as the table of their fields
+func HandleBackupShow(folder storage.Folder, backupName string, output io.Writer, asJSON, pretty bool) error {
+	baseBackup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	if err != nil {
+		return err
+	}
+	backup := NewBackup(baseBackup.Folder, baseBackup.Name)
+	sentinel, err := backup.getSentinel(backup.FetchExistingSentinel)
+	if err != nil {
+		return err
+	}
+
+	details := BackupShowDetails{BackupName: backup.Name, IsIncremental: sentinel.IncrementFrom != nil, Sentinel: sentinel}
+	if meta, err := backup.FetchMeta(); err == nil {
+		details.Metadata = &meta
+		if !meta.StartTime.IsZero() && !meta.FinishTime.IsZero() {
+			details.Duration = meta.FinishTime.Sub(meta.StartTime).String()
+		}
+	} else {
+		tracelog.WarningLogger.Printf("Failed to read the metadata of backup '%s': %v", backup.Name, err)
+	}
+	return internal.WriteBackupDetails(details, details.fields(), output, asJSON, pretty)
+}
+
+//nolint:gocritic,hugeParam
+func (details BackupShowDetails) fields() []internal.BackupDetailsField {
+	sentinel := details.Sentinel
+	fields := []internal.BackupDetailsField{
+		{Name: "Name", Value: details.BackupName},
+		{Name: "PG version", Value: strconv.Itoa(sentinel.PgVersion)},
+		{Name: "Start LSN", Value: formatShowOptionalUint(sentinel.BackupStartLSN)},
+		{Name: "Finish LSN", Value: formatShowOptionalUint(sentinel.BackupFinishLSN)},
+		{Name: "System identifier", Value: formatShowOptionalUint(sentinel.SystemIdentifier)},
+		{Name: "Incremental", Value: strconv.FormatBool(details.IsIncremental)},
+	}
+	if details.IsIncremental {
+		fields = append(fields,
+			internal.BackupDetailsField{Name: "Increment from", Value: *sentinel.IncrementFrom},
+			internal.BackupDetailsField{Name: "Increment from LSN", Value: formatShowOptionalUint(sentinel.IncrementFromLSN)},
+			internal.BackupDetailsField{Name: "Increment full backup", Value: formatShowOptionalString(sentinel.IncrementFullName)})
+		if sentinel.IncrementCount != nil {
+			fields = append(fields, internal.BackupDetailsField{Name: "Increment count",
+				Value: strconv.Itoa(*sentinel.IncrementCount)})
+		}
+	}
+	if meta := details.Metadata; meta != nil {
+		fields = append(fields,
+			internal.BackupDetailsField{Name: "Start time", Value: internal.FormatDetailsTime(meta.StartTime)},
+			internal.BackupDetailsField{Name: "Finish time", Value: internal.FormatDetailsTime(meta.FinishTime)},
+			internal.BackupDetailsField{Name: "Duration", Value: details.Duration},
+			internal.BackupDetailsField{Name: "Hostname", Value: meta.Hostname},
+			internal.BackupDetailsField{Name: "Data directory", Value: meta.DataDir},
+			internal.BackupDetailsField{Name: "Permanent", Value: strconv.FormatBool(meta.IsPermanent)})
+	}
+	databases := make([]string, 0, len(sentinel.Databases))
+	for name, oid := range sentinel.Databases {
+		databases = append(databases, name+" ("+strconv.FormatUint(uint64(oid), 10)+")")
+	}
+	sort.Strings(databases)
+	return append(fields,
+		internal.BackupDetailsField{Name: "Uncompressed size", Value: internal.FormatDetailsSize(sentinel.UncompressedSize)},
+		internal.BackupDetailsField{Name: "Compressed size", Value: internal.FormatDetailsSize(sentinel.CompressedSize)},
+		internal.BackupDetailsField{Name: "Files metadata disabled", Value: strconv.FormatBool(sentinel.FilesMetadataDisabled)},
+		internal.BackupDetailsField{Name: "Databases", Value: strings.Join(databases, ", ")},
+		internal.BackupDetailsField{Name: "User data", Value: internal.FormatDetailsJSON(sentinel.UserData)})
+}
+
+func formatShowOptionalUint(value *uint64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatUint(*value, 10)
+}
+
+func formatShowOptionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func uploadShowTestBackup(t *testing.T, folder storage.Folder, name string, sentinel, meta interface{}) {
+	backup := internal.NewBackup(folder.GetSubFolder(utility.BaseBackupPath), name)
+	assert.NoError(t, backup.UploadSentinel(sentinel))
+	if meta != nil {
+		assert.NoError(t, backup.UploadMetadata(meta))
+	}
+}
+
+func TestHandleBackupShow(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	startLSN, finishLSN, fromLSN := uint64(100), uint64(200), uint64(50)
+	from, fullName, count := "base_000000010000000000000001", "base_000000010000000000000001", 1
+	startTime := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	uploadShowTestBackup(t, folder, "base_000000010000000000000002", postgres.BackupSentinelDto{
+		BackupStartLSN: &startLSN, BackupFinishLSN: &finishLSN, PgVersion: 140000,
+		IncrementFromLSN: &fromLSN, IncrementFrom: &from, IncrementFullName: &fullName, IncrementCount: &count,
+		UncompressedSize: 1024, UserData: map[string]string{"owner": "ops"},
+	}, postgres.ExtendedMetadataDto{StartTime: startTime, FinishTime: startTime.Add(90 * time.Second), Hostname: "db1"})
+
+	output := &bytes.Buffer{}
+	assert.NoError(t, postgres.HandleBackupShow(folder, "base_000000010000000000000002", output, false, false))
+	for _, expected := range []string{"base_000000010000000000000001", "Incremental", "true", "1m30s", "db1",
+		`{"owner":"ops"}`, "140000"} {
+		assert.Contains(t, output.String(), expected)
+	}
+
+	output.Reset()
+	assert.NoError(t, postgres.HandleBackupShow(folder, internal.LatestString, output, true, false))
+	var details postgres.BackupShowDetails
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &details))
+	assert.True(t, details.IsIncremental)
+	assert.Equal(t, "1m30s", details.Duration)
+	assert.Equal(t, from, *details.Sentinel.IncrementFrom)
+	assert.Equal(t, "db1", details.Metadata.Hostname)
+}
+
+func TestHandleBackupShow_MissingOrCorruptSentinel(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	err := postgres.HandleBackupShow(folder, "base_000000010000000000000002", &bytes.Buffer{}, false, false)
+	assert.IsType(t, internal.BackupNonExistenceError{}, err)
+
+	assert.NoError(t, folder.GetSubFolder(utility.BaseBackupPath).PutObject(
+		internal.SentinelNameFromBackup("base_000000010000000000000002"), bytes.NewBufferString("{broken")))
+	err = postgres.HandleBackupShow(folder, "base_000000010000000000000002", &bytes.Buffer{}, false, false)
+	assert.IsType(t, internal.CorruptSentinelError{}, err)
+}
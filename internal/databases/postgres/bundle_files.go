@@ -18,6 +18,8 @@ type BundleFiles interface {
 	AddSkippedFile(tarHeader *tar.Header, fileInfo os.FileInfo)
 	AddFile(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool)
 	AddFileDescription(name string, backupFileDescription internal.BackupFileDescription)
+	// AddFileChecksum sets the checksum of the packed contents to the added file
+	AddFileChecksum(name string, checksum internal.FileChecksum)
 	AddFileWithCorruptBlocks(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool,
 		corruptedBlocks []uint32, storeAllBlocks bool)
 	GetUnderlyingMap() *sync.Map
@@ -34,13 +36,18 @@ func (files *RegularBundleFiles) AddSkippedFile(tarHeader *tar.Header, fileInfo
 
 func (files *RegularBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool) {
 	files.AddFileDescription(tarHeader.Name,
-		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()})
+		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime(),
+			FileMode: tarHeader.Mode})
 }
 
 func (files *RegularBundleFiles) AddFileDescription(name string, backupFileDescription internal.BackupFileDescription) {
 	files.Store(name, backupFileDescription)
 }
 
+func (files *RegularBundleFiles) AddFileChecksum(name string, checksum internal.FileChecksum) {
+	addFileChecksum(&files.Map, name, checksum)
+}
+
 func (files *RegularBundleFiles) AddFileWithCorruptBlocks(tarHeader *tar.Header, fileInfo os.FileInfo,
 	isIncremented bool, corruptedBlocks []uint32, storeAllBlocks bool) {
 	fileDescription := internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()}
@@ -52,6 +59,17 @@ func (files *RegularBundleFiles) GetUnderlyingMap() *sync.Map {
 	return &files.Map
 }
 
+// addFileChecksum updates the description of the file added by the same goroutine
+func addFileChecksum(files *sync.Map, name string, checksum internal.FileChecksum) {
+	value, ok := files.Load(name)
+	if !ok {
+		return
+	}
+	fileDescription := value.(internal.BackupFileDescription)
+	fileDescription.Checksum = &checksum
+	files.Store(name, fileDescription)
+}
+
 func newStatBundleFiles(fileStat RelFileStatistics) *StatBundleFiles {
 	return &StatBundleFiles{fileStats: fileStat}
 }
@@ -86,13 +104,17 @@ func (files *StatBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInf
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
 	files.AddFileDescription(tarHeader.Name,
 		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented,
-			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount})
+			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount, FileMode: tarHeader.Mode})
 }
 
 func (files *StatBundleFiles) AddFileDescription(name string, backupFileDescription internal.BackupFileDescription) {
 	files.Store(name, backupFileDescription)
 }
 
+func (files *StatBundleFiles) AddFileChecksum(name string, checksum internal.FileChecksum) {
+	addFileChecksum(&files.Map, name, checksum)
+}
+
 func (files *StatBundleFiles) GetUnderlyingMap() *sync.Map {
 	return &files.Map
 }
@@ -109,6 +131,9 @@ func (files *NopBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInfo
 func (files *NopBundleFiles) AddFileDescription(name string, backupFileDescription internal.BackupFileDescription) {
 }
 
+func (files *NopBundleFiles) AddFileChecksum(name string, checksum internal.FileChecksum) {
+}
+
 func (files *NopBundleFiles) AddFileWithCorruptBlocks(tarHeader *tar.Header, fileInfo os.FileInfo,
 	isIncremented bool, corruptedBlocks []uint32, storeAllBlocks bool) {
 }
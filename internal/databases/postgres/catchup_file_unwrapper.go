@@ -36,7 +36,8 @@ func (u *CatchupFileUnwrapper) UnwrapNewFile(reader io.Reader, header *tar.Heade
 		}
 		return NewCreatedFromIncrementResult(missingBlockCount), nil
 	}
-	err := WriteLocalFile(reader, header, file, fsync)
+	err := WriteLocalFile(reader, header, file, fsync, u.options.expectedChecksum, u.options.copyBuffer,
+		u.options.contentFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +63,8 @@ func (u *CatchupFileUnwrapper) UnwrapExistingFile(reader io.Reader, header *tar.
 	if err != nil {
 		return nil, err
 	}
-	err = WriteLocalFile(reader, header, file, fsync)
+	err = WriteLocalFile(reader, header, file, fsync, u.options.expectedChecksum, u.options.copyBuffer,
+		u.options.contentFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"archive/tar"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// idMapping maps count ids since hostID to the ones since containerID, like a line of /proc/<pid>/uid_map
+type idMapping struct {
+	hostID      int
+	containerID int
+	count       int
+}
+
+// idMap translates the uid or gid recorded in the tar to the target user namespace,
+// the ids not covered by the mappings are kept as is
+type idMap []idMapping
+
+// parseIDMap parses the comma-separated '<host id>:<container id>[:<count>]' mappings, the count defaults to 1
+func parseIDMap(value string) (idMap, error) {
+	var mappings idMap
+	for _, mappingStr := range strings.Split(value, ",") {
+		mappingStr = strings.TrimSpace(mappingStr)
+		if mappingStr == "" {
+			continue
+		}
+		fields := strings.Split(mappingStr, ":")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, errors.Errorf("invalid id mapping '%s', expected '<host id>:<container id>[:<count>]'",
+				mappingStr)
+		}
+		ids := []int{0, 0, 1}
+		for i, field := range fields {
+			id, err := strconv.Atoi(field)
+			if err != nil || id < 0 {
+				return nil, errors.Errorf("invalid id mapping '%s': '%s' is not a non-negative integer", mappingStr, field)
+			}
+			ids[i] = id
+		}
+		if ids[2] == 0 {
+			return nil, errors.Errorf("invalid id mapping '%s': the count should be positive", mappingStr)
+		}
+		mappings = append(mappings, idMapping{hostID: ids[0], containerID: ids[1], count: ids[2]})
+	}
+	return mappings, nil
+}
+
+func (mappings idMap) mapID(id int) int {
+	for _, mapping := range mappings {
+		if id >= mapping.hostID && id < mapping.hostID+mapping.count {
+			return mapping.containerID + id - mapping.hostID
+		}
+	}
+	return id
+}
+
+// restoreOwnership changes the owner of the extracted file, directory or symlink to the uid and gid
+// recorded in the header translated by the id maps. The symlinks themselves are changed, not their targets.
+// The failure (e.g. lack of privileges) is logged with a warning unless the strict mode is on.
+func (tarInterpreter *FileTarInterpreter) restoreOwnership(targetPath string, header *tar.Header) error {
+	if !tarInterpreter.chownEnabled {
+		return nil
+	}
+	uid, gid := tarInterpreter.uidMap.mapID(header.Uid), tarInterpreter.gidMap.mapID(header.Gid)
+	err := lchown(targetPath, uid, gid)
+	if err == nil {
+		return nil
+	}
+	if tarInterpreter.strictChown {
+		return errors.Wrapf(err, "Interpret: failed to change owner of '%s' to %d:%d", targetPath, uid, gid)
+	}
+	if errors.Is(err, os.ErrPermission) {
+		tracelog.WarningLogger.Printf("Not permitted to change owner of '%s' to %d:%d, keeping the current one",
+			targetPath, uid, gid)
+		return nil
+	}
+	tracelog.WarningLogger.Printf("Failed to change owner of '%s' to %d:%d: %v", targetPath, uid, gid, err)
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package postgres
+
+import "golang.org/x/sys/unix"
+
+func lchown(path string, uid, gid int) error {
+	return unix.Lchown(path, uid, gid)
+}
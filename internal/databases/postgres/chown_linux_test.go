@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestParseIDMap(t *testing.T) {
+	idMap, err := parseIDMap("0:100000:65536, 70000:1000")
+	assert.NoError(t, err)
+	assert.Equal(t, 100000, idMap.mapID(0))
+	assert.Equal(t, 100026, idMap.mapID(26))
+	assert.Equal(t, 1000, idMap.mapID(70000))
+	assert.Equal(t, 70001, idMap.mapID(70001))
+
+	empty, err := parseIDMap("")
+	assert.NoError(t, err)
+	assert.Equal(t, 26, empty.mapID(26))
+
+	for _, invalid := range []string{"1", "1:2:3:4", "a:1", "1:-2", "1:2:0"} {
+		_, err := parseIDMap(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestInterpret_RestoresMappedOwnership(t *testing.T) {
+	uid, gid := os.Getuid(), os.Getgid()
+	if os.Geteuid() == 0 {
+		uid, gid = 12345, 12346
+	}
+	viper.Set(internal.RestoreChownSetting, true)
+	viper.Set(internal.RestoreChownStrictSetting, true)
+	viper.Set(internal.RestoreChownUIDMapSetting, "70000:"+strconv.Itoa(uid))
+	viper.Set(internal.RestoreChownGIDMapSetting, "70000:"+strconv.Itoa(gid))
+	defer func() {
+		for _, setting := range []string{internal.RestoreChownSetting, internal.RestoreChownStrictSetting,
+			internal.RestoreChownUIDMapSetting, internal.RestoreChownGIDMapSetting} {
+			viper.Set(setting, nil)
+		}
+	}()
+
+	dir := t.TempDir()
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	headers := []*tar.Header{
+		{Name: "dir", Typeflag: tar.TypeDir, Mode: 0700, Uid: 70000, Gid: 70000},
+		{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0600, Size: 4, Uid: 70000, Gid: 70000},
+		{Name: "dir/link", Linkname: "file", Typeflag: tar.TypeSymlink, Uid: 70000, Gid: 70000},
+	}
+	for _, header := range headers {
+		assert.NoError(t, tarInterpreter.Interpret(strings.NewReader("data"), header))
+		info, err := os.Lstat(filepath.Join(dir, header.Name))
+		assert.NoError(t, err)
+		stat := info.Sys().(*syscall.Stat_t)
+		assert.Equal(t, uint32(uid), stat.Uid, header.Name)
+		assert.Equal(t, uint32(gid), stat.Gid, header.Name)
+	}
+}
+
+func TestRestoreOwnership_NotPermitted(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("the owner can be changed by root")
+	}
+	path := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(path, []byte("data"), 0600))
+	header := &tar.Header{Name: "file", Uid: 0, Gid: 0}
+
+	assert.NoError(t, (&FileTarInterpreter{chownEnabled: true}).restoreOwnership(path, header))
+	assert.Error(t, (&FileTarInterpreter{chownEnabled: true, strictChown: true}).restoreOwnership(path, header))
+}
+
+func TestRestoreOwnership_Disabled(t *testing.T) {
+	assert.NoError(t, (&FileTarInterpreter{strictChown: true}).restoreOwnership("missing", &tar.Header{}))
+}
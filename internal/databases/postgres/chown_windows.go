@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package postgres
+
+import "github.com/pkg/errors"
+
+func lchown(path string, uid, gid int) error {
+	return errors.New("the file ownership is not restored on Windows")
+}
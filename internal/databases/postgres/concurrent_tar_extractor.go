@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// extractTars extracts the backup tars by ExtractAllInOrder if the deterministic order is requested,
+// by ExtractTarsConcurrently if the extraction concurrency is configured, by ExtractAll with its retries otherwise
+func extractTars(tarInterpreter *FileTarInterpreter, files []internal.ReaderMaker) error {
+	if viper.GetBool(internal.RestoreInOrderSetting) {
+		return internal.ExtractAllInOrder(tarInterpreter, files)
+	}
+	if !viper.IsSet(internal.TarExtractConcurrencySetting) {
+		return internal.ExtractAll(tarInterpreter, files)
+	}
+	concurrency, err := internal.GetMaxConcurrency(internal.TarExtractConcurrencySetting)
+	if err != nil {
+		return err
+	}
+	return ExtractTarsConcurrently(tarInterpreter, files, concurrency)
+}
+
+// ExtractTarsConcurrently extracts the tars by at most concurrency workers sharing the tarInterpreter,
+// so its UnwrapResult accumulates the results of all of them. The entries depending on the other tars are ordered:
+// the directories exist before the files in them are written and the hardlinks are created once their sources
+// are extracted. The first failure aborts the rest of the workers.
+func ExtractTarsConcurrently(tarInterpreter *FileTarInterpreter, files []internal.ReaderMaker, concurrency int) error {
+	ctx := tarInterpreter.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	orderedInterpreter := newOrderedTarInterpreter(tarInterpreter)
+	if err := internal.ExtractAllWithContext(ctx, orderedInterpreter, files, concurrency); err != nil {
+		return err
+	}
+	return orderedInterpreter.finish(ctx)
+}
+
+// orderedTarInterpreter postpones the tar entries which can not be interpreted before the entries of the other tars:
+// the directory modes are applied once all the files are extracted, so the restrictive modes do not block them,
+// and the hardlinks wait for their sources
+type orderedTarInterpreter struct {
+	tarInterpreter *FileTarInterpreter
+
+	deferredMutex sync.Mutex
+	deferredDirs  []*tar.Header
+	deferredLinks []*tar.Header
+}
+
+func newOrderedTarInterpreter(tarInterpreter *FileTarInterpreter) *orderedTarInterpreter {
+	return &orderedTarInterpreter{tarInterpreter: tarInterpreter}
+}
+
+func (interpreter *orderedTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	return interpreter.InterpretContext(interpreter.tarInterpreter.Ctx, reader, header)
+}
+
+func (interpreter *orderedTarInterpreter) InterpretContext(ctx context.Context, reader io.Reader, header *tar.Header) error {
+	if interpreter.tarInterpreter.DryRun {
+		return interpreter.tarInterpreter.InterpretContext(ctx, reader, header)
+	}
+	header, err := resolvePAXHeader(header)
+	if err != nil {
+		return err
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		targetPath, err := interpreter.tarInterpreter.getTargetPath(header.Name)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(targetPath, 0700); err != nil {
+			return errors.Wrapf(err, "Interpret: failed to create all directories in %s", targetPath)
+		}
+		interpreter.deferEntry(&interpreter.deferredDirs, header)
+		return nil
+	case tar.TypeLink:
+		isReady, err := interpreter.isLinkSourceExtracted(header)
+		if err != nil {
+			return err
+		}
+		if !isReady {
+			interpreter.deferEntry(&interpreter.deferredLinks, header)
+			return nil
+		}
+	}
+	return interpreter.tarInterpreter.InterpretContext(ctx, reader, header)
+}
+
+func (interpreter *orderedTarInterpreter) deferEntry(entries *[]*tar.Header, header *tar.Header) {
+	interpreter.deferredMutex.Lock()
+	defer interpreter.deferredMutex.Unlock()
+	*entries = append(*entries, header)
+}
+
+func (interpreter *orderedTarInterpreter) isLinkSourceExtracted(header *tar.Header) (bool, error) {
+	linkSourcePath, err := interpreter.tarInterpreter.getLinkSourcePath(header)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Lstat(linkSourcePath)
+	return err == nil, nil
+}
+
+// finish interprets the deferred entries once all the tars are extracted: the hardlinks first,
+// then the directories from the deepest ones
+func (interpreter *orderedTarInterpreter) finish(ctx context.Context) error {
+	links := interpreter.deferredLinks
+	for len(links) > 0 {
+		// the hardlink source may be another deferred hardlink
+		var pendingLinks []*tar.Header
+		for _, link := range links {
+			isReady, err := interpreter.isLinkSourceExtracted(link)
+			if err != nil {
+				return err
+			}
+			if !isReady {
+				pendingLinks = append(pendingLinks, link)
+				continue
+			}
+			if err = interpreter.tarInterpreter.InterpretContext(ctx, &bytes.Buffer{}, link); err != nil {
+				return err
+			}
+		}
+		if len(pendingLinks) == len(links) {
+			return errors.Errorf("Interpret: source '%s' of hardlink '%s' is not extracted",
+				pendingLinks[0].Linkname, pendingLinks[0].Name)
+		}
+		links = pendingLinks
+	}
+
+	dirs := interpreter.deferredDirs
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return strings.Count(strings.Trim(dirs[i].Name, "/"), "/") > strings.Count(strings.Trim(dirs[j].Name, "/"), "/")
+	})
+	for _, dir := range dirs {
+		if err := interpreter.tarInterpreter.InterpretContext(ctx, &bytes.Buffer{}, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type testTarEntry struct {
+	header  tar.Header
+	content string
+}
+
+func putTestTar(t *testing.T, folder storage.Folder, name string, entries ...testTarEntry) internal.ReaderMaker {
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	for _, entry := range entries {
+		header := entry.header
+		header.Size = int64(len(entry.content))
+		assert.NoError(t, tarWriter.WriteHeader(&header))
+		_, err := tarWriter.Write([]byte(entry.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, folder.PutObject(name, &archive))
+	return internal.NewStorageReaderMaker(folder, name)
+}
+
+func TestExtractTarsConcurrently_OrdersDependentEntries(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	files := []internal.ReaderMaker{
+		putTestTar(t, folder, "part_1.tar",
+			testTarEntry{header: tar.Header{Name: "/readonly", Typeflag: tar.TypeDir, Mode: 0500}},
+			testTarEntry{header: tar.Header{Name: "/base/1/link_of_link", Typeflag: tar.TypeLink, Linkname: "/base/1/link"}},
+			testTarEntry{header: tar.Header{Name: "/base/1/link", Typeflag: tar.TypeLink, Linkname: "/base/1/file"}}),
+		putTestTar(t, folder, "part_2.tar",
+			testTarEntry{header: tar.Header{Name: "/readonly/file", Typeflag: tar.TypeReg, Mode: 0600}, content: "readonly"},
+			testTarEntry{header: tar.Header{Name: "/base/1/file", Typeflag: tar.TypeReg, Mode: 0600}, content: "file"}),
+	}
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+
+	err := postgres.ExtractTarsConcurrently(tarInterpreter, files, 1)
+	assert.NoError(t, err)
+
+	dirInfo, err := os.Stat(path.Join(tarInterpreter.DBDataDirectory, "readonly"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0500), dirInfo.Mode().Perm())
+	data, err := os.ReadFile(path.Join(tarInterpreter.DBDataDirectory, "readonly/file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "readonly", string(data))
+
+	fileInfo, err := os.Stat(path.Join(tarInterpreter.DBDataDirectory, "base/1/file"))
+	assert.NoError(t, err)
+	for _, link := range []string{"base/1/link", "base/1/link_of_link"} {
+		linkInfo, err := os.Stat(path.Join(tarInterpreter.DBDataDirectory, link))
+		assert.NoError(t, err)
+		assert.True(t, os.SameFile(fileInfo, linkInfo))
+	}
+	assert.NoError(t, os.Chmod(path.Join(tarInterpreter.DBDataDirectory, "readonly"), 0700))
+}
+
+func TestExtractTarsConcurrently_Fails(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+
+	files := []internal.ReaderMaker{putTestTar(t, folder, "part_1.tar",
+		testTarEntry{header: tar.Header{Name: "/base/1/link", Typeflag: tar.TypeLink, Linkname: "/base/1/missing"}})}
+	err := postgres.ExtractTarsConcurrently(tarInterpreter, files, 2)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "/base/1/link")
+
+	files = []internal.ReaderMaker{
+		internal.NewStorageReaderMaker(folder, "missing.tar"),
+		putTestTar(t, folder, "part_2.tar",
+			testTarEntry{header: tar.Header{Name: "/base/1/file", Typeflag: tar.TypeReg, Mode: 0600}, content: "file"}),
+	}
+	err = postgres.ExtractTarsConcurrently(tarInterpreter, files, 1)
+	assert.Error(t, err)
+	_, err = os.Stat(path.Join(tarInterpreter.DBDataDirectory, "base/1/file"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"io"
+	"path"
+	"strings"
+)
+
+// ContentFilter transforms the contents of the extracted file, e.g. decrypts the files
+// encrypted apart from the archive. The returned reader is written to disk instead of the original one.
+type ContentFilter func(name string, reader io.Reader) (io.Reader, error)
+
+// NewNameMatchingContentFilter applies the filter to the files with names matching the path.Match pattern,
+// the leading '/' of the tar entry name is ignored. The other files are passed through unchanged.
+func NewNameMatchingContentFilter(pattern string, filter ContentFilter) ContentFilter {
+	pattern = strings.TrimPrefix(pattern, "/")
+	return func(name string, reader io.Reader) (io.Reader, error) {
+		matched, err := path.Match(pattern, strings.TrimPrefix(name, "/"))
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return reader, nil
+		}
+		return filter(name, reader)
+	}
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import "sync"
+
+// minCopyBufferSize is the smallest copy buffer, it is allocated for the tiny and empty files
+const minCopyBufferSize = 512
+
+// copyBufferPool reuses the copy buffers of the configured size between the extracted files.
+// The files smaller than the buffer get their own buffer of the file size, so they do not hold the large ones.
+type copyBufferPool struct {
+	size    int
+	buffers sync.Pool
+}
+
+func newCopyBufferPool(size int) *copyBufferPool {
+	if size <= 0 {
+		return nil
+	}
+	return &copyBufferPool{size: size}
+}
+
+// getCopyBuffer returns the buffer to extract the file of the given size,
+// nil if the buffer size is not configured and the default io.Copy buffer should be used
+func (tarInterpreter *FileTarInterpreter) getCopyBuffer(fileSize int64) []byte {
+	pool := tarInterpreter.copyBuffers
+	if pool == nil {
+		return nil
+	}
+	if fileSize < int64(pool.size) {
+		if fileSize < minCopyBufferSize {
+			fileSize = minCopyBufferSize
+		}
+		return make([]byte, fileSize)
+	}
+	if buffer, ok := pool.buffers.Get().(*[]byte); ok {
+		return *buffer
+	}
+	return make([]byte, pool.size)
+}
+
+// putCopyBuffer returns the full sized buffer to the pool
+func (tarInterpreter *FileTarInterpreter) putCopyBuffer(buffer []byte) {
+	pool := tarInterpreter.copyBuffers
+	if pool == nil || len(buffer) != pool.size {
+		return
+	}
+	pool.buffers.Put(&buffer)
+}
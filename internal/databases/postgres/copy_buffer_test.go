@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCopyBuffer(t *testing.T) {
+	tarInterpreter := &FileTarInterpreter{copyBuffers: newCopyBufferPool(1 << 20)}
+
+	assert.Len(t, tarInterpreter.getCopyBuffer(0), minCopyBufferSize)
+	assert.Len(t, tarInterpreter.getCopyBuffer(4096), 4096)
+	buffer := tarInterpreter.getCopyBuffer(16 << 20)
+	assert.Len(t, buffer, 1<<20)
+	tarInterpreter.putCopyBuffer(buffer)
+	tarInterpreter.putCopyBuffer(make([]byte, 4096))
+	assert.Len(t, tarInterpreter.getCopyBuffer(1<<20), 1<<20)
+
+	assert.Nil(t, (&FileTarInterpreter{copyBuffers: newCopyBufferPool(0)}).getCopyBuffer(16<<20))
+}
+
+func TestInterpretWithCopyBuffer(t *testing.T) {
+	tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	tarInterpreter.copyBuffers = newCopyBufferPool(1000)
+
+	for name, content := range map[string][]byte{"small": []byte("small"), "large": bytes.Repeat([]byte("large"), 1000)} {
+		err := tarInterpreter.Interpret(bytes.NewReader(content), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     int64(len(content)),
+		})
+		assert.NoError(t, err)
+		restored, err := os.ReadFile(filepath.Join(tarInterpreter.DBDataDirectory, name))
+		assert.NoError(t, err)
+		assert.Equal(t, content, restored)
+	}
+}
+
+// onlyReader hides the io.WriterTo of the source, so the copy goes through the buffer like the tar reader does
+type onlyReader struct {
+	reader *bytes.Reader
+}
+
+func (reader onlyReader) Read(p []byte) (int, error) {
+	return reader.reader.Read(p)
+}
+
+func BenchmarkCopyToSparseFile(b *testing.B) {
+	content := bytes.Repeat([]byte{1}, 64<<20)
+	for _, bufferSize := range []int{32 << 10, 1 << 20, 4 << 20} {
+		b.Run(fmt.Sprintf("buffer_%dKB", bufferSize>>10), func(b *testing.B) {
+			buffer := make([]byte, bufferSize)
+			path := filepath.Join(b.TempDir(), "file")
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err = copyToSparseFile(file, onlyReader{bytes.NewReader(content)}, buffer); err != nil {
+					b.Fatal(err)
+				}
+				_ = file.Close()
+			}
+		})
+	}
+}
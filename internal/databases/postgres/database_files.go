@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// FirstNormalObjectID is the first OID assigned to user objects,
+// the databases with lower OIDs (template1, template0, postgres) are created by initdb
+const FirstNormalObjectID = 16384
+
+type UnknownDatabaseError struct {
+	error
+}
+
+func NewUnknownDatabaseError(database string, databases map[string]uint32) UnknownDatabaseError {
+	if len(databases) == 0 {
+		return UnknownDatabaseError{errors.Errorf("database '%s' is not found: "+
+			"backup has no list of databases, it was probably made by an older WAL-G version", database)}
+	}
+	available := make([]string, 0, len(databases))
+	for name, oid := range databases {
+		available = append(available, fmt.Sprintf("%s (%d)", name, oid))
+	}
+	sort.Strings(available)
+	return UnknownDatabaseError{errors.Errorf("database '%s' is not found in backup, available databases: %s",
+		database, strings.Join(available, ", "))}
+}
+
+func (err UnknownDatabaseError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetDatabaseFilesToUnwrap computes the files needed for a consistent restore of the given databases only:
+// the requested and system database directories in base and pg_tblspc, and all the files outside of them.
+// The databases are specified by names or OIDs and must be listed in the sentinel.
+func GetDatabaseFilesToUnwrap(sentinel BackupSentinelDto, filesMeta FilesMetadataDto,
+	databases []string) (map[string]bool, error) {
+	if len(filesMeta.Files) == 0 {
+		return nil, errors.New("partial restore requires files metadata, which is missing in the backup")
+	}
+
+	selectedOids := make(map[uint64]bool, len(databases))
+	for _, database := range databases {
+		oid, err := findDatabaseOid(sentinel.Databases, database)
+		if err != nil {
+			return nil, err
+		}
+		selectedOids[oid] = true
+	}
+
+	filesToUnwrap := make(map[string]bool)
+	for file := range filesMeta.Files {
+		if isDatabaseFileNeeded(file, selectedOids) {
+			filesToUnwrap[file] = true
+		}
+	}
+	for utilityFilePath := range UtilityFilePaths {
+		filesToUnwrap[utilityFilePath] = true
+	}
+	return filesToUnwrap, nil
+}
+
+func findDatabaseOid(databases map[string]uint32, database string) (uint64, error) {
+	if oid, ok := databases[database]; ok {
+		return uint64(oid), nil
+	}
+	if oid, err := strconv.ParseUint(database, 10, 32); err == nil {
+		for _, knownOid := range databases {
+			if uint64(knownOid) == oid {
+				return oid, nil
+			}
+		}
+	}
+	return 0, NewUnknownDatabaseError(database, databases)
+}
+
+// isDatabaseFileNeeded checks if the file belongs to the selected or system database, or to no database at all
+func isDatabaseFileNeeded(file string, selectedOids map[uint64]bool) bool {
+	parts := strings.Split(strings.TrimPrefix(file, "/"), "/")
+	var oidPart string
+	switch {
+	case parts[0] == DefaultTablespace && len(parts) > 2:
+		// base/<database oid>/<relfilenode>
+		oidPart = parts[1]
+	case parts[0] == TablespaceFolder && len(parts) > 4:
+		// pg_tblspc/<tablespace oid>/<version directory>/<database oid>/<relfilenode>
+		oidPart = parts[3]
+	default:
+		return true
+	}
+	oid, err := strconv.ParseUint(oidPart, 10, 32)
+	if err != nil {
+		// not a database directory, e.g. pgsql_tmp
+		return true
+	}
+	return oid < FirstNormalObjectID || selectedOids[oid]
+}
@@ -0,0 +1,84 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+var databaseTestFiles = []string{
+	"/global/1262",
+	"/pg_xact/0000",
+	"/base/1/1259",
+	"/base/13757/1259",
+	"/base/16384/16385",
+	"/base/16390/16391",
+	"/base/pgsql_tmp/pgsql_tmp1.0",
+	"/pg_tblspc/16400/PG_14_202107181/16384/16401",
+	"/pg_tblspc/16400/PG_14_202107181/16390/16402",
+}
+
+func newDatabaseTestBackup() (postgres.BackupSentinelDto, postgres.FilesMetadataDto) {
+	sentinel := postgres.BackupSentinelDto{
+		Databases: map[string]uint32{"postgres": 13757, "app": 16384, "other": 16390},
+	}
+	filesMeta := postgres.FilesMetadataDto{Files: internal.BackupFileList{}}
+	for _, file := range databaseTestFiles {
+		filesMeta.Files[file] = internal.BackupFileDescription{}
+	}
+	return sentinel, filesMeta
+}
+
+func TestGetDatabaseFilesToUnwrap(t *testing.T) {
+	sentinel, filesMeta := newDatabaseTestBackup()
+
+	for _, database := range []string{"app", "16384"} {
+		files, err := postgres.GetDatabaseFilesToUnwrap(sentinel, filesMeta, []string{database})
+		assert.NoError(t, err)
+
+		for _, file := range databaseTestFiles {
+			expected := file != "/base/16390/16391" && file != "/pg_tblspc/16400/PG_14_202107181/16390/16402"
+			assert.Equal(t, expected, files[file], file)
+		}
+		for utilityFilePath := range postgres.UtilityFilePaths {
+			assert.True(t, files[utilityFilePath])
+		}
+	}
+}
+
+func TestGetDatabaseFilesToUnwrap_UnknownDatabase(t *testing.T) {
+	sentinel, filesMeta := newDatabaseTestBackup()
+
+	for _, database := range []string{"missing", "16385"} {
+		_, err := postgres.GetDatabaseFilesToUnwrap(sentinel, filesMeta, []string{database})
+		assert.IsType(t, postgres.UnknownDatabaseError{}, err)
+		assert.Contains(t, err.Error(), "app (16384), other (16390), postgres (13757)")
+	}
+
+	_, err := postgres.GetDatabaseFilesToUnwrap(postgres.BackupSentinelDto{}, filesMeta, []string{"app"})
+	assert.IsType(t, postgres.UnknownDatabaseError{}, err)
+}
+
+func TestGetDatabaseFilesToUnwrap_NoFilesMetadata(t *testing.T) {
+	sentinel, _ := newDatabaseTestBackup()
+
+	_, err := postgres.GetDatabaseFilesToUnwrap(sentinel, postgres.FilesMetadataDto{}, []string{"app"})
+	assert.Error(t, err)
+}
+
+func TestBackup_GetDatabaseFilesToUnwrap_IntersectsWithMask(t *testing.T) {
+	sentinel, filesMeta := newDatabaseTestBackup()
+	backup := postgres.Backup{SentinelDto: &sentinel, FilesMetadataDto: &filesMeta}
+
+	files, err := backup.GetDatabaseFilesToUnwrap(map[string]bool{"/base/16384/16385": true, "/base/16390/16391": true},
+		[]string{"app"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"/base/16384/16385": true}, files)
+
+	allFiles := map[string]bool{"/base/16390/16391": true}
+	files, err = backup.GetDatabaseFilesToUnwrap(allFiles, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, allFiles, files)
+}
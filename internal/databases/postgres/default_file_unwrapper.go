@@ -26,7 +26,8 @@ func (u *DefaultFileUnwrapper) UnwrapNewFile(reader io.Reader, header *tar.Heade
 		}
 		return NewCreatedFromIncrementResult(missingBlockCount), nil
 	}
-	err := WriteLocalFile(reader, header, file, fsync)
+	err := WriteLocalFile(reader, header, file, fsync, u.options.expectedChecksum, u.options.copyBuffer,
+		u.options.contentFilter)
 	if err != nil {
 		return nil, err
 	}
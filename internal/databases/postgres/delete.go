@@ -21,7 +21,7 @@ const (
 )
 
 func NewDeleteHandler(folder storage.Folder, permanentBackups, permanentWals map[string]bool,
-	useSentinelTime bool,
+	useSentinelTime bool, options ...internal.DeleteHandlerOption,
 ) (*DeleteHandler, error) {
 	backups, err := internal.GetBackupSentinelObjects(folder)
 	if err != nil {
@@ -47,14 +47,16 @@ func NewDeleteHandler(folder storage.Folder, permanentBackups, permanentWals map
 		return nil, err
 	}
 
+	options = append([]internal.DeleteHandlerOption{
+		internal.IsPermanentFunc(makePermanentFunc(permanentBackups, permanentWals)),
+	}, options...)
 	deleteHandler :=
 		&DeleteHandler{
 			*internal.NewDeleteHandler(
 				folder,
 				postgresBackups,
 				lessFunc,
-				internal.IsPermanentFunc(
-					makePermanentFunc(permanentBackups, permanentWals))),
+				options...),
 		}
 
 	return deleteHandler, nil
@@ -19,6 +19,8 @@ import (
 
 type TestPostgresBackupObject struct {
 	storage.Object
+	// incrementFrom is the backup the increment is taken from, the backup itself if it is not set
+	incrementFrom string
 }
 
 func (o TestPostgresBackupObject) GetBackupName() string {
@@ -30,6 +32,9 @@ func (o TestPostgresBackupObject) GetBaseBackupName() string {
 }
 
 func (o TestPostgresBackupObject) GetIncrementFromName() string {
+	if o.incrementFrom != "" {
+		return o.incrementFrom
+	}
 	return o.GetBackupName()
 }
 
@@ -273,7 +278,7 @@ func TestDeleteBeforeTargetWithPermanentBackups(t *testing.T) {
 	isPermanent := makeTestPermanentFunc(permanentBackups, permanentWals)
 	deleteHandler := newTestDeleteHandler(folder, lessByTime, internal.IsPermanentFunc(isPermanent))
 
-	err := deleteHandler.DeleteBeforeTarget(TestPostgresBackupObject{target}, true)
+	err := deleteHandler.DeleteBeforeTarget(TestPostgresBackupObject{Object: target}, true)
 	assert.NoError(t, err)
 
 	// verify expected permanent still exists
@@ -358,7 +363,7 @@ func newTestDeleteHandler(
 
 	testBackupObjects := make([]internal.BackupObject, 0, len(objects))
 	for _, object := range objects {
-		testBackupObjects = append(testBackupObjects, TestPostgresBackupObject{object})
+		testBackupObjects = append(testBackupObjects, TestPostgresBackupObject{Object: object})
 	}
 
 	return internal.NewDeleteHandler(folder, testBackupObjects, lessFunc, options...)
@@ -379,3 +384,42 @@ func makeTestPermanentFunc(permanentBackups, permanentWals map[string]bool) func
 		return postgres.IsPermanent(object.GetName(), permanentBackups, permanentWals)
 	}
 }
+
+func newIncrementChainDeleteHandler(options ...internal.DeleteHandlerOption) (*internal.DeleteHandler, []internal.BackupObject) {
+	backups := []internal.BackupObject{
+		TestPostgresBackupObject{Object: storage.NewLocalObject("base_1", time.Time{}, 0)},
+		TestPostgresBackupObject{Object: storage.NewLocalObject("base_2_D_1", time.Time{}, 0), incrementFrom: "base_1"},
+		TestPostgresBackupObject{Object: storage.NewLocalObject("base_3_D_2", time.Time{}, 0), incrementFrom: "base_2_D_1"},
+		TestPostgresBackupObject{Object: storage.NewLocalObject("base_4", time.Time{}, 0)},
+	}
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	return internal.NewDeleteHandler(folder, backups, lessByName, options...), backups
+}
+
+func getBackupNames(backups []internal.BackupObject) []string {
+	names := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		names = append(names, backup.GetBackupName())
+	}
+	return names
+}
+
+func TestFindOrphanedBackups(t *testing.T) {
+	deleteHandler, backups := newIncrementChainDeleteHandler()
+
+	assert.Equal(t, []string{"base_2_D_1", "base_3_D_2"},
+		getBackupNames(deleteHandler.FindOrphanedBackups(backups[:1])))
+	assert.Equal(t, []string{"base_3_D_2"},
+		getBackupNames(deleteHandler.FindOrphanedBackups(backups[1:2])))
+	assert.Empty(t, deleteHandler.FindOrphanedBackups(backups[:3]))
+	assert.Empty(t, deleteHandler.FindOrphanedBackups(backups[3:]))
+}
+
+func TestDeleteTargets_RefusesToOrphanIncrements(t *testing.T) {
+	deleteHandler, backups := newIncrementChainDeleteHandler()
+	err := deleteHandler.DeleteTargets(backups[:1], false)
+	assert.IsType(t, utility.ForbiddenActionError{}, err)
+
+	forcedDeleteHandler, backups := newIncrementChainDeleteHandler(internal.AllowOrphanedIncrements(true))
+	assert.NoError(t, forcedDeleteHandler.DeleteTargets(backups[:1], false))
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// retryFileCopy runs the copyFile writing the targetPath from the fileReader. If the copy fails with the transient
+// read error and the fileReader is the internal.ReopenableReader, the partially written file is removed
+// and the copy is repeated from the entry beginning up to fileRetries times. The other errors fail fast.
+func (tarInterpreter *FileTarInterpreter) retryFileCopy(fileReader io.Reader, name, targetPath string,
+	copyFile func() error) error {
+	err := copyFile()
+	for attempt := 1; err != nil && attempt <= tarInterpreter.fileRetries && internal.IsTransientReadError(err); attempt++ {
+		reopenable, ok := fileReader.(internal.ReopenableReader)
+		if !ok {
+			return err
+		}
+		tracelog.WarningLogger.Printf("Copy of '%s' failed, retrying (%d/%d): %v",
+			name, attempt, tarInterpreter.fileRetries, err)
+		if removeErr := os.Remove(targetPath); removeErr != nil && !os.IsNotExist(removeErr) {
+			return errors.Wrapf(removeErr, "Interpret: failed to remove partially written '%s'", targetPath)
+		}
+		if reopenErr := reopenable.Reopen(); reopenErr != nil {
+			tracelog.WarningLogger.Printf("Failed to reopen '%s': %v", name, reopenErr)
+			return err
+		}
+		err = copyFile()
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// flakyReopenableReader fails with the err after the half of the content is read until it is reopened
+type flakyReopenableReader struct {
+	content  []byte
+	err      error
+	reader   io.Reader
+	reopened int
+}
+
+var _ internal.ReopenableReader = &flakyReopenableReader{}
+
+func newFlakyReopenableReader(content []byte, err error) *flakyReopenableReader {
+	return &flakyReopenableReader{content: content, err: err,
+		reader: io.MultiReader(bytes.NewReader(content[:len(content)/2]), iotest.ErrReader(err))}
+}
+
+func (reader *flakyReopenableReader) Read(p []byte) (int, error) {
+	return reader.reader.Read(p)
+}
+
+func (reader *flakyReopenableReader) Reopen() error {
+	reader.reopened++
+	reader.reader = bytes.NewReader(reader.content)
+	return nil
+}
+
+func TestInterpret_RetriesTransientCopyFailure(t *testing.T) {
+	viper.Set(internal.RestoreFileRetriesSetting, 2)
+	defer viper.Set(internal.RestoreFileRetriesSetting, nil)
+
+	for _, useNew := range []bool{false, true} {
+		useNewUnwrapImplementation = useNew
+		dir := t.TempDir()
+		tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+		content := []byte("the contents broken in the middle")
+		reader := newFlakyReopenableReader(content, io.ErrUnexpectedEOF)
+
+		err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600,
+			Size: int64(len(content))})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reader.reopened)
+		written, err := os.ReadFile(filepath.Join(dir, "file"))
+		assert.NoError(t, err)
+		assert.Equal(t, content, written)
+	}
+	useNewUnwrapImplementation = false
+}
+
+func TestInterpret_FailsFastOnPermanentCopyFailure(t *testing.T) {
+	viper.Set(internal.RestoreFileRetriesSetting, 2)
+	defer viper.Set(internal.RestoreFileRetriesSetting, nil)
+
+	dir := t.TempDir()
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	reader := newFlakyReopenableReader([]byte("contents"), errors.New("decryption failed"))
+
+	err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 8})
+	assert.Error(t, err)
+	assert.Equal(t, 0, reader.reopened)
+	_, err = os.Stat(filepath.Join(dir, "file"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpret_NoRetriesByDefault(t *testing.T) {
+	tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	reader := newFlakyReopenableReader([]byte("contents"), io.ErrUnexpectedEOF)
+
+	err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 8})
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 0, reader.reopened)
+}
@@ -4,6 +4,8 @@ import (
 	"archive/tar"
 	"io"
 	"os"
+
+	"github.com/wal-g/wal-g/internal"
 )
 
 type FileUnwrapperType int
@@ -19,6 +21,7 @@ const (
 	CreatedFromIncrement
 	WroteIncrementBlocks
 	Skipped
+	Unchanged
 )
 
 func NewFileUnwrapper(unwrapperType FileUnwrapperType, options *BackupFileOptions) IBackupFileUnwrapper {
@@ -53,9 +56,16 @@ func NewSkippedResult() *FileUnwrapResult {
 	return &FileUnwrapResult{Skipped, 0}
 }
 
+func NewUnchangedResult() *FileUnwrapResult {
+	return &FileUnwrapResult{Unchanged, 0}
+}
+
 type BackupFileOptions struct {
-	isIncremented bool
-	isPageFile    bool
+	isIncremented    bool
+	isPageFile       bool
+	expectedChecksum *internal.FileChecksum
+	copyBuffer       []byte
+	contentFilter    ContentFilter
 }
 
 type IBackupFileUnwrapper interface {
@@ -0,0 +1,18 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package postgres
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdatasync is not available on this platform, so the regular fsync is used instead
+func fdatasync(file *os.File) error {
+	return file.Sync()
+}
+
+func globalSync() {
+	syscall.Sync()
+}
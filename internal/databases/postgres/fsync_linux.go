@@ -0,0 +1,23 @@
+//go:build linux
+// +build linux
+
+package postgres
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdatasync flushes the file data without the unnecessary metadata,
+// falls back to the regular fsync if fdatasync is not supported by the file system
+func fdatasync(file *os.File) error {
+	err := syscall.Fdatasync(int(file.Fd()))
+	if err == syscall.ENOSYS || err == syscall.EINVAL {
+		return file.Sync()
+	}
+	return err
+}
+
+func globalSync() {
+	syscall.Sync()
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package postgres
+
+import (
+	"os"
+
+	"github.com/wal-g/tracelog"
+)
+
+// fdatasync is not available on this platform, so the regular fsync is used instead
+func fdatasync(file *os.File) error {
+	return file.Sync()
+}
+
+func globalSync() {
+	tracelog.WarningLogger.Println("Global sync is not supported on Windows, skipping it")
+}
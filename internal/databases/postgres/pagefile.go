@@ -25,7 +25,6 @@ import (
 	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/internal/limiters"
 	"github.com/wal-g/wal-g/internal/walparser"
-	"github.com/wal-g/wal-g/internal/walparser/parsingutil"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -57,6 +56,22 @@ func (err InvalidBlockError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+// IncrementBlockOutOfRangeError indicates that the increment lists the block beyond the incremented file size
+type IncrementBlockOutOfRangeError struct {
+	error
+	BlockNo  uint32
+	FileSize uint64
+}
+
+func newIncrementBlockOutOfRangeError(blockNo uint32, fileSize uint64) IncrementBlockOutOfRangeError {
+	return IncrementBlockOutOfRangeError{errors.Errorf("increment block %d is out of range of the %d bytes file",
+		blockNo, fileSize), blockNo, fileSize}
+}
+
+func (err IncrementBlockOutOfRangeError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type InvalidIncrementFileHeaderError struct {
 	error
 }
@@ -170,27 +185,12 @@ func convertBlocksToLocations(filePath string, blocks []uint32) ([]walparser.Blo
 	return locations, nil
 }
 
-// ApplyFileIncrement changes pages according to supplied change map file
+// ApplyFileIncrement changes pages according to supplied change map file: each changed block is written
+// at its offset on top of the base file truncated to the new size, the other blocks are not touched.
+// The missing base file is created if createNewIncrementalFiles is set.
 func ApplyFileIncrement(fileName string, increment io.Reader, createNewIncrementalFiles bool, fsync bool) error {
 	tracelog.DebugLogger.Printf("Incrementing %s\n", fileName)
-	err := ReadIncrementFileHeader(increment)
-	if err != nil {
-		return err
-	}
-
-	var fileSize uint64
-	var diffBlockCount uint32
-	err = parsingutil.ParseMultipleFieldsFromReader([]parsingutil.FieldToParse{
-		{Field: &fileSize, Name: "fileSize"},
-		{Field: &diffBlockCount, Name: "diffBlockCount"},
-	}, increment)
-	if err != nil {
-		return err
-	}
-
-	diffMap := make([]byte, diffBlockCount*sizeofInt32)
-
-	_, err = io.ReadFull(increment, diffMap)
+	fileSize, diffBlockCount, diffMap, err := GetIncrementHeaderFields(increment)
 	if err != nil {
 		return err
 	}
@@ -178,6 +178,8 @@ func isTarReaderEmpty(reader io.Reader) bool {
 	return all == 0
 }
 
+// GetIncrementHeaderFields reads the file size, the changed block count and the block numbers map of the increment,
+// the blocks not fitting into the file size are refused
 func GetIncrementHeaderFields(increment io.Reader) (uint64, uint32, []byte, error) {
 	err := ReadIncrementFileHeader(increment)
 	if err != nil {
@@ -200,5 +202,12 @@ func GetIncrementHeaderFields(increment io.Reader) (uint64, uint32, []byte, erro
 	if err != nil {
 		return 0, 0, nil, err
 	}
+	// the block is written at its offset, so the whole block must fit into the file
+	for i := uint32(0); i < diffBlockCount; i++ {
+		blockNo := binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
+		if (uint64(blockNo)+1)*uint64(DatabasePageSize) > fileSize {
+			return 0, 0, nil, newIncrementBlockOutOfRangeError(blockNo, fileSize)
+		}
+	}
 	return fileSize, diffBlockCount, diffMap, nil
 }
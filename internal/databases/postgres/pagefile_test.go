@@ -3,10 +3,12 @@ package postgres_test
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/wal-g/wal-g/internal/databases/postgres"
@@ -186,6 +188,32 @@ func postgresCreateFileFromIncrementTest(testIncrement *TestIncrement, t *testin
 	checkAllWrittenBlocksCorrect(mockFile, sourceFile, testIncrement.diffBlockCount, t)
 }
 
+// The increment listing the block beyond the incremented file size
+// should be refused before anything is written to the base file
+func TestApplyFileIncrement_BlockOutOfRange(t *testing.T) {
+	fileSize := uint64(2 * postgres.DatabasePageSize)
+	var increment bytes.Buffer
+	increment.Write([]byte{'w', 'i', '1', postgres.SignatureMagicNumber})
+	increment.Write(utility.ToBytes(fileSize))
+	increment.Write(utility.ToBytes(uint32(2)))
+	increment.Write(utility.ToBytes(uint32(1)))
+	increment.Write(utility.ToBytes(uint32(2)))
+	increment.Write(make([]byte, 2*postgres.DatabasePageSize))
+
+	baseFileName := filepath.Join(t.TempDir(), "base")
+	baseContents := bytes.Repeat([]byte{1}, int(fileSize))
+	assert.NoError(t, os.WriteFile(baseFileName, baseContents, 0600))
+	err := postgres.ApplyFileIncrement(baseFileName, &increment, false, false)
+	var rangeErr postgres.IncrementBlockOutOfRangeError
+	if assert.True(t, errors.As(err, &rangeErr)) {
+		assert.Equal(t, uint32(2), rangeErr.BlockNo)
+		assert.Equal(t, fileSize, rangeErr.FileSize)
+	}
+	contents, err := os.ReadFile(baseFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, baseContents, contents)
+}
+
 // In this test series we test that
 // no increment blocks are being written if the
 // local file is completed (no missing blocks)
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"archive/tar"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PathRemapper maps the tar entry name prefixes to their replacements.
+// Relative replacements are resolved against the DBDataDirectory,
+// absolute ones are used as is (e.g. to restore a tablespace to a mounted volume).
+// The absolute prefixes also remap the absolute symlink targets, e.g. of the tablespace symlinks,
+// the targets not within the allowed roots after remapping are rejected.
+type PathRemapper map[string]string
+
+// remap replaces the longest matching prefix of the name, returns false if no prefix matches
+func (remapper PathRemapper) remap(name string) (remapped string, ok bool) {
+	cleanName := path.Clean(name)
+	longestPrefix, longestReplacement := "", ""
+	for prefix, replacement := range remapper {
+		cleanPrefix := path.Clean(prefix)
+		if !hasPathPrefix(cleanName, cleanPrefix) || (ok && len(cleanPrefix) <= len(longestPrefix)) {
+			continue
+		}
+		longestPrefix, longestReplacement, ok = cleanPrefix, replacement, true
+	}
+	if !ok {
+		return "", false
+	}
+	return path.Join(longestReplacement, strings.TrimPrefix(cleanName, longestPrefix)), true
+}
+
+// hasPathPrefix checks that the prefix matches the whole path components of the name
+func hasPathPrefix(name, prefix string) bool {
+	if name == prefix {
+		return true
+	}
+	return strings.HasPrefix(name, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// isPathWithin checks that the target path does not escape the root directory
+func isPathWithin(targetPath, root string) bool {
+	relative, err := filepath.Rel(filepath.Clean(root), filepath.Clean(targetPath))
+	if err != nil {
+		return false
+	}
+	return relative != ".." && !strings.HasPrefix(relative, "../")
+}
+
+// getTargetPath builds the local path for the tar entry name, applying the PathRemapper.
+// The names are relative to the DBDataDirectory even if they start with '/',
+// the names escaping it by '..' components are rejected.
+func (tarInterpreter *FileTarInterpreter) getTargetPath(name string) (string, error) {
+	remapped, ok := tarInterpreter.PathRemapper.remap(name)
+	if !ok {
+		targetPath := path.Join(tarInterpreter.DBDataDirectory, name)
+		if !isPathWithin(targetPath, tarInterpreter.DBDataDirectory) {
+			return "", newPathTraversalError(errors.Errorf("Interpret: tar entry '%s' escapes the data directory '%s'",
+				name, tarInterpreter.DBDataDirectory), name, targetPath, tarInterpreter.DBDataDirectory)
+		}
+		return targetPath, nil
+	}
+	if !filepath.IsAbs(remapped) {
+		remapped = path.Join(tarInterpreter.DBDataDirectory, remapped)
+	}
+	allowedRoots := tarInterpreter.getAllowedRoots()
+	for _, allowedRoot := range allowedRoots {
+		if isPathWithin(remapped, allowedRoot) {
+			return remapped, nil
+		}
+	}
+	return "", newPathTraversalError(errors.Errorf("Interpret: remapped path '%s' of '%s' is not within the allowed roots %v",
+		remapped, name, allowedRoots), name, remapped, allowedRoots...)
+}
+
+// getAllowedRoots returns the AllowedRoots if set, otherwise
+// the DBDataDirectory and the absolute PathRemapper replacements are allowed
+func (tarInterpreter *FileTarInterpreter) getAllowedRoots() []string {
+	if len(tarInterpreter.AllowedRoots) > 0 {
+		return tarInterpreter.AllowedRoots
+	}
+	allowedRoots := []string{tarInterpreter.DBDataDirectory}
+	for _, replacement := range tarInterpreter.PathRemapper {
+		if filepath.IsAbs(replacement) {
+			allowedRoots = append(allowedRoots, replacement)
+		}
+	}
+	return allowedRoots
+}
+
+// getLinkSourcePath builds the local path of the file the hardlink points to,
+// the source is resolved the same way as the extracted files are, so it does not depend on the working directory
+func (tarInterpreter *FileTarInterpreter) getLinkSourcePath(fileInfo *tar.Header) (string, error) {
+	linkSource := fileInfo.Linkname
+	if linkSource == "" {
+		linkSource = fileInfo.Name
+	}
+	linkSourcePath, err := tarInterpreter.getTargetPath(linkSource)
+	return linkSourcePath, errors.Wrapf(err, "Interpret: invalid source of hardlink '%s'", fileInfo.Name)
+}
+
+// getSymlinkTarget returns the target of the symlink to create at the targetPath. The relative target
+// is resolved against the symlink entry directory and built the same way as the entry paths are,
+// it is kept relative unless the PathRemapper moves it away from the symlink. The absolute one
+// (e.g. of the tablespace) is remapped if the PathRemapper has the matching absolute prefix,
+// so the tablespace can be pointed to its new location, and must be within the allowed roots either way
+func (tarInterpreter *FileTarInterpreter) getSymlinkTarget(fileInfo *tar.Header, targetPath string) (string, error) {
+	linkTarget := fileInfo.Linkname
+	if linkTarget == "" {
+		return "", errors.Errorf("Interpret: symlink '%s' has no target", fileInfo.Name)
+	}
+	if filepath.IsAbs(linkTarget) {
+		resolvedTarget := path.Clean(linkTarget)
+		if remapped, ok := tarInterpreter.PathRemapper.remap(linkTarget); ok {
+			resolvedTarget = remapped
+			if !filepath.IsAbs(resolvedTarget) {
+				resolvedTarget = path.Join(tarInterpreter.DBDataDirectory, resolvedTarget)
+			}
+		}
+		allowedRoots := tarInterpreter.getAllowedRoots()
+		for _, allowedRoot := range allowedRoots {
+			if isPathWithin(resolvedTarget, allowedRoot) {
+				return resolvedTarget, nil
+			}
+		}
+		return "", newPathTraversalError(errors.Errorf("Interpret: symlink '%s' target '%s' is not within the allowed roots %v",
+			targetPath, resolvedTarget, allowedRoots), linkTarget, resolvedTarget, allowedRoots...)
+	}
+	// the relative target is resolved among the tar entry names, so it is remapped the same way as the entry it points to
+	entryTarget := path.Join(path.Dir(fileInfo.Name), linkTarget)
+	if relativeTarget := path.Join(strings.TrimPrefix(path.Dir(fileInfo.Name), "/"), linkTarget); relativeTarget == ".." ||
+		strings.HasPrefix(relativeTarget, "../") {
+		// the rooted entry names are cleaned up to the root, so the escaping target would be silently cut otherwise
+		return "", newPathTraversalError(errors.Errorf("Interpret: symlink '%s' target '%s' escapes the data directory '%s'",
+			targetPath, linkTarget, tarInterpreter.DBDataDirectory), linkTarget, entryTarget, tarInterpreter.DBDataDirectory)
+	}
+	resolvedTarget, err := tarInterpreter.getTargetPath(entryTarget)
+	if err != nil {
+		return "", errors.Wrapf(err, "Interpret: invalid target '%s' of symlink '%s'", linkTarget, targetPath)
+	}
+	if resolvedTarget != filepath.Join(filepath.Dir(targetPath), linkTarget) {
+		return resolvedTarget, nil
+	}
+	return linkTarget, nil
+}
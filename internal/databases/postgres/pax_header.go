@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"archive/tar"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// The PAX extended header keywords overriding the USTAR header fields
+const (
+	paxPathKeyword     = "path"
+	paxLinkpathKeyword = "linkpath"
+	paxSizeKeyword     = "size"
+)
+
+// resolvePAXHeader applies the PAX extended header records to the header fields, so the long names and the sizes
+// exceeding the USTAR limits are used for the metadata lookups and extraction.
+// archive/tar reader does this by itself, but the header is not guaranteed to come from it.
+// The header is copied if any of the fields is changed, the input is never modified.
+func resolvePAXHeader(header *tar.Header) (*tar.Header, error) {
+	resolved := header
+	resolve := func() *tar.Header {
+		if resolved == header {
+			headerCopy := *header
+			resolved = &headerCopy
+		}
+		return resolved
+	}
+
+	if name, ok := header.PAXRecords[paxPathKeyword]; ok && name != header.Name {
+		resolve().Name = name
+	}
+	if linkname, ok := header.PAXRecords[paxLinkpathKeyword]; ok && linkname != header.Linkname {
+		resolve().Linkname = linkname
+	}
+	if sizeRecord, ok := header.PAXRecords[paxSizeKeyword]; ok {
+		size, err := strconv.ParseInt(sizeRecord, 10, 64)
+		if err != nil || size < 0 {
+			return nil, errors.Errorf("Interpret: invalid PAX size '%s' of tar entry '%s'", sizeRecord, resolved.Name)
+		}
+		if size != header.Size {
+			resolve().Size = size
+		}
+	}
+	return resolved, nil
+}
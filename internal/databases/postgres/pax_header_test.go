@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+var longTarEntryName = "/pg_tblspc/16385/" + strings.Repeat("long_directory_name/", 14) + "16386"
+
+func TestResolvePAXHeader(t *testing.T) {
+	header := &tar.Header{
+		Name:     longTarEntryName[:100],
+		Linkname: "short",
+		Size:     0,
+		PAXRecords: map[string]string{
+			paxPathKeyword:     longTarEntryName,
+			paxLinkpathKeyword: longTarEntryName + "_link",
+			paxSizeKeyword:     "9000000000",
+		},
+	}
+
+	resolved, err := resolvePAXHeader(header)
+	assert.NoError(t, err)
+	assert.Equal(t, longTarEntryName, resolved.Name)
+	assert.Equal(t, longTarEntryName+"_link", resolved.Linkname)
+	assert.Equal(t, int64(9000000000), resolved.Size)
+	assert.Equal(t, longTarEntryName[:100], header.Name)
+	assert.Equal(t, int64(0), header.Size)
+
+	header = &tar.Header{Name: "base/1/2", Size: 8192}
+	resolved, err = resolvePAXHeader(header)
+	assert.NoError(t, err)
+	assert.Same(t, header, resolved)
+
+	_, err = resolvePAXHeader(&tar.Header{Name: "base/1/2", PAXRecords: map[string]string{paxSizeKeyword: "-1"}})
+	assert.Error(t, err)
+}
+
+func TestResolvePAXHeader_WrittenByTarWriter(t *testing.T) {
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	assert.NoError(t, tarWriter.WriteHeader(&tar.Header{
+		Name:     longTarEntryName,
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+		Size:     9000000000,
+	}))
+
+	header, err := tar.NewReader(&archive).Next()
+	assert.NoError(t, err)
+	resolved, err := resolvePAXHeader(header)
+	assert.NoError(t, err)
+	assert.Greater(t, len(longTarEntryName), 255)
+	assert.Equal(t, longTarEntryName, resolved.Name)
+	assert.Equal(t, int64(9000000000), resolved.Size)
+}
+
+func TestInterpretLooksUpMetadataByPAXPath(t *testing.T) {
+	defer func() { useNewUnwrapImplementation = false }()
+	digest := sha256.Sum256([]byte("content"))
+	checksum := &internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm, Value: hex.EncodeToString(digest[:])}
+
+	for _, useNewImplementation := range []bool{false, true} {
+		useNewUnwrapImplementation = useNewImplementation
+		for content, expectedErr := range map[string]bool{"content": false, "corrupt": true} {
+			tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{},
+				FilesMetadataDto{Files: internal.BackupFileList{longTarEntryName: {Checksum: checksum}}},
+				map[string]bool{longTarEntryName: true}, false)
+			tarInterpreter.verifyChecksums = true
+
+			err := tarInterpreter.Interpret(bytes.NewBufferString(content), &tar.Header{
+				Name:       longTarEntryName[:100],
+				Typeflag:   tar.TypeReg,
+				Mode:       0600,
+				PAXRecords: map[string]string{paxPathKeyword: longTarEntryName, paxSizeKeyword: "7"},
+			})
+			if expectedErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), longTarEntryName)
+				continue
+			}
+			assert.NoError(t, err)
+			_, err = os.Stat(filepath.Join(tarInterpreter.DBDataDirectory, longTarEntryName))
+			assert.NoError(t, err)
+		}
+	}
+}
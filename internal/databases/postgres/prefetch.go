@@ -25,19 +25,32 @@ import (
 // HandleWALPrefetch is invoked by wal-fetch command to speed up database restoration
 func HandleWALPrefetch(uploader *WalUploader, walFileName string, location string) {
 	folder := uploader.UploadingFolder.GetSubFolder(utility.WalPath)
-	var fileName = walFileName
 	location = path.Dir(location)
 	waitGroup := &sync.WaitGroup{}
 	concurrency, err := internal.GetMaxDownloadConcurrency()
 	tracelog.ErrorLogger.FatalOnError(err)
+	depth, err := internal.GetPrefetchDepth()
+	tracelog.ErrorLogger.FatalOnError(err)
+	cacheSize, err := internal.GetPrefetchCacheSize()
+	tracelog.ErrorLogger.FatalOnError(err)
 
-	for i := 0; i < concurrency; i++ {
-		fileName, err = GetNextWalFilename(fileName)
-		if err != nil {
-			tracelog.ErrorLogger.Println("WAL-prefetch failed: ", err, " file: ", fileName)
-		}
+	// the consumed segments are evicted first, so they do not occupy the cache
+	cleaner := fsutil.FileSystemCleaner{}
+	CleanupPrefetchDirectories(walFileName, location, cleaner)
+	prefetchLocation, _, _, _ := getPrefetchLocations(location, walFileName)
+	cachedFiles, err := cleaner.GetFiles(prefetchLocation)
+	if err != nil && !os.IsNotExist(err) {
+		tracelog.WarningLogger.Println("WAL-prefetch failed to list the cached files: ", err)
+	}
+
+	downloadSlots := make(chan struct{}, concurrency)
+	for _, fileName := range selectSegmentsToPrefetch(walFileName, depth, cacheSize, cachedFiles) {
 		waitGroup.Add(1)
-		go prefetchFile(location, folder, fileName, waitGroup)
+		go func(fileName string) {
+			downloadSlots <- struct{}{}
+			defer func() { <-downloadSlots }()
+			prefetchFile(location, folder, fileName, waitGroup)
+		}(fileName)
 
 		prefaultStartLsn, shouldPrefault, timelineID, err := shouldPrefault(fileName)
 		if err != nil {
@@ -51,11 +64,43 @@ func HandleWALPrefetch(uploader *WalUploader, walFileName string, location strin
 		time.Sleep(10 * time.Millisecond) // ramp up in order
 	}
 
-	go CleanupPrefetchDirectories(walFileName, location, fsutil.FileSystemCleaner{})
-
 	waitGroup.Wait()
 }
 
+// selectSegmentsToPrefetch returns up to depth segments following the walFileName, which are not cached yet.
+// The cache holds at most cacheSize segments including the already cached ones, unlimited if cacheSize is 0.
+func selectSegmentsToPrefetch(walFileName string, depth, cacheSize int, cachedFiles []string) []string {
+	cached := make(map[string]bool, len(cachedFiles))
+	for _, cachedFile := range cachedFiles {
+		if _, _, err := ParseWALFilename(cachedFile); err == nil {
+			cached[cachedFile] = true
+		}
+	}
+	freeSlots := cacheSize - len(cached)
+
+	segments := make([]string, 0, depth)
+	fileName := walFileName
+	for i := 0; i < depth; i++ {
+		var err error
+		fileName, err = GetNextWalFilename(fileName)
+		if err != nil {
+			tracelog.ErrorLogger.Println("WAL-prefetch failed: ", err, " file: ", fileName)
+			break
+		}
+		if cached[fileName] {
+			continue
+		}
+		if cacheSize > 0 {
+			if freeSlots <= 0 {
+				break
+			}
+			freeSlots--
+		}
+		segments = append(segments, fileName)
+	}
+	return segments
+}
+
 // TODO : unit tests
 func prefaultData(prefaultStartLsn uint64, timelineID uint32, waitGroup *sync.WaitGroup, uploader *WalUploader) {
 	defer func() {
@@ -208,6 +253,11 @@ func prefetchFile(location string, folder storage.Folder, walFileName string, wa
 	tracelog.ErrorLogger.PrintOnError(err)
 
 	err = internal.DownloadFileTo(folder, walFileName, oldPath)
+	if _, isArchNonExistErr := err.(internal.ArchiveNonExistenceError); isArchNonExistErr {
+		// the segment is beyond the end of the archived WAL, e.g. the timeline ends or it is not archived yet
+		tracelog.DebugLogger.Println("WAL-prefetch: segment is not archived yet: ", walFileName)
+		return
+	}
 	tracelog.ErrorLogger.PrintOnError(err)
 
 	_, errO = os.Stat(oldPath)
@@ -238,9 +288,13 @@ func forkPrefetch(walFileName string, location string) {
 	if err != nil {
 		tracelog.ErrorLogger.Println("WAL-prefetch failed: ", err)
 	}
+	depth, err := internal.GetPrefetchDepth()
+	if err != nil {
+		tracelog.ErrorLogger.Println("WAL-prefetch failed: ", err)
+	}
 	if strings.Contains(walFileName, "history") ||
 		strings.Contains(walFileName, "partial") ||
-		concurrency == 1 {
+		concurrency == 1 || depth < 1 {
 		return // There will be nothing ot prefetch anyway
 	}
 	prefetchArgs := []string{"wal-prefetch", walFileName, location}
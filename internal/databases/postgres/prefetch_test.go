@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectSegmentsToPrefetch(t *testing.T) {
+	segments := selectSegmentsToPrefetch("000000010000000100000058", 3, 0, nil)
+	assert.Equal(t, []string{"000000010000000100000059", "00000001000000010000005A", "00000001000000010000005B"}, segments)
+}
+
+func TestSelectSegmentsToPrefetch_SkipsCached(t *testing.T) {
+	cached := []string{"000000010000000100000059", "running"}
+	segments := selectSegmentsToPrefetch("000000010000000100000058", 3, 0, cached)
+	assert.Equal(t, []string{"00000001000000010000005A", "00000001000000010000005B"}, segments)
+}
+
+func TestSelectSegmentsToPrefetch_CacheSizeLimit(t *testing.T) {
+	cached := []string{"000000010000000100000059"}
+	segments := selectSegmentsToPrefetch("000000010000000100000058", 5, 3, cached)
+	assert.Equal(t, []string{"00000001000000010000005A", "00000001000000010000005B"}, segments)
+
+	assert.Empty(t, selectSegmentsToPrefetch("000000010000000100000058", 5, 1, cached))
+	assert.Empty(t, selectSegmentsToPrefetch("000000010000000100000058", 0, 0, nil))
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+// getRangeReader returns the fileReader if the file is large enough to be downloaded by the concurrent ranges
+// and it is written as is, nil otherwise. The incremented and the filtered files are always read sequentially.
+func (tarInterpreter *FileTarInterpreter) getRangeReader(fileReader io.Reader,
+	header *tar.Header) internal.EntryRangeReader {
+	if tarInterpreter.rangeThreshold <= 0 || header.Size < tarInterpreter.rangeThreshold ||
+		tarInterpreter.ContentFilter != nil || tarInterpreter.createNewIncrementalFiles {
+		return nil
+	}
+	if fileDescription, ok := tarInterpreter.FilesMetadata.Files[header.Name]; ok && fileDescription.IsIncremented {
+		return nil
+	}
+	rangeReader, ok := fileReader.(internal.EntryRangeReader)
+	if !ok || !rangeReader.SupportsRanges() {
+		return nil
+	}
+	return rangeReader
+}
+
+// writeLocalFileByRanges preallocates the localFile and writes the disjoint ranges of the contents concurrently
+// at their offsets, then verifies the reassembled file against the expected checksum.
+// The rest of the tar is continued after the entry, so its contents are not downloaded twice.
+func writeLocalFileByRanges(rangeReader internal.EntryRangeReader, header *tar.Header, localFile *os.File,
+	fsync bool, expectedChecksum *internal.FileChecksum, streams int) error {
+	if err := localFile.Truncate(header.Size); err != nil {
+		removeLocalFile(localFile)
+		return errors.Wrapf(err, "Interpret: failed to preallocate '%s'", header.Name)
+	}
+	if streams < 1 {
+		streams = 1
+	}
+	rangeSize := (header.Size + int64(streams) - 1) / int64(streams)
+	errGroup := new(errgroup.Group)
+	for offset := int64(0); offset < header.Size; offset += rangeSize {
+		offset, length := offset, rangeSize
+		if offset+length > header.Size {
+			length = header.Size - offset
+		}
+		errGroup.Go(func() error {
+			return copyRange(rangeReader, localFile, offset, length)
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		removeLocalFile(localFile)
+		return errors.Wrap(err, "Interpret: ranged copy failed")
+	}
+
+	if expectedChecksum != nil {
+		if err := verifyLocalFileChecksum(localFile.Name(), header, *expectedChecksum); err != nil {
+			removeLocalFile(localFile)
+			return err
+		}
+	}
+	if err := rangeReader.SkipContents(); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to continue the tar after '%s'", header.Name)
+	}
+
+	if err := localFile.Chmod(os.FileMode(header.Mode)); err != nil {
+		return errors.Wrap(err, "Interpret: chmod failed")
+	}
+	if fsync {
+		return errors.Wrap(localFile.Sync(), "Interpret: fsync failed")
+	}
+	return nil
+}
+
+// copyRange writes length bytes of the entry contents since the offset to the same offset of the localFile
+func copyRange(rangeReader internal.EntryRangeReader, localFile io.WriterAt, offset, length int64) error {
+	reader, err := rangeReader.ReadRange(offset, length)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	writer := &extentWriter{target: localFile, offset: offset, limit: offset + length}
+	written, err := io.Copy(writer, reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy the range at offset %d", offset)
+	}
+	if written != length {
+		return errors.Wrapf(io.ErrUnexpectedEOF, "range at offset %d has %d of %d bytes", offset, written, length)
+	}
+	return nil
+}
+
+// verifyLocalFileChecksum reads the written file back and compares its checksum with the expected one
+func verifyLocalFileChecksum(targetPath string, header *tar.Header, expectedChecksum internal.FileChecksum) error {
+	checksumHash, err := internal.NewChecksumHash(expectedChecksum.Algorithm)
+	if err != nil {
+		return errors.Wrapf(err, "Interpret: failed to verify checksum of '%s'", header.Name)
+	}
+	file, err := os.Open(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "Interpret: failed to read back '%s'", targetPath)
+	}
+	defer utility.LoggedClose(file, "")
+	if _, err = io.Copy(checksumHash, file); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to read back '%s'", targetPath)
+	}
+	actualChecksum := internal.NewFileChecksum(expectedChecksum.Algorithm, checksumHash)
+	if actualChecksum.Value != expectedChecksum.Value {
+		return newChecksumMismatchError(header.Name, expectedChecksum, actualChecksum.Value)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// fakeEntryRangeReader serves the ranges of the content, its sequential reads fail
+type fakeEntryRangeReader struct {
+	content []byte
+	mutex   sync.Mutex
+	ranges  int
+	skipped bool
+}
+
+var _ internal.EntryRangeReader = &fakeEntryRangeReader{}
+
+func (reader *fakeEntryRangeReader) Read(p []byte) (int, error) {
+	return 0, errors.New("the entry is read sequentially")
+}
+
+func (reader *fakeEntryRangeReader) SupportsRanges() bool { return true }
+
+func (reader *fakeEntryRangeReader) ReadRange(offset, length int64) (io.ReadCloser, error) {
+	reader.mutex.Lock()
+	defer reader.mutex.Unlock()
+	reader.ranges++
+	return io.NopCloser(bytes.NewReader(reader.content[offset : offset+length])), nil
+}
+
+func (reader *fakeEntryRangeReader) SkipContents() error {
+	reader.skipped = true
+	return nil
+}
+
+func setRangeTestSettings(threshold, streams int) func() {
+	viper.Set(internal.RestoreRangeThresholdSetting, threshold)
+	viper.Set(internal.RestoreRangeStreamsSetting, streams)
+	return func() {
+		viper.Set(internal.RestoreRangeThresholdSetting, nil)
+		viper.Set(internal.RestoreRangeStreamsSetting, 4)
+	}
+}
+
+func TestInterpret_WritesLargeFileByRanges(t *testing.T) {
+	defer setRangeTestSettings(10, 3)()
+
+	for _, useNew := range []bool{false, true} {
+		useNewUnwrapImplementation = useNew
+		dir := t.TempDir()
+		tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+		reader := &fakeEntryRangeReader{content: []byte("the contents written by the ranges")}
+
+		err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600,
+			Size: int64(len(reader.content))})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, reader.ranges)
+		assert.True(t, reader.skipped)
+		written, err := os.ReadFile(filepath.Join(dir, "file"))
+		assert.NoError(t, err)
+		assert.Equal(t, reader.content, written)
+	}
+	useNewUnwrapImplementation = false
+}
+
+func TestInterpret_RemovesRangedFileWithChecksumMismatch(t *testing.T) {
+	defer setRangeTestSettings(10, 2)()
+	viper.Set(internal.VerifyFileChecksumsSetting, true)
+	defer viper.Set(internal.VerifyFileChecksumsSetting, nil)
+
+	dir := t.TempDir()
+	filesMetadata := FilesMetadataDto{Files: internal.BackupFileList{"file": {Checksum: newSeedTestChecksum("other")}}}
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, filesMetadata, nil, false)
+	reader := &fakeEntryRangeReader{content: []byte("the contents written by the ranges")}
+
+	err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600,
+		Size: int64(len(reader.content))})
+	assert.Error(t, err)
+	assert.False(t, reader.skipped)
+	_, err = os.Stat(filepath.Join(dir, "file"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpret_ReadsSmallFileSequentially(t *testing.T) {
+	defer setRangeTestSettings(1024, 2)()
+
+	dir := t.TempDir()
+	tarInterpreter := NewFileTarInterpreter(dir, BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+	reader := &fakeEntryRangeReader{content: []byte("small")}
+
+	err := tarInterpreter.Interpret(reader, &tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 5})
+	assert.Error(t, err)
+	assert.Equal(t, 0, reader.ranges)
+}
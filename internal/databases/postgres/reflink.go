@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ReflinkedFiles returns the files cloned from the seed directory instead of being extracted
+func (result *UnwrapResult) ReflinkedFiles() []string {
+	result.reflinkedFilesMutex.Lock()
+	defer result.reflinkedFilesMutex.Unlock()
+	return append([]string{}, result.reflinkedFiles...)
+}
+
+// WrittenFiles returns the files extracted from the backup while the seed directory was set
+func (result *UnwrapResult) WrittenFiles() []string {
+	result.reflinkedFilesMutex.Lock()
+	defer result.reflinkedFilesMutex.Unlock()
+	return append([]string{}, result.writtenFiles...)
+}
+
+func (result *UnwrapResult) addReflinkedFile(fileName string) {
+	result.reflinkedFilesMutex.Lock()
+	result.reflinkedFiles = append(result.reflinkedFiles, fileName)
+	result.reflinkedFilesMutex.Unlock()
+}
+
+func (result *UnwrapResult) addWrittenFile(fileName string) {
+	result.reflinkedFilesMutex.Lock()
+	result.writtenFiles = append(result.writtenFiles, fileName)
+	result.reflinkedFilesMutex.Unlock()
+}
+
+// tryReflinkFromSeed clones the file from the SeedDirectory if the seed copy matches the checksum stored in the backup.
+// The file is extracted as usual if it has no checksum, is incremented, differs from the seed copy
+// or the file system does not support reflinks.
+func (tarInterpreter *FileTarInterpreter) tryReflinkFromSeed(fileInfo *tar.Header, targetPath string) bool {
+	fileDescription, ok := tarInterpreter.FilesMetadata.Files[fileInfo.Name]
+	if !ok || fileDescription.Checksum == nil || fileDescription.IsIncremented {
+		return false
+	}
+	seedPath := filepath.Join(tarInterpreter.SeedDirectory, fileInfo.Name)
+	seedFile, err := os.Open(seedPath)
+	if err != nil {
+		return false
+	}
+	defer utility.LoggedClose(seedFile, "")
+	if !isLocalFileUnchanged(seedFile, fileInfo.Size, *fileDescription.Checksum) {
+		return false
+	}
+
+	if err = tarInterpreter.prepareDirs(fileInfo.Name, targetPath); err != nil {
+		return false
+	}
+	if _, err = os.Lstat(targetPath); err == nil {
+		// do not replace the existing files, they may be newer than the seed ones
+		return false
+	}
+	targetFile, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(fileInfo.Mode))
+	if err != nil {
+		return false
+	}
+	defer utility.LoggedClose(targetFile, "")
+	if err = reflinkFile(targetFile, seedFile); err != nil {
+		tracelog.DebugLogger.Printf("Failed to reflink '%s' from the seed directory, extracting it: %v", fileInfo.Name, err)
+		removeLocalFile(targetFile)
+		return false
+	}
+	if err = targetFile.Chmod(os.FileMode(fileInfo.Mode)); err != nil {
+		tracelog.WarningLogger.Printf("Failed to chmod the reflinked '%s', extracting it: %v", targetPath, err)
+		removeLocalFile(targetFile)
+		return false
+	}
+	return true
+}
+
+// isLocalFileUnchanged checks the file has the expected size and checksum
+func isLocalFileUnchanged(localFile *os.File, expectedSize int64, expectedChecksum internal.FileChecksum) bool {
+	localFileInfo, err := localFile.Stat()
+	if err != nil || !localFileInfo.Mode().IsRegular() || localFileInfo.Size() != expectedSize {
+		return false
+	}
+	checksumHash, err := internal.NewChecksumHash(expectedChecksum.Algorithm)
+	if err != nil {
+		return false
+	}
+	if _, err = io.Copy(checksumHash, localFile); err != nil {
+		return false
+	}
+	return internal.NewFileChecksum(expectedChecksum.Algorithm, checksumHash).Value == expectedChecksum.Value
+}
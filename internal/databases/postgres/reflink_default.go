@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package postgres
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func reflinkFile(target, seed *os.File) error {
+	return errors.New("reflinks are supported only on linux")
+}
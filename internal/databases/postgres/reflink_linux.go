@@ -0,0 +1,16 @@
+//go:build linux
+// +build linux
+
+package postgres
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflinkFile makes the target share the seed file extents by the FICLONE ioctl,
+// fails if the file system does not support copy-on-write or the files are on different file systems
+func reflinkFile(target, seed *os.File) error {
+	return unix.IoctlFileClone(int(target.Fd()), int(seed.Fd()))
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func newSeedTestChecksum(content string) *internal.FileChecksum {
+	digest := sha256.Sum256([]byte(content))
+	return &internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm, Value: hex.EncodeToString(digest[:])}
+}
+
+func TestInterpretWithSeedDirectory(t *testing.T) {
+	seedDirectory := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(seedDirectory, "base", "1"), 0700))
+	for name, content := range map[string]string{"base/1/unchanged": "same", "base/1/changed": "old"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(seedDirectory, name), []byte(content), 0600))
+	}
+
+	tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{}, FilesMetadataDto{Files: internal.BackupFileList{
+		"base/1/unchanged": {Checksum: newSeedTestChecksum("same")},
+		"base/1/changed":   {Checksum: newSeedTestChecksum("new")},
+	}}, nil, false)
+	tarInterpreter.SeedDirectory = seedDirectory
+
+	contents := map[string]string{"base/1/unchanged": "same", "base/1/changed": "new", "base/1/missing": "missing"}
+	for name, content := range contents {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(content), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     int64(len(content)),
+		})
+		assert.NoError(t, err)
+
+		restored, err := os.ReadFile(filepath.Join(tarInterpreter.DBDataDirectory, name))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(restored))
+	}
+
+	reflinked, written := tarInterpreter.UnwrapResult.ReflinkedFiles(), tarInterpreter.UnwrapResult.WrittenFiles()
+	assert.Len(t, append(reflinked, written...), len(contents))
+	// the unchanged file is reflinked only on the file systems supporting copy-on-write
+	assert.Subset(t, []string{"base/1/unchanged"}, reflinked)
+	// the reflinked files are complete, so the reverse delta unpack does not patch them
+	assert.Subset(t, tarInterpreter.UnwrapResult.completedFiles, reflinked)
+	assert.Subset(t, written, []string{"base/1/changed", "base/1/missing"})
+}
+
+func TestIsSeedFileUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed")
+	assert.NoError(t, os.WriteFile(path, []byte("seed"), 0600))
+	seedFile, err := os.Open(path)
+	assert.NoError(t, err)
+	defer seedFile.Close()
+
+	for _, seedCase := range []struct {
+		size     int64
+		checksum *internal.FileChecksum
+		expected bool
+	}{
+		{4, newSeedTestChecksum("seed"), true},
+		{4, newSeedTestChecksum("diff"), false},
+		{5, newSeedTestChecksum("seed"), false},
+		{4, &internal.FileChecksum{Algorithm: "unknown", Value: ""}, false},
+	} {
+		_, err = seedFile.Seek(0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, seedCase.expected, isLocalFileUnchanged(seedFile, seedCase.size, *seedCase.checksum))
+	}
+}
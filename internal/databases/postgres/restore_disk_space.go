@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// InsufficientDiskSpaceError is returned once the projected size of the restored backup
+// does not fit into the free space of the target filesystem
+type InsufficientDiskSpaceError struct {
+	error
+	Directory      string
+	RequiredBytes  int64
+	AvailableBytes int64
+}
+
+func newInsufficientDiskSpaceError(directory string, requiredBytes, availableBytes int64) InsufficientDiskSpaceError {
+	return InsufficientDiskSpaceError{
+		error: errors.Errorf("restore needs %d bytes in '%s', but only %d bytes are available, use --force to restore anyway",
+			requiredBytes, directory, availableBytes),
+		Directory: directory, RequiredBytes: requiredBytes, AvailableBytes: availableBytes,
+	}
+}
+
+func (err InsufficientDiskSpaceError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func (err InsufficientDiskSpaceError) Unwrap() error {
+	return err.error
+}
+
+// checkRestoreDiskSpace refuses the restore of the backup into the dbDataDirectory once its projected size
+// plus the WALG_RESTORE_DISK_HEADROOM_BYTES exceeds the space available on the filesystem of the directory.
+// The check is skipped with the warning if the available space can not be determined.
+func checkRestoreDiskSpace(backup Backup, dbDataDirectory string) error {
+	requiredBytes, err := projectRestoreSize(backup)
+	if err != nil {
+		return err
+	}
+	requiredBytes += viper.GetInt64(internal.RestoreDiskHeadroomSetting)
+
+	directory := nearestExistingDirectory(dbDataDirectory)
+	availableBytes, err := availableDiskSpace(directory)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to determine the free space in '%s', skipping the check: %v", directory, err)
+		return nil
+	}
+	tracelog.DebugLogger.Printf("Restore needs %d bytes, %d bytes are available in '%s'",
+		requiredBytes, availableBytes, directory)
+	if requiredBytes > availableBytes {
+		return newInsufficientDiskSpaceError(directory, requiredBytes, availableBytes)
+	}
+	return nil
+}
+
+// projectRestoreSize returns the expected size of the restored backup: the largest uncompressed size
+// among the backups of its delta chain, since the deltas rewrite the files of the base backup in place
+func projectRestoreSize(backup Backup) (int64, error) {
+	var size int64
+	for {
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return 0, err
+		}
+		if sentinelDto.UncompressedSize > size {
+			size = sentinelDto.UncompressedSize
+		}
+		if !sentinelDto.IsIncremental() {
+			return size, nil
+		}
+		backup = NewBackup(backup.Folder, *sentinelDto.IncrementFrom)
+	}
+}
+
+// nearestExistingDirectory returns the directory itself or its closest existing parent,
+// the restore creates the missing ones on the same filesystem
+func nearestExistingDirectory(directory string) string {
+	directory = filepath.Clean(utility.ResolveSymlink(directory))
+	for {
+		if _, err := os.Stat(directory); err == nil {
+			return directory
+		}
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return directory
+		}
+		directory = parent
+	}
+}
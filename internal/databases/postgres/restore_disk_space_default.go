@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package postgres
+
+import "golang.org/x/sys/unix"
+
+// availableDiskSpace returns the number of bytes available to the unprivileged user on the filesystem of the path
+func availableDiskSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestCheckRestoreDiskSpace(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], baseBackupFolder)
+	baseName, incrementFromLSN, count := flattenBaseBackupName, flattenIncrementLSN, 1
+	assert.NoError(t, internal.UploadSentinel(uploader, BackupSentinelDto{UncompressedSize: 1000}, baseName))
+	assert.NoError(t, internal.UploadSentinel(uploader, BackupSentinelDto{UncompressedSize: 100,
+		IncrementFrom: &baseName, IncrementFullName: &baseName, IncrementFromLSN: &incrementFromLSN,
+		IncrementCount: &count}, flattenDeltaBackupName))
+
+	backup := NewBackup(baseBackupFolder, flattenDeltaBackupName)
+	size, err := projectRestoreSize(backup)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), size)
+
+	// the missing data directory is checked by its existing parent
+	dbDataDirectory := filepath.Join(t.TempDir(), "missing", "data")
+	assert.NoError(t, checkRestoreDiskSpace(backup, dbDataDirectory))
+
+	viper.Set(internal.RestoreDiskHeadroomSetting, int64(1)<<62)
+	defer viper.Set(internal.RestoreDiskHeadroomSetting, nil)
+	err = checkRestoreDiskSpace(backup, dbDataDirectory)
+	var diskSpaceErr InsufficientDiskSpaceError
+	if assert.True(t, errors.As(err, &diskSpaceErr)) {
+		assert.Equal(t, int64(1)<<62+1000, diskSpaceErr.RequiredBytes)
+		assert.Equal(t, filepath.Dir(filepath.Dir(dbDataDirectory)), diskSpaceErr.Directory)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package postgres
+
+import "github.com/pkg/errors"
+
+func availableDiskSpace(path string) (int64, error) {
+	return 0, errors.New("the free space is not determined on Windows")
+}
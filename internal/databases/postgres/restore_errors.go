@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// ChecksumMismatchError is returned once the extracted file does not match the checksum stored in the backup
+type ChecksumMismatchError struct {
+	error
+	FileName  string
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func newChecksumMismatchError(fileName string, expected internal.FileChecksum, actual string) ChecksumMismatchError {
+	return ChecksumMismatchError{
+		error: errors.Errorf("Interpret: %s checksum mismatch for '%s': expected %s, got %s",
+			expected.Algorithm, fileName, expected.Value, actual),
+		FileName: fileName, Algorithm: expected.Algorithm, Expected: expected.Value, Actual: actual,
+	}
+}
+
+func (err ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func (err ChecksumMismatchError) Unwrap() error {
+	return err.error
+}
+
+// PathTraversalError is returned once the tar entry or its symlink target resolves outside the allowed directories
+type PathTraversalError struct {
+	error
+	FileName string
+	// TargetPath is the local path the entry resolves to, or the symlink target
+	TargetPath   string
+	AllowedRoots []string
+}
+
+func newPathTraversalError(err error, fileName, targetPath string, allowedRoots ...string) PathTraversalError {
+	return PathTraversalError{error: err, FileName: fileName, TargetPath: targetPath, AllowedRoots: allowedRoots}
+}
+
+func (err PathTraversalError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func (err PathTraversalError) Unwrap() error {
+	return err.error
+}
+
+// DiskFullError is returned once the extraction of the file runs out of the disk space (ENOSPC)
+type DiskFullError struct {
+	error
+	FileName string
+	// WrittenBytes is the size of the files extracted completely before the disk became full
+	WrittenBytes int64
+}
+
+func (err DiskFullError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func (err DiskFullError) Unwrap() error {
+	return err.error
+}
+
+// asDiskFullError returns the DiskFullError if the err is caused by ENOSPC, the err itself otherwise
+func asDiskFullError(err error, fileName string, writtenBytes int64) error {
+	var diskFullError DiskFullError
+	if err == nil || !errors.Is(err, syscall.ENOSPC) || errors.As(err, &diskFullError) {
+		return err
+	}
+	return DiskFullError{error: errors.Wrapf(err, "disk is full after %d bytes were restored", writtenBytes),
+		FileName: fileName, WrittenBytes: writtenBytes}
+}
+
+// LinkCreationError is returned once the hardlink or the symlink from the tar can not be created
+type LinkCreationError struct {
+	error
+	FileName   string
+	LinkTarget string
+}
+
+func newLinkCreationError(err error, fileName, linkTarget string) LinkCreationError {
+	return LinkCreationError{error: err, FileName: fileName, LinkTarget: linkTarget}
+}
+
+func (err LinkCreationError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func (err LinkCreationError) Unwrap() error {
+	return err.error
+}
@@ -0,0 +1,79 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestInterpret_ReturnsChecksumMismatchError(t *testing.T) {
+	viper.Set(internal.VerifyFileChecksumsSetting, true)
+	defer viper.Set(internal.VerifyFileChecksumsSetting, nil)
+	expected := internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm, Value: "00"}
+	filesMetadata := postgres.FilesMetadataDto{Files: internal.BackupFileList{"file": {Checksum: &expected}}}
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{}, filesMetadata,
+		nil, false)
+
+	err := tarInterpreter.Interpret(bytes.NewReader([]byte("contents")),
+		&tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 8})
+	var mismatchErr postgres.ChecksumMismatchError
+	assert.True(t, errors.As(err, &mismatchErr))
+	assert.Equal(t, "file", mismatchErr.FileName)
+	assert.Equal(t, internal.SHA256ChecksumAlgorithm, mismatchErr.Algorithm)
+	assert.Equal(t, "00", mismatchErr.Expected)
+	assert.NotEqual(t, "00", mismatchErr.Actual)
+	assert.Contains(t, err.Error(), "checksum mismatch for 'file'")
+}
+
+func TestInterpret_ReturnsPathTraversalError(t *testing.T) {
+	dir := t.TempDir()
+	tarInterpreter := postgres.NewFileTarInterpreter(dir, postgres.BackupSentinelDto{}, postgres.FilesMetadataDto{},
+		nil, false)
+
+	err := tarInterpreter.Interpret(bytes.NewReader(nil),
+		&tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0600})
+	var traversalErr postgres.PathTraversalError
+	assert.True(t, errors.As(err, &traversalErr))
+	assert.Equal(t, "../escape", traversalErr.FileName)
+	assert.Equal(t, []string{dir}, traversalErr.AllowedRoots)
+	assert.Contains(t, err.Error(), "escapes the data directory")
+}
+
+func TestInterpret_ReturnsDiskFullError(t *testing.T) {
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	tarInterpreter.ContentFilter = func(name string, reader io.Reader) (io.Reader, error) {
+		return iotest.ErrReader(syscall.ENOSPC), nil
+	}
+
+	err := tarInterpreter.Interpret(bytes.NewReader([]byte("contents")),
+		&tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 0600, Size: 8})
+	var diskFullErr postgres.DiskFullError
+	assert.True(t, errors.As(err, &diskFullErr))
+	assert.Equal(t, "file", diskFullErr.FileName)
+	assert.Equal(t, int64(0), diskFullErr.WrittenBytes)
+	assert.True(t, errors.Is(err, syscall.ENOSPC))
+}
+
+func TestInterpret_ReturnsLinkCreationError(t *testing.T) {
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+
+	err := tarInterpreter.Interpret(bytes.NewReader(nil),
+		&tar.Header{Name: "link", Linkname: "missing", Typeflag: tar.TypeLink})
+	var linkErr postgres.LinkCreationError
+	assert.True(t, errors.As(err, &linkErr))
+	assert.Equal(t, "link", linkErr.FileName)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.Contains(t, err.Error(), "failed to create hardlink")
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// DefaultRestoreExcludePatterns are the optional files a standby can be bootstrapped without:
+// the statistics temporary files, the server logs and the replication slots of the primary
+var DefaultRestoreExcludePatterns = []string{"pg_stat_tmp", "pg_log", "log", "pg_replslot"}
+
+// GetRestoreExcludePatterns returns the patterns of the files not to restore set by the WALG_RESTORE_EXCLUDE
+// (comma-separated globs), the DefaultRestoreExcludePatterns are used if it is not set and excludeOptional is true.
+// Nil is returned if nothing should be excluded.
+func GetRestoreExcludePatterns(excludeOptional bool) ([]string, error) {
+	if patternsStr, ok := internal.GetSetting(internal.RestoreExcludeSetting); ok && patternsStr != "" {
+		return ParseRestoreExcludePatterns(strings.Split(patternsStr, ","))
+	}
+	if excludeOptional {
+		return DefaultRestoreExcludePatterns, nil
+	}
+	return nil, nil
+}
+
+// ParseRestoreExcludePatterns validates the path.Match patterns relative to the data directory.
+// The pattern excludes the matching files and everything under the matching directories.
+func ParseRestoreExcludePatterns(patterns []string) ([]string, error) {
+	return parseRestorePatterns(patterns, internal.RestoreExcludeSetting)
+}
+
+// parseRestorePatterns validates the path.Match patterns of the setting
+func parseRestorePatterns(patterns []string, setting string) ([]string, error) {
+	parsed := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			return nil, errors.Errorf("empty pattern in %s", setting)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s pattern '%s'", setting, pattern)
+		}
+		parsed = append(parsed, pattern)
+	}
+	return parsed, nil
+}
+
+// GetExcludedFilesToUnwrap removes the files matching the exclude patterns from the files to unwrap,
+// the files required to start the server (UtilityFilePaths) are never excluded.
+// All the files of the backup are considered if filesToUnwrap is UnwrapAll.
+func (backup *Backup) GetExcludedFilesToUnwrap(filesToUnwrap map[string]bool,
+	excludePatterns []string) (map[string]bool, error) {
+	if len(excludePatterns) == 0 {
+		return filesToUnwrap, nil
+	}
+	if filesToUnwrap == nil {
+		_, filesMeta, err := backup.GetSentinelAndFilesMetadata()
+		if err != nil {
+			return nil, err
+		}
+		if len(filesMeta.Files) == 0 {
+			tracelog.WarningLogger.Printf("Backup has no files metadata, %s is ignored", internal.RestoreExcludeSetting)
+			return UnwrapAll, nil
+		}
+		filesToUnwrap = make(map[string]bool, len(filesMeta.Files))
+		for file := range filesMeta.Files {
+			filesToUnwrap[file] = true
+		}
+		for utilityFilePath := range UtilityFilePaths {
+			filesToUnwrap[utilityFilePath] = true
+		}
+	}
+	return ExcludeFilesToUnwrap(filesToUnwrap, excludePatterns), nil
+}
+
+// ExcludeFilesToUnwrap returns the copy of filesToUnwrap without the files matching the exclude patterns
+func ExcludeFilesToUnwrap(filesToUnwrap map[string]bool, excludePatterns []string) map[string]bool {
+	remaining := make(map[string]bool, len(filesToUnwrap))
+	excludedCount := 0
+	for file, unwrap := range filesToUnwrap {
+		if !UtilityFilePaths[file] && matchesRestorePatterns(file, excludePatterns) {
+			excludedCount++
+			continue
+		}
+		remaining[file] = unwrap
+	}
+	tracelog.InfoLogger.Printf("%d files are excluded from the restore by the patterns: %s",
+		excludedCount, strings.Join(excludePatterns, ", "))
+	return remaining
+}
+
+// matchesRestorePatterns matches the file path and all its parent directories against the patterns
+func matchesRestorePatterns(file string, patterns []string) bool {
+	parts := strings.Split(strings.Trim(file, "/"), "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, prefix); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestGetRestoreExcludePatterns(t *testing.T) {
+	patterns, err := postgres.GetRestoreExcludePatterns(false)
+	assert.NoError(t, err)
+	assert.Nil(t, patterns)
+
+	patterns, err = postgres.GetRestoreExcludePatterns(true)
+	assert.NoError(t, err)
+	assert.Equal(t, postgres.DefaultRestoreExcludePatterns, patterns)
+
+	viper.Set(internal.RestoreExcludeSetting, "/pg_log/, base/pgsql_tmp ,*.history")
+	defer viper.Set(internal.RestoreExcludeSetting, nil)
+	for _, excludeOptional := range []bool{false, true} {
+		patterns, err = postgres.GetRestoreExcludePatterns(excludeOptional)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"pg_log", "base/pgsql_tmp", "*.history"}, patterns)
+	}
+
+	for _, invalid := range []string{"pg_log,,base", "pg_[log", " / "} {
+		viper.Set(internal.RestoreExcludeSetting, invalid)
+		_, err = postgres.GetRestoreExcludePatterns(true)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestExcludeFilesToUnwrap(t *testing.T) {
+	filesToUnwrap := map[string]bool{
+		"/global/1262":                 true,
+		"/global/pg_control":           true,
+		"backup_label":                 true,
+		"/pg_stat_tmp/global.stat":     true,
+		"/pg_log/postgresql.log":       true,
+		"/pg_replslot/slot/state":      true,
+		"/base/16384/16385":            true,
+		"/base/pgsql_tmp/pgsql_tmp1.0": true,
+		"/pg_logical/snapshots/0.snap": true,
+	}
+
+	files := postgres.ExcludeFilesToUnwrap(filesToUnwrap, postgres.DefaultRestoreExcludePatterns)
+	assert.Equal(t, map[string]bool{
+		"/global/1262":                 true,
+		"/global/pg_control":           true,
+		"backup_label":                 true,
+		"/base/16384/16385":            true,
+		"/base/pgsql_tmp/pgsql_tmp1.0": true,
+		"/pg_logical/snapshots/0.snap": true,
+	}, files)
+	assert.Len(t, filesToUnwrap, 9)
+
+	files = postgres.ExcludeFilesToUnwrap(filesToUnwrap, []string{"global", "base/*/pgsql_tmp*", "*_label"})
+	assert.True(t, files["/global/pg_control"])
+	assert.True(t, files["backup_label"])
+	assert.False(t, files["/global/1262"])
+	assert.False(t, files["/base/pgsql_tmp/pgsql_tmp1.0"])
+	assert.True(t, files["/base/16384/16385"])
+}
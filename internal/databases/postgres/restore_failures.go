@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// FailedFile is the file which was not restored in the continue on error mode
+type FailedFile struct {
+	FileName string
+	Err      error
+}
+
+// RestoreFailuresError is returned once the restore in the continue on error mode is finished,
+// if any of the files were not restored
+type RestoreFailuresError struct {
+	error
+	FailedFiles []FailedFile
+}
+
+// newRestoreFailuresError lists the failed files, nil is returned if there are none
+func newRestoreFailuresError(failedFiles []FailedFile) error {
+	if len(failedFiles) == 0 {
+		return nil
+	}
+	failures := make([]string, 0, len(failedFiles))
+	for _, failedFile := range failedFiles {
+		failures = append(failures, fmt.Sprintf("'%s': %v", failedFile.FileName, failedFile.Err))
+	}
+	return RestoreFailuresError{
+		error:       errors.Errorf("%d files were not restored:\n%s", len(failedFiles), strings.Join(failures, "\n")),
+		FailedFiles: failedFiles,
+	}
+}
+
+func (err RestoreFailuresError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// FailedFiles returns the files which were not restored in the continue on error mode
+func (result *UnwrapResult) FailedFiles() []FailedFile {
+	result.failedFilesMutex.Lock()
+	defer result.failedFilesMutex.Unlock()
+	return append([]FailedFile{}, result.failedFiles...)
+}
+
+func (result *UnwrapResult) addFailedFile(failedFile FailedFile) {
+	result.failedFilesMutex.Lock()
+	result.failedFiles = append(result.failedFiles, failedFile)
+	result.failedFilesMutex.Unlock()
+}
+
+func (result *UnwrapResult) removeFailedFile(fileName string) {
+	result.failedFilesMutex.Lock()
+	defer result.failedFilesMutex.Unlock()
+	for i, failedFile := range result.failedFiles {
+		if failedFile.FileName == fileName {
+			result.failedFiles = append(result.failedFiles[:i], result.failedFiles[i+1:]...)
+			return
+		}
+	}
+}
+
+// isFatalRestoreError checks if the failure must abort the restore even in the continue on error mode:
+// the disk is full or the restore is cancelled, so the remaining files can not be restored either
+func isFatalRestoreError(ctx context.Context, err error) bool {
+	var diskFullError DiskFullError
+	if errors.As(err, &diskFullError) || errors.Is(err, syscall.ENOSPC) {
+		return true
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return true
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,143 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestInterpretContinueOnError_SkipsCorruptFile(t *testing.T) {
+	tarInterpreter := newChecksumVerifyingInterpreter(t, internal.BackupFileList{
+		"/base/1/1":       {Checksum: sha256Checksum("first")},
+		"/base/1/corrupt": {Checksum: sha256Checksum("expected")},
+		"/base/1/3":       {Checksum: sha256Checksum("third")},
+	})
+	tarInterpreter.ContinueOnError = true
+	folder := memory.NewFolder("", memory.NewStorage())
+	files := []internal.ReaderMaker{putTestTar(t, folder, "part_1.tar",
+		testTarEntry{header: tar.Header{Name: "/base/1/1", Typeflag: tar.TypeReg, Mode: 0600}, content: "first"},
+		testTarEntry{header: tar.Header{Name: "/base/1/corrupt", Typeflag: tar.TypeReg, Mode: 0600}, content: "damaged"},
+		testTarEntry{header: tar.Header{Name: "/base/1/3", Typeflag: tar.TypeReg, Mode: 0600}, content: "third"})}
+
+	assert.NoError(t, internal.ExtractAll(tarInterpreter, files))
+	for name, content := range map[string]string{"base/1/1": "first", "base/1/3": "third"} {
+		data, err := os.ReadFile(path.Join(tarInterpreter.DBDataDirectory, name))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+	_, err := os.Stat(path.Join(tarInterpreter.DBDataDirectory, "base/1/corrupt"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = tarInterpreter.OnInterpretFinish()
+	var failuresError postgres.RestoreFailuresError
+	assert.True(t, errors.As(err, &failuresError))
+	assert.Len(t, failuresError.FailedFiles, 1)
+	assert.Equal(t, "/base/1/corrupt", failuresError.FailedFiles[0].FileName)
+	var mismatchError postgres.ChecksumMismatchError
+	assert.True(t, errors.As(failuresError.FailedFiles[0].Err, &mismatchError))
+	assert.Equal(t, failuresError.FailedFiles, tarInterpreter.UnwrapResult.FailedFiles())
+}
+
+func TestInterpretContinueOnError_AbortsOnCancellation(t *testing.T) {
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	tarInterpreter.ContinueOnError = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := tarInterpreter.InterpretContext(ctx, bytes.NewBufferString("content"), &tar.Header{
+		Name:     "/base/1/1",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, tarInterpreter.UnwrapResult.FailedFiles())
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+}
+
+type nopSleeper struct{}
+
+func (nopSleeper) Sleep() {}
+
+// droppingReaderMaker drops the first read of the archive in the middle as the dropped connection does
+type droppingReaderMaker struct {
+	internal.ReaderMaker
+	reads int
+}
+
+func (readerMaker *droppingReaderMaker) Reader() (io.ReadCloser, error) {
+	reader, err := readerMaker.ReaderMaker.Reader()
+	if err != nil {
+		return nil, err
+	}
+	readerMaker.reads++
+	if readerMaker.reads > 1 {
+		return reader, nil
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: io.MultiReader(io.LimitReader(reader, 1024), iotest.ErrReader(io.ErrUnexpectedEOF)),
+		Closer: reader,
+	}, nil
+}
+
+func TestExtractAllContinueOnError_SkipsCorruptArchives(t *testing.T) {
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	tarInterpreter.ContinueOnError = true
+	folder := memory.NewFolder("", memory.NewStorage())
+	files := []internal.ReaderMaker{putTestTar(t, folder, "part_1.tar",
+		testTarEntry{header: tar.Header{Name: "/base/1/1", Typeflag: tar.TypeReg, Mode: 0600}, content: "first"})}
+	// neither the garbage tar headers nor the garbage lz4 stream can be read
+	for _, name := range []string{"part_2.tar", "part_3.tar.lz4"} {
+		assert.NoError(t, folder.PutObject(name, bytes.NewBufferString(strings.Repeat("garbage", 100))))
+		files = append(files, internal.NewStorageReaderMaker(folder, name))
+	}
+
+	// the dropped read of the archive is retried instead of being recorded as its failure
+	files = append(files, &droppingReaderMaker{ReaderMaker: putTestTar(t, folder, "part_4.tar",
+		testTarEntry{header: tar.Header{Name: "/base/1/4", Typeflag: tar.TypeReg, Mode: 0600},
+			content: strings.Repeat("fourth", 1000)})})
+
+	assert.NoError(t, internal.ExtractAllWithSleeper(tarInterpreter, files, nopSleeper{}))
+	for name, content := range map[string]string{"base/1/1": "first", "base/1/4": strings.Repeat("fourth", 1000)} {
+		data, err := os.ReadFile(path.Join(tarInterpreter.DBDataDirectory, name))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	err := tarInterpreter.OnInterpretFinish()
+	var failuresError postgres.RestoreFailuresError
+	assert.True(t, errors.As(err, &failuresError))
+	failedNames := make([]string, 0)
+	for _, failedFile := range failuresError.FailedFiles {
+		failedNames = append(failedNames, failedFile.FileName)
+	}
+	assert.ElementsMatch(t, []string{"part_2.tar", "part_3.tar.lz4"}, failedNames)
+}
+
+func TestExtractAll_AbortsOnCorruptArchive(t *testing.T) {
+	viper.Set(internal.DownloadConcurrencySetting, 1)
+	defer viper.Set(internal.DownloadConcurrencySetting, nil)
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	folder := memory.NewFolder("", memory.NewStorage())
+	assert.NoError(t, folder.PutObject("part_1.tar", bytes.NewBufferString(strings.Repeat("garbage", 100))))
+
+	assert.Error(t, internal.ExtractAll(tarInterpreter, []internal.ReaderMaker{
+		internal.NewStorageReaderMaker(folder, "part_1.tar")}))
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+}
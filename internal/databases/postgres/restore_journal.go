@@ -0,0 +1,298 @@
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// restoreJournalHeader is the first line of the journal
+type restoreJournalHeader struct {
+	BackupName string `json:"backup_name"`
+}
+
+// restoreJournalEntry records the tar member extracted completely from the backup of the restored chain
+type restoreJournalEntry struct {
+	Backup   string `json:"backup"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Checksum string `json:"sha256"`
+}
+
+// RestoreJournalMismatchError is returned once the journal was written by the restore of another backup
+type RestoreJournalMismatchError struct {
+	error
+	JournalBackupName string
+	BackupName        string
+}
+
+func newRestoreJournalMismatchError(journalPath, journalBackupName, backupName string) RestoreJournalMismatchError {
+	return RestoreJournalMismatchError{
+		error: errors.Errorf("restore journal '%s' belongs to backup '%s', refusing to resume the restore of '%s'",
+			journalPath, journalBackupName, backupName),
+		JournalBackupName: journalBackupName, BackupName: backupName,
+	}
+}
+
+func (err RestoreJournalMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func (err RestoreJournalMismatchError) Unwrap() error {
+	return err.error
+}
+
+// RestoreJournal records the files extracted completely by each backup of the restored chain,
+// so the interrupted restore of the same backup skips them once rerun.
+// Each record is flushed to disk before the next file is reported complete. The restored file is hashed
+// on completion and the hash is compared with the file on disk on resume: the file changed since is extracted again.
+type RestoreJournal struct {
+	path       string
+	backupName string
+	resumed    bool
+
+	mutex sync.Mutex
+	file  *os.File
+	// completed lists the files completed by each backup of the chain
+	completed map[string]map[string]bool
+	// latest is the last record of each file, it describes the file contents on disk
+	latest map[string]restoreJournalEntry
+	// verified lists the files matching their last records on disk
+	verified map[string]bool
+}
+
+// openConfiguredRestoreJournal opens the journal set by WALG_RESTORE_JOURNAL, nil if it is not set
+func openConfiguredRestoreJournal(backupName string) (*RestoreJournal, error) {
+	journalPath := viper.GetString(internal.RestoreJournalSetting)
+	if journalPath == "" {
+		return nil, nil
+	}
+	return OpenRestoreJournal(journalPath, backupName)
+}
+
+// OpenRestoreJournal resumes the journal of the backup restore or starts the new one,
+// the journal of another backup is refused with the RestoreJournalMismatchError
+func OpenRestoreJournal(journalPath, backupName string) (*RestoreJournal, error) {
+	journal := &RestoreJournal{
+		path:       journalPath,
+		backupName: backupName,
+		completed:  make(map[string]map[string]bool),
+		latest:     make(map[string]restoreJournalEntry),
+		verified:   make(map[string]bool),
+	}
+	validSize, err := journal.load()
+	if err != nil {
+		return nil, err
+	}
+
+	journal.file, err = os.OpenFile(journalPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open restore journal '%s'", journalPath)
+	}
+	// the record torn by the interruption is dropped
+	if err = journal.file.Truncate(validSize); err == nil {
+		_, err = journal.file.Seek(validSize, io.SeekStart)
+	}
+	if err == nil && !journal.resumed {
+		err = journal.append(restoreJournalHeader{BackupName: backupName})
+		if err == nil {
+			err = syncDirectory(filepath.Dir(journalPath))
+		}
+	}
+	if err != nil {
+		utility.LoggedClose(journal.file, "")
+		return nil, errors.Wrapf(err, "failed to prepare restore journal '%s'", journalPath)
+	}
+	if journal.resumed {
+		tracelog.InfoLogger.Printf("Resuming the restore of %s by journal '%s': %d files are already restored",
+			backupName, journalPath, len(journal.latest))
+	}
+	return journal, nil
+}
+
+// load reads the existing journal, returns the size of its complete records
+func (journal *RestoreJournal) load() (int64, error) {
+	contents, err := os.ReadFile(journal.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read restore journal '%s'", journal.path)
+	}
+
+	var validSize int64
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(nil, len(contents)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if int(validSize)+len(line) >= len(contents) {
+			// the last record has no line end, so it may be incomplete
+			break
+		}
+		if !journal.resumed {
+			var header restoreJournalHeader
+			if err := json.Unmarshal(line, &header); err != nil {
+				return 0, errors.Wrapf(err, "failed to parse restore journal '%s' header", journal.path)
+			}
+			if header.BackupName != journal.backupName {
+				return 0, newRestoreJournalMismatchError(journal.path, header.BackupName, journal.backupName)
+			}
+			journal.resumed = true
+		} else {
+			var entry restoreJournalEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return 0, errors.Wrapf(err, "failed to parse restore journal '%s' record", journal.path)
+			}
+			journal.addEntry(entry, false)
+		}
+		validSize += int64(len(line)) + 1
+	}
+	return validSize, nil
+}
+
+func (journal *RestoreJournal) addEntry(entry restoreJournalEntry, verified bool) {
+	if journal.completed[entry.Backup] == nil {
+		journal.completed[entry.Backup] = make(map[string]bool)
+	}
+	journal.completed[entry.Backup][entry.Name] = true
+	journal.latest[entry.Name] = entry
+	journal.verified[entry.Name] = verified
+}
+
+// append writes the record and flushes it to disk
+func (journal *RestoreJournal) append(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err = journal.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return journal.file.Sync()
+}
+
+// Resumed is true if the journal records the earlier attempt to restore the backup
+func (journal *RestoreJournal) Resumed() bool {
+	return journal.resumed
+}
+
+// pendingFiles excludes the files completed by the backup of the chain from the filesToUnwrap,
+// the completed file is kept if it differs from the last record of it
+func (journal *RestoreJournal) pendingFiles(backupName string, filesToUnwrap map[string]bool) map[string]bool {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	pending := make(map[string]bool, len(filesToUnwrap))
+	skipped := 0
+	for fileName, value := range filesToUnwrap {
+		if journal.completed[backupName][fileName] && journal.verifyFile(fileName) {
+			skipped++
+			continue
+		}
+		pending[fileName] = value
+	}
+	if skipped > 0 {
+		tracelog.InfoLogger.Printf("Skipping %d files of %s restored before the interruption", skipped, backupName)
+	}
+	return pending
+}
+
+// verifyFile compares the file on disk with its last record once, the records of the changed file are forgotten
+func (journal *RestoreJournal) verifyFile(fileName string) bool {
+	if journal.verified[fileName] {
+		return true
+	}
+	entry, ok := journal.latest[fileName]
+	if !ok {
+		return false
+	}
+	checksum, err := fileSHA256(entry.Path)
+	if err == nil && checksum != entry.Checksum {
+		err = errors.New("checksum mismatch")
+	}
+	if err == nil {
+		journal.verified[fileName] = true
+		return true
+	}
+	tracelog.WarningLogger.Printf("File '%s' changed since it was restored, extracting it again: %v", fileName, err)
+	for _, completed := range journal.completed {
+		delete(completed, fileName)
+	}
+	delete(journal.latest, fileName)
+	return false
+}
+
+// fileCompleted records the file extracted by the backup of the chain
+func (journal *RestoreJournal) fileCompleted(backupName, fileName, targetPath string) error {
+	checksum, err := fileSHA256(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to hash restored file '%s' for the restore journal", targetPath)
+	}
+	entry := restoreJournalEntry{Backup: backupName, Name: fileName, Path: targetPath, Checksum: checksum}
+
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	if err = journal.append(entry); err != nil {
+		return errors.Wrapf(err, "failed to write restore journal '%s'", journal.path)
+	}
+	// the file written by this run needs no verification
+	journal.addEntry(entry, true)
+	return nil
+}
+
+// Close closes the journal, it is kept on disk to resume the restore
+func (journal *RestoreJournal) Close() error {
+	return journal.file.Close()
+}
+
+// Remove deletes the journal of the finished restore
+func (journal *RestoreJournal) Remove() error {
+	if err := journal.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(journal.path)
+}
+
+// journalFileCompleted records the extracted file in the journal, if set
+func (tarInterpreter *FileTarInterpreter) journalFileCompleted(fileName, targetPath string) error {
+	if tarInterpreter.journal == nil {
+		return nil
+	}
+	return tarInterpreter.journal.fileCompleted(tarInterpreter.journalBackupName, fileName, targetPath)
+}
+
+// removeResumedLink removes the link created by the interrupted restore, so it can be created again
+func (tarInterpreter *FileTarInterpreter) removeResumedLink(targetPath string) {
+	if tarInterpreter.journal == nil || !tarInterpreter.journal.Resumed() {
+		return
+	}
+	if err := os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
+		tracelog.WarningLogger.Printf("Interpret: failed to remove '%s' left by the interrupted restore: %v",
+			targetPath, err)
+	}
+}
+
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer utility.LoggedClose(file, "")
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestOpenRestoreJournal_RefusesOtherBackup(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenRestoreJournal(journalPath, "base_000000010000000000000002")
+	assert.NoError(t, err)
+	assert.False(t, journal.Resumed())
+	assert.NoError(t, journal.Close())
+
+	_, err = OpenRestoreJournal(journalPath, "base_000000010000000000000004")
+	var mismatchErr RestoreJournalMismatchError
+	assert.True(t, errors.As(err, &mismatchErr))
+	assert.Equal(t, "base_000000010000000000000002", mismatchErr.JournalBackupName)
+	assert.Equal(t, "base_000000010000000000000004", mismatchErr.BackupName)
+
+	journal, err = OpenRestoreJournal(journalPath, "base_000000010000000000000002")
+	assert.NoError(t, err)
+	assert.True(t, journal.Resumed())
+	assert.NoError(t, journal.Remove())
+	_, err = os.Stat(journalPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOpenRestoreJournal_DropsTornRecord(t *testing.T) {
+	directory := t.TempDir()
+	journalPath := filepath.Join(directory, "journal")
+	restoredPath := filepath.Join(directory, "file")
+	assert.NoError(t, os.WriteFile(restoredPath, []byte("contents"), 0600))
+
+	journal, err := OpenRestoreJournal(journalPath, flattenBaseBackupName)
+	assert.NoError(t, err)
+	assert.NoError(t, journal.fileCompleted(flattenBaseBackupName, "file", restoredPath))
+	_, err = journal.file.WriteString(`{"backup":"` + flattenBaseBackupName + `","name":"other`)
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	journal, err = OpenRestoreJournal(journalPath, flattenBaseBackupName)
+	assert.NoError(t, err)
+	pending := journal.pendingFiles(flattenBaseBackupName, map[string]bool{"file": true, "other": true})
+	assert.Equal(t, map[string]bool{"other": true}, pending)
+	assert.NoError(t, journal.Close())
+
+	// the file changed since it was restored is extracted again
+	assert.NoError(t, os.WriteFile(restoredPath, []byte("changed"), 0600))
+	journal, err = OpenRestoreJournal(journalPath, flattenBaseBackupName)
+	assert.NoError(t, err)
+	pending = journal.pendingFiles(flattenBaseBackupName, map[string]bool{"file": true})
+	assert.Equal(t, map[string]bool{"file": true}, pending)
+	assert.NoError(t, journal.Close())
+}
+
+func TestDeltaFetchRecursionOld_ResumesByJournal(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	dataDirectory := t.TempDir()
+	baseTime := time.Now().Add(-time.Hour)
+	writeFlattenTestFile(t, dataDirectory, "global/pg_control", []byte("pg_control"), baseTime)
+	writeFlattenTestFile(t, dataDirectory, "PG_VERSION", []byte("14\n"), baseTime)
+	writeFlattenTestFile(t, dataDirectory, "base/1/16384", []byte("relation"), baseTime)
+	startLSN := uint64(1)
+	pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenBaseBackupName,
+		BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &startLSN}, nil, nil)
+
+	backup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), flattenBaseBackupName)
+	filesToUnwrap, err := backup.GetFilesToUnwrap("")
+	assert.NoError(t, err)
+	restoreDirectory := t.TempDir()
+	journalPath := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenRestoreJournal(journalPath, flattenBaseBackupName)
+	assert.NoError(t, err)
+	assert.NoError(t, deltaFetchRecursionOld(backup, rootFolder, restoreDirectory, nil, filesToUnwrap, journal))
+	assert.NoError(t, journal.Close())
+
+	// the interrupted restore lost one file and left the other one broken
+	assert.NoError(t, os.Remove(filepath.Join(restoreDirectory, "PG_VERSION")))
+	assert.NoError(t, os.WriteFile(filepath.Join(restoreDirectory, "base/1/16384"), []byte("rel"), 0600))
+	untouchedPath := filepath.Join(restoreDirectory, "global/pg_control")
+	assert.NoError(t, os.Chtimes(untouchedPath, baseTime, baseTime))
+
+	journal, err = OpenRestoreJournal(journalPath, flattenBaseBackupName)
+	assert.NoError(t, err)
+	assert.NoError(t, deltaFetchRecursionOld(backup, rootFolder, restoreDirectory, nil, filesToUnwrap, journal))
+	assert.NoError(t, journal.Remove())
+
+	for _, name := range []string{"global/pg_control", "PG_VERSION", "base/1/16384"} {
+		assert.Equal(t, readFlattenTestFile(t, dataDirectory, name), readFlattenTestFile(t, restoreDirectory, name), name)
+	}
+	info, err := os.Stat(untouchedPath)
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(baseTime))
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"sync/atomic"
+
+	"github.com/wal-g/wal-g/internal"
+)
+
+// ProgressReporter is the alias of the extraction progress receiver shared by the engines
+type ProgressReporter = internal.ProgressReporter
+
+func (tarInterpreter *FileTarInterpreter) reportFileStart(name string, size int64) {
+	if tarInterpreter.ProgressReporter == nil {
+		return
+	}
+	tarInterpreter.ProgressReporter.OnFileStart(name, size)
+}
+
+func (tarInterpreter *FileTarInterpreter) reportFileComplete(name string, bytes int64) {
+	totalBytes := atomic.AddInt64(&tarInterpreter.extractedBytes, bytes)
+	if tarInterpreter.ProgressReporter == nil {
+		return
+	}
+	tarInterpreter.ProgressReporter.OnFileComplete(name, bytes, totalBytes)
+}
+
+func (tarInterpreter *FileTarInterpreter) reportFileSkipped(name string) {
+	if tarInterpreter.ProgressReporter == nil {
+		return
+	}
+	tarInterpreter.ProgressReporter.OnFileSkipped(name)
+}
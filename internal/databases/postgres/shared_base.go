@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// DefaultRestoreSharedFilePatterns are the files shared with the read-only base if WALG_RESTORE_SHARED_FILES
+// is not set: the relation files of the databases, the cluster-wide files and the WAL stay local
+var DefaultRestoreSharedFilePatterns = []string{"base"}
+
+// SharedFiles returns the files symlinked to the shared base directory instead of being extracted
+func (result *UnwrapResult) SharedFiles() []string {
+	result.sharedFilesMutex.Lock()
+	defer result.sharedFilesMutex.Unlock()
+	return append([]string{}, result.sharedFiles...)
+}
+
+func (result *UnwrapResult) addSharedFile(fileName string) {
+	result.sharedFilesMutex.Lock()
+	result.sharedFiles = append(result.sharedFiles, fileName)
+	result.sharedFilesMutex.Unlock()
+}
+
+// getRestoreSharedBase returns the read-only base directory set by the WALG_RESTORE_SHARED_BASE
+// and the patterns of the files shared with it. The empty directory is returned if the setting is not set
+// or the directory is absent, then all the files are extracted.
+func getRestoreSharedBase() (string, []string, error) {
+	sharedBaseDirectory := viper.GetString(internal.RestoreSharedBaseSetting)
+	if sharedBaseDirectory == "" {
+		return "", nil, nil
+	}
+	patterns := DefaultRestoreSharedFilePatterns
+	if patternsStr := viper.GetString(internal.RestoreSharedFilesSetting); patternsStr != "" {
+		var err error
+		patterns, err = parseRestorePatterns(strings.Split(patternsStr, ","), internal.RestoreSharedFilesSetting)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	info, err := os.Stat(sharedBaseDirectory)
+	if err != nil || !info.IsDir() {
+		tracelog.WarningLogger.Printf("Shared base directory '%s' is not available, all the files are extracted",
+			sharedBaseDirectory)
+		return "", nil, nil
+	}
+	sharedBaseDirectory, err = filepath.Abs(sharedBaseDirectory)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid %s setting", internal.RestoreSharedBaseSetting)
+	}
+	return sharedBaseDirectory, patterns, nil
+}
+
+// isSharedFile tells the file may be taken from the shared base: it matches the shared file patterns,
+// is not required to start the server and the FilesMetadata has its checksum to verify the base copy against.
+// The incremented files are local, since the increment is applied on top of the restored base version,
+// as well as the files incremented by the later backups of the chain.
+func (tarInterpreter *FileTarInterpreter) isSharedFile(fileName string) bool {
+	if UtilityFilePaths[fileName] || tarInterpreter.incrementedLater[fileName] ||
+		!matchesRestorePatterns(fileName, tarInterpreter.SharedFilePatterns) {
+		return false
+	}
+	fileDescription, ok := tarInterpreter.FilesMetadata.Files[fileName]
+	return ok && fileDescription.Checksum != nil && !fileDescription.IsIncremented
+}
+
+// trySymlinkToSharedBase replaces the shared file by the symlink to its copy in the SharedBaseDirectory
+// if the copy matches the checksum stored in the backup. The file is extracted as usual otherwise.
+func (tarInterpreter *FileTarInterpreter) trySymlinkToSharedBase(fileInfo *tar.Header, targetPath string) bool {
+	if !tarInterpreter.isSharedFile(fileInfo.Name) {
+		return false
+	}
+	sharedPath := filepath.Join(tarInterpreter.SharedBaseDirectory, fileInfo.Name)
+	sharedFile, err := os.Open(sharedPath)
+	if err != nil {
+		return false
+	}
+	defer utility.LoggedClose(sharedFile, "")
+	checksum := tarInterpreter.FilesMetadata.Files[fileInfo.Name].Checksum
+	if !isLocalFileUnchanged(sharedFile, fileInfo.Size, *checksum) {
+		tracelog.DebugLogger.Printf("Shared base copy of '%s' differs from the backup, extracting it", fileInfo.Name)
+		return false
+	}
+
+	if err = tarInterpreter.prepareDirs(fileInfo.Name, targetPath); err != nil {
+		return false
+	}
+	if _, err = os.Lstat(targetPath); err == nil {
+		// do not replace the existing files, they may be newer than the shared ones
+		return false
+	}
+	if err = os.Symlink(sharedPath, targetPath); err != nil {
+		tracelog.DebugLogger.Printf("Failed to symlink '%s' to the shared base, extracting it: %v", fileInfo.Name, err)
+		return false
+	}
+	return true
+}
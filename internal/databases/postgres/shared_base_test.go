@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestInterpretWithSharedBase(t *testing.T) {
+	sharedBase := t.TempDir()
+	for name, content := range map[string]string{
+		"base/1/shared": "same", "base/1/changed": "old", "global/1262": "global", PgControlPath: "control"} {
+		writeFlattenTestFile(t, sharedBase, name, []byte(content), time.Now())
+	}
+
+	tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{}, FilesMetadataDto{Files: internal.BackupFileList{
+		"/base/1/shared":   {Checksum: newSeedTestChecksum("same")},
+		"/base/1/changed":  {Checksum: newSeedTestChecksum("new")},
+		"/base/1/unsigned": {},
+		"/global/1262":     {Checksum: newSeedTestChecksum("global")},
+		PgControlPath:      {Checksum: newSeedTestChecksum("control")},
+	}}, nil, false)
+	tarInterpreter.SharedBaseDirectory = sharedBase
+	tarInterpreter.SharedFilePatterns = []string{"base", "global"}
+
+	contents := map[string]string{"/base/1/shared": "same", "/base/1/changed": "new", "/base/1/unsigned": "unsigned",
+		"/base/1/missing": "missing", "/global/1262": "global", PgControlPath: "control"}
+	for name, content := range contents {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(content), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     int64(len(content)),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(content), readFlattenTestFile(t, tarInterpreter.DBDataDirectory, name), name)
+	}
+
+	assert.ElementsMatch(t, []string{"/base/1/shared", "/global/1262"}, tarInterpreter.UnwrapResult.SharedFiles())
+	// the shared files are complete, so the reverse delta unpack does not patch them
+	assert.Subset(t, tarInterpreter.UnwrapResult.completedFiles, []string{"/base/1/shared", "/global/1262"})
+	linkTarget, err := os.Readlink(filepath.Join(tarInterpreter.DBDataDirectory, "base/1/shared"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(sharedBase, "base/1/shared"), linkTarget)
+	// the files required to start the server are always local
+	info, err := os.Lstat(filepath.Join(tarInterpreter.DBDataDirectory, PgControlPath))
+	assert.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+}
+
+func TestGetRestoreSharedBase_FallsBackWithoutBase(t *testing.T) {
+	sharedBase := t.TempDir()
+	defer func() {
+		viper.Set(internal.RestoreSharedBaseSetting, nil)
+		viper.Set(internal.RestoreSharedFilesSetting, nil)
+	}()
+
+	viper.Set(internal.RestoreSharedBaseSetting, sharedBase)
+	directory, patterns, err := getRestoreSharedBase()
+	assert.NoError(t, err)
+	assert.Equal(t, sharedBase, directory)
+	assert.Equal(t, DefaultRestoreSharedFilePatterns, patterns)
+
+	viper.Set(internal.RestoreSharedFilesSetting, "base/1, global/")
+	_, patterns, err = getRestoreSharedBase()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"base/1", "global"}, patterns)
+
+	viper.Set(internal.RestoreSharedFilesSetting, "[")
+	_, _, err = getRestoreSharedBase()
+	assert.Error(t, err)
+
+	viper.Set(internal.RestoreSharedFilesSetting, "")
+	viper.Set(internal.RestoreSharedBaseSetting, filepath.Join(sharedBase, "absent"))
+	directory, _, err = getRestoreSharedBase()
+	assert.NoError(t, err)
+	assert.Empty(t, directory)
+}
+
+// The base backup of the chain should not symlink the file incremented by the delta,
+// otherwise the increment is written through the symlink into the shared base
+func TestDeltaChainWithSharedBase_KeepsSharedBaseUntouched(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	dataDirectory := t.TempDir()
+	baseTime := time.Now().Add(-time.Hour)
+	writeFlattenTestFile(t, dataDirectory, "global/pg_control", []byte("pg_control"), baseTime)
+	pagedFile, err := os.ReadFile("../../../test/testdata/base_paged_file.bin")
+	assert.NoError(t, err)
+	writeFlattenTestFile(t, dataDirectory, flattenPagedFile, pagedFile, baseTime)
+	writeFlattenTestFile(t, dataDirectory, "base/1/16386", []byte("unchanged file"), baseTime)
+
+	baseStartLSN := uint64(1)
+	baseFiles := pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenBaseBackupName,
+		BackupSentinelDto{BackupStartLSN: &baseStartLSN, BackupFinishLSN: &baseStartLSN}, nil, nil)
+	// the shared base holds the data directory as of the base backup
+	sharedBase := t.TempDir()
+	writeFlattenTestFile(t, sharedBase, flattenPagedFile, pagedFile, baseTime)
+	writeFlattenTestFile(t, sharedBase, "base/1/16386", []byte("unchanged file"), baseTime)
+
+	basePagedFile := append([]byte{}, pagedFile...)
+	pageLSN := flattenIncrementLSN + 1
+	binary.LittleEndian.PutUint32(pagedFile[2*DatabasePageSize:], uint32(pageLSN>>32))
+	binary.LittleEndian.PutUint32(pagedFile[2*DatabasePageSize+4:], uint32(pageLSN))
+	copy(pagedFile[2*DatabasePageSize+4096:], "updated page")
+	writeFlattenTestFile(t, dataDirectory, flattenPagedFile, pagedFile, time.Now())
+
+	incrementFromLSN := flattenIncrementLSN
+	deltaStartLSN := flattenIncrementLSN + 1
+	baseName, count := flattenBaseBackupName, 1
+	deltaFiles := pushFlattenTestBackup(t, rootFolder, dataDirectory, flattenDeltaBackupName,
+		BackupSentinelDto{BackupStartLSN: &deltaStartLSN, BackupFinishLSN: &deltaStartLSN,
+			IncrementFromLSN: &incrementFromLSN, IncrementFrom: &baseName, IncrementFullName: &baseName,
+			IncrementCount: &count}, &incrementFromLSN, baseFiles)
+	assert.True(t, deltaFiles["/"+flattenPagedFile].IsIncremented)
+
+	viper.Set(internal.RestoreSharedBaseSetting, sharedBase)
+	defer viper.Set(internal.RestoreSharedBaseSetting, nil)
+	restoreDirectory := restoreFlattenTestBackup(t, rootFolder,
+		NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), flattenDeltaBackupName))
+
+	assert.Equal(t, basePagedFile, readFlattenTestFile(t, sharedBase, flattenPagedFile))
+	assert.Equal(t, pagedFile, readFlattenTestFile(t, restoreDirectory, flattenPagedFile))
+	info, err := os.Lstat(filepath.Join(restoreDirectory, flattenPagedFile))
+	assert.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"io"
+	"os"
+
+	"github.com/wal-g/tracelog"
+)
+
+// MinSparseZeroRun is the minimal length of zero run which is skipped instead of being written,
+// so the file system can leave a hole in place of it
+const MinSparseZeroRun = 8 * DatabasePageSize
+
+// sparseFileWriter writes the file skipping long runs of zero pages by seeking past them.
+// The skipped runs become holes on the file systems supporting sparse files and read as zeros on the others.
+type sparseFileWriter struct {
+	file         *os.File
+	pendingZeros int64
+	zeros        []byte
+	sparse       bool
+}
+
+func newSparseFileWriter(file *os.File) *sparseFileWriter {
+	return &sparseFileWriter{file: file, zeros: make([]byte, DatabasePageSize), sparse: true}
+}
+
+// Write checks the data page by page, the consecutive non-zero pages are written at once
+// so the larger copy buffers result in the fewer write calls
+func (writer *sparseFileWriter) Write(p []byte) (int, error) {
+	dataStart := 0
+	for offset := 0; offset < len(p); offset += int(DatabasePageSize) {
+		end := offset + int(DatabasePageSize)
+		if end > len(p) {
+			end = len(p)
+		}
+		page := p[offset:end]
+		if !isZeroPage(page) {
+			continue
+		}
+		if err := writer.writeData(p[dataStart:offset]); err != nil {
+			return dataStart, err
+		}
+		writer.pendingZeros += int64(len(page))
+		dataStart = end
+	}
+	if err := writer.writeData(p[dataStart:]); err != nil {
+		return dataStart, err
+	}
+	return len(p), nil
+}
+
+func (writer *sparseFileWriter) writeData(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := writer.flushZeros(); err != nil {
+		return err
+	}
+	_, err := writer.file.Write(data)
+	return err
+}
+
+// Finish flushes the trailing zero run, the file is extended to its full size if it ends with a hole
+func (writer *sparseFileWriter) Finish() error {
+	if err := writer.flushZeros(); err != nil {
+		return err
+	}
+	offset, err := writer.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	return writer.file.Truncate(offset)
+}
+
+func (writer *sparseFileWriter) flushZeros() error {
+	if writer.pendingZeros == 0 {
+		return nil
+	}
+	zeroRun := writer.pendingZeros
+	writer.pendingZeros = 0
+
+	if writer.sparse && zeroRun >= MinSparseZeroRun {
+		_, err := writer.file.Seek(zeroRun, io.SeekCurrent)
+		if err == nil {
+			return nil
+		}
+		tracelog.WarningLogger.Printf("Failed to seek in '%s', falling back to writing zeros: %v", writer.file.Name(), err)
+		writer.sparse = false
+	}
+	for ; zeroRun > 0; zeroRun -= int64(len(writer.zeros)) {
+		chunk := writer.zeros
+		if zeroRun < int64(len(chunk)) {
+			chunk = chunk[:zeroRun]
+		}
+		if _, err := writer.file.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyToSparseFile copies the content to the file leaving holes in place of long zero runs,
+// falls back to the plain copy if there is data after the current offset that holes would not overwrite.
+// The copy goes through the buffer if provided, otherwise the default io.Copy buffer is allocated.
+func copyToSparseFile(localFile *os.File, fileReader io.Reader, buffer []byte) (int64, error) {
+	offset, err := localFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return io.CopyBuffer(localFile, fileReader, buffer)
+	}
+	fileInfo, err := localFile.Stat()
+	if err != nil || fileInfo.Size() > offset {
+		return io.CopyBuffer(localFile, fileReader, buffer)
+	}
+
+	writer := newSparseFileWriter(localFile)
+	written, err := io.CopyBuffer(writer, fileReader, buffer)
+	if err != nil {
+		return written, err
+	}
+	return written, writer.Finish()
+}
+
+func isZeroPage(page []byte) bool {
+	for _, b := range page {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
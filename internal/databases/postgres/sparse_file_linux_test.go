@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyToSparseFile_LeavesHoles(t *testing.T) {
+	page := bytes.Repeat([]byte{1}, int(DatabasePageSize))
+	content := sparseTestContent(page, make([]byte, 1024*DatabasePageSize), page)
+	path := writeSparseTestFile(t, content)
+
+	fileInfo, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), fileInfo.Size())
+
+	// st_blocks is measured in 512-byte units regardless of the file system block size
+	allocatedSize := fileInfo.Sys().(*syscall.Stat_t).Blocks * 512
+	assert.Less(t, allocatedSize, fileInfo.Size())
+}
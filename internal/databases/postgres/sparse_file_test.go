@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sparseTestContent(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func writeSparseTestFile(t *testing.T, content []byte) string {
+	path := filepath.Join(t.TempDir(), "relation")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	written, err := copyToSparseFile(file, bytes.NewReader(content), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), written)
+	return path
+}
+
+func TestCopyToSparseFile_KeepsContent(t *testing.T) {
+	page := bytes.Repeat([]byte{1}, int(DatabasePageSize))
+	longZeroRun := make([]byte, MinSparseZeroRun*2)
+	shortZeroRun := make([]byte, DatabasePageSize)
+
+	contents := map[string][]byte{
+		"zero middle":  sparseTestContent(page, longZeroRun, page),
+		"zero tail":    sparseTestContent(page, longZeroRun),
+		"zero head":    sparseTestContent(longZeroRun, page),
+		"short zeros":  sparseTestContent(page, shortZeroRun, page),
+		"partial page": sparseTestContent(page, longZeroRun, []byte{1, 2, 3}),
+		"only zeros":   longZeroRun,
+		"empty":        {},
+	}
+	for name, content := range contents {
+		actual, err := os.ReadFile(writeSparseTestFile(t, content))
+		assert.NoError(t, err)
+		assert.Equal(t, content, actual, name)
+	}
+}
+
+func TestCopyToSparseFile_FallsBackForNonEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relation")
+	assert.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{1}, int(MinSparseZeroRun*2)), 0666))
+	file, err := os.OpenFile(path, os.O_WRONLY, 0666)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	content := make([]byte, MinSparseZeroRun)
+	_, err = copyToSparseFile(file, bytes.NewReader(content), nil)
+	assert.NoError(t, err)
+
+	actual, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, actual[:len(content)])
+}
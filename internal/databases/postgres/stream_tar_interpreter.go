@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// StreamRecordType is the kind of the tar entry emitted by the StreamTarInterpreter
+type StreamRecordType byte
+
+const (
+	FileStreamRecord     StreamRecordType = 'F'
+	DirStreamRecord      StreamRecordType = 'D'
+	HardlinkStreamRecord StreamRecordType = 'H'
+	SymlinkStreamRecord  StreamRecordType = 'S'
+)
+
+// StreamRecord describes the tar entry emitted by the StreamTarInterpreter.
+//
+// The records follow each other in the stream with no stream header, each one is framed as
+// (all the integers are big-endian):
+//
+//	type      1 byte, one of 'F' (file), 'D' (directory), 'H' (hardlink), 'S' (symlink)
+//	name      4 bytes length followed by the name of the tar entry
+//	linkname  4 bytes length followed by the hardlink source or the symlink target, empty for the others
+//	mode      4 bytes, the permission bits of the tar entry
+//	size      8 bytes length followed by the file contents as they are stored in the tar, 0 for the others
+//
+// The contents of the incremented files are the increments, they are not applied to anything.
+type StreamRecord struct {
+	Type     StreamRecordType
+	Name     string
+	Linkname string
+	Mode     int64
+	Size     int64
+}
+
+var streamRecordTypes = map[byte]StreamRecordType{
+	tar.TypeReg:     FileStreamRecord,
+	tar.TypeRegA:    FileStreamRecord,
+	tar.TypeDir:     DirStreamRecord,
+	tar.TypeLink:    HardlinkStreamRecord,
+	tar.TypeSymlink: SymlinkStreamRecord,
+}
+
+// StreamTarInterpreter writes the extracted tar entries to the Writer as the StreamRecords instead of the disk,
+// e.g. to pipe the backup to a custom processor. The records of the concurrently extracted tars are not interleaved.
+type StreamTarInterpreter struct {
+	Writer        io.Writer
+	FilesToUnwrap map[string]bool
+
+	writerMutex sync.Mutex
+}
+
+func NewStreamTarInterpreter(writer io.Writer, filesToUnwrap map[string]bool) *StreamTarInterpreter {
+	return &StreamTarInterpreter{Writer: writer, FilesToUnwrap: filesToUnwrap}
+}
+
+// Interpret writes the tar entry to the Writer, the files not in the FilesToUnwrap are skipped if it is set
+func (interpreter *StreamTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	header, err := resolvePAXHeader(header)
+	if err != nil {
+		return err
+	}
+	recordType, ok := streamRecordTypes[header.Typeflag]
+	if !ok {
+		tracelog.WarningLogger.Printf("Skipping tar entry '%s' of unsupported type '%c'", header.Name, header.Typeflag)
+		return nil
+	}
+	record := StreamRecord{Type: recordType, Name: header.Name, Linkname: header.Linkname, Mode: header.Mode}
+	if recordType == FileStreamRecord {
+		if interpreter.FilesToUnwrap != nil && !interpreter.FilesToUnwrap[header.Name] {
+			tracelog.DebugLogger.Printf("Don't have to unwrap '%s' this time\n", header.Name)
+			return nil
+		}
+		record.Size = header.Size
+	}
+
+	interpreter.writerMutex.Lock()
+	defer interpreter.writerMutex.Unlock()
+	if err = writeStreamRecordHeader(interpreter.Writer, record); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to write stream record of '%s'", header.Name)
+	}
+	if _, err = io.CopyN(interpreter.Writer, reader, record.Size); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to write contents of '%s' to stream", header.Name)
+	}
+	return nil
+}
+
+func writeStreamRecordHeader(writer io.Writer, record StreamRecord) error {
+	header := make([]byte, 0, 1+4+len(record.Name)+4+len(record.Linkname)+12)
+	header = append(header, byte(record.Type))
+	header = appendStreamRecordField(header, record.Name)
+	header = appendStreamRecordField(header, record.Linkname)
+	numbers := make([]byte, 12)
+	binary.BigEndian.PutUint32(numbers[:4], uint32(record.Mode))
+	binary.BigEndian.PutUint64(numbers[4:], uint64(record.Size))
+	_, err := writer.Write(append(header, numbers...))
+	return err
+}
+
+func appendStreamRecordField(header []byte, field string) []byte {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(field)))
+	return append(append(header, length...), field...)
+}
+
+// StreamRecordReader reads the StreamRecords written by the StreamTarInterpreter,
+// the contents of the current file record are read by Read
+type StreamRecordReader struct {
+	reader  io.Reader
+	content io.LimitedReader
+}
+
+func NewStreamRecordReader(reader io.Reader) *StreamRecordReader {
+	return &StreamRecordReader{reader: reader}
+}
+
+// Next skips the unread contents of the current record and reads the next record,
+// io.EOF is returned at the end of the stream
+func (streamReader *StreamRecordReader) Next() (*StreamRecord, error) {
+	if _, err := io.Copy(io.Discard, &streamReader.content); err != nil {
+		return nil, err
+	}
+	if streamReader.content.N > 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	recordType := make([]byte, 1)
+	if _, err := io.ReadFull(streamReader.reader, recordType); err != nil {
+		return nil, err
+	}
+	record := &StreamRecord{Type: StreamRecordType(recordType[0])}
+	var err error
+	if record.Name, err = streamReader.readField(); err != nil {
+		return nil, err
+	}
+	if record.Linkname, err = streamReader.readField(); err != nil {
+		return nil, err
+	}
+	numbers := make([]byte, 12)
+	if _, err = io.ReadFull(streamReader.reader, numbers); err != nil {
+		return nil, errors.Wrapf(noEOF(err), "failed to read stream record of '%s'", record.Name)
+	}
+	record.Mode = int64(binary.BigEndian.Uint32(numbers[:4]))
+	record.Size = int64(binary.BigEndian.Uint64(numbers[4:]))
+	streamReader.content = io.LimitedReader{R: streamReader.reader, N: record.Size}
+	return record, nil
+}
+
+// Read reads the contents of the current record
+func (streamReader *StreamRecordReader) Read(p []byte) (int, error) {
+	n, err := streamReader.content.Read(p)
+	if err == io.EOF && streamReader.content.N > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (streamReader *StreamRecordReader) readField() (string, error) {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(streamReader.reader, length); err != nil {
+		return "", errors.Wrap(noEOF(err), "failed to read stream record")
+	}
+	field := make([]byte, binary.BigEndian.Uint32(length))
+	if _, err := io.ReadFull(streamReader.reader, field); err != nil {
+		return "", errors.Wrap(noEOF(err), "failed to read stream record")
+	}
+	return string(field), nil
+}
+
+// noEOF reports the stream ending inside of the record as truncated
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+type streamedEntry struct {
+	record  postgres.StreamRecord
+	content string
+}
+
+func readStreamedEntries(t *testing.T, stream io.Reader) []streamedEntry {
+	var entries []streamedEntry
+	streamReader := postgres.NewStreamRecordReader(stream)
+	for {
+		record, err := streamReader.Next()
+		if err == io.EOF {
+			return entries
+		}
+		assert.NoError(t, err)
+		content, err := io.ReadAll(streamReader)
+		assert.NoError(t, err)
+		entries = append(entries, streamedEntry{record: *record, content: string(content)})
+	}
+}
+
+func TestStreamTarInterpreter_RoundTrip(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	files := []internal.ReaderMaker{putTestTar(t, folder, "part_1.tar",
+		testTarEntry{header: tar.Header{Name: "/base/1", Typeflag: tar.TypeDir, Mode: 0700}},
+		testTarEntry{header: tar.Header{Name: "/base/1/2", Typeflag: tar.TypeReg, Mode: 0600}, content: "relation"},
+		testTarEntry{header: tar.Header{Name: "/base/1/skipped", Typeflag: tar.TypeReg, Mode: 0600}, content: "skipped"},
+		testTarEntry{header: tar.Header{Name: "/base/1/empty", Typeflag: tar.TypeReg, Mode: 0600}},
+		testTarEntry{header: tar.Header{Name: "/base/1/3", Typeflag: tar.TypeLink, Linkname: "/base/1/2"}},
+		testTarEntry{header: tar.Header{Name: "/pg_wal", Typeflag: tar.TypeSymlink, Linkname: "/wal", Mode: 0777}})}
+	var stream bytes.Buffer
+	interpreter := postgres.NewStreamTarInterpreter(&stream,
+		map[string]bool{"/base/1/2": true, "/base/1/empty": true})
+
+	err := internal.ExtractAllWithContext(context.Background(), interpreter, files, 1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []streamedEntry{
+		{record: postgres.StreamRecord{Type: postgres.DirStreamRecord, Name: "/base/1", Mode: 0700}},
+		{record: postgres.StreamRecord{Type: postgres.FileStreamRecord, Name: "/base/1/2", Mode: 0600, Size: 8},
+			content: "relation"},
+		{record: postgres.StreamRecord{Type: postgres.FileStreamRecord, Name: "/base/1/empty", Mode: 0600}},
+		{record: postgres.StreamRecord{Type: postgres.HardlinkStreamRecord, Name: "/base/1/3", Linkname: "/base/1/2"}},
+		{record: postgres.StreamRecord{Type: postgres.SymlinkStreamRecord, Name: "/pg_wal", Linkname: "/wal", Mode: 0777}},
+	}, readStreamedEntries(t, &stream))
+}
+
+func TestStreamRecordReader_SkipsUnreadContents(t *testing.T) {
+	var stream bytes.Buffer
+	interpreter := postgres.NewStreamTarInterpreter(&stream, nil)
+	for _, name := range []string{"first", "second"} {
+		err := interpreter.Interpret(bytes.NewBufferString(name), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(name)),
+		})
+		assert.NoError(t, err)
+	}
+
+	streamReader := postgres.NewStreamRecordReader(bytes.NewReader(stream.Bytes()))
+	for _, name := range []string{"first", "second"} {
+		record, err := streamReader.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, name, record.Name)
+	}
+	_, err := streamReader.Next()
+	assert.Equal(t, io.EOF, err)
+
+	streamReader = postgres.NewStreamRecordReader(bytes.NewReader(stream.Bytes()[:stream.Len()-1]))
+	_, err = streamReader.Next()
+	assert.NoError(t, err)
+	_, err = streamReader.Next()
+	assert.NoError(t, err)
+	_, err = io.ReadAll(streamReader)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -3,7 +3,9 @@ package postgres
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 
@@ -103,22 +105,26 @@ func (p *TarBallFilePacker) PackFileIntoTar(cfi *ComposeFileInfo, tarBall intern
 	}
 	errorGroup, _ := errgroup.WithContext(context.Background())
 
+	var corruptBlocks []uint32
 	if p.options.verifyPageChecksums {
 		var secondReadCloser io.ReadCloser
 		// newTeeReadCloser is used to provide the fileReadCloser to two consumers:
 		// fileReadCloser is needed for PackFileTo, secondReadCloser is for the page verification
 		fileReadCloser, secondReadCloser = newTeeReadCloser(fileReadCloser)
 		errorGroup.Go(func() (err error) {
-			corruptBlocks, err := verifyFile(cfi.path, cfi.fileInfo, secondReadCloser, cfi.isIncremented)
-			if err != nil {
-				return err
-			}
-			p.files.AddFileWithCorruptBlocks(cfi.header, cfi.fileInfo, cfi.isIncremented,
-				corruptBlocks, p.options.storeAllCorruptBlocks)
-			return nil
+			corruptBlocks, err = verifyFile(cfi.path, cfi.fileInfo, secondReadCloser, cfi.isIncremented)
+			return err
 		})
-	} else {
-		p.files.AddFile(cfi.header, cfi.fileInfo, cfi.isIncremented)
+	}
+
+	// the checksum of the increment is not stored, since the restored file is built from several backups
+	var checksumHash hash.Hash
+	if !cfi.isIncremented {
+		checksumHash = sha256.New()
+		fileReadCloser = &ioextensions.ReadCascadeCloser{
+			Reader: io.TeeReader(fileReadCloser, checksumHash),
+			Closer: fileReadCloser,
+		}
 	}
 
 	errorGroup.Go(func() error {
@@ -133,7 +139,19 @@ func (p *TarBallFilePacker) PackFileIntoTar(cfi *ComposeFileInfo, tarBall intern
 		return nil
 	})
 
-	return errorGroup.Wait()
+	if err = errorGroup.Wait(); err != nil {
+		return err
+	}
+	if p.options.verifyPageChecksums {
+		p.files.AddFileWithCorruptBlocks(cfi.header, cfi.fileInfo, cfi.isIncremented,
+			corruptBlocks, p.options.storeAllCorruptBlocks)
+	} else {
+		p.files.AddFile(cfi.header, cfi.fileInfo, cfi.isIncremented)
+	}
+	if checksumHash != nil {
+		p.files.AddFileChecksum(cfi.header.Name, internal.NewFileChecksum(internal.SHA256ChecksumAlgorithm, checksumHash))
+	}
+	return nil
 }
 
 func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadCloser, error) {
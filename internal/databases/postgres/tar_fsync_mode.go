@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/semaphore"
+)
+
+// TarFsyncMode is the alias of the fsync mode shared by the engines
+type TarFsyncMode = internal.TarFsyncMode
+
+const (
+	DefaultTarFsyncMode         = internal.DefaultTarFsyncMode
+	DisabledTarFsyncMode        = internal.DisabledTarFsyncMode
+	GlobalTarFsyncMode          = internal.GlobalTarFsyncMode
+	PerFileDatasyncTarFsyncMode = internal.PerFileDatasyncTarFsyncMode
+)
+
+// filesToSync stores the paths of successfully written files
+// which should be flushed when the extraction is finished
+type filesToSync struct {
+	paths []string
+	mutex sync.Mutex
+}
+
+func (files *filesToSync) add(path string) {
+	files.mutex.Lock()
+	files.paths = append(files.paths, path)
+	files.mutex.Unlock()
+}
+
+func (files *filesToSync) takeAll() []string {
+	files.mutex.Lock()
+	defer files.mutex.Unlock()
+	paths := files.paths
+	files.paths = nil
+	return paths
+}
+
+// syncExtractedDirectory flushes the directory of the extracted files, it is replaced in the tests
+var syncExtractedDirectory = syncDirectory
+
+// dirsToSync stores the directories whose entries were added by the extraction,
+// which should be flushed when the extraction is finished
+type dirsToSync struct {
+	paths map[string]bool
+	mutex sync.Mutex
+}
+
+// add remembers the directory along with its parents up to the root, since the entry of each directory is in its
+// parent. The directory outside of the root, e.g. the tablespace, is remembered alone.
+func (dirs *dirsToSync) add(dir string, root string) {
+	dirs.mutex.Lock()
+	defer dirs.mutex.Unlock()
+	if dirs.paths == nil {
+		dirs.paths = make(map[string]bool)
+	}
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); !dirs.paths[dir]; dir = filepath.Dir(dir) {
+		dirs.paths[dir] = true
+		if dir == root || !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+			return
+		}
+	}
+}
+
+// takeAll returns the directories ordered from the deepest ones, so each directory is flushed after its children
+func (dirs *dirsToSync) takeAll() []string {
+	dirs.mutex.Lock()
+	defer dirs.mutex.Unlock()
+	paths := make([]string, 0, len(dirs.paths))
+	for path := range dirs.paths {
+		paths = append(paths, path)
+	}
+	dirs.paths = nil
+	sort.Slice(paths, func(i, j int) bool {
+		leftDepth := strings.Count(paths[i], string(filepath.Separator))
+		rightDepth := strings.Count(paths[j], string(filepath.Separator))
+		if leftDepth != rightDepth {
+			return leftDepth > rightDepth
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// syncDirectories calls fsync on each of the provided directories one by one in the given order
+func syncDirectories(paths []string) error {
+	for _, path := range paths {
+		if err := syncExtractedDirectory(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// datasyncFiles calls fdatasync on each of the provided files using at most concurrency workers
+func datasyncFiles(paths []string, concurrency int) error {
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(int64(concurrency))
+	errs := make(chan error, len(paths))
+
+	for _, path := range paths {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return err
+		}
+		go func(path string) {
+			defer sem.Release(1)
+			errs <- datasyncFile(path)
+		}(path)
+	}
+	if err := sem.Acquire(ctx, int64(concurrency)); err != nil {
+		return err
+	}
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func datasyncFile(path string) error {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open '%s' for fdatasync", path)
+	}
+	defer utility.LoggedClose(file, "")
+
+	if err = fdatasync(file); err != nil {
+		return errors.Wrapf(err, "failed to fdatasync '%s'", path)
+	}
+	return nil
+}
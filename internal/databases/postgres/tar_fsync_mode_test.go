@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+type failingReader struct{}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestPerFileDatasync_QueuesWrittenFiles(t *testing.T) {
+	dir := t.TempDir()
+	tarInterpreter := &FileTarInterpreter{
+		DBDataDirectory: dir,
+		UnwrapResult:    newUnwrapResult(),
+		fsyncModes:      internal.TarFsyncModes{Default: PerFileDatasyncTarFsyncMode},
+	}
+
+	for _, name := range []string{"first", "nested/second"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(name), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.ElementsMatch(t,
+		[]string{filepath.Join(dir, "first"), filepath.Join(dir, "nested/second")},
+		tarInterpreter.filesToSync.paths)
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+	assert.Empty(t, tarInterpreter.filesToSync.paths)
+}
+
+func TestPerFileDatasync_SkipsFailedFiles(t *testing.T) {
+	dir := t.TempDir()
+	tarInterpreter := &FileTarInterpreter{
+		DBDataDirectory: dir,
+		UnwrapResult:    newUnwrapResult(),
+		fsyncModes:      internal.TarFsyncModes{Default: PerFileDatasyncTarFsyncMode},
+	}
+
+	err := tarInterpreter.Interpret(failingReader{}, &tar.Header{
+		Name:     "broken",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+	})
+	assert.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "broken"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, tarInterpreter.filesToSync.paths)
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+}
+
+func TestFsyncModeOverrides_QueueOnlyDatasyncTablespace(t *testing.T) {
+	dir := t.TempDir()
+	tarInterpreter := &FileTarInterpreter{
+		DBDataDirectory: dir,
+		UnwrapResult:    newUnwrapResult(),
+		fsyncModes: internal.TarFsyncModes{Default: DefaultTarFsyncMode, Overrides: []internal.TarFsyncModeOverride{
+			{PathPrefix: filepath.Join(dir, "pg_tblspc", "16400"), Mode: DisabledTarFsyncMode},
+			{PathPrefix: filepath.Join(dir, "pg_tblspc", "16401"), Mode: PerFileDatasyncTarFsyncMode},
+		}},
+	}
+
+	for _, name := range []string{"base/1/1259", "pg_tblspc/16400/PG_14/16384/16385", "pg_tblspc/16401/PG_14/16384/16386"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString("data"), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     4,
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{filepath.Join(dir, "pg_tblspc/16401/PG_14/16384/16386")}, tarInterpreter.filesToSync.paths)
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+	assert.Empty(t, tarInterpreter.filesToSync.paths)
+}
+
+func TestDatasyncFiles_ReturnsErrorForMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing")
+	assert.NoError(t, os.WriteFile(existing, []byte("data"), 0600))
+
+	err := datasyncFiles([]string{existing, filepath.Join(dir, "missing")}, 2)
+	assert.Error(t, err)
+	assert.NoError(t, datasyncFiles([]string{existing}, 2))
+}
+
+func recordSyncedDirectories(t *testing.T) *[]string {
+	synced := make([]string, 0)
+	syncExtractedDirectory = func(path string) error {
+		synced = append(synced, path)
+		return syncDirectory(path)
+	}
+	t.Cleanup(func() { syncExtractedDirectory = syncDirectory })
+	return &synced
+}
+
+func TestDefaultFsync_SyncsDirectoriesAfterChildren(t *testing.T) {
+	synced := recordSyncedDirectories(t)
+	dir := t.TempDir()
+	tarInterpreter := &FileTarInterpreter{
+		DBDataDirectory: dir,
+		UnwrapResult:    newUnwrapResult(),
+		fsyncModes: internal.TarFsyncModes{Default: DefaultTarFsyncMode, Overrides: []internal.TarFsyncModeOverride{
+			{PathPrefix: filepath.Join(dir, "pg_tblspc", "16400"), Mode: DisabledTarFsyncMode},
+		}},
+	}
+
+	headers := []*tar.Header{
+		{Name: "base", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "base/1/1259", Typeflag: tar.TypeReg, Mode: 0600, Size: 4},
+		{Name: "global/pg_control", Typeflag: tar.TypeReg, Mode: 0600, Size: 4},
+		{Name: "pg_tblspc/16400/PG_14/16385", Typeflag: tar.TypeReg, Mode: 0600, Size: 4},
+		{Name: "base/1/1259_link", Typeflag: tar.TypeSymlink, Linkname: "1259"},
+	}
+	for _, header := range headers {
+		assert.NoError(t, tarInterpreter.Interpret(bytes.NewBufferString("data"), header))
+	}
+	assert.Empty(t, *synced)
+
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+	assert.Equal(t, []string{filepath.Join(dir, "base/1"), filepath.Join(dir, "base"), filepath.Join(dir, "global"), dir},
+		*synced)
+	assert.Empty(t, tarInterpreter.dirsToSync.paths)
+}
+
+func TestPerFileDatasync_DoesNotSyncDirectories(t *testing.T) {
+	synced := recordSyncedDirectories(t)
+	dir := t.TempDir()
+	tarInterpreter := &FileTarInterpreter{
+		DBDataDirectory: dir,
+		UnwrapResult:    newUnwrapResult(),
+		fsyncModes:      internal.TarFsyncModes{Default: PerFileDatasyncTarFsyncMode},
+	}
+
+	err := tarInterpreter.Interpret(bytes.NewBufferString("data"), &tar.Header{
+		Name: "nested/file", Typeflag: tar.TypeReg, Mode: 0600, Size: 4,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+	assert.Empty(t, *synced)
+}
+
+func TestSyncDirectories_ReturnsErrorForMissingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, syncDirectories([]string{dir}))
+	assert.Error(t, syncDirectories([]string{filepath.Join(dir, "missing")}))
+}
@@ -2,16 +2,20 @@ package postgres
 
 import (
 	"archive/tar"
+	"context"
+	"hash"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -22,30 +26,207 @@ type FileTarInterpreter struct {
 	FilesMetadata   FilesMetadataDto
 	FilesToUnwrap   map[string]bool
 	UnwrapResult    *UnwrapResult
+	// PathRemapper redirects the entries with matching prefixes to other locations
+	PathRemapper PathRemapper
+	// AllowedRoots restricts the remapped destinations, if set
+	AllowedRoots []string
+	// ProgressReporter is notified about the extracted files, if set
+	ProgressReporter ProgressReporter
+	// DryRun records the planned actions into the UnwrapResult instead of writing to disk
+	DryRun bool
+	// SeedDirectory holds the earlier restored copy, unchanged files are reflinked from it
+	// instead of being extracted, if set
+	SeedDirectory string
+	// SharedBaseDirectory holds the read-only copy of the data directory, the files matching
+	// the SharedFilePatterns are symlinked to it if their copies match the backup checksums, if set
+	SharedBaseDirectory string
+	SharedFilePatterns  []string
+	// Ctx aborts the extraction once it is cancelled: the file being written is removed
+	// and no further files are extracted, if set
+	Ctx context.Context
+	// ContentFilter transforms the regular file contents before they are written, if set.
+	// It is not applied to the increments.
+	ContentFilter ContentFilter
+	// ForceRewrite extracts the files already on disk even if their contents match the backup checksums
+	ForceRewrite bool
+	// ScanReport makes the entries to be read and verified against the FilesMetadata checksums
+	// instead of being written to disk, the results are recorded in it, if set
+	ScanReport *BackupScanReport
+	// ContinueOnError records the failures of the files into the UnwrapResult and proceeds with the remaining files,
+	// the failures are returned by OnInterpretFinish. The full disk and the cancellation still abort the restore.
+	ContinueOnError bool
+	// VirtualLayout indexes the entries instead of writing them to disk, so the files are read
+	// from the backing archive on demand, if set. The archive is indexed by the IndexArchive.
+	VirtualLayout *VirtualLayout
 
 	createNewIncrementalFiles bool
+	fsyncModes                internal.TarFsyncModes
+	filesToSync               filesToSync
+	dirsToSync                dirsToSync
+	verifyChecksums           bool
+	restoreXattrsEnabled      bool
+	strictXattrs              bool
+	chownEnabled              bool
+	strictChown               bool
+	uidMap                    idMap
+	gidMap                    idMap
+	copyBuffers               *copyBufferPool
+	// extractedBytes is the size of the files extracted completely
+	extractedBytes int64
+	// fileRetries is the number of times the copy broken by the storage is repeated from the entry beginning
+	fileRetries int
+	// rangeThreshold is the size of the files downloaded by rangeStreams concurrent ranges, 0 disables the ranges
+	rangeThreshold int64
+	rangeStreams   int
+	// atomicWrites overrides the default of writesAtomically, if set
+	atomicWrites *bool
+	// incrementedLater are the files the increments of the later backups of the chain are applied to,
+	// so they are not symlinked to the shared base
+	incrementedLater map[string]bool
+	// journal records the completed files of the journalBackupName, if set
+	journal           *RestoreJournal
+	journalBackupName string
+	// stopScanOnError fails the scan on the first checksum mismatch instead of recording it to the ScanReport
+	stopScanOnError bool
+}
+
+// TarInterpreterEngine is the name the FileTarInterpreter is registered by in the internal tar interpreter registry
+const TarInterpreterEngine = "postgres"
+
+// FileTarInterpreterMetadata is the internal.TarInterpreterOptions metadata of the FileTarInterpreter
+type FileTarInterpreterMetadata struct {
+	Sentinel                  BackupSentinelDto
+	FilesMetadata             FilesMetadataDto
+	CreateNewIncrementalFiles bool
+}
+
+var _ internal.RestoreTarInterpreter = &FileTarInterpreter{}
+
+func init() {
+	internal.RegisterTarInterpreter(TarInterpreterEngine, newRegisteredFileTarInterpreter)
 }
 
 func NewFileTarInterpreter(
 	dbDataDirectory string, sentinel BackupSentinelDto, filesMetadata FilesMetadataDto,
 	filesToUnwrap map[string]bool, createNewIncrementalFiles bool,
 ) *FileTarInterpreter {
-	return &FileTarInterpreter{dbDataDirectory, sentinel, filesMetadata,
-		filesToUnwrap, newUnwrapResult(), createNewIncrementalFiles}
+	tarInterpreter, err := newFileTarInterpreter(dbDataDirectory, sentinel, filesMetadata, filesToUnwrap, createNewIncrementalFiles)
+	tracelog.ErrorLogger.FatalOnError(err)
+	return tarInterpreter
+}
+
+func newFileTarInterpreter(
+	dbDataDirectory string, sentinel BackupSentinelDto, filesMetadata FilesMetadataDto,
+	filesToUnwrap map[string]bool, createNewIncrementalFiles bool,
+) (*FileTarInterpreter, error) {
+	fsyncModes, err := internal.GetTarFsyncModes()
+	if err != nil {
+		return nil, err
+	}
+	uidMap, err := parseIDMap(viper.GetString(internal.RestoreChownUIDMapSetting))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s setting", internal.RestoreChownUIDMapSetting)
+	}
+	gidMap, err := parseIDMap(viper.GetString(internal.RestoreChownGIDMapSetting))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s setting", internal.RestoreChownGIDMapSetting)
+	}
+	sharedBaseDirectory, sharedFilePatterns, err := getRestoreSharedBase()
+	if err != nil {
+		return nil, err
+	}
+	return &FileTarInterpreter{DBDataDirectory: dbDataDirectory, Sentinel: sentinel, FilesMetadata: filesMetadata,
+		FilesToUnwrap: filesToUnwrap, UnwrapResult: newUnwrapResult(),
+		createNewIncrementalFiles: createNewIncrementalFiles, fsyncModes: fsyncModes,
+		verifyChecksums:      viper.GetBool(internal.VerifyFileChecksumsSetting),
+		restoreXattrsEnabled: viper.GetBool(internal.RestoreXattrsSetting),
+		strictXattrs:         viper.GetBool(internal.RestoreXattrsStrictSetting),
+		chownEnabled:         viper.GetBool(internal.RestoreChownSetting),
+		strictChown:          viper.GetBool(internal.RestoreChownStrictSetting),
+		uidMap:               uidMap,
+		gidMap:               gidMap,
+		SeedDirectory:        viper.GetString(internal.RestoreSeedDirSetting),
+		SharedBaseDirectory:  sharedBaseDirectory,
+		SharedFilePatterns:   sharedFilePatterns,
+		ForceRewrite:         viper.GetBool(internal.RestoreForceRewriteSetting),
+		copyBuffers:          newCopyBufferPool(viper.GetInt(internal.RestoreCopyBufferSetting)),
+		fileRetries:          viper.GetInt(internal.RestoreFileRetriesSetting),
+		rangeThreshold:       viper.GetInt64(internal.RestoreRangeThresholdSetting),
+		rangeStreams:         viper.GetInt(internal.RestoreRangeStreamsSetting),
+		atomicWrites:         getRestoreAtomicWrites(),
+		ContinueOnError:      viper.GetBool(internal.RestoreContinueOnErrSetting)}, nil
 }
 
-// write file from reader to local file
-func WriteLocalFile(fileReader io.Reader, header *tar.Header, localFile *os.File, fsync bool) error {
-	_, err := io.Copy(localFile, fileReader)
+// getRestoreAtomicWrites returns the configured atomic writes mode, nil if it is not configured
+func getRestoreAtomicWrites() *bool {
+	if !viper.IsSet(internal.RestoreAtomicWritesSetting) {
+		return nil
+	}
+	atomicWrites := viper.GetBool(internal.RestoreAtomicWritesSetting)
+	return &atomicWrites
+}
+
+// newRegisteredFileTarInterpreter adapts the registry options to the FileTarInterpreter,
+// the metadata may be omitted to extract the files without the backup metadata
+func newRegisteredFileTarInterpreter(options internal.TarInterpreterOptions) (internal.RestoreTarInterpreter, error) {
+	var metadata FileTarInterpreterMetadata
+	switch typedMetadata := options.Metadata.(type) {
+	case nil:
+	case FileTarInterpreterMetadata:
+		metadata = typedMetadata
+	case *FileTarInterpreterMetadata:
+		metadata = *typedMetadata
+	default:
+		return nil, errors.Errorf("unexpected %s tar interpreter metadata type %T", TarInterpreterEngine, options.Metadata)
+	}
+	tarInterpreter, err := newFileTarInterpreter(options.DataDirectory, metadata.Sentinel, metadata.FilesMetadata,
+		options.FilesToUnwrap, metadata.CreateNewIncrementalFiles)
 	if err != nil {
-		err1 := os.Remove(localFile.Name())
-		if err1 != nil {
-			tracelog.ErrorLogger.Fatalf("Interpret: failed to remove localFile '%s' because of error: %v",
-				localFile.Name(), err1)
+		return nil, err
+	}
+	tarInterpreter.ProgressReporter = options.ProgressReporter
+	return tarInterpreter, nil
+}
+
+// write file from reader to local file, long zero runs are left as holes,
+// verifies the file contents if the expected checksum is provided.
+// The copy goes through the copyBuffer if it is not nil.
+// The contents are transformed by the contentFilter if it is not nil, the checksum is verified before the transform.
+func WriteLocalFile(fileReader io.Reader, header *tar.Header, localFile *os.File, fsync bool,
+	expectedChecksum *internal.FileChecksum, copyBuffer []byte, contentFilter ContentFilter) error {
+	var checksumHash hash.Hash
+	if expectedChecksum != nil {
+		var err error
+		checksumHash, err = internal.NewChecksumHash(expectedChecksum.Algorithm)
+		if err != nil {
+			return errors.Wrapf(err, "Interpret: failed to verify checksum of '%s'", header.Name)
 		}
+		fileReader = io.TeeReader(fileReader, checksumHash)
+	}
+
+	if contentFilter != nil {
+		var err error
+		fileReader, err = contentFilter(header.Name, fileReader)
+		if err != nil {
+			removeLocalFile(localFile)
+			return errors.Wrapf(err, "Interpret: content filter failed for '%s'", header.Name)
+		}
+	}
+
+	_, err := copyToSparseFile(localFile, fileReader, copyBuffer)
+	if err != nil {
+		removeLocalFile(localFile)
 		return errors.Wrap(err, "Interpret: copy failed")
 	}
 
+	if checksumHash != nil {
+		actualChecksum := internal.NewFileChecksum(expectedChecksum.Algorithm, checksumHash)
+		if actualChecksum.Value != expectedChecksum.Value {
+			removeLocalFile(localFile)
+			return newChecksumMismatchError(header.Name, *expectedChecksum, actualChecksum.Value)
+		}
+	}
+
 	mode := os.FileMode(header.Mode)
 	if err = localFile.Chmod(mode); err != nil {
 		return errors.Wrap(err, "Interpret: chmod failed")
@@ -59,72 +240,380 @@ func WriteLocalFile(fileReader io.Reader, header *tar.Header, localFile *os.File
 	return nil
 }
 
+func removeLocalFile(localFile *os.File) {
+	err := os.Remove(localFile.Name())
+	if err != nil {
+		tracelog.ErrorLogger.Fatalf("Interpret: failed to remove localFile '%s' because of error: %v",
+			localFile.Name(), err)
+	}
+}
+
+// contextReader fails the reads once the context is cancelled, so the copy of a large file is aborted promptly
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (reader *contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.Read(p)
+}
+
+// Reopen reopens the wrapped reader if it is the internal.ReopenableReader
+func (reader *contextReader) Reopen() error {
+	if err := reader.ctx.Err(); err != nil {
+		return err
+	}
+	reopenable, ok := reader.reader.(internal.ReopenableReader)
+	if !ok {
+		return errors.New("reader can not be reopened")
+	}
+	return reopenable.Reopen()
+}
+
+// SupportsRanges is true if the wrapped reader is the internal.EntryRangeReader supporting the ranges
+func (reader *contextReader) SupportsRanges() bool {
+	rangeReader, ok := reader.reader.(internal.EntryRangeReader)
+	return ok && rangeReader.SupportsRanges()
+}
+
+func (reader *contextReader) ReadRange(offset, length int64) (io.ReadCloser, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return nil, err
+	}
+	rangeReader, ok := reader.reader.(internal.EntryRangeReader)
+	if !ok {
+		return nil, errors.New("reader can not read the ranges")
+	}
+	source, err := rangeReader.ReadRange(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return &ioextensions.ReadCascadeCloser{Reader: &contextReader{ctx: reader.ctx, reader: source}, Closer: source}, nil
+}
+
+func (reader *contextReader) SkipContents() error {
+	rangeReader, ok := reader.reader.(internal.EntryRangeReader)
+	if !ok {
+		return errors.New("reader can not skip the contents")
+	}
+	return rangeReader.SkipContents()
+}
+
+// unwrapContextReader returns the reader wrapped by the contextReader, if any
+func unwrapContextReader(fileReader io.Reader) io.Reader {
+	if reader, ok := fileReader.(*contextReader); ok {
+		return reader.reader
+	}
+	return fileReader
+}
+
+// getExpectedChecksum returns the checksum to verify the extracted file against,
+// nil if the verification is disabled or the backup has no checksum for the file
+func (tarInterpreter *FileTarInterpreter) getExpectedChecksum(fileName string) *internal.FileChecksum {
+	if !tarInterpreter.verifyChecksums {
+		return nil
+	}
+	fileDescription, ok := tarInterpreter.FilesMetadata.Files[fileName]
+	if !ok {
+		return nil
+	}
+	return fileDescription.Checksum
+}
+
 // TODO : unit tests
 func (tarInterpreter *FileTarInterpreter) unwrapRegularFileOld(fileReader io.Reader,
 	fileInfo *tar.Header,
 	targetPath string,
 	fsync bool) error {
-	if tarInterpreter.FilesToUnwrap != nil {
-		if _, ok := tarInterpreter.FilesToUnwrap[fileInfo.Name]; !ok {
-			// don't have to unwrap it this time
-			tracelog.DebugLogger.Printf("Don't have to unwrap '%s' this time\n", fileInfo.Name)
-			return nil
-		}
-	}
 	fileDescription, haveFileDescription := tarInterpreter.FilesMetadata.Files[fileInfo.Name]
 
 	// If this file is incremental we use it's base version from incremental path
 	if haveFileDescription && tarInterpreter.Sentinel.IsIncremental() && fileDescription.IsIncremented {
 		err := ApplyFileIncrement(targetPath, fileReader, tarInterpreter.createNewIncrementalFiles, fsync)
-		return errors.Wrapf(err, "Interpret: failed to apply increment for '%s'", targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "Interpret: failed to apply increment for '%s'", targetPath)
+		}
+		tarInterpreter.addToFilesToSync(targetPath)
+		return nil
 	}
-	err := PrepareDirs(fileInfo.Name, targetPath)
+	err := tarInterpreter.prepareDirs(fileInfo.Name, targetPath)
 	if err != nil {
 		return errors.Wrap(err, "Interpret: failed to create all directories")
 	}
-	file, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	copyBuffer := tarInterpreter.getCopyBuffer(fileInfo.Size)
+	defer tarInterpreter.putCopyBuffer(copyBuffer)
+	// the atomically written file is flushed before the rename regardless of the fsync mode
+	atomicWrite := tarInterpreter.writesAtomically()
+	writePath := targetPath
+	if atomicWrite {
+		writePath = atomicWriteTempPath(targetPath)
+	}
+	err = tarInterpreter.retryFileCopy(fileReader, fileInfo.Name, writePath, func() error {
+		file, err := os.OpenFile(writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create new file: '%s'", writePath)
+		}
+		defer utility.LoggedClose(file, "")
+		if rangeReader := tarInterpreter.getRangeReader(fileReader, fileInfo); rangeReader != nil {
+			err = writeLocalFileByRanges(rangeReader, fileInfo, file, fsync && !atomicWrite,
+				tarInterpreter.getExpectedChecksum(fileInfo.Name), tarInterpreter.rangeStreams)
+		} else {
+			err = WriteLocalFile(fileReader, fileInfo, file, fsync && !atomicWrite,
+				tarInterpreter.getExpectedChecksum(fileInfo.Name), copyBuffer, tarInterpreter.ContentFilter)
+		}
+		if err == nil && atomicWrite {
+			err = commitAtomicWrite(file, targetPath)
+		}
+		return err
+	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to create new file: '%s'", targetPath)
+		if atomicWrite {
+			removeAtomicWriteTemp(writePath)
+		}
+		return err
 	}
-	defer utility.LoggedClose(file, "")
-
-	return WriteLocalFile(fileReader, fileInfo, file, fsync)
+	tarInterpreter.addToFilesToSync(targetPath)
+	return nil
 }
 
 // Interpret extracts a tar file to disk and creates needed directories.
-// Returns the first error encountered. Calls fsync after each file
-// is written successfully.
+// Returns the first error encountered. Depending on the fsync mode, calls fsync
+// after each file is written successfully or postpones the flush until OnInterpretFinish.
 func (tarInterpreter *FileTarInterpreter) Interpret(fileReader io.Reader, fileInfo *tar.Header) error {
+	return tarInterpreter.InterpretContext(tarInterpreter.Ctx, fileReader, fileInfo)
+}
+
+// InterpretContext is the Interpret aborted once the ctx is cancelled instead of the Ctx field
+func (tarInterpreter *FileTarInterpreter) InterpretContext(ctx context.Context,
+	fileReader io.Reader, fileInfo *tar.Header) error {
+	err := tarInterpreter.interpretContext(ctx, fileReader, fileInfo)
+	if err == nil && tarInterpreter.ContinueOnError {
+		// the file failed by the earlier attempt to extract its archive is restored by the retry
+		tarInterpreter.UnwrapResult.removeFailedFile(fileInfo.Name)
+	}
+	if err == nil || !tarInterpreter.ContinueOnError || isFatalRestoreError(ctx, err) {
+		return err
+	}
+	tracelog.ErrorLogger.Printf("Failed to restore '%s', continuing with the remaining files: %v", fileInfo.Name, err)
+	tarInterpreter.UnwrapResult.addFailedFile(FailedFile{FileName: fileInfo.Name, Err: err})
+	return nil
+}
+
+func (tarInterpreter *FileTarInterpreter) interpretContext(ctx context.Context,
+	fileReader io.Reader, fileInfo *tar.Header) error {
+	fileInfo, err := resolvePAXHeader(fileInfo)
+	if err != nil {
+		return err
+	}
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrapf(err, "Interpret: extraction of '%s' is aborted", fileInfo.Name)
+		}
+		fileReader = &contextReader{ctx: ctx, reader: fileReader}
+	}
 	tracelog.DebugLogger.Println("Interpreting: ", fileInfo.Name)
-	targetPath := path.Join(tarInterpreter.DBDataDirectory, fileInfo.Name)
-	fsync := !viper.GetBool(internal.TarDisableFsyncSetting)
+	if tarInterpreter.ScanReport != nil {
+		return tarInterpreter.scanEntry(fileReader, fileInfo)
+	}
+	targetPath, err := tarInterpreter.getTargetPath(fileInfo.Name)
+	if err != nil {
+		return err
+	}
+	if tarInterpreter.VirtualLayout != nil {
+		return tarInterpreter.VirtualLayout.add(unwrapContextReader(fileReader), fileInfo)
+	}
+	if tarInterpreter.DryRun {
+		return tarInterpreter.planAction(fileInfo, targetPath)
+	}
+	fsync := tarInterpreter.fsyncModes.ModeFor(targetPath) == DefaultTarFsyncMode
 	switch fileInfo.Typeflag {
 	case tar.TypeReg, tar.TypeRegA:
-		// temporary switch to determine if new unwrap logic should be used
-		if useNewUnwrapImplementation {
-			return tarInterpreter.unwrapRegularFileNew(fileReader, fileInfo, targetPath, fsync)
-		}
-		return tarInterpreter.unwrapRegularFileOld(fileReader, fileInfo, targetPath, fsync)
+		return asDiskFullError(tarInterpreter.unwrapRegularFile(fileReader, fileInfo, targetPath, fsync), fileInfo.Name,
+			atomic.LoadInt64(&tarInterpreter.extractedBytes))
 	case tar.TypeDir:
-		err := os.MkdirAll(targetPath, 0755)
+		err = os.MkdirAll(targetPath, 0755)
 		if err != nil {
-			return errors.Wrapf(err, "Interpret: failed to create all directories in %s", targetPath)
+			return asDiskFullError(errors.Wrapf(err, "Interpret: failed to create all directories in %s", targetPath),
+				fileInfo.Name, atomic.LoadInt64(&tarInterpreter.extractedBytes))
 		}
 		if err = os.Chmod(targetPath, os.FileMode(fileInfo.Mode)); err != nil {
 			return errors.Wrap(err, "Interpret: chmod failed")
 		}
+		if err = tarInterpreter.restoreOwnership(targetPath, fileInfo); err != nil {
+			return err
+		}
+		if err = tarInterpreter.restoreXattrs(targetPath, fileInfo); err != nil {
+			return err
+		}
+		tarInterpreter.addToDirsToSync(targetPath)
 	case tar.TypeLink:
-		if err := os.Link(fileInfo.Name, targetPath); err != nil {
-			return errors.Wrapf(err, "Interpret: failed to create hardlink %s", targetPath)
+		linkSourcePath, err := tarInterpreter.getLinkSourcePath(fileInfo)
+		if err != nil {
+			return err
 		}
+		tarInterpreter.removeResumedLink(targetPath)
+		if err = os.Link(linkSourcePath, targetPath); err != nil {
+			return newLinkCreationError(errors.Wrapf(err, "Interpret: failed to create hardlink %s", targetPath),
+				fileInfo.Name, linkSourcePath)
+		}
+		tarInterpreter.addToDirsToSync(targetPath)
 	case tar.TypeSymlink:
-		if err := os.Symlink(fileInfo.Name, targetPath); err != nil {
-			return errors.Wrapf(err, "Interpret: failed to create symlink %s", targetPath)
+		linkTarget, err := tarInterpreter.getSymlinkTarget(fileInfo, targetPath)
+		if err != nil {
+			return err
+		}
+		tarInterpreter.removeResumedLink(targetPath)
+		if err = os.Symlink(linkTarget, targetPath); err != nil {
+			return newLinkCreationError(errors.Wrapf(err, "Interpret: failed to create symlink %s", targetPath),
+				fileInfo.Name, linkTarget)
 		}
+		tarInterpreter.addToDirsToSync(targetPath)
+		return tarInterpreter.restoreOwnership(targetPath, fileInfo)
 	}
 	return nil
 }
 
+func (tarInterpreter *FileTarInterpreter) unwrapRegularFile(fileReader io.Reader,
+	fileInfo *tar.Header,
+	targetPath string,
+	fsync bool) error {
+	if tarInterpreter.FilesToUnwrap != nil {
+		if _, ok := tarInterpreter.FilesToUnwrap[fileInfo.Name]; !ok {
+			// don't have to unwrap it this time
+			tracelog.DebugLogger.Printf("Don't have to unwrap '%s' this time\n", fileInfo.Name)
+			tarInterpreter.reportFileSkipped(fileInfo.Name)
+			return nil
+		}
+	}
+	tarInterpreter.reportFileStart(fileInfo.Name, fileInfo.Size)
+
+	if tarInterpreter.SharedBaseDirectory != "" && tarInterpreter.trySymlinkToSharedBase(fileInfo, targetPath) {
+		tracelog.DebugLogger.Printf("Symlinked '%s' to the shared base\n", fileInfo.Name)
+		if err := tarInterpreter.restoreOwnership(targetPath, fileInfo); err != nil {
+			return err
+		}
+		tarInterpreter.UnwrapResult.addSharedFile(fileInfo.Name)
+		tarInterpreter.AddFileUnwrapResult(NewCompletedResult(), fileInfo.Name)
+		tarInterpreter.addToDirsToSync(targetPath)
+		tarInterpreter.reportFileComplete(fileInfo.Name, fileInfo.Size)
+		return tarInterpreter.journalFileCompleted(fileInfo.Name, targetPath)
+	}
+
+	if tarInterpreter.SeedDirectory != "" && tarInterpreter.tryReflinkFromSeed(fileInfo, targetPath) {
+		tracelog.DebugLogger.Printf("Reflinked '%s' from the seed directory\n", fileInfo.Name)
+		if err := tarInterpreter.restoreOwnership(targetPath, fileInfo); err != nil {
+			return err
+		}
+		if err := tarInterpreter.restoreXattrs(targetPath, fileInfo); err != nil {
+			return err
+		}
+		tarInterpreter.UnwrapResult.addReflinkedFile(fileInfo.Name)
+		tarInterpreter.AddFileUnwrapResult(NewCompletedResult(), fileInfo.Name)
+		tarInterpreter.addToFilesToSync(targetPath)
+		tarInterpreter.reportFileComplete(fileInfo.Name, fileInfo.Size)
+		return tarInterpreter.journalFileCompleted(fileInfo.Name, targetPath)
+	}
+
+	var err error
+	// temporary switch to determine if new unwrap logic should be used
+	if useNewUnwrapImplementation {
+		err = tarInterpreter.unwrapRegularFileNew(fileReader, fileInfo, targetPath, fsync)
+	} else {
+		err = tarInterpreter.unwrapRegularFileOld(fileReader, fileInfo, targetPath, fsync)
+	}
+	if err != nil {
+		return err
+	}
+	// the owner is changed before the extended attributes, since chown drops the file capabilities
+	if err = tarInterpreter.restoreOwnership(targetPath, fileInfo); err != nil {
+		return err
+	}
+	if err = tarInterpreter.restoreXattrs(targetPath, fileInfo); err != nil {
+		return err
+	}
+	if tarInterpreter.SeedDirectory != "" {
+		tarInterpreter.UnwrapResult.addWrittenFile(fileInfo.Name)
+	}
+	tarInterpreter.reportFileComplete(fileInfo.Name, fileInfo.Size)
+	return tarInterpreter.journalFileCompleted(fileInfo.Name, targetPath)
+}
+
+// RecordArchiveFailure records the failure of the whole archive in the continue on error mode,
+// so the extraction proceeds with the next archive. The files of the archive extracted before the failure are kept.
+func (tarInterpreter *FileTarInterpreter) RecordArchiveFailure(archivePath string, err error) bool {
+	if !tarInterpreter.ContinueOnError || isFatalRestoreError(nil, err) {
+		return false
+	}
+	tracelog.ErrorLogger.Printf("Failed to restore the archive '%s', continuing with the remaining archives: %v",
+		archivePath, err)
+	tarInterpreter.UnwrapResult.addFailedFile(FailedFile{FileName: archivePath,
+		Err: errors.Wrap(err, "the files of the archive past the failure were not restored")})
+	return true
+}
+
+// OnInterpretFinish flushes the extracted files if required by the fsync modes and returns the failures
+// of the files recorded in the continue on error mode.
+// Should be called once all the tars are extracted.
+func (tarInterpreter *FileTarInterpreter) OnInterpretFinish() error {
+	if err := tarInterpreter.syncExtractedFiles(); err != nil {
+		return err
+	}
+	if !tarInterpreter.ContinueOnError {
+		return nil
+	}
+	return newRestoreFailuresError(tarInterpreter.UnwrapResult.FailedFiles())
+}
+
+// syncExtractedFiles flushes the extracted files and their directories as required by the fsync modes
+func (tarInterpreter *FileTarInterpreter) syncExtractedFiles() error {
+	if tarInterpreter.DryRun || tarInterpreter.VirtualLayout != nil {
+		return nil
+	}
+	if tarInterpreter.fsyncModes.Uses(GlobalTarFsyncMode) {
+		tracelog.InfoLogger.Println("Calling global sync for the extracted files")
+		globalSync()
+	}
+	if tarInterpreter.fsyncModes.Uses(PerFileDatasyncTarFsyncMode) {
+		paths := tarInterpreter.filesToSync.takeAll()
+		concurrency, err := internal.GetMaxConcurrency(internal.TarFsyncConcurrencySetting)
+		if err != nil {
+			return err
+		}
+		tracelog.InfoLogger.Printf("Calling fdatasync for %d extracted files\n", len(paths))
+		if err = datasyncFiles(paths, concurrency); err != nil {
+			return err
+		}
+	}
+	// the files are flushed by now, so the entries of the directories are flushed after their children
+	if dirs := tarInterpreter.dirsToSync.takeAll(); len(dirs) > 0 {
+		tracelog.InfoLogger.Printf("Calling fsync for %d directories of the extracted files\n", len(dirs))
+		return syncDirectories(dirs)
+	}
+	return nil
+}
+
+// addToFilesToSync remembers the successfully written file
+// to flush it in OnInterpretFinish if required by the fsync mode of its path
+func (tarInterpreter *FileTarInterpreter) addToFilesToSync(targetPath string) {
+	if tarInterpreter.fsyncModes.ModeFor(targetPath) == PerFileDatasyncTarFsyncMode {
+		tarInterpreter.filesToSync.add(targetPath)
+	}
+	tarInterpreter.addToDirsToSync(targetPath)
+}
+
+// addToDirsToSync remembers the directories holding the entry of the successfully created file, directory or link
+// to flush them in OnInterpretFinish if the fsync mode of its path is the default or the global one.
+// The per file datasync mode flushes only the file data, so the directories are not flushed.
+func (tarInterpreter *FileTarInterpreter) addToDirsToSync(targetPath string) {
+	switch tarInterpreter.fsyncModes.ModeFor(targetPath) {
+	case DefaultTarFsyncMode, GlobalTarFsyncMode:
+		tarInterpreter.dirsToSync.add(filepath.Dir(targetPath), tarInterpreter.DBDataDirectory)
+	}
+}
+
 // PrepareDirs makes sure all dirs exist
 func PrepareDirs(fileName string, targetPath string) error {
 	if fileName == targetPath {
@@ -135,3 +624,56 @@ func PrepareDirs(fileName string, targetPath string) error {
 	err := os.MkdirAll(dir, 0755)
 	return err
 }
+
+// prepareDirs creates the missing parent directories of the targetPath,
+// applying the modes recorded in the FilesMetadata, 0755 is used if there is no recorded mode
+func (tarInterpreter *FileTarInterpreter) prepareDirs(fileName string, targetPath string) error {
+	if fileName == targetPath {
+		return nil // because it runs in the local directory
+	}
+	var missingDirs, missingDirNames []string
+	localDir, dirName := filepath.Dir(targetPath), path.Dir(fileName)
+	for {
+		if _, err := os.Stat(localDir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missingDirs = append(missingDirs, localDir)
+		missingDirNames = append(missingDirNames, dirName)
+		parentDir := filepath.Dir(localDir)
+		if parentDir == localDir {
+			break
+		}
+		localDir, dirName = parentDir, path.Dir(dirName)
+	}
+
+	for i := len(missingDirs) - 1; i >= 0; i-- {
+		mode, haveMode := tarInterpreter.getRecordedDirMode(missingDirNames[i])
+		if !haveMode {
+			mode = 0755
+		}
+		if err := os.Mkdir(missingDirs[i], mode); err != nil && !os.IsExist(err) {
+			return err
+		}
+		if haveMode {
+			// the recorded mode should not be affected by umask
+			if err := os.Chmod(missingDirs[i], mode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getRecordedDirMode looks up the directory mode stored in the FilesMetadata
+func (tarInterpreter *FileTarInterpreter) getRecordedDirMode(dirName string) (os.FileMode, bool) {
+	if dirName == "." || dirName == "/" {
+		return 0, false
+	}
+	fileDescription, ok := tarInterpreter.FilesMetadata.Files[dirName]
+	if !ok || fileDescription.FileMode == 0 {
+		return 0, false
+	}
+	return os.FileMode(fileDescription.FileMode).Perm(), true
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// PlannedActionType describes what the FileTarInterpreter would have done with the tar entry
+type PlannedActionType int
+
+const (
+	// CreateFilePlannedAction writes a new file
+	CreateFilePlannedAction PlannedActionType = iota
+	// OverwriteFilePlannedAction replaces the file existing on disk
+	OverwriteFilePlannedAction
+	// ApplyIncrementPlannedAction applies the increment to the file existing on disk
+	ApplyIncrementPlannedAction
+	// SkipFilePlannedAction leaves the file untouched
+	SkipFilePlannedAction
+	// CreateDirPlannedAction creates the directory
+	CreateDirPlannedAction
+	// CreateHardlinkPlannedAction creates the hardlink to the LinkSource
+	CreateHardlinkPlannedAction
+	// CreateSymlinkPlannedAction creates the symlink pointing to the LinkSource
+	CreateSymlinkPlannedAction
+)
+
+var plannedActionTypeNames = map[PlannedActionType]string{
+	CreateFilePlannedAction:     "create",
+	OverwriteFilePlannedAction:  "overwrite",
+	ApplyIncrementPlannedAction: "increment",
+	SkipFilePlannedAction:       "skip",
+	CreateDirPlannedAction:      "mkdir",
+	CreateHardlinkPlannedAction: "hardlink",
+	CreateSymlinkPlannedAction:  "symlink",
+}
+
+func (actionType PlannedActionType) String() string {
+	if name, ok := plannedActionTypeNames[actionType]; ok {
+		return name
+	}
+	return fmt.Sprintf("PlannedActionType(%d)", int(actionType))
+}
+
+// PlannedAction is recorded by the FileTarInterpreter in the DryRun mode instead of writing to disk
+type PlannedAction struct {
+	Type PlannedActionType
+	// Exists is true if the target path is already present on disk
+	Exists bool
+	// LinkSource is the hardlink source path or the symlink target
+	LinkSource string
+}
+
+// PlannedActions returns the actions recorded in the DryRun mode keyed by the target path
+func (result *UnwrapResult) PlannedActions() map[string]PlannedAction {
+	result.plannedActionsMutex.Lock()
+	defer result.plannedActionsMutex.Unlock()
+	plannedActions := make(map[string]PlannedAction, len(result.plannedActions))
+	for targetPath, action := range result.plannedActions {
+		plannedActions[targetPath] = action
+	}
+	return plannedActions
+}
+
+// planAction records what Interpret would have done with the tar entry without touching the disk
+func (tarInterpreter *FileTarInterpreter) planAction(fileInfo *tar.Header, targetPath string) error {
+	exists, err := isPathExisting(targetPath)
+	if err != nil {
+		return err
+	}
+	action := PlannedAction{Exists: exists}
+	switch fileInfo.Typeflag {
+	case tar.TypeReg, tar.TypeRegA:
+		action.Type = tarInterpreter.planRegularFileAction(fileInfo, exists)
+	case tar.TypeDir:
+		action.Type = CreateDirPlannedAction
+	case tar.TypeLink:
+		action.Type = CreateHardlinkPlannedAction
+		if action.LinkSource, err = tarInterpreter.getLinkSourcePath(fileInfo); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		action.Type = CreateSymlinkPlannedAction
+		if action.LinkSource, err = tarInterpreter.getSymlinkTarget(fileInfo, targetPath); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	tarInterpreter.addToPlannedActions(targetPath, action)
+	return nil
+}
+
+func (tarInterpreter *FileTarInterpreter) planRegularFileAction(fileInfo *tar.Header, exists bool) PlannedActionType {
+	if tarInterpreter.FilesToUnwrap != nil {
+		if _, ok := tarInterpreter.FilesToUnwrap[fileInfo.Name]; !ok {
+			return SkipFilePlannedAction
+		}
+	}
+	fileDescription, haveFileDescription := tarInterpreter.FilesMetadata.Files[fileInfo.Name]
+	if haveFileDescription && tarInterpreter.Sentinel.IsIncremental() && fileDescription.IsIncremented {
+		return ApplyIncrementPlannedAction
+	}
+	if exists {
+		return OverwriteFilePlannedAction
+	}
+	return CreateFilePlannedAction
+}
+
+func (tarInterpreter *FileTarInterpreter) addToPlannedActions(targetPath string, action PlannedAction) {
+	tarInterpreter.UnwrapResult.plannedActionsMutex.Lock()
+	tarInterpreter.UnwrapResult.plannedActions[targetPath] = action
+	tarInterpreter.UnwrapResult.plannedActionsMutex.Unlock()
+}
+
+func isPathExisting(targetPath string) (bool, error) {
+	_, err := os.Lstat(targetPath)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, errors.Wrapf(err, "Interpret: failed to stat '%s'", targetPath)
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestDryRun_RecordsPlannedActions(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "existing"), []byte("data"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "incremented"), []byte("data"), 0600))
+
+	incrementFrom, incrementFullName := "base_000000010000000000000002", "base_000000010000000000000002"
+	incrementFromLSN, incrementCount := uint64(1), 1
+	tarInterpreter := &FileTarInterpreter{
+		DBDataDirectory: dir,
+		Sentinel: BackupSentinelDto{IncrementFrom: &incrementFrom, IncrementFullName: &incrementFullName,
+			IncrementFromLSN: &incrementFromLSN, IncrementCount: &incrementCount},
+		FilesMetadata: FilesMetadataDto{Files: internal.BackupFileList{
+			"incremented": {IsIncremented: true},
+		}},
+		FilesToUnwrap: map[string]bool{"new": true, "existing": true, "incremented": true},
+		UnwrapResult:  newUnwrapResult(),
+		DryRun:        true,
+	}
+
+	headers := []*tar.Header{
+		{Name: "new", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "existing", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "incremented", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "skipped", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "base", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "hardlink", Linkname: "existing", Typeflag: tar.TypeLink},
+		{Name: "symlink", Linkname: "existing", Typeflag: tar.TypeSymlink},
+	}
+	for _, header := range headers {
+		assert.NoError(t, tarInterpreter.Interpret(bytes.NewBufferString("content"), header))
+	}
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+
+	assert.Equal(t, map[string]PlannedAction{
+		filepath.Join(dir, "new"):         {Type: CreateFilePlannedAction},
+		filepath.Join(dir, "existing"):    {Type: OverwriteFilePlannedAction, Exists: true},
+		filepath.Join(dir, "incremented"): {Type: ApplyIncrementPlannedAction, Exists: true},
+		filepath.Join(dir, "skipped"):     {Type: SkipFilePlannedAction},
+		filepath.Join(dir, "base"):        {Type: CreateDirPlannedAction},
+		filepath.Join(dir, "hardlink"):    {Type: CreateHardlinkPlannedAction, LinkSource: filepath.Join(dir, "existing")},
+		filepath.Join(dir, "symlink"):     {Type: CreateSymlinkPlannedAction, LinkSource: "existing"},
+	}, tarInterpreter.UnwrapResult.PlannedActions())
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	content, err := os.ReadFile(filepath.Join(dir, "incremented"))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+}
+
+func TestDryRun_ValidatesSymlinkLinkname(t *testing.T) {
+	dir := t.TempDir()
+	tarInterpreter := &FileTarInterpreter{DBDataDirectory: dir, UnwrapResult: newUnwrapResult(), DryRun: true}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{},
+		&tar.Header{Name: "base/link", Linkname: "../../escaped", Typeflag: tar.TypeSymlink})
+	var traversalErr PathTraversalError
+	assert.ErrorAs(t, err, &traversalErr)
+	assert.NotContains(t, tarInterpreter.UnwrapResult.PlannedActions(), filepath.Join(dir, "base/link"))
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"io"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
@@ -15,54 +16,153 @@ func (tarInterpreter *FileTarInterpreter) unwrapRegularFileNew(fileReader io.Rea
 	header *tar.Header,
 	targetPath string,
 	fsync bool) error {
-	if tarInterpreter.FilesToUnwrap != nil {
-		if _, ok := tarInterpreter.FilesToUnwrap[header.Name]; !ok {
-			// don't have to unwrap it this time
-			tracelog.DebugLogger.Printf("Don't have to unwrap '%s' this time\n", header.Name)
+	if !tarInterpreter.ForceRewrite {
+		isUnchanged, err := tarInterpreter.keepUnchangedLocalFile(header, targetPath, fsync)
+		if err != nil {
+			return err
+		}
+		if isUnchanged {
+			tracelog.DebugLogger.Printf("'%s' on disk matches the backup checksum, not rewriting it\n", header.Name)
+			tarInterpreter.AddFileUnwrapResult(NewUnchangedResult(), header.Name)
+			tarInterpreter.addToFilesToSync(targetPath)
 			return nil
 		}
 	}
-	fileUnwrapper := getFileUnwrapper(tarInterpreter, header, targetPath)
-	localFile, isNewFile, err := getLocalFile(targetPath, header)
+	copyBuffer := tarInterpreter.getCopyBuffer(header.Size)
+	defer tarInterpreter.putCopyBuffer(copyBuffer)
+	fileUnwrapper := getFileUnwrapper(tarInterpreter, header, targetPath, copyBuffer)
+	// the new files are written to the temporary ones and renamed over the targets once complete,
+	// the existing ones are patched in place
+	writePath := targetPath
+	if tarInterpreter.writesAtomically() {
+		writePath = atomicWriteTempPath(targetPath)
+	}
+	localFile, isNewFile, err := tarInterpreter.getLocalFile(targetPath, writePath, header)
 	if err != nil {
 		return err
 	}
-	defer utility.LoggedClose(localFile, "")
-	defer utility.LoggedSync(localFile, "", fsync)
+	defer func() {
+		if localFile != nil {
+			utility.LoggedClose(localFile, "")
+		}
+	}()
+	// the file is flushed here rather than by the unwrapper to measure the flush separately from the copy
+	copyStart := time.Now()
 	var unwrapResult *FileUnwrapResult
 	var unwrapError error
 	if isNewFile {
-		unwrapResult, unwrapError = fileUnwrapper.UnwrapNewFile(fileReader, header, localFile, fsync)
+		// only the new files are retried, since the partially written one is removed before the next attempt
+		unwrapError = tarInterpreter.retryFileCopy(fileReader, header.Name, writePath, func() (err error) {
+			if localFile == nil {
+				if localFile, err = tarInterpreter.createLocalFile(writePath, header.Name); err != nil {
+					return err
+				}
+			}
+			if rangeReader := tarInterpreter.getRangeReader(fileReader, header); rangeReader != nil {
+				unwrapResult, err = NewCompletedResult(), writeLocalFileByRanges(rangeReader, header, localFile, false,
+					tarInterpreter.getExpectedChecksum(header.Name), tarInterpreter.rangeStreams)
+			} else {
+				unwrapResult, err = fileUnwrapper.UnwrapNewFile(fileReader, header, localFile, false)
+			}
+			if err != nil {
+				utility.LoggedClose(localFile, "")
+				localFile = nil
+			}
+			return err
+		})
 	} else {
-		unwrapResult, unwrapError = fileUnwrapper.UnwrapExistingFile(fileReader, header, localFile, fsync)
+		unwrapResult, unwrapError = fileUnwrapper.UnwrapExistingFile(fileReader, header, localFile, false)
 	}
+	isAtomicWrite := isNewFile && writePath != targetPath
 	if unwrapError != nil {
+		if isAtomicWrite {
+			removeAtomicWriteTemp(writePath)
+		}
 		return unwrapError
 	}
+	timing := FileUnwrapTiming{Name: header.Name, Bytes: header.Size, CopyDuration: time.Since(copyStart)}
+	if isAtomicWrite {
+		// the temporary file is flushed before the rename regardless of the fsync mode
+		fsyncStart := time.Now()
+		if err = commitAtomicWrite(localFile, targetPath); err != nil {
+			removeAtomicWriteTemp(writePath)
+			return err
+		}
+		timing.FsyncDuration = time.Since(fsyncStart)
+	} else if fsync {
+		fsyncStart := time.Now()
+		if err = localFile.Sync(); err != nil {
+			return errors.Wrap(err, "Interpret: fsync failed")
+		}
+		timing.FsyncDuration = time.Since(fsyncStart)
+	}
+	tarInterpreter.UnwrapResult.addFileTiming(timing)
 	tarInterpreter.AddFileUnwrapResult(unwrapResult, header.Name)
+	if unwrapResult.FileUnwrapResultType != Skipped {
+		tarInterpreter.addToFilesToSync(targetPath)
+	}
 	return nil
 }
 
-// get local file, create new if not existed
-func getLocalFile(targetPath string, header *tar.Header) (localFile *os.File, isNewFile bool, err error) {
+// keepUnchangedLocalFile checks if the file on disk has the contents with the checksum stored in the backup,
+// the mode of such a file is fixed to match the backup and it is flushed if fsync is set, as the written files are.
+// The incremented files are not checked, since their backup checksums are of the increments.
+func (tarInterpreter *FileTarInterpreter) keepUnchangedLocalFile(header *tar.Header, targetPath string,
+	fsync bool) (bool, error) {
+	fileDescription, ok := tarInterpreter.FilesMetadata.Files[header.Name]
+	if !ok || fileDescription.Checksum == nil || fileDescription.IsIncremented {
+		return false, nil
+	}
+	localFile, err := os.Open(targetPath)
+	if err != nil {
+		return false, nil
+	}
+	defer utility.LoggedClose(localFile, "")
+	if !isLocalFileUnchanged(localFile, header.Size, *fileDescription.Checksum) {
+		return false, nil
+	}
+
+	localFileInfo, err := localFile.Stat()
+	if err != nil {
+		return false, errors.Wrapf(err, "Interpret: failed to stat '%s'", targetPath)
+	}
+	if mode := os.FileMode(header.Mode).Perm(); localFileInfo.Mode().Perm() != mode {
+		if err = os.Chmod(targetPath, mode); err != nil {
+			return false, errors.Wrap(err, "Interpret: chmod failed")
+		}
+	}
+	if fsync {
+		if err = localFile.Sync(); err != nil {
+			return false, errors.Wrap(err, "Interpret: fsync failed")
+		}
+	}
+	return true, nil
+}
+
+// get local file, create new at the writePath if not existed
+func (tarInterpreter *FileTarInterpreter) getLocalFile(targetPath, writePath string,
+	header *tar.Header) (localFile *os.File, isNewFile bool, err error) {
 	if localFileInfo, _ := getLocalFileInfo(targetPath); localFileInfo != nil {
 		localFile, err = os.OpenFile(targetPath, os.O_RDWR, 0666)
 	} else {
-		localFile, err = createLocalFile(targetPath, header.Name)
+		localFile, err = tarInterpreter.createLocalFile(writePath, header.Name)
 		isNewFile = true
 	}
 	return localFile, isNewFile, err
 }
 
 // get file unwrapper for file depending on backup type
-func getFileUnwrapper(tarInterpreter *FileTarInterpreter, header *tar.Header, targetPath string) IBackupFileUnwrapper {
+func getFileUnwrapper(tarInterpreter *FileTarInterpreter, header *tar.Header, targetPath string,
+	copyBuffer []byte) IBackupFileUnwrapper {
 	fileDescription, haveFileDescription := tarInterpreter.FilesMetadata.Files[header.Name]
 	isIncremented := haveFileDescription && fileDescription.IsIncremented
 	var isPageFile bool
 	if localFileInfo, _ := getLocalFileInfo(targetPath); localFileInfo != nil {
 		isPageFile = isPagedFile(localFileInfo, targetPath)
 	}
-	options := &BackupFileOptions{isIncremented: isIncremented, isPageFile: isPageFile}
+	options := &BackupFileOptions{isIncremented: isIncremented, isPageFile: isPageFile,
+		expectedChecksum: tarInterpreter.getExpectedChecksum(header.Name), copyBuffer: copyBuffer,
+		contentFilter: tarInterpreter.ContentFilter}
 
 	// todo: clearer catchup backup detection logic
 	isCatchup := tarInterpreter.createNewIncrementalFiles
@@ -85,8 +185,8 @@ func getLocalFileInfo(targetPath string) (fileInfo os.FileInfo, err error) {
 }
 
 // create new local file on disk
-func createLocalFile(targetPath, name string) (*os.File, error) {
-	err := PrepareDirs(name, targetPath)
+func (tarInterpreter *FileTarInterpreter) createLocalFile(targetPath, name string) (*os.File, error) {
+	err := tarInterpreter.prepareDirs(name, targetPath)
 	if err != nil {
 		return nil, errors.Wrap(err, "Interpret: failed to create all directories")
 	}
@@ -101,6 +201,10 @@ func (tarInterpreter *FileTarInterpreter) AddFileUnwrapResult(result *FileUnwrap
 	switch result.FileUnwrapResultType {
 	case Skipped:
 		return
+	case Unchanged:
+		// the unchanged file is complete, so the later backups of the chain do not patch it
+		tarInterpreter.addToUnchangedFiles(fileName)
+		tarInterpreter.addToCompletedFiles(fileName)
 	case Completed:
 		tarInterpreter.addToCompletedFiles(fileName)
 	case CreatedFromIncrement:
@@ -116,6 +220,19 @@ func (tarInterpreter *FileTarInterpreter) addToCompletedFiles(fileName string) {
 	tarInterpreter.UnwrapResult.completedFilesMutex.Unlock()
 }
 
+func (tarInterpreter *FileTarInterpreter) addToUnchangedFiles(fileName string) {
+	tarInterpreter.UnwrapResult.unchangedFilesMutex.Lock()
+	tarInterpreter.UnwrapResult.unchangedFiles = append(tarInterpreter.UnwrapResult.unchangedFiles, fileName)
+	tarInterpreter.UnwrapResult.unchangedFilesMutex.Unlock()
+}
+
+// UnchangedFiles returns the files which were not rewritten since their contents on disk match the backup
+func (result *UnwrapResult) UnchangedFiles() []string {
+	result.unchangedFilesMutex.Lock()
+	defer result.unchangedFilesMutex.Unlock()
+	return append([]string{}, result.unchangedFiles...)
+}
+
 func (tarInterpreter *FileTarInterpreter) addToCreatedPageFiles(fileName string, blocksToRestoreCount int64) {
 	tarInterpreter.UnwrapResult.createdPageFilesMutex.Lock()
 	tarInterpreter.UnwrapResult.createdPageFiles[fileName] = blocksToRestoreCount
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestUnwrapRegularFileNew_KeepsUnchangedFiles(t *testing.T) {
+	useNewUnwrapImplementation = true
+	defer func() { useNewUnwrapImplementation = false }()
+
+	digest := sha256.Sum256([]byte("data"))
+	checksum := &internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm, Value: hex.EncodeToString(digest[:])}
+	files := internal.BackupFileList{"unchanged": {Checksum: checksum}, "changed": {Checksum: checksum}}
+
+	for _, forceRewrite := range []bool{false, true} {
+		dataDirectory := t.TempDir()
+		tarInterpreter := NewFileTarInterpreter(dataDirectory, BackupSentinelDto{}, FilesMetadataDto{Files: files}, nil, false)
+		tarInterpreter.ForceRewrite = forceRewrite
+		assert.NoError(t, os.WriteFile(filepath.Join(dataDirectory, "unchanged"), []byte("data"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dataDirectory, "changed"), []byte("old!"), 0644))
+
+		for _, name := range []string{"unchanged", "changed"} {
+			err := tarInterpreter.Interpret(bytes.NewBufferString("data"), &tar.Header{
+				Name:     name,
+				Typeflag: tar.TypeReg,
+				Mode:     0600,
+				Size:     4,
+			})
+			assert.NoError(t, err)
+		}
+
+		if forceRewrite {
+			assert.Empty(t, tarInterpreter.UnwrapResult.UnchangedFiles())
+			continue
+		}
+		assert.Equal(t, []string{"unchanged"}, tarInterpreter.UnwrapResult.UnchangedFiles())
+		// the unchanged file is complete, so the later backups of the chain do not patch it
+		assert.Contains(t, tarInterpreter.UnwrapResult.completedFiles, "unchanged")
+		info, err := os.Stat(filepath.Join(dataDirectory, "unchanged"))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestKeepUnchangedLocalFile_SkipsIncrementedAndMissingFiles(t *testing.T) {
+	digest := sha256.Sum256([]byte("data"))
+	checksum := &internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm, Value: hex.EncodeToString(digest[:])}
+	dataDirectory := t.TempDir()
+	tarInterpreter := NewFileTarInterpreter(dataDirectory, BackupSentinelDto{}, FilesMetadataDto{Files: internal.BackupFileList{
+		"incremented": {Checksum: checksum, IsIncremented: true},
+		"missing":     {Checksum: checksum},
+		"unknown":     {},
+	}}, nil, false)
+
+	for _, name := range []string{"incremented", "missing", "unknown"} {
+		targetPath := filepath.Join(dataDirectory, name)
+		if name != "missing" {
+			assert.NoError(t, os.WriteFile(targetPath, []byte("data"), 0600))
+		}
+		isUnchanged, err := tarInterpreter.keepUnchangedLocalFile(&tar.Header{Name: name, Mode: 0600, Size: 4}, targetPath,
+			false)
+		assert.NoError(t, err)
+		assert.False(t, isUnchanged, name)
+	}
+}
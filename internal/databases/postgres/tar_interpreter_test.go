@@ -3,11 +3,19 @@ package postgres_test
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
 	"os"
 	"path"
 	"testing"
 
+	"github.com/spf13/viper"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -128,34 +136,615 @@ func TestInterpretTypeDirNested(t *testing.T) {
 }
 
 func TestInterpretTypeLink(t *testing.T) {
-	srcFileInfo, dstFileInfo := testInterpret(t,
-		os.TempDir(),
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+	assert.NoError(t, createFile(path.Join(dbDataDirectory, "test_file")))
 
-		"test_file",
-		tar.TypeLink,
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "test_link",
+		Linkname: "test_file",
+		Typeflag: tar.TypeLink,
+	})
+	assert.NoError(t, err)
 
-		createFile,
-		os.Remove,
-	)
+	srcFileInfo, err := os.Lstat(path.Join(dbDataDirectory, "test_file"))
+	assert.NoError(t, err)
+	dstFileInfo, err := os.Lstat(path.Join(dbDataDirectory, "test_link"))
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(srcFileInfo, dstFileInfo))
+}
 
+func TestInterpretTypeLink_FromOtherWorkingDirectory(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer func() {
+		assert.NoError(t, os.Chdir(workingDirectory))
+	}()
+
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+	assert.NoError(t, createDir(path.Join(dbDataDirectory, "base/1")))
+	assert.NoError(t, createFile(path.Join(dbDataDirectory, "base/1/2")))
+	assert.NoError(t, createDir("base/1"))
+	assert.NoError(t, createFile("base/1/2"))
+
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "base/1/3",
+		Linkname: "base/1/2",
+		Typeflag: tar.TypeLink,
+	})
+	assert.NoError(t, err)
+
+	srcFileInfo, err := os.Lstat(path.Join(dbDataDirectory, "base/1/2"))
+	assert.NoError(t, err)
+	dstFileInfo, err := os.Lstat(path.Join(dbDataDirectory, "base/1/3"))
+	assert.NoError(t, err)
 	assert.True(t, os.SameFile(srcFileInfo, dstFileInfo))
+
+	workingDirectoryFileInfo, err := os.Lstat("base/1/2")
+	assert.NoError(t, err)
+	assert.False(t, os.SameFile(workingDirectoryFileInfo, dstFileInfo))
 }
 
 func TestInterpretTypeSymlink(t *testing.T) {
-	_, dstFileInfo := testInterpret(t,
-		os.TempDir(),
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+	assert.NoError(t, os.MkdirAll(path.Join(dbDataDirectory, "base/1"), 0700))
 
-		"test_file",
-		tar.TypeSymlink,
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "base/1/link",
+		Linkname: "../2/target",
+		Typeflag: tar.TypeSymlink,
+	})
+	assert.NoError(t, err)
 
-		createFile,
-		os.Remove,
-	)
+	linkTarget, err := os.Readlink(path.Join(dbDataDirectory, "base/1/link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "../2/target", linkTarget)
+}
+
+// The symlink points to its link target rather than to its own name,
+// and the target is validated rather than the name
+func TestInterpretTypeSymlink_UsesLinkname(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_wal_link",
+		Linkname: "base/1/16384",
+		Typeflag: tar.TypeSymlink,
+	})
+	assert.NoError(t, err)
+	linkTarget, err := os.Readlink(path.Join(dbDataDirectory, "pg_wal_link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "base/1/16384", linkTarget)
+
+	assert.NoError(t, os.MkdirAll(path.Join(dbDataDirectory, "base/1"), 0700))
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "base/1/link",
+		Linkname: "../../../escaped",
+		Typeflag: tar.TypeSymlink,
+	})
+	var traversalErr postgres.PathTraversalError
+	assert.True(t, errors.As(err, &traversalErr))
+}
+
+func TestInterpretTypeSymlink_RejectsEscapingRelativeTarget(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "base/link",
+		Linkname: "../../escaped",
+		Typeflag: tar.TypeSymlink,
+	})
+	var traversalErr postgres.PathTraversalError
+	assert.True(t, errors.As(err, &traversalErr))
+
+	_, err = os.Lstat(path.Join(dbDataDirectory, "base/link"))
+	assert.True(t, os.IsNotExist(err))
+}
 
-	assert.True(t, dstFileInfo.Mode()&os.ModeSymlink != 0)
+func TestInterpretTypeSymlink_RemapsRelativeTarget(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	volume := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+		PathRemapper:    postgres.PathRemapper{"pg_tblspc/16385": volume},
+	}
+	assert.NoError(t, os.MkdirAll(path.Join(volume, "PG_15"), 0700))
+	assert.NoError(t, os.MkdirAll(path.Join(dbDataDirectory, "pg_tblspc"), 0700))
+
+	for name, linkname := range map[string]string{
+		"pg_tblspc/16385/PG_15/link": "../PG_14",
+		"pg_tblspc/link":             "16385/PG_14",
+	} {
+		err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+			Name:     name,
+			Linkname: linkname,
+			Typeflag: tar.TypeSymlink,
+		})
+		assert.NoError(t, err)
+	}
+
+	linkTarget, err := os.Readlink(path.Join(volume, "PG_15/link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "../PG_14", linkTarget)
+	linkTarget, err = os.Readlink(path.Join(dbDataDirectory, "pg_tblspc/link"))
+	assert.NoError(t, err)
+	assert.Equal(t, path.Join(volume, "PG_14"), linkTarget)
+}
+
+func TestInterpretTypeSymlink_RejectsEscapingTargetOfRootedEntry(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+	assert.NoError(t, os.MkdirAll(path.Join(dbDataDirectory, "base"), 0700))
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "/base/link",
+		Linkname: "../../escaped",
+		Typeflag: tar.TypeSymlink,
+	})
+	var traversalErr postgres.PathTraversalError
+	assert.ErrorAs(t, err, &traversalErr)
+	_, err = os.Lstat(path.Join(dbDataDirectory, "base/link"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpretTypeSymlink_AbsoluteTablespaceTarget(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	volume := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+		PathRemapper:    postgres.PathRemapper{"/mnt/tablespaces": volume},
+	}
+	assert.NoError(t, os.MkdirAll(path.Join(dbDataDirectory, "pg_tblspc"), 0700))
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_tblspc/16385",
+		Linkname: "/mnt/tablespaces/ts1",
+		Typeflag: tar.TypeSymlink,
+	})
+	assert.NoError(t, err)
+	linkTarget, err := os.Readlink(path.Join(dbDataDirectory, "pg_tblspc/16385"))
+	assert.NoError(t, err)
+	assert.Equal(t, path.Join(volume, "ts1"), linkTarget)
+
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_tblspc/16386",
+		Linkname: "/mnt/other/ts2",
+		Typeflag: tar.TypeSymlink,
+	})
+	var pathTraversalError postgres.PathTraversalError
+	assert.ErrorAs(t, err, &pathTraversalError)
+	_, err = os.Lstat(path.Join(dbDataDirectory, "pg_tblspc/16386"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpretTypeSymlink_AbsoluteTargetWithinAllowedRoots(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+	assert.NoError(t, os.MkdirAll(path.Join(dbDataDirectory, "pg_wal"), 0700))
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_xlog",
+		Linkname: path.Join(dbDataDirectory, "pg_wal"),
+		Typeflag: tar.TypeSymlink,
+	})
+	assert.NoError(t, err)
+	linkTarget, err := os.Readlink(path.Join(dbDataDirectory, "pg_xlog"))
+	assert.NoError(t, err)
+	assert.Equal(t, path.Join(dbDataDirectory, "pg_wal"), linkTarget)
+}
+
+func TestInterpretRejectsEscapingEntries(t *testing.T) {
+	root := t.TempDir()
+	dbDataDirectory := path.Join(root, "data")
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+
+	for _, name := range []string{"../escaped", "base/../../escaped", "/../escaped", "/base/1/../../../escaped"} {
+		for _, typeflag := range []byte{tar.TypeReg, tar.TypeDir, tar.TypeSymlink} {
+			err := tarInterpreter.Interpret(bytes.NewBufferString("escaped"), &tar.Header{
+				Name:     name,
+				Typeflag: typeflag,
+				Mode:     0600,
+			})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), name)
+		}
+	}
+	_, err := os.Lstat(path.Join(root, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpretPlacesAbsoluteEntriesInDataDirectory(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+
+	err := tarInterpreter.Interpret(bytes.NewBufferString("content"), &tar.Header{
+		Name:     "/etc/passwd",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+	})
+	assert.NoError(t, err)
+
+	_, err = os.Stat(path.Join(dbDataDirectory, "etc/passwd"))
+	assert.NoError(t, err)
+}
+
+func TestInterpretTypeLink_RejectsEscapingSource(t *testing.T) {
+	root := t.TempDir()
+	dbDataDirectory := path.Join(root, "data")
+	assert.NoError(t, createDir(dbDataDirectory))
+	assert.NoError(t, createFile(path.Join(root, "outside")))
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+	}
+
+	for _, linkname := range []string{"../outside", "/../outside"} {
+		err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+			Name:     "test_link",
+			Linkname: linkname,
+			Typeflag: tar.TypeLink,
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test_link")
+		assert.Contains(t, err.Error(), linkname)
+	}
+	_, err := os.Lstat(path.Join(dbDataDirectory, "test_link"))
+	assert.True(t, os.IsNotExist(err))
 }
 
 func TestPrepareDirsForLocalDirectory(t *testing.T) {
 	err := postgres.PrepareDirs("filename", "filename")
 	assert.NoError(t, err)
 }
+
+func TestInterpretAppliesRecordedDirModes(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+		FilesMetadata: postgres.FilesMetadataDto{Files: internal.BackupFileList{
+			"/pg_tblspc/16385": {FileMode: 0700},
+		}},
+	}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "/pg_tblspc/16385/PG_14/1/2",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+	})
+	assert.NoError(t, err)
+
+	dirFileInfo, err := os.Stat(path.Join(dbDataDirectory, "pg_tblspc/16385"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), dirFileInfo.Mode().Perm())
+
+	dirFileInfo, err = os.Stat(path.Join(dbDataDirectory, "pg_tblspc/16385/PG_14"))
+	assert.NoError(t, err)
+	assert.True(t, dirFileInfo.IsDir())
+	_, err = os.Stat(path.Join(dbDataDirectory, "pg_tblspc/16385/PG_14/1/2"))
+	assert.NoError(t, err)
+}
+
+func TestInterpretWithPathRemapper(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	volume := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+		PathRemapper: postgres.PathRemapper{
+			"pg_tblspc":       "relocated",
+			"pg_tblspc/16385": volume,
+		},
+	}
+
+	for _, name := range []string{"pg_tblspc/16385/PG_14/1/2", "pg_tblspc/16386/PG_14/1/3"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(name), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		})
+		assert.NoError(t, err)
+	}
+
+	_, err := os.Stat(path.Join(volume, "PG_14/1/2"))
+	assert.NoError(t, err)
+	_, err = os.Stat(path.Join(dbDataDirectory, "relocated/16386/PG_14/1/3"))
+	assert.NoError(t, err)
+	_, err = os.Stat(path.Join(dbDataDirectory, "pg_tblspc"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpretWithPathRemapper_MatchesWholePathComponents(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+		PathRemapper:    postgres.PathRemapper{"pg_tblspc/1638": t.TempDir()},
+	}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_tblspc/16385",
+		Typeflag: tar.TypeDir,
+		Mode:     0700,
+	})
+	assert.NoError(t, err)
+
+	_, err = os.Stat(path.Join(dbDataDirectory, "pg_tblspc/16385"))
+	assert.NoError(t, err)
+}
+
+func TestInterpretWithPathRemapper_RejectsEscapingPaths(t *testing.T) {
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: t.TempDir(),
+		PathRemapper:    postgres.PathRemapper{"pg_tblspc/16385": "../escaped"},
+	}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_tblspc/16385/PG_14",
+		Typeflag: tar.TypeDir,
+		Mode:     0700,
+	})
+	assert.Error(t, err)
+}
+
+func TestInterpretWithPathRemapper_RejectsNotAllowedRoots(t *testing.T) {
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: t.TempDir(),
+		PathRemapper:    postgres.PathRemapper{"pg_tblspc/16385": t.TempDir()},
+		AllowedRoots:    []string{t.TempDir()},
+	}
+
+	err := tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "pg_tblspc/16385/PG_14",
+		Typeflag: tar.TypeDir,
+		Mode:     0700,
+	})
+	assert.Error(t, err)
+}
+
+func newChecksumVerifyingInterpreter(t *testing.T, files internal.BackupFileList) *postgres.FileTarInterpreter {
+	viper.Set(internal.VerifyFileChecksumsSetting, true)
+	defer viper.Set(internal.VerifyFileChecksumsSetting, false)
+	return postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{Files: files}, nil, false)
+}
+
+func sha256Checksum(content string) *internal.FileChecksum {
+	digest := sha256.Sum256([]byte(content))
+	return &internal.FileChecksum{Algorithm: internal.SHA256ChecksumAlgorithm, Value: hex.EncodeToString(digest[:])}
+}
+
+func TestInterpretVerifiesChecksum(t *testing.T) {
+	tarInterpreter := newChecksumVerifyingInterpreter(t, internal.BackupFileList{
+		"valid":   {Checksum: sha256Checksum("valid")},
+		"corrupt": {Checksum: sha256Checksum("expected")},
+		"crc":     {Checksum: &internal.FileChecksum{Algorithm: internal.CRC32CChecksumAlgorithm, Value: "00000000"}},
+	})
+
+	for name, content := range map[string]string{"valid": "valid", "unknown": "no checksum"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(content), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		})
+		assert.NoError(t, err)
+		_, err = os.Stat(path.Join(tarInterpreter.DBDataDirectory, name))
+		assert.NoError(t, err)
+	}
+
+	for name, content := range map[string]string{"corrupt": "actual", "crc": "actual"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(content), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), name)
+		_, err = os.Stat(path.Join(tarInterpreter.DBDataDirectory, name))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestInterpretIgnoresChecksumWhenVerificationDisabled(t *testing.T) {
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{Files: internal.BackupFileList{"corrupt": {Checksum: sha256Checksum("expected")}}},
+		nil, false)
+
+	err := tarInterpreter.Interpret(bytes.NewBufferString("actual"), &tar.Header{
+		Name:     "corrupt",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+	})
+	assert.NoError(t, err)
+}
+
+func TestInterpretAppliesContentFilter(t *testing.T) {
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	tarInterpreter.ContentFilter = postgres.NewNameMatchingContentFilter("/base/*/encrypted_*",
+		func(name string, reader io.Reader) (io.Reader, error) {
+			data, err := io.ReadAll(reader)
+			return bytes.NewReader(bytes.ToUpper(data)), err
+		})
+
+	for _, name := range []string{"/base/1/encrypted_1", "/base/1/plain_1"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString("content"), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		})
+		assert.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path.Join(tarInterpreter.DBDataDirectory, "base/1/encrypted_1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "CONTENT", string(data))
+	data, err = os.ReadFile(path.Join(tarInterpreter.DBDataDirectory, "base/1/plain_1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+// failingReader returns the error once the underlying reader is drained
+type failingReader struct {
+	reader io.Reader
+}
+
+func (reader *failingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("decryption failed")
+	}
+	return n, err
+}
+
+func TestInterpretRemovesFileOnContentFilterError(t *testing.T) {
+	filters := map[string]postgres.ContentFilter{
+		"open": func(name string, reader io.Reader) (io.Reader, error) {
+			return nil, errors.New("unknown key")
+		},
+		"read": func(name string, reader io.Reader) (io.Reader, error) {
+			return &failingReader{reader: reader}, nil
+		},
+	}
+	for name, filter := range filters {
+		tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+			postgres.FilesMetadataDto{}, nil, false)
+		tarInterpreter.ContentFilter = filter
+
+		err := tarInterpreter.Interpret(bytes.NewBufferString("content"), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		})
+		assert.Error(t, err)
+		_, err = os.Stat(path.Join(tarInterpreter.DBDataDirectory, name))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+type recordingProgressReporter struct {
+	started    []string
+	completed  []string
+	skipped    []string
+	totalBytes int64
+}
+
+func (reporter *recordingProgressReporter) OnFileStart(name string, size int64) {
+	reporter.started = append(reporter.started, name)
+}
+
+func (reporter *recordingProgressReporter) OnFileComplete(name string, bytes int64, totalBytes int64) {
+	reporter.completed = append(reporter.completed, name)
+	reporter.totalBytes = totalBytes
+}
+
+func (reporter *recordingProgressReporter) OnFileSkipped(name string) {
+	reporter.skipped = append(reporter.skipped, name)
+}
+
+func TestInterpretReportsProgress(t *testing.T) {
+	reporter := &recordingProgressReporter{}
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory:  t.TempDir(),
+		FilesToUnwrap:    map[string]bool{"first": true, "second": true},
+		ProgressReporter: reporter,
+	}
+
+	for _, name := range []string{"first", "skipped", "second"} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(name), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     int64(len(name)),
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"first", "second"}, reporter.started)
+	assert.Equal(t, []string{"first", "second"}, reporter.completed)
+	assert.Equal(t, []string{"skipped"}, reporter.skipped)
+	assert.Equal(t, int64(len("first")+len("second")), reporter.totalBytes)
+}
+
+func TestNewTarInterpreter_CreatesRegisteredFileTarInterpreter(t *testing.T) {
+	reporter := &recordingProgressReporter{}
+	dataDirectory := t.TempDir()
+	tarInterpreter, err := internal.NewTarInterpreter(postgres.TarInterpreterEngine, internal.TarInterpreterOptions{
+		DataDirectory:    dataDirectory,
+		Metadata:         postgres.FileTarInterpreterMetadata{Sentinel: postgres.BackupSentinelDto{}},
+		ProgressReporter: reporter,
+	})
+	assert.NoError(t, err)
+
+	err = tarInterpreter.Interpret(bytes.NewBufferString("data"), &tar.Header{
+		Name:     "file",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+		Size:     4,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+	assert.Equal(t, []string{"file"}, reporter.completed)
+	assert.FileExists(t, path.Join(dataDirectory, "file"))
+
+	_, err = internal.NewTarInterpreter(postgres.TarInterpreterEngine, internal.TarInterpreterOptions{Metadata: "unknown"})
+	assert.Error(t, err)
+}
+
+// cancellingReader cancels the context once the first chunk is read
+type cancellingReader struct {
+	reader io.Reader
+	cancel context.CancelFunc
+}
+
+func (reader *cancellingReader) Read(p []byte) (int, error) {
+	defer reader.cancel()
+	return reader.reader.Read(p[:utility.Min(len(p), 16)])
+}
+
+func TestInterpretAbortsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tarInterpreter := postgres.NewFileTarInterpreter(t.TempDir(), postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	tarInterpreter.Ctx = ctx
+
+	content := bytes.Repeat([]byte("partially written"), 1024)
+	err := tarInterpreter.Interpret(&cancellingReader{reader: bytes.NewReader(content), cancel: cancel}, &tar.Header{
+		Name:     "in_progress",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+		Size:     int64(len(content)),
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = os.Stat(path.Join(tarInterpreter.DBDataDirectory, "in_progress"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = tarInterpreter.Interpret(bytes.NewBufferString("next"), &tar.Header{
+		Name:     "next",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = os.Stat(path.Join(tarInterpreter.DBDataDirectory, "next"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/sha256"
+	"hash"
 	"io"
 	"regexp"
 	"strings"
@@ -76,6 +78,8 @@ type TarballStreamer struct {
 	Remaps TarballStreamerRemaps
 	// list of processed files
 	Files BundleFiles
+	// checksum of the current file data read so far
+	curChecksum hash.Hash
 }
 
 func NewTarballStreamer(input io.Reader, maxTarSize int64, bundleFiles BundleFiles) (streamer *TarballStreamer) {
@@ -152,10 +156,21 @@ func (streamer *TarballStreamer) addFile() (err error) {
 		filePath = strings.TrimPrefix(filePath, "./")
 		streamer.Files.AddFileDescription(filePath, internal.BackupFileDescription{MTime: streamer.curHeader.ModTime})
 		streamer.tarFileReadIndex += streamer.curHeader.Size
+		streamer.curChecksum = sha256.New()
+		if streamer.curHeader.Size == 0 {
+			streamer.addFileChecksum()
+		}
 	}
 	return nil
 }
 
+//addFileChecksum stores the checksum of the current file once all its data is read
+func (streamer *TarballStreamer) addFileChecksum() {
+	filePath := strings.TrimPrefix(streamer.curHeader.Name, "./")
+	streamer.Files.AddFileChecksum(filePath,
+		internal.NewFileChecksum(internal.SHA256ChecksumAlgorithm, streamer.curChecksum))
+}
+
 //remap rebuilds the name of the file according to remapping rules
 func (streamer *TarballStreamer) remap() {
 	for _, remap := range streamer.Remaps {
@@ -181,11 +196,13 @@ func (streamer *TarballStreamer) readFileData() (err error) {
 	streamer.bufReadIndex = 0
 	// Update index as read from file
 	streamer.fileReadIndex += int64(streamer.bufDataSize)
+	streamer.curChecksum.Write(streamer.inputBuf[:streamer.bufDataSize])
 	if streamer.fileReadIndex > streamer.curHeader.Size {
 		// Issue. We are reading more bytes than size in header.
 		return tar.ErrWriteTooLong
 	} else if streamer.fileReadIndex == streamer.curHeader.Size {
 		// Seems we have read all from this file. Next file.
+		streamer.addFileChecksum()
 		streamer.curHeader = nil
 		return nil
 	}
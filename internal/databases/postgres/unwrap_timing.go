@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"sort"
+	"time"
+
+	"github.com/wal-g/tracelog"
+)
+
+// slowestFilesToLog is the number of the slowest unwrapped files logged in the debug mode
+const slowestFilesToLog = 10
+
+// FileUnwrapTiming is the time spent on the single file during unwrap.
+// CopyDuration covers reading the file from the backup and writing it, FsyncDuration is the flush to disk.
+type FileUnwrapTiming struct {
+	Name          string
+	Bytes         int64
+	CopyDuration  time.Duration
+	FsyncDuration time.Duration
+}
+
+func (timing FileUnwrapTiming) Duration() time.Duration {
+	return timing.CopyDuration + timing.FsyncDuration
+}
+
+// FileTimings returns the timings of the unwrapped files in the order they were unwrapped
+func (result *UnwrapResult) FileTimings() []FileUnwrapTiming {
+	result.fileTimingsMutex.Lock()
+	defer result.fileTimingsMutex.Unlock()
+	return append([]FileUnwrapTiming{}, result.fileTimings...)
+}
+
+// SlowestFiles returns at most count files which took the longest time to unwrap, the slowest first
+func (result *UnwrapResult) SlowestFiles(count int) []FileUnwrapTiming {
+	timings := result.FileTimings()
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].Duration() > timings[j].Duration()
+	})
+	if count < len(timings) {
+		timings = timings[:count]
+	}
+	return timings
+}
+
+func (result *UnwrapResult) addFileTiming(timing FileUnwrapTiming) {
+	result.fileTimingsMutex.Lock()
+	result.fileTimings = append(result.fileTimings, timing)
+	result.fileTimingsMutex.Unlock()
+}
+
+func logSlowestFiles(result *UnwrapResult) {
+	for _, timing := range result.SlowestFiles(slowestFilesToLog) {
+		tracelog.DebugLogger.Printf("Unwrapped '%s' (%d bytes) in %v: copy %v, fsync %v",
+			timing.Name, timing.Bytes, timing.Duration(), timing.CopyDuration, timing.FsyncDuration)
+	}
+}
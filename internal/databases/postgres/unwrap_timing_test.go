@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrapRegularFileNew_RecordsTimings(t *testing.T) {
+	useNewUnwrapImplementation = true
+	defer func() { useNewUnwrapImplementation = false }()
+	tarInterpreter := NewFileTarInterpreter(t.TempDir(), BackupSentinelDto{}, FilesMetadataDto{}, nil, false)
+
+	for name, content := range map[string]string{"small": "data", "large": string(make([]byte, 1<<16))} {
+		err := tarInterpreter.Interpret(bytes.NewBufferString(content), &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     int64(len(content)),
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+
+	timings := tarInterpreter.UnwrapResult.FileTimings()
+	assert.Len(t, timings, 2)
+	for _, timing := range timings {
+		assert.Contains(t, []string{"small", "large"}, timing.Name)
+		assert.Equal(t, map[string]int64{"small": 4, "large": 1 << 16}[timing.Name], timing.Bytes)
+		assert.Positive(t, timing.CopyDuration)
+		// the default fsync mode flushes each file
+		assert.Positive(t, timing.FsyncDuration)
+	}
+}
+
+func TestUnwrapResult_SlowestFiles(t *testing.T) {
+	result := newUnwrapResult()
+	result.addFileTiming(FileUnwrapTiming{Name: "fast", CopyDuration: time.Millisecond})
+	result.addFileTiming(FileUnwrapTiming{Name: "slow_fsync", CopyDuration: time.Millisecond, FsyncDuration: time.Second})
+	result.addFileTiming(FileUnwrapTiming{Name: "slow_copy", CopyDuration: 2 * time.Second})
+
+	slowest := result.SlowestFiles(2)
+	assert.Len(t, slowest, 2)
+	assert.Equal(t, "slow_copy", slowest[0].Name)
+	assert.Equal(t, "slow_fsync", slowest[1].Name)
+	assert.Len(t, result.SlowestFiles(10), 3)
+}
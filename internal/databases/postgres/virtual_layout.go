@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"archive/tar"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// VirtualLayout is the index of the extracted tree kept instead of the files on disk, so the tools needing
+// a few files of the huge backup read them on demand. The regular files are recorded by the offsets and the sizes
+// of their contents in the backing tar archives rather than by the contents, so the memory used depends only
+// on the number of the entries. The backing archives must be seekable, i.e. the uncompressed tar files
+// rather than the storage streams: the offsets are meaningless in the compressed or the encrypted streams.
+type VirtualLayout struct {
+	mutex sync.RWMutex
+	files map[string]VirtualFile
+}
+
+// VirtualFile is the entry of the VirtualLayout
+type VirtualFile struct {
+	Name     string
+	Typeflag byte
+	Mode     int64
+	Size     int64
+	// Linkname is the target of the symlink or the source of the hardlink
+	Linkname string
+
+	archive io.ReaderAt
+	offset  int64
+}
+
+// IsRegular checks if the file has the contents to be read by the VirtualLayout.Open
+func (file VirtualFile) IsRegular() bool {
+	return file.Typeflag == tar.TypeReg || file.Typeflag == tar.TypeRegA
+}
+
+func NewVirtualLayout() *VirtualLayout {
+	return &VirtualLayout{files: make(map[string]VirtualFile)}
+}
+
+// IndexArchive reads the headers of the tar archive of the size and passes its entries to the tarInterpreter
+// with the VirtualLayout set, the contents are skipped without being read. The archives of the backup
+// may be indexed concurrently into the same layout. The caller should call the OnInterpretFinish of the tarInterpreter
+// once all the archives are indexed.
+func IndexArchive(tarInterpreter *FileTarInterpreter, archive io.ReaderAt, size int64) error {
+	if tarInterpreter.VirtualLayout == nil {
+		return errors.New("IndexArchive: the tar interpreter has no virtual layout to index the archive into")
+	}
+	reader := &offsetReader{reader: io.NewSectionReader(archive, 0, size)}
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "IndexArchive: failed to read the tar header")
+		}
+		// the contents of the entry start right after its headers
+		entry := &virtualEntryReader{Reader: tarReader, archive: archive, offset: reader.offset}
+		if err = tarInterpreter.Interpret(entry, header); err != nil {
+			return err
+		}
+	}
+}
+
+// Lookup returns the entry by its name in the tar archive
+func (layout *VirtualLayout) Lookup(name string) (VirtualFile, bool) {
+	layout.mutex.RLock()
+	defer layout.mutex.RUnlock()
+	file, ok := layout.files[path.Clean(name)]
+	return file, ok
+}
+
+// Files returns the entries sorted by name
+func (layout *VirtualLayout) Files() []VirtualFile {
+	layout.mutex.RLock()
+	defer layout.mutex.RUnlock()
+	files := make([]VirtualFile, 0, len(layout.files))
+	for _, file := range layout.files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// Open returns the reader of the regular file contents read from the backing archive on demand,
+// the hardlinks are resolved to their sources. The readers of the different files may be used concurrently.
+func (layout *VirtualLayout) Open(name string) (io.ReadCloser, error) {
+	file, ok := layout.Lookup(name)
+	if ok && file.Typeflag == tar.TypeLink {
+		file, ok = layout.Lookup(file.Linkname)
+	}
+	if !ok {
+		return nil, errors.Errorf("VirtualLayout: file '%s' is not found", name)
+	}
+	if !file.IsRegular() {
+		return nil, errors.Errorf("VirtualLayout: '%s' is not a regular file", name)
+	}
+	return io.NopCloser(io.NewSectionReader(file.archive, file.offset, file.Size)), nil
+}
+
+// add records the entry located by the reader passed by IndexArchive
+func (layout *VirtualLayout) add(fileReader io.Reader, fileInfo *tar.Header) error {
+	file := VirtualFile{
+		Name:     path.Clean(fileInfo.Name),
+		Typeflag: fileInfo.Typeflag,
+		Mode:     fileInfo.Mode,
+		Linkname: fileInfo.Linkname,
+	}
+	if file.IsRegular() {
+		if isSparseEntry(fileInfo) {
+			return errors.Errorf("VirtualLayout: sparse file '%s' can not be read from the archive by offset", fileInfo.Name)
+		}
+		entry, ok := fileReader.(*virtualEntryReader)
+		if !ok {
+			return errors.Errorf("VirtualLayout: the backing stream of '%s' is not seekable, "+
+				"the archive must be indexed by IndexArchive", fileInfo.Name)
+		}
+		file.Size, file.archive, file.offset = fileInfo.Size, entry.archive, entry.offset
+	}
+
+	layout.mutex.Lock()
+	defer layout.mutex.Unlock()
+	layout.files[file.Name] = file
+	return nil
+}
+
+// isSparseEntry checks if the contents of the entry are stored without the holes, so they are not contiguous
+func isSparseEntry(fileInfo *tar.Header) bool {
+	if fileInfo.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range fileInfo.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// virtualEntryReader is the contents of the tar entry along with its location in the backing archive
+type virtualEntryReader struct {
+	io.Reader
+	archive io.ReaderAt
+	offset  int64
+}
+
+// offsetReader tracks the offset of the tar reader in the archive,
+// the contents not read by the interpreter are skipped by Seek
+type offsetReader struct {
+	reader *io.SectionReader
+	offset int64
+}
+
+func (reader *offsetReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.offset += int64(n)
+	return n, err
+}
+
+func (reader *offsetReader) Seek(offset int64, whence int) (int64, error) {
+	position, err := reader.reader.Seek(offset, whence)
+	if err == nil {
+		reader.offset = position
+	}
+	return position, err
+}
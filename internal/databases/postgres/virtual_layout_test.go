@@ -0,0 +1,94 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func buildVirtualLayoutArchive(t *testing.T) []byte {
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	files := map[string]string{
+		"base/1/1259":       strings.Repeat("relation", 1000),
+		"global/pg_control": "control",
+	}
+	assert.NoError(t, writer.WriteHeader(&tar.Header{Name: "base/1", Typeflag: tar.TypeDir, Mode: 0700}))
+	for _, name := range []string{"base/1/1259", "global/pg_control"} {
+		assert.NoError(t, writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0600,
+			Size: int64(len(files[name]))}))
+		_, err := writer.Write([]byte(files[name]))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.WriteHeader(&tar.Header{Name: "base/1/1259_copy", Linkname: "base/1/1259",
+		Typeflag: tar.TypeLink}))
+	assert.NoError(t, writer.WriteHeader(&tar.Header{Name: "base/1/1259_link", Linkname: "1259",
+		Typeflag: tar.TypeSymlink}))
+	assert.NoError(t, writer.Close())
+	return archive.Bytes()
+}
+
+func TestIndexArchive(t *testing.T) {
+	dbDataDirectory := t.TempDir()
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: dbDataDirectory,
+		VirtualLayout:   postgres.NewVirtualLayout(),
+	}
+	archive := buildVirtualLayoutArchive(t)
+
+	assert.NoError(t, postgres.IndexArchive(tarInterpreter, bytes.NewReader(archive), int64(len(archive))))
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+
+	entries, err := os.ReadDir(dbDataDirectory)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	names := make([]string, 0)
+	for _, file := range tarInterpreter.VirtualLayout.Files() {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"base/1", "base/1/1259", "base/1/1259_copy", "base/1/1259_link", "global/pg_control"}, names)
+
+	for name, expected := range map[string]string{
+		"base/1/1259":       strings.Repeat("relation", 1000),
+		"base/1/1259_copy":  strings.Repeat("relation", 1000),
+		"global/pg_control": "control",
+	} {
+		reader, err := tarInterpreter.VirtualLayout.Open(name)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+		assert.NoError(t, reader.Close())
+	}
+
+	link, ok := tarInterpreter.VirtualLayout.Lookup("base/1/1259_link")
+	assert.True(t, ok)
+	assert.Equal(t, "1259", link.Linkname)
+	_, err = tarInterpreter.VirtualLayout.Open("base/1/1259_link")
+	assert.Error(t, err)
+	_, err = tarInterpreter.VirtualLayout.Open("base/1/missing")
+	assert.Error(t, err)
+}
+
+func TestVirtualLayout_RejectsNotSeekableStream(t *testing.T) {
+	tarInterpreter := &postgres.FileTarInterpreter{
+		DBDataDirectory: t.TempDir(),
+		VirtualLayout:   postgres.NewVirtualLayout(),
+	}
+
+	err := tarInterpreter.Interpret(bytes.NewBufferString("content"), &tar.Header{
+		Name:     "base/1/1259",
+		Typeflag: tar.TypeReg,
+		Size:     int64(len("content")),
+	})
+	assert.Error(t, err)
+	_, ok := tarInterpreter.VirtualLayout.Lookup("base/1/1259")
+	assert.False(t, ok)
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"archive/tar"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// ImageExtent is the region of the preallocated image the file is written to
+type ImageExtent struct {
+	Offset int64 `json:"Offset"`
+	Length int64 `json:"Length"`
+}
+
+// WriterAtTarInterpreter writes the regular files at the offsets designated by the image layout
+// instead of creating them on disk, e.g. to restore into a block device or a memory-mapped region.
+// The directories and the links have no place in the image and are skipped.
+// The increments of the delta backups are not applied, the incremented files are rejected.
+type WriterAtTarInterpreter struct {
+	Target io.WriterAt
+	Layout map[string]ImageExtent
+	// FilesToUnwrap restricts the written files, all the files are written if it is nil
+	FilesToUnwrap map[string]bool
+	// IncrementedFiles are the files stored as the increments to the previous backup
+	IncrementedFiles map[string]bool
+}
+
+var _ internal.RestoreTarInterpreter = &WriterAtTarInterpreter{}
+
+// NewWriterAtTarInterpreter creates the WriterAtTarInterpreter using the image layout stored in the files metadata,
+// fails if the backup has no layout or the layout extents overlap
+func NewWriterAtTarInterpreter(target io.WriterAt, filesMetadata FilesMetadataDto,
+	filesToUnwrap map[string]bool) (*WriterAtTarInterpreter, error) {
+	if len(filesMetadata.ImageLayout) == 0 {
+		return nil, errors.New("backup files metadata has no image layout")
+	}
+	if err := validateImageLayout(filesMetadata.ImageLayout); err != nil {
+		return nil, err
+	}
+	incrementedFiles := make(map[string]bool)
+	for name, description := range filesMetadata.Files {
+		if description.IsIncremented {
+			incrementedFiles[name] = true
+		}
+	}
+	return &WriterAtTarInterpreter{Target: target, Layout: filesMetadata.ImageLayout, FilesToUnwrap: filesToUnwrap,
+		IncrementedFiles: incrementedFiles}, nil
+}
+
+// validateImageLayout checks the extents are not negative and do not overlap,
+// so the files written concurrently never clobber each other
+func validateImageLayout(layout map[string]ImageExtent) error {
+	names := make([]string, 0, len(layout))
+	for name, extent := range layout {
+		if extent.Offset < 0 || extent.Length < 0 {
+			return errors.Errorf("invalid image extent of '%s': offset %d, length %d", name, extent.Offset, extent.Length)
+		}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if layout[names[i]].Offset != layout[names[j]].Offset {
+			return layout[names[i]].Offset < layout[names[j]].Offset
+		}
+		return names[i] < names[j]
+	})
+	for i := 1; i < len(names); i++ {
+		previous := layout[names[i-1]]
+		if previous.Offset+previous.Length > layout[names[i]].Offset {
+			return errors.Errorf("image extents of '%s' and '%s' overlap", names[i-1], names[i])
+		}
+	}
+	return nil
+}
+
+// Interpret writes the regular file at its extent of the image,
+// fails if the file has no extent, does not fit into it or is incremented
+func (tarInterpreter *WriterAtTarInterpreter) Interpret(fileReader io.Reader, fileInfo *tar.Header) error {
+	fileInfo, err := resolvePAXHeader(fileInfo)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Typeflag != tar.TypeReg && fileInfo.Typeflag != tar.TypeRegA {
+		tracelog.DebugLogger.Printf("Skipping '%s': only the regular files are written to the image\n", fileInfo.Name)
+		return nil
+	}
+	if tarInterpreter.FilesToUnwrap != nil {
+		if _, ok := tarInterpreter.FilesToUnwrap[fileInfo.Name]; !ok {
+			return nil
+		}
+	}
+	if tarInterpreter.IncrementedFiles[fileInfo.Name] {
+		return errors.Errorf("Interpret: '%s' is incremented, the increments are not applied to the image", fileInfo.Name)
+	}
+	extent, ok := tarInterpreter.Layout[fileInfo.Name]
+	if !ok {
+		return errors.Errorf("Interpret: no image extent for '%s'", fileInfo.Name)
+	}
+	if fileInfo.Size > extent.Length {
+		return errors.Errorf("Interpret: '%s' of size %d does not fit into the image extent of length %d",
+			fileInfo.Name, fileInfo.Size, extent.Length)
+	}
+	writer := &extentWriter{target: tarInterpreter.Target, offset: extent.Offset, limit: extent.Offset + extent.Length}
+	if _, err = io.Copy(writer, fileReader); err != nil {
+		return errors.Wrapf(err, "Interpret: failed to write '%s' to the image", fileInfo.Name)
+	}
+	return nil
+}
+
+// OnInterpretFinish does nothing, the target is flushed by its owner
+func (tarInterpreter *WriterAtTarInterpreter) OnInterpretFinish() error {
+	return nil
+}
+
+// extentWriter writes sequentially to the io.WriterAt starting at the offset, the writes beyond the limit fail
+type extentWriter struct {
+	target io.WriterAt
+	offset int64
+	limit  int64
+}
+
+func (writer *extentWriter) Write(p []byte) (int, error) {
+	if writer.offset+int64(len(p)) > writer.limit {
+		return 0, errors.Errorf("write of %d bytes at offset %d exceeds the image extent end %d",
+			len(p), writer.offset, writer.limit)
+	}
+	n, err := writer.target.WriteAt(p, writer.offset)
+	writer.offset += int64(n)
+	return n, err
+}
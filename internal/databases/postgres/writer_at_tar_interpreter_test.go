@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// bufferWriterAt is the fixed size io.WriterAt over the byte slice
+type bufferWriterAt []byte
+
+func (buffer bufferWriterAt) WriteAt(p []byte, offset int64) (int, error) {
+	return copy(buffer[offset:], p), nil
+}
+
+func TestWriterAtTarInterpreter_WritesFilesAtOffsets(t *testing.T) {
+	image := make(bufferWriterAt, 12)
+	tarInterpreter, err := NewWriterAtTarInterpreter(image, FilesMetadataDto{ImageLayout: map[string]ImageExtent{
+		"first":  {Offset: 0, Length: 4},
+		"second": {Offset: 6, Length: 6},
+	}}, nil)
+	assert.NoError(t, err)
+
+	// the files come in the reverse order to check the offsets do not depend on it
+	assert.NoError(t, tarInterpreter.Interpret(bytes.NewBufferString("second"),
+		&tar.Header{Name: "second", Typeflag: tar.TypeReg, Size: 6}))
+	assert.NoError(t, tarInterpreter.Interpret(bytes.NewBufferString("1st"),
+		&tar.Header{Name: "first", Typeflag: tar.TypeReg, Size: 3}))
+	assert.NoError(t, tarInterpreter.Interpret(nil, &tar.Header{Name: "dir", Typeflag: tar.TypeDir}))
+	assert.NoError(t, tarInterpreter.OnInterpretFinish())
+
+	assert.Equal(t, []byte("1st\x00\x00\x00second"), []byte(image))
+}
+
+func TestWriterAtTarInterpreter_RejectsFilesOutsideLayout(t *testing.T) {
+	tarInterpreter, err := NewWriterAtTarInterpreter(make(bufferWriterAt, 4), FilesMetadataDto{
+		ImageLayout: map[string]ImageExtent{"file": {Offset: 0, Length: 4}}}, nil)
+	assert.NoError(t, err)
+
+	assert.Error(t, tarInterpreter.Interpret(bytes.NewBufferString("data"),
+		&tar.Header{Name: "unknown", Typeflag: tar.TypeReg, Size: 4}))
+	assert.Error(t, tarInterpreter.Interpret(bytes.NewBufferString("too long"),
+		&tar.Header{Name: "file", Typeflag: tar.TypeReg, Size: 8}))
+}
+
+func TestWriterAtTarInterpreter_RejectsIncrementedFiles(t *testing.T) {
+	image := make(bufferWriterAt, 8)
+	tarInterpreter, err := NewWriterAtTarInterpreter(image, FilesMetadataDto{
+		Files: internal.BackupFileList{
+			"full":        {IsIncremented: false},
+			"incremented": {IsIncremented: true},
+		},
+		ImageLayout: map[string]ImageExtent{"full": {Offset: 0, Length: 4}, "incremented": {Offset: 4, Length: 4}},
+	}, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tarInterpreter.Interpret(bytes.NewBufferString("full"),
+		&tar.Header{Name: "full", Typeflag: tar.TypeReg, Size: 4}))
+	err = tarInterpreter.Interpret(bytes.NewBufferString("incr"),
+		&tar.Header{Name: "incremented", Typeflag: tar.TypeReg, Size: 4})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "incremented")
+	assert.Equal(t, []byte("full\x00\x00\x00\x00"), []byte(image))
+}
+
+func TestNewWriterAtTarInterpreter_RequiresValidLayout(t *testing.T) {
+	_, err := NewWriterAtTarInterpreter(make(bufferWriterAt, 4), FilesMetadataDto{}, nil)
+	assert.Error(t, err)
+
+	_, err = NewWriterAtTarInterpreter(make(bufferWriterAt, 8), FilesMetadataDto{ImageLayout: map[string]ImageExtent{
+		"first":  {Offset: 0, Length: 4},
+		"second": {Offset: 3, Length: 4},
+	}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"archive/tar"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// paxXattrPrefix marks the extended attributes in the PAX records, as written by GNU tar and star.
+// POSIX ACLs and SELinux contexts are stored as the system.posix_acl_* and security.selinux attributes.
+const paxXattrPrefix = "SCHILY.xattr."
+
+// restoreXattrs applies the extended attributes recorded in the header to the extracted file.
+// The attributes rejected by the file system are skipped with a warning unless the strict mode is on.
+func (tarInterpreter *FileTarInterpreter) restoreXattrs(targetPath string, header *tar.Header) error {
+	if !tarInterpreter.restoreXattrsEnabled {
+		return nil
+	}
+	for _, name := range getHeaderXattrNames(header) {
+		err := setXattr(targetPath, name, []byte(header.PAXRecords[paxXattrPrefix+name]))
+		if err == nil {
+			continue
+		}
+		if tarInterpreter.strictXattrs {
+			return errors.Wrapf(err, "Interpret: failed to set extended attribute '%s' of '%s'", name, targetPath)
+		}
+		tracelog.WarningLogger.Printf("Failed to set extended attribute '%s' of '%s': %v", name, targetPath, err)
+	}
+	return nil
+}
+
+func getHeaderXattrNames(header *tar.Header) []string {
+	names := make([]string, 0)
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, paxXattrPrefix) && len(key) > len(paxXattrPrefix) {
+			names = append(names, strings.TrimPrefix(key, paxXattrPrefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package postgres
+
+import "github.com/pkg/errors"
+
+func setXattr(path, name string, value []byte) error {
+	return errors.New("extended attributes are restored only on linux")
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func newXattrTestFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(path, []byte("data"), 0600))
+	return path
+}
+
+func TestRestoreXattrs(t *testing.T) {
+	path := newXattrTestFile(t)
+	if err := unix.Setxattr(path, "user.probe", []byte("1"), 0); err != nil {
+		t.Skipf("user extended attributes are not supported here: %v", err)
+	}
+
+	header := &tar.Header{PAXRecords: map[string]string{paxXattrPrefix + "user.restored": "value", "path": "file"}}
+	interpreter := &FileTarInterpreter{restoreXattrsEnabled: true, strictXattrs: true}
+	assert.NoError(t, interpreter.restoreXattrs(path, header))
+
+	value := make([]byte, 16)
+	size, err := unix.Getxattr(path, "user.restored", value)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(value[:size]))
+}
+
+func TestRestoreXattrs_RejectedAttribute(t *testing.T) {
+	path := newXattrTestFile(t)
+	header := &tar.Header{PAXRecords: map[string]string{paxXattrPrefix + "unknown.namespace": "value"}}
+
+	assert.NoError(t, (&FileTarInterpreter{restoreXattrsEnabled: true}).restoreXattrs(path, header))
+	assert.Error(t, (&FileTarInterpreter{restoreXattrsEnabled: true, strictXattrs: true}).restoreXattrs(path, header))
+	assert.NoError(t, (&FileTarInterpreter{strictXattrs: true}).restoreXattrs(path, header))
+}
+
+func TestGetHeaderXattrNames(t *testing.T) {
+	header := &tar.Header{PAXRecords: map[string]string{
+		paxXattrPrefix + "security.selinux":        "system_u:object_r:postgresql_db_t:s0",
+		paxXattrPrefix + "system.posix_acl_access": "acl",
+		paxXattrPrefix:      "",
+		"SCHILY.acl.access": "user::rw-",
+	}}
+	assert.Equal(t, []string{"security.selinux", "system.posix_acl_access"}, getHeaderXattrNames(header))
+}
@@ -13,13 +13,18 @@ import (
 
 // Backup represents backup sentinel data
 type Backup struct {
-	BackupName      string      `json:"BackupName,omitempty"`
-	StartLocalTime  time.Time   `json:"StartLocalTime,omitempty"`
-	FinishLocalTime time.Time   `json:"FinishLocalTime,omitempty"`
-	UserData        interface{} `json:"UserData,omitempty"`
-	Permanent       bool        `json:"Permanent"`
-	DataSize        int64       `json:"DataSize,omitempty"`
-	BackupSize      int64       `json:"BackupSize,omitempty"`
+	BackupName      string            `json:"BackupName,omitempty"`
+	StartLocalTime  time.Time         `json:"StartLocalTime,omitempty"`
+	FinishLocalTime time.Time         `json:"FinishLocalTime,omitempty"`
+	UserData        interface{}       `json:"UserData,omitempty"`
+	Labels          map[string]string `json:"Labels,omitempty"`
+	Permanent       bool              `json:"Permanent"`
+	DataSize        int64             `json:"DataSize,omitempty"`
+	BackupSize      int64             `json:"BackupSize,omitempty"`
+	// SHA256 is the hex-encoded digest of the uncompressed backup stream
+	SHA256 string `json:"SHA256,omitempty"`
+	// CompressedSHA256 are the hex-encoded digests of the stored (compressed) stream objects by their paths
+	CompressedSHA256 map[string]string `json:"CompressedSHA256,omitempty"`
 }
 
 func (b Backup) Name() string {
@@ -64,6 +69,7 @@ type BackupMeta struct {
 	CompressedSize int64
 	Permanent      bool
 	User           interface{}
+	Labels         map[string]string
 	StartTime      time.Time
 	FinishTime     time.Time
 }
@@ -73,6 +79,7 @@ type RedisMetaConstructor struct {
 	folder    storage.Folder
 	meta      BackupMeta
 	permanent bool
+	labels    map[string]string
 }
 
 // Init - required for internal.MetaConstructor
@@ -84,6 +91,7 @@ func (m *RedisMetaConstructor) Init() error {
 	m.meta = BackupMeta{
 		Permanent: m.permanent,
 		User:      userData,
+		Labels:    m.labels,
 		StartTime: utility.TimeNowCrossPlatformLocal(),
 	}
 	return nil
@@ -94,6 +102,7 @@ func (m *RedisMetaConstructor) MetaInfo() interface{} {
 	return &Backup{
 		Permanent:       meta.Permanent,
 		UserData:        meta.User,
+		Labels:          meta.Labels,
 		StartLocalTime:  meta.StartTime,
 		FinishLocalTime: meta.FinishTime,
 	}
@@ -104,8 +113,9 @@ func (m *RedisMetaConstructor) Finalize(backupName string) error {
 	return nil
 }
 
-func NewBackupRedisMetaConstructor(ctx context.Context, folder storage.Folder, permanent bool) internal.MetaConstructor {
-	return &RedisMetaConstructor{ctx: ctx, folder: folder, permanent: permanent}
+func NewBackupRedisMetaConstructor(ctx context.Context, folder storage.Folder, permanent bool,
+	labels map[string]string) internal.MetaConstructor {
+	return &RedisMetaConstructor{ctx: ctx, folder: folder, permanent: permanent, labels: labels}
 }
 
 type StorageUploader struct {
@@ -124,7 +134,8 @@ func (su *StorageUploader) UploadBackup(stream io.Reader, cmd internal.ErrWaiter
 		return fmt.Errorf("can not init meta provider: %+v", err)
 	}
 
-	dstPath, err := su.PushStream(stream)
+	digestReader := internal.NewStreamDigestReader(stream)
+	dstPath, err := su.PushStream(digestReader)
 	if err != nil {
 		return fmt.Errorf("can not upload backup: %+v", err)
 	}
@@ -149,6 +160,8 @@ func (su *StorageUploader) UploadBackup(stream io.Reader, cmd internal.ErrWaiter
 	backup.BackupSize = uploadedSize
 	backup.BackupName = dstPath
 	backup.DataSize = rawSize
+	backup.SHA256 = digestReader.SHA256()
+	backup.CompressedSHA256 = su.CompressedStreamDigests()
 	if err := internal.UploadSentinel(su, backupSentinelInfo, dstPath); err != nil {
 		return fmt.Errorf("can not upload sentinel: %+v", err)
 	}
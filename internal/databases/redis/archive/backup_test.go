@@ -0,0 +1,54 @@
+package archive_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/redis/archive"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type doneWaiter struct{}
+
+func (doneWaiter) Wait() error {
+	return nil
+}
+
+func TestStorageUploader_UploadBackup_RecordsSentinel(t *testing.T) {
+	viper.Set(internal.SerializerTypeSetting, string(internal.RegularJSONSerializer))
+	defer viper.Set(internal.SerializerTypeSetting, nil)
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	uploader := archive.NewRedisStorageUploader(internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder))
+	content := strings.Repeat("redis rdb ", 1000)
+	assert.NoError(t, uploader.UploadBackup(strings.NewReader(content), doneWaiter{},
+		archive.NewBackupRedisMetaConstructor(context.Background(), folder, false, map[string]string{"region": "eu-west"})))
+
+	objects, _, err := folder.ListFolder()
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	var sentinel archive.Backup
+	assert.NoError(t, internal.FetchDto(folder, &sentinel, objects[0].GetName()))
+	assert.Equal(t, strings.TrimSuffix(objects[0].GetName(), utility.SentinelSuffix), sentinel.BackupName)
+
+	streamPath := internal.GetStreamName(sentinel.BackupName, lz4.FileExtension)
+	reader, err := folder.ReadObject(streamPath)
+	assert.NoError(t, err)
+	stored, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	storedDigest := sha256.Sum256(stored)
+	assert.Equal(t, map[string]string{streamPath: hex.EncodeToString(storedDigest[:])}, sentinel.CompressedSHA256)
+	contentDigest := sha256.Sum256([]byte(content))
+	assert.Equal(t, hex.EncodeToString(contentDigest[:]), sentinel.SHA256)
+	assert.Equal(t, map[string]string{"region": "eu-west"}, sentinel.Labels)
+}
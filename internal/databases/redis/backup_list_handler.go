@@ -65,14 +65,14 @@ func GetBackupDetails(folder storage.Folder, backupTime internal.BackupTime) (ar
 func writeBackupListDetails(backupDetails []archive.Backup, output io.Writer) error {
 	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
 	defer func() { _ = writer.Flush() }()
-	_, err := fmt.Fprintln(writer, "name\tstart_time\tfinish_time\tuser_data\tdata_size\tbackup_size\tpermanent") //nolint:lll
+	_, err := fmt.Fprintln(writer, "name\tstart_time\tfinish_time\tuser_data\tdata_size\tbackup_size\tpermanent\tlabels") //nolint:lll
 	if err != nil {
 		return err
 	}
 	for i, count := 0, len(backupDetails); i < count; i++ {
 		b := backupDetails[i]
-		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
-			b.BackupName, b.StartLocalTime.Format(time.RFC3339), b.FinishLocalTime.Format(time.RFC3339), b.UserData, b.DataSize, b.BackupSize, b.Permanent) //nolint:lll
+		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			b.BackupName, b.StartLocalTime.Format(time.RFC3339), b.FinishLocalTime.Format(time.RFC3339), b.UserData, b.DataSize, b.BackupSize, b.Permanent, internal.FormatBackupLabels(b.Labels)) //nolint:lll
 		if err != nil {
 			return err
 		}
@@ -85,9 +85,9 @@ func writePrettyBackupListDetails(backupDetails []archive.Backup, output io.Writ
 	writer := table.NewWriter()
 	writer.SetOutputMirror(output)
 	defer writer.Render()
-	writer.AppendHeader(table.Row{"#", "Name", "Start time", "Finish time", "UserData", "Data size", "Backup size", "Permanent"}) //nolint:lll
+	writer.AppendHeader(table.Row{"#", "Name", "Start time", "Finish time", "UserData", "Data size", "Backup size", "Permanent", "Labels"}) //nolint:lll
 	for idx := range backupDetails {
 		b := &backupDetails[idx]
-		writer.AppendRow(table.Row{idx + 1, b.BackupName, b.StartLocalTime.Format(time.RFC850), b.FinishLocalTime.Format(time.RFC850), b.UserData, b.DataSize, b.BackupSize, b.Permanent}) //nolint:lll
+		writer.AppendRow(table.Row{idx + 1, b.BackupName, b.StartLocalTime.Format(time.RFC850), b.FinishLocalTime.Format(time.RFC850), b.UserData, b.DataSize, b.BackupSize, b.Permanent, internal.FormatBackupLabels(b.Labels)}) //nolint:lll
 	}
 }
@@ -81,6 +81,14 @@ func IsIgnoredFunc(isIgnored func(storage.Object) bool) DeleteHandlerOption {
 	}
 }
 
+// AllowOrphanedIncrements makes the deletion to proceed with a warning
+// if it leaves some increments without the backups they are based on
+func AllowOrphanedIncrements(allow bool) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.allowOrphans = allow
+	}
+}
+
 func NewDeleteHandler(
 	folder storage.Folder,
 	backups []BackupObject,
@@ -116,6 +124,8 @@ type DeleteHandler struct {
 
 	isPermanent func(object storage.Object) bool
 	isIgnored   func(object storage.Object) bool
+
+	allowOrphans bool
 }
 
 func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
@@ -356,11 +366,22 @@ func (h *DeleteHandler) DeleteBeforeTargetWhere(target BackupObject, confirmed b
 		errorMessage := "%v is incremental and it's predecessors cannot be deleted. Consider FIND_FULL option."
 		return utility.NewForbiddenActionError(fmt.Sprintf(errorMessage, target.GetName()))
 	}
+	isDeleted := func(object storage.Object) bool {
+		return selector(object) && h.less(object, target) && !h.isPermanent(object) && !h.isIgnored(object)
+	}
+
+	var backupsToDelete []BackupObject
+	for _, backup := range h.backups {
+		if isDeleted(backup) {
+			backupsToDelete = append(backupsToDelete, backup)
+		}
+	}
+	if err := h.checkOrphanedBackups(backupsToDelete); err != nil {
+		return err
+	}
 	tracelog.InfoLogger.Println("Start delete")
 
-	return storage.DeleteObjectsWhere(h.Folder, confirmed, func(object storage.Object) bool {
-		return selector(object) && h.less(object, target) && !h.isPermanent(object) && !h.isIgnored(object)
-	})
+	return storage.DeleteObjectsWhere(h.Folder, confirmed, isDeleted)
 }
 
 func (h *DeleteHandler) DeleteTargets(targets []BackupObject, confirmed bool) error {
@@ -371,6 +392,9 @@ func (h *DeleteHandler) DeleteTargets(targets []BackupObject, confirmed bool) er
 		}
 		backupNamesToDelete[target.GetBackupName()] = true
 	}
+	if err := h.checkOrphanedBackups(targets); err != nil {
+		return err
+	}
 
 	return storage.DeleteObjectsWhere(h.Folder.GetSubFolder(utility.BaseBackupPath),
 		confirmed, func(object storage.Object) bool {
@@ -378,6 +402,61 @@ func (h *DeleteHandler) DeleteTargets(targets []BackupObject, confirmed bool) er
 		})
 }
 
+// FindOrphanedBackups returns the backups which are not deleted, but become unrestorable
+// because some backup in their increment chain is deleted
+func (h *DeleteHandler) FindOrphanedBackups(backupsToDelete []BackupObject) []BackupObject {
+	deleted := make(map[string]bool, len(backupsToDelete))
+	for _, backup := range backupsToDelete {
+		deleted[backup.GetBackupName()] = true
+	}
+	backupsByName := make(map[string]BackupObject, len(h.backups))
+	for _, backup := range h.backups {
+		backupsByName[backup.GetBackupName()] = backup
+	}
+
+	var orphanedBackups []BackupObject
+	for _, backup := range h.backups {
+		if deleted[backup.GetBackupName()] {
+			continue
+		}
+		// walk up the increment chain until the full backup
+		visited := map[string]bool{backup.GetBackupName(): true}
+		for curr, ok := backup, true; ok && !curr.IsFullBackup(); {
+			incrementFrom := curr.GetIncrementFromName()
+			if deleted[incrementFrom] {
+				orphanedBackups = append(orphanedBackups, backup)
+				break
+			}
+			if visited[incrementFrom] {
+				break
+			}
+			visited[incrementFrom] = true
+			curr, ok = backupsByName[incrementFrom]
+		}
+	}
+	return orphanedBackups
+}
+
+// checkOrphanedBackups reports the increments which become orphaned by the deletion,
+// returns an error unless the orphaned increments are allowed
+func (h *DeleteHandler) checkOrphanedBackups(backupsToDelete []BackupObject) error {
+	orphanedBackups := h.FindOrphanedBackups(backupsToDelete)
+	if len(orphanedBackups) == 0 {
+		return nil
+	}
+	orphanedNames := make([]string, 0, len(orphanedBackups))
+	for _, backup := range orphanedBackups {
+		orphanedNames = append(orphanedNames, backup.GetBackupName())
+	}
+	if h.allowOrphans {
+		tracelog.WarningLogger.Printf("The following increments will be orphaned by deletion: %s\n",
+			strings.Join(orphanedNames, ", "))
+		return nil
+	}
+	return utility.NewForbiddenActionError(fmt.Sprintf("deletion would orphan the increments %s, "+
+		"delete them too or use --force", strings.Join(orphanedNames, ", ")))
+}
+
 // Find all backups related to the target.
 // All delta backups with the same base backup are considered as related.
 func (h *DeleteHandler) findRelatedBackups(target BackupObject) []BackupObject {
@@ -0,0 +1,19 @@
+package internal
+
+import (
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/crypto/envelope"
+)
+
+// HandleEnvelopeRewrap rewraps the detached envelope data keys with the current master key
+func HandleEnvelopeRewrap(crypter crypto.Crypter) error {
+	envelopeCrypter, ok := crypter.(*envelope.Crypter)
+	if !ok {
+		return errors.New("envelope crypter is not configured")
+	}
+	result, err := envelopeCrypter.RewrapDataKeys()
+	tracelog.InfoLogger.Printf("Envelope data keys rewrapped: %d, skipped: %d", result.Rewrapped, result.Skipped)
+	return err
+}
@@ -10,10 +10,13 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/none"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -52,6 +55,12 @@ type TarInterpreter interface {
 	Interpret(reader io.Reader, header *tar.Header) error
 }
 
+// ContextTarInterpreter is the TarInterpreter able to abort the entry being interpreted once the context is cancelled
+type ContextTarInterpreter interface {
+	TarInterpreter
+	InterpretContext(ctx context.Context, reader io.Reader, header *tar.Header) error
+}
+
 type DevNullWriter struct {
 	io.WriteCloser
 	statPrinter sync.Once
@@ -73,10 +82,13 @@ func (e *DevNullWriter) Write(p []byte) (int, error) {
 
 var _ io.Writer = &DevNullWriter{}
 
-// TODO : unit tests
-// Extract exactly one tar bundle.
-func extractOneTar(tarInterpreter TarInterpreter, source io.Reader) error {
-	tarReader := tar.NewReader(source)
+// Extract exactly one tar bundle and check it is followed by zeros only.
+// The interpreters may reopen the tar by reopenSource to retry the entry broken by the storage,
+// and read the large entries by ranges if readRange of the raw tar is not nil.
+func extractOneTar(tarInterpreter TarInterpreter, source io.Reader, reopenSource func() (io.ReadCloser, error),
+	readRange func(offset int64) (io.ReadCloser, error)) error {
+	tarReader := newReopenableTarReader(source, reopenSource, readRange)
+	defer utility.LoggedClose(tarReader, "")
 
 	for {
 		header, err := tarReader.Next()
@@ -92,7 +104,7 @@ func extractOneTar(tarInterpreter TarInterpreter, source io.Reader) error {
 			return errors.Wrap(err, "extractOne: Interpret failed")
 		}
 	}
-	return nil
+	return readTrailingZeros(tarReader.source)
 }
 
 func extractNonTar(tarInterpreter TarInterpreter, source io.Reader, path string, fileType FileType, mode int) error {
@@ -132,13 +144,14 @@ func DecryptAndDecompressTar(reader io.Reader, filePath string, crypter crypto.C
 		return nil, newUnsupportedFileTypeError(filePath, fileExtension)
 	}
 
-	return decompressor.Decompress(reader)
+	return compression.Pooled(decompressor).Decompress(reader)
 }
 
 // ExtractAll Handles all files passed in. Supports `.lzo`, `.lz4`, `.lzma`, and `.tar`.
 // File type `.nop` is used for testing purposes. Each file is extracted
 // in its own goroutine and ExtractAll will wait for all goroutines to finish.
 // Retries unsuccessful attempts log2(MaxConcurrency) times, dividing concurrency by two each time.
+// Once the retries are exhausted, the failures may be recorded by the ArchiveFailureRecorder to skip the archives.
 func ExtractAll(tarInterpreter TarInterpreter, files []ReaderMaker) error {
 	return ExtractAllWithSleeper(tarInterpreter, files, NewExponentialSleeper(MinExtractRetryWait, MaxExtractRetryWait))
 }
@@ -154,10 +167,19 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 		return err
 	}
 	for currentRun := files; len(currentRun) > 0; {
-		failed := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency)
+		failures := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency)
+		failed := make([]ReaderMaker, 0, len(failures))
+		for file := range failures {
+			failed = append(failed, file)
+		}
 		if downloadingConcurrency > 1 {
 			downloadingConcurrency /= 2
 		} else if len(failed) == len(currentRun) {
+			// the retries are exhausted, the archives which failures are recorded by the interpreter are skipped
+			failed = recordArchiveFailures(tarInterpreter, failures)
+			if len(failed) == 0 {
+				return nil
+			}
 			return errors.Errorf("failed to extract files:\n%s\n",
 				strings.Join(readerMakersToFilePaths(failed), "\n"))
 		}
@@ -170,17 +192,92 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 	return nil
 }
 
+// recordArchiveFailures passes the failures of the archives which retries are exhausted to the ArchiveFailureRecorder,
+// the archives which failures are not recorded are returned
+func recordArchiveFailures(tarInterpreter TarInterpreter, failures map[ReaderMaker]error) (unrecorded []ReaderMaker) {
+	for file, err := range failures {
+		if !recordArchiveFailure(tarInterpreter, file.Path(), err) {
+			unrecorded = append(unrecorded, file)
+		}
+	}
+	return unrecorded
+}
+
+// ExtractAllWithContext extracts the files by at most concurrency workers without retries.
+// The first failure cancels the context passed to the rest of the workers,
+// the files not started yet are skipped and the extraction of started ones stops before the next tar entry.
+func ExtractAllWithContext(ctx context.Context, tarInterpreter TarInterpreter, files []ReaderMaker, concurrency int) error {
+	if len(files) == 0 {
+		return newNoFilesToExtractError()
+	}
+	errGroup, workersCtx := errgroup.WithContext(ctx)
+	workers := semaphore.NewWeighted(int64(concurrency))
+	crypter := ConfigureCrypter()
+	interpreter := &contextTarInterpreter{ctx: workersCtx, tarInterpreter: tarInterpreter}
+
+	for _, file := range files {
+		if err := workers.Acquire(workersCtx, 1); err != nil {
+			break
+		}
+		if workersCtx.Err() != nil {
+			workers.Release(1)
+			break
+		}
+		fileClosure := file
+		errGroup.Go(func() error {
+			defer workers.Release(1)
+			readCloser, err := fileClosure.Reader()
+			if err != nil {
+				return errors.Wrapf(err, "Extraction error in %s", fileClosure.Path())
+			}
+			defer utility.LoggedClose(readCloser, "")
+			extractingReader, err := DecryptAndDecompressTar(readCloser, fileClosure.Path(), crypter)
+			if err == nil {
+				defer extractingReader.Close()
+				err = extractFile(interpreter, extractingReader, fileClosure, crypter)
+			}
+			if err != nil {
+				if recordArchiveFailure(tarInterpreter, fileClosure.Path(), err) {
+					return nil
+				}
+				return errors.Wrapf(err, "Extraction error in %s", fileClosure.Path())
+			}
+			tracelog.InfoLogger.Printf("Finished extraction of %s", fileClosure.Path())
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// contextTarInterpreter stops the extraction before the next tar entry once the context is cancelled,
+// the ContextTarInterpreter also aborts the entry being interpreted
+type contextTarInterpreter struct {
+	ctx            context.Context
+	tarInterpreter TarInterpreter
+}
+
+func (interpreter *contextTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	if err := interpreter.ctx.Err(); err != nil {
+		return err
+	}
+	if contextInterpreter, ok := interpreter.tarInterpreter.(ContextTarInterpreter); ok {
+		return contextInterpreter.InterpretContext(interpreter.ctx, reader, header)
+	}
+	return interpreter.tarInterpreter.Interpret(reader, header)
+}
+
 // Extract single file from backup
 // If it is .tar file unpack it and store internal files (there will be .tar file if you work with wal-g backup)
 // Otherwise store this file (there will be regular file if you work with pgbackrest backup)
-func extractFile(tarInterpreter TarInterpreter, extractingReader io.Reader, fileClosure ReaderMaker) error {
+func extractFile(tarInterpreter TarInterpreter, extractingReader io.Reader, fileClosure ReaderMaker,
+	crypter crypto.Crypter) error {
 	switch fileClosure.FileType() {
 	case TarFileType:
-		err := extractOneTar(tarInterpreter, extractingReader)
-		if err == nil {
-			err = readTrailingZeros(extractingReader)
-		}
-		return err
+		return extractOneTar(tarInterpreter, extractingReader, newTarSourceReopener(fileClosure, crypter),
+			getRawTarRangeReader(fileClosure, crypter))
 	case RegularFileType:
 		filePath := utility.TrimFileExtension(fileClosure.Path())
 		return extractNonTar(tarInterpreter, extractingReader, filePath, fileClosure.FileType(), fileClosure.Mode())
@@ -190,10 +287,58 @@ func extractFile(tarInterpreter TarInterpreter, extractingReader io.Reader, file
 	}
 }
 
+// recordArchiveFailure passes the failure of the opened archive to the ArchiveFailureRecorder,
+// true is returned if the extraction should proceed with the remaining archives
+func recordArchiveFailure(tarInterpreter TarInterpreter, archivePath string, err error) bool {
+	recorder, ok := tarInterpreter.(ArchiveFailureRecorder)
+	return ok && recorder.RecordArchiveFailure(archivePath, err)
+}
+
+// getRawTarRangeReader returns the range reader of the tar stored as is, nil if the tar is compressed,
+// encrypted or the storage can not read the ranges
+func getRawTarRangeReader(fileClosure ReaderMaker, crypter crypto.Crypter) func(offset int64) (io.ReadCloser, error) {
+	rangeReaderMaker, ok := fileClosure.(RangeReaderMaker)
+	if !ok || !rangeReaderMaker.SupportsRanges() {
+		return nil
+	}
+	if crypter != nil || !isRawTarPath(fileClosure.Path()) {
+		warnRangeRestoreUnsupported(fileClosure.Path())
+		return nil
+	}
+	return rangeReaderMaker.ReadRange
+}
+
+// isRawTarPath checks the tar is stored as is: either without the compression extension
+// or with the extension of the none compressor, the backup parts are uploaded as 'part_NNN.tar.raw'
+func isRawTarPath(tarPath string) bool {
+	extension := utility.GetFileExtension(tarPath)
+	if extension == none.FileExtension {
+		extension = utility.GetFileExtension(utility.TrimFileExtension(tarPath))
+	}
+	return extension == "tar"
+}
+
+// rangeRestoreUnsupportedWarning is printed once per restore, all the tars of the backup are stored the same way
+var rangeRestoreUnsupportedWarning sync.Once
+
+// warnRangeRestoreUnsupported tells the configured range reads are not used: the offsets of the tar entries
+// are known in the uncompressed and unencrypted tars only
+func warnRangeRestoreUnsupported(tarPath string) {
+	if viper.GetInt64(RestoreRangeThresholdSetting) <= 0 {
+		return
+	}
+	rangeRestoreUnsupportedWarning.Do(func() {
+		tracelog.WarningLogger.Printf("%s is ignored for the compressed or encrypted tar '%s': "+
+			"the range reads are limited to the uncompressed and unencrypted backups, "+
+			"the large files are restored sequentially", RestoreRangeThresholdSetting, tarPath)
+	})
+}
+
 // TODO : unit tests
+// tryExtractFiles extracts the files once, the failed files are returned with their errors to be retried
 func tryExtractFiles(files []ReaderMaker,
 	tarInterpreter TarInterpreter,
-	downloadingConcurrency int) (failed []ReaderMaker) {
+	downloadingConcurrency int) (failures map[ReaderMaker]error) {
 	downloadingContext := context.TODO()
 	downloadingSemaphore := semaphore.NewWeighted(int64(downloadingConcurrency))
 	crypter := ConfigureCrypter()
@@ -203,7 +348,7 @@ func tryExtractFiles(files []ReaderMaker,
 		err := downloadingSemaphore.Acquire(downloadingContext, 1)
 		if err != nil {
 			tracelog.ErrorLogger.Println(err)
-			return files //Should never happen, but if we are asked to cancel - consider all files unfinished
+			return allFailed(files, err) //Should never happen, but if we are asked to cancel - consider all files unfinished
 		}
 		fileClosure := file
 
@@ -219,14 +364,14 @@ func tryExtractFiles(files []ReaderMaker,
 				extractingReader, err = DecryptAndDecompressTar(readCloser, filePath, crypter)
 				if err == nil {
 					defer extractingReader.Close()
-					err = extractFile(tarInterpreter, extractingReader, fileClosure)
+					err = extractFile(tarInterpreter, extractingReader, fileClosure, crypter)
 					err = errors.Wrapf(err, "Extraction error in %s", filePath)
 					tracelog.InfoLogger.Printf("Finished extraction of %s", filePath)
 				}
 			}
 
 			if err != nil {
-				isFailed.Store(fileClosure, true)
+				isFailed.Store(fileClosure, err)
 				tracelog.ErrorLogger.Println(err)
 			}
 		}()
@@ -235,14 +380,23 @@ func tryExtractFiles(files []ReaderMaker,
 	err := downloadingSemaphore.Acquire(downloadingContext, int64(downloadingConcurrency))
 	if err != nil {
 		tracelog.ErrorLogger.Println(err)
-		return files //Should never happen, but if we are asked to cancel - consider all files unfinished
+		return allFailed(files, err) //Should never happen, but if we are asked to cancel - consider all files unfinished
 	}
 
-	isFailed.Range(func(failedFile, _ interface{}) bool {
-		failed = append(failed, failedFile.(ReaderMaker))
+	failures = make(map[ReaderMaker]error)
+	isFailed.Range(func(failedFile, err interface{}) bool {
+		failures[failedFile.(ReaderMaker)] = err.(error)
 		return true
 	})
-	return failed
+	return failures
+}
+
+func allFailed(files []ReaderMaker, err error) map[ReaderMaker]error {
+	failures := make(map[ReaderMaker]error, len(files))
+	for _, file := range files {
+		failures[file] = err
+	}
+	return failures
 }
 
 func readTrailingZeros(r io.Reader) error {
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// bufferedTarEntry is the tar entry spooled to the temporary file to be interpreted out of the stream order
+type bufferedTarEntry struct {
+	header *tar.Header
+	offset int64
+	size   int64
+}
+
+// ExtractAllInOrder extracts the files one by one in the order of their paths without retries, so the repeated
+// restores of the same backup interpret the same entries in the same order. The entries of each tar are spooled
+// to the temporary file and interpreted sorted: the directories, then the files by name. The links of all the tars
+// are interpreted last, each hardlink after its target. It trades the concurrency and the temporary disk space
+// of the largest tar for the reproducibility, so it is meant for the testing and debugging.
+func ExtractAllInOrder(tarInterpreter TarInterpreter, files []ReaderMaker) error {
+	if len(files) == 0 {
+		return newNoFilesToExtractError()
+	}
+	sortedFiles := append([]ReaderMaker{}, files...)
+	sort.SliceStable(sortedFiles, func(i, j int) bool {
+		return sortedFiles[i].Path() < sortedFiles[j].Path()
+	})
+
+	crypter := ConfigureCrypter()
+	var links []*tar.Header
+	for _, file := range sortedFiles {
+		fileLinks, err := extractFileInOrder(tarInterpreter, file, crypter)
+		if err != nil {
+			return errors.Wrapf(err, "Extraction error in %s", file.Path())
+		}
+		links = append(links, fileLinks...)
+		tracelog.InfoLogger.Printf("Finished extraction of %s", file.Path())
+	}
+
+	links, err := orderTarLinks(links)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if err = tarInterpreter.Interpret(&bytes.Buffer{}, link); err != nil {
+			return errors.Wrap(err, "extractInOrder: Interpret failed")
+		}
+	}
+	return nil
+}
+
+// extractFileInOrder extracts the file except for the links of the tar, which are returned to be interpreted
+// once all the files are extracted
+func extractFileInOrder(tarInterpreter TarInterpreter, file ReaderMaker, crypter crypto.Crypter) ([]*tar.Header, error) {
+	readCloser, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(readCloser, "")
+	extractingReader, err := DecryptAndDecompressTar(readCloser, file.Path(), crypter)
+	if err != nil {
+		return nil, err
+	}
+	defer extractingReader.Close()
+
+	if file.FileType() != TarFileType {
+		return nil, extractFile(tarInterpreter, extractingReader, file, crypter)
+	}
+	return extractTarInOrder(tarInterpreter, extractingReader, newTarSourceReopener(file, crypter),
+		getRawTarRangeReader(file, crypter))
+}
+
+// extractTarInOrder spools the tar entries to the temporary file and interprets them sorted by compareTarEntries,
+// the links are returned without being interpreted
+func extractTarInOrder(tarInterpreter TarInterpreter, source io.Reader, reopenSource func() (io.ReadCloser, error),
+	readRange func(offset int64) (io.ReadCloser, error)) ([]*tar.Header, error) {
+	spool, err := os.CreateTemp("", "wal-g-extract-")
+	if err != nil {
+		return nil, errors.Wrap(err, "extractInOrder: failed to create the spool file")
+	}
+	defer func() {
+		utility.LoggedClose(spool, "")
+		if err := os.Remove(spool.Name()); err != nil {
+			tracelog.WarningLogger.Printf("Failed to remove the spool file '%s': %v", spool.Name(), err)
+		}
+	}()
+
+	tarReader := newReopenableTarReader(source, reopenSource, readRange)
+	defer utility.LoggedClose(tarReader, "")
+	var entries []bufferedTarEntry
+	var links []*tar.Header
+	var offset int64
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "extractInOrder: tar extract failed")
+		}
+		if isTarLink(header) {
+			links = append(links, header)
+			continue
+		}
+		size, err := io.Copy(spool, tarReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "extractInOrder: failed to spool '%s'", header.Name)
+		}
+		entries = append(entries, bufferedTarEntry{header: header, offset: offset, size: size})
+		offset += size
+	}
+	if err = readTrailingZeros(tarReader.source); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareTarEntries(entries[i].header, entries[j].header)
+	})
+	for _, entry := range entries {
+		err = tarInterpreter.Interpret(io.NewSectionReader(spool, entry.offset, entry.size), entry.header)
+		if err != nil {
+			return nil, errors.Wrap(err, "extractInOrder: Interpret failed")
+		}
+	}
+	return links, nil
+}
+
+func isTarLink(header *tar.Header) bool {
+	return header.Typeflag == tar.TypeLink || header.Typeflag == tar.TypeSymlink
+}
+
+// compareTarEntries orders the directories before the other entries, the entries of the same kind by name
+func compareTarEntries(left, right *tar.Header) bool {
+	leftIsDir, rightIsDir := left.Typeflag == tar.TypeDir, right.Typeflag == tar.TypeDir
+	if leftIsDir != rightIsDir {
+		return leftIsDir
+	}
+	return left.Name < right.Name
+}
+
+// orderTarLinks sorts the links by name and moves each hardlink after the hardlink it refers to, if any
+func orderTarLinks(links []*tar.Header) ([]*tar.Header, error) {
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].Name < links[j].Name
+	})
+	pendingHardlinks := make(map[string]int)
+	for _, link := range links {
+		if link.Typeflag == tar.TypeLink {
+			pendingHardlinks[cleanTarEntryName(link.Name)]++
+		}
+	}
+
+	ordered := make([]*tar.Header, 0, len(links))
+	for len(links) > 0 {
+		var postponed []*tar.Header
+		for _, link := range links {
+			if link.Typeflag == tar.TypeLink && pendingHardlinks[cleanTarEntryName(link.Linkname)] > 0 {
+				postponed = append(postponed, link)
+				continue
+			}
+			ordered = append(ordered, link)
+			if link.Typeflag == tar.TypeLink {
+				pendingHardlinks[cleanTarEntryName(link.Name)]--
+			}
+		}
+		if len(postponed) == len(links) {
+			return nil, errors.Errorf("extractInOrder: hardlink '%s' refers to the cyclic hardlink '%s'",
+				postponed[0].Name, postponed[0].Linkname)
+		}
+		links = postponed
+	}
+	return ordered, nil
+}
+
+func cleanTarEntryName(name string) string {
+	return path.Clean(strings.TrimPrefix(name, "/"))
+}
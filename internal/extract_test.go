@@ -1,8 +1,10 @@
 package internal_test
 
 import (
+	"archive/tar"
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"strconv"
@@ -142,8 +144,46 @@ func TestExtractAll_multipleConcurrentTars(t *testing.T) {
 	}
 }
 
-func noPassphrase() (string, bool) {
-	return "", false
+func TestExtractAllWithContext_multipleTars(t *testing.T) {
+	fileAmount := 8
+	bufs := [][]byte{}
+	brms := []internal.ReaderMaker{}
+
+	for i := 0; i < fileAmount; i++ {
+		brm, b := makeTar(strconv.Itoa(i))
+		bufs = append(bufs, b)
+		brms = append(brms, &brm)
+	}
+
+	buf := testtools.NewConcurrentConcatBufferTarInterpreter()
+
+	err := internal.ExtractAllWithContext(context.Background(), buf, brms, 3)
+	assert.NoError(t, err)
+
+	for i := 0; i < fileAmount; i++ {
+		assert.Equal(t, bufs[i], buf.Out[strconv.Itoa(i)], "Some of outputs do not match input")
+	}
+}
+
+func TestExtractAllWithContext_failsFast(t *testing.T) {
+	brm, _ := makeTar("booba")
+	files := []internal.ReaderMaker{&testtools.FileReaderMaker{Key: "testdata/booba.tar"}, &brm}
+
+	err := internal.ExtractAllWithContext(context.Background(), testtools.NewConcurrentConcatBufferTarInterpreter(), files, 1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "testdata/booba.tar")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	brm, _ = makeTar("booba")
+	buf := testtools.NewConcurrentConcatBufferTarInterpreter()
+	err = internal.ExtractAllWithContext(ctx, buf, []internal.ReaderMaker{&brm}, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, buf.Out)
+}
+
+func noPassphrase() ([]byte, bool, error) {
+	return nil, false, nil
 }
 
 func TestDecryptAndDecompressTar_unencrypted(t *testing.T) {
@@ -293,3 +333,71 @@ func (b *BufferReaderMaker) Mode() int                      { return 0 }
 type NOPSleeper struct{}
 
 func (s NOPSleeper) Sleep() {}
+
+// recordingTarInterpreter records the names and the contents of the interpreted entries in the order of interpretation
+type recordingTarInterpreter struct {
+	names    []string
+	contents map[string]string
+}
+
+func (interpreter *recordingTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	interpreter.names = append(interpreter.names, header.Name)
+	interpreter.contents[header.Name] = string(content)
+	return nil
+}
+
+func makeOrderTestTar(t *testing.T, key string, headers []*tar.Header) *BufferReaderMaker {
+	tarContents := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(tarContents)
+	for _, header := range headers {
+		assert.NoError(t, tarWriter.WriteHeader(header))
+		if header.Typeflag == tar.TypeReg {
+			_, err := tarWriter.Write([]byte("content of " + header.Name))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, tarWriter.Close())
+	return &BufferReaderMaker{tarContents, key}
+}
+
+func regularTarHeader(name string) *tar.Header {
+	return &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len("content of " + name))}
+}
+
+func TestExtractAllInOrder(t *testing.T) {
+	files := []internal.ReaderMaker{
+		makeOrderTestTar(t, "/part_2.tar", []*tar.Header{
+			// the hardlink to the hardlink, both refer to the file of the other tar
+			{Name: "base/hardlink_1", Typeflag: tar.TypeLink, Linkname: "base/hardlink_2"},
+			regularTarHeader("base/c"),
+			{Name: "base/hardlink_2", Typeflag: tar.TypeLink, Linkname: "base/a"},
+		}),
+		makeOrderTestTar(t, "/part_1.tar", []*tar.Header{
+			regularTarHeader("base/b"),
+			{Name: "base/symlink", Typeflag: tar.TypeSymlink, Linkname: "b"},
+			regularTarHeader("base/a"),
+			{Name: "base", Typeflag: tar.TypeDir, Mode: 0700},
+		}),
+	}
+
+	interpreter := &recordingTarInterpreter{contents: make(map[string]string)}
+	assert.NoError(t, internal.ExtractAllInOrder(interpreter, files))
+	assert.Equal(t, []string{"base", "base/a", "base/b", "base/c",
+		"base/hardlink_2", "base/symlink", "base/hardlink_1"}, interpreter.names)
+	for _, name := range []string{"base/a", "base/b", "base/c"} {
+		assert.Equal(t, "content of "+name, interpreter.contents[name])
+	}
+}
+
+func TestExtractAllInOrder_cyclicHardlinks(t *testing.T) {
+	files := []internal.ReaderMaker{makeOrderTestTar(t, "/part_1.tar", []*tar.Header{
+		{Name: "a", Typeflag: tar.TypeLink, Linkname: "b"},
+		{Name: "b", Typeflag: tar.TypeLink, Linkname: "a"},
+	})}
+	err := internal.ExtractAllInOrder(&recordingTarInterpreter{contents: make(map[string]string)}, files)
+	assert.Error(t, err)
+}
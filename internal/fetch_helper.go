@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -28,37 +29,50 @@ func (err ArchiveNonExistenceError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
-// DownloadFile downloads, decompresses and decrypts
+// DownloadFile downloads, decompresses and decrypts the file into the writeCloser.
+// The writeCloser is closed once the file is written or the download fails, the Close error is returned.
 func DownloadFile(folder storage.Folder, filename, ext string, writeCloser io.WriteCloser) error {
-	utility.LoggedClose(writeCloser, "")
+	decompressedReader, err := DownloadFileReader(folder, filename, ext)
+	if err != nil {
+		utility.LoggedClose(writeCloser, "")
+		return err
+	}
+	defer utility.LoggedClose(decompressedReader, "")
 
-	decompressor := compression.FindDecompressor(ext)
-	if decompressor == nil {
-		return fmt.Errorf("decompressor for extension '%s' was not found", ext)
+	_, err = utility.FastCopy(&utility.EmptyWriteIgnorer{Writer: writeCloser}, decompressedReader)
+	if err != nil {
+		utility.LoggedClose(writeCloser, "")
+		return err
 	}
-	tracelog.DebugLogger.Printf("Found decompressor for %s", decompressor.FileExtension())
+	return writeCloser.Close()
+}
 
+// DownloadFileReader opens the file and returns the reader which decrypts and decompresses it as it is read.
+// Closing the reader closes the storage object reader as well.
+func DownloadFileReader(folder storage.Folder, filename, ext string) (io.ReadCloser, error) {
 	archiveReader, exists, err := TryDownloadFile(folder, filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !exists {
-		return fmt.Errorf("file '%s' does not exist", filename)
+		return nil, fmt.Errorf("file '%s' does not exist", filename)
 	}
-	defer utility.LoggedClose(archiveReader, "")
 
-	decompressedReader, err := DecompressDecryptBytes(archiveReader, decompressor)
+	decompressedReader, err := decompressDecryptBytesDetected(archiveReader, ext)
 	if err != nil {
-		return err
+		utility.LoggedClose(archiveReader, "")
+		return nil, err
 	}
-	defer utility.LoggedClose(decompressedReader, "")
-
-	_, err = utility.FastCopy(&utility.EmptyWriteIgnorer{Writer: writeCloser}, decompressedReader)
-	return err
+	return ioextensions.ReadCascadeCloser{
+		Reader: decompressedReader,
+		Closer: ioextensions.NewMultiCloser([]io.Closer{decompressedReader, archiveReader}),
+	}, nil
 }
 
+// TryDownloadFile opens the object, the failed reads are resumed since the offset read
+// if WALG_DOWNLOAD_RANGE_RESUMES is set and the storage supports the range requests
 func TryDownloadFile(folder storage.Folder, path string) (fileReader io.ReadCloser, exists bool, err error) {
-	fileReader, err = folder.ReadObject(path)
+	fileReader, err = ReadObjectResumable(folder, path, viper.GetInt(DownloadRangeResumesSetting))
 	if err == nil {
 		exists = true
 		return
@@ -78,7 +92,28 @@ func DecompressDecryptBytes(archiveReader io.Reader, decompressor compression.De
 		tracelog.DebugLogger.Printf("No decompressor has been selected")
 		return io.NopCloser(decryptReader), nil
 	}
-	return decompressor.Decompress(decryptReader)
+	return compression.Pooled(decompressor).Decompress(decryptReader)
+}
+
+// decompressDecryptBytesDetected decompresses the decrypted archive by the decompressor of the extension.
+// The decompressor is picked by the leading bytes of the archive only if the extension is missing or unknown,
+// e.g. stripped from the file name.
+func decompressDecryptBytesDetected(archiveReader io.Reader, ext string) (io.ReadCloser, error) {
+	decryptReader, err := DecryptBytes(archiveReader)
+	if err != nil {
+		return nil, err
+	}
+	if decompressor := compression.FindDecompressor(ext); decompressor != nil {
+		tracelog.DebugLogger.Printf("Found decompressor for %s", decompressor.FileExtension())
+		return compression.Pooled(decompressor).Decompress(decryptReader)
+	}
+
+	decompressor, bufferedReader, err := compression.DetectDecompressor(decryptReader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompressor for extension '%s' was not found", ext)
+	}
+	tracelog.DebugLogger.Printf("Detected decompressor for %s", decompressor.FileExtension())
+	return compression.Pooled(decompressor).Decompress(bufferedReader)
 }
 
 func DecryptBytes(archiveReader io.Reader) (io.Reader, error) {
@@ -0,0 +1,61 @@
+package internal_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/none"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestDownloadFileReader_StoredObjectIsNotDecompressed(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	// the stored gzip file must not be detected as the gzip compressed object
+	var gzipped bytes.Buffer
+	gzipWriter := compression.Compressors[none.AlgorithmName].NewWriter(&gzipped)
+	_, err := gzipWriter.Write([]byte{0x1F, 0x8B, 0x08, 0x00, 'd', 'a', 't', 'a'})
+	assert.NoError(t, err)
+	assert.NoError(t, gzipWriter.Close())
+	stored := append([]byte{}, gzipped.Bytes()...)
+	assert.NoError(t, folder.PutObject("image.gz."+none.FileExtension, &gzipped))
+
+	reader, err := internal.DownloadFileReader(folder, "image.gz."+none.FileExtension, none.FileExtension)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, stored, data)
+}
+
+func TestDownloadFileReader_DetectsDecompressorOfUnknownExtension(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	var compressed bytes.Buffer
+	writer := compression.Compressors[gzip.AlgorithmName].NewWriter(&compressed)
+	_, err := writer.Write([]byte("wal segment"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, folder.PutObject("segment", bytes.NewReader(compressed.Bytes())))
+	assert.NoError(t, folder.PutObject("segment."+lz4.FileExtension, bytes.NewReader(compressed.Bytes())))
+
+	for _, ext := range []string{"", "unknown"} {
+		reader, err := internal.DownloadFileReader(folder, "segment", ext)
+		assert.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.NoError(t, reader.Close())
+		assert.Equal(t, "wal segment", string(data))
+	}
+
+	// the known extension is trusted, the leading bytes are not sniffed
+	reader, err := internal.DownloadFileReader(folder, "segment."+lz4.FileExtension, lz4.FileExtension)
+	assert.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	assert.Error(t, err)
+	assert.NoError(t, reader.Close())
+}
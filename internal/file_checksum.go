@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	CRC32CChecksumAlgorithm = "CRC32C"
+	SHA256ChecksumAlgorithm = "SHA256"
+)
+
+// FileChecksum describes the digest of the backup file contents
+type FileChecksum struct {
+	Algorithm string
+	// Value is the hex-encoded digest
+	Value string
+}
+
+// NewChecksumHash creates the hash for the provided checksum algorithm
+func NewChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case CRC32CChecksumAlgorithm:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case SHA256ChecksumAlgorithm:
+		return sha256.New(), nil
+	default:
+		return nil, errors.Errorf("unknown checksum algorithm '%s'", algorithm)
+	}
+}
+
+// NewFileChecksum builds the FileChecksum from the calculated hash
+func NewFileChecksum(algorithm string, h hash.Hash) FileChecksum {
+	return FileChecksum{Algorithm: algorithm, Value: hex.EncodeToString(h.Sum(nil))}
+}
@@ -89,3 +89,36 @@ func (m *MultiCloser) Close() error {
 	}
 	return err
 }
+
+// MultiWriteCloser fans out the writes to all the sinks, e.g. to the file, the validating hasher
+// and the metrics counter at once
+type MultiWriteCloser struct {
+	sinks []io.WriteCloser
+}
+
+func NewMultiWriteCloser(sinks ...io.WriteCloser) *MultiWriteCloser {
+	return &MultiWriteCloser{sinks: sinks}
+}
+
+// Write writes p to the sinks in order, the first failed sink stops the write and its error is returned
+func (m *MultiWriteCloser) Write(p []byte) (int, error) {
+	for i, sink := range m.sinks {
+		n, err := sink.Write(p)
+		if err != nil {
+			return n, fmt.Errorf("sink %d: %w", i, err)
+		}
+		if n != len(p) {
+			return n, fmt.Errorf("sink %d: %w", i, io.ErrShortWrite)
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes all the sinks even if some of them fail, the errors of all the failed ones are returned
+func (m *MultiWriteCloser) Close() error {
+	closers := make([]io.Closer, 0, len(m.sinks))
+	for _, sink := range m.sinks {
+		closers = append(closers, sink)
+	}
+	return NewMultiCloser(closers).Close()
+}
@@ -3,6 +3,7 @@ package limiters_test
 import (
 	"bytes"
 	"io"
+	"sync"
 	"testing"
 	"time"
 
@@ -45,3 +46,25 @@ func TestLimiter(t *testing.T) {
 		t.Errorf("Rate limiter did not work")
 	}
 }
+
+func TestNetworkLimiter_IsSharedByConcurrentStreams(t *testing.T) {
+	limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(10000), 1024)
+	defer func() { limiters.NetworkLimiter = nil }()
+	start := utility.TimeNowCrossPlatformLocal()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := io.ReadAll(limiters.NewNetworkLimitReader(bytes.NewReader(make([]byte, 1000))))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// 2000 bytes in total at 10000 bytes per second after the 1024 bytes burst
+	if utility.TimeNowCrossPlatformLocal().Sub(start) < time.Millisecond*80 {
+		t.Errorf("Rate limiter is not shared by the streams")
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import "time"
+
+// Operation labels the measured upload.
+type Operation string
+
+const (
+	BackupOperation       Operation = "backup"
+	OplogArchiveOperation Operation = "oplog_archive"
+	OplogListOperation    Operation = "oplog_list"
+	FolderListOperation   Operation = "folder_list"
+)
+
+// Upload describes the finished upload.
+type Upload struct {
+	Operation Operation
+	// RawBytes is the size of the uploaded stream before compression and encryption
+	RawBytes int64
+	// UploadedBytes is the size of the objects put to storage
+	UploadedBytes int64
+	Duration      time.Duration
+}
+
+// CompressionRatio returns how many times the stream shrank, 0 if nothing was uploaded.
+func (upload Upload) CompressionRatio() float64 {
+	if upload.UploadedBytes == 0 {
+		return 0
+	}
+	return float64(upload.RawBytes) / float64(upload.UploadedBytes)
+}
+
+// Throughput returns the uploaded bytes per second.
+func (upload Upload) Throughput() float64 {
+	if upload.Duration <= 0 {
+		return 0
+	}
+	return float64(upload.UploadedBytes) / upload.Duration.Seconds()
+}
+
+// Collector receives the upload metrics, e.g. to expose them as prometheus counters and histograms.
+// Implementations must be safe for concurrent use.
+type Collector interface {
+	ObserveUpload(upload Upload)
+	IncRetries(operation Operation)
+	IncFailures(operation Operation)
+}
+
+// FailoverCounter counts the operations served by a secondary storage because the primary one failed.
+// Implementations must be safe for concurrent use.
+type FailoverCounter interface {
+	IncFailovers(operation Operation)
+}
+
+// CacheCounter counts the operations served from the cache and the ones which had to reach the storage,
+// the hit ratio is hits / (hits + misses). Implementations must be safe for concurrent use.
+type CacheCounter interface {
+	IncCacheHits(operation Operation)
+	IncCacheMisses(operation Operation)
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+)
+
+// ExpvarName is the name of the expvar map the DefaultCollector publishes the counters to,
+// it is served at /debug/vars if HTTP_EXPOSE_EXPVAR is set
+const ExpvarName = "walg_metrics"
+
+var (
+	defaultCollector     *ExpvarCollector
+	defaultCollectorOnce sync.Once
+)
+
+// DefaultCollector returns the collector published to the expvar map named ExpvarName
+func DefaultCollector() *ExpvarCollector {
+	defaultCollectorOnce.Do(func() {
+		defaultCollector = NewExpvarCollector()
+		expvar.Publish(ExpvarName, defaultCollector.counters)
+	})
+	return defaultCollector
+}
+
+// ExpvarCollector keeps the counters in the expvar map, the keys are prefixed by the operation,
+// e.g. "folder_list_cache_hits" or "backup_uploaded_bytes". It is safe for concurrent use.
+type ExpvarCollector struct {
+	counters *expvar.Map
+}
+
+var (
+	_ Collector       = &ExpvarCollector{}
+	_ FailoverCounter = &ExpvarCollector{}
+	_ CacheCounter    = &ExpvarCollector{}
+)
+
+// NewExpvarCollector creates the collector which is not published,
+// it is to be read by its methods or published by the caller
+func NewExpvarCollector() *ExpvarCollector {
+	return &ExpvarCollector{counters: new(expvar.Map).Init()}
+}
+
+// ObserveUpload counts the upload and adds its sizes and duration to the operation totals
+func (collector *ExpvarCollector) ObserveUpload(upload Upload) {
+	collector.counters.Add(counterKey(upload.Operation, "uploads"), 1)
+	collector.counters.Add(counterKey(upload.Operation, "raw_bytes"), upload.RawBytes)
+	collector.counters.Add(counterKey(upload.Operation, "uploaded_bytes"), upload.UploadedBytes)
+	collector.counters.AddFloat(counterKey(upload.Operation, "upload_seconds"), upload.Duration.Seconds())
+}
+
+func (collector *ExpvarCollector) IncRetries(operation Operation) {
+	collector.counters.Add(counterKey(operation, "retries"), 1)
+}
+
+func (collector *ExpvarCollector) IncFailures(operation Operation) {
+	collector.counters.Add(counterKey(operation, "failures"), 1)
+}
+
+func (collector *ExpvarCollector) IncFailovers(operation Operation) {
+	collector.counters.Add(counterKey(operation, "failovers"), 1)
+}
+
+func (collector *ExpvarCollector) IncCacheHits(operation Operation) {
+	collector.counters.Add(counterKey(operation, "cache_hits"), 1)
+	collector.publishCacheHitRatio(operation)
+}
+
+func (collector *ExpvarCollector) IncCacheMisses(operation Operation) {
+	collector.counters.Add(counterKey(operation, "cache_misses"), 1)
+	collector.publishCacheHitRatio(operation)
+}
+
+// CacheHitRatio returns the share of the operations served from the cache, 0 if nothing was counted
+func (collector *ExpvarCollector) CacheHitRatio(operation Operation) float64 {
+	hits := collector.Counter(operation, "cache_hits")
+	total := hits + collector.Counter(operation, "cache_misses")
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// Counter returns the value of the operation counter, 0 if it was not increased
+func (collector *ExpvarCollector) Counter(operation Operation, name string) int64 {
+	if counter, ok := collector.counters.Get(counterKey(operation, name)).(*expvar.Int); ok {
+		return counter.Value()
+	}
+	return 0
+}
+
+// publishCacheHitRatio adds the ratio calculated on read next to the cache counters of the operation
+func (collector *ExpvarCollector) publishCacheHitRatio(operation Operation) {
+	key := counterKey(operation, "cache_hit_ratio")
+	if collector.counters.Get(key) == nil {
+		collector.counters.Set(key, expvar.Func(func() interface{} { return collector.CacheHitRatio(operation) }))
+	}
+}
+
+func counterKey(operation Operation, name string) string {
+	return string(operation) + "_" + name
+}
@@ -0,0 +1,52 @@
+package metrics_test
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/metrics"
+)
+
+func TestExpvarCollector_CacheHitRatio(t *testing.T) {
+	collector := metrics.NewExpvarCollector()
+	assert.Equal(t, 0.0, collector.CacheHitRatio(metrics.FolderListOperation))
+
+	collector.IncCacheMisses(metrics.FolderListOperation)
+	for i := 0; i < 3; i++ {
+		collector.IncCacheHits(metrics.FolderListOperation)
+	}
+	assert.Equal(t, int64(3), collector.Counter(metrics.FolderListOperation, "cache_hits"))
+	assert.Equal(t, int64(1), collector.Counter(metrics.FolderListOperation, "cache_misses"))
+	assert.Equal(t, 0.75, collector.CacheHitRatio(metrics.FolderListOperation))
+	assert.Equal(t, 0.0, collector.CacheHitRatio(metrics.OplogListOperation))
+}
+
+func TestExpvarCollector_UploadsAndFailures(t *testing.T) {
+	collector := metrics.NewExpvarCollector()
+	collector.ObserveUpload(metrics.Upload{Operation: metrics.BackupOperation, RawBytes: 100, UploadedBytes: 40,
+		Duration: time.Second})
+	collector.ObserveUpload(metrics.Upload{Operation: metrics.BackupOperation, RawBytes: 50, UploadedBytes: 20,
+		Duration: time.Second})
+	collector.IncRetries(metrics.OplogArchiveOperation)
+	collector.IncFailures(metrics.OplogArchiveOperation)
+	collector.IncFailovers(metrics.OplogArchiveOperation)
+
+	assert.Equal(t, int64(2), collector.Counter(metrics.BackupOperation, "uploads"))
+	assert.Equal(t, int64(150), collector.Counter(metrics.BackupOperation, "raw_bytes"))
+	assert.Equal(t, int64(60), collector.Counter(metrics.BackupOperation, "uploaded_bytes"))
+	assert.Equal(t, int64(1), collector.Counter(metrics.OplogArchiveOperation, "retries"))
+	assert.Equal(t, int64(1), collector.Counter(metrics.OplogArchiveOperation, "failures"))
+	assert.Equal(t, int64(1), collector.Counter(metrics.OplogArchiveOperation, "failovers"))
+	assert.Equal(t, int64(0), collector.Counter(metrics.BackupOperation, "retries"))
+}
+
+func TestDefaultCollector_PublishesExpvar(t *testing.T) {
+	metrics.DefaultCollector().IncCacheHits(metrics.OplogListOperation)
+
+	published, ok := expvar.Get(metrics.ExpvarName).(*expvar.Map)
+	assert.True(t, ok)
+	assert.NotNil(t, published.Get("oplog_list_cache_hits"))
+	assert.Equal(t, "1", published.Get("oplog_list_cache_hit_ratio").String())
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/crypto/openpgp"
+)
+
+// commandPassphraseCrypters keeps the PGP crypters whose passphrase is printed by the WALG_PASSPHRASE_COMMAND
+// by the SHA-256 of their key and command, so the command is executed once per process.
+// Only the decrypted keyring is kept, the passphrase is zeroed once the keyring is decrypted.
+var commandPassphraseCrypters sync.Map
+
+// configurePgpCrypter builds the PGP crypter of the key by newCrypter,
+// the failing WALG_PASSPHRASE_COMMAND of the private key is reported before anything is downloaded
+func configurePgpCrypter(key string,
+	newCrypter func(loadPassphrase func() ([]byte, bool, error)) crypto.Crypter) crypto.Crypter {
+	crypter, err := newPgpCrypter(key, newCrypter)
+	tracelog.ErrorLogger.FatalfOnError("Can't load the PGP key passphrase: %v", err)
+	return crypter
+}
+
+// newPgpCrypter builds the PGP crypter of the key by newCrypter. If the passphrase is obtained
+// by the WALG_PASSPHRASE_COMMAND, the crypter is reused by the later calls and the private key
+// is decrypted at once, so the command is validated even if nothing is to be decrypted.
+func newPgpCrypter(key string,
+	newCrypter func(loadPassphrase func() ([]byte, bool, error)) crypto.Crypter) (crypto.Crypter, error) {
+	command, isCommandSet := GetSetting(PassphraseCommandSetting)
+	if _, isPassphraseSet := GetSetting(PgpKeyPassphraseSetting); isPassphraseSet || !isCommandSet {
+		return newCrypter(loadPgpKeyPassphrase), nil
+	}
+	cacheKey := sha256.Sum256([]byte(key + "\n" + command))
+	crypter, ok := commandPassphraseCrypters.Load(cacheKey)
+	if !ok {
+		crypter, _ = commandPassphraseCrypters.LoadOrStore(cacheKey, newCrypter(loadPgpKeyPassphrase))
+	}
+	pgpCrypter, ok := crypter.(*openpgp.Crypter)
+	if !ok {
+		return crypter.(crypto.Crypter), nil
+	}
+	hasPrivateKey, err := pgpCrypter.HasPrivateKey()
+	if err != nil || !hasPrivateKey {
+		// the unreadable key is reported by the first encryption or decryption as before
+		return pgpCrypter, nil
+	}
+	// the decrypted key is not loaded again, the failed one is
+	return pgpCrypter, pgpCrypter.LoadSecretKey()
+}
+
+// loadPgpKeyPassphrase returns the passphrase of the PGP private key set by the WALG_PGP_KEY_PASSPHRASE
+// or printed by the WALG_PASSPHRASE_COMMAND otherwise. The returned copy should be zeroed by the caller.
+func loadPgpKeyPassphrase() ([]byte, bool, error) {
+	if passphrase, ok := GetSetting(PgpKeyPassphraseSetting); ok {
+		return []byte(passphrase), true, nil
+	}
+	if _, ok := GetSetting(PassphraseCommandSetting); !ok {
+		return nil, false, nil
+	}
+	passphrase, err := runPassphraseCommand()
+	return passphrase, err == nil, err
+}
+
+// runPassphraseCommand executes the WALG_PASSPHRASE_COMMAND and returns the copy of its trimmed stdout,
+// the stderr is logged if the command fails. The output buffer is zeroed once the passphrase is copied from it.
+func runPassphraseCommand() ([]byte, error) {
+	cmd, err := GetCommandSetting(PassphraseCommandSetting)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	defer func() { zeroBytes(stdout.Bytes()) }()
+
+	if err = cmd.Run(); err != nil {
+		tracelog.ErrorLogger.Printf("%s stderr:\n%s", PassphraseCommandSetting, stderr.String())
+		return nil, errors.Wrapf(err, "failed to obtain the PGP key passphrase by %s", PassphraseCommandSetting)
+	}
+	trimmed := bytes.TrimSpace(stdout.Bytes())
+	if len(trimmed) == 0 {
+		return nil, errors.Errorf("%s printed the empty PGP key passphrase", PassphraseCommandSetting)
+	}
+	return append([]byte(nil), trimmed...), nil
+}
+
+func zeroBytes(buffer []byte) {
+	for i := range buffer {
+		buffer[i] = 0
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/crypto"
+	walgopenpgp "github.com/wal-g/wal-g/internal/crypto/openpgp"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func TestRunPassphraseCommand(t *testing.T) {
+	defer viper.Set(PassphraseCommandSetting, nil)
+
+	viper.Set(PassphraseCommandSetting, "printf '  secret\\n'")
+	passphrase, err := runPassphraseCommand()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), passphrase)
+
+	viper.Set(PassphraseCommandSetting, "echo locked >&2; exit 3")
+	_, err = runPassphraseCommand()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), PassphraseCommandSetting)
+
+	viper.Set(PassphraseCommandSetting, "true")
+	_, err = runPassphraseCommand()
+	assert.Error(t, err)
+}
+
+func TestLoadPgpKeyPassphrase_PrefersSetting(t *testing.T) {
+	defer func() {
+		viper.Set(PgpKeyPassphraseSetting, nil)
+		viper.Set(PassphraseCommandSetting, nil)
+	}()
+
+	passphrase, ok, err := loadPgpKeyPassphrase()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, passphrase)
+
+	viper.Set(PgpKeyPassphraseSetting, "from setting")
+	viper.Set(PassphraseCommandSetting, "exit 1")
+	passphrase, ok, err = loadPgpKeyPassphrase()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("from setting"), passphrase)
+}
+
+func TestConfigureCrypter_RunsPassphraseCommandOnceForPrivateKey(t *testing.T) {
+	runsPath := filepath.Join(t.TempDir(), "runs")
+	viper.Set(PgpKeyPathSetting, "../test/testdata/waleGpgKey")
+	viper.Set(PassphraseCommandSetting, fmt.Sprintf("echo run >> %s; printf secret", runsPath))
+	defer func() {
+		viper.Set(PgpKeyPathSetting, nil)
+		viper.Set(PassphraseCommandSetting, nil)
+	}()
+
+	crypter := ConfigureCrypter()
+	// the passphrase of the private key is checked before anything is downloaded
+	runs, err := os.ReadFile(runsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(runs), "run"))
+
+	var encrypted bytes.Buffer
+	writer, err := crypter.Encrypt(&encrypted)
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("wal segment"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	for i := 0; i < 2; i++ {
+		reader, err := ConfigureCrypter().Decrypt(bytes.NewReader(encrypted.Bytes()))
+		assert.NoError(t, err)
+		decrypted, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "wal segment", string(decrypted))
+	}
+	runs, err = os.ReadFile(runsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(runs), "run"))
+}
+
+func TestConfigureCrypter_DoesNotRunPassphraseCommandForPublicKey(t *testing.T) {
+	entityList, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(readTestFile(t, "../test/testdata/waleGpgKey")))
+	assert.NoError(t, err)
+	var publicKey bytes.Buffer
+	armorWriter, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, entityList[0].Serialize(armorWriter))
+	assert.NoError(t, armorWriter.Close())
+	dir := t.TempDir()
+	publicKeyPath, runsPath := filepath.Join(dir, "public"), filepath.Join(dir, "runs")
+	assert.NoError(t, os.WriteFile(publicKeyPath, publicKey.Bytes(), 0600))
+
+	viper.Set(PgpKeyPathSetting, publicKeyPath)
+	viper.Set(PassphraseCommandSetting, fmt.Sprintf("echo run >> %s; printf secret", runsPath))
+	defer func() {
+		viper.Set(PgpKeyPathSetting, nil)
+		viper.Set(PassphraseCommandSetting, nil)
+	}()
+
+	writer, err := ConfigureCrypter().Encrypt(&bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	_, err = os.Stat(runsPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewPgpCrypter_FailingPassphraseCommand(t *testing.T) {
+	viper.Set(PassphraseCommandSetting, "exit 1")
+	defer viper.Set(PassphraseCommandSetting, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err := newPgpCrypter(PgpKeyPathSetting+"=../test/testdata/waleGpgKey",
+			func(loadPassphrase func() ([]byte, bool, error)) crypto.Crypter {
+				return walgopenpgp.CrypterFromKeyPath("../test/testdata/waleGpgKey", loadPassphrase)
+			})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), PassphraseCommandSetting)
+	}
+}
+
+func readTestFile(t *testing.T, filePath string) []byte {
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	return content
+}
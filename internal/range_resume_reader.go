@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// rangeResumeReader reopens the object since the offset already read if the reading fails in the middle,
+// so the stored bytes are not downloaded again. The decryption and decompression above it see the continuous stream.
+// The range reads are pinned to the version read first, so the reading fails if the object is overwritten.
+type rangeResumeReader struct {
+	folder     storage.RangeReader
+	objectPath string
+	version    string
+	reader     io.ReadCloser
+	offset     int64
+	// resumes is the number of resumptions left
+	resumes int
+}
+
+// ReadObjectResumable opens the object to resume its failed reading by the range requests up to maxResumes times.
+// The object is read as is if the folder does not support them, so the failed download is to be restarted.
+func ReadObjectResumable(folder storage.Folder, objectPath string, maxResumes int) (io.ReadCloser, error) {
+	rangeFolder, ok := folder.(storage.RangeReader)
+	if !ok || maxResumes <= 0 {
+		return folder.ReadObject(objectPath)
+	}
+	reader, version, err := rangeFolder.ReadObjectVersion(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeResumeReader{folder: rangeFolder, objectPath: objectPath, version: version, reader: reader,
+		resumes: maxResumes}, nil
+}
+
+func (reader *rangeResumeReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.offset += int64(n)
+	if err == nil || err == io.EOF || reader.resumes == 0 {
+		return n, err
+	}
+
+	reader.resumes--
+	tracelog.WarningLogger.Printf("Reading '%s' failed at byte %d, resuming: %v", reader.objectPath, reader.offset, err)
+	utility.LoggedClose(reader.reader, "")
+	resumed, resumeErr := reader.folder.ReadObjectRange(reader.objectPath, reader.offset, reader.version)
+	if resumeErr != nil {
+		tracelog.WarningLogger.Printf("Failed to resume reading '%s': %v", reader.objectPath, resumeErr)
+		if _, ok := errors.Cause(resumeErr).(storage.ObjectChangedError); ok {
+			// the rest of the other object version must not be joined to the read bytes
+			err = resumeErr
+		}
+		reader.reader, reader.resumes = io.NopCloser(errorReader{err}), 0
+		return n, err
+	}
+	reader.reader = resumed
+	if n == 0 {
+		return reader.Read(p)
+	}
+	return n, nil
+}
+
+func (reader *rangeResumeReader) Close() error {
+	return reader.reader.Close()
+}
+
+type errorReader struct {
+	err error
+}
+
+func (reader errorReader) Read([]byte) (int, error) {
+	return 0, reader.err
+}
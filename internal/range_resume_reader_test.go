@@ -0,0 +1,151 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// droppingReader fails after limit bytes as the dropped connection does
+type droppingReader struct {
+	reader io.Reader
+	limit  int
+}
+
+func (reader *droppingReader) Read(p []byte) (int, error) {
+	if reader.limit == 0 {
+		return 0, errors.New("connection reset by peer")
+	}
+	if len(p) > reader.limit {
+		p = p[:reader.limit]
+	}
+	n, err := reader.reader.Read(p)
+	reader.limit -= n
+	return n, err
+}
+
+// droppingFolder drops each read of the object after dropAfter bytes
+type droppingFolder struct {
+	*memory.Folder
+	dropAfter    int
+	rangeOffsets []int64
+	// beforeResume is called before each resumed read
+	beforeResume func()
+}
+
+func (folder *droppingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.ReadObjectRange(objectRelativePath, 0, "")
+}
+
+func (folder *droppingFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	folder.rangeOffsets = append(folder.rangeOffsets, 0)
+	reader, version, err := folder.Folder.ReadObjectVersion(objectRelativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return io.NopCloser(&droppingReader{reader: reader, limit: folder.dropAfter}), version, nil
+}
+
+func (folder *droppingFolder) ReadObjectRange(objectRelativePath string, offset int64,
+	version string) (io.ReadCloser, error) {
+	if offset > 0 && folder.beforeResume != nil {
+		folder.beforeResume()
+	}
+	folder.rangeOffsets = append(folder.rangeOffsets, offset)
+	reader, err := folder.Folder.ReadObjectRange(objectRelativePath, offset, version)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&droppingReader{reader: reader, limit: folder.dropAfter}), nil
+}
+
+// noRangeFolder hides the range requests support
+type noRangeFolder struct {
+	storage.Folder
+}
+
+func putCompressedObject(t *testing.T, folder storage.Folder, name string, data []byte) {
+	var compressed bytes.Buffer
+	writer := compression.Compressors[lz4.AlgorithmName].NewWriter(&compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, folder.PutObject(name, &compressed))
+}
+
+func randomTestData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func downloadAll(folder storage.Folder, name string) ([]byte, error) {
+	reader, err := internal.DownloadFileReader(folder, name, lz4.FileExtension)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func TestDownloadFileReader_ResumesByRange(t *testing.T) {
+	data := randomTestData(1 << 16)
+	folder := &droppingFolder{Folder: memory.NewFolder("", memory.NewStorage()), dropAfter: 10000}
+	putCompressedObject(t, folder.Folder, "archive.lz4", data)
+
+	_, err := downloadAll(folder, "archive.lz4")
+	assert.Error(t, err, "resumption must be opt-in")
+
+	viper.Set(internal.DownloadRangeResumesSetting, 100)
+	defer viper.Set(internal.DownloadRangeResumesSetting, nil)
+	folder.rangeOffsets = nil
+	downloaded, err := downloadAll(folder, "archive.lz4")
+	assert.NoError(t, err)
+	assert.Equal(t, data, downloaded)
+	assert.Greater(t, len(folder.rangeOffsets), 2)
+	for i, offset := range folder.rangeOffsets {
+		assert.Equal(t, int64(i*folder.dropAfter), offset)
+	}
+
+	viper.Set(internal.DownloadRangeResumesSetting, 1)
+	_, err = downloadAll(folder, "archive.lz4")
+	assert.Error(t, err)
+}
+
+func TestDownloadFileReader_NoRangeSupport(t *testing.T) {
+	viper.Set(internal.DownloadRangeResumesSetting, 100)
+	defer viper.Set(internal.DownloadRangeResumesSetting, nil)
+
+	folder := &droppingFolder{Folder: memory.NewFolder("", memory.NewStorage()), dropAfter: 100}
+	putCompressedObject(t, folder.Folder, "archive.lz4", randomTestData(1000))
+
+	_, err := downloadAll(noRangeFolder{folder}, "archive.lz4")
+	assert.Error(t, err)
+	assert.Equal(t, []int64{0}, folder.rangeOffsets)
+}
+
+func TestDownloadFileReader_FailsIfObjectChanged(t *testing.T) {
+	viper.Set(internal.DownloadRangeResumesSetting, 100)
+	defer viper.Set(internal.DownloadRangeResumesSetting, nil)
+
+	folder := &droppingFolder{Folder: memory.NewFolder("", memory.NewStorage()), dropAfter: 10000}
+	putCompressedObject(t, folder.Folder, "archive.lz4", randomTestData(1<<16))
+	folder.beforeResume = func() {
+		folder.beforeResume = nil
+		putCompressedObject(t, folder.Folder, "archive.lz4", randomTestData(1<<15))
+	}
+
+	_, err := downloadAll(folder, "archive.lz4")
+	assert.Error(t, err)
+	assert.Equal(t, []int64{0, 10000}, folder.rangeOffsets)
+}
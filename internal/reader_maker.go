@@ -18,6 +18,13 @@ type ReaderMaker interface {
 	Mode() int
 }
 
+// RangeReaderMaker is the ReaderMaker able to read the stored file since the byte offset
+type RangeReaderMaker interface {
+	ReaderMaker
+	SupportsRanges() bool
+	ReadRange(offset int64) (io.ReadCloser, error)
+}
+
 func readerMakersToFilePaths(readerMakers []ReaderMaker) []string {
 	paths := make([]string, 0)
 	for _, readerMaker := range readerMakers {
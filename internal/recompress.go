@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// RecompressedObject is the object compressed by the other method and its copy recompressed by the target one,
+// the paths are relative to the recompressed folder. The sizes and the checksum are not known in the dry run.
+type RecompressedObject struct {
+	Path    string `json:"Path"`
+	NewPath string `json:"NewPath"`
+	// Size and SHA256 describe the decompressed contents, which are the same for both objects
+	Size           int64  `json:"Size,omitempty"`
+	SHA256         string `json:"SHA256,omitempty"`
+	CompressedSize int64  `json:"CompressedSize,omitempty"`
+}
+
+// RecompressionMismatchError is returned if the recompressed object read back differs from the source one
+type RecompressionMismatchError struct {
+	error
+}
+
+func newRecompressionMismatchError(objectPath string, expectedSize, actualSize int64,
+	expectedSHA256, actualSHA256 string) RecompressionMismatchError {
+	return RecompressionMismatchError{errors.Errorf(
+		"recompressed object '%s' does not match the source: %d bytes with sha256 %s, read back %d bytes with sha256 %s",
+		objectPath, expectedSize, expectedSHA256, actualSize, actualSHA256)}
+}
+
+func (err RecompressionMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RecompressedPath returns the path of the object recompressed by the compressor: its compression extension
+// is replaced by the one of the compressor. The object is not recompressed if it is not compressed
+// by a known method, e.g. the sentinel, or it is compressed by the compressor already.
+func RecompressedPath(objectPath string, compressor compression.Compressor) (string, bool) {
+	ext := strings.TrimPrefix(path.Ext(objectPath), ".")
+	if ext == "" || ext == compressor.FileExtension() || compression.FindDecompressor(ext) == nil {
+		return "", false
+	}
+	return strings.TrimSuffix(objectPath, ext) + compressor.FileExtension(), true
+}
+
+// RecompressObject streams the object through the decompressor detected by its leading bytes and the compressor
+// to the RecompressedPath, neither of the objects is buffered as a whole. The new object is read back
+// and its decompressed size and checksum are compared with the source ones, the mismatching object is deleted.
+// The source object is kept, so the caller deletes it once the new one is referenced.
+func RecompressObject(folder storage.Folder, objectPath string, compressor compression.Compressor) (RecompressedObject, error) {
+	newPath, ok := RecompressedPath(objectPath, compressor)
+	if !ok {
+		return RecompressedObject{}, errors.Errorf("object '%s' is not compressed by the other known method", objectPath)
+	}
+	object := RecompressedObject{Path: objectPath, NewPath: newPath}
+	source, err := DownloadFileReader(folder, objectPath, path.Ext(objectPath))
+	if err != nil {
+		return object, err
+	}
+	defer utility.LoggedClose(source, "")
+
+	sourceHash := sha256.New()
+	content := io.TeeReader(NewWithSizeReader(source, &object.Size), sourceHash)
+	compressed := NewWithSizeReader(CompressAndEncrypt(content, compressor, ConfigureCrypter()), &object.CompressedSize)
+	if err = folder.PutObject(newPath, compressed); err != nil {
+		deleteRecompressedObjects(folder, []RecompressedObject{object})
+		return object, errors.Wrapf(err, "failed to upload the recompressed object '%s'", newPath)
+	}
+	object.SHA256 = hex.EncodeToString(sourceHash.Sum(nil))
+
+	if err = verifyRecompressedObject(folder, object, compressor); err != nil {
+		deleteRecompressedObjects(folder, []RecompressedObject{object})
+		return object, err
+	}
+	tracelog.InfoLogger.Printf("Recompressed '%s' to '%s': %d bytes, sha256 %s", objectPath, newPath,
+		object.Size, object.SHA256)
+	return object, nil
+}
+
+func verifyRecompressedObject(folder storage.Folder, object RecompressedObject, compressor compression.Compressor) error {
+	reader, exists, err := TryDownloadFile(folder, object.NewPath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("recompressed object '%s' is not found", object.NewPath)
+	}
+	defer utility.LoggedClose(reader, "")
+	decompressed, err := DecompressDecryptBytes(reader, compression.GetDecompressorByCompressor(compressor))
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(decompressed, "")
+
+	var size int64
+	readBackHash := sha256.New()
+	if _, err = io.Copy(readBackHash, NewWithSizeReader(decompressed, &size)); err != nil {
+		return errors.Wrapf(err, "failed to read back the recompressed object '%s'", object.NewPath)
+	}
+	readBackSHA256 := hex.EncodeToString(readBackHash.Sum(nil))
+	if size != object.Size || readBackSHA256 != object.SHA256 {
+		return newRecompressionMismatchError(object.NewPath, object.Size, size, object.SHA256, readBackSHA256)
+	}
+	return nil
+}
+
+// RecompressFolder recompresses the objects of the folder and its subfolders compressed by the other method
+// than the compressor one by one. Once all of them are recompressed, commit is called, e.g. to reference
+// the new objects in the sentinel, and the source objects are deleted. If the recompression or the commit fails,
+// the new objects are deleted and the source ones are kept. The objects are only listed in the dry run.
+// The commit may be nil, it is not called if there is nothing to recompress.
+func RecompressFolder(folder storage.Folder, compressor compression.Compressor, dryRun bool,
+	commit func(objects []RecompressedObject) error) ([]RecompressedObject, error) {
+	objects, err := storage.ListFolderRecursively(folder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list '%s'", folder.GetPath())
+	}
+	paths := make([]string, 0, len(objects))
+	for _, object := range objects {
+		if _, ok := RecompressedPath(object.GetName(), compressor); ok {
+			paths = append(paths, object.GetName())
+		}
+	}
+	sort.Strings(paths)
+
+	recompressed := make([]RecompressedObject, 0, len(paths))
+	for _, objectPath := range paths {
+		if dryRun {
+			newPath, _ := RecompressedPath(objectPath, compressor)
+			recompressed = append(recompressed, RecompressedObject{Path: objectPath, NewPath: newPath})
+			continue
+		}
+		object, err := RecompressObject(folder, objectPath, compressor)
+		if err != nil {
+			deleteRecompressedObjects(folder, recompressed)
+			return nil, err
+		}
+		recompressed = append(recompressed, object)
+	}
+	if dryRun || len(recompressed) == 0 {
+		return recompressed, nil
+	}
+
+	if commit != nil {
+		if err = commit(recompressed); err != nil {
+			deleteRecompressedObjects(folder, recompressed)
+			return nil, err
+		}
+	}
+	sourcePaths := make([]string, 0, len(recompressed))
+	for _, object := range recompressed {
+		sourcePaths = append(sourcePaths, object.Path)
+	}
+	if err = folder.DeleteObjects(sourcePaths); err != nil {
+		return recompressed, errors.Wrapf(err, "failed to delete the objects replaced by the recompressed ones")
+	}
+	return recompressed, nil
+}
+
+// deleteRecompressedObjects deletes the new objects of the failed recompression, the failure is only logged,
+// since the source objects are intact
+func deleteRecompressedObjects(folder storage.Folder, objects []RecompressedObject) {
+	if len(objects) == 0 {
+		return
+	}
+	newPaths := make([]string, 0, len(objects))
+	for _, object := range objects {
+		newPaths = append(newPaths, object.NewPath)
+	}
+	if err := folder.DeleteObjects(newPaths); err != nil {
+		tracelog.WarningLogger.Printf("Failed to delete the recompressed objects %v: %v", newPaths, err)
+	}
+}
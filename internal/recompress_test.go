@@ -0,0 +1,129 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func putRecompressSource(t *testing.T, folder storage.Folder, name string, compressor compression.Compressor, content string) {
+	assert.NoError(t, folder.PutObject(name, internal.CompressAndEncrypt(strings.NewReader(content), compressor, nil)))
+}
+
+func readDecompressedObject(t *testing.T, folder storage.Folder, name string) string {
+	reader, err := internal.DownloadFileReader(folder, name, "")
+	assert.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func newRecompressFixture(t *testing.T) storage.Folder {
+	folder := memory.NewFolder("", memory.NewStorage())
+	putRecompressSource(t, folder, "backup_1/stream.lz4", compression.Compressors[lz4.AlgorithmName],
+		strings.Repeat("first backup ", 1000))
+	putRecompressSource(t, folder, "backup_2/stream.lzma", compression.Compressors[lzma.AlgorithmName],
+		strings.Repeat("second backup ", 1000))
+	putRecompressSource(t, folder, "backup_3/stream.gz", compression.Compressors[gzip.AlgorithmName], "third backup")
+	assert.NoError(t, folder.PutObject("backup_1_sentinel.json", bytes.NewBufferString("{}")))
+	return folder
+}
+
+func listObjectNames(t *testing.T, folder storage.Folder) []string {
+	objects, err := storage.ListFolderRecursively(folder)
+	assert.NoError(t, err)
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	return names
+}
+
+func TestRecompressFolder(t *testing.T) {
+	folder := newRecompressFixture(t)
+	compressor := compression.Compressors[gzip.AlgorithmName]
+
+	var committed []internal.RecompressedObject
+	objects, err := internal.RecompressFolder(folder, compressor, false, func(objects []internal.RecompressedObject) error {
+		// the sources are kept until the commit
+		assert.Contains(t, listObjectNames(t, folder), "backup_1/stream.lz4")
+		committed = objects
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, committed, objects)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, "backup_1/stream.lz4", objects[0].Path)
+	assert.Equal(t, "backup_1/stream.gz", objects[0].NewPath)
+	assert.Equal(t, int64(len(strings.Repeat("first backup ", 1000))), objects[0].Size)
+	assert.NotZero(t, objects[0].CompressedSize)
+
+	assert.ElementsMatch(t, []string{"backup_1/stream.gz", "backup_2/stream.gz", "backup_3/stream.gz",
+		"backup_1_sentinel.json"}, listObjectNames(t, folder))
+	assert.Equal(t, strings.Repeat("first backup ", 1000), readDecompressedObject(t, folder, "backup_1/stream.gz"))
+	assert.Equal(t, strings.Repeat("second backup ", 1000), readDecompressedObject(t, folder, "backup_2/stream.gz"))
+	assert.Equal(t, "third backup", readDecompressedObject(t, folder, "backup_3/stream.gz"))
+}
+
+func TestRecompressFolder_DryRun(t *testing.T) {
+	folder := newRecompressFixture(t)
+	before := listObjectNames(t, folder)
+
+	objects, err := internal.RecompressFolder(folder, compression.Compressors[gzip.AlgorithmName], true,
+		func(objects []internal.RecompressedObject) error {
+			t.Fatal("commit must not be called in the dry run")
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.RecompressedObject{
+		{Path: "backup_1/stream.lz4", NewPath: "backup_1/stream.gz"},
+		{Path: "backup_2/stream.lzma", NewPath: "backup_2/stream.gz"},
+	}, objects)
+	assert.ElementsMatch(t, before, listObjectNames(t, folder))
+}
+
+func TestRecompressFolder_FailedCommitKeepsSources(t *testing.T) {
+	folder := newRecompressFixture(t)
+	before := listObjectNames(t, folder)
+
+	_, err := internal.RecompressFolder(folder, compression.Compressors[gzip.AlgorithmName], false,
+		func(objects []internal.RecompressedObject) error {
+			return errors.New("sentinel upload failed")
+		})
+	assert.Error(t, err)
+	assert.ElementsMatch(t, before, listObjectNames(t, folder))
+	assert.Equal(t, strings.Repeat("first backup ", 1000), readDecompressedObject(t, folder, "backup_1/stream.lz4"))
+}
+
+func TestRecompressObject_CorruptSource(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	assert.NoError(t, folder.PutObject("stream.lz4", bytes.NewBufferString("not lz4 at all")))
+
+	_, err := internal.RecompressObject(folder, "stream.lz4", compression.Compressors[gzip.AlgorithmName])
+	assert.Error(t, err)
+	assert.Equal(t, []string{"stream.lz4"}, listObjectNames(t, folder))
+}
+
+func TestRecompressedPath(t *testing.T) {
+	compressor := compression.Compressors[gzip.AlgorithmName]
+	newPath, ok := internal.RecompressedPath("base/stream.lz4", compressor)
+	assert.True(t, ok)
+	assert.Equal(t, "base/stream.gz", newPath)
+
+	for _, objectPath := range []string{"base/stream.gz", "base/sentinel.json", "base/stream"} {
+		_, ok = internal.RecompressedPath(objectPath, compressor)
+		assert.False(t, ok, objectPath)
+	}
+}
@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"archive/tar"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ReopenableReader is the tar entry contents reader which can restart reading from the beginning of the entry,
+// e.g. once the storage stream breaks in the middle of the entry. The interpreters may Reopen it
+// to retry the failed copy instead of failing the whole tar.
+type ReopenableReader interface {
+	io.Reader
+	// Reopen reads the tar from the storage again and skips to the beginning of the current entry
+	Reopen() error
+}
+
+// IsTransientReadError reports if the read failure is caused by the broken storage stream
+// and may not repeat once the object is read again
+func IsTransientReadError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// EntryRangeReader is the tar entry contents reader able to read the ranges of the contents
+// straight from the storage, concurrently with each other
+type EntryRangeReader interface {
+	io.Reader
+	// SupportsRanges is false if the tar is compressed or encrypted, or the storage can not read the ranges
+	SupportsRanges() bool
+	// ReadRange reads length bytes of the entry contents since the offset
+	ReadRange(offset, length int64) (io.ReadCloser, error)
+	// SkipContents continues the tar after the current entry without downloading its contents,
+	// the entry contents are not available to Read afterwards
+	SkipContents() error
+}
+
+// reopenableTarReader is the tar.Reader which reopens the tar by reopenSource on Reopen
+// and continues reading the entries from the reopened source
+type reopenableTarReader struct {
+	source       io.Reader
+	tarReader    *tar.Reader
+	reopenSource func() (io.ReadCloser, error)
+	// reopened is the source opened by Reopen, it is closed on the next Reopen or on Close
+	reopened io.Closer
+	// entryIndex is the number of the current entry in the tar, -1 before the first Next
+	entryIndex int
+	entryName  string
+	// readRange reads the raw tar since the offset, it is nil if the tar can not be read by ranges
+	readRange func(offset int64) (io.ReadCloser, error)
+	// consumed counts the bytes of the tar read through source, it is nil if readRange is nil
+	consumed       *offsetCountingReader
+	entryOffset    int64
+	entrySize      int64
+	entryHasRanges bool
+}
+
+var _ ReopenableReader = &reopenableTarReader{}
+var _ EntryRangeReader = &reopenableTarReader{}
+
+func newReopenableTarReader(source io.Reader, reopenSource func() (io.ReadCloser, error),
+	readRange func(offset int64) (io.ReadCloser, error)) *reopenableTarReader {
+	reader := &reopenableTarReader{reopenSource: reopenSource, readRange: readRange, entryIndex: -1}
+	reader.setSource(source, 0)
+	return reader
+}
+
+func (reader *reopenableTarReader) setSource(source io.Reader, offset int64) {
+	if reader.readRange != nil {
+		reader.consumed = &offsetCountingReader{reader: source, offset: offset}
+		source = reader.consumed
+	}
+	reader.source, reader.tarReader = source, tar.NewReader(source)
+}
+
+// Next advances to the next entry of the tar
+func (reader *reopenableTarReader) Next() (*tar.Header, error) {
+	header, err := reader.tarReader.Next()
+	if err == nil {
+		reader.entryIndex++
+		reader.entryName = header.Name
+		reader.entryHasRanges = reader.consumed != nil && isContiguousRegularFile(header)
+		if reader.entryHasRanges {
+			// the tar.Reader reads the whole header blocks, so the contents start right after the consumed bytes
+			reader.entryOffset, reader.entrySize = reader.consumed.offset, header.Size
+		}
+	}
+	return header, err
+}
+
+func isContiguousRegularFile(header *tar.Header) bool {
+	if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+		return false
+	}
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return false
+		}
+	}
+	return true
+}
+
+func (reader *reopenableTarReader) Read(p []byte) (int, error) {
+	return reader.tarReader.Read(p)
+}
+
+func (reader *reopenableTarReader) Reopen() error {
+	if reader.reopenSource == nil || reader.entryIndex < 0 {
+		return errors.New("tar entry reader can not be reopened")
+	}
+	source, err := reader.reopenSource()
+	if err != nil {
+		return errors.Wrap(err, "failed to reopen the tar")
+	}
+	reopened := &reopenableTarReader{readRange: reader.readRange}
+	reopened.setSource(source, 0)
+	var header *tar.Header
+	for i := 0; i <= reader.entryIndex; i++ {
+		if header, err = reopened.tarReader.Next(); err != nil {
+			utility.LoggedClose(source, "")
+			return errors.Wrapf(err, "failed to skip to the entry '%s' of the reopened tar", reader.entryName)
+		}
+	}
+	if header.Name != reader.entryName {
+		utility.LoggedClose(source, "")
+		return errors.Errorf("reopened tar has the entry '%s' instead of '%s'", header.Name, reader.entryName)
+	}
+	reader.closeReopened()
+	reader.source, reader.tarReader, reader.consumed, reader.reopened =
+		reopened.source, reopened.tarReader, reopened.consumed, source
+	return nil
+}
+
+func (reader *reopenableTarReader) SupportsRanges() bool {
+	return reader.entryHasRanges
+}
+
+func (reader *reopenableTarReader) ReadRange(offset, length int64) (io.ReadCloser, error) {
+	if !reader.entryHasRanges {
+		return nil, errors.New("tar entry can not be read by ranges")
+	}
+	if offset < 0 || length < 0 || offset+length > reader.entrySize {
+		return nil, errors.Errorf("range [%d, %d) is out of the tar entry '%s' of size %d",
+			offset, offset+length, reader.entryName, reader.entrySize)
+	}
+	source, err := reader.readRange(reader.entryOffset + offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the range of the tar entry '%s'", reader.entryName)
+	}
+	return &ioextensions.ReadCascadeCloser{Reader: io.LimitReader(source, length), Closer: source}, nil
+}
+
+func (reader *reopenableTarReader) SkipContents() error {
+	if !reader.entryHasRanges {
+		return errors.New("tar entry contents can not be skipped")
+	}
+	nextHeaderOffset := reader.entryOffset + (reader.entrySize+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	source, err := reader.readRange(nextHeaderOffset)
+	if err != nil {
+		return errors.Wrapf(err, "failed to skip the contents of the tar entry '%s'", reader.entryName)
+	}
+	reader.closeReopened()
+	reader.setSource(source, nextHeaderOffset)
+	reader.reopened = source
+	reader.entryHasRanges = false
+	return nil
+}
+
+// Close closes the source opened by Reopen, the original source is closed by its owner
+func (reader *reopenableTarReader) Close() error {
+	reader.closeReopened()
+	return nil
+}
+
+func (reader *reopenableTarReader) closeReopened() {
+	if reader.reopened != nil {
+		utility.LoggedClose(reader.reopened, "")
+		reader.reopened = nil
+	}
+}
+
+const tarBlockSize = 512
+
+// offsetCountingReader counts the offset of the next byte to read in the raw tar
+type offsetCountingReader struct {
+	reader io.Reader
+	offset int64
+}
+
+func (reader *offsetCountingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.offset += int64(n)
+	return n, err
+}
+
+// newTarSourceReopener reads the file from the storage again,
+// decrypting and decompressing it the same way as the original source
+func newTarSourceReopener(fileClosure ReaderMaker, crypter crypto.Crypter) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		readCloser, err := fileClosure.Reader()
+		if err != nil {
+			return nil, err
+		}
+		extractingReader, err := DecryptAndDecompressTar(readCloser, fileClosure.Path(), crypter)
+		if err != nil {
+			utility.LoggedClose(readCloser, "")
+			return nil, err
+		}
+		return &ioextensions.ReadCascadeCloser{
+			Reader: extractingReader,
+			Closer: ioextensions.NewMultiCloser([]io.Closer{extractingReader, readCloser}),
+		}, nil
+	}
+}
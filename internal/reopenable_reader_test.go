@@ -0,0 +1,180 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/none"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+// flakyTarReaderMaker breaks the first read of the tar after dropAfter bytes
+type flakyTarReaderMaker struct {
+	data      []byte
+	dropAfter int
+	opened    int
+}
+
+func (readerMaker *flakyTarReaderMaker) Reader() (io.ReadCloser, error) {
+	readerMaker.opened++
+	if readerMaker.opened == 1 {
+		return io.NopCloser(io.MultiReader(bytes.NewReader(readerMaker.data[:readerMaker.dropAfter]),
+			iotest.ErrReader(io.ErrUnexpectedEOF))), nil
+	}
+	return io.NopCloser(bytes.NewReader(readerMaker.data)), nil
+}
+
+func (readerMaker *flakyTarReaderMaker) Path() string { return "flaky.tar" }
+
+func (readerMaker *flakyTarReaderMaker) FileType() internal.FileType { return internal.TarFileType }
+
+func (readerMaker *flakyTarReaderMaker) Mode() int { return 0 }
+
+// retryingTarInterpreter reopens the entry once its read fails with the transient error
+type retryingTarInterpreter struct {
+	contents map[string][]byte
+	reopened int
+}
+
+func (interpreter *retryingTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	content, err := io.ReadAll(reader)
+	for err != nil && internal.IsTransientReadError(err) && interpreter.reopened == 0 {
+		interpreter.reopened++
+		if err = reader.(internal.ReopenableReader).Reopen(); err != nil {
+			return err
+		}
+		content, err = io.ReadAll(reader)
+	}
+	interpreter.contents[header.Name] = content
+	return err
+}
+
+func makeTestTar(t *testing.T, contents map[string][]byte, names ...string) []byte {
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, name := range names {
+		assert.NoError(t, writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0600,
+			Size: int64(len(contents[name]))}))
+		_, err := writer.Write(contents[name])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestExtractAll_ReopensBrokenTarEntry(t *testing.T) {
+	contents := map[string][]byte{"first": randomTestData(4096), "second": randomTestData(8192), "third": []byte("third")}
+	data := makeTestTar(t, contents, "first", "second", "third")
+	// the stream breaks in the middle of the second entry
+	readerMaker := &flakyTarReaderMaker{data: data, dropAfter: 512 + 4096 + 512 + 1000}
+	interpreter := &retryingTarInterpreter{contents: map[string][]byte{}}
+
+	err := internal.ExtractAllWithContext(context.Background(), interpreter, []internal.ReaderMaker{readerMaker}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, readerMaker.opened)
+	assert.Equal(t, 1, interpreter.reopened)
+	assert.Equal(t, contents, interpreter.contents)
+}
+
+// rangingTarInterpreter reads the entries supporting the ranges in two halves, last half first,
+// and skips their contents in the tar
+type rangingTarInterpreter struct {
+	contents map[string][]byte
+	ranged   []string
+}
+
+func (interpreter *rangingTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	rangeReader, ok := reader.(internal.EntryRangeReader)
+	if !ok || !rangeReader.SupportsRanges() {
+		content, err := io.ReadAll(reader)
+		interpreter.contents[header.Name] = content
+		return err
+	}
+	half := header.Size / 2
+	second, err := readTestRange(rangeReader, half, header.Size-half)
+	if err != nil {
+		return err
+	}
+	first, err := readTestRange(rangeReader, 0, half)
+	if err != nil {
+		return err
+	}
+	interpreter.contents[header.Name] = append(first, second...)
+	interpreter.ranged = append(interpreter.ranged, header.Name)
+	return rangeReader.SkipContents()
+}
+
+func readTestRange(rangeReader internal.EntryRangeReader, offset, length int64) ([]byte, error) {
+	reader, err := rangeReader.ReadRange(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func TestExtractAll_ReadsRawTarEntriesByRanges(t *testing.T) {
+	contents := map[string][]byte{"first": randomTestData(1000), "second": randomTestData(8193), "third": []byte("third")}
+	folder := memory.NewFolder("", memory.NewStorage())
+	assert.NoError(t, folder.PutObject("base/part_1.tar",
+		bytes.NewReader(makeTestTar(t, contents, "first", "second", "third"))))
+	interpreter := &rangingTarInterpreter{contents: map[string][]byte{}}
+
+	err := internal.ExtractAllWithContext(context.Background(), interpreter,
+		[]internal.ReaderMaker{internal.NewStorageReaderMaker(folder, "base/part_1.tar")}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, interpreter.ranged)
+	assert.Equal(t, contents, interpreter.contents)
+}
+
+func TestExtractAll_ReadsNoneCompressedTarEntriesByRanges(t *testing.T) {
+	contents := map[string][]byte{"first": randomTestData(1000), "second": randomTestData(8193)}
+	folder := memory.NewFolder("", memory.NewStorage())
+	var compressed bytes.Buffer
+	writer := compression.Compressors[none.AlgorithmName].NewWriter(&compressed)
+	_, err := writer.Write(makeTestTar(t, contents, "first", "second"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, folder.PutObject("base/part_001.tar."+none.FileExtension, &compressed))
+	interpreter := &rangingTarInterpreter{contents: map[string][]byte{}}
+
+	err = internal.ExtractAllWithContext(context.Background(), interpreter,
+		[]internal.ReaderMaker{internal.NewStorageReaderMaker(folder, "base/part_001.tar."+none.FileExtension)}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, interpreter.ranged)
+	assert.Equal(t, contents, interpreter.contents)
+}
+
+func TestExtractAll_DoesNotReadCompressedTarByRanges(t *testing.T) {
+	contents := map[string][]byte{"first": randomTestData(1000)}
+	var compressed bytes.Buffer
+	writer := compression.Compressors[lz4.AlgorithmName].NewWriter(&compressed)
+	_, err := writer.Write(makeTestTar(t, contents, "first"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	folder := memory.NewFolder("", memory.NewStorage())
+	assert.NoError(t, folder.PutObject("base/part_1.tar.lz4", &compressed))
+	interpreter := &rangingTarInterpreter{contents: map[string][]byte{}}
+
+	err = internal.ExtractAllWithContext(context.Background(), interpreter,
+		[]internal.ReaderMaker{internal.NewStorageReaderMaker(folder, "base/part_1.tar.lz4")}, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, interpreter.ranged)
+	assert.Equal(t, contents, interpreter.contents)
+}
+
+func TestIsTransientReadError(t *testing.T) {
+	assert.True(t, internal.IsTransientReadError(io.ErrUnexpectedEOF))
+	assert.True(t, internal.IsTransientReadError(fmt.Errorf("copy failed: %w", io.ErrUnexpectedEOF)))
+	assert.False(t, internal.IsTransientReadError(io.EOF))
+	assert.False(t, internal.IsTransientReadError(fmt.Errorf("checksum mismatch")))
+}
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// StreamPartsRecord describes the uploaded part of the resumable stream
+type StreamPartsRecord struct {
+	Number int    `json:"number"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	// CompressedSHA256 is the digest of the stored part, so it is known when the upload is resumed
+	CompressedSHA256 string `json:"compressed_sha256,omitempty"`
+	// CompressedSize is the size of the stored part, so the uploaded data size covers the skipped parts
+	CompressedSize int64 `json:"compressed_size,omitempty"`
+}
+
+// StreamPartsSidecar lists the confirmed parts of the resumable stream,
+// it is updated after each uploaded part so the interrupted upload can be continued
+type StreamPartsSidecar struct {
+	PartSize    int                 `json:"part_size"`
+	Compression string              `json:"compression"`
+	Parts       []StreamPartsRecord `json:"parts"`
+}
+
+// ResumableStreamUploader - UploaderProvider implementation that splits the stream into parts of partSize bytes,
+// records the uploaded parts to the sidecar object and skips them when the upload is resumed with the resumeToken
+type ResumableStreamUploader struct {
+	*Uploader
+	partSize    int
+	resumeToken string
+	// unmeasuredParts is set if a skipped part was recorded without its compressed size
+	unmeasuredParts bool
+}
+
+var _ UploaderProvider = &ResumableStreamUploader{}
+
+func NewResumableStreamUploader(
+	compressor compression.Compressor,
+	uploadingLocation storage.Folder,
+	partSize int,
+	resumeToken string,
+) *ResumableStreamUploader {
+	uploader := &ResumableStreamUploader{
+		Uploader: &Uploader{
+			UploadingFolder: uploadingLocation,
+			Compressor:      compressor,
+			waitGroup:       &sync.WaitGroup{},
+			tarSize:         new(int64),
+			dataSize:        new(int64),
+			streamDigests:   newStreamDigests(),
+		},
+		partSize:    partSize,
+		resumeToken: resumeToken,
+	}
+	uploader.Failed.Store(false)
+	return uploader
+}
+
+// ResumeToken returns the name of the backup which upload is continued, empty for the new upload
+func (uploader *ResumableStreamUploader) ResumeToken() string {
+	return uploader.resumeToken
+}
+
+// UploadedDataSize returns the compressed size of the stream parts including the ones skipped on resume,
+// an error is returned if the size of a skipped part was not recorded by the previous attempt
+func (uploader *ResumableStreamUploader) UploadedDataSize() (int64, error) {
+	if uploader.unmeasuredParts {
+		return 0, fmt.Errorf("compressed size of the parts uploaded before resume is unknown")
+	}
+	return uploader.Uploader.UploadedDataSize()
+}
+
+// StreamPartsNameFromBackup returns the path of the resumable stream parts sidecar
+func StreamPartsNameFromBackup(backupName string) string {
+	return backupName + "/" + utility.StreamPartsFileName
+}
+
+// PushStream uploads the stream part by part and returns backup_prefix, which is also the resume token:
+// if the upload fails, it can be continued by the uploader created with this token
+func (uploader *ResumableStreamUploader) PushStream(stream io.Reader) (string, error) {
+	backupName := uploader.resumeToken
+	if backupName == "" {
+		backupName = StreamPrefix + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
+	}
+
+	sidecar, err := uploader.fetchPartsSidecar(backupName)
+	if err != nil {
+		return backupName, err
+	}
+
+	buf := make([]byte, uploader.partSize)
+	partNumber := 0
+	for ; ; partNumber++ {
+		n, readErr := io.ReadFull(stream, buf)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.ErrUnexpectedEOF {
+			return backupName, fmt.Errorf("failed to read part %d of stream: %w", partNumber, readErr)
+		}
+
+		if err = uploader.pushPart(backupName, sidecar, partNumber, buf[:n]); err != nil {
+			return backupName, err
+		}
+		if readErr == io.ErrUnexpectedEOF {
+			partNumber++
+			break
+		}
+	}
+	if partNumber < len(sidecar.Parts) {
+		return backupName, fmt.Errorf("can not resume upload of '%s': stream has %d parts, but %d parts are uploaded",
+			backupName, partNumber, len(sidecar.Parts))
+	}
+
+	// all parts are confirmed, upload StreamMetadata
+	meta := BackupStreamMetadata{
+		Type:        ResumableStreamBackup,
+		Partitions:  uint(partNumber),
+		BlockSize:   uint(uploader.partSize),
+		Compression: uploader.Compressor.FileExtension(),
+	}
+	err = UploadBackupStreamMetadata(uploader, meta, backupName)
+
+	return backupName, err
+}
+
+// pushPart uploads the part and records it to the sidecar, the already uploaded parts are skipped
+func (uploader *ResumableStreamUploader) pushPart(backupName string, sidecar *StreamPartsSidecar,
+	partNumber int, part []byte) error {
+	checksum := sha256.Sum256(part)
+	record := StreamPartsRecord{Number: partNumber, Size: len(part), SHA256: hex.EncodeToString(checksum[:])}
+
+	dstPath := GetPartitionedStreamName(backupName, uploader.Compressor.FileExtension(), partNumber)
+	if partNumber < len(sidecar.Parts) {
+		record.CompressedSHA256 = sidecar.Parts[partNumber].CompressedSHA256
+		record.CompressedSize = sidecar.Parts[partNumber].CompressedSize
+		if sidecar.Parts[partNumber] != record {
+			return fmt.Errorf("can not resume upload of '%s': part %d differs from the uploaded one",
+				backupName, partNumber)
+		}
+		tracelog.DebugLogger.Printf("Part %d of '%s' is already uploaded, skipping", partNumber, backupName)
+		if record.CompressedSHA256 != "" {
+			uploader.streamDigests.add(dstPath, record.CompressedSHA256)
+		}
+		uploader.addSkippedPartSize(record.CompressedSize)
+		return nil
+	}
+
+	tracelog.InfoLogger.Printf("Uploading... %v", dstPath)
+	sizeBefore, _ := uploader.Uploader.UploadedDataSize()
+	if err := uploader.PushStreamToDestination(bytes.NewReader(part), dstPath); err != nil {
+		return fmt.Errorf("failed to upload part %d of '%s': %w", partNumber, backupName, err)
+	}
+	sizeAfter, _ := uploader.Uploader.UploadedDataSize()
+
+	record.CompressedSHA256 = uploader.streamDigests.get(dstPath)
+	record.CompressedSize = sizeAfter - sizeBefore
+	sidecar.Parts = append(sidecar.Parts, record)
+	if err := UploadDto(uploader.Folder(), sidecar, StreamPartsNameFromBackup(backupName)); err != nil {
+		return fmt.Errorf("failed to record part %d of '%s': %w", partNumber, backupName, err)
+	}
+	return nil
+}
+
+// addSkippedPartSize counts the part uploaded by the previous attempt to the uploaded data size
+func (uploader *ResumableStreamUploader) addSkippedPartSize(compressedSize int64) {
+	if compressedSize == 0 {
+		uploader.unmeasuredParts = true
+		return
+	}
+	if uploader.tarSize != nil {
+		atomic.AddInt64(uploader.tarSize, compressedSize)
+	}
+}
+
+// fetchPartsSidecar loads the parts uploaded by the previous attempt, returns the empty sidecar for the new upload
+func (uploader *ResumableStreamUploader) fetchPartsSidecar(backupName string) (*StreamPartsSidecar, error) {
+	sidecar := &StreamPartsSidecar{PartSize: uploader.partSize, Compression: uploader.Compressor.FileExtension()}
+	if uploader.resumeToken == "" {
+		return sidecar, nil
+	}
+
+	var uploaded StreamPartsSidecar
+	err := FetchDto(uploader.Folder(), &uploaded, StreamPartsNameFromBackup(backupName))
+	var notFoundError storage.ObjectNotFoundError
+	if errors.As(err, &notFoundError) {
+		tracelog.WarningLogger.Printf("No uploaded parts found for '%s', starting from the beginning", backupName)
+		return sidecar, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploaded parts of '%s': %w", backupName, err)
+	}
+
+	if uploaded.PartSize != sidecar.PartSize || uploaded.Compression != sidecar.Compression {
+		return nil, fmt.Errorf("can not resume upload of '%s': it was started with part size %d and compression '%s'",
+			backupName, uploaded.PartSize, uploaded.Compression)
+	}
+	tracelog.InfoLogger.Printf("Resuming upload of '%s' after %d uploaded parts", backupName, len(uploaded.Parts))
+	return &uploaded, nil
+}
+
+// DownloadAndDecompressResumableStream downloads, decompresses and writes the parts of the stream one by one
+func DownloadAndDecompressResumableStream(backup Backup, parts int, extension string, writeCloser io.WriteCloser) error {
+	defer utility.LoggedClose(writeCloser, "")
+
+	decompressor := compression.FindDecompressor(extension)
+	if decompressor == nil {
+		return fmt.Errorf("decompressor for file type '%s' not found", extension)
+	}
+
+	for i := 0; i < parts; i++ {
+		fileName := GetPartitionedStreamName(backup.Name, decompressor.FileExtension(), i)
+		if err := downloadAndDecompressPart(backup.Folder, fileName, decompressor, writeCloser); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadAndDecompressPart(folder storage.Folder, fileName string, decompressor compression.Decompressor,
+	writer io.Writer) error {
+	archiveReader, exists, err := TryDownloadFile(folder, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to dowload file %v: %w", fileName, err)
+	}
+	if !exists {
+		return newArchiveNonExistenceError(fmt.Sprintf("Part '%s' does not exist.\n", fileName))
+	}
+	defer utility.LoggedClose(archiveReader, "")
+	tracelog.DebugLogger.Printf("Found file: %s", fileName)
+
+	decompressedReader, err := DecompressDecryptBytes(archiveReader, decompressor)
+	if err != nil {
+		return fmt.Errorf("failed to decompress/decrypt file %v: %w", fileName, err)
+	}
+	defer utility.LoggedClose(decompressedReader, "")
+
+	_, err = utility.FastCopy(&utility.EmptyWriteIgnorer{Writer: writer}, decompressedReader)
+	if err != nil {
+		return fmt.Errorf("failed to decompress/decrypt/pipe file %v: %w", fileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package internal_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const resumableStreamContent = "0123456789"
+
+// failingFolder counts the uploaded objects and fails to upload the objects with the failName suffix
+type failingFolder struct {
+	storage.Folder
+	failName string
+	puts     map[string]int
+}
+
+func (folder *failingFolder) PutObject(name string, content io.Reader) error {
+	if folder.failName != "" && strings.HasSuffix(name, folder.failName) {
+		return errors.New("failed to upload")
+	}
+	folder.puts[name]++
+	return folder.Folder.PutObject(name, content)
+}
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+}
+
+func (*bufferWriteCloser) Close() error {
+	return nil
+}
+
+func fetchResumableStream(t *testing.T, folder storage.Folder, backupName string) string {
+	backup := internal.NewBackup(folder, backupName)
+	fetcher, err := internal.GetBackupStreamFetcher(backup)
+	assert.NoError(t, err)
+	writer := &bufferWriteCloser{}
+	assert.NoError(t, fetcher(backup, writer))
+	return writer.String()
+}
+
+func TestResumableStreamUploader_PushStream(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	uploader := internal.NewResumableStreamUploader(compression.Compressors[lz4.AlgorithmName], folder, 4, "")
+
+	backupName, err := uploader.PushStream(strings.NewReader(resumableStreamContent))
+	assert.NoError(t, err)
+
+	var sidecar internal.StreamPartsSidecar
+	assert.NoError(t, internal.FetchDto(folder, &sidecar, internal.StreamPartsNameFromBackup(backupName)))
+	assert.Equal(t, 4, sidecar.PartSize)
+	assert.Len(t, sidecar.Parts, 3)
+	assert.Equal(t, 2, sidecar.Parts[2].Size)
+
+	assert.Equal(t, resumableStreamContent, fetchResumableStream(t, folder, backupName))
+}
+
+func TestResumableStreamUploader_PushStream_SkipsUploadedParts(t *testing.T) {
+	folder := &failingFolder{
+		Folder:   memory.NewFolder("", memory.NewStorage()),
+		failName: "part_0001." + lz4.FileExtension,
+		puts:     map[string]int{},
+	}
+	compressor := compression.Compressors[lz4.AlgorithmName]
+
+	backupName, err := internal.NewResumableStreamUploader(compressor, folder, 4, "").
+		PushStream(strings.NewReader(resumableStreamContent))
+	assert.Error(t, err)
+	exists, err := folder.Exists(internal.StreamMetadataNameFromBackup(backupName))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	folder.failName = ""
+	resumedUploader := internal.NewResumableStreamUploader(compressor, folder, 4, backupName)
+	resumedName, err := resumedUploader.PushStream(strings.NewReader(resumableStreamContent))
+	assert.NoError(t, err)
+	assert.Equal(t, backupName, resumedName)
+	assert.Equal(t, 1, folder.puts[internal.GetPartitionedStreamName(backupName, lz4.FileExtension, 0)])
+	// the digest of the skipped part is taken from the sidecar
+	digests := resumedUploader.CompressedStreamDigests()
+	assert.Len(t, digests, 3)
+	var storedSize int64
+	for dstPath, digest := range digests {
+		reader, err := folder.ReadObject(dstPath)
+		assert.NoError(t, err)
+		stored, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		storedDigest := sha256.Sum256(stored)
+		assert.Equal(t, hex.EncodeToString(storedDigest[:]), digest, dstPath)
+		storedSize += int64(len(stored))
+	}
+	// the size of the skipped part is taken from the sidecar as well
+	uploadedSize, err := resumedUploader.UploadedDataSize()
+	assert.NoError(t, err)
+	assert.Equal(t, storedSize, uploadedSize)
+
+	assert.Equal(t, resumableStreamContent, fetchResumableStream(t, folder, backupName))
+}
+
+func TestResumableStreamUploader_PushStream_RejectsChangedStream(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	backupName := "stream_" + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
+	assert.NoError(t, internal.UploadDto(folder, internal.StreamPartsSidecar{
+		PartSize:    4,
+		Compression: lz4.FileExtension,
+		Parts:       []internal.StreamPartsRecord{{Number: 0, Size: 4, SHA256: "garbage"}},
+	}, internal.StreamPartsNameFromBackup(backupName)))
+
+	_, err := internal.NewResumableStreamUploader(compressor, folder, 4, backupName).
+		PushStream(strings.NewReader(resumableStreamContent))
+	assert.Error(t, err)
+}
+
+func TestResumableStreamUploader_UploadedDataSize_UnknownForLegacySidecar(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	backupName, err := internal.NewResumableStreamUploader(compressor, folder, 4, "").
+		PushStream(strings.NewReader(resumableStreamContent))
+	assert.NoError(t, err)
+
+	var sidecar internal.StreamPartsSidecar
+	assert.NoError(t, internal.FetchDto(folder, &sidecar, internal.StreamPartsNameFromBackup(backupName)))
+	for i := range sidecar.Parts {
+		sidecar.Parts[i].CompressedSize = 0
+	}
+	assert.NoError(t, internal.UploadDto(folder, sidecar, internal.StreamPartsNameFromBackup(backupName)))
+
+	resumedUploader := internal.NewResumableStreamUploader(compressor, folder, 4, backupName)
+	_, err = resumedUploader.PushStream(strings.NewReader(resumableStreamContent))
+	assert.NoError(t, err)
+	_, err = resumedUploader.UploadedDataSize()
+	assert.Error(t, err)
+}
@@ -3,6 +3,8 @@ package internal
 import (
 	"io"
 
+	"github.com/pkg/errors"
+
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
@@ -31,3 +33,18 @@ func (readerMaker *StorageReaderMaker) Reader() (io.ReadCloser, error) {
 func (readerMaker *StorageReaderMaker) FileType() FileType { return readerMaker.StorageFileType }
 
 func (readerMaker *StorageReaderMaker) Mode() int { return readerMaker.FileMode }
+
+// SupportsRanges is true if the storage folder is able to read the object since the offset
+func (readerMaker *StorageReaderMaker) SupportsRanges() bool {
+	_, ok := readerMaker.Folder.(storage.RangeReader)
+	return ok
+}
+
+// ReadRange reads the object since the offset, it fails if the folder does not support the range reads
+func (readerMaker *StorageReaderMaker) ReadRange(offset int64) (io.ReadCloser, error) {
+	rangeReader, ok := readerMaker.Folder.(storage.RangeReader)
+	if !ok {
+		return nil, errors.New("storage folder does not support the range reads")
+	}
+	return rangeReader.ReadObjectRange(readerMaker.RelativePath, offset, "")
+}
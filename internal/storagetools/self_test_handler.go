@@ -0,0 +1,280 @@
+package storagetools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"path"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// SelfTestStage is the part of the upload and download pipeline checked by the self-test
+type SelfTestStage string
+
+const (
+	CompressionStage   SelfTestStage = "compression"
+	EncryptionStage    SelfTestStage = "encryption"
+	NetworkStage       SelfTestStage = "network"
+	DecryptionStage    SelfTestStage = "decryption"
+	DecompressionStage SelfTestStage = "decompression"
+	VerificationStage  SelfTestStage = "verification"
+
+	selfTestObjectPrefix = "walg_self_test_"
+	selfTestBufferSize   = 1 << 20
+)
+
+// SelfTestError reports the stage of the pipeline which failed
+type SelfTestError struct {
+	Stage SelfTestStage
+	Err   error
+}
+
+func (err SelfTestError) Error() string {
+	return fmt.Sprintf("self-test failed at %s stage: %v", err.Stage, err.Err)
+}
+
+func (err SelfTestError) Unwrap() error {
+	return err.Err
+}
+
+// newSelfTestError keeps the stage of the error coming from the inner stage of the pipeline
+func newSelfTestError(stage SelfTestStage, err error) error {
+	var stageErr SelfTestError
+	if errors.As(err, &stageErr) {
+		return err
+	}
+	return SelfTestError{Stage: stage, Err: err}
+}
+
+// SelfTestResult describes the payload passed through the pipeline
+type SelfTestResult struct {
+	ObjectName       string
+	PayloadSize      int64
+	StoredSize       int64
+	SHA256           string
+	UploadDuration   time.Duration
+	DownloadDuration time.Duration
+}
+
+// HandleSelfTest uploads and downloads back the generated payload of payloadSize bytes
+// and reports the throughput or the failed stage
+func HandleSelfTest(folder storage.Folder, compressor compression.Compressor, payloadSize int64) {
+	result, err := RunSelfTest(folder, compressor, payloadSize)
+	tracelog.ErrorLogger.FatalOnError(err)
+	tracelog.InfoLogger.Printf("Self-test passed: %d bytes with SHA256 %s were stored as %d bytes", result.PayloadSize,
+		result.SHA256, result.StoredSize)
+	tracelog.InfoLogger.Printf("Upload took %v (%s), download took %v (%s)",
+		result.UploadDuration, formatThroughput(result.PayloadSize, result.UploadDuration),
+		result.DownloadDuration, formatThroughput(result.PayloadSize, result.DownloadDuration))
+}
+
+// RunSelfTest pushes the generated payload of payloadSize bytes to the folder by the regular uploader,
+// so it is compressed and encrypted (if the crypter is configured) as the backups are, then downloads,
+// decrypts and decompresses it back and checks it is not changed byte for byte.
+// The failures are reported as SelfTestError. The crypter is checked at the encryption stage before the upload,
+// the later failures of the compressing and encrypting pipe are reported at the compression stage.
+// The uploaded object is deleted afterwards.
+func RunSelfTest(folder storage.Folder, compressor compression.Compressor, payloadSize int64) (SelfTestResult, error) {
+	if payloadSize < 0 {
+		return SelfTestResult{}, fmt.Errorf("self-test payload size must not be negative: %d", payloadSize)
+	}
+	seed := time.Now().UnixNano()
+	result := SelfTestResult{
+		ObjectName:  fmt.Sprintf("%s%d.%s", selfTestObjectPrefix, seed, compressor.FileExtension()),
+		PayloadSize: payloadSize,
+	}
+	if crypter := internal.ConfigureCrypter(); crypter != nil {
+		if err := checkSelfTestCrypter(crypter); err != nil {
+			return result, err
+		}
+	}
+
+	uploader := internal.NewUploader(compressor, selfTestFolder{folder})
+	uploadHash := sha256.New()
+	// the object may be partially stored by the failed upload as well
+	defer deleteSelfTestObject(folder, result.ObjectName)
+	startTime := time.Now()
+	err := uploader.PushStreamToDestination(io.TeeReader(newSelfTestPayload(seed, payloadSize), uploadHash),
+		result.ObjectName)
+	if err != nil {
+		return result, newSelfTestError(NetworkStage, err)
+	}
+	result.UploadDuration = time.Since(startTime)
+	result.StoredSize, err = uploader.UploadedDataSize()
+	if err != nil {
+		return result, err
+	}
+	result.SHA256 = hex.EncodeToString(uploadHash.Sum(nil))
+
+	startTime = time.Now()
+	downloadHash, err := downloadSelfTestPayload(folder, result.ObjectName, newSelfTestPayload(seed, payloadSize))
+	if err != nil {
+		return result, err
+	}
+	result.DownloadDuration = time.Since(startTime)
+	if downloaded := hex.EncodeToString(downloadHash.Sum(nil)); downloaded != result.SHA256 {
+		return result, newSelfTestError(VerificationStage,
+			fmt.Errorf("downloaded payload SHA256 %s does not match uploaded %s", downloaded, result.SHA256))
+	}
+	return result, nil
+}
+
+// checkSelfTestCrypter reports the crypter failure at the encryption stage,
+// CompressAndEncrypt of the uploader does not return it
+func checkSelfTestCrypter(crypter crypto.Crypter) error {
+	encryptWriter, err := crypter.Encrypt(io.Discard)
+	if err != nil {
+		return newSelfTestError(EncryptionStage, err)
+	}
+	if err = encryptWriter.Close(); err != nil {
+		return newSelfTestError(EncryptionStage, err)
+	}
+	return nil
+}
+
+// downloadSelfTestPayload reads the object as the regular downloader does,
+// compares the downloaded payload with the expected one and returns its hash
+func downloadSelfTestPayload(folder storage.Folder, objectName string, expected io.Reader) (hash.Hash, error) {
+	objectReader, exists, err := internal.TryDownloadFile(folder, objectName)
+	if err == nil && !exists {
+		err = fmt.Errorf("object '%s' does not exist", objectName)
+	}
+	if err != nil {
+		return nil, newSelfTestError(NetworkStage, err)
+	}
+	defer utility.LoggedClose(objectReader, "")
+
+	decryptReader, err := internal.DecryptBytes(stageReader{objectReader, NetworkStage})
+	if err != nil {
+		return nil, newSelfTestError(DecryptionStage, err)
+	}
+	decompressor := compression.FindDecompressor(path.Ext(objectName))
+	if decompressor == nil {
+		return nil, newSelfTestError(DecompressionStage,
+			fmt.Errorf("decompressor for extension '%s' was not found", path.Ext(objectName)))
+	}
+	decompressReader, err := compression.Pooled(decompressor).Decompress(stageReader{decryptReader, DecryptionStage})
+	if err != nil {
+		return nil, newSelfTestError(DecompressionStage, err)
+	}
+	defer utility.LoggedClose(decompressReader, "")
+
+	downloadHash := sha256.New()
+	err = compareSelfTestPayload(io.TeeReader(stageReader{decompressReader, DecompressionStage}, downloadHash), expected)
+	if err != nil {
+		return nil, err
+	}
+	return downloadHash, nil
+}
+
+func compareSelfTestPayload(downloaded, expected io.Reader) error {
+	downloadedBuf, expectedBuf := make([]byte, selfTestBufferSize), make([]byte, selfTestBufferSize)
+	var offset int64
+	for {
+		expectedN, expectedErr := io.ReadFull(expected, expectedBuf)
+		downloadedN, err := io.ReadFull(downloaded, downloadedBuf[:expectedN])
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("downloaded payload is truncated to %d bytes", offset+int64(downloadedN))
+			}
+			return newSelfTestError(VerificationStage, err)
+		}
+		if i := firstMismatch(downloadedBuf[:expectedN], expectedBuf[:expectedN]); i >= 0 {
+			return newSelfTestError(VerificationStage,
+				fmt.Errorf("downloaded payload differs from uploaded at byte %d", offset+int64(i)))
+		}
+		offset += int64(expectedN)
+		if expectedErr != nil {
+			break
+		}
+	}
+	if n, err := downloaded.Read(downloadedBuf[:1]); n > 0 || (err != nil && err != io.EOF) {
+		if err == nil {
+			err = fmt.Errorf("downloaded payload is longer than uploaded %d bytes", offset)
+		}
+		return newSelfTestError(VerificationStage, err)
+	}
+	return nil
+}
+
+func firstMismatch(downloaded, expected []byte) int {
+	if bytes.Equal(downloaded, expected) {
+		return -1
+	}
+	for i := range expected {
+		if downloaded[i] != expected[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func deleteSelfTestObject(folder storage.Folder, objectName string) {
+	if err := folder.DeleteObjects([]string{objectName}); err != nil {
+		tracelog.WarningLogger.Printf("Failed to delete the self-test object '%s': %v", objectName, err)
+	}
+}
+
+func formatThroughput(size int64, duration time.Duration) string {
+	if duration <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f MiB/s", float64(size)/(1<<20)/duration.Seconds())
+}
+
+// selfTestPayload generates the same pseudo-random bytes for the same seed
+type selfTestPayload struct {
+	random    *rand.Rand
+	remaining int64
+}
+
+func newSelfTestPayload(seed, size int64) io.Reader {
+	return &selfTestPayload{random: rand.New(rand.NewSource(seed)), remaining: size}
+}
+
+func (payload *selfTestPayload) Read(p []byte) (int, error) {
+	if payload.remaining == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > payload.remaining {
+		p = p[:payload.remaining]
+	}
+	n, _ := payload.random.Read(p)
+	payload.remaining -= int64(n)
+	return n, nil
+}
+
+type stageReader struct {
+	reader io.Reader
+	stage  SelfTestStage
+}
+
+func (reader stageReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = newSelfTestError(reader.stage, err)
+	}
+	return n, err
+}
+
+// selfTestFolder reports the failures of the uploaded content at the compression stage,
+// the content is read from the compressing and encrypting pipe of the uploader
+type selfTestFolder struct {
+	storage.Folder
+}
+
+func (folder selfTestFolder) PutObject(name string, content io.Reader) error {
+	return folder.Folder.PutObject(name, stageReader{content, CompressionStage})
+}
@@ -0,0 +1,105 @@
+package storagetools
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/none"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type failingReadFolder struct {
+	storage.Folder
+}
+
+func (folder failingReadFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return nil, errors.New("connection reset")
+}
+
+// partialPutFolder stores the half of the object and fails the upload
+type partialPutFolder struct {
+	storage.Folder
+}
+
+func (folder partialPutFolder) PutObject(name string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	if err = folder.Folder.PutObject(name, bytes.NewReader(data[:len(data)/2])); err != nil {
+		return err
+	}
+	return errors.New("connection reset")
+}
+
+type corruptingFolder struct {
+	storage.Folder
+}
+
+func (folder corruptingFolder) PutObject(name string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	data[len(data)-1] ^= 0xff
+	return folder.Folder.PutObject(name, bytes.NewReader(data))
+}
+
+func assertSelfTestStage(t *testing.T, err error, stage SelfTestStage) {
+	var stageErr SelfTestError
+	if assert.True(t, errors.As(err, &stageErr), "unexpected error: %v", err) {
+		assert.Equal(t, stage, stageErr.Stage)
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+
+	for _, size := range []int64{0, 1, 3<<20 + 17} {
+		result, err := RunSelfTest(folder, compressor, size)
+		assert.NoError(t, err)
+		assert.Equal(t, size, result.PayloadSize)
+		assert.NotEmpty(t, result.SHA256)
+	}
+
+	objects, subFolders, err := folder.ListFolder()
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+	assert.Empty(t, subFolders)
+}
+
+func TestRunSelfTest_ReportsFailedStage(t *testing.T) {
+	compressor := compression.Compressors[lz4.AlgorithmName]
+
+	viper.Set(internal.PgpKeySetting, "not an armored key")
+	_, err := RunSelfTest(memory.NewFolder("", memory.NewStorage()), compressor, 1024)
+	viper.Set(internal.PgpKeySetting, nil)
+	assertSelfTestStage(t, err, EncryptionStage)
+
+	folder := memory.NewFolder("", memory.NewStorage())
+	_, err = RunSelfTest(failingReadFolder{folder}, compressor, 1024)
+	assertSelfTestStage(t, err, NetworkStage)
+	objects, _, listErr := folder.ListFolder()
+	assert.NoError(t, listErr)
+	assert.Empty(t, objects)
+
+	folder = memory.NewFolder("", memory.NewStorage())
+	_, err = RunSelfTest(partialPutFolder{folder}, compressor, 1024)
+	assertSelfTestStage(t, err, NetworkStage)
+	objects, _, listErr = folder.ListFolder()
+	assert.NoError(t, listErr)
+	assert.Empty(t, objects)
+
+	_, err = RunSelfTest(corruptingFolder{memory.NewFolder("", memory.NewStorage())},
+		compression.Compressors[none.AlgorithmName], 1024)
+	assertSelfTestStage(t, err, VerificationStage)
+}
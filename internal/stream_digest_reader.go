@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// StreamDigestReader calculates the SHA256 of the data read through it,
+// the stream is expected to be read by one goroutine at a time
+type StreamDigestReader struct {
+	underlying io.Reader
+	hash       hash.Hash
+}
+
+func NewStreamDigestReader(underlying io.Reader) *StreamDigestReader {
+	return &StreamDigestReader{underlying: underlying, hash: sha256.New()}
+}
+
+func (reader *StreamDigestReader) Read(p []byte) (n int, err error) {
+	n, err = reader.underlying.Read(p)
+	reader.hash.Write(p[:n])
+	return
+}
+
+// SHA256 returns the hex-encoded digest of the data read so far
+func (reader *StreamDigestReader) SHA256() string {
+	return hex.EncodeToString(reader.hash.Sum(nil))
+}
+
+// UploadWithDigest passes the content to the upload func and calculates its SHA256 by the separate goroutine
+// fed through the pipe, so the digest is ready once the upload completes without the second pass over the data.
+// The error of either the upload or the hashing is returned.
+func UploadWithDigest(content io.Reader, upload func(io.Reader) error) (string, error) {
+	pipeReader, pipeWriter := io.Pipe()
+	digestResult := make(chan error, 1)
+	digest := sha256.New()
+	go func() {
+		_, err := io.Copy(digest, pipeReader)
+		// fail the writes of the tee reader, so the upload is interrupted too
+		_ = pipeReader.CloseWithError(err)
+		digestResult <- err
+	}()
+
+	uploadErr := upload(io.TeeReader(content, pipeWriter))
+	// the upload error is delivered to the hashing goroutine, nil closes the pipe with io.EOF
+	_ = pipeWriter.CloseWithError(uploadErr)
+	digestErr := <-digestResult
+	if uploadErr != nil {
+		return "", uploadErr
+	}
+	if digestErr != nil {
+		return "", fmt.Errorf("failed to calculate the uploaded stream digest: %w", digestErr)
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// streamDigests keeps the SHA256 of the compressed streams by their destination paths
+type streamDigests struct {
+	mutex   sync.Mutex
+	digests map[string]string
+}
+
+func newStreamDigests() *streamDigests {
+	return &streamDigests{digests: make(map[string]string)}
+}
+
+func (sd *streamDigests) add(dstPath, digest string) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	sd.digests[dstPath] = digest
+}
+
+func (sd *streamDigests) get(dstPath string) string {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	return sd.digests[dstPath]
+}
+
+func (sd *streamDigests) copy() map[string]string {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	digests := make(map[string]string, len(sd.digests))
+	for dstPath, digest := range sd.digests {
+		digests[dstPath] = digest
+	}
+	return digests
+}
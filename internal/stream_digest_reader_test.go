@@ -0,0 +1,84 @@
+package internal_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestStreamDigestReader_SplitStreamUpload(t *testing.T) {
+	data := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(data)
+	folder := memory.NewFolder("", memory.NewStorage())
+	uploader := internal.NewSplitStreamUploader(compression.Compressors[lz4.AlgorithmName], folder, 3, 1024)
+
+	digestReader := internal.NewStreamDigestReader(bytes.NewReader(data))
+	backupName, err := uploader.PushStream(digestReader)
+	assert.NoError(t, err)
+
+	expected := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(expected[:]), digestReader.SHA256())
+	rawSize, err := uploader.RawDataSize()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), rawSize)
+
+	objects, _, err := folder.GetSubFolder(backupName).ListFolder()
+	assert.NoError(t, err)
+	var partsSize int64
+	for _, object := range objects {
+		if strings.HasPrefix(object.GetName(), "part_") {
+			partsSize += object.GetSize()
+		}
+	}
+	uploadedSize, err := uploader.UploadedDataSize()
+	assert.NoError(t, err)
+	assert.Equal(t, partsSize, uploadedSize)
+}
+
+func TestPushStream_RecordsCompressedStreamDigest(t *testing.T) {
+	data := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(data)
+	folder := memory.NewFolder("", memory.NewStorage())
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)
+
+	backupName, err := uploader.PushStream(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	dstPath := internal.GetStreamName(backupName, lz4.FileExtension)
+	stored, err := folder.ReadObject(dstPath)
+	assert.NoError(t, err)
+	storedData, err := io.ReadAll(stored)
+	assert.NoError(t, err)
+	expected := sha256.Sum256(storedData)
+	assert.Equal(t, map[string]string{dstPath: hex.EncodeToString(expected[:])}, uploader.CompressedStreamDigests())
+}
+
+func TestUploadWithDigest_ReturnsUploadError(t *testing.T) {
+	uploadErr := errors.New("upload failed")
+	_, err := internal.UploadWithDigest(strings.NewReader("content"), func(content io.Reader) error {
+		_, _ = io.CopyN(io.Discard, content, 3)
+		return uploadErr
+	})
+	assert.ErrorIs(t, err, uploadErr)
+}
+
+func TestUploadWithDigest_ReturnsReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	_, err := internal.UploadWithDigest(iotest.ErrReader(readErr), func(content io.Reader) error {
+		_, err := io.Copy(io.Discard, content)
+		return err
+	})
+	assert.ErrorIs(t, err, readErr)
+}
@@ -66,6 +66,45 @@ func DownloadAndDecompressStream(backup Backup, writeCloser io.WriteCloser) erro
 	return newArchiveNonExistenceError(fmt.Sprintf("Archive '%s' does not exist.\n", backup.Name))
 }
 
+// DownloadAndDecompressStreamByExtension returns the StreamFetcher of the single stream compressed by the method
+// with the extension: the decompressor of the extension is used as is, so the stream is neither mistaken
+// for the one left by the other method under the same name nor detected by its leading bytes.
+// If there is no stream with the extension, the stream is looked up as DownloadAndDecompressStream does.
+func DownloadAndDecompressStreamByExtension(extension string) StreamFetcher {
+	return func(backup Backup, writeCloser io.WriteCloser) error {
+		decompressor := compression.FindDecompressor(extension)
+		if decompressor == nil {
+			utility.LoggedClose(writeCloser, "")
+			return fmt.Errorf("decompressor for file type '%s' not found", extension)
+		}
+		streamName := GetStreamName(backup.Name, decompressor.FileExtension())
+		archiveReader, exists, err := TryDownloadFile(backup.Folder, streamName)
+		if err != nil {
+			utility.LoggedClose(writeCloser, "")
+			return fmt.Errorf("failed to dowload file: %w", err)
+		}
+		if !exists {
+			tracelog.WarningLogger.Printf("Stream '%s' is not found, looking for the stream of the other compression method",
+				streamName)
+			return DownloadAndDecompressStream(backup, writeCloser)
+		}
+		defer utility.LoggedClose(writeCloser, "")
+		defer utility.LoggedClose(archiveReader, "")
+
+		decompressedReader, err := DecompressDecryptBytes(archiveReader, decompressor)
+		if err != nil {
+			return fmt.Errorf("failed to decompress and decrypt file: %w", err)
+		}
+		defer utility.LoggedClose(decompressedReader, "")
+
+		_, err = utility.FastCopy(&utility.EmptyWriteIgnorer{Writer: writeCloser}, decompressedReader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress and decrypt file: %w", err)
+		}
+		return nil
+	}
+}
+
 // TODO : unit tests
 // DownloadAndDecompressSplittedStream downloads, decompresses and writes stream to stdout
 func DownloadAndDecompressSplittedStream(backup Backup, blockSize int, extension string, writeCloser io.WriteCloser) error {
@@ -12,6 +12,7 @@ import (
 const (
 	SplitMergeStreamBackup   = "SPLIT_MERGE_STREAM_BACKUP"
 	SingleStreamStreamBackup = "STREAM_BACKUP"
+	ResumableStreamBackup    = "RESUMABLE_STREAM_BACKUP"
 )
 
 type BackupStreamMetadata struct {
@@ -22,11 +23,26 @@ type BackupStreamMetadata struct {
 }
 
 func GetBackupStreamFetcher(backup Backup) (StreamFetcher, error) {
+	return getBackupStreamFetcher(backup, DownloadAndDecompressStream)
+}
+
+// GetBackupStreamFetcherByExtension is GetBackupStreamFetcher for the backup whose compression is known beforehand,
+// e.g. recorded in its sentinel: the single stream is decompressed by the decompressor of the extension rather than
+// the first one whose stream is found. The metadata of the split and resumable streams records the compression
+// itself, so it takes precedence. The empty extension is unknown, so the stream is looked up as usual.
+func GetBackupStreamFetcherByExtension(backup Backup, extension string) (StreamFetcher, error) {
+	if extension == "" {
+		return GetBackupStreamFetcher(backup)
+	}
+	return getBackupStreamFetcher(backup, DownloadAndDecompressStreamByExtension(extension))
+}
+
+func getBackupStreamFetcher(backup Backup, singleStreamFetcher StreamFetcher) (StreamFetcher, error) {
 	var metadata BackupStreamMetadata
 	err := FetchDto(backup.Folder, &metadata, StreamMetadataNameFromBackup(backup.Name))
 	var test storage.ObjectNotFoundError
 	if errors.As(err, &test) {
-		return DownloadAndDecompressStream, nil
+		return singleStreamFetcher, nil
 	}
 	if err != nil {
 		return nil, err
@@ -39,8 +55,14 @@ func GetBackupStreamFetcher(backup Backup) (StreamFetcher, error) {
 		return func(backup Backup, writer io.WriteCloser) error {
 			return DownloadAndDecompressSplittedStream(backup, int(blockSize), compression, writer)
 		}, nil
+	case ResumableStreamBackup:
+		var parts = metadata.Partitions
+		var compression = metadata.Compression
+		return func(backup Backup, writer io.WriteCloser) error {
+			return DownloadAndDecompressResumableStream(backup, int(parts), compression, writer)
+		}, nil
 	case SingleStreamStreamBackup, "":
-		return DownloadAndDecompressStream, nil
+		return singleStreamFetcher, nil
 	}
 	tracelog.ErrorLogger.Fatalf("Unknown backup type %s", metadata.Type)
 	return nil, nil // unreachable
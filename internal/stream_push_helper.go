@@ -8,6 +8,7 @@ import (
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/wal-g/wal-g/internal/limiters"
 	"github.com/wal-g/wal-g/internal/splitmerge"
 
 	"github.com/wal-g/tracelog"
@@ -69,14 +70,21 @@ func (uploader *SplitStreamUploader) PushStream(stream io.Reader) (string, error
 }
 
 // TODO : unit tests
-// PushStreamToDestination compresses a stream and push it to specifyed destination
+// PushStreamToDestination compresses a stream and push it to specifyed destination,
+// the SHA256 of the compressed stream is calculated along with the upload (see CompressedStreamDigests)
 func (uploader *Uploader) PushStreamToDestination(stream io.Reader, dstPath string) error {
 	if uploader.dataSize != nil {
 		stream = NewWithSizeReader(stream, uploader.dataSize)
 	}
-	compressed := CompressAndEncrypt(stream, uploader.Compressor, ConfigureCrypter())
-	err := uploader.Upload(dstPath, compressed)
+	// the network limit is applied to the bytes sent, i.e. after the compression
+	compressed := limiters.NewNetworkLimitReader(CompressAndEncrypt(stream, uploader.Compressor, ConfigureCrypter()))
+	digest, err := UploadWithDigest(compressed, func(content io.Reader) error {
+		return uploader.Upload(dstPath, content)
+	})
 	tracelog.InfoLogger.Println("FILE PATH:", dstPath)
+	if err == nil && uploader.streamDigests != nil {
+		uploader.streamDigests.add(dstPath, digest)
+	}
 
 	return err
 }
@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+)
+
+// TarFsyncMode defines how the extracted files are flushed to the disk
+type TarFsyncMode int
+
+const (
+	// DefaultTarFsyncMode calls fsync after each file is written
+	DefaultTarFsyncMode TarFsyncMode = iota
+	// DisabledTarFsyncMode does not flush the extracted files at all
+	DisabledTarFsyncMode
+	// GlobalTarFsyncMode calls the global sync once the extraction is finished
+	GlobalTarFsyncMode
+	// PerFileDatasyncTarFsyncMode calls fdatasync on each written file once the extraction is finished
+	PerFileDatasyncTarFsyncMode
+)
+
+var tarFsyncModeNames = map[TarFsyncMode]string{
+	DefaultTarFsyncMode:         "DEFAULT",
+	DisabledTarFsyncMode:        "DISABLED",
+	GlobalTarFsyncMode:          "GLOBAL",
+	PerFileDatasyncTarFsyncMode: "PER_FILE_DATASYNC",
+}
+
+func (mode TarFsyncMode) String() string {
+	if name, ok := tarFsyncModeNames[mode]; ok {
+		return name
+	}
+	return fmt.Sprintf("TarFsyncMode(%d)", int(mode))
+}
+
+// ParseTarFsyncMode converts the setting value to the TarFsyncMode
+func ParseTarFsyncMode(value string) (TarFsyncMode, error) {
+	for mode, name := range tarFsyncModeNames {
+		if strings.EqualFold(value, name) {
+			return mode, nil
+		}
+	}
+	return DefaultTarFsyncMode, fmt.Errorf("unknown %s value '%s', supported values are: %s",
+		TarFsyncModeSetting, value, strings.Join(tarFsyncModeNamesList(), ", "))
+}
+
+func tarFsyncModeNamesList() []string {
+	names := make([]string, 0, len(tarFsyncModeNames))
+	for mode := DefaultTarFsyncMode; mode <= PerFileDatasyncTarFsyncMode; mode++ {
+		names = append(names, tarFsyncModeNames[mode])
+	}
+	return names
+}
+
+// GetTarFsyncMode reads the fsync mode from the config,
+// falling back to the deprecated TarDisableFsyncSetting if the mode is not set
+func GetTarFsyncMode() (TarFsyncMode, error) {
+	if modeStr, ok := GetSetting(TarFsyncModeSetting); ok {
+		return ParseTarFsyncMode(modeStr)
+	}
+	if viper.GetBool(TarDisableFsyncSetting) {
+		tracelog.WarningLogger.Printf("%s is deprecated, please set %s=%s instead",
+			TarDisableFsyncSetting, TarFsyncModeSetting, DisabledTarFsyncMode)
+		return DisabledTarFsyncMode, nil
+	}
+	return DefaultTarFsyncMode, nil
+}
+
+// TarFsyncModeOverride sets the fsync mode of the files extracted under the path prefix
+type TarFsyncModeOverride struct {
+	PathPrefix string
+	Mode       TarFsyncMode
+}
+
+// TarFsyncModes is the fsync mode of the extracted files with the per path overrides
+type TarFsyncModes struct {
+	Default   TarFsyncMode
+	Overrides []TarFsyncModeOverride
+}
+
+// GetTarFsyncModes reads the fsync mode and its per path overrides from the config
+func GetTarFsyncModes() (TarFsyncModes, error) {
+	mode, err := GetTarFsyncMode()
+	if err != nil {
+		return TarFsyncModes{}, err
+	}
+	modes := TarFsyncModes{Default: mode}
+	if overridesStr, ok := GetSetting(TarFsyncModeOverridesSetting); ok && overridesStr != "" {
+		if modes.Overrides, err = ParseTarFsyncModeOverrides(strings.Split(overridesStr, ",")); err != nil {
+			return TarFsyncModes{}, err
+		}
+	}
+	return modes, nil
+}
+
+// ParseTarFsyncModeOverrides parses the path=MODE pairs, the paths are the absolute prefixes of the extracted file paths
+func ParseTarFsyncModeOverrides(pairs []string) ([]TarFsyncModeOverride, error) {
+	overrides := make([]TarFsyncModeOverride, 0, len(pairs))
+	prefixes := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		pathAndMode := strings.SplitN(pair, "=", 2)
+		if len(pathAndMode) != 2 {
+			return nil, fmt.Errorf("invalid %s entry '%s': expected path=MODE", TarFsyncModeOverridesSetting, pair)
+		}
+		pathPrefix := strings.TrimSpace(pathAndMode[0])
+		if pathPrefix == "" {
+			return nil, fmt.Errorf("invalid %s entry '%s': path is empty", TarFsyncModeOverridesSetting, pair)
+		}
+		if !filepath.IsAbs(pathPrefix) {
+			return nil, fmt.Errorf("invalid %s entry '%s': path is not absolute", TarFsyncModeOverridesSetting, pair)
+		}
+		pathPrefix = filepath.Clean(pathPrefix)
+		if prefixes[pathPrefix] {
+			return nil, fmt.Errorf("duplicate %s path '%s'", TarFsyncModeOverridesSetting, pathPrefix)
+		}
+		mode, err := ParseTarFsyncMode(strings.TrimSpace(pathAndMode[1]))
+		if err != nil {
+			return nil, err
+		}
+		prefixes[pathPrefix] = true
+		overrides = append(overrides, TarFsyncModeOverride{PathPrefix: pathPrefix, Mode: mode})
+	}
+	return overrides, nil
+}
+
+// ModeFor returns the mode of the longest override prefix matching the whole path components, the default otherwise.
+// The symlinks of the path are resolved first, so the override of the tablespace location applies to the files
+// extracted through its pg_tblspc symlink, the path is matched as is if no override matches the resolved one.
+func (modes TarFsyncModes) ModeFor(path string) TarFsyncMode {
+	if len(modes.Overrides) == 0 {
+		return modes.Default
+	}
+	path = filepath.Clean(path)
+	if resolvedPath := resolveExistingPath(path); resolvedPath != path {
+		if mode, ok := modes.overrideFor(resolvedPath); ok {
+			return mode
+		}
+	}
+	if mode, ok := modes.overrideFor(path); ok {
+		return mode
+	}
+	return modes.Default
+}
+
+// overrideFor returns the mode of the longest override prefix matching the whole path components
+func (modes TarFsyncModes) overrideFor(path string) (mode TarFsyncMode, ok bool) {
+	matchedLength := -1
+	for _, override := range modes.Overrides {
+		prefix := override.PathPrefix
+		matches := path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator))
+		if matches && len(prefix) > matchedLength {
+			mode, matchedLength, ok = override.Mode, len(prefix), true
+		}
+	}
+	return mode, ok
+}
+
+// resolveExistingPath resolves the symlinks of the longest existing ancestor of the path,
+// the not yet created components are appended to it as is
+func resolveExistingPath(path string) string {
+	existing, rest := path, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path
+		}
+		existing, rest = parent, filepath.Join(filepath.Base(existing), rest)
+	}
+}
+
+// Uses is true if the mode is used by default or by any of the overrides
+func (modes TarFsyncModes) Uses(mode TarFsyncMode) bool {
+	if modes.Default == mode {
+		return true
+	}
+	for _, override := range modes.Overrides {
+		if override.Mode == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// TarFsyncModeMigration is the fsync mode equivalent to the deprecated settings of the current config
+type TarFsyncModeMigration struct {
+	Mode TarFsyncMode
+	// DeprecatedDisableFsync is the value of the deprecated TarDisableFsyncSetting, it should be removed from the config
+	DeprecatedDisableFsync bool
+	// DeprecatedIgnored is true if the deprecated setting has no effect since it is overridden by the TarFsyncModeSetting
+	DeprecatedIgnored bool
+}
+
+// MigrateTarFsyncMode computes the TarFsyncModeSetting value keeping the current behavior,
+// the mode is chosen the same way GetTarFsyncMode does
+func MigrateTarFsyncMode() (TarFsyncModeMigration, error) {
+	disableFsync := viper.GetBool(TarDisableFsyncSetting)
+	migration := TarFsyncModeMigration{Mode: DefaultTarFsyncMode, DeprecatedDisableFsync: disableFsync}
+	if modeStr, ok := GetSetting(TarFsyncModeSetting); ok {
+		mode, err := ParseTarFsyncMode(modeStr)
+		if err != nil {
+			return TarFsyncModeMigration{}, err
+		}
+		migration.Mode = mode
+		migration.DeprecatedIgnored = disableFsync && mode != DisabledTarFsyncMode
+		return migration, nil
+	}
+	if disableFsync {
+		migration.Mode = DisabledTarFsyncMode
+	}
+	return migration, nil
+}
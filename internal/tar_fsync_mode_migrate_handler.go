@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// HandleTarFsyncModeMigrate prints the TarFsyncModeSetting equivalent to the current config,
+// the config file is updated with it if the configFile is not empty
+func HandleTarFsyncModeMigrate(output io.Writer, configFile string) error {
+	migration, err := MigrateTarFsyncMode()
+	if err != nil {
+		return err
+	}
+	if migration.DeprecatedIgnored {
+		tracelog.WarningLogger.Printf("%s is ignored since %s is set", TarDisableFsyncSetting, TarFsyncModeSetting)
+	}
+	if _, err = fmt.Fprintf(output, "%s=%s\n", TarFsyncModeSetting, migration.Mode); err != nil {
+		return err
+	}
+	if configFile == "" {
+		return nil
+	}
+	if err = WriteTarFsyncModeToConfig(configFile, migration.Mode); err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("%s is set to %s in %s, %s is removed", TarFsyncModeSetting, migration.Mode,
+		configFile, TarDisableFsyncSetting)
+	return nil
+}
+
+// WriteTarFsyncModeToConfig sets the TarFsyncModeSetting in the JSON config file
+// and removes the deprecated TarDisableFsyncSetting from it
+func WriteTarFsyncModeToConfig(configFile string, mode TarFsyncMode) error {
+	if extension := filepath.Ext(configFile); extension != "" && !strings.EqualFold(extension, ".json") {
+		return errors.Errorf("only JSON config files can be updated, please set %s=%s in %s manually",
+			TarFsyncModeSetting, mode, configFile)
+	}
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to stat the config file")
+	}
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the config file")
+	}
+	config := make(map[string]interface{})
+	if err = json.Unmarshal(content, &config); err != nil {
+		return errors.Wrapf(err, "failed to parse the config file %s", configFile)
+	}
+
+	for key := range config {
+		if strings.EqualFold(key, TarDisableFsyncSetting) || strings.EqualFold(key, TarFsyncModeSetting) {
+			delete(config, key)
+		}
+	}
+	config[TarFsyncModeSetting] = mode.String()
+
+	content, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(configFile, append(content, '\n'), info.Mode().Perm()),
+		"failed to write the config file")
+}
@@ -0,0 +1,144 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestParseTarFsyncMode(t *testing.T) {
+	for mode := internal.DefaultTarFsyncMode; mode <= internal.PerFileDatasyncTarFsyncMode; mode++ {
+		parsed, err := internal.ParseTarFsyncMode(mode.String())
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+
+	parsed, err := internal.ParseTarFsyncMode("per_file_datasync")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.PerFileDatasyncTarFsyncMode, parsed)
+
+	_, err = internal.ParseTarFsyncMode("SOMETIMES")
+	assert.Error(t, err)
+}
+
+func TestGetTarFsyncMode_DeprecatedDisableFsync(t *testing.T) {
+	viper.Set(internal.TarDisableFsyncSetting, true)
+	defer viper.Set(internal.TarDisableFsyncSetting, false)
+
+	mode, err := internal.GetTarFsyncMode()
+	assert.NoError(t, err)
+	assert.Equal(t, internal.DisabledTarFsyncMode, mode)
+}
+
+func TestGetTarFsyncMode_Default(t *testing.T) {
+	mode, err := internal.GetTarFsyncMode()
+	assert.NoError(t, err)
+	assert.Equal(t, internal.DefaultTarFsyncMode, mode)
+}
+
+func TestMigrateTarFsyncMode(t *testing.T) {
+	testCases := []struct {
+		disableFsync interface{}
+		mode         interface{}
+		expected     internal.TarFsyncModeMigration
+	}{
+		{nil, nil, internal.TarFsyncModeMigration{Mode: internal.DefaultTarFsyncMode}},
+		{false, nil, internal.TarFsyncModeMigration{Mode: internal.DefaultTarFsyncMode}},
+		{true, nil, internal.TarFsyncModeMigration{Mode: internal.DisabledTarFsyncMode, DeprecatedDisableFsync: true}},
+		{true, "DISABLED", internal.TarFsyncModeMigration{Mode: internal.DisabledTarFsyncMode, DeprecatedDisableFsync: true}},
+		{true, "GLOBAL", internal.TarFsyncModeMigration{
+			Mode: internal.GlobalTarFsyncMode, DeprecatedDisableFsync: true, DeprecatedIgnored: true}},
+		{true, "default", internal.TarFsyncModeMigration{
+			Mode: internal.DefaultTarFsyncMode, DeprecatedDisableFsync: true, DeprecatedIgnored: true}},
+		{false, "GLOBAL", internal.TarFsyncModeMigration{Mode: internal.GlobalTarFsyncMode}},
+		{false, "PER_FILE_DATASYNC", internal.TarFsyncModeMigration{Mode: internal.PerFileDatasyncTarFsyncMode}},
+	}
+	defer viper.Set(internal.TarDisableFsyncSetting, nil)
+	defer viper.Set(internal.TarFsyncModeSetting, nil)
+
+	for _, testCase := range testCases {
+		viper.Set(internal.TarDisableFsyncSetting, testCase.disableFsync)
+		viper.Set(internal.TarFsyncModeSetting, testCase.mode)
+		migration, err := internal.MigrateTarFsyncMode()
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, migration, "disable fsync: %v, mode: %v", testCase.disableFsync, testCase.mode)
+	}
+
+	viper.Set(internal.TarFsyncModeSetting, "SOMETIMES")
+	_, err := internal.MigrateTarFsyncMode()
+	assert.Error(t, err)
+}
+
+func TestHandleTarFsyncModeMigrate_WritesConfig(t *testing.T) {
+	viper.Set(internal.TarDisableFsyncSetting, true)
+	defer viper.Set(internal.TarDisableFsyncSetting, nil)
+	configFile := filepath.Join(t.TempDir(), ".walg.json")
+	assert.NoError(t, os.WriteFile(configFile, []byte(`{"WALG_TAR_DISABLE_FSYNC": "true", "PGDATA": "/data"}`), 0600))
+
+	var output bytes.Buffer
+	assert.NoError(t, internal.HandleTarFsyncModeMigrate(&output, configFile))
+	assert.Equal(t, "WALG_TAR_FSYNC_MODE=DISABLED\n", output.String())
+
+	content, err := os.ReadFile(configFile)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"WALG_TAR_FSYNC_MODE": "DISABLED", "PGDATA": "/data"}`, string(content))
+	info, err := os.Stat(configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Error(t, internal.WriteTarFsyncModeToConfig(filepath.Join(t.TempDir(), ".walg.yaml"), internal.DisabledTarFsyncMode))
+}
+
+func TestParseTarFsyncModeOverrides(t *testing.T) {
+	overrides, err := internal.ParseTarFsyncModeOverrides([]string{"/mnt/scratch/=disabled", " /data/pg_wal = GLOBAL"})
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.TarFsyncModeOverride{
+		{PathPrefix: "/mnt/scratch", Mode: internal.DisabledTarFsyncMode},
+		{PathPrefix: "/data/pg_wal", Mode: internal.GlobalTarFsyncMode},
+	}, overrides)
+
+	for _, invalid := range [][]string{{"/mnt/scratch"}, {"=DISABLED"}, {"/mnt/scratch=SOMETIMES"}, {"/mnt/a=DEFAULT", "/mnt/a/=GLOBAL"}, {"mnt/scratch=DISABLED"}} {
+		_, err = internal.ParseTarFsyncModeOverrides(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGetTarFsyncModes_TablespaceDisabled(t *testing.T) {
+	viper.Set(internal.TarFsyncModeOverridesSetting, "/mnt/scratch=DISABLED,/data/pg_tblspc/16400/PG_14=PER_FILE_DATASYNC")
+	defer viper.Set(internal.TarFsyncModeOverridesSetting, nil)
+
+	modes, err := internal.GetTarFsyncModes()
+	assert.NoError(t, err)
+	assert.Equal(t, internal.DefaultTarFsyncMode, modes.ModeFor("/data/base/1/1259"))
+	assert.Equal(t, internal.DisabledTarFsyncMode, modes.ModeFor("/mnt/scratch/PG_14/16384/16385"))
+	assert.Equal(t, internal.DisabledTarFsyncMode, modes.ModeFor("/mnt/scratch"))
+	assert.Equal(t, internal.DefaultTarFsyncMode, modes.ModeFor("/mnt/scratch2/file"))
+	assert.Equal(t, internal.PerFileDatasyncTarFsyncMode, modes.ModeFor("/data/pg_tblspc/16400/PG_14/16384/16385"))
+	assert.True(t, modes.Uses(internal.DisabledTarFsyncMode))
+	assert.True(t, modes.Uses(internal.DefaultTarFsyncMode))
+	assert.False(t, modes.Uses(internal.GlobalTarFsyncMode))
+
+	viper.Set(internal.TarFsyncModeOverridesSetting, "/mnt/scratch")
+	_, err = internal.GetTarFsyncModes()
+	assert.Error(t, err)
+}
+
+func TestTarFsyncModes_ModeForResolvesTablespaceSymlink(t *testing.T) {
+	root := t.TempDir()
+	dataDirectory, scratch := filepath.Join(root, "data"), filepath.Join(root, "scratch")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dataDirectory, "pg_tblspc"), 0700))
+	assert.NoError(t, os.MkdirAll(scratch, 0700))
+	assert.NoError(t, os.Symlink(scratch, filepath.Join(dataDirectory, "pg_tblspc/16400")))
+	modes := internal.TarFsyncModes{Default: internal.DefaultTarFsyncMode, Overrides: []internal.TarFsyncModeOverride{
+		{PathPrefix: scratch, Mode: internal.DisabledTarFsyncMode},
+	}}
+
+	// the directories under the tablespace do not exist yet
+	assert.Equal(t, internal.DisabledTarFsyncMode, modes.ModeFor(filepath.Join(dataDirectory, "pg_tblspc/16400/PG_14/16384/16385")))
+	assert.Equal(t, internal.DefaultTarFsyncMode, modes.ModeFor(filepath.Join(dataDirectory, "base/1/1259")))
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RestoreTarInterpreter is the TarInterpreter extracting the backup files to disk,
+// OnInterpretFinish should be called once all the tars are extracted, e.g. to flush the files per the fsync mode
+type RestoreTarInterpreter interface {
+	TarInterpreter
+	OnInterpretFinish() error
+}
+
+// ArchiveFailureRecorder is the TarInterpreter able to continue the restore past the corrupt archive,
+// e.g. the one failed to decompress or with the broken tar headers. RecordArchiveFailure returns false
+// if the failure must abort the extraction. It is called once the retries of the archive are exhausted,
+// the remaining files of the recorded archive are skipped.
+type ArchiveFailureRecorder interface {
+	RecordArchiveFailure(archivePath string, err error) bool
+}
+
+// ProgressReporter receives the notifications about the files extracted by the RestoreTarInterpreter.
+// Methods may be called concurrently from the different extraction goroutines.
+type ProgressReporter interface {
+	// OnFileStart is called before the file is extracted
+	OnFileStart(name string, size int64)
+	// OnFileComplete is called after the file is extracted,
+	// totalBytes is the cumulative size of all files extracted so far
+	OnFileComplete(name string, bytes int64, totalBytes int64)
+	// OnFileSkipped is called for the files which are not required to be extracted
+	OnFileSkipped(name string)
+}
+
+// TarInterpreterOptions are passed to the registered RestoreTarInterpreter constructors
+type TarInterpreterOptions struct {
+	DataDirectory string
+	// FilesToUnwrap restricts the extracted files, all the files are extracted if it is nil
+	FilesToUnwrap map[string]bool
+	// Metadata is the engine specific backup metadata, the constructor rejects the type it does not know
+	Metadata interface{}
+	// ProgressReporter is notified about the extracted files, if set
+	ProgressReporter ProgressReporter
+}
+
+// TarInterpreterConstructor creates the engine's RestoreTarInterpreter
+type TarInterpreterConstructor func(options TarInterpreterOptions) (RestoreTarInterpreter, error)
+
+var (
+	tarInterpreterConstructors      = make(map[string]TarInterpreterConstructor)
+	tarInterpreterConstructorsMutex sync.RWMutex
+)
+
+// RegisterTarInterpreter makes the engine's RestoreTarInterpreter available by NewTarInterpreter,
+// it is expected to be called from the engine package init
+func RegisterTarInterpreter(engine string, constructor TarInterpreterConstructor) {
+	tarInterpreterConstructorsMutex.Lock()
+	defer tarInterpreterConstructorsMutex.Unlock()
+	if _, ok := tarInterpreterConstructors[engine]; ok {
+		panic(fmt.Sprintf("tar interpreter for '%s' is already registered", engine))
+	}
+	tarInterpreterConstructors[engine] = constructor
+}
+
+// NewTarInterpreter creates the RestoreTarInterpreter registered for the engine
+func NewTarInterpreter(engine string, options TarInterpreterOptions) (RestoreTarInterpreter, error) {
+	tarInterpreterConstructorsMutex.RLock()
+	constructor, ok := tarInterpreterConstructors[engine]
+	tarInterpreterConstructorsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no tar interpreter is registered for '%s', registered are: %s",
+			engine, strings.Join(RegisteredTarInterpreters(), ", "))
+	}
+	return constructor(options)
+}
+
+// RegisteredTarInterpreters returns the sorted names of the engines which registered the tar interpreter
+func RegisteredTarInterpreters() []string {
+	tarInterpreterConstructorsMutex.RLock()
+	defer tarInterpreterConstructorsMutex.RUnlock()
+	engines := make([]string, 0, len(tarInterpreterConstructors))
+	for engine := range tarInterpreterConstructors {
+		engines = append(engines, engine)
+	}
+	sort.Strings(engines)
+	return engines
+}
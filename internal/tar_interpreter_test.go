@@ -0,0 +1,40 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+type finishingTarInterpreter struct {
+	options  internal.TarInterpreterOptions
+	finished bool
+}
+
+func (interpreter *finishingTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	return nil
+}
+
+func (interpreter *finishingTarInterpreter) OnInterpretFinish() error {
+	interpreter.finished = true
+	return nil
+}
+
+func TestTarInterpreterRegistry(t *testing.T) {
+	constructor := func(options internal.TarInterpreterOptions) (internal.RestoreTarInterpreter, error) {
+		return &finishingTarInterpreter{options: options}, nil
+	}
+	internal.RegisterTarInterpreter("registry-test", constructor)
+	assert.Contains(t, internal.RegisteredTarInterpreters(), "registry-test")
+	assert.Panics(t, func() { internal.RegisterTarInterpreter("registry-test", constructor) })
+
+	interpreter, err := internal.NewTarInterpreter("registry-test", internal.TarInterpreterOptions{DataDirectory: "/data"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/data", interpreter.(*finishingTarInterpreter).options.DataDirectory)
+
+	_, err = internal.NewTarInterpreter("unknown", internal.TarInterpreterOptions{})
+	assert.Error(t, err)
+}
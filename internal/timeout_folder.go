@@ -0,0 +1,341 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// StorageOperationTimeoutError is returned by the storage operation making no progress for the timeout
+type StorageOperationTimeoutError struct {
+	error
+}
+
+func newStorageOperationTimeoutError(operation string, path string, timeout time.Duration) StorageOperationTimeoutError {
+	return StorageOperationTimeoutError{errors.Errorf("storage %s of '%s' made no progress for %v", operation, path, timeout)}
+}
+
+func (err StorageOperationTimeoutError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// TimeoutFolder fails the operations of the wrapped folder making no progress for the timeout, so the hung
+// connection fails the operation to be retried rather than stalls it forever. The timeout is an idle one:
+// it is reset by every chunk of the object read or the content pushed, so the large transfers are not limited
+// as long as the bytes are flowing. The operations without the transfer, e.g. the listings, are limited as a whole.
+// The timed out operation is abandoned: its goroutine ends once the storage client returns.
+type TimeoutFolder struct {
+	storage.Folder
+	timeout time.Duration
+}
+
+// timeoutRangeFolder is the TimeoutFolder of the folder able to read the object ranges
+type timeoutRangeFolder struct {
+	*TimeoutFolder
+	rangeReader storage.RangeReader
+}
+
+var _ storage.RangeReader = timeoutRangeFolder{}
+
+// NewTimeoutFolder wraps the folder to fail its operations idle for the timeout,
+// the range reads are passed through with the same timeout if the folder supports them
+func NewTimeoutFolder(folder storage.Folder, timeout time.Duration) storage.Folder {
+	timeoutFolder := &TimeoutFolder{Folder: folder, timeout: timeout}
+	if rangeReader, ok := folder.(storage.RangeReader); ok {
+		return timeoutRangeFolder{TimeoutFolder: timeoutFolder, rangeReader: rangeReader}
+	}
+	return timeoutFolder
+}
+
+// ConfigureStorageOpTimeout wraps the folder to fail its operations idle for WALG_STORAGE_OP_TIMEOUT,
+// the folder is returned as is if the setting is not set or zero
+func ConfigureStorageOpTimeout(folder storage.Folder) (storage.Folder, error) {
+	if !viper.IsSet(StorageOpTimeoutSetting) {
+		return folder, nil
+	}
+	timeout, err := GetDurationSetting(StorageOpTimeoutSetting)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		return folder, nil
+	}
+	return NewTimeoutFolder(folder, timeout), nil
+}
+
+func (folder *TimeoutFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewTimeoutFolder(folder.Folder.GetSubFolder(subFolderRelativePath), folder.timeout)
+}
+
+func (folder *TimeoutFolder) Exists(objectRelativePath string) (bool, error) {
+	var exists bool
+	err := folder.run("check", objectRelativePath, nil, func() (err error) {
+		exists, err = folder.Folder.Exists(objectRelativePath)
+		return err
+	})
+	return exists, err
+}
+
+func (folder *TimeoutFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	var objects []storage.Object
+	var subFolders []storage.Folder
+	err := folder.run("listing", folder.GetPath(), nil, func() (err error) {
+		objects, subFolders, err = folder.Folder.ListFolder()
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, subFolder := range subFolders {
+		subFolders[i] = NewTimeoutFolder(subFolder, folder.timeout)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *TimeoutFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.run("deletion", folder.GetPath(), nil, func() error {
+		return folder.Folder.DeleteObjects(objectRelativePaths)
+	})
+}
+
+func (folder *TimeoutFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.run("copy", srcPath, nil, func() error {
+		return folder.Folder.CopyObject(srcPath, dstPath)
+	})
+}
+
+// PutObject fails once the storage client neither reads the content nor returns for the timeout
+func (folder *TimeoutFolder) PutObject(name string, content io.Reader) error {
+	progress := newStorageProgress()
+	trackedContent := &progressReader{reader: content, progress: progress}
+	err := folder.run("upload", name, progress, func() error {
+		return folder.Folder.PutObject(name, trackedContent)
+	})
+	if _, ok := err.(StorageOperationTimeoutError); ok {
+		// make the abandoned upload fail instead of completing the object later
+		trackedContent.abort(err)
+	}
+	return err
+}
+
+// ReadObject fails once the object is not opened for the timeout,
+// the returned reader fails the Read waiting for the next chunk for the timeout
+func (folder *TimeoutFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.openReader(objectRelativePath, func() (io.ReadCloser, error) {
+		return folder.Folder.ReadObject(objectRelativePath)
+	})
+}
+
+func (folder timeoutRangeFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	var version string
+	reader, err := folder.openReader(objectRelativePath, func() (io.ReadCloser, error) {
+		reader, readVersion, err := folder.rangeReader.ReadObjectVersion(objectRelativePath)
+		version = readVersion
+		return reader, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return reader, version, nil
+}
+
+func (folder timeoutRangeFolder) ReadObjectRange(objectRelativePath string, offset int64,
+	version string) (io.ReadCloser, error) {
+	return folder.openReader(objectRelativePath, func() (io.ReadCloser, error) {
+		return folder.rangeReader.ReadObjectRange(objectRelativePath, offset, version)
+	})
+}
+
+func (folder *TimeoutFolder) openReader(objectRelativePath string, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	var mutex sync.Mutex
+	abandoned := false
+	err := folder.run("download", objectRelativePath, nil, func() error {
+		openedReader, err := open()
+		mutex.Lock()
+		defer mutex.Unlock()
+		if abandoned && openedReader != nil {
+			// nobody waits for the reader opened after the timeout
+			return openedReader.Close()
+		}
+		reader = openedReader
+		return err
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if err != nil {
+		abandoned = true
+		if reader != nil {
+			utility.LoggedClose(reader, "")
+		}
+		return nil, err
+	}
+	return newIdleTimeoutReader(reader, objectRelativePath, folder.timeout), nil
+}
+
+// run waits for the operation until it makes no progress for the timeout, the operation without the progress
+// reports is limited as a whole. The results set by op are read only if it is done in time,
+// the abandoned one sets them unobserved.
+func (folder *TimeoutFolder) run(operation string, path string, progress *storageProgress, op func() error) error {
+	if progress == nil {
+		progress = newStorageProgress()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- op()
+	}()
+
+	timer := time.NewTimer(folder.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-timer.C:
+			idle := progress.idleTime()
+			if idle >= folder.timeout {
+				return newStorageOperationTimeoutError(operation, path, folder.timeout)
+			}
+			timer.Reset(folder.timeout - idle)
+		}
+	}
+}
+
+// storageProgress is the time of the last progress of the storage operation
+type storageProgress struct {
+	lastProgress int64
+}
+
+func newStorageProgress() *storageProgress {
+	progress := &storageProgress{}
+	progress.report()
+	return progress
+}
+
+func (progress *storageProgress) report() {
+	atomic.StoreInt64(&progress.lastProgress, time.Now().UnixNano())
+}
+
+func (progress *storageProgress) idleTime() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&progress.lastProgress)))
+}
+
+// progressReader reports the progress on every Read of the content being pushed
+type progressReader struct {
+	reader   io.Reader
+	progress *storageProgress
+
+	mutex sync.Mutex
+	err   error
+}
+
+func (reader *progressReader) Read(p []byte) (int, error) {
+	reader.mutex.Lock()
+	err := reader.err
+	reader.mutex.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := reader.reader.Read(p)
+	reader.progress.report()
+	return n, err
+}
+
+func (reader *progressReader) abort(err error) {
+	reader.mutex.Lock()
+	defer reader.mutex.Unlock()
+	reader.err = err
+}
+
+type readChunk struct {
+	data []byte
+	err  error
+}
+
+// idleTimeoutReader reads the object by the background goroutine, so the Read waiting for the next chunk
+// longer than the timeout fails instead of blocking on the hung connection. The time spent by the consumer
+// between the Reads is not counted.
+type idleTimeoutReader struct {
+	source  io.ReadCloser
+	path    string
+	timeout time.Duration
+
+	startOnce sync.Once
+	chunks    chan readChunk
+	stop      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	pending []byte
+	err     error
+}
+
+func newIdleTimeoutReader(source io.ReadCloser, path string, timeout time.Duration) *idleTimeoutReader {
+	return &idleTimeoutReader{source: source, path: path, timeout: timeout,
+		chunks: make(chan readChunk), stop: make(chan struct{})}
+}
+
+func (reader *idleTimeoutReader) Read(p []byte) (int, error) {
+	if len(reader.pending) == 0 && reader.err == nil {
+		reader.startOnce.Do(func() { go reader.readChunks(len(p)) })
+		timer := time.NewTimer(reader.timeout)
+		select {
+		case chunk := <-reader.chunks:
+			reader.pending, reader.err = chunk.data, chunk.err
+		case <-timer.C:
+			reader.err = newStorageOperationTimeoutError("download", reader.path, reader.timeout)
+			// unblock the background read, most storage clients abort the transfer on close
+			_ = reader.closeSource()
+		}
+		timer.Stop()
+	}
+	if len(reader.pending) > 0 {
+		n := copy(p, reader.pending)
+		reader.pending = reader.pending[n:]
+		return n, nil
+	}
+	return 0, reader.err
+}
+
+func (reader *idleTimeoutReader) readChunks(chunkSize int) {
+	if chunkSize < minIdleTimeoutChunkSize {
+		chunkSize = minIdleTimeoutChunkSize
+	}
+	for {
+		buffer := make([]byte, chunkSize)
+		n, err := reader.source.Read(buffer)
+		if n == 0 && err == nil {
+			continue
+		}
+		select {
+		case reader.chunks <- readChunk{data: buffer[:n], err: err}:
+		case <-reader.stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+const minIdleTimeoutChunkSize = 32 * 1024
+
+func (reader *idleTimeoutReader) Close() error {
+	return reader.closeSource()
+}
+
+func (reader *idleTimeoutReader) closeSource() error {
+	reader.closeOnce.Do(func() {
+		close(reader.stop)
+		reader.closeErr = reader.source.Close()
+	})
+	return reader.closeErr
+}
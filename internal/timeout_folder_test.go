@@ -0,0 +1,102 @@
+package internal_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const testStorageOpTimeout = 100 * time.Millisecond
+
+// slowFolder delays the chunks of the read and pushed objects, the hung operations block until unblock is closed
+type slowFolder struct {
+	storage.Folder
+	chunkDelay time.Duration
+	hang       bool
+	unblock    chan struct{}
+}
+
+func (folder *slowFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	if folder.hang {
+		<-folder.unblock
+	}
+	return folder.Folder.ListFolder()
+}
+
+func (folder *slowFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.Folder.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	return &slowReader{ReadCloser: reader, folder: folder}, nil
+}
+
+func (folder *slowFolder) PutObject(name string, content io.Reader) error {
+	return folder.Folder.PutObject(name, &slowReader{ReadCloser: io.NopCloser(content), folder: folder})
+}
+
+// slowReader reads one byte per the chunk delay, the hung reader blocks after the first byte
+type slowReader struct {
+	io.ReadCloser
+	folder *slowFolder
+	read   int
+}
+
+func (reader *slowReader) Read(p []byte) (int, error) {
+	if reader.folder.hang && reader.read > 0 {
+		<-reader.folder.unblock
+	}
+	time.Sleep(reader.folder.chunkDelay)
+	reader.read++
+	return reader.ReadCloser.Read(p[:1])
+}
+
+func newSlowTestFolder(t *testing.T, hang bool) (storage.Folder, func()) {
+	baseFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	assert.NoError(t, baseFolder.PutObject("object", bytes.NewBufferString("0123456789")))
+	folder := &slowFolder{Folder: baseFolder, chunkDelay: testStorageOpTimeout / 5, hang: hang, unblock: make(chan struct{})}
+	return internal.NewTimeoutFolder(folder, testStorageOpTimeout), func() { close(folder.unblock) }
+}
+
+func TestTimeoutFolder_ActiveTransfers(t *testing.T) {
+	folder, unblock := newSlowTestFolder(t, false)
+	defer unblock()
+
+	// the transfers take longer than the timeout, but each chunk comes in time
+	reader, err := folder.ReadObject("object")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(content))
+	assert.NoError(t, reader.Close())
+
+	assert.NoError(t, folder.PutObject("pushed", bytes.NewBufferString("0123456789")))
+	objects, _, err := folder.ListFolder()
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
+
+func TestTimeoutFolder_IdleTransfers(t *testing.T) {
+	folder, unblock := newSlowTestFolder(t, true)
+	defer unblock()
+
+	reader, err := folder.ReadObject("object")
+	assert.NoError(t, err)
+	started := time.Now()
+	_, err = io.ReadAll(reader)
+	assert.IsType(t, internal.StorageOperationTimeoutError{}, err)
+	assert.Less(t, time.Since(started), 10*testStorageOpTimeout)
+	_ = reader.Close()
+
+	err = folder.PutObject("pushed", bytes.NewBufferString("0123456789"))
+	assert.IsType(t, internal.StorageOperationTimeoutError{}, err)
+
+	_, _, err = folder.ListFolder()
+	assert.IsType(t, internal.StorageOperationTimeoutError{}, err)
+}
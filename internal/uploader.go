@@ -26,6 +26,7 @@ type UploaderProvider interface {
 	DisableSizeTracking()
 	UploadedDataSize() (int64, error)
 	RawDataSize() (int64, error)
+	CompressedStreamDigests() map[string]string
 	ChangeDirectory(relativePath string)
 	Folder() storage.Folder
 }
@@ -41,6 +42,7 @@ type Uploader struct {
 	Failed                 atomic.Value
 	tarSize                *int64
 	dataSize               *int64
+	streamDigests          *streamDigests
 }
 
 var _ UploaderProvider = &Uploader{}
@@ -72,6 +74,7 @@ func NewUploader(
 		waitGroup:       &sync.WaitGroup{},
 		tarSize:         new(int64),
 		dataSize:        new(int64),
+		streamDigests:   newStreamDigests(),
 	}
 	uploader.Failed.Store(false)
 	return uploader
@@ -95,6 +98,7 @@ func NewSplitStreamUploader(
 			waitGroup:       &sync.WaitGroup{},
 			tarSize:         new(int64),
 			dataSize:        new(int64),
+			streamDigests:   newStreamDigests(),
 		},
 		partitions: partitions,
 		blockSize:  blockSize,
@@ -119,6 +123,15 @@ func (uploader *Uploader) RawDataSize() (int64, error) {
 	return atomic.LoadInt64(uploader.dataSize), nil
 }
 
+// CompressedStreamDigests returns the hex-encoded SHA256 of the compressed streams pushed so far
+// by their destination paths
+func (uploader *Uploader) CompressedStreamDigests() map[string]string {
+	if uploader.streamDigests == nil {
+		return map[string]string{}
+	}
+	return uploader.streamDigests.copy()
+}
+
 // Finish waits for all waiting parts to be uploaded. If an error occurs,
 // prints alert to stderr.
 func (uploader *Uploader) Finish() {
@@ -138,6 +151,7 @@ func (uploader *Uploader) Clone() *Uploader {
 		Failed:               uploader.Failed,
 		tarSize:              uploader.tarSize,
 		dataSize:             uploader.dataSize,
+		streamDigests:        uploader.streamDigests,
 	}
 }
 
@@ -0,0 +1,61 @@
+//go:build !windows
+// +build !windows
+
+package internal
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// zstd trains the dictionaries best on the small samples, so only the beginning of each archive is used
+const maxZstdDictionarySampleSize = 128 << 10
+
+// TrainZstdDictionary samples sampleCount archives evenly spread over the folder,
+// trains the zstd dictionary of at most dictionarySize bytes on their content and writes it to the outputPath
+func TrainZstdDictionary(folder storage.Folder, sampleCount, dictionarySize int, outputPath string) error {
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return errors.Wrap(err, "failed to list archives to sample")
+	}
+	if len(objects) == 0 {
+		return errors.New("no archives found to sample")
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].GetName() < objects[j].GetName()
+	})
+
+	sampleCount = utility.Min(sampleCount, len(objects))
+	samples := make([][]byte, 0, sampleCount)
+	for i := 0; i < sampleCount; i++ {
+		archiveName := objects[i*len(objects)/sampleCount].GetName()
+		sample, err := readZstdDictionarySample(folder, archiveName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to sample archive '%s'", archiveName)
+		}
+		samples = append(samples, sample)
+	}
+
+	tracelog.InfoLogger.Printf("Training zstd dictionary on %d archives\n", len(samples))
+	dictionary, err := zstd.TrainDictionary(samples, dictionarySize)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, dictionary, 0644)
+}
+
+func readZstdDictionarySample(folder storage.Folder, archiveName string) ([]byte, error) {
+	reader, err := DownloadAndDecompressStorageFile(folder, utility.TrimFileExtension(archiveName))
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+	return io.ReadAll(io.LimitReader(reader, maxZstdDictionarySampleSize))
+}
@@ -0,0 +1,30 @@
+// Package compression lets the custom builds of WAL-G add their own compression methods.
+//
+// The plugin package registers its codec from init() and is linked into the build by the blank import:
+//
+//	func init() {
+//		compression.MustRegisterCompressor("mycodec", "myc", myCompressor{}, myDecompressor{})
+//	}
+package compression
+
+import (
+	"github.com/wal-g/wal-g/internal/compression"
+)
+
+// Compressor makes the writer compressing the data into the files with its FileExtension
+type Compressor = compression.Compressor
+
+// Decompressor reads the files with its FileExtension
+type Decompressor = compression.Decompressor
+
+// RegisterCompressor adds the compression method selected by the name in WALG_COMPRESSION_METHOD,
+// the decompressor is found by the ext of the stored files. Either of c and d may be nil.
+// It must be called only during the program initialization, see the internal compression.RegisterCompressor.
+func RegisterCompressor(name, ext string, c Compressor, d Decompressor) error {
+	return compression.RegisterCompressor(name, ext, c, d)
+}
+
+// MustRegisterCompressor is RegisterCompressor panicking on the error, it is convenient in init()
+func MustRegisterCompressor(name, ext string, c Compressor, d Decompressor) {
+	compression.MustRegisterCompressor(name, ext, c, d)
+}
@@ -0,0 +1,45 @@
+package compression_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	internalcompression "github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/compression"
+)
+
+// plainCodec stores the data as is under its own extension
+type plainCodec struct{}
+
+func (plainCodec) FileExtension() string {
+	return "plain"
+}
+
+func (plainCodec) NewWriter(writer io.Writer) io.WriteCloser {
+	return nopWriteCloser{writer}
+}
+
+func (plainCodec) Decompress(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(src), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestRegisterCompressor(t *testing.T) {
+	algorithms, decompressors := internalcompression.CompressingAlgorithms, internalcompression.Decompressors
+	defer func() {
+		delete(internalcompression.Compressors, "plain")
+		internalcompression.CompressingAlgorithms, internalcompression.Decompressors = algorithms, decompressors
+	}()
+
+	assert.NoError(t, compression.RegisterCompressor("plain", "plain", plainCodec{}, plainCodec{}))
+	assert.Equal(t, plainCodec{}, internalcompression.Compressors["plain"])
+	assert.Equal(t, plainCodec{}, internalcompression.FindDecompressor("plain"))
+	assert.Error(t, compression.RegisterCompressor("plain", "plain", plainCodec{}, nil))
+	assert.Panics(t, func() { compression.MustRegisterCompressor("other", "plain", nil, plainCodec{}) })
+}
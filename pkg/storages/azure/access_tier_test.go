@@ -0,0 +1,84 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAccessTier(t *testing.T) {
+	tier, err := getAccessTier(map[string]string{})
+	assert.NoError(t, err)
+	assert.Nil(t, tier)
+
+	for value, expected := range map[string]azblob.AccessTier{
+		"Hot":     azblob.AccessTierHot,
+		"cool":    azblob.AccessTierCool,
+		"ARCHIVE": azblob.AccessTierArchive,
+	} {
+		tier, err = getAccessTier(map[string]string{AccessTierSetting: value})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, *tier)
+	}
+
+	for _, value := range []string{"P10", "Premium", "glacier"} {
+		_, err = getAccessTier(map[string]string{AccessTierSetting: value})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), AccessTierSetting)
+	}
+}
+
+func TestPutObject_KeepsMetadataOutOfArchiveTier(t *testing.T) {
+	var mutex sync.Mutex
+	tiers := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("comp") == "tier" {
+			mutex.Lock()
+			tiers[strings.TrimPrefix(r.URL.Path, "/container/backups/")] = r.Header.Get("x-ms-access-tier")
+			mutex.Unlock()
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	containerClient, err := azblob.NewContainerClientWithNoCredential(server.URL+"/container", nil)
+	assert.NoError(t, err)
+	folder := NewFolder(azblob.UploadStreamToBlockBlobOptions{AccessTier: azblob.AccessTierArchive.ToPtr()},
+		containerClient, nil, 0, "backups/")
+
+	for _, name := range []string{"base_1/tar_partitions/part_1.tar.lz4", "base_1_backup_stop_sentinel.json",
+		"base_1/metadata.json"} {
+		assert.NoError(t, folder.PutObject(name, strings.NewReader("content")))
+	}
+	assert.Equal(t, map[string]string{"base_1/tar_partitions/part_1.tar.lz4": string(azblob.AccessTierArchive)}, tiers)
+}
+
+func TestReadObject_ArchivedBlob(t *testing.T) {
+	for errorCode, rehydrating := range map[azblob.StorageErrorCode]bool{
+		azblob.StorageErrorCodeBlobArchived:        false,
+		azblob.StorageErrorCodeBlobBeingRehydrated: true,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("x-ms-error-code", string(errorCode))
+			w.WriteHeader(http.StatusConflict)
+		}))
+		containerClient, err := azblob.NewContainerClientWithNoCredential(server.URL+"/container", nil)
+		assert.NoError(t, err)
+		folder := NewFolder(azblob.UploadStreamToBlockBlobOptions{}, containerClient, nil, 0, "backups/")
+
+		_, err = folder.ReadObject("base_000000010000000000000002/tar_partitions/part_1.tar.lz4")
+		var archivedErr ArchivedBlobError
+		assert.True(t, errors.As(err, &archivedErr))
+		assert.Equal(t, rehydrating, archivedErr.Rehydrating)
+		assert.Equal(t, "backups/base_000000010000000000000002/tar_partitions/part_1.tar.lz4", archivedErr.Path)
+		assert.Contains(t, err.Error(), "Archive tier")
+		server.Close()
+	}
+}
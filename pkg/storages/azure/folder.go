@@ -30,6 +30,7 @@ const (
 	BufferSizeSetting = "AZURE_BUFFER_SIZE"
 	MaxBuffersSetting = "AZURE_MAX_BUFFERS"
 	TryTimeoutSetting = "AZURE_TRY_TIMEOUT"
+	AccessTierSetting = "AZURE_ACCESS_TIER"
 	minBufferSize     = 1024
 	defaultBufferSize = 8 * 1024 * 1024
 	minBuffers        = 1
@@ -46,8 +47,12 @@ var SettingList = []string{
 	EndpointSuffix,
 	BufferSizeSetting,
 	MaxBuffersSetting,
+	AccessTierSetting,
 }
 
+// accessTiers are the tiers the uploaded block blobs may be put to
+var accessTiers = []azblob.AccessTier{azblob.AccessTierHot, azblob.AccessTierCool, azblob.AccessTierArchive}
+
 func NewFolderError(err error, format string, args ...interface{}) storage.Error {
 	return storage.NewError(err, "Azure", format, args...)
 }
@@ -57,6 +62,29 @@ func NewCredentialError(settingName string) storage.Error {
 		"%s setting is not set", settingName)
 }
 
+// ArchivedBlobError is returned by ReadObject if the blob is in the Archive tier,
+// it can not be read until it is rehydrated to the Hot or the Cool tier
+type ArchivedBlobError struct {
+	error
+	Path string
+	// Rehydrating is true if the rehydration of the blob is already in progress
+	Rehydrating bool
+}
+
+func newArchivedBlobError(path string, rehydrating bool) ArchivedBlobError {
+	if rehydrating {
+		return ArchivedBlobError{errors.Errorf("object '%s' is being rehydrated from the Archive tier, "+
+			"retry once the rehydration is complete, it may take several hours", path), path, true}
+	}
+	return ArchivedBlobError{errors.Errorf("object '%s' is in the Archive tier, rehydration required: "+
+		"set the tier of the blob to Hot or Cool, e.g. by 'az storage blob set-tier', "+
+		"and retry once the rehydration is complete", path), path, false}
+}
+
+func (err ArchivedBlobError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 func NewFolder(
 	uploadStreamToBlockBlobOptions azblob.UploadStreamToBlockBlobOptions,
 	containerClient azblob.ContainerClient,
@@ -148,8 +176,12 @@ func ConfigureFolder(prefix string, settings map[string]string) (storage.Folder,
 	if err != nil {
 		return nil, NewFolderError(err, "Unable to create service client")
 	}
+	uploadStreamToBlockBlobOptions := getUploadStreamToBlockBlobOptions(settings)
+	if uploadStreamToBlockBlobOptions.AccessTier, err = getAccessTier(settings); err != nil {
+		return nil, err
+	}
 	path = storage.AddDelimiterToPath(path)
-	return NewFolder(getUploadStreamToBlockBlobOptions(settings), containerClient, credential, timeout, path), nil
+	return NewFolder(uploadStreamToBlockBlobOptions, containerClient, credential, timeout, path), nil
 }
 
 type Folder struct {
@@ -359,8 +391,11 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		resp.Body.Close()
+		errorCode := azblob.StorageErrorCode(resp.Header.Get("x-ms-error-code"))
 		if resp.StatusCode == 404 {
 			return nil, storage.NewObjectNotFoundError(path)
+		} else if errorCode == azblob.StorageErrorCodeBlobArchived || errorCode == azblob.StorageErrorCodeBlobBeingRehydrated {
+			return nil, newArchivedBlobError(path, errorCode == azblob.StorageErrorCodeBlobBeingRehydrated)
 		} else {
 			return nil, NewFolderError(errors.New(resp.Status), "Unable to download blob %s.", path)
 		}
@@ -378,6 +413,12 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 	if err != nil {
 		return NewFolderError(err, "Unable to upload blob %v", name)
 	}
+	// the tier is set by the separate request, since the stream upload does not pass it to the block list commit
+	if tier := folder.accessTier(name); tier != nil {
+		if _, err = blobClient.SetTier(context.Background(), *tier, nil); err != nil {
+			return NewFolderError(err, "Unable to set access tier %v of blob %v", *tier, name)
+		}
+	}
 
 	tracelog.DebugLogger.Printf("Put %v done\n", name)
 	return nil
@@ -393,7 +434,11 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	}
 	srcClient := folder.containerClient.NewBlockBlobClient(srcPath)
 	dstClient := folder.containerClient.NewBlockBlobClient(dstPath)
-	_, err := dstClient.StartCopyFromURL(context.Background(), srcClient.URL(), &azblob.StartCopyBlobOptions{Tier: azblob.AccessTierHot.ToPtr()})
+	tier := folder.accessTier(dstPath)
+	if tier == nil {
+		tier = azblob.AccessTierHot.ToPtr()
+	}
+	_, err := dstClient.StartCopyFromURL(context.Background(), srcClient.URL(), &azblob.StartCopyBlobOptions{Tier: tier})
 	return err
 }
 
@@ -433,6 +478,35 @@ func getUploadStreamToBlockBlobOptions(settings map[string]string) azblob.Upload
 	return azblob.UploadStreamToBlockBlobOptions{MaxBuffers: maxBuffers, BufferSize: bufferSize}
 }
 
+// getAccessTier returns the tier of the uploaded blobs set by the AccessTierSetting,
+// nil if it is not set, so the default tier of the account is used
+func getAccessTier(settings map[string]string) (*azblob.AccessTier, error) {
+	value, ok := settings[AccessTierSetting]
+	if !ok || value == "" {
+		return nil, nil
+	}
+	allowed := make([]string, 0, len(accessTiers))
+	for _, tier := range accessTiers {
+		if strings.EqualFold(value, string(tier)) {
+			return tier.ToPtr(), nil
+		}
+		allowed = append(allowed, string(tier))
+	}
+	return nil, NewFolderError(errors.Errorf("unknown access tier '%s'", value),
+		"Invalid %s setting, allowed values: %s", AccessTierSetting, strings.Join(allowed, ", "))
+}
+
+// accessTier returns the configured tier of the uploaded blob. The sentinels and the metadata are kept
+// in the default tier of the account instead of the Archive one, since they are read by backup-list and backup-fetch
+// to find the archived blobs to rehydrate.
+func (folder *Folder) accessTier(name string) *azblob.AccessTier {
+	tier := folder.uploadStreamToBlockBlobOptions.AccessTier
+	if tier != nil && *tier == azblob.AccessTierArchive && strings.HasSuffix(name, ".json") {
+		return nil
+	}
+	return tier
+}
+
 // Function will get environment's name and return string with the environment's Azure storage account endpoint suffix.
 // Expected names AzureUSGovernmentCloud, AzureChinaCloud, AzureGermanCloud. If any other name is used the func will return
 // the Azure storage account endpoint suffix for AzurePublicCloud.
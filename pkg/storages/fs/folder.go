@@ -105,6 +105,54 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return file, nil
 }
 
+// ReadObjectVersion returns the file with the version made of its modification time and size
+func (folder *Folder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	file, err := folder.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	version, err := fileVersion(file.(*os.File))
+	if err != nil {
+		_ = file.Close()
+		return nil, "", NewError(err, "Unable to stat object %v", objectRelativePath)
+	}
+	return file, version, nil
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset int64, version string) (io.ReadCloser, error) {
+	file, err := folder.ReadObject(objectRelativePath)
+	if _, ok := err.(storage.ObjectNotFoundError); ok && version != "" {
+		return nil, storage.NewObjectChangedError(objectRelativePath, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		currentVersion, err := fileVersion(file.(*os.File))
+		if err != nil {
+			_ = file.Close()
+			return nil, NewError(err, "Unable to stat object %v", objectRelativePath)
+		}
+		if currentVersion != version {
+			_ = file.Close()
+			return nil, storage.NewObjectChangedError(objectRelativePath, version)
+		}
+	}
+	if _, err := file.(*os.File).Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, NewError(err, "Unable to seek object %v to %d", objectRelativePath, offset)
+	}
+	return file, nil
+}
+
+func fileVersion(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
 func (folder *Folder) PutObject(name string, content io.Reader) error {
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.subpath)
 	filePath := folder.GetFilePath(name)
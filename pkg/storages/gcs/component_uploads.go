@@ -0,0 +1,82 @@
+package gcs
+
+import (
+	"context"
+	"sync"
+
+	gcs "cloud.google.com/go/storage"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// componentUploads uploads the chunks of the object concurrently. The number of the chunks in flight is limited,
+// since each of them holds its buffer. The first failure cancels the rest of the uploads.
+type componentUploads struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  *semaphore.Weighted
+	group  errgroup.Group
+
+	createdMutex sync.Mutex
+	// created are the temporary chunks which may exist in the bucket, they are removed if the upload fails
+	created []*gcs.ObjectHandle
+}
+
+func newComponentUploads(ctx context.Context, maxParallelComponents int) *componentUploads {
+	if maxParallelComponents < 1 {
+		maxParallelComponents = 1
+	}
+	uploadsCtx, cancel := context.WithCancel(ctx)
+	return &componentUploads{ctx: uploadsCtx, cancel: cancel, slots: semaphore.NewWeighted(int64(maxParallelComponents))}
+}
+
+// acquire waits for the slot for the next chunk, it fails once any of the uploads fails
+func (uploads *componentUploads) acquire() error {
+	return uploads.slots.Acquire(uploads.ctx, 1)
+}
+
+func (uploads *componentUploads) release() {
+	uploads.slots.Release(1)
+}
+
+// upload uploads the chunk in the background, the slot acquired for the chunk is released once it is done
+func (uploads *componentUploads) upload(uploader *Uploader, chunk chunk) {
+	uploads.addCreated(uploader.objHandle)
+	uploads.group.Go(func() error {
+		defer uploads.release()
+		if err := uploader.UploadChunk(uploads.ctx, chunk); err != nil {
+			uploads.cancel()
+			return err
+		}
+		return nil
+	})
+}
+
+// wait waits for the started uploads and returns the first of their errors, the err if there is none
+func (uploads *componentUploads) wait(err error) error {
+	if waitErr := uploads.group.Wait(); waitErr != nil {
+		return waitErr
+	}
+	return err
+}
+
+// abort cancels the started uploads and waits for them to stop
+func (uploads *componentUploads) abort() {
+	uploads.cancel()
+	_ = uploads.group.Wait()
+}
+
+func (uploads *componentUploads) addCreated(object *gcs.ObjectHandle) {
+	uploads.createdMutex.Lock()
+	defer uploads.createdMutex.Unlock()
+	uploads.created = append(uploads.created, object)
+}
+
+// takeCreated returns the temporary chunks which are not removed yet and forgets them
+func (uploads *componentUploads) takeCreated() []*gcs.ObjectHandle {
+	uploads.createdMutex.Lock()
+	defer uploads.createdMutex.Unlock()
+	created := uploads.created
+	uploads.created = nil
+	return created
+}
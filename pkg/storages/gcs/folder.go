@@ -25,6 +25,8 @@ const (
 	EncryptionKey   = "GCS_ENCRYPTION_KEY"
 	MaxChunkSize    = "GCS_MAX_CHUNK_SIZE"
 	MaxRetries      = "GCS_MAX_RETRIES"
+	// MaxParallelComponents is the number of the object chunks uploaded concurrently before they are composed
+	MaxParallelComponents = "GCS_MAX_PARALLEL_COMPONENTS"
 
 	defaultContextTimeout = 60 * 60 // 1 hour
 	maxRetryDelay         = 5 * time.Minute
@@ -44,6 +46,7 @@ var (
 		EncryptionKey,
 		MaxChunkSize,
 		MaxRetries,
+		MaxParallelComponents,
 	}
 )
 
@@ -156,6 +159,19 @@ func getUploaderOptions(settings map[string]string) ([]UploaderOption, error) {
 		uploaderOptions = append(uploaderOptions, func(uploader *Uploader) { uploader.maxUploadRetries = maxRetries })
 	}
 
+	if maxParallelComponentsSetting, ok := settings[MaxParallelComponents]; ok {
+		maxParallelComponents, err := strconv.Atoi(maxParallelComponentsSetting)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid maximum parallel components setting")
+		}
+		if maxParallelComponents < 1 {
+			return nil, errors.Errorf("invalid maximum parallel components setting: %d is less than 1", maxParallelComponents)
+		}
+		uploaderOptions = append(uploaderOptions, func(uploader *Uploader) {
+			uploader.maxParallelComponents = maxParallelComponents
+		})
+	}
+
 	return uploaderOptions, nil
 }
 
@@ -277,17 +293,71 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return io.NopCloser(reader), err
 }
 
+// ReadObjectVersion returns the object with its generation as the version
+func (folder *Folder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	path := folder.joinPath(folder.path, objectRelativePath)
+	object := folder.BuildObjectHandle(path)
+	reader, err := object.NewReader(context.Background())
+	if err == gcs.ErrObjectNotExist {
+		return nil, "", storage.NewObjectNotFoundError(path)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return io.NopCloser(reader), strconv.FormatInt(reader.Attrs.Generation, 10), nil
+}
+
+// ReadObjectRange reads the object since the offset, the read is pinned to the generation if the version is set
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset int64, version string) (io.ReadCloser, error) {
+	path := folder.joinPath(folder.path, objectRelativePath)
+	object := folder.BuildObjectHandle(path)
+	if version != "" {
+		generation, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return nil, NewError(err, "Invalid generation '%s' of object %v", version, path)
+		}
+		object = object.Generation(generation)
+	}
+	reader, err := object.NewRangeReader(context.Background(), offset, -1)
+	if err == gcs.ErrObjectNotExist {
+		if version != "" {
+			return nil, storage.NewObjectChangedError(path, version)
+		}
+		return nil, storage.NewObjectNotFoundError(path)
+	}
+	return io.NopCloser(reader), err
+}
+
+// PutObject uploads the content by chunks, up to maxParallelComponents chunks are uploaded concurrently,
+// then the chunks are composed into the object. The temporary chunks are removed both on success and on failure.
 func (folder *Folder) PutObject(name string, content io.Reader) error {
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.path)
 	object := folder.BuildObjectHandle(folder.joinPath(folder.path, name))
+	objectUploader := NewUploader(object, folder.uploaderOptions...)
 
 	ctx, cancel := folder.createTimeoutContext()
 	defer cancel()
 
+	uploads := newComponentUploads(ctx, objectUploader.maxParallelComponents)
+	if err := folder.putObject(uploads, objectUploader, name, content); err != nil {
+		folder.cleanUpComponents(uploads)
+		return err
+	}
+
+	tracelog.DebugLogger.Printf("Put %v done\n", name)
+
+	return nil
+}
+
+func (folder *Folder) putObject(uploads *componentUploads, objectUploader *Uploader, name string, content io.Reader) error {
 	chunkNum := 0
 	tmpChunks := make([]*gcs.ObjectHandle, 0)
 
 	for {
+		if err := uploads.acquire(); err != nil {
+			return NewError(uploads.wait(err), "Unable to upload an object chunk")
+		}
+
 		tmpChunkName := folder.joinPath(name+"_chunks", "chunk"+strconv.Itoa(chunkNum))
 		objectChunk := folder.BuildObjectHandle(folder.joinPath(folder.path, tmpChunkName))
 		chunkUploader := NewUploader(objectChunk, folder.uploaderOptions...)
@@ -295,24 +365,23 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 
 		n, err := fillBuffer(content, dataChunk)
 		if err != nil && err != io.EOF {
+			uploads.release()
 			tracelog.ErrorLogger.Printf("Unable to read content of %s, err: %v", name, err)
+			uploads.abort()
 			return NewError(err, "Unable to read a chunk of data to upload")
 		}
 
 		if n == 0 {
+			uploads.release()
 			break
 		}
 
-		chunk := chunk{
+		uploads.upload(chunkUploader, chunk{
 			name:  tmpChunkName,
 			index: chunkNum,
 			data:  dataChunk,
 			size:  n,
-		}
-
-		if err := chunkUploader.UploadChunk(ctx, chunk); err != nil {
-			return NewError(err, "Unable to upload an object chunk")
-		}
+		})
 
 		tmpChunks = append(tmpChunks, objectChunk)
 
@@ -324,30 +393,54 @@ func (folder *Folder) PutObject(name string, content io.Reader) error {
 
 		if len(tmpChunks) == composeChunkLimit {
 			// Since there is a limit to the number of components that can be composed in a single operation, merge chunks partially.
+			if err := uploads.wait(nil); err != nil {
+				return NewError(err, "Unable to upload an object chunk")
+			}
+
 			compositeChunkName := folder.joinPath(name+"_chunks", "composite"+strconv.Itoa(chunkNum))
 			compositeChunk := folder.BuildObjectHandle(folder.joinPath(folder.path, compositeChunkName))
 
 			tracelog.DebugLogger.Printf("Compose temporary chunks into an intermediate chunk %v\n", compositeChunkName)
 
-			if err := composeChunks(ctx, NewUploader(compositeChunk, folder.uploaderOptions...), tmpChunks); err != nil {
+			uploads.addCreated(compositeChunk)
+			if err := composeChunks(uploads.ctx, NewUploader(compositeChunk, folder.uploaderOptions...), tmpChunks); err != nil {
 				return NewError(err, "Failed to compose temporary chunks into an intermediate chunk")
 			}
+			// the composed chunks are removed already, only the intermediate one is left
+			uploads.takeCreated()
+			uploads.addCreated(compositeChunk)
 
 			tmpChunks = []*gcs.ObjectHandle{compositeChunk}
 		}
 	}
 
-	tracelog.DebugLogger.Printf("Compose file %v from chunks\n", object.ObjectName())
+	if err := uploads.wait(nil); err != nil {
+		return NewError(err, "Unable to upload an object chunk")
+	}
+
+	tracelog.DebugLogger.Printf("Compose file %v from chunks\n", objectUploader.objHandle.ObjectName())
 
-	if err := composeChunks(ctx, NewUploader(object, folder.uploaderOptions...), tmpChunks); err != nil {
+	if err := composeChunks(uploads.ctx, objectUploader, tmpChunks); err != nil {
 		return NewError(err, "Failed to compose temporary chunks into an object")
 	}
-
-	tracelog.DebugLogger.Printf("Put %v done\n", name)
+	uploads.takeCreated()
 
 	return nil
 }
 
+// cleanUpComponents removes the temporary chunks left by the failed upload,
+// a fresh context is used since the upload one may be already cancelled
+func (folder *Folder) cleanUpComponents(uploads *componentUploads) {
+	created := uploads.takeCreated()
+	if len(created) == 0 {
+		return
+	}
+	tracelog.DebugLogger.Printf("Remove %d temporary chunks of the failed upload\n", len(created))
+	ctx, cancel := folder.createTimeoutContext()
+	defer cancel()
+	NewUploader(nil, folder.uploaderOptions...).CleanUpChunks(ctx, created)
+}
+
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	if exists, err := folder.Exists(srcPath); !exists {
 		if err == nil {
@@ -1,6 +1,7 @@
 package gcs
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -99,6 +100,22 @@ func TestUploadingReaderFails(t *testing.T) {
 	assert.EqualError(t, err, "GCS error : Unable to read a chunk of data to upload: failed to fake read")
 }
 
+func TestComponentUploadsLimitChunksInFlight(t *testing.T) {
+	uploads := newComponentUploads(context.Background(), 2)
+	assert.NoError(t, uploads.acquire())
+	assert.NoError(t, uploads.acquire())
+	assert.False(t, uploads.slots.TryAcquire(1))
+	uploads.release()
+	assert.True(t, uploads.slots.TryAcquire(1))
+
+	uploads.addCreated(&gcs.ObjectHandle{})
+	assert.Len(t, uploads.takeCreated(), 1)
+	assert.Empty(t, uploads.takeCreated())
+
+	uploads.abort()
+	assert.Error(t, uploads.acquire())
+}
+
 func TestJitterDelay(t *testing.T) {
 	baseDelay := time.Second
 	delay := getJitterDelay(baseDelay)
@@ -138,22 +155,26 @@ func TestMinDuration(t *testing.T) {
 
 func TestUploaderOptions(t *testing.T) {
 	testCases := []struct {
-		settings          map[string]string
-		expectedChunkSize int64
-		expectedRetries   int
+		settings                      map[string]string
+		expectedChunkSize             int64
+		expectedRetries               int
+		expectedMaxParallelComponents int
 	}{
 		{
-			settings:          map[string]string{},
-			expectedChunkSize: 50 << 20,
-			expectedRetries:   16,
+			settings:                      map[string]string{},
+			expectedChunkSize:             50 << 20,
+			expectedRetries:               16,
+			expectedMaxParallelComponents: 1,
 		},
 		{
 			settings: map[string]string{
-				"GCS_MAX_CHUNK_SIZE": "100",
-				"GCS_MAX_RETRIES":    "5",
+				"GCS_MAX_CHUNK_SIZE":          "100",
+				"GCS_MAX_RETRIES":             "5",
+				"GCS_MAX_PARALLEL_COMPONENTS": "8",
 			},
-			expectedChunkSize: 100,
-			expectedRetries:   5,
+			expectedChunkSize:             100,
+			expectedRetries:               5,
+			expectedMaxParallelComponents: 8,
 		},
 	}
 
@@ -165,6 +186,7 @@ func TestUploaderOptions(t *testing.T) {
 
 		assert.Equal(t, tc.expectedChunkSize, uploader.maxChunkSize)
 		assert.Equal(t, tc.expectedRetries, uploader.maxUploadRetries)
+		assert.Equal(t, tc.expectedMaxParallelComponents, uploader.maxParallelComponents)
 	}
 }
 
@@ -181,6 +203,10 @@ func TestInvalidUploaderOptions(t *testing.T) {
 			settings:  map[string]string{"GCS_MAX_RETRIES": "test"},
 			errString: `invalid maximum retries setting: strconv.Atoi: parsing "test": invalid syntax`,
 		},
+		{
+			settings:  map[string]string{"GCS_MAX_PARALLEL_COMPONENTS": "0"},
+			errString: `invalid maximum parallel components setting: 0 is less than 1`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -19,6 +19,9 @@ const (
 
 	// defaultMaxRetries limits upload and download retries during interaction with GCS.
 	defaultMaxRetries = 16
+
+	// defaultMaxParallelComponents uploads the chunks one by one, each concurrent chunk holds its own buffer.
+	defaultMaxParallelComponents = 1
 )
 
 type Uploader struct {
@@ -27,6 +30,8 @@ type Uploader struct {
 	baseRetryDelay   time.Duration
 	maxRetryDelay    time.Duration
 	maxUploadRetries int
+	// maxParallelComponents is the number of the chunks of the object uploaded concurrently
+	maxParallelComponents int
 }
 
 type UploaderOption func(*Uploader)
@@ -45,6 +50,8 @@ func NewUploader(objHandle *storage.ObjectHandle, options ...UploaderOption) *Up
 		baseRetryDelay:   BaseRetryDelay,
 		maxRetryDelay:    maxRetryDelay,
 		maxUploadRetries: defaultMaxRetries,
+
+		maxParallelComponents: defaultMaxParallelComponents,
 	}
 
 	for _, opt := range options {
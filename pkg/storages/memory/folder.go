@@ -2,6 +2,7 @@ package memory
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"path"
 	"path/filepath"
@@ -77,6 +78,39 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return io.NopCloser(&object.Data), nil
 }
 
+// ReadObjectVersion returns the object with the version made of its timestamp and size
+func (folder *Folder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	objectAbsPath := path.Join(folder.path, objectRelativePath)
+	object, exists := folder.Storage.Load(objectAbsPath)
+	if !exists {
+		return nil, "", storage.NewObjectNotFoundError(objectAbsPath)
+	}
+	return io.NopCloser(bytes.NewReader(object.Data.Bytes())), objectVersion(object), nil
+}
+
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset int64, version string) (io.ReadCloser, error) {
+	objectAbsPath := path.Join(folder.path, objectRelativePath)
+	object, exists := folder.Storage.Load(objectAbsPath)
+	if !exists {
+		if version != "" {
+			return nil, storage.NewObjectChangedError(objectAbsPath, version)
+		}
+		return nil, storage.NewObjectNotFoundError(objectAbsPath)
+	}
+	if version != "" && objectVersion(object) != version {
+		return nil, storage.NewObjectChangedError(objectAbsPath, version)
+	}
+	data := object.Data.Bytes()
+	if offset < 0 || offset > int64(len(data)) {
+		return nil, errors.Errorf("offset %d is out of '%s' object of %d bytes", offset, objectAbsPath, len(data))
+	}
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+func objectVersion(object TimeStampedData) string {
+	return fmt.Sprintf("%d-%d", object.Timestamp.UnixNano(), object.Size)
+}
+
 func (folder *Folder) PutObject(name string, content io.Reader) error {
 	data, err := io.ReadAll(content)
 	objectPath := path.Join(folder.path, name)
@@ -2,6 +2,7 @@ package s3
 
 import (
 	"io"
+	"net/http"
 	"path"
 	"strconv"
 	"strings"
@@ -30,6 +31,7 @@ const (
 	SseSetting               = "S3_SSE"
 	SseCSetting              = "S3_SSE_C"
 	SseKmsIdSetting          = "S3_SSE_KMS_ID"
+	SseKmsKeyIdSetting       = "S3_SSE_KMS_KEY_ID"
 	StorageClassSetting      = "S3_STORAGE_CLASS"
 	UploadConcurrencySetting = "UPLOAD_CONCURRENCY"
 	s3CertFile               = "S3_CA_CERT_FILE"
@@ -67,6 +69,7 @@ var (
 		SseSetting,
 		SseCSetting,
 		SseKmsIdSetting,
+		SseKmsKeyIdSetting,
 		StorageClassSetting,
 		UploadConcurrencySetting,
 		s3CertFile,
@@ -176,6 +179,12 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 }
 
 func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, _, err := folder.ReadObjectVersion(objectRelativePath)
+	return reader, err
+}
+
+// ReadObjectVersion returns the object with its ETag as the version
+func (folder *Folder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
 	objectPath := folder.Path + objectRelativePath
 	input := &s3.GetObjectInput{
 		Bucket: folder.Bucket,
@@ -185,9 +194,9 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	object, err := folder.S3API.GetObject(input)
 	if err != nil {
 		if isAwsNotExist(err) {
-			return nil, storage.NewObjectNotFoundError(objectPath)
+			return nil, "", storage.NewObjectNotFoundError(objectPath)
 		}
-		return nil, errors.Wrapf(err, "failed to read object: '%s' from S3", objectPath)
+		return nil, "", errors.Wrapf(err, "failed to read object: '%s' from S3", objectPath)
 	}
 
 	rangeEnabled, maxRetries, minRetryDelay, maxRetryDelay := folder.getReaderSettings()
@@ -196,7 +205,42 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	if rangeEnabled {
 		reader = NewS3Reader(object.Body, objectPath, maxRetries, folder, minRetryDelay, maxRetryDelay)
 	}
-	return reader, nil
+	return reader, aws.StringValue(object.ETag), nil
+}
+
+// ReadObjectRange reads the object since the offset, the read is pinned to the ETag if the version is set.
+// If the storage ignores the Range header the object is read from the beginning and the offset bytes are skipped.
+func (folder *Folder) ReadObjectRange(objectRelativePath string, offset int64, version string) (io.ReadCloser, error) {
+	objectPath := folder.Path + objectRelativePath
+	input := &s3.GetObjectInput{
+		Bucket: folder.Bucket,
+		Key:    aws.String(objectPath),
+		Range:  aws.String("bytes=" + strconv.FormatInt(offset, 10) + "-"),
+	}
+	if version != "" {
+		input.IfMatch = aws.String(version)
+	}
+
+	object, err := folder.S3API.GetObject(input)
+	if err != nil {
+		if isAwsNotExist(err) {
+			if version != "" {
+				return nil, storage.NewObjectChangedError(objectPath, version)
+			}
+			return nil, storage.NewObjectNotFoundError(objectPath)
+		}
+		if isAwsPreconditionFailed(err) {
+			return nil, storage.NewObjectChangedError(objectPath, version)
+		}
+		return nil, errors.Wrapf(err, "failed to read object: '%s' from S3 since %d", objectPath, offset)
+	}
+	if offset > 0 && object.ContentRange == nil {
+		if _, err := io.CopyN(io.Discard, object.Body, offset); err != nil {
+			_ = object.Body.Close()
+			return nil, errors.Wrapf(err, "failed to skip %d bytes of object: '%s' from S3", offset, objectPath)
+		}
+	}
+	return object.Body, nil
 }
 
 func (folder *Folder) getReaderSettings() (rangeEnabled bool, retriesCount int, minRetryDelay, maxRetryDelay time.Duration) {
@@ -317,6 +361,13 @@ func (folder *Folder) partitionToObjects(keys []string) []*s3.ObjectIdentifier {
 	return objects
 }
 
+func isAwsPreconditionFailed(err error) bool {
+	if requestErr, ok := err.(awserr.RequestFailure); ok {
+		return requestErr.StatusCode() == http.StatusPreconditionFailed
+	}
+	return false
+}
+
 func isAwsNotExist(err error) bool {
 	if awsErr, ok := err.(awserr.Error); ok {
 		if awsErr.Code() == NotFoundAWSErrorCode || awsErr.Code() == NoSuchKeyAWSErrorCode {
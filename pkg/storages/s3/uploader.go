@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -32,6 +34,43 @@ func (err SseKmsIdNotSetError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type SseKmsIdConflictError struct {
+	error
+}
+
+func NewSseKmsIdConflictError() SseKmsIdConflictError {
+	return SseKmsIdConflictError{errors.Errorf("%s and %s are set to different values", SseKmsIdSetting, SseKmsKeyIdSetting)}
+}
+
+func (err SseKmsIdConflictError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// SseKmsKeyError is returned when S3 rejects the upload because of the configured aws:kms key
+type SseKmsKeyError struct {
+	error
+}
+
+func NewSseKmsKeyError(err error, sseKmsKeyId string) SseKmsKeyError {
+	return SseKmsKeyError{errors.Wrapf(err, "S3 rejected the object encrypted with aws:kms key '%s' (%s), "+
+		"check that the key exists in the bucket region, is enabled and may be used by the storage credentials",
+		sseKmsKeyId, SseKmsIdSetting)}
+}
+
+func (err SseKmsKeyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// kmsErrorCodes are returned by S3 if the object can not be encrypted with the requested KMS key,
+// the generic codes as AccessDenied are not KMS-specific, so they are kept as is
+var kmsErrorCodes = map[string]bool{
+	"KMS.AccessDeniedException":    true,
+	"KMS.DisabledException":        true,
+	"KMS.KMSInvalidStateException": true,
+	"KMS.NotFoundException":        true,
+	"KMS.InvalidKeyUsageException": true,
+}
+
 type Uploader struct {
 	uploaderAPI          s3manageriface.UploaderAPI
 	serverSideEncryption string
@@ -74,17 +113,54 @@ func (uploader *Uploader) createUploadInput(bucket, path string, content io.Read
 }
 
 func (uploader *Uploader) upload(bucket, path string, content io.Reader) error {
-	input := uploader.createUploadInput(bucket, path, content)
-	_, err := uploader.uploaderAPI.Upload(input)
+	counter := &countingReader{reader: content}
+	input := uploader.createUploadInput(bucket, path, counter)
+	startTime := time.Now()
+	output, err := uploader.uploaderAPI.Upload(input)
+	if err != nil && uploader.isSseKmsKeyError(err) {
+		err = NewSseKmsKeyError(err, uploader.SSEKMSKeyId)
+	}
+	if err == nil && output != nil && output.UploadID != "" {
+		// only the objects larger than the part size are uploaded in parts, the rest are put by a single request
+		duration := time.Since(startTime)
+		tracelog.InfoLogger.Printf("Uploaded '%s' in parts: %d bytes in %v (%.1f MB/s)",
+			path, counter.size, duration.Round(time.Millisecond), float64(counter.size)/(1<<20)/duration.Seconds())
+	}
 	return errors.Wrapf(err, "failed to upload '%s' to bucket '%s'", path, bucket)
 }
 
+// countingReader counts the bytes read by the uploader to report the throughput
+type countingReader struct {
+	reader io.Reader
+	size   int64
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.size += int64(n)
+	return n, err
+}
+
+// isSseKmsKeyError checks if the upload error is caused by the aws:kms key, since S3 reports it as a generic error
+func (uploader *Uploader) isSseKmsKeyError(err error) bool {
+	if uploader.SSEKMSKeyId == "" {
+		return false
+	}
+	if awsErr, ok := err.(awserr.Error); ok {
+		return kmsErrorCodes[awsErr.Code()]
+	}
+	return false
+}
+
 // CreateUploaderAPI returns an uploader with customizable concurrency
-// and part size.
+// and part size. The streams larger than the part size are uploaded by the multipart upload: up to concurrency
+// parts are uploaded in parallel, so about (concurrency + 1) * partsize bytes are buffered per stream.
+// The failed multipart upload is aborted, so its uploaded parts are not left in the bucket.
 func CreateUploaderAPI(svc s3iface.S3API, partsize, concurrency int) s3manageriface.UploaderAPI {
 	uploaderAPI := s3manager.NewUploaderWithClient(svc, func(uploader *s3manager.Uploader) {
 		uploader.PartSize = int64(partsize)
 		uploader.Concurrency = concurrency
+		uploader.LeavePartsOnError = false
 	})
 	return uploaderAPI
 }
@@ -94,6 +170,12 @@ func configureServerSideEncryption(settings map[string]string) (serverSideEncryp
 	serverSideEncryption, _ = settings[SseSetting]
 	sseCustomerKey, _ = settings[SseCSetting]
 	sseKmsKeyId, _ = settings[SseKmsIdSetting]
+	if sseKmsKeyIdAlias, ok := settings[SseKmsKeyIdSetting]; ok {
+		if sseKmsKeyId != "" && sseKmsKeyId != sseKmsKeyIdAlias {
+			return "", "", "", NewSseKmsIdConflictError()
+		}
+		sseKmsKeyId = sseKmsKeyIdAlias
+	}
 
 	// Only aws:kms implies sseKmsKeyId
 	if (serverSideEncryption == "aws:kms") == (sseKmsKeyId == "") {
@@ -125,6 +207,10 @@ func configureUploader(s3Client *s3.S3, settings map[string]string) (*Uploader,
 		if err != nil {
 			return nil, NewFolderError(err, "Invalid upload concurrency setting")
 		}
+		if concurrency < 1 {
+			return nil, NewFolderError(errors.Errorf("got %d", concurrency),
+				"Invalid upload concurrency setting, it must be positive")
+		}
 	} else {
 		return nil, NewConfiguringError(UploadConcurrencySetting)
 	}
@@ -135,6 +221,10 @@ func configureUploader(s3Client *s3.S3, settings map[string]string) (*Uploader,
 		if err != nil {
 			return nil, NewFolderError(err, "Invalid s3 max part size setting")
 		}
+		if int64(maxPartSize) < s3manager.MinUploadPartSize {
+			return nil, NewFolderError(errors.Errorf("got %d", maxPartSize),
+				"Invalid s3 max part size setting, S3 requires the parts of at least %d bytes", s3manager.MinUploadPartSize)
+		}
 	} else {
 		maxPartSize = DefaultMaxPartSize
 	}
@@ -0,0 +1,175 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingUploaderAPI struct {
+	err error
+}
+
+func (api *failingUploaderAPI) Upload(input *s3manager.UploadInput,
+	options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	return nil, api.err
+}
+
+func (api *failingUploaderAPI) UploadWithContext(ctx aws.Context, input *s3manager.UploadInput,
+	options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	return nil, api.err
+}
+
+func TestUpload_ReportsSseKmsKeyError(t *testing.T) {
+	api := &failingUploaderAPI{err: awserr.New("KMS.NotFoundException", "Invalid keyId", nil)}
+	uploader := NewUploader(api, "aws:kms", "", "test-key", "STANDARD")
+
+	err := uploader.upload("bucket", "path", strings.NewReader(""))
+	var kmsKeyError SseKmsKeyError
+	assert.True(t, errors.As(err, &kmsKeyError))
+	assert.Contains(t, err.Error(), "test-key")
+}
+
+func TestUpload_KeepsOtherErrors(t *testing.T) {
+	api := &failingUploaderAPI{err: awserr.New("AccessDenied", "Access Denied", nil)}
+	uploader := NewUploader(api, "AES256", "", "", "STANDARD")
+
+	err := uploader.upload("bucket", "path", strings.NewReader(""))
+	var kmsKeyError SseKmsKeyError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &kmsKeyError))
+}
+
+func TestUpload_KeepsGenericErrorsWithKmsKey(t *testing.T) {
+	api := &failingUploaderAPI{err: awserr.New("AccessDenied", "Access Denied", nil)}
+	uploader := NewUploader(api, "aws:kms", "", "test-key", "STANDARD")
+
+	err := uploader.upload("bucket", "path", strings.NewReader(""))
+	var kmsKeyError SseKmsKeyError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &kmsKeyError))
+}
+
+func TestConfigureServerSideEncryption_KmsKeyIdAlias(t *testing.T) {
+	_, _, sseKmsKeyId, err := configureServerSideEncryption(map[string]string{
+		SseSetting:         "aws:kms",
+		SseKmsKeyIdSetting: "test-key",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-key", sseKmsKeyId)
+
+	_, _, _, err = configureServerSideEncryption(map[string]string{
+		SseSetting:         "aws:kms",
+		SseKmsIdSetting:    "test-key",
+		SseKmsKeyIdSetting: "other-key",
+	})
+	assert.Error(t, err)
+}
+
+// multipartS3API serves the multipart upload requests in memory, the client is only used
+// to presign the location of the uploaded object
+type multipartS3API struct {
+	*s3.S3
+	failPart int64
+
+	mutex     sync.Mutex
+	parts     map[int64]int
+	completed bool
+	aborted   bool
+}
+
+func newMultipartS3API(t *testing.T, failPart int64) *multipartS3API {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.AnonymousCredentials,
+	})
+	assert.NoError(t, err)
+	return &multipartS3API{S3: s3.New(sess), failPart: failPart, parts: make(map[int64]int)}
+}
+
+func (api *multipartS3API) CreateMultipartUploadWithContext(ctx aws.Context, input *s3.CreateMultipartUploadInput,
+	options ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}, nil
+}
+
+func (api *multipartS3API) UploadPartWithContext(ctx aws.Context, input *s3.UploadPartInput,
+	options ...request.Option) (*s3.UploadPartOutput, error) {
+	if *input.PartNumber == api.failPart {
+		return nil, awserr.New("InternalError", "We encountered an internal error", nil)
+	}
+	content, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.parts[*input.PartNumber] = len(content)
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (api *multipartS3API) CompleteMultipartUploadWithContext(ctx aws.Context, input *s3.CompleteMultipartUploadInput,
+	options ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.completed = true
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (api *multipartS3API) AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput,
+	options ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.aborted = true
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestUpload_UploadsStreamInParts(t *testing.T) {
+	partSize := int(s3manager.MinUploadPartSize)
+	api := newMultipartS3API(t, 0)
+	uploader := NewUploader(CreateUploaderAPI(api, partSize, 2), "", "", "", "STANDARD")
+	content := bytes.Repeat([]byte("x"), 2*partSize+partSize/2)
+
+	// the stream is not seekable, so its parts are buffered
+	assert.NoError(t, uploader.upload("bucket", "path", io.MultiReader(bytes.NewReader(content))))
+
+	assert.True(t, api.completed)
+	assert.False(t, api.aborted)
+	assert.Equal(t, map[int64]int{1: partSize, 2: partSize, 3: partSize / 2}, api.parts)
+}
+
+func TestUpload_AbortsFailedMultipartUpload(t *testing.T) {
+	partSize := int(s3manager.MinUploadPartSize)
+	api := newMultipartS3API(t, 2)
+	uploader := NewUploader(CreateUploaderAPI(api, partSize, 2), "", "", "", "STANDARD")
+	content := bytes.Repeat([]byte("x"), 3*partSize)
+
+	err := uploader.upload("bucket", "path", io.MultiReader(bytes.NewReader(content)))
+	assert.Error(t, err)
+	assert.True(t, api.aborted)
+	assert.False(t, api.completed)
+}
+
+func TestConfigureUploader_RejectsTooSmallPartSize(t *testing.T) {
+	_, err := configureUploader(nil, map[string]string{
+		UploadConcurrencySetting: "4",
+		MaxPartSize:              "1024",
+	})
+	assert.Error(t, err)
+
+	_, err = configureUploader(nil, map[string]string{
+		UploadConcurrencySetting: "0",
+	})
+	assert.Error(t, err)
+}
@@ -19,6 +19,20 @@ func (err ObjectNotFoundError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+// ObjectChangedError is returned by the range read pinned to the version of the object
+// if the object was overwritten or removed since that version was read
+type ObjectChangedError struct {
+	error
+}
+
+func NewObjectChangedError(path string, version string) ObjectChangedError {
+	return ObjectChangedError{errors.Errorf("object '%s' is not of version '%s' anymore", path, version)}
+}
+
+func (err ObjectChangedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type Error struct {
 	error
 }
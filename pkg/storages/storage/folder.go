@@ -31,6 +31,19 @@ type Folder interface {
 	CopyObject(srcPath string, dstPath string) error
 }
 
+// RangeReader is implemented by the folders able to read the object since the byte offset,
+// e.g. by HTTP Range requests. The range reads may be pinned to the object version, e.g. ETag,
+// so the parts of the different versions of the overwritten object are never joined.
+type RangeReader interface {
+	// ReadObjectVersion reads the object as ReadObject does and returns its version,
+	// the version is empty if the storage does not tell it
+	ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error)
+
+	// Should return ObjectNotFoundError in case, there is no such object,
+	// and ObjectChangedError if the version is not empty and the object is not of this version anymore
+	ReadObjectRange(objectRelativePath string, offset int64, version string) (io.ReadCloser, error)
+}
+
 func DeleteObjectsWhere(folder Folder, confirm bool, filter func(object1 Object) bool) error {
 	relativePathObjects, err := ListFolderRecursively(folder)
 	if err != nil {
@@ -56,6 +56,7 @@ const (
 	CopiedBlockMaxSize     = CompressedBlockMaxSize
 	MetadataFileName       = "metadata.json"
 	StreamMetadataFileName = "stream_metadata.json"
+	StreamPartsFileName    = "stream_parts.json"
 	PathSeparator          = string(os.PathSeparator)
 	Mebibyte               = 1024 * 1024
 )